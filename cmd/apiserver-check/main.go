@@ -0,0 +1,148 @@
+// Package main implements an API server check for Kuberhealthy.  It performs representative operations against the
+// kube-apiserver by creating, getting, listing and deleting a canary ConfigMap, and fails when an operation fails or
+// its latency exceeds the threshold of its verb.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultSamples is the number of times each operation is measured when SAMPLES is not set
+	defaultSamples = 3
+
+	// defaultRequestTimeout is how long a single operation may take when REQUEST_TIMEOUT is not set
+	defaultRequestTimeout = time.Second * 10
+
+	// defaultMaxGetLatency is the highest median latency of gets that passes when MAX_GET_LATENCY is not set
+	defaultMaxGetLatency = time.Millisecond * 500
+
+	// defaultMaxListLatency is the highest median latency of lists that passes when MAX_LIST_LATENCY is not set
+	defaultMaxListLatency = time.Second
+
+	// defaultMaxCreateLatency is the highest median latency of creates that passes when MAX_CREATE_LATENCY is not set
+	defaultMaxCreateLatency = time.Second
+
+	// defaultMaxDeleteLatency is the highest median latency of deletes that passes when MAX_DELETE_LATENCY is not set
+	defaultMaxDeleteLatency = time.Second
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile      = os.Getenv("KUBECONFIG")
+	checkNamespace      = os.Getenv("CHECK_NAMESPACE")
+	samplesEnv          = os.Getenv("SAMPLES")
+	requestTimeoutEnv   = os.Getenv("REQUEST_TIMEOUT")
+	maxGetLatencyEnv    = os.Getenv("MAX_GET_LATENCY")
+	maxListLatencyEnv   = os.Getenv("MAX_LIST_LATENCY")
+	maxCreateLatencyEnv = os.Getenv("MAX_CREATE_LATENCY")
+	maxDeleteLatencyEnv = os.Getenv("MAX_DELETE_LATENCY")
+	runUUID             = os.Getenv("KH_RUN_UUID")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace      string
+	samples        int
+	requestTimeout time.Duration
+	thresholds     map[verb]time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	// the canaries are deleted before the check, in case earlier runs left any behind, and again after it.  Deleting
+	// them is bounded by the request timeout, so that is the time left before the deadline to do it.
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "canaries",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: cfg.requestTimeout,
+		Run: func(ctx context.Context) []string {
+			latencies, err := measureOperations(ctx, client, cfg, canaryPrefix(runUUID))
+			problems := evaluateLatencies(latencies, cfg.thresholds)
+			if err != nil {
+				problems = append([]string{err.Error()}, problems...)
+			}
+			return problems
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deleteCanaries(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:      os.Getenv("KH_POD_NAMESPACE"),
+		samples:        defaultSamples,
+		requestTimeout: defaultRequestTimeout,
+		thresholds: map[verb]time.Duration{
+			verbCreate: defaultMaxCreateLatency,
+			verbGet:    defaultMaxGetLatency,
+			verbList:   defaultMaxListLatency,
+			verbDelete: defaultMaxDeleteLatency,
+		},
+	}
+	var err error
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+
+	if len(samplesEnv) > 0 {
+		cfg.samples, err = strconv.Atoi(samplesEnv)
+		if err != nil || cfg.samples <= 0 {
+			return cfg, fmt.Errorf("SAMPLES must be a positive number, but was %q", samplesEnv)
+		}
+	}
+
+	if len(requestTimeoutEnv) > 0 {
+		cfg.requestTimeout, err = time.ParseDuration(requestTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing REQUEST_TIMEOUT: %w", err)
+		}
+	}
+
+	thresholdEnvs := map[verb]string{
+		verbCreate: maxCreateLatencyEnv,
+		verbGet:    maxGetLatencyEnv,
+		verbList:   maxListLatencyEnv,
+		verbDelete: maxDeleteLatencyEnv,
+	}
+	for _, v := range verbs {
+		value := thresholdEnvs[v]
+		if len(value) == 0 {
+			continue
+		}
+		cfg.thresholds[v], err = time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing the maximum %s latency %q: %w", v, value, err)
+		}
+	}
+
+	return cfg, nil
+}
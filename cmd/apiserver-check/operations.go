@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// canaryLabel is the label that marks the canary ConfigMaps created by the check
+const canaryLabel = "kuberhealthy-apiserver-canary"
+
+// canaryNamePrefix is the prefix of the names of canary ConfigMaps
+const canaryNamePrefix = "kh-apiserver-canary-"
+
+// verb is an operation performed against the API server
+type verb string
+
+// the operations that are measured, in the order that they are performed on a canary
+const (
+	verbCreate verb = "create"
+	verbGet    verb = "get"
+	verbList   verb = "list"
+	verbDelete verb = "delete"
+)
+
+// verbs are the measured operations in the order that they are performed
+var verbs = []verb{verbCreate, verbGet, verbList, verbDelete}
+
+// canaryPrefix returns the prefix of the names of the canaries of a run
+func canaryPrefix(runUUID string) string {
+	id := runUUID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	if len(id) == 0 {
+		id = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return canaryNamePrefix + strings.ToLower(id) + "-"
+}
+
+// deleteCanaries deletes the canary ConfigMaps of the check, including the ones that earlier runs failed to delete
+func deleteCanaries(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	reqCtx, cancel := context.WithTimeout(ctx, cfg.requestTimeout)
+	defer cancel()
+	configMaps, err := client.CoreV1().ConfigMaps(cfg.namespace).List(reqCtx, metav1.ListOptions{LabelSelector: canaryLabel + "=true"})
+	if err != nil {
+		return fmt.Errorf("failed to list canary configmaps: %w", err)
+	}
+	var errs []error
+	for _, cm := range configMaps.Items {
+		log.Infoln("Deleting canary configmap", cm.Name)
+		err := client.CoreV1().ConfigMaps(cfg.namespace).Delete(reqCtx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete canary configmap %s: %w", cm.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// measureOperations creates, gets, lists and deletes a canary ConfigMap the configured number of times and returns
+// the latency of every operation by verb.  The first operation that fails stops the measurements, and is returned as
+// an error naming its verb along with the latencies measured until then.
+func measureOperations(ctx context.Context, client kubernetes.Interface, cfg checkConfig, prefix string) (map[verb][]time.Duration, error) {
+	configMaps := client.CoreV1().ConfigMaps(cfg.namespace)
+	latencies := make(map[verb][]time.Duration)
+
+	// measure runs an operation with the request timeout and records its latency when it succeeds
+	measure := func(v verb, name string, op func(ctx context.Context) error) error {
+		reqCtx, cancel := context.WithTimeout(ctx, cfg.requestTimeout)
+		defer cancel()
+		start := time.Now()
+		err := op(reqCtx)
+		latency := time.Since(start)
+		if err != nil {
+			return fmt.Errorf("%s of configmap %s in namespace %s failed after %s: %w", v, name, cfg.namespace, latency.Round(time.Millisecond), err)
+		}
+		log.Infoln(v, "of configmap", name, "took", latency)
+		latencies[v] = append(latencies[v], latency)
+		return nil
+	}
+
+	for i := 0; i < cfg.samples; i++ {
+		name := prefix + strconv.Itoa(i)
+		canary := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{canaryLabel: "true"},
+			},
+			Data: map[string]string{"sample": strconv.Itoa(i)},
+		}
+
+		err := measure(verbCreate, name, func(ctx context.Context) error {
+			_, err := configMaps.Create(ctx, canary, metav1.CreateOptions{})
+			return err
+		})
+		if err != nil {
+			return latencies, err
+		}
+
+		err = measure(verbGet, name, func(ctx context.Context) error {
+			_, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+			return err
+		})
+		if err == nil {
+			err = measure(verbList, canaryLabel, func(ctx context.Context) error {
+				_, err := configMaps.List(ctx, metav1.ListOptions{LabelSelector: canaryLabel + "=true"})
+				return err
+			})
+		}
+
+		// the canary is always deleted, even when reading it failed
+		deleteErr := measure(verbDelete, name, func(ctx context.Context) error {
+			return configMaps.Delete(ctx, name, metav1.DeleteOptions{})
+		})
+		if err != nil {
+			return latencies, err
+		}
+		if deleteErr != nil {
+			return latencies, deleteErr
+		}
+	}
+	return latencies, nil
+}
+
+// median returns the median of the supplied latencies
+func median(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// evaluateLatencies returns a problem for every verb whose median latency exceeds its threshold
+func evaluateLatencies(latencies map[verb][]time.Duration, thresholds map[verb]time.Duration) []string {
+	var problems []string
+	for _, v := range verbs {
+		if len(latencies[v]) == 0 {
+			continue
+		}
+		m := median(latencies[v])
+		log.Infoln("Median", v, "latency is", m, "over", len(latencies[v]), "samples")
+		threshold, ok := thresholds[v]
+		if !ok || threshold <= 0 || m <= threshold {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s latency of %s exceeds %s", v, m.Round(time.Millisecond), threshold))
+	}
+	return problems
+}
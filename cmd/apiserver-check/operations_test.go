@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// samplingConfig returns the settings of a check that times each API server operation the supplied number of times
+// and gives up on a request after a second
+func samplingConfig(samples int) checkConfig {
+	return checkConfig{
+		namespace:      "kuberhealthy",
+		samples:        samples,
+		requestTimeout: time.Second,
+	}
+}
+
+func TestMeasureOperations(t *testing.T) {
+	stale := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      canaryNamePrefix + "stale-0",
+		Namespace: "kuberhealthy",
+		Labels:    map[string]string{canaryLabel: "true"},
+	}}
+	client := fake.NewSimpleClientset(stale)
+	cfg := samplingConfig(3)
+
+	err := deleteCanaries(context.Background(), client, cfg)
+	if err != nil {
+		t.Fatalf("expected the stale canary to be deleted but got: %s", err)
+	}
+	latencies, err := measureOperations(context.Background(), client, cfg, canaryPrefix("4f8b1c2a-0000"))
+	if err != nil {
+		t.Fatalf("expected all operations to succeed but got: %s", err)
+	}
+	for _, v := range verbs {
+		if len(latencies[v]) != 3 {
+			t.Errorf("expected three %s samples but got %d", v, len(latencies[v]))
+		}
+	}
+
+	// the stale canary and the canaries of the run are all deleted
+	configMaps, err := client.CoreV1().ConfigMaps("kuberhealthy").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != 0 {
+		t.Fatalf("expected no canaries to be left behind but got %d", len(configMaps.Items))
+	}
+}
+
+func TestMeasureOperationsFailure(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("etcdserver: request timed out")
+	})
+
+	latencies, err := measureOperations(context.Background(), client, samplingConfig(3), canaryPrefix("4f8b1c2a"))
+	if err == nil {
+		t.Fatal("expected a failed get to fail the measurements")
+	}
+	if !strings.HasPrefix(err.Error(), "get of configmap kh-apiserver-canary-4f8b1c2a-0") {
+		t.Fatalf("expected the error to name the verb and canary but got: %s", err)
+	}
+	if len(latencies[verbCreate]) != 1 || len(latencies[verbDelete]) != 1 {
+		t.Fatalf("expected the canary to be created and deleted once but got %v", latencies)
+	}
+
+	// the canary is deleted even though reading it failed
+	configMaps, err := client.CoreV1().ConfigMaps("kuberhealthy").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != 0 {
+		t.Fatalf("expected the canary to be deleted but got %d configmaps", len(configMaps.Items))
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := map[string]struct {
+		latencies []time.Duration
+		expected  time.Duration
+	}{
+		"none": {nil, 0},
+		"odd":  {[]time.Duration{time.Second * 3, time.Second, time.Second * 2}, time.Second * 2},
+		"even": {[]time.Duration{time.Second * 4, time.Second, time.Second * 2, time.Second * 3}, time.Millisecond * 2500},
+	}
+	for name, test := range tests {
+		if m := median(test.latencies); m != test.expected {
+			t.Errorf("%s: expected a median of %s but got %s", name, test.expected, m)
+		}
+	}
+}
+
+func TestEvaluateLatencies(t *testing.T) {
+	latencies := map[verb][]time.Duration{
+		verbCreate: {time.Millisecond * 100, time.Millisecond * 120, time.Millisecond * 110},
+		verbGet:    {time.Millisecond * 20, time.Second * 5, time.Millisecond * 30},
+		verbList:   {time.Millisecond * 2300, time.Millisecond * 2200, time.Millisecond * 2400},
+		verbDelete: {time.Millisecond * 90},
+	}
+	thresholds := map[verb]time.Duration{
+		verbCreate: time.Second,
+		verbGet:    time.Millisecond * 500,
+		verbList:   time.Second,
+		verbDelete: 0,
+	}
+
+	// a single slow get does not move the median, and a threshold of zero is never exceeded
+	problems := evaluateLatencies(latencies, thresholds)
+	if len(problems) != 1 {
+		t.Fatalf("expected only the list latency to be reported but got %v", problems)
+	}
+	if problems[0] != "list latency of 2.3s exceeds 1s" {
+		t.Fatalf("unexpected problem: %s", problems[0])
+	}
+}
+
+func TestCanaryPrefix(t *testing.T) {
+	if prefix := canaryPrefix("4F8B1C2A-1D3E-4B5F"); prefix != "kh-apiserver-canary-4f8b1c2a-" {
+		t.Fatalf("expected the prefix to hold the start of the run uuid but got %s", prefix)
+	}
+	if prefix := canaryPrefix(""); !strings.HasPrefix(prefix, canaryNamePrefix) || len(prefix) <= len(canaryNamePrefix)+1 {
+		t.Fatalf("expected a generated prefix but got %s", prefix)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultDNSPort is the port that endpoints are queried on when the DNS service does not name a UDP port
+const defaultDNSPort = 53
+
+// dnsEndpoint is a single DNS server behind the cluster DNS service
+type dnsEndpoint struct {
+	ip    string
+	port  int32
+	pod   string
+	ready bool
+}
+
+// String returns the address of the endpoint along with the pod that serves it, such as 10.244.1.5:53 (coredns-abc)
+func (e dnsEndpoint) String() string {
+	if len(e.pod) == 0 {
+		return e.address()
+	}
+	return e.address() + " (" + e.pod + ")"
+}
+
+// address returns the host and port that the endpoint is queried on
+func (e dnsEndpoint) address() string {
+	return net.JoinHostPort(e.ip, strconv.Itoa(int(e.port)))
+}
+
+// listEndpoints returns the ready and not ready endpoints of the supplied DNS service
+func listEndpoints(ctx context.Context, client kubernetes.Interface, namespace string, service string) ([]dnsEndpoint, error) {
+	endpoints, err := client.CoreV1().Endpoints(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the endpoints of DNS service %s/%s: %w", namespace, service, err)
+	}
+
+	var dnsEndpoints []dnsEndpoint
+	for _, subset := range endpoints.Subsets {
+		port := dnsPort(subset.Ports)
+		for _, address := range subset.Addresses {
+			dnsEndpoints = append(dnsEndpoints, newDNSEndpoint(address, port, true))
+		}
+		for _, address := range subset.NotReadyAddresses {
+			dnsEndpoints = append(dnsEndpoints, newDNSEndpoint(address, port, false))
+		}
+	}
+	if len(dnsEndpoints) == 0 {
+		return nil, fmt.Errorf("DNS service %s/%s has no endpoints", namespace, service)
+	}
+	return dnsEndpoints, nil
+}
+
+// newDNSEndpoint creates a dnsEndpoint from an address of an endpoints subset
+func newDNSEndpoint(address v1.EndpointAddress, port int32, ready bool) dnsEndpoint {
+	e := dnsEndpoint{ip: address.IP, port: port, ready: ready}
+	if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+		e.pod = address.TargetRef.Name
+	}
+	return e
+}
+
+// dnsPort returns the UDP port named dns, or else the first UDP port, of an endpoints subset
+func dnsPort(ports []v1.EndpointPort) int32 {
+	var port int32
+	for _, p := range ports {
+		if p.Protocol != v1.ProtocolUDP {
+			continue
+		}
+		if p.Name == "dns" {
+			return p.Port
+		}
+		if port == 0 {
+			port = p.Port
+		}
+	}
+	if port == 0 {
+		return defaultDNSPort
+	}
+	return port
+}
+
+// notReadyEndpoints returns a problem for every endpoint that is not ready to serve queries
+func notReadyEndpoints(endpoints []dnsEndpoint) []string {
+	var problems []string
+	for _, e := range endpoints {
+		if !e.ready {
+			problems = append(problems, "DNS endpoint "+e.String()+" is not ready")
+		}
+	}
+	return problems
+}
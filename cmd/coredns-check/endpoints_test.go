@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListEndpoints(t *testing.T) {
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{
+				{IP: "10.244.1.5", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "coredns-a"}},
+				{IP: "10.244.2.7", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "coredns-b"}},
+			},
+			NotReadyAddresses: []v1.EndpointAddress{
+				{IP: "10.244.3.9", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "coredns-c"}},
+			},
+			Ports: []v1.EndpointPort{
+				{Name: "metrics", Port: 9153, Protocol: v1.ProtocolTCP},
+				{Name: "dns-tcp", Port: 53, Protocol: v1.ProtocolTCP},
+				{Name: "dns", Port: 5353, Protocol: v1.ProtocolUDP},
+			},
+		}},
+	}
+	client := fake.NewSimpleClientset(endpoints)
+
+	dnsEndpoints, err := listEndpoints(context.Background(), client, "kube-system", "kube-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []dnsEndpoint{
+		{ip: "10.244.1.5", port: 5353, pod: "coredns-a", ready: true},
+		{ip: "10.244.2.7", port: 5353, pod: "coredns-b", ready: true},
+		{ip: "10.244.3.9", port: 5353, pod: "coredns-c", ready: false},
+	}
+	if !reflect.DeepEqual(dnsEndpoints, expected) {
+		t.Fatalf("expected endpoints %v but got %v", expected, dnsEndpoints)
+	}
+
+	problems := notReadyEndpoints(dnsEndpoints)
+	if len(problems) != 1 || problems[0] != "DNS endpoint 10.244.3.9:5353 (coredns-c) is not ready" {
+		t.Fatalf("expected only the not ready endpoint to be reported but got %v", problems)
+	}
+}
+
+func TestListEndpointsEmpty(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"}})
+	_, err := listEndpoints(context.Background(), client, "kube-system", "kube-dns")
+	if err == nil || err.Error() != "DNS service kube-system/kube-dns has no endpoints" {
+		t.Fatalf("expected a service without endpoints to fail but got: %v", err)
+	}
+
+	_, err = listEndpoints(context.Background(), client, "kube-system", "coredns")
+	if err == nil {
+		t.Fatal("expected a missing service to fail")
+	}
+}
+
+func TestDNSPort(t *testing.T) {
+	tests := map[string]struct {
+		ports    []v1.EndpointPort
+		expected int32
+	}{
+		"none":     {nil, defaultDNSPort},
+		"tcp only": {[]v1.EndpointPort{{Port: 5353, Protocol: v1.ProtocolTCP}}, defaultDNSPort},
+		"unnamed":  {[]v1.EndpointPort{{Port: 5353, Protocol: v1.ProtocolUDP}}, 5353},
+		"named":    {[]v1.EndpointPort{{Name: "other", Port: 1053, Protocol: v1.ProtocolUDP}, {Name: "dns", Port: 5353, Protocol: v1.ProtocolUDP}}, 5353},
+	}
+	for name, test := range tests {
+		if port := dnsPort(test.ports); port != test.expected {
+			t.Errorf("%s: expected port %d but got %d", name, test.expected, port)
+		}
+	}
+}
+
+func TestEndpointString(t *testing.T) {
+	if s := (dnsEndpoint{ip: "fd00::10", port: 53}).String(); s != "[fd00::10]:53" {
+		t.Fatalf("expected an IPv6 address without a pod but got %s", s)
+	}
+}
@@ -0,0 +1,175 @@
+// Package main implements a CoreDNS check for Kuberhealthy.  It resolves internal and external hostnames against
+// every endpoint of the cluster DNS service directly, bypassing the service IP, and fails when an endpoint is not
+// ready, fails to resolve a hostname or takes longer than the maximum latency to do so.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultDNSNamespace is the namespace of the cluster DNS service when DNS_NAMESPACE is not set
+	defaultDNSNamespace = "kube-system"
+
+	// defaultDNSService is the name of the cluster DNS service when DNS_SERVICE is not set
+	defaultDNSService = "kube-dns"
+
+	// defaultInternalHostnames are the cluster internal hostnames that are resolved when INTERNAL_HOSTNAMES is not set
+	defaultInternalHostnames = "kubernetes.default.svc.cluster.local"
+
+	// defaultExternalHostnames are the external hostnames that are resolved when EXTERNAL_HOSTNAMES is not set
+	defaultExternalHostnames = "google.com"
+
+	// defaultQueryTimeout is how long a single lookup may take when QUERY_TIMEOUT is not set
+	defaultQueryTimeout = time.Second * 5
+
+	// defaultMaxLatency is the highest latency of a lookup that passes when MAX_LATENCY is not set
+	defaultMaxLatency = time.Millisecond * 500
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile  = os.Getenv("KUBECONFIG")
+	dnsNamespaceEnv = os.Getenv("DNS_NAMESPACE")
+	dnsServiceEnv   = os.Getenv("DNS_SERVICE")
+	queryTimeoutEnv = os.Getenv("QUERY_TIMEOUT")
+	maxLatencyEnv   = os.Getenv("MAX_LATENCY")
+
+	// the hostname lists may be set to an empty value to not resolve any internal or external hostnames
+	internalHostnamesEnv, internalHostnamesSet = os.LookupEnv("INTERNAL_HOSTNAMES")
+	externalHostnamesEnv, externalHostnamesSet = os.LookupEnv("EXTERNAL_HOSTNAMES")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace    string
+	service      string
+	hostnames    []string
+	queryTimeout time.Duration
+	maxLatency   time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	endpoints, err := listEndpoints(ctx, client, cfg.namespace, cfg.service)
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	problems := notReadyEndpoints(endpoints)
+	results := queryEndpoints(ctx, endpoints, cfg.hostnames, cfg.queryTimeout)
+	problems = append(problems, evaluateResults(results, cfg.maxLatency)...)
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:    defaultDNSNamespace,
+		service:      defaultDNSService,
+		queryTimeout: defaultQueryTimeout,
+		maxLatency:   defaultMaxLatency,
+	}
+	var err error
+
+	if len(dnsNamespaceEnv) > 0 {
+		cfg.namespace = dnsNamespaceEnv
+	}
+	if len(dnsServiceEnv) > 0 {
+		cfg.service = dnsServiceEnv
+	}
+
+	internalHostnames := defaultInternalHostnames
+	if internalHostnamesSet {
+		internalHostnames = internalHostnamesEnv
+	}
+	externalHostnames := defaultExternalHostnames
+	if externalHostnamesSet {
+		externalHostnames = externalHostnamesEnv
+	}
+	cfg.hostnames = append(splitList(internalHostnames), splitList(externalHostnames)...)
+	if len(cfg.hostnames) == 0 {
+		return cfg, fmt.Errorf("INTERNAL_HOSTNAMES or EXTERNAL_HOSTNAMES must list at least one hostname to resolve")
+	}
+
+	if len(queryTimeoutEnv) > 0 {
+		cfg.queryTimeout, err = time.ParseDuration(queryTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing QUERY_TIMEOUT: %w", err)
+		}
+	}
+
+	if len(maxLatencyEnv) > 0 {
+		cfg.maxLatency, err = time.ParseDuration(maxLatencyEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing MAX_LATENCY: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// splitList splits a comma separated list and drops its empty entries
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// lookup resolves a hostname against the DNS server at the supplied address.  Hostnames are resolved as fully
+// qualified names, so that the search domains of the pod do not add lookups to the measured latency.  It is a
+// variable so that tests can replace it.
+var lookup = func(ctx context.Context, address string, hostname string) error {
+	if !strings.HasSuffix(hostname, ".") {
+		hostname += "."
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+	_, err := r.LookupHost(ctx, hostname)
+
+	// the errors of the resolver name the nameserver of the pod instead of the endpoint that was queried
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return errors.New(dnsErr.Err)
+	}
+	return err
+}
+
+// queryResult is the outcome of resolving one hostname against one endpoint
+type queryResult struct {
+	endpoint dnsEndpoint
+	hostname string
+	latency  time.Duration
+	err      error
+}
+
+// queryEndpoints resolves every hostname against every endpoint, including the endpoints that are not ready, and
+// returns the outcome of each lookup
+func queryEndpoints(ctx context.Context, endpoints []dnsEndpoint, hostnames []string, timeout time.Duration) []queryResult {
+	var results []queryResult
+	for _, e := range endpoints {
+		for _, hostname := range hostnames {
+			queryCtx, cancel := context.WithTimeout(ctx, timeout)
+			start := time.Now()
+			err := lookup(queryCtx, e.address(), hostname)
+			latency := time.Since(start)
+			cancel()
+			if err == nil {
+				log.Infoln("DNS endpoint", e, "resolved", hostname, "in", latency)
+			}
+			results = append(results, queryResult{endpoint: e, hostname: hostname, latency: latency, err: err})
+		}
+	}
+	return results
+}
+
+// evaluateResults returns a problem for every lookup that failed or took longer than the maximum latency.  A
+// maximum latency of zero is never exceeded.
+func evaluateResults(results []queryResult, maxLatency time.Duration) []string {
+	var problems []string
+	for _, r := range results {
+		latency := r.latency.Round(time.Millisecond)
+		if r.err != nil {
+			problems = append(problems, fmt.Sprintf("DNS endpoint %s failed to resolve %s after %s: %s", r.endpoint, r.hostname, latency, r.err))
+			continue
+		}
+		if maxLatency > 0 && r.latency > maxLatency {
+			problems = append(problems, fmt.Sprintf("DNS endpoint %s took %s to resolve %s, which exceeds %s", r.endpoint, latency, r.hostname, maxLatency))
+		}
+	}
+	return problems
+}
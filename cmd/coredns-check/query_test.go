@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryEndpoints(t *testing.T) {
+	defer func(original func(context.Context, string, string) error) { lookup = original }(lookup)
+	var queried []string
+	lookup = func(ctx context.Context, address string, hostname string) error {
+		queried = append(queried, address+" "+hostname)
+		if address == "10.244.2.7:53" && hostname == "google.com" {
+			return errors.New("i/o timeout")
+		}
+		return nil
+	}
+
+	endpoints := []dnsEndpoint{
+		{ip: "10.244.1.5", port: 53, pod: "coredns-a", ready: true},
+		{ip: "10.244.2.7", port: 53, pod: "coredns-b", ready: true},
+	}
+	results := queryEndpoints(context.Background(), endpoints, []string{"kubernetes.default.svc.cluster.local", "google.com"}, time.Second)
+
+	expected := []string{
+		"10.244.1.5:53 kubernetes.default.svc.cluster.local",
+		"10.244.1.5:53 google.com",
+		"10.244.2.7:53 kubernetes.default.svc.cluster.local",
+		"10.244.2.7:53 google.com",
+	}
+	if !reflect.DeepEqual(queried, expected) {
+		t.Fatalf("expected every hostname to be resolved against every endpoint but got %v", queried)
+	}
+	if len(results) != 4 || results[3].err == nil || results[3].endpoint.pod != "coredns-b" {
+		t.Fatalf("expected the failed lookup to be returned but got %v", results)
+	}
+}
+
+func TestEvaluateResults(t *testing.T) {
+	a := dnsEndpoint{ip: "10.244.1.5", port: 53, pod: "coredns-a", ready: true}
+	b := dnsEndpoint{ip: "10.244.2.7", port: 53, pod: "coredns-b", ready: true}
+	results := []queryResult{
+		{endpoint: a, hostname: "kubernetes.default.svc.cluster.local", latency: time.Millisecond * 2},
+		{endpoint: a, hostname: "google.com", latency: time.Millisecond * 40},
+		{endpoint: b, hostname: "kubernetes.default.svc.cluster.local", latency: time.Millisecond * 812},
+		{endpoint: b, hostname: "google.com", latency: time.Second * 5, err: errors.New("i/o timeout")},
+	}
+
+	problems := evaluateResults(results, time.Millisecond*500)
+	expected := []string{
+		"DNS endpoint 10.244.2.7:53 (coredns-b) took 812ms to resolve kubernetes.default.svc.cluster.local, which exceeds 500ms",
+		"DNS endpoint 10.244.2.7:53 (coredns-b) failed to resolve google.com after 5s: i/o timeout",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("expected problems %v but got %v", expected, problems)
+	}
+
+	// a maximum latency of zero only reports failed lookups
+	if problems := evaluateResults(results, 0); len(problems) != 1 {
+		t.Fatalf("expected only the failed lookup to be reported but got %v", problems)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// canarySchedule is the schedule of the canary CronJob
+const canarySchedule = "* * * * *"
+
+// canaryLabel is the label that marks the canary CronJob and its jobs, with the name of the check as value
+const canaryLabel = "kuberhealthy-cronjob-canary"
+
+// buildCronJob returns the canary CronJob, which runs a job that exits right away every minute
+func buildCronJob(cfg checkConfig) *batchv1.CronJob {
+	labels := map[string]string{canaryLabel: cfg.name, "source": "kuberhealthy"}
+	historyLimit := int32(1)
+	backoffLimit := int32(0)
+	automount := false
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   canarySchedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{{
+								Name:    "canary",
+								Image:   cfg.image,
+								Command: []string{"true"},
+								Resources: v1.ResourceRequirements{
+									Requests: v1.ResourceList{
+										v1.ResourceCPU:    resource.MustParse("1m"),
+										v1.ResourceMemory: resource.MustParse("8Mi"),
+									},
+								},
+							}},
+							RestartPolicy:                 v1.RestartPolicyNever,
+							AutomountServiceAccountToken:  &automount,
+							TerminationGracePeriodSeconds: new(int64),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createCanary creates the canary CronJob and returns it as created by the apiserver
+func createCanary(ctx context.Context, client kubernetes.Interface, cfg checkConfig) (*batchv1.CronJob, error) {
+	cronJob, err := client.BatchV1().CronJobs(cfg.namespace).Create(ctx, buildCronJob(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cronjob %s: %w", cfg.name, err)
+	}
+	log.Infoln("Created cronjob", cfg.name, "in namespace", cfg.namespace, "at", cronJob.CreationTimestamp.Time)
+	return cronJob, nil
+}
+
+// deleteCanary deletes the canary CronJob along with its jobs and waits until they are gone.  A canary that does not
+// exist is skipped.
+func deleteCanary(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	propagation := metav1.DeletePropagationForeground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	err := client.BatchV1().CronJobs(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete cronjob %s: %w", cfg.name, err)
+	}
+
+	// jobs that lost their cronjob, such as when it was deleted without propagation, are deleted as well
+	jobs, err := client.BatchV1().Jobs(cfg.namespace).List(ctx, metav1.ListOptions{LabelSelector: canaryLabel + "=" + cfg.name})
+	if err != nil {
+		return fmt.Errorf("failed to list the jobs of cronjob %s: %w", cfg.name, err)
+	}
+	for _, job := range jobs.Items {
+		err := client.BatchV1().Jobs(cfg.namespace).Delete(ctx, job.Name, options)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete job %s: %w", job.Name, err)
+		}
+	}
+
+	// foreground deletion keeps the cronjob and jobs around until their dependents are gone
+	err = resourcecheck.Poll(ctx, func() (bool, error) {
+		_, err := client.BatchV1().CronJobs(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		jobs, err := client.BatchV1().Jobs(cfg.namespace).List(ctx, metav1.ListOptions{LabelSelector: canaryLabel + "=" + cfg.name})
+		if err != nil {
+			return false, err
+		}
+		return len(jobs.Items) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("cronjob %s was not deleted: %w", cfg.name, err)
+	}
+	return nil
+}
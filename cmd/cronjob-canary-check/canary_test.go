@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// canaryConfig returns the settings of a check whose canary job must be created within 30 seconds of its schedule
+// and complete within a minute
+func canaryConfig() checkConfig {
+	return checkConfig{
+		namespace:         "kuberhealthy",
+		name:              defaultResourceName,
+		image:             defaultImage,
+		scheduleTolerance: time.Second * 30,
+		completionTimeout: time.Minute,
+	}
+}
+
+func TestBuildCronJob(t *testing.T) {
+	cronJob := buildCronJob(canaryConfig())
+	if cronJob.Spec.Schedule != "* * * * *" || cronJob.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Fatalf("expected a cronjob that runs every minute without overlapping but got %+v", cronJob.Spec)
+	}
+	if cronJob.Spec.JobTemplate.Labels[canaryLabel] != defaultResourceName {
+		t.Fatalf("expected the jobs of the cronjob to be labeled but got %v", cronJob.Spec.JobTemplate.Labels)
+	}
+	if *cronJob.Spec.JobTemplate.Spec.BackoffLimit != 0 {
+		t.Fatal("expected a failed job not to be retried")
+	}
+}
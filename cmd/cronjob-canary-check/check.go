@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// runCheck creates the canary CronJob, waits for it to create its first job close to its scheduled time, and waits
+// for the job to complete.  A problem is returned for every step that failed.  The canary is not deleted.
+func runCheck(ctx context.Context, client kubernetes.Interface, cfg checkConfig) []string {
+	cronJob, err := createCanary(ctx, client, cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	// the wait is measured by the clock of the checker pod, so only the time until the schedule is taken from the apiserver
+	created := cronJob.CreationTimestamp.Time
+	expected := firstSchedule(created)
+	wait := expected.Sub(created) + cfg.scheduleTolerance
+	log.Infoln("Waiting", wait.Round(time.Second), "for cronjob", cfg.name, "to create a job scheduled at", expected.UTC().Format(time.RFC3339))
+
+	job, err := waitForJob(ctx, client, cfg, wait)
+	if err != nil {
+		return []string{fmt.Sprintf("cronjob %s did not create a job within %s of its scheduled time %s, so kube-controller-manager may not be scheduling cronjobs: %s",
+			cfg.name, cfg.scheduleTolerance, expected.UTC().Format(time.RFC3339), err)}
+	}
+
+	var problems []string
+	err = evaluateSchedule(job, scheduledTime(job, expected), cfg.scheduleTolerance)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	err = waitForCompletion(ctx, client, cfg, job.Name, cfg.completionTimeout)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// createCanaryAt makes the fake apiserver create canary CronJobs at the supplied time
+func createCanaryAt(client *fake.Clientset, created time.Time) {
+	client.PrependReactor("create", "cronjobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		cronJob := action.(k8stesting.CreateAction).GetObject().(*batchv1.CronJob)
+		cronJob.CreationTimestamp = metav1.NewTime(created)
+		return false, nil, nil
+	})
+}
+
+// TestRunCheckMissedSchedule ensures that a CronJob that never creates its job is reported as a controller that does
+// not schedule cronjobs
+func TestRunCheckMissedSchedule(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := canaryConfig()
+	client := fake.NewSimpleClientset()
+
+	// the check runs out of time long before the first schedule of the canary, as no job is ever created
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	problems := runCheck(ctx, client, cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0], "did not create a job within 30s of its scheduled time") || !strings.Contains(problems[0], "may not be scheduling cronjobs") {
+		t.Fatalf("expected the missed schedule to be reported but got %v", problems)
+	}
+}
+
+// TestRunCheckLateJob ensures that a job that was created later than the tolerance after its schedule fails the
+// check, even when the job itself completes
+func TestRunCheckLateJob(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := canaryConfig()
+
+	created := time.Now().Add(-time.Minute * 2)
+	scheduled := firstSchedule(created)
+	late := testJob(cfg.name+"-late", scheduled.Add(time.Second*45), "")
+	late.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	client := fake.NewSimpleClientset(late)
+	createCanaryAt(client, created)
+
+	problems := runCheck(context.Background(), client, cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0], "job "+late.Name+" was created 45s after its scheduled time") {
+		t.Fatalf("expected the late job to be reported but got %v", problems)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// firstSchedule returns the first time that a CronJob created at the supplied time is scheduled at, which is the start
+// of the next minute
+func firstSchedule(created time.Time) time.Time {
+	return created.Truncate(time.Minute).Add(time.Minute)
+}
+
+// scheduledTime returns the time that a job was scheduled at by the cronjob controller, or the supplied expected time
+// when the controller does not record it
+func scheduledTime(job *batchv1.Job, expected time.Time) time.Time {
+	scheduled, err := time.Parse(time.RFC3339, job.Annotations[batchv1.CronJobScheduledTimestampAnnotation])
+	if err != nil {
+		return expected
+	}
+	return scheduled
+}
+
+// evaluateSchedule returns an error when a job was created further from its scheduled time than the tolerance.  A job
+// that was created before its scheduled time by the clock of the apiserver points at clock skew between the
+// kube-controller-manager and the apiserver.
+func evaluateSchedule(job *batchv1.Job, scheduled time.Time, tolerance time.Duration) error {
+	delay := job.CreationTimestamp.Time.Sub(scheduled)
+	log.Infoln("Job", job.Name, "was created", delay, "after its scheduled time", scheduled.UTC().Format(time.RFC3339))
+	if delay < -tolerance {
+		return fmt.Errorf("job %s was created %s before its scheduled time %s, which points at clock skew between kube-controller-manager and the apiserver", job.Name, -delay, scheduled.UTC().Format(time.RFC3339))
+	}
+	if delay > tolerance {
+		return fmt.Errorf("job %s was created %s after its scheduled time %s, which exceeds the tolerance of %s", job.Name, delay, scheduled.UTC().Format(time.RFC3339), tolerance)
+	}
+	return nil
+}
+
+// jobState indicates if a job is done, and returns the reason when it failed
+func jobState(job *batchv1.Job) (bool, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, ""
+		case batchv1.JobFailed:
+			return true, condition.Reason + ": " + condition.Message
+		}
+	}
+	return false, ""
+}
+
+// waitForJob waits up to the supplied timeout for the canary CronJob to create a job, and returns the first one
+func waitForJob(ctx context.Context, client kubernetes.Interface, cfg checkConfig, timeout time.Duration) (*batchv1.Job, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var first *batchv1.Job
+	err := resourcecheck.Poll(waitCtx, func() (bool, error) {
+		jobs, err := client.BatchV1().Jobs(cfg.namespace).List(waitCtx, metav1.ListOptions{LabelSelector: canaryLabel + "=" + cfg.name})
+		if err != nil {
+			return false, err
+		}
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			if first == nil || job.CreationTimestamp.Before(&first.CreationTimestamp) {
+				first = job
+			}
+		}
+		return first != nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infoln("Cronjob", cfg.name, "created job", first.Name, "at", first.CreationTimestamp.Time)
+	return first, nil
+}
+
+// waitForCompletion waits up to the supplied timeout for a job to complete, and returns an error when it failed or
+// did not complete in time
+func waitForCompletion(ctx context.Context, client kubernetes.Interface, cfg checkConfig, name string, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var failure string
+	err := resourcecheck.Poll(waitCtx, func() (bool, error) {
+		job, err := client.BatchV1().Jobs(cfg.namespace).Get(waitCtx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		var done bool
+		done, failure = jobState(job)
+		return done, nil
+	})
+	if err != nil {
+		return fmt.Errorf("job %s did not complete within %s: %w", name, timeout, err)
+	}
+	if len(failure) > 0 {
+		return fmt.Errorf("job %s failed: %s", name, failure)
+	}
+	log.Infoln("Job", name, "completed")
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// testJob returns a canary job created at the supplied time with the supplied scheduled timestamp annotation
+func testJob(name string, created time.Time, scheduled string) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         "kuberhealthy",
+		Labels:            map[string]string{canaryLabel: defaultResourceName},
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+	if len(scheduled) > 0 {
+		job.Annotations = map[string]string{batchv1.CronJobScheduledTimestampAnnotation: scheduled}
+	}
+	return job
+}
+
+func TestFirstSchedule(t *testing.T) {
+	created := time.Date(2023, 6, 1, 12, 0, 59, 500000000, time.UTC)
+	if first := firstSchedule(created); !first.Equal(time.Date(2023, 6, 1, 12, 1, 0, 0, time.UTC)) {
+		t.Fatalf("expected the first schedule at the next minute but got %s", first)
+	}
+	created = time.Date(2023, 6, 1, 12, 1, 0, 0, time.UTC)
+	if first := firstSchedule(created); !first.Equal(time.Date(2023, 6, 1, 12, 2, 0, 0, time.UTC)) {
+		t.Fatalf("expected a cronjob created on the minute to be scheduled at the next one but got %s", first)
+	}
+}
+
+func TestEvaluateSchedule(t *testing.T) {
+	expected := time.Date(2023, 6, 1, 12, 1, 0, 0, time.UTC)
+	tolerance := time.Second * 30
+
+	// the controller records the time that it scheduled the job at, which wins over the expected time
+	job := testJob("canary-a", time.Date(2023, 6, 1, 12, 2, 4, 0, time.UTC), "2023-06-01T12:02:00Z")
+	if scheduled := scheduledTime(job, expected); !scheduled.Equal(expected.Add(time.Minute)) {
+		t.Fatalf("expected the recorded scheduled time but got %s", scheduled)
+	}
+	if err := evaluateSchedule(job, scheduledTime(job, expected), tolerance); err != nil {
+		t.Fatalf("expected a job created 4s after its schedule to pass but got: %s", err)
+	}
+
+	late := testJob("canary-b", time.Date(2023, 6, 1, 12, 1, 45, 0, time.UTC), "")
+	err := evaluateSchedule(late, scheduledTime(late, expected), tolerance)
+	if err == nil || err.Error() != "job canary-b was created 45s after its scheduled time 2023-06-01T12:01:00Z, which exceeds the tolerance of 30s" {
+		t.Fatalf("expected the late job to be reported but got: %v", err)
+	}
+
+	// a controller whose clock is two minutes ahead creates the job long before the apiserver reaches its schedule
+	skewed := testJob("canary-c", time.Date(2023, 6, 1, 12, 0, 35, 0, time.UTC), "2023-06-01T12:02:00Z")
+	err = evaluateSchedule(skewed, scheduledTime(skewed, expected), tolerance)
+	if err == nil || !strings.Contains(err.Error(), "created 1m25s before its scheduled time") || !strings.Contains(err.Error(), "clock skew") {
+		t.Fatalf("expected the clock skew to be reported but got: %v", err)
+	}
+}
+
+func TestJobState(t *testing.T) {
+	job := testJob("canary", time.Now(), "")
+	if done, _ := jobState(job); done {
+		t.Fatal("expected a job without conditions to still run")
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"}}
+	if done, failure := jobState(job); !done || failure != "BackoffLimitExceeded: Job has reached the specified backoff limit" {
+		t.Fatalf("expected the failure to be reported but got %v %q", done, failure)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	if done, failure := jobState(job); !done || len(failure) > 0 {
+		t.Fatalf("expected the job to be complete but got %v %q", done, failure)
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := canaryConfig()
+
+	client := fake.NewSimpleClientset()
+	_, err := waitForJob(context.Background(), client, cfg, time.Millisecond*20)
+	if err == nil {
+		t.Fatal("expected a cronjob without jobs to time out")
+	}
+
+	first := testJob("canary-28150001", time.Date(2023, 6, 1, 12, 1, 2, 0, time.UTC), "")
+	second := testJob("canary-28150002", time.Date(2023, 6, 1, 12, 2, 2, 0, time.UTC), "")
+	client = fake.NewSimpleClientset(second, first)
+	job, err := waitForJob(context.Background(), client, cfg, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Name != first.Name {
+		t.Fatalf("expected the first job but got %s", job.Name)
+	}
+}
+
+func TestWaitForCompletion(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := canaryConfig()
+
+	running := testJob("canary-running", time.Now(), "")
+	failed := testJob("canary-failed", time.Now(), "")
+	failed.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "DeadlineExceeded", Message: "Job was active longer than specified deadline"}}
+	complete := testJob("canary-complete", time.Now(), "")
+	complete.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+	client := fake.NewSimpleClientset(running, failed, complete)
+
+	err := waitForCompletion(context.Background(), client, cfg, running.Name, time.Millisecond*20)
+	if err == nil || !strings.HasPrefix(err.Error(), "job canary-running did not complete within 20ms") {
+		t.Fatalf("expected the running job to time out but got: %v", err)
+	}
+	err = waitForCompletion(context.Background(), client, cfg, failed.Name, time.Second)
+	if err == nil || err.Error() != "job canary-failed failed: DeadlineExceeded: Job was active longer than specified deadline" {
+		t.Fatalf("expected the failed job to be reported but got: %v", err)
+	}
+	err = waitForCompletion(context.Background(), client, cfg, complete.Name, time.Second)
+	if err != nil {
+		t.Fatalf("expected the job to complete but got: %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package main implements a CronJob canary check for Kuberhealthy.  It creates a throwaway CronJob that runs every
+// minute and fails when its first Job is not created close to its scheduled time, or does not complete in time.  This
+// catches a kube-controller-manager that stopped scheduling CronJobs, and clock skew between it and the apiserver,
+// which otherwise silently stop every CronJob of the cluster.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultResourceName is the name of the canary CronJob when RESOURCE_NAME is not set
+	defaultResourceName = "cronjob-canary"
+
+	// defaultImage is the image of the canary job when CHECK_IMAGE is not set.  It must provide the true command.
+	defaultImage = "busybox:1.36"
+
+	// defaultScheduleTolerance is how far the creation of a job may be off its scheduled time when
+	// SCHEDULE_TOLERANCE is not set
+	defaultScheduleTolerance = time.Second * 30
+
+	// defaultCompletionTimeout is how long a job may take to complete when COMPLETION_TIMEOUT is not set
+	defaultCompletionTimeout = time.Minute * 2
+
+	// cleanUpTimeout is how long deleting the canary may take, even after the deadline of the check
+	cleanUpTimeout = time.Second * 30
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile       = os.Getenv("KUBECONFIG")
+	checkNamespace       = os.Getenv("CHECK_NAMESPACE")
+	resourceName         = os.Getenv("RESOURCE_NAME")
+	checkImage           = os.Getenv("CHECK_IMAGE")
+	scheduleToleranceEnv = os.Getenv("SCHEDULE_TOLERANCE")
+	completionTimeoutEnv = os.Getenv("COMPLETION_TIMEOUT")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace         string
+	name              string
+	image             string
+	scheduleTolerance time.Duration
+	completionTimeout time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "canary",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: cleanUpTimeout,
+		Run: func(ctx context.Context) []string {
+			return runCheck(ctx, client, cfg)
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deleteCanary(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:         os.Getenv("KH_POD_NAMESPACE"),
+		name:              defaultResourceName,
+		image:             defaultImage,
+		scheduleTolerance: defaultScheduleTolerance,
+		completionTimeout: defaultCompletionTimeout,
+	}
+	var err error
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+	if len(resourceName) > 0 {
+		cfg.name = resourceName
+	}
+	if len(checkImage) > 0 {
+		cfg.image = checkImage
+	}
+
+	if len(scheduleToleranceEnv) > 0 {
+		cfg.scheduleTolerance, err = time.ParseDuration(scheduleToleranceEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing SCHEDULE_TOLERANCE: %w", err)
+		}
+	}
+	if len(completionTimeoutEnv) > 0 {
+		cfg.completionTimeout, err = time.ParseDuration(completionTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing COMPLETION_TIMEOUT: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
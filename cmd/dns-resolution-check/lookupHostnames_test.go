@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupHostnames(t *testing.T) {
+	// a resolver that can never reach its server fails every lookup
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("unreachable")
+		},
+	}
+
+	errorMessages := lookupHostnames("DNS endpoint 10.0.0.10", r, []string{"kubernetes.default.svc.cluster.local", "example.com"})
+	if len(errorMessages) != 2 {
+		t.Fatalf("expected an error for each hostname but got %v", errorMessages)
+	}
+	for _, msg := range errorMessages {
+		if !strings.HasPrefix(msg, "DNS endpoint 10.0.0.10: ") {
+			t.Errorf("expected error to name the resolver that failed: %s", msg)
+		}
+	}
+}
+
+func TestParseHostnames(t *testing.T) {
+	hostnames := parseHostnames("kubernetes.default", "google.com, kubernetes.default,,kuberhealthy.kuberhealthy.svc")
+	expected := []string{"kubernetes.default", "google.com", "kuberhealthy.kuberhealthy.svc"}
+	if !reflect.DeepEqual(hostnames, expected) {
+		t.Fatalf("expected %v but got %v", expected, hostnames)
+	}
+
+	if len(parseHostnames("", "")) != 0 {
+		t.Fatalf("expected no hostnames")
+	}
+}
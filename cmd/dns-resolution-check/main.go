@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -35,6 +36,9 @@ var CheckTimeout time.Duration
 // Hostname is a variable for container/pod name
 var Hostname string
 
+// Hostnames is the list of cluster internal and external hostnames to resolve
+var Hostnames []string
+
 // NodeName is a variable for the node where the container/pod is created
 var NodeName string
 
@@ -51,6 +55,7 @@ type Checker struct {
 	client           *kubernetes.Clientset
 	MaxTimeInFailure time.Duration
 	Hostname         string
+	Hostnames        []string
 }
 
 func init() {
@@ -66,10 +71,12 @@ func init() {
 	log.Infoln("Check time limit set to:", CheckTimeout)
 
 	Hostname = os.Getenv("HOSTNAME")
-	if len(Hostname) == 0 {
-		log.Errorln("ERROR: The ENDPOINT environment variable has not been set.")
+	Hostnames = parseHostnames(Hostname, os.Getenv("HOSTNAMES"))
+	if len(Hostnames) == 0 {
+		log.Errorln("ERROR: The HOSTNAME or HOSTNAMES environment variable has not been set.")
 		return
 	}
+	log.Infoln("Resolving hostnames:", Hostnames)
 
 	NodeName = os.Getenv("NODE_NAME")
 	if len(NodeName) == 0 {
@@ -120,29 +127,45 @@ func main() {
 
 	err = dc.Run(client)
 	if err != nil {
-		log.Errorln("Error running DNS Status check for hostname:", Hostname)
+		log.Errorln("Error running DNS Status check for hostnames:", Hostnames)
 	}
-	log.Infoln("Done running DNS Status check for hostname:", Hostname)
+	log.Infoln("Done running DNS Status check for hostnames:", Hostnames)
 }
 
 // New returns a new DNS Checker
 func New() *Checker {
 	return &Checker{
 		Hostname:         Hostname,
+		Hostnames:        Hostnames,
 		MaxTimeInFailure: maxTimeInFailure,
 	}
 }
 
+// parseHostnames combines the single hostname and the comma separated list of hostnames to resolve into one list
+// without duplicates
+func parseHostnames(hostname string, hostnames string) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, h := range append([]string{hostname}, strings.Split(hostnames, ",")...) {
+		h = strings.TrimSpace(h)
+		if len(h) == 0 || seen[h] {
+			continue
+		}
+		seen[h] = true
+		list = append(list, h)
+	}
+	return list
+}
+
 // Run implements the entrypoint for check execution
 func (dc *Checker) Run(client *kubernetes.Clientset) error {
 	log.Infoln("Running DNS status checker")
-	doneChan := make(chan error)
+	doneChan := make(chan []string)
 
 	dc.client = client
 	// run the check in a goroutine and notify the doneChan when completed
-	go func(doneChan chan error) {
-		err := dc.doChecks()
-		doneChan <- err
+	go func(doneChan chan []string) {
+		doneChan <- dc.doChecks()
 	}(doneChan)
 
 	// wait for either a timeout or job completion
@@ -156,9 +179,9 @@ func (dc *Checker) Run(client *kubernetes.Clientset) error {
 			return err
 		}
 		return err
-	case err := <-doneChan:
-		if err != nil {
-			return reportKHFailure(err.Error())
+	case errorMessages := <-doneChan:
+		if len(errorMessages) > 0 {
+			return reportKHFailure(errorMessages)
 		}
 		return reportKHSuccess()
 	}
@@ -213,63 +236,65 @@ func dnsLookup(r *net.Resolver, host string) error {
 	return nil
 }
 
-func (dc *Checker) checkEndpoints() error {
+// checkEndpoints resolves every hostname against each DNS endpoint selected by the label selector individually, so
+// that a single broken DNS pod is noticed even if the service in front of it hides it most of the time.  An error is
+// returned for every hostname that an endpoint fails to resolve.
+func (dc *Checker) checkEndpoints() []string {
 	endpoints, err := dc.client.CoreV1().Endpoints(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
 		message := "DNS status check unable to get dns endpoints from cluster: " + err.Error()
 		log.Errorln(message)
-		return errors.New(message)
+		return []string{message}
 	}
 
 	//get ips from endpoint list to check
 	ips, err := getIpsFromEndpoint(endpoints)
 	if err != nil {
-		return err
+		return []string{err.Error() + " with label: " + labelSelector}
 	}
 
-	//given that we got valid ips from endpoint list, parse them
-	if len(ips) > 0 {
-		for ip := 0; ip < len(ips); ip++ {
-			//create a resolver for each ip and return any error
-			r, err := createResolver(ips[ip])
-			if err != nil {
-				return err
-			}
-			//run a lookup for each ip if we successfully created a resolver, return error
-			err = dnsLookup(r, dc.Hostname)
-			if err != nil {
-				return err
-			}
+	var errorMessages []string
+	for _, ip := range ips {
+		//create a resolver for each ip and collect the errors of each lookup
+		r, err := createResolver(ip)
+		if err != nil {
+			errorMessages = append(errorMessages, err.Error())
+			continue
 		}
-		log.Infoln("DNS Status check from service endpoint determined that", dc.Hostname, "was OK.")
-		return nil
+		errorMessages = append(errorMessages, lookupHostnames("DNS endpoint "+ip, r, dc.Hostnames)...)
 	}
-	return errors.New("No ips found in endpoint with label: " + labelSelector)
+	return errorMessages
 }
 
-// doChecks does validations on the DNS call to the endpoint
-func (dc *Checker) doChecks() error {
-
-	log.Infoln("DNS Status check testing hostname:", dc.Hostname)
-
-	// if there's a label selector, do checks against endpoints
-	if len(labelSelector) > 0 {
-		err := dc.checkEndpoints()
+// lookupHostnames resolves every hostname with the supplied resolver.  Errors are prefixed with the name of the
+// resolver so that the resolvers that failed can be told apart.
+func lookupHostnames(resolverName string, r *net.Resolver, hostnames []string) []string {
+	var errorMessages []string
+	for _, host := range hostnames {
+		err := dnsLookup(r, host)
 		if err != nil {
-			return err
+			log.Errorln(resolverName+":", err)
+			errorMessages = append(errorMessages, resolverName+": "+err.Error())
+			continue
 		}
-		return nil
+		log.Infoln("DNS Status check with", resolverName, "determined that", host, "was OK.")
 	}
+	return errorMessages
+}
 
-	// otherwise do lookup against service endpoint
-	_, err := net.LookupHost(dc.Hostname)
-	if err != nil {
-		errorMessage := "DNS Status check determined that " + dc.Hostname + " is DOWN: " + err.Error()
-		log.Errorln(errorMessage)
-		return errors.New(errorMessage)
+// doChecks resolves every hostname with the resolver of the pod and, if a label selector is set, against each DNS
+// endpoint individually.  An error is returned for every failed lookup.
+func (dc *Checker) doChecks() []string {
+
+	log.Infoln("DNS Status check testing hostnames:", dc.Hostnames)
+
+	errorMessages := lookupHostnames("pod resolver", net.DefaultResolver, dc.Hostnames)
+
+	// if there's a label selector, also do checks against endpoints
+	if len(labelSelector) > 0 {
+		errorMessages = append(errorMessages, dc.checkEndpoints()...)
 	}
-	log.Infoln("DNS Status check from service endpoint determined that", dc.Hostname, "was OK.")
-	return nil
+	return errorMessages
 }
 
 // reportKHSuccess reports success to Kuberhealthy servers and verifies the report successfully went through
@@ -284,8 +309,8 @@ func reportKHSuccess() error {
 }
 
 // reportKHFailure reports failure to Kuberhealthy servers and verifies the report successfully went through
-func reportKHFailure(errorMessage string) error {
-	err := checkclient.ReportFailure([]string{errorMessage})
+func reportKHFailure(errorMessages []string) error {
+	err := checkclient.ReportFailure(errorMessages)
 	if err != nil {
 		log.Println("Error reporting failure to Kuberhealthy servers:", err)
 		return err
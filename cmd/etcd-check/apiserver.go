@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+)
+
+// componentCheck is the result of one of the component checks that a verbose apiserver health endpoint lists
+type componentCheck struct {
+	name    string
+	healthy bool
+	reason  string
+}
+
+// queryHealthEndpoint queries a verbose health endpoint of the apiserver, such as readyz, and returns its component
+// checks.  The apiserver responds with an error status when a component check fails, so the response is parsed
+// regardless of its status and only an error is returned when it lists no component checks at all.
+func queryHealthEndpoint(ctx context.Context, client rest.Interface, endpoint string) ([]componentCheck, error) {
+	body, err := client.Get().AbsPath("/"+endpoint).Param("verbose", "true").Do(ctx).Raw()
+	checks := parseVerboseHealth(body)
+	if len(checks) > 0 {
+		log.Infoln("/"+endpoint, "listed", len(checks), "component checks")
+		return checks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query /%s of the apiserver: %w", endpoint, err)
+	}
+	return nil, fmt.Errorf("/%s of the apiserver listed no component checks", endpoint)
+}
+
+// parseVerboseHealth parses the component checks from the response of a verbose health endpoint, which lists one
+// check per line, such as:
+//
+//	[+]ping ok
+//	[-]etcd failed: reason withheld
+func parseVerboseHealth(body []byte) []componentCheck {
+	var checks []componentCheck
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var check componentCheck
+		switch {
+		case strings.HasPrefix(line, "[+]"):
+			check.healthy = true
+		case strings.HasPrefix(line, "[-]"):
+		default:
+			continue
+		}
+		name, status, _ := strings.Cut(line[3:], " ")
+		check.name = name
+		if !check.healthy {
+			check.reason = strings.TrimSpace(strings.TrimPrefix(status, "failed:"))
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// degradedComponents returns a problem for every failed component check of an endpoint whose name starts with one
+// of the supplied prefixes.  A problem is also returned when no component check matches, so that a typo in the
+// prefixes or an apiserver that stopped checking etcd does not pass silently.
+func degradedComponents(endpoint string, checks []componentCheck, prefixes []string) []string {
+	var problems []string
+	var matched bool
+	for _, check := range checks {
+		if !hasAnyPrefix(check.name, prefixes) {
+			continue
+		}
+		matched = true
+		if check.healthy {
+			log.Infoln("/"+endpoint, "component check", check.name, "is ok")
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("/%s component check %s failed: %s", endpoint, check.name, check.reason))
+	}
+	if !matched {
+		problems = append(problems, fmt.Sprintf("/%s of the apiserver has no component checks starting with %s", endpoint, strings.Join(prefixes, " or ")))
+	}
+	return problems
+}
+
+// hasAnyPrefix indicates if the supplied name starts with one of the supplied prefixes
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+// readyzFailed is the response of a verbose readyz endpoint of an apiserver that lost etcd
+const readyzFailed = `[+]ping ok
+[+]log ok
+[-]etcd failed: reason withheld
+[+]etcd-readiness ok
+[+]informer-sync ok
+[-]poststarthook/start-apiextensions-controllers failed: not finished
+[+]shutdown ok
+readyz check failed
+`
+
+func TestParseVerboseHealth(t *testing.T) {
+	checks := parseVerboseHealth([]byte(readyzFailed))
+	if len(checks) != 7 {
+		t.Fatalf("expected seven component checks but got %v", checks)
+	}
+	if checks[2].name != "etcd" || checks[2].healthy || checks[2].reason != "reason withheld" {
+		t.Fatalf("expected the etcd check to have failed but got %+v", checks[2])
+	}
+	if checks[3].name != "etcd-readiness" || !checks[3].healthy {
+		t.Fatalf("expected the etcd-readiness check to be ok but got %+v", checks[3])
+	}
+
+	if checks := parseVerboseHealth([]byte("Unauthorized")); len(checks) != 0 {
+		t.Fatalf("expected no component checks in a response that is not verbose but got %v", checks)
+	}
+}
+
+func TestDegradedComponents(t *testing.T) {
+	checks := parseVerboseHealth([]byte(readyzFailed))
+
+	// only the failed checks that match a prefix are reported
+	problems := degradedComponents("readyz", checks, []string{"etcd"})
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem but got %v", problems)
+	}
+	if problems[0] != "/readyz component check etcd failed: reason withheld" {
+		t.Fatalf("unexpected problem: %s", problems[0])
+	}
+
+	problems = degradedComponents("readyz", checks, []string{"etcd", "poststarthook/"})
+	if len(problems) != 2 {
+		t.Fatalf("expected two problems but got %v", problems)
+	}
+
+	// prefixes that match nothing fail the check instead of passing silently
+	problems = degradedComponents("livez", checks, []string{"etdc"})
+	if len(problems) != 1 || problems[0] != "/livez of the apiserver has no component checks starting with etdc" {
+		t.Fatalf("expected a problem about the missing component checks but got %v", problems)
+	}
+}
@@ -0,0 +1,168 @@
+// Package main implements an etcd check for Kuberhealthy.  It queries the verbose readyz and livez endpoints of the
+// kube-apiserver and fails when one of their etcd component checks fails.  The health endpoints of etcd members can
+// also be queried directly where they are reachable from the checker pod.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultEndpoints are the health endpoints of the apiserver that are queried when ENDPOINTS is not set
+	defaultEndpoints = "readyz,livez"
+
+	// defaultComponents are the prefixes of the component checks that are evaluated when COMPONENTS is not set
+	defaultComponents = "etcd"
+
+	// defaultRequestTimeout is how long a single request may take when REQUEST_TIMEOUT is not set
+	defaultRequestTimeout = time.Second * 10
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile    = os.Getenv("KUBECONFIG")
+	endpointsEnv      = os.Getenv("ENDPOINTS")
+	componentsEnv     = os.Getenv("COMPONENTS")
+	memberEndpointEnv = os.Getenv("MEMBER_ENDPOINTS")
+	caFile            = os.Getenv("CA_FILE")
+	certFile          = os.Getenv("CERT_FILE")
+	keyFile           = os.Getenv("KEY_FILE")
+	requestTimeoutEnv = os.Getenv("REQUEST_TIMEOUT")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	endpoints       []string
+	components      []string
+	memberEndpoints []string
+	requestTimeout  time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	var memberClient *http.Client
+	if len(cfg.memberEndpoints) > 0 {
+		memberClient, err = newMemberClient(caFile, certFile, keyFile, cfg.requestTimeout)
+		if err != nil {
+			kh.ReportFailureAndExit(err)
+		}
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	var problems []string
+	for _, endpoint := range cfg.endpoints {
+		reqCtx, cancel := context.WithTimeout(ctx, cfg.requestTimeout)
+		checks, err := queryHealthEndpoint(reqCtx, client.Discovery().RESTClient(), endpoint)
+		cancel()
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		problems = append(problems, degradedComponents(endpoint, checks, cfg.components)...)
+	}
+	for _, endpoint := range cfg.memberEndpoints {
+		err := checkMember(ctx, memberClient, endpoint)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		endpoints:      splitList(defaultEndpoints),
+		components:     splitList(defaultComponents),
+		requestTimeout: defaultRequestTimeout,
+	}
+
+	if len(endpointsEnv) > 0 {
+		cfg.endpoints = nil
+		for _, endpoint := range splitList(endpointsEnv) {
+			cfg.endpoints = append(cfg.endpoints, strings.Trim(endpoint, "/"))
+		}
+	}
+	if len(componentsEnv) > 0 {
+		cfg.components = splitList(componentsEnv)
+	}
+	cfg.memberEndpoints = splitList(memberEndpointEnv)
+
+	if len(requestTimeoutEnv) > 0 {
+		var err error
+		cfg.requestTimeout, err = time.ParseDuration(requestTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing REQUEST_TIMEOUT: %w", err)
+		}
+	}
+
+	if len(cfg.endpoints) == 0 && len(cfg.memberEndpoints) == 0 {
+		return cfg, fmt.Errorf("ENDPOINTS or MEMBER_ENDPOINTS must list at least one endpoint to query")
+	}
+	return cfg, nil
+}
+
+// splitList splits a comma separated list and drops its empty entries
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// memberHealth is the response of the health endpoint of an etcd member
+type memberHealth struct {
+	Health string `json:"health"`
+	Reason string `json:"reason"`
+}
+
+// newMemberClient creates the http client that queries etcd members.  The CA file is trusted in addition to the
+// system roots, and the client certificate is presented to members that require one.
+func newMemberClient(caFile string, certFile string, keyFile string, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+	if len(caFile) > 0 {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA_FILE %s: %w", caFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA_FILE %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate from CERT_FILE and KEY_FILE: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// checkMember queries the health endpoint of an etcd member, such as http://10.0.0.10:2381, and returns an error
+// when the member is unreachable or reports itself unhealthy
+func checkMember(ctx context.Context, client *http.Client, endpoint string) error {
+	url := strings.TrimSuffix(endpoint, "/") + "/health"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid etcd member endpoint %s: %w", endpoint, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("etcd member %s is unreachable: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	// unhealthy members respond with an error status and the reason in the body
+	var health memberHealth
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&health)
+	if err != nil {
+		return fmt.Errorf("etcd member %s responded with status %d and an invalid health response: %w", endpoint, resp.StatusCode, err)
+	}
+	if health.Health != "true" {
+		reason := health.Reason
+		if len(reason) == 0 {
+			reason = "no reason given"
+		}
+		return fmt.Errorf("etcd member %s is unhealthy: %s", endpoint, reason)
+	}
+	log.Infoln("etcd member", endpoint, "is healthy")
+	return nil
+}
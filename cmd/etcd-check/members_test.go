@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckMember(t *testing.T) {
+	tests := map[string]struct {
+		status   int
+		body     string
+		expected string
+	}{
+		"healthy":   {http.StatusOK, `{"health":"true","reason":""}`, ""},
+		"no leader": {http.StatusServiceUnavailable, `{"health":"false","reason":"RAFT NO LEADER"}`, "is unhealthy: RAFT NO LEADER"},
+		"no reason": {http.StatusServiceUnavailable, `{"health":"false"}`, "is unhealthy: no reason given"},
+		"invalid":   {http.StatusNotFound, `404 page not found`, "responded with status 404"},
+	}
+
+	client, err := newMemberClient("", "", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/health" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+
+		err := checkMember(context.Background(), client, server.URL+"/")
+		server.Close()
+		if len(test.expected) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected the member to be healthy but got: %s", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q but got: %v", name, test.expected, err)
+		}
+	}
+}
+
+func TestCheckMemberUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client, err := newMemberClient("", "", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkMember(context.Background(), client, url)
+	if err == nil || !strings.Contains(err.Error(), "is unreachable") {
+		t.Fatalf("expected the member to be unreachable but got: %v", err)
+	}
+}
+
+func TestNewMemberClientMissingFiles(t *testing.T) {
+	_, err := newMemberClient("/nonexistent/ca.crt", "", "", time.Second)
+	if err == nil {
+		t.Fatal("expected a missing CA_FILE to fail")
+	}
+	_, err = newMemberClient("", "/nonexistent/tls.crt", "/nonexistent/tls.key", time.Second)
+	if err == nil {
+		t.Fatal("expected a missing client certificate to fail")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+// runCheck creates the Deployment and HPA, waits for the HPA to scale the Deployment up while it is under load, and
+// then waits for the HPA to scale it back down once the load has ended.  A problem is returned for the first step
+// that failed.  The resources are not torn down.
+func runCheck(ctx context.Context, client kubernetes.Interface, cfg checkConfig) []string {
+	loadUntil := time.Now().Add(cfg.scaleUpTimeout)
+	err := createResources(ctx, client, cfg, loadUntil)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	replicas, took, err := waitForReplicas(ctx, client, cfg, time.Until(loadUntil), func(r int32) bool { return r > 1 })
+	if err != nil {
+		return []string{fmt.Sprintf("hpa %s did not scale deployment %s up within %s under load: %s", cfg.name, cfg.name, cfg.scaleUpTimeout, err)}
+	}
+	log.Infoln("HPA", cfg.name, "scaled up to", replicas, "replicas after", took.Round(time.Second))
+
+	// the load ends at a fixed time, and scaling down is only timed from then on
+	log.Infoln("Waiting", time.Until(loadUntil).Round(time.Second), "for the load to end")
+	select {
+	case <-ctx.Done():
+		return []string{"the deadline of the check expired before the load ended"}
+	case <-time.After(time.Until(loadUntil)):
+	}
+
+	replicas, took, err = waitForReplicas(ctx, client, cfg, cfg.scaleDownTimeout, func(r int32) bool { return r <= 1 })
+	if err != nil {
+		return []string{fmt.Sprintf("hpa %s did not scale deployment %s back down within %s after the load ended: %s", cfg.name, cfg.name, cfg.scaleDownTimeout, err)}
+	}
+	log.Infoln("HPA", cfg.name, "scaled down to", replicas, "replica after", took.Round(time.Second))
+	return nil
+}
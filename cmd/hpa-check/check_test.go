@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// reportConditions makes the HPA of the check report the supplied conditions without ever scaling the Deployment
+func reportConditions(client *fake.Clientset, cfg checkConfig, conditions []autoscalingv2.HorizontalPodAutoscalerCondition) {
+	client.PrependReactor("get", "horizontalpodautoscalers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		hpa := buildHPA(cfg)
+		hpa.Status.Conditions = conditions
+		return true, hpa, nil
+	})
+}
+
+func TestRunCheckScaleTargetNotFound(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+	cfg := hpaConfig()
+	cfg.scaleUpTimeout = time.Millisecond * 20
+
+	// the hpa can not resolve its scale target, so it has no replicas at all
+	reportConditions(client, cfg, []autoscalingv2.HorizontalPodAutoscalerCondition{
+		{Type: autoscalingv2.AbleToScale, Status: v1.ConditionFalse, Reason: "FailedGetScale", Message: `deployments/scale.apps "hpa-check" not found`},
+	})
+
+	problems := runCheck(context.Background(), client, cfg)
+	if len(problems) != 1 {
+		t.Fatalf("expected a single problem but got %v", problems)
+	}
+	expected := []string{
+		"hpa hpa-check did not scale deployment hpa-check up",
+		"0 current and 0 desired replicas",
+		`AbleToScale is False: FailedGetScale: deployments/scale.apps "hpa-check" not found`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(problems[0], e) {
+			t.Fatalf("expected the problem to contain %q but got: %s", e, problems[0])
+		}
+	}
+}
+
+func TestRunCheckMetricsUnavailable(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+	cfg := hpaConfig()
+	cfg.scaleUpTimeout = time.Millisecond * 20
+
+	// the hpa found its scale target, but metrics-server has no cpu usage for its pods
+	reportConditions(client, cfg, []autoscalingv2.HorizontalPodAutoscalerCondition{
+		{Type: autoscalingv2.AbleToScale, Status: v1.ConditionTrue, Reason: "SucceededGetScale"},
+		{Type: autoscalingv2.ScalingActive, Status: v1.ConditionFalse, Reason: "FailedGetResourceMetric", Message: "unable to get metrics for resource cpu: no metrics returned from resource metrics API"},
+	})
+
+	problems := runCheck(context.Background(), client, cfg)
+	if len(problems) != 1 {
+		t.Fatalf("expected a single problem but got %v", problems)
+	}
+	expected := []string{
+		"hpa hpa-check did not scale deployment hpa-check up",
+		"no cpu utilization",
+		"ScalingActive is False: FailedGetResourceMetric: unable to get metrics for resource cpu",
+	}
+	for _, e := range expected {
+		if !strings.Contains(problems[0], e) {
+			t.Fatalf("expected the problem to contain %q but got: %s", e, problems[0])
+		}
+	}
+	if strings.Contains(problems[0], "AbleToScale") {
+		t.Fatalf("expected conditions that are true to be left out but got: %s", problems[0])
+	}
+}
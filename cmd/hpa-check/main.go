@@ -0,0 +1,169 @@
+// Package main implements a HorizontalPodAutoscaler check for Kuberhealthy.  It creates a throwaway Deployment that
+// burns CPU for a while and a HorizontalPodAutoscaler that scales it on CPU utilization, and fails when the
+// Deployment is not scaled up while it is under load, or not scaled back down after the load ends.  This validates the
+// whole metrics pipeline from the kubelet through metrics-server to the HPA controller.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultResourceName is the name of the Deployment and HorizontalPodAutoscaler when RESOURCE_NAME is not set
+	defaultResourceName = "hpa-check"
+
+	// defaultImage is the image of the Deployment when CHECK_IMAGE is not set.  It must provide sh and date.
+	defaultImage = "busybox:1.36"
+
+	// defaultCPURequest is the CPU request of the pods of the Deployment when CPU_REQUEST is not set
+	defaultCPURequest = "100m"
+
+	// defaultTargetCPUUtilization is the CPU utilization that the HPA aims for when TARGET_CPU_UTILIZATION is not set
+	defaultTargetCPUUtilization = 50
+
+	// defaultMaxReplicas is the number of replicas that the HPA scales up to when MAX_REPLICAS is not set
+	defaultMaxReplicas = 2
+
+	// defaultScaleUpTimeout is how long the Deployment is under load, and may take to be scaled up, when
+	// SCALE_UP_TIMEOUT is not set
+	defaultScaleUpTimeout = time.Minute * 3
+
+	// defaultScaleDownTimeout is how long the Deployment may take to be scaled down after the load ends when
+	// SCALE_DOWN_TIMEOUT is not set
+	defaultScaleDownTimeout = time.Minute * 5
+
+	// cleanUpTimeout is how long tearing down the throwaway resources may take, even after the deadline of the check
+	cleanUpTimeout = time.Second * 30
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile          = os.Getenv("KUBECONFIG")
+	checkNamespace          = os.Getenv("CHECK_NAMESPACE")
+	resourceName            = os.Getenv("RESOURCE_NAME")
+	checkImage              = os.Getenv("CHECK_IMAGE")
+	cpuRequestEnv           = os.Getenv("CPU_REQUEST")
+	targetCPUUtilizationEnv = os.Getenv("TARGET_CPU_UTILIZATION")
+	maxReplicasEnv          = os.Getenv("MAX_REPLICAS")
+	scaleUpTimeoutEnv       = os.Getenv("SCALE_UP_TIMEOUT")
+	scaleDownTimeoutEnv     = os.Getenv("SCALE_DOWN_TIMEOUT")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace            string
+	name                 string
+	image                string
+	cpuRequest           resource.Quantity
+	targetCPUUtilization int32
+	maxReplicas          int32
+	scaleUpTimeout       time.Duration
+	scaleDownTimeout     time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "resources",
+		DefaultRunTime: time.Minute * 10,
+		CleanUpTimeout: cleanUpTimeout,
+		Run: func(ctx context.Context) []string {
+			return runCheck(ctx, client, cfg)
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deleteResources(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:            os.Getenv("KH_POD_NAMESPACE"),
+		name:                 defaultResourceName,
+		image:                defaultImage,
+		cpuRequest:           resource.MustParse(defaultCPURequest),
+		targetCPUUtilization: defaultTargetCPUUtilization,
+		maxReplicas:          defaultMaxReplicas,
+		scaleUpTimeout:       defaultScaleUpTimeout,
+		scaleDownTimeout:     defaultScaleDownTimeout,
+	}
+	var err error
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+	if len(resourceName) > 0 {
+		cfg.name = resourceName
+	}
+	if len(checkImage) > 0 {
+		cfg.image = checkImage
+	}
+
+	if len(cpuRequestEnv) > 0 {
+		cfg.cpuRequest, err = resource.ParseQuantity(cpuRequestEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing CPU_REQUEST: %w", err)
+		}
+	}
+
+	if len(targetCPUUtilizationEnv) > 0 {
+		utilization, err := strconv.ParseInt(targetCPUUtilizationEnv, 10, 32)
+		if err != nil || utilization <= 0 || utilization >= 100 {
+			return cfg, fmt.Errorf("TARGET_CPU_UTILIZATION must be a percentage between 1 and 99, but was %q", targetCPUUtilizationEnv)
+		}
+		cfg.targetCPUUtilization = int32(utilization)
+	}
+
+	if len(maxReplicasEnv) > 0 {
+		replicas, err := strconv.ParseInt(maxReplicasEnv, 10, 32)
+		if err != nil || replicas < 2 {
+			return cfg, fmt.Errorf("MAX_REPLICAS must be at least 2, but was %q", maxReplicasEnv)
+		}
+		cfg.maxReplicas = int32(replicas)
+	}
+
+	if len(scaleUpTimeoutEnv) > 0 {
+		cfg.scaleUpTimeout, err = time.ParseDuration(scaleUpTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing SCALE_UP_TIMEOUT: %w", err)
+		}
+	}
+	if len(scaleDownTimeoutEnv) > 0 {
+		cfg.scaleDownTimeout, err = time.ParseDuration(scaleDownTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing SCALE_DOWN_TIMEOUT: %w", err)
+		}
+	}
+	if cfg.scaleDownTimeout <= scaleDownStabilization {
+		return cfg, fmt.Errorf("SCALE_DOWN_TIMEOUT must be longer than the scale down stabilization window of %s, but was %s", scaleDownStabilization, cfg.scaleDownTimeout)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// scaleDownStabilization is the stabilization window of the HPA for scaling down, which keeps it from scaling down
+// before the load has ended for this long.  Scaling up is not stabilized.
+const scaleDownStabilization = time.Minute
+
+// loadScript burns CPU until the unix time in LOAD_UNTIL and then idles.  The inner loop keeps the date command from
+// being run more than a few times a second.
+const loadScript = `while [ "$(date +%s)" -lt "$LOAD_UNTIL" ]; do i=0; while [ $i -lt 10000 ]; do i=$((i+1)); done; done; while true; do sleep 3600; done`
+
+// resourceLabels returns the labels of the throwaway resources, which also select the pods of the Deployment
+func resourceLabels(cfg checkConfig) map[string]string {
+	return map[string]string{
+		"app":    cfg.name,
+		"source": "kuberhealthy",
+	}
+}
+
+// buildDeployment returns the Deployment that is scaled by the HPA.  Its pods burn CPU until the supplied time, so
+// that the pods added by scaling up stop at the same time as the first one.
+func buildDeployment(cfg checkConfig, loadUntil time.Time) *appsv1.Deployment {
+	replicas := int32(1)
+	limit := cfg.cpuRequest.DeepCopy()
+	limit.Add(cfg.cpuRequest)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    resourceLabels(cfg),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: resourceLabels(cfg)},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: resourceLabels(cfg)},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name:    "load",
+						Image:   cfg.image,
+						Command: []string{"sh", "-c", loadScript},
+						Env:     []v1.EnvVar{{Name: "LOAD_UNTIL", Value: strconv.FormatInt(loadUntil.Unix(), 10)}},
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    cfg.cpuRequest,
+								v1.ResourceMemory: resource.MustParse("8Mi"),
+							},
+							Limits: v1.ResourceList{
+								v1.ResourceCPU: limit,
+							},
+						},
+					}},
+					AutomountServiceAccountToken:  new(bool),
+					TerminationGracePeriodSeconds: new(int64),
+				},
+			},
+		},
+	}
+}
+
+// buildHPA returns the HorizontalPodAutoscaler that scales the Deployment on its CPU utilization
+func buildHPA(cfg checkConfig) *autoscalingv2.HorizontalPodAutoscaler {
+	minReplicas := int32(1)
+	utilization := cfg.targetCPUUtilization
+	scaleUpWindow := int32(0)
+	scaleDownWindow := int32(scaleDownStabilization.Seconds())
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    resourceLabels(cfg),
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       cfg.name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: cfg.maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: v1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: &utilization,
+					},
+				},
+			}},
+			Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleUp:   &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &scaleUpWindow},
+				ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &scaleDownWindow},
+			},
+		},
+	}
+}
+
+// createResources creates the Deployment and the HPA that scales it
+func createResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig, loadUntil time.Time) error {
+	_, err := client.AppsV1().Deployments(cfg.namespace).Create(ctx, buildDeployment(cfg, loadUntil), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create deployment %s: %w", cfg.name, err)
+	}
+	_, err = client.AutoscalingV2().HorizontalPodAutoscalers(cfg.namespace).Create(ctx, buildHPA(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create hpa %s: %w", cfg.name, err)
+	}
+	log.Infoln("Created deployment and hpa", cfg.name, "in namespace", cfg.namespace, "with load until", loadUntil)
+	return nil
+}
+
+// deleteResources deletes the HPA and Deployment of the check and waits until they are gone.  Resources that do not
+// exist are skipped.
+func deleteResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	propagation := metav1.DeletePropagationForeground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+
+	// the hpa goes first, so that it does not act on a deployment that is going away
+	err := client.AutoscalingV2().HorizontalPodAutoscalers(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete hpa %s: %w", cfg.name, err)
+	}
+	err = client.AppsV1().Deployments(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete deployment %s: %w", cfg.name, err)
+	}
+
+	// foreground deletion keeps the resources around until their dependents are gone
+	err = resourcecheck.Poll(ctx, func() (bool, error) {
+		_, err := client.AutoscalingV2().HorizontalPodAutoscalers(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		_, err = client.AppsV1().Deployments(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("resources %s were not deleted: %w", cfg.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// hpaConfig returns the settings of a check that waits up to a second for its HPA to scale the Deployment
+func hpaConfig() checkConfig {
+	return checkConfig{
+		namespace:            "kuberhealthy",
+		name:                 defaultResourceName,
+		image:                defaultImage,
+		cpuRequest:           resource.MustParse(defaultCPURequest),
+		targetCPUUtilization: defaultTargetCPUUtilization,
+		maxReplicas:          defaultMaxReplicas,
+		scaleUpTimeout:       time.Second,
+		scaleDownTimeout:     time.Second,
+	}
+}
+
+func TestBuildDeployment(t *testing.T) {
+	loadUntil := time.Unix(1700000000, 0)
+	deployment := buildDeployment(hpaConfig(), loadUntil)
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if len(container.Env) != 1 || container.Env[0].Value != "1700000000" {
+		t.Fatalf("expected the end of the load to be passed as a unix time but got %v", container.Env)
+	}
+	if container.Resources.Requests.Cpu().String() != "100m" || container.Resources.Limits.Cpu().String() != "200m" {
+		t.Fatalf("expected a cpu request of 100m and a limit of twice that but got %+v", container.Resources)
+	}
+	if deployment.Spec.Selector.MatchLabels["app"] != defaultResourceName {
+		t.Fatalf("expected the deployment to select its pods but got %v", deployment.Spec.Selector)
+	}
+}
+
+func TestBuildHPA(t *testing.T) {
+	hpa := buildHPA(hpaConfig())
+	if hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != defaultResourceName {
+		t.Fatalf("expected the hpa to scale the deployment but got %+v", hpa.Spec.ScaleTargetRef)
+	}
+	if *hpa.Spec.MinReplicas != 1 || hpa.Spec.MaxReplicas != 2 {
+		t.Fatalf("expected the hpa to scale between 1 and 2 replicas but got %d and %d", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization != 50 {
+		t.Fatalf("expected a target cpu utilization of 50 but got %d", *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization)
+	}
+	if *hpa.Spec.Behavior.ScaleUp.StabilizationWindowSeconds != 0 || *hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds != 60 {
+		t.Fatalf("expected scaling up to be immediate and scaling down to be stabilized but got %+v", hpa.Spec.Behavior)
+	}
+}
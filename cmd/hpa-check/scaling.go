@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// waitForReplicas waits up to the supplied timeout for the HPA to want a number of replicas that satisfies the
+// supplied condition, and returns that number and how long it took.  When the HPA does not get there in time, the
+// error describes the state of the HPA, which tells a broken metrics pipeline apart from too little load.
+func waitForReplicas(ctx context.Context, client kubernetes.Interface, cfg checkConfig, timeout time.Duration, satisfied func(int32) bool) (int32, time.Duration, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var hpa *autoscalingv2.HorizontalPodAutoscaler
+	err := resourcecheck.Poll(waitCtx, func() (bool, error) {
+		current, err := client.AutoscalingV2().HorizontalPodAutoscalers(cfg.namespace).Get(waitCtx, cfg.name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		hpa = current
+		return satisfied(hpa.Status.DesiredReplicas), nil
+	})
+	if err != nil {
+		if hpa == nil {
+			return 0, time.Since(start), err
+		}
+		return hpa.Status.DesiredReplicas, time.Since(start), fmt.Errorf("%w (%s)", err, describeHPA(hpa))
+	}
+	return hpa.Status.DesiredReplicas, time.Since(start), nil
+}
+
+// describeHPA describes the replicas and CPU utilization of an HPA, and its conditions that are not true
+func describeHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) string {
+	parts := []string{fmt.Sprintf("%d current and %d desired replicas", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)}
+	utilization, ok := cpuUtilization(hpa)
+	if ok {
+		parts = append(parts, fmt.Sprintf("cpu utilization of %d%%", utilization))
+	} else {
+		parts = append(parts, "no cpu utilization")
+	}
+	for _, condition := range hpa.Status.Conditions {
+		if condition.Status == v1.ConditionTrue {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s is %s: %s: %s", condition.Type, condition.Status, condition.Reason, condition.Message))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cpuUtilization returns the current average CPU utilization of the pods scaled by an HPA, and if it is known
+func cpuUtilization(hpa *autoscalingv2.HorizontalPodAutoscaler) (int32, bool) {
+	for _, metric := range hpa.Status.CurrentMetrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil || metric.Resource.Name != v1.ResourceCPU {
+			continue
+		}
+		if metric.Resource.Current.AverageUtilization != nil {
+			return *metric.Resource.Current.AverageUtilization, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+func TestWaitForReplicas(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+	cfg := hpaConfig()
+
+	// the hpa wants a second replica on the third poll
+	polls := 0
+	client.PrependReactor("get", "horizontalpodautoscalers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		polls++
+		hpa := buildHPA(cfg)
+		hpa.Status.CurrentReplicas = 1
+		hpa.Status.DesiredReplicas = 1
+		if polls >= 3 {
+			hpa.Status.DesiredReplicas = 2
+		}
+		return true, hpa, nil
+	})
+
+	replicas, _, err := waitForReplicas(context.Background(), client, cfg, time.Second, func(r int32) bool { return r > 1 })
+	if err != nil {
+		t.Fatalf("expected the hpa to scale up but got: %s", err)
+	}
+	if replicas != 2 || polls != 3 {
+		t.Fatalf("expected 2 replicas after 3 polls but got %d after %d", replicas, polls)
+	}
+}
+
+func TestWaitForReplicasTimeout(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+	cfg := hpaConfig()
+
+	// the hpa never gets metrics
+	client.PrependReactor("get", "horizontalpodautoscalers", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		hpa := buildHPA(cfg)
+		hpa.Status.CurrentReplicas = 1
+		hpa.Status.DesiredReplicas = 1
+		hpa.Status.Conditions = []autoscalingv2.HorizontalPodAutoscalerCondition{
+			{Type: autoscalingv2.AbleToScale, Status: v1.ConditionTrue, Reason: "SucceededGetScale"},
+			{Type: autoscalingv2.ScalingActive, Status: v1.ConditionFalse, Reason: "FailedGetResourceMetric", Message: "unable to fetch metrics"},
+		}
+		return true, hpa, nil
+	})
+
+	_, _, err := waitForReplicas(context.Background(), client, cfg, time.Millisecond*20, func(r int32) bool { return r > 1 })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out but got: %v", err)
+	}
+	expected := "1 current and 1 desired replicas, no cpu utilization, ScalingActive is False: FailedGetResourceMetric: unable to fetch metrics"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected the error to describe the hpa but got: %s", err)
+	}
+}
+
+func TestCPUUtilization(t *testing.T) {
+	utilization := int32(180)
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+		CurrentMetrics: []autoscalingv2.MetricStatus{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricStatus{
+				Name:    v1.ResourceCPU,
+				Current: autoscalingv2.MetricValueStatus{AverageUtilization: &utilization},
+			},
+		}},
+	}}
+	if u, ok := cpuUtilization(hpa); !ok || u != 180 {
+		t.Fatalf("expected a cpu utilization of 180 but got %d %v", u, ok)
+	}
+	if _, ok := cpuUtilization(&autoscalingv2.HorizontalPodAutoscaler{}); ok {
+		t.Fatal("expected no cpu utilization without metrics")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statusCodeRange is an inclusive range of status codes that pass the check
+type statusCodeRange struct {
+	min int
+	max int
+}
+
+// jsonAssertion asserts that a JSON path exists in a response body and, if a value is set, that it holds that value
+type jsonAssertion struct {
+	path     string
+	segments []pathSegment
+	value    string
+	hasValue bool
+}
+
+// pathSegment is a single step of a JSON path.  It either looks up a key of an object or an index of an array.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// checkURL requests a single URL and returns a problem for every assertion that its response fails.  Problems are
+// prefixed with the URL so that failures of different URLs can be told apart.
+func checkURL(ctx context.Context, client *http.Client, cfg checkConfig, u string) []string {
+	req, err := http.NewRequestWithContext(ctx, cfg.method, u, nil)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: failed to create request: %s", u, err)}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: request failed: %s", u, err)}
+	}
+	defer resp.Body.Close()
+
+	var problems []string
+	if !statusCodeMatches(cfg.statusCodes, resp.StatusCode) {
+		problems = append(problems, fmt.Sprintf("%s: unexpected status code %d, expected %s", u, resp.StatusCode, formatStatusCodes(cfg.statusCodes)))
+	}
+
+	if cfg.bodyRegex == nil && len(cfg.jsonAssertions) == 0 {
+		log.Infoln("Got a", resp.StatusCode, "with a", cfg.method, "to", u)
+		return problems
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return append(problems, fmt.Sprintf("%s: failed to read response body: %s", u, err))
+	}
+
+	if cfg.bodyRegex != nil && !cfg.bodyRegex.Match(body) {
+		problems = append(problems, fmt.Sprintf("%s: response body does not match regex %q", u, cfg.bodyRegex.String()))
+	}
+
+	if len(cfg.jsonAssertions) > 0 {
+		for _, p := range checkJSONAssertions(body, cfg.jsonAssertions) {
+			problems = append(problems, u+": "+p)
+		}
+	}
+
+	log.Infoln("Got a", resp.StatusCode, "with a", cfg.method, "to", u, "with", len(problems), "failed assertions")
+	return problems
+}
+
+// parseStatusCodes parses a comma separated list of status codes and inclusive ranges, such as "200,201,300-399"
+func parseStatusCodes(s string) ([]statusCodeRange, error) {
+	var ranges []statusCodeRange
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		low, high, isRange := strings.Cut(item, "-")
+		min, err := strconv.Atoi(strings.TrimSpace(low))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", item)
+		}
+		max := min
+		if isRange {
+			max, err = strconv.Atoi(strings.TrimSpace(high))
+			if err != nil || max < min {
+				return nil, fmt.Errorf("invalid status code range %q", item)
+			}
+		}
+		ranges = append(ranges, statusCodeRange{min: min, max: max})
+	}
+	if len(ranges) == 0 {
+		return nil, errors.New("no status codes specified")
+	}
+	return ranges, nil
+}
+
+// statusCodeMatches determines if a status code is in any of the supplied ranges
+func statusCodeMatches(ranges []statusCodeRange, code int) bool {
+	for _, r := range ranges {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStatusCodes formats status code ranges the way they are configured
+func formatStatusCodes(ranges []statusCodeRange) string {
+	var codes []string
+	for _, r := range ranges {
+		if r.min == r.max {
+			codes = append(codes, strconv.Itoa(r.min))
+			continue
+		}
+		codes = append(codes, strconv.Itoa(r.min)+"-"+strconv.Itoa(r.max))
+	}
+	return strings.Join(codes, ",")
+}
+
+// parseJSONAssertions parses a semicolon separated list of JSON path assertions.  Each assertion is either a path,
+// which must exist, or a path and the value it must hold separated by '=', such as "$.status=ok;$.items[0].id".
+func parseJSONAssertions(s string) ([]jsonAssertion, error) {
+	var assertions []jsonAssertion
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		path, value, hasValue := strings.Cut(item, "=")
+		path = strings.TrimSpace(path)
+		segments, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, jsonAssertion{
+			path:     path,
+			segments: segments,
+			value:    strings.TrimSpace(value),
+			hasValue: hasValue,
+		})
+	}
+	return assertions, nil
+}
+
+// parseJSONPath parses a simple JSON path of object keys and array indexes, such as "$.data.items[0].name".  The
+// leading "$" is optional.
+func parseJSONPath(path string) ([]pathSegment, error) {
+	p := strings.TrimPrefix(path, "$")
+	var segments []pathSegment
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end == -1 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			}
+			segments = append(segments, pathSegment{key: p[:end]})
+			p = p[end:]
+		case '[':
+			end := strings.Index(p, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSON path %q: missing ]", path)
+			}
+			index, err := strconv.Atoi(p[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: invalid index %q", path, p[1:end])
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			p = p[end+1:]
+		default:
+			// paths may leave out the leading dot
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("invalid JSON path %q", path)
+			}
+			p = "." + p
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid JSON path %q: no keys or indexes", path)
+	}
+	return segments, nil
+}
+
+// checkJSONAssertions decodes a JSON body and returns a problem for every assertion that it fails
+func checkJSONAssertions(body []byte, assertions []jsonAssertion) []string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return []string{"response body is not valid JSON: " + err.Error()}
+	}
+
+	var problems []string
+	for _, a := range assertions {
+		value, found := lookupJSONPath(doc, a.segments)
+		if !found {
+			problems = append(problems, fmt.Sprintf("JSON path %s not found in response body", a.path))
+			continue
+		}
+		if !a.hasValue {
+			continue
+		}
+		actual := formatJSONValue(value)
+		if actual != a.value {
+			problems = append(problems, fmt.Sprintf("JSON path %s is %q, expected %q", a.path, actual, a.value))
+		}
+	}
+	return problems
+}
+
+// lookupJSONPath follows a parsed JSON path through a decoded JSON document
+func lookupJSONPath(doc interface{}, segments []pathSegment) (interface{}, bool) {
+	current := doc
+	for _, s := range segments {
+		if s.isIndex {
+			list, ok := current.([]interface{})
+			if !ok || s.index >= len(list) {
+				return nil, false
+			}
+			current = list[s.index]
+			continue
+		}
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// formatJSONValue formats a decoded JSON value for comparing it to the value of an assertion.  Strings are compared
+// without their quotes and everything else is compared as JSON.
+func formatJSONValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	ranges, err := parseStatusCodes("200, 204,300-399")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []int{200, 204, 300, 302, 399} {
+		if !statusCodeMatches(ranges, code) {
+			t.Errorf("expected status code %d to match", code)
+		}
+	}
+	for _, code := range []int{201, 299, 400, 500} {
+		if statusCodeMatches(ranges, code) {
+			t.Errorf("expected status code %d to not match", code)
+		}
+	}
+	if formatStatusCodes(ranges) != "200,204,300-399" {
+		t.Errorf("unexpected formatted status codes %s", formatStatusCodes(ranges))
+	}
+
+	for _, s := range []string{"", "ok", "400-300", "200-"} {
+		_, err = parseStatusCodes(s)
+		if err == nil {
+			t.Errorf("expected an error parsing status codes %q", s)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		segments int
+		valid    bool
+	}{
+		{path: "$.status", segments: 1, valid: true},
+		{path: "status", segments: 1, valid: true},
+		{path: "$.data.items[0].name", segments: 4, valid: true},
+		{path: "[1]", segments: 1, valid: true},
+		{path: "$", valid: false},
+		{path: "$.items[x]", valid: false},
+		{path: "$.items[0", valid: false},
+		{path: "$..status", valid: false},
+	}
+
+	for _, test := range tests {
+		segments, err := parseJSONPath(test.path)
+		if test.valid && err != nil {
+			t.Errorf("expected path %q to be valid but got %v", test.path, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected an error parsing path %q", test.path)
+		}
+		if test.valid && len(segments) != test.segments {
+			t.Errorf("expected %d segments for path %q but got %d", test.segments, test.path, len(segments))
+		}
+	}
+}
+
+func TestCheckJSONAssertions(t *testing.T) {
+	body := []byte(`{"status":"ok","count":3,"ready":true,"items":[{"name":"a"},{"name":"b"}]}`)
+
+	assertions, err := parseJSONAssertions("$.status=ok; $.count=3; $.ready=true; $.items[1].name=b; $.items[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := checkJSONAssertions(body, assertions)
+	if len(problems) != 0 {
+		t.Fatalf("expected all assertions to pass but got %v", problems)
+	}
+
+	assertions, err = parseJSONAssertions("$.status=degraded;$.items[5];$.missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems = checkJSONAssertions(body, assertions)
+	if len(problems) != 3 {
+		t.Fatalf("expected three failed assertions but got %v", problems)
+	}
+
+	problems = checkJSONAssertions([]byte("not json"), assertions)
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem for an invalid body but got %v", problems)
+	}
+}
+
+func TestCheckURLList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	mux.HandleFunc("/degraded", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"degraded"}`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	statusCodes, _ := parseStatusCodes("200")
+	assertions, _ := parseJSONAssertions("$.status=ok")
+	cfg := checkConfig{
+		urls:            []string{server.URL + "/ok", server.URL + "/degraded", server.URL + "/redirect"},
+		method:          http.MethodGet,
+		statusCodes:     statusCodes,
+		bodyRegex:       regexp.MustCompile("status"),
+		jsonAssertions:  assertions,
+		timeout:         time.Second * 5,
+		followRedirects: true,
+	}
+
+	problems := checkURLList(context.Background(), newHTTPClient(cfg), cfg)
+	if len(problems) != 2 {
+		t.Fatalf("expected a status code and a JSON problem for the degraded URL but got %v", problems)
+	}
+	for _, p := range problems {
+		if !strings.HasPrefix(p, server.URL+"/degraded: ") {
+			t.Errorf("expected problem to be prefixed with the degraded URL: %s", p)
+		}
+	}
+
+	// without following redirects, the redirect response is asserted instead
+	cfg.followRedirects = false
+	cfg.urls = []string{server.URL + "/redirect"}
+	cfg.jsonAssertions = nil
+	cfg.bodyRegex = nil
+	problems = checkURLList(context.Background(), newHTTPClient(cfg), cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0], "302") {
+		t.Fatalf("expected a status code problem for the redirect but got %v", problems)
+	}
+}
@@ -0,0 +1,212 @@
+// Package main implements an HTTP endpoint check for Kuberhealthy.  It requests a list of URLs and asserts the status
+// code, body and JSON content of each response, reporting every failed assertion along with the URL it belongs to.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+)
+
+const (
+	// defaultRequestTimeout is the time allowed for each request when REQUEST_TIMEOUT is not set
+	defaultRequestTimeout = time.Second * 10
+
+	// defaultExpectedStatusCodes are the status codes that pass when EXPECTED_STATUS_CODES is not set
+	defaultExpectedStatusCodes = "200"
+
+	// maxBodySize is the most of each response body that is read for assertions
+	maxBodySize = 10 << 20
+)
+
+var (
+	// Environment Variables fetched from spec file
+	checkURLs           = os.Getenv("CHECK_URLS")
+	requestMethod       = os.Getenv("REQUEST_METHOD")
+	expectedStatusCodes = os.Getenv("EXPECTED_STATUS_CODES")
+	bodyRegex           = os.Getenv("BODY_REGEX")
+	jsonPathAssertions  = os.Getenv("JSON_PATH_ASSERTIONS")
+	requestTimeout      = os.Getenv("REQUEST_TIMEOUT")
+	followRedirects     = os.Getenv("FOLLOW_REDIRECTS")
+	insecureSkipVerify  = os.Getenv("INSECURE_SKIP_VERIFY")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	urls            []string
+	method          string
+	statusCodes     []statusCodeRange
+	bodyRegex       *regexp.Regexp
+	jsonAssertions  []jsonAssertion
+	timeout         time.Duration
+	followRedirects bool
+	insecure        bool
+}
+
+func init() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+}
+
+func main() {
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	problems := checkURLList(ctx, newHTTPClient(cfg), cfg)
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		method:          http.MethodGet,
+		timeout:         defaultRequestTimeout,
+		followRedirects: true,
+	}
+
+	for _, u := range strings.Split(checkURLs, ",") {
+		u = strings.TrimSpace(u)
+		if len(u) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return cfg, fmt.Errorf("URL %q does not declare a supported protocol. (http | https)", u)
+		}
+		cfg.urls = append(cfg.urls, u)
+	}
+	if len(cfg.urls) == 0 {
+		return cfg, errors.New("empty CHECK_URLS specified. Please update your CHECK_URLS environment variable")
+	}
+
+	if len(requestMethod) > 0 {
+		cfg.method = strings.ToUpper(requestMethod)
+	}
+
+	codes := expectedStatusCodes
+	if len(codes) == 0 {
+		codes = defaultExpectedStatusCodes
+	}
+	var err error
+	cfg.statusCodes, err = parseStatusCodes(codes)
+	if err != nil {
+		return cfg, fmt.Errorf("error parsing EXPECTED_STATUS_CODES: %w", err)
+	}
+
+	if len(bodyRegex) > 0 {
+		cfg.bodyRegex, err = regexp.Compile(bodyRegex)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing BODY_REGEX: %w", err)
+		}
+	}
+
+	cfg.jsonAssertions, err = parseJSONAssertions(jsonPathAssertions)
+	if err != nil {
+		return cfg, fmt.Errorf("error parsing JSON_PATH_ASSERTIONS: %w", err)
+	}
+
+	if len(requestTimeout) > 0 {
+		cfg.timeout, err = time.ParseDuration(requestTimeout)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing REQUEST_TIMEOUT: %w", err)
+		}
+	}
+
+	if len(followRedirects) > 0 {
+		cfg.followRedirects, err = strconv.ParseBool(followRedirects)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing FOLLOW_REDIRECTS: %w", err)
+		}
+	}
+
+	if len(insecureSkipVerify) > 0 {
+		cfg.insecure, err = strconv.ParseBool(insecureSkipVerify)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing INSECURE_SKIP_VERIFY: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// newHTTPClient creates the client that requests are made with
+func newHTTPClient(cfg checkConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: transport,
+	}
+	if !cfg.followRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
+
+// checkURLList checks every configured URL at the same time and returns the problems found, in the order of the URLs
+func checkURLList(ctx context.Context, client *http.Client, cfg checkConfig) []string {
+	results := make([][]string, len(cfg.urls))
+	var wg sync.WaitGroup
+	for i, u := range cfg.urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			results[i] = checkURL(ctx, client, cfg, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var problems []string
+	for _, r := range results {
+		problems = append(problems, r...)
+	}
+	return problems
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// runCheck creates the resources of the check, waits for the backend to become available and for the host of the
+// check to resolve, and then requests it through the ingress controller.  The resources are not torn down.
+func runCheck(ctx context.Context, client kubernetes.Interface, httpClient *http.Client, cfg checkConfig) error {
+	err := createResources(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+	err = waitForBackend(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+	err = waitForHost(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return waitForResponse(ctx, httpClient, cfg)
+}
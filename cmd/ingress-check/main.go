@@ -0,0 +1,196 @@
+// Package main implements an ingress check for Kuberhealthy.  It creates a throwaway Deployment, Service and Ingress,
+// resolves the host of the Ingress and requests it by that public name through the ingress controller,
+// and tears everything down again, which validates the whole north-south path into the cluster.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultResourceName is the name of the Deployment, Service and Ingress when RESOURCE_NAME is not set
+	defaultResourceName = "ingress-check"
+
+	// defaultImage is the image of the backend when CHECK_IMAGE is not set
+	defaultImage = "nginxinc/nginx-unprivileged:1.17.8"
+
+	// defaultContainerPort is the port that the backend listens on when CONTAINER_PORT is not set
+	defaultContainerPort = 8080
+
+	// defaultScheme is the scheme of the request through the ingress when SCHEME is not set
+	defaultScheme = "https"
+
+	// defaultPath is the path that is requested through the ingress when REQUEST_PATH is not set
+	defaultPath = "/"
+
+	// defaultExpectedStatus is the status of the response through the ingress that passes when EXPECTED_STATUS is
+	// not set
+	defaultExpectedStatus = http.StatusOK
+
+	// defaultRequestTimeout is how long a single request through the ingress may take when REQUEST_TIMEOUT is not set
+	defaultRequestTimeout = time.Second * 10
+
+	// cleanUpTimeout is how long tearing down the throwaway resources may take, even after the deadline of the check
+	cleanUpTimeout = time.Second * 30
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile     = os.Getenv("KUBECONFIG")
+	checkNamespace     = os.Getenv("CHECK_NAMESPACE")
+	resourceName       = os.Getenv("RESOURCE_NAME")
+	ingressHost        = os.Getenv("INGRESS_HOST")
+	ingressClass       = os.Getenv("INGRESS_CLASS")
+	tlsSecret          = os.Getenv("TLS_SECRET")
+	checkImage         = os.Getenv("CHECK_IMAGE")
+	containerPortEnv   = os.Getenv("CONTAINER_PORT")
+	schemeEnv          = os.Getenv("SCHEME")
+	requestPath        = os.Getenv("REQUEST_PATH")
+	expectedStatusEnv  = os.Getenv("EXPECTED_STATUS")
+	requestTimeoutEnv  = os.Getenv("REQUEST_TIMEOUT")
+	caFile             = os.Getenv("CA_FILE")
+	insecureSkipVerify = os.Getenv("INSECURE_SKIP_VERIFY")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace          string
+	name               string
+	host               string
+	ingressClass       string
+	tlsSecret          string
+	image              string
+	containerPort      int32
+	scheme             string
+	path               string
+	expectedStatus     int
+	requestTimeout     time.Duration
+	caFile             string
+	insecureSkipVerify bool
+}
+
+// url returns the url that is requested through the ingress
+func (cfg checkConfig) url() string {
+	return cfg.scheme + "://" + cfg.host + cfg.path
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "resources",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: cleanUpTimeout,
+		Run: func(ctx context.Context) []string {
+			err := runCheck(ctx, client, httpClient, cfg)
+			if err != nil {
+				return []string{err.Error()}
+			}
+			return nil
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deleteResources(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:      os.Getenv("KH_POD_NAMESPACE"),
+		name:           defaultResourceName,
+		host:           strings.TrimSpace(ingressHost),
+		ingressClass:   ingressClass,
+		tlsSecret:      tlsSecret,
+		image:          defaultImage,
+		containerPort:  defaultContainerPort,
+		scheme:         defaultScheme,
+		path:           defaultPath,
+		expectedStatus: defaultExpectedStatus,
+		requestTimeout: defaultRequestTimeout,
+		caFile:         caFile,
+	}
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+	if len(cfg.host) == 0 {
+		return cfg, fmt.Errorf("INGRESS_HOST must be set to a host name that resolves to the ingress controller")
+	}
+	if len(resourceName) > 0 {
+		cfg.name = resourceName
+	}
+	if len(checkImage) > 0 {
+		cfg.image = checkImage
+	}
+
+	if len(containerPortEnv) > 0 {
+		port, err := strconv.ParseInt(containerPortEnv, 10, 32)
+		if err != nil || port <= 0 || port > 65535 {
+			return cfg, fmt.Errorf("CONTAINER_PORT must be a port number, but was %q", containerPortEnv)
+		}
+		cfg.containerPort = int32(port)
+	}
+
+	if len(schemeEnv) > 0 {
+		cfg.scheme = strings.ToLower(schemeEnv)
+	}
+	if cfg.scheme != "http" && cfg.scheme != "https" {
+		return cfg, fmt.Errorf("SCHEME must be http or https, but was %q", schemeEnv)
+	}
+	if len(requestPath) > 0 {
+		cfg.path = "/" + strings.TrimPrefix(requestPath, "/")
+	}
+
+	var err error
+	if len(expectedStatusEnv) > 0 {
+		cfg.expectedStatus, err = strconv.Atoi(expectedStatusEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing EXPECTED_STATUS: %w", err)
+		}
+	}
+	if len(requestTimeoutEnv) > 0 {
+		cfg.requestTimeout, err = time.ParseDuration(requestTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing REQUEST_TIMEOUT: %w", err)
+		}
+	}
+	if len(insecureSkipVerify) > 0 {
+		cfg.insecureSkipVerify, err = strconv.ParseBool(insecureSkipVerify)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing INSECURE_SKIP_VERIFY: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// lookupHost resolves host names.  It is replaced in tests.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// newHTTPClient creates the http client that requests the host of the check through the ingress controller.  The CA
+// file is trusted in addition to the system roots.
+func newHTTPClient(cfg checkConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.insecureSkipVerify}
+	if len(cfg.caFile) > 0 {
+		caPEM, err := os.ReadFile(cfg.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA_FILE %s: %w", cfg.caFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA_FILE %s", cfg.caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// every attempt opens a new connection, so that a connection to a stale ingress controller pod is not reused
+	transport.DisableKeepAlives = true
+	return &http.Client{Transport: transport, Timeout: cfg.requestTimeout}, nil
+}
+
+// waitForHost waits until the host of the check resolves
+func waitForHost(ctx context.Context, cfg checkConfig) error {
+	var addresses []string
+	err := resourcecheck.Poll(ctx, func() (bool, error) {
+		var err error
+		addresses, err = lookupHost(ctx, cfg.host)
+		return err == nil && len(addresses) > 0, err
+	})
+	if err != nil {
+		return fmt.Errorf("ingress host %s did not resolve: %w", cfg.host, err)
+	}
+	log.Infoln("Ingress host", cfg.host, "resolves to", strings.Join(addresses, ", "))
+	return nil
+}
+
+// waitForResponse requests the url of the check until it responds with the expected status.  Ingress controllers
+// take a moment to pick up a new Ingress, and respond from their default backend until they have.
+func waitForResponse(ctx context.Context, client *http.Client, cfg checkConfig) error {
+	url := cfg.url()
+	err := resourcecheck.Poll(ctx, func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Infoln("Request to", url, "failed:", err)
+			return false, err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if resp.StatusCode != cfg.expectedStatus {
+			log.Infoln("Request to", url, "responded with status", resp.StatusCode)
+			return false, fmt.Errorf("responded with status %d instead of %d", resp.StatusCode, cfg.expectedStatus)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("request to %s through the ingress failed: %w", url, err)
+	}
+	log.Infoln("Request to", url, "responded with status", cfg.expectedStatus)
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+func TestWaitForResponse(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+
+	// the ingress controller responds from its default backend until it picked up the ingress
+	var requests int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := ingressConfig(strings.TrimPrefix(server.URL, "https://"))
+	err := waitForResponse(context.Background(), server.Client(), cfg)
+	if err != nil {
+		t.Fatalf("expected the request to succeed but got: %s", err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected three requests but got %d", requests)
+	}
+
+	// the last response is reported when the expected status never arrives
+	cfg.expectedStatus = http.StatusTeapot
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err = waitForResponse(ctx, server.Client(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "responded with status 200 instead of 418") {
+		t.Fatalf("expected the unexpected status to be reported but got: %v", err)
+	}
+}
+
+func TestWaitForHost(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	defer func() { lookupHost = net.DefaultResolver.LookupHost }()
+
+	var lookups int
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if lookups < 2 {
+			return nil, errors.New("no such host")
+		}
+		return []string{"192.0.2.10"}, nil
+	}
+	err := waitForHost(context.Background(), ingressConfig("kh-ingress.example.com"))
+	if err != nil {
+		t.Fatalf("expected the host to resolve but got: %s", err)
+	}
+
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = waitForHost(ctx, ingressConfig("kh-ingress.example.com"))
+	if err == nil || !strings.Contains(err.Error(), "ingress host kh-ingress.example.com did not resolve") || !strings.Contains(err.Error(), "no such host") {
+		t.Fatalf("expected the lookup error to be reported but got: %v", err)
+	}
+}
+
+func TestNewHTTPClientMissingCA(t *testing.T) {
+	cfg := ingressConfig("kh-ingress.example.com")
+	cfg.caFile = "/nonexistent/ca.crt"
+	_, err := newHTTPClient(cfg)
+	if err == nil {
+		t.Fatal("expected a missing CA_FILE to fail")
+	}
+}
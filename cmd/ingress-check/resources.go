@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// servicePort is the port of the Service in front of the backend
+const servicePort = 80
+
+// resourceLabels returns the labels of the throwaway resources, which also select the pods of the backend
+func resourceLabels(cfg checkConfig) map[string]string {
+	return map[string]string{
+		"app":    cfg.name,
+		"source": "kuberhealthy",
+	}
+}
+
+// buildDeployment returns the Deployment of the backend that is requested through the ingress
+func buildDeployment(cfg checkConfig) *appsv1.Deployment {
+	replicas := int32(1)
+	probe := &v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{Path: "/", Port: intstr.FromInt32(cfg.containerPort)},
+		},
+		PeriodSeconds: 2,
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    resourceLabels(cfg),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: resourceLabels(cfg)},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: resourceLabels(cfg)},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name:  "backend",
+						Image: cfg.image,
+						Ports: []v1.ContainerPort{{ContainerPort: cfg.containerPort, Protocol: v1.ProtocolTCP}},
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    resource.MustParse("10m"),
+								v1.ResourceMemory: resource.MustParse("20Mi"),
+							},
+						},
+						ReadinessProbe: probe,
+					}},
+					TerminationGracePeriodSeconds: new(int64),
+				},
+			},
+		},
+	}
+}
+
+// buildService returns the Service in front of the backend
+func buildService(cfg checkConfig) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    resourceLabels(cfg),
+		},
+		Spec: v1.ServiceSpec{
+			Selector: resourceLabels(cfg),
+			Ports: []v1.ServicePort{{
+				Port:       servicePort,
+				TargetPort: intstr.FromInt32(cfg.containerPort),
+				Protocol:   v1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// buildIngress returns the Ingress that routes the host of the check to the Service of the backend
+func buildIngress(cfg checkConfig) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    resourceLabels(cfg),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: cfg.host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: cfg.name,
+									Port: networkingv1.ServiceBackendPort{Number: servicePort},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if len(cfg.ingressClass) > 0 {
+		ingress.Spec.IngressClassName = &cfg.ingressClass
+	}
+	if len(cfg.tlsSecret) > 0 {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{cfg.host}, SecretName: cfg.tlsSecret}}
+	}
+	return ingress
+}
+
+// createResources creates the Deployment, Service and Ingress of the check
+func createResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	_, err := client.AppsV1().Deployments(cfg.namespace).Create(ctx, buildDeployment(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create deployment %s: %w", cfg.name, err)
+	}
+	_, err = client.CoreV1().Services(cfg.namespace).Create(ctx, buildService(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", cfg.name, err)
+	}
+	_, err = client.NetworkingV1().Ingresses(cfg.namespace).Create(ctx, buildIngress(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create ingress %s: %w", cfg.name, err)
+	}
+	log.Infoln("Created deployment, service and ingress", cfg.name, "in namespace", cfg.namespace)
+	return nil
+}
+
+// waitForBackend waits until the Deployment of the backend has an available replica
+func waitForBackend(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	err := resourcecheck.Poll(ctx, func() (bool, error) {
+		deployment, err := client.AppsV1().Deployments(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return deployment.Status.AvailableReplicas > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("deployment %s did not become available: %w", cfg.name, err)
+	}
+	log.Infoln("Deployment", cfg.name, "is available")
+	return nil
+}
+
+// deleteResources deletes the Ingress, Service and Deployment of the check and waits until they are gone.  Resources
+// that do not exist are skipped.
+func deleteResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	propagation := metav1.DeletePropagationForeground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+
+	err := client.NetworkingV1().Ingresses(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ingress %s: %w", cfg.name, err)
+	}
+	err = client.CoreV1().Services(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete service %s: %w", cfg.name, err)
+	}
+	err = client.AppsV1().Deployments(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete deployment %s: %w", cfg.name, err)
+	}
+
+	// foreground deletion keeps the resources around until their dependents are gone
+	err = resourcecheck.Poll(ctx, func() (bool, error) {
+		_, err := client.NetworkingV1().Ingresses(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		_, err = client.CoreV1().Services(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		_, err = client.AppsV1().Deployments(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("resources %s were not deleted: %w", cfg.name, err)
+	}
+	return nil
+}
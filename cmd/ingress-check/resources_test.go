@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// ingressConfig returns the settings of a check that requests the supplied host over https through the ingress
+// controller, expecting the backend to answer within a second
+func ingressConfig(host string) checkConfig {
+	return checkConfig{
+		namespace:      "kuberhealthy",
+		name:           defaultResourceName,
+		host:           host,
+		image:          defaultImage,
+		containerPort:  defaultContainerPort,
+		scheme:         "https",
+		path:           "/",
+		expectedStatus: 200,
+		requestTimeout: time.Second,
+	}
+}
+
+func TestBuildIngress(t *testing.T) {
+	cfg := ingressConfig("kh-ingress.example.com")
+	ingress := buildIngress(cfg)
+	if ingress.Spec.IngressClassName != nil || len(ingress.Spec.TLS) != 0 {
+		t.Fatalf("expected no ingress class or tls by default but got %+v", ingress.Spec)
+	}
+	rule := ingress.Spec.Rules[0]
+	if rule.Host != cfg.host || rule.HTTP.Paths[0].Backend.Service.Name != cfg.name {
+		t.Fatalf("expected the host to be routed to the service of the check but got %+v", rule)
+	}
+
+	cfg.ingressClass = "nginx"
+	cfg.tlsSecret = "kh-ingress-tls"
+	ingress = buildIngress(cfg)
+	if ingress.Spec.IngressClassName == nil || *ingress.Spec.IngressClassName != "nginx" {
+		t.Fatalf("expected the ingress class to be set but got %v", ingress.Spec.IngressClassName)
+	}
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "kh-ingress-tls" || ingress.Spec.TLS[0].Hosts[0] != cfg.host {
+		t.Fatalf("expected tls to be terminated with the secret but got %+v", ingress.Spec.TLS)
+	}
+}
+
+func TestBuildServiceSelectsDeployment(t *testing.T) {
+	cfg := ingressConfig("kh-ingress.example.com")
+	deployment := buildDeployment(cfg)
+	service := buildService(cfg)
+	for key, value := range service.Spec.Selector {
+		if deployment.Spec.Template.Labels[key] != value {
+			t.Fatalf("expected the service selector %v to select the pods %v", service.Spec.Selector, deployment.Spec.Template.Labels)
+		}
+	}
+	if service.Spec.Ports[0].TargetPort.IntVal != cfg.containerPort {
+		t.Fatalf("expected the service to target port %d but got %v", cfg.containerPort, service.Spec.Ports[0].TargetPort)
+	}
+}
+
+// TestRunCheckWithoutBackend ensures that the host is never requested while the backend is not available, and that
+// the Ingress is torn down with the rest of the resources
+func TestRunCheckWithoutBackend(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	defer func() { lookupHost = net.DefaultResolver.LookupHost }()
+	var lookups int
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"192.0.2.10"}, nil
+	}
+
+	client := fake.NewSimpleClientset()
+	cfg := ingressConfig("kh-ingress.example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := runCheck(ctx, client, http.DefaultClient, cfg)
+	if err == nil || !strings.Contains(err.Error(), "deployment ingress-check did not become available") {
+		t.Fatalf("expected the check to fail on the unavailable backend but got: %v", err)
+	}
+	if lookups != 0 {
+		t.Fatalf("expected the host not to be resolved before the backend is available but it was resolved %d times", lookups)
+	}
+	ingress, err := client.NetworkingV1().Ingresses(cfg.namespace).Get(context.Background(), cfg.name, metav1.GetOptions{})
+	if err != nil || ingress.Spec.Rules[0].Host != cfg.host {
+		t.Fatalf("expected the ingress for the host to be created but got %v: %v", ingress, err)
+	}
+
+	err = deleteResources(context.Background(), client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingresses, err := client.NetworkingV1().Ingresses(cfg.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := client.CoreV1().Services(cfg.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ingresses.Items)+len(services.Items) != 0 {
+		t.Fatalf("expected the ingress and service to be deleted but got %d ingresses and %d services", len(ingresses.Items), len(services.Items))
+	}
+}
+
+func TestWaitForBackend(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := ingressConfig("kh-ingress.example.com")
+
+	deployment := buildDeployment(cfg)
+	client := fake.NewSimpleClientset(deployment)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := waitForBackend(ctx, client, cfg)
+	if err == nil {
+		t.Fatal("expected a deployment without available replicas to time out")
+	}
+
+	deployment.Status = appsv1.DeploymentStatus{AvailableReplicas: 1}
+	client = fake.NewSimpleClientset(deployment)
+	err = waitForBackend(context.Background(), client, cfg)
+	if err != nil {
+		t.Fatalf("expected the deployment to be available but got: %s", err)
+	}
+}
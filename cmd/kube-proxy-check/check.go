@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// runCheck creates the server and its Service, waits for every replica of the server to be a ready endpoint of the
+// Service, and then requests the cluster IP of the Service from every selected node.  A problem is returned for every
+// node that failed to reach the Service.  The resources are not torn down.
+func runCheck(ctx context.Context, client kubernetes.Interface, cfg checkConfig) []string {
+	nodes, err := listNodes(ctx, client, cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	err = createResources(ctx, client, cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	ip, err := waitForServer(ctx, client, cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return runClients(ctx, client, cfg, ip, nodes)
+}
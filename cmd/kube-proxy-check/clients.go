@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// clientPodLabel is the label that marks the client pods created by the check, with the name of the check as value
+const clientPodLabel = "kuberhealthy-kube-proxy-check-client"
+
+// imageFailureReasons are the reasons of waiting containers whose image could not be pulled
+var imageFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// listNodes lists the nodes that match the node selector and selects the ones that run a client
+func listNodes(ctx context.Context, client kubernetes.Interface, cfg checkConfig) ([]string, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: cfg.nodeSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	selected := selectNodes(nodes.Items, cfg.maxNodes)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("none of the %d nodes that match node selector %q are ready and schedulable", len(nodes.Items), cfg.nodeSelector)
+	}
+	log.Infoln("Selected", len(selected), "of", len(nodes.Items), "nodes to run a client")
+	return selected, nil
+}
+
+// selectNodes selects the names of the ready and schedulable nodes that run a client, up to the supplied maximum or
+// all of them when it is zero.  Nodes are selected in the order of their names, so that runs are comparable.
+func selectNodes(nodes []v1.Node, maxNodes int) []string {
+	sorted := append([]v1.Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var selected []string
+	for _, node := range sorted {
+		if maxNodes > 0 && len(selected) >= maxNodes {
+			break
+		}
+		if !nodeReady(node) {
+			log.Infoln("Skipping node", node.Name, "as it is not ready or not schedulable")
+			continue
+		}
+		selected = append(selected, node.Name)
+	}
+	return selected
+}
+
+// nodeReady indicates if a node is ready and schedulable
+func nodeReady(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// clientPodName returns the name of the client pod on a node
+func clientPodName(cfg checkConfig, nodeName string) string {
+	name := cfg.name + "-client-" + nodeName
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return strings.TrimRight(name, ".-")
+}
+
+// clientScript returns the shell script of a client, which requests the cluster IP of the Service the configured
+// number of times and fails on the first request that fails
+func clientScript(cfg checkConfig, ip string) string {
+	url := "http://" + net.JoinHostPort(ip, strconv.Itoa(servicePort)) + "/"
+	timeout := int(math.Ceil(cfg.requestTimeout.Seconds()))
+	return fmt.Sprintf(`for i in $(seq 1 %d); do wget -q -T %d -O /dev/null %s || { echo "request $i of %d to %s failed"; exit 1; }; done`,
+		cfg.requests, timeout, url, cfg.requests, url)
+}
+
+// buildClientPod returns the client pod on a node.  The pod is bound to the node directly, bypassing the scheduler,
+// and tolerates every taint so that the rules of kube-proxy are also checked on tainted nodes.  The output of a client
+// that fails ends up in its termination message.
+func buildClientPod(cfg checkConfig, nodeName string, ip string) *v1.Pod {
+	automount := false
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clientPodName(cfg, nodeName),
+			Namespace: cfg.namespace,
+			Labels:    map[string]string{clientPodLabel: cfg.name, "source": "kuberhealthy"},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{
+				Name:                     "client",
+				Image:                    cfg.clientImage,
+				Command:                  []string{"sh", "-c", clientScript(cfg, ip)},
+				TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("10m"),
+						v1.ResourceMemory: resource.MustParse("8Mi"),
+					},
+				},
+			}},
+			RestartPolicy:                 v1.RestartPolicyNever,
+			AutomountServiceAccountToken:  &automount,
+			TerminationGracePeriodSeconds: new(int64),
+			Tolerations:                   []v1.Toleration{{Operator: v1.TolerationOpExists}},
+		},
+	}
+}
+
+// clientState indicates if a client pod is done, and returns the reason when it failed
+func clientState(pod *v1.Pod) (bool, string) {
+	switch pod.Status.Phase {
+	case v1.PodSucceeded:
+		return true, ""
+	case v1.PodFailed:
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && len(status.State.Terminated.Message) > 0 {
+				return true, strings.TrimSpace(status.State.Terminated.Message)
+			}
+		}
+		// the kubelet may reject the pod before it runs anything, such as when the node is out of pods
+		return true, "pod failed: " + pod.Status.Reason + ": " + pod.Status.Message
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		waiting := status.State.Waiting
+		if waiting != nil && imageFailureReasons[waiting.Reason] {
+			return true, "client image could not be pulled: " + waiting.Reason + ": " + waiting.Message
+		}
+	}
+	return false, ""
+}
+
+// pendingReason describes why a client pod is not done yet
+func pendingReason(pod *v1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && len(status.State.Waiting.Reason) > 0 {
+			return string(pod.Status.Phase) + ": " + status.State.Waiting.Reason
+		}
+	}
+	return string(pod.Status.Phase)
+}
+
+// runClients runs a client pod on every node and waits until they are done.  A problem is returned for every node
+// whose client failed to reach the cluster IP, or that did not finish before the context expired.
+func runClients(ctx context.Context, client kubernetes.Interface, cfg checkConfig, ip string, nodes []string) []string {
+	pods := client.CoreV1().Pods(cfg.namespace)
+	var problems []string
+
+	running := make(map[string]string) // the node of every client pod that is not done yet, by pod name
+	for _, nodeName := range nodes {
+		pod, err := pods.Create(ctx, buildClientPod(cfg, nodeName, ip), metav1.CreateOptions{})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("node %s: failed to create client pod: %s", nodeName, err))
+			continue
+		}
+		running[pod.Name] = nodeName
+	}
+
+	lastSeen := make(map[string]*v1.Pod)
+	err := resourcecheck.Poll(ctx, func() (bool, error) {
+		list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: clientPodLabel + "=" + cfg.name})
+		if err != nil {
+			return false, err
+		}
+		for i := range list.Items {
+			pod := &list.Items[i]
+			nodeName, ok := running[pod.Name]
+			if !ok {
+				continue
+			}
+			lastSeen[pod.Name] = pod
+			done, failure := clientState(pod)
+			if !done {
+				continue
+			}
+			delete(running, pod.Name)
+			if len(failure) > 0 {
+				problems = append(problems, fmt.Sprintf("node %s: failed to reach service %s at %s: %s", nodeName, cfg.name, ip, failure))
+				continue
+			}
+			log.Infoln("Client on node", nodeName, "reached service", cfg.name, "at", ip)
+		}
+		return len(running) == 0, nil
+	})
+	if err != nil {
+		log.Errorln("Stopped waiting for the clients:", err)
+	}
+
+	// the clients that are still running are reported in the order of their nodes
+	var names []string
+	for name := range running {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return running[names[i]] < running[names[j]] })
+	for _, name := range names {
+		reason := "Unknown"
+		if pod, ok := lastSeen[name]; ok {
+			reason = pendingReason(pod)
+		}
+		problems = append(problems, fmt.Sprintf("node %s: client pod did not finish before the deadline of the check (%s)", running[name], reason))
+	}
+	return problems
+}
+
+// deleteClientPods deletes every client pod created by the check
+func deleteClientPods(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	pods := client.CoreV1().Pods(cfg.namespace)
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: clientPodLabel + "=" + cfg.name})
+	if err != nil {
+		return fmt.Errorf("failed to list client pods: %w", err)
+	}
+	for _, pod := range list.Items {
+		err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: new(int64)})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete client pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// testNode returns a node with the supplied name and ready status
+func testNode(name string, ready v1.ConditionStatus) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}}},
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	cordoned := testNode("node-b", v1.ConditionTrue)
+	cordoned.Spec.Unschedulable = true
+	nodes := []v1.Node{
+		testNode("node-d", v1.ConditionTrue),
+		testNode("node-a", v1.ConditionTrue),
+		cordoned,
+		testNode("node-c", v1.ConditionFalse),
+		testNode("node-e", v1.ConditionTrue),
+	}
+
+	if selected := selectNodes(nodes, 0); !reflect.DeepEqual(selected, []string{"node-a", "node-d", "node-e"}) {
+		t.Fatalf("expected every ready and schedulable node in order but got %v", selected)
+	}
+	if selected := selectNodes(nodes, 2); !reflect.DeepEqual(selected, []string{"node-a", "node-d"}) {
+		t.Fatalf("expected the first two ready nodes but got %v", selected)
+	}
+}
+
+func TestBuildClientPod(t *testing.T) {
+	cfg := proxyConfig()
+	pod := buildClientPod(cfg, "node-a", "10.96.12.34")
+	if pod.Spec.NodeName != "node-a" || pod.Labels[clientPodLabel] != cfg.name {
+		t.Fatalf("expected the pod to be bound to the node and labeled but got %+v", pod)
+	}
+	if pod.Spec.Containers[0].TerminationMessagePolicy != v1.TerminationMessageFallbackToLogsOnError {
+		t.Fatal("expected the output of failed clients to end up in their termination message")
+	}
+
+	expected := `for i in $(seq 1 3); do wget -q -T 3 -O /dev/null http://10.96.12.34:80/ || { echo "request $i of 3 to http://10.96.12.34:80/ failed"; exit 1; }; done`
+	if script := clientScript(cfg, "10.96.12.34"); script != expected {
+		t.Fatalf("expected script %q but got %q", expected, script)
+	}
+	if script := clientScript(cfg, "fd00::1234"); !strings.Contains(script, "http://[fd00::1234]:80/") {
+		t.Fatalf("expected an IPv6 cluster IP to be bracketed but got %q", script)
+	}
+
+	if name := clientPodName(cfg, strings.Repeat("a", 300)); len(name) > 253 {
+		t.Fatalf("expected the pod name to be truncated but it has %d characters", len(name))
+	}
+}
+
+func TestClientState(t *testing.T) {
+	tests := map[string]struct {
+		status  v1.PodStatus
+		done    bool
+		failure string
+	}{
+		"running":   {v1.PodStatus{Phase: v1.PodRunning}, false, ""},
+		"succeeded": {v1.PodStatus{Phase: v1.PodSucceeded}, true, ""},
+		"failed": {v1.PodStatus{Phase: v1.PodFailed, ContainerStatuses: []v1.ContainerStatus{{
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "wget: download timed out\nrequest 1 of 3 to http://10.96.12.34:80/ failed\n"}},
+		}}}, true, "wget: download timed out\nrequest 1 of 3 to http://10.96.12.34:80/ failed"},
+		"rejected": {v1.PodStatus{Phase: v1.PodFailed, Reason: "OutOfpods", Message: "node is full"}, true, "pod failed: OutOfpods: node is full"},
+		"image": {v1.PodStatus{Phase: v1.PodPending, ContainerStatuses: []v1.ContainerStatus{{
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "429 Too Many Requests"}},
+		}}}, true, "client image could not be pulled: ImagePullBackOff: 429 Too Many Requests"},
+	}
+	for name, test := range tests {
+		done, failure := clientState(&v1.Pod{Status: test.status})
+		if done != test.done || failure != test.failure {
+			t.Errorf("%s: expected %v %q but got %v %q", name, test.done, test.failure, done, failure)
+		}
+	}
+}
+
+func TestRunClients(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+
+	// the client on node-a succeeds, the one on node-b fails and the one on node-c never starts
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*v1.Pod)
+		switch pod.Spec.NodeName {
+		case "node-a":
+			pod.Status.Phase = v1.PodSucceeded
+		case "node-b":
+			pod.Status.Phase = v1.PodFailed
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{{State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "request 1 of 3 to http://10.96.12.34:80/ failed"},
+			}}}
+		default:
+			pod.Status.Phase = v1.PodPending
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{{State: v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"},
+			}}}
+		}
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	problems := runClients(ctx, client, proxyConfig(), "10.96.12.34", []string{"node-a", "node-b", "node-c"})
+	expected := []string{
+		"node node-b: failed to reach service kube-proxy-check at 10.96.12.34: request 1 of 3 to http://10.96.12.34:80/ failed",
+		"node node-c: client pod did not finish before the deadline of the check (Pending: ContainerCreating)",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("expected problems %v but got %v", expected, problems)
+	}
+}
@@ -0,0 +1,173 @@
+// Package main implements a kube-proxy check for Kuberhealthy.  It creates a throwaway server Deployment behind a
+// ClusterIP Service, and then requests the IP of the Service from a client pod on every selected node.  A node whose
+// client fails to reach the Service points at broken iptables or IPVS rules programmed by kube-proxy on that node.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultResourceName is the name of the Deployment and Service when RESOURCE_NAME is not set
+	defaultResourceName = "kube-proxy-check"
+
+	// defaultServerImage is the image of the server when SERVER_IMAGE is not set
+	defaultServerImage = "nginxinc/nginx-unprivileged:1.17.8"
+
+	// defaultContainerPort is the port that the server listens on when CONTAINER_PORT is not set
+	defaultContainerPort = 8080
+
+	// defaultServerReplicas is the number of server pods behind the Service when SERVER_REPLICAS is not set
+	defaultServerReplicas = 1
+
+	// defaultClientImage is the image of the clients when CLIENT_IMAGE is not set.  It must provide sh, seq and wget.
+	defaultClientImage = "busybox:1.36"
+
+	// defaultRequests is the number of requests that each client makes when REQUESTS is not set
+	defaultRequests = 3
+
+	// defaultRequestTimeout is how long a single request of a client may take when REQUEST_TIMEOUT is not set
+	defaultRequestTimeout = time.Second * 5
+
+	// cleanUpTimeout is how long tearing down the throwaway resources may take, even after the deadline of the check
+	cleanUpTimeout = time.Second * 30
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile    = os.Getenv("KUBECONFIG")
+	checkNamespace    = os.Getenv("CHECK_NAMESPACE")
+	resourceName      = os.Getenv("RESOURCE_NAME")
+	serverImage       = os.Getenv("SERVER_IMAGE")
+	containerPortEnv  = os.Getenv("CONTAINER_PORT")
+	serverReplicasEnv = os.Getenv("SERVER_REPLICAS")
+	clientImage       = os.Getenv("CLIENT_IMAGE")
+	nodeSelector      = os.Getenv("NODE_SELECTOR")
+	maxNodesEnv       = os.Getenv("MAX_NODES")
+	requestsEnv       = os.Getenv("REQUESTS")
+	requestTimeoutEnv = os.Getenv("REQUEST_TIMEOUT")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace      string
+	name           string
+	serverImage    string
+	containerPort  int32
+	serverReplicas int32
+	clientImage    string
+	nodeSelector   string
+	maxNodes       int
+	requests       int
+	requestTimeout time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "resources",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: cleanUpTimeout,
+		Run: func(ctx context.Context) []string {
+			return runCheck(ctx, client, cfg)
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deleteResources(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:      os.Getenv("KH_POD_NAMESPACE"),
+		name:           defaultResourceName,
+		serverImage:    defaultServerImage,
+		containerPort:  defaultContainerPort,
+		serverReplicas: defaultServerReplicas,
+		clientImage:    defaultClientImage,
+		nodeSelector:   nodeSelector,
+		requests:       defaultRequests,
+		requestTimeout: defaultRequestTimeout,
+	}
+	var err error
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+	if len(resourceName) > 0 {
+		cfg.name = resourceName
+	}
+	if len(serverImage) > 0 {
+		cfg.serverImage = serverImage
+	}
+	if len(clientImage) > 0 {
+		cfg.clientImage = clientImage
+	}
+
+	if len(containerPortEnv) > 0 {
+		port, err := strconv.ParseInt(containerPortEnv, 10, 32)
+		if err != nil || port <= 0 || port > 65535 {
+			return cfg, fmt.Errorf("CONTAINER_PORT must be a port number, but was %q", containerPortEnv)
+		}
+		cfg.containerPort = int32(port)
+	}
+
+	if len(serverReplicasEnv) > 0 {
+		replicas, err := strconv.ParseInt(serverReplicasEnv, 10, 32)
+		if err != nil || replicas <= 0 {
+			return cfg, fmt.Errorf("SERVER_REPLICAS must be a positive number, but was %q", serverReplicasEnv)
+		}
+		cfg.serverReplicas = int32(replicas)
+	}
+
+	if len(maxNodesEnv) > 0 {
+		cfg.maxNodes, err = strconv.Atoi(maxNodesEnv)
+		if err != nil || cfg.maxNodes < 0 {
+			return cfg, fmt.Errorf("MAX_NODES must be zero or a positive number, but was %q", maxNodesEnv)
+		}
+	}
+
+	if len(requestsEnv) > 0 {
+		cfg.requests, err = strconv.Atoi(requestsEnv)
+		if err != nil || cfg.requests <= 0 {
+			return cfg, fmt.Errorf("REQUESTS must be a positive number, but was %q", requestsEnv)
+		}
+	}
+
+	if len(requestTimeoutEnv) > 0 {
+		cfg.requestTimeout, err = time.ParseDuration(requestTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing REQUEST_TIMEOUT: %w", err)
+		}
+		if cfg.requestTimeout < time.Second {
+			return cfg, fmt.Errorf("REQUEST_TIMEOUT must be at least 1s, but was %q", requestTimeoutEnv)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// servicePort is the port of the Service in front of the server
+const servicePort = 80
+
+// serverLabels returns the labels of the Deployment and Service, which also select the pods of the server
+func serverLabels(cfg checkConfig) map[string]string {
+	return map[string]string{
+		"app":    cfg.name,
+		"source": "kuberhealthy",
+	}
+}
+
+// buildDeployment returns the Deployment of the server behind the Service
+func buildDeployment(cfg checkConfig) *appsv1.Deployment {
+	replicas := cfg.serverReplicas
+	probe := &v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{Path: "/", Port: intstr.FromInt32(cfg.containerPort)},
+		},
+		PeriodSeconds: 2,
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    serverLabels(cfg),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: serverLabels(cfg)},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: serverLabels(cfg)},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name:  "server",
+						Image: cfg.serverImage,
+						Ports: []v1.ContainerPort{{ContainerPort: cfg.containerPort, Protocol: v1.ProtocolTCP}},
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    resource.MustParse("10m"),
+								v1.ResourceMemory: resource.MustParse("20Mi"),
+							},
+						},
+						ReadinessProbe: probe,
+					}},
+					TerminationGracePeriodSeconds: new(int64),
+				},
+			},
+		},
+	}
+}
+
+// buildService returns the ClusterIP Service in front of the server
+func buildService(cfg checkConfig) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cfg.name,
+			Namespace: cfg.namespace,
+			Labels:    serverLabels(cfg),
+		},
+		Spec: v1.ServiceSpec{
+			Type:     v1.ServiceTypeClusterIP,
+			Selector: serverLabels(cfg),
+			Ports: []v1.ServicePort{{
+				Port:       servicePort,
+				TargetPort: intstr.FromInt32(cfg.containerPort),
+				Protocol:   v1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// createResources creates the Deployment and Service of the server
+func createResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	_, err := client.AppsV1().Deployments(cfg.namespace).Create(ctx, buildDeployment(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create deployment %s: %w", cfg.name, err)
+	}
+	_, err = client.CoreV1().Services(cfg.namespace).Create(ctx, buildService(cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", cfg.name, err)
+	}
+	log.Infoln("Created deployment and service", cfg.name, "in namespace", cfg.namespace)
+	return nil
+}
+
+// waitForServer waits until every replica of the server is a ready endpoint of the Service, and returns the cluster
+// IP of the Service
+func waitForServer(ctx context.Context, client kubernetes.Interface, cfg checkConfig) (string, error) {
+	err := resourcecheck.Poll(ctx, func() (bool, error) {
+		endpoints, err := client.CoreV1().Endpoints(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		var ready int32
+		for _, subset := range endpoints.Subsets {
+			ready += int32(len(subset.Addresses))
+		}
+		return ready >= cfg.serverReplicas, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("service %s did not get %d ready endpoints: %w", cfg.name, cfg.serverReplicas, err)
+	}
+
+	service, err := client.CoreV1().Services(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get service %s: %w", cfg.name, err)
+	}
+	ip := service.Spec.ClusterIP
+	if len(ip) == 0 || ip == v1.ClusterIPNone {
+		return "", fmt.Errorf("service %s has no cluster IP", cfg.name)
+	}
+	log.Infoln("Service", cfg.name, "has", cfg.serverReplicas, "ready endpoints behind cluster IP", ip)
+	return ip, nil
+}
+
+// deleteResources deletes the client pods, Service and Deployment of the check and waits until they are gone.
+// Resources that do not exist are skipped.
+func deleteResources(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	err := deleteClientPods(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationForeground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	err = client.CoreV1().Services(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete service %s: %w", cfg.name, err)
+	}
+	err = client.AppsV1().Deployments(cfg.namespace).Delete(ctx, cfg.name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete deployment %s: %w", cfg.name, err)
+	}
+
+	// foreground deletion keeps the resources around until their dependents are gone
+	err = resourcecheck.Poll(ctx, func() (bool, error) {
+		pods, err := client.CoreV1().Pods(cfg.namespace).List(ctx, metav1.ListOptions{LabelSelector: clientPodLabel + "=" + cfg.name})
+		if err != nil || len(pods.Items) > 0 {
+			return false, err
+		}
+		_, err = client.CoreV1().Services(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		_, err = client.AppsV1().Deployments(cfg.namespace).Get(ctx, cfg.name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("resources %s were not deleted: %w", cfg.name, err)
+	}
+	return nil
+}
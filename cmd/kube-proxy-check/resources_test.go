@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+)
+
+// proxyConfig returns the settings of a check that puts two server replicas behind its Service and has each client
+// request the cluster IP three times
+func proxyConfig() checkConfig {
+	return checkConfig{
+		namespace:      "kuberhealthy",
+		name:           defaultResourceName,
+		serverImage:    defaultServerImage,
+		containerPort:  defaultContainerPort,
+		serverReplicas: 2,
+		clientImage:    defaultClientImage,
+		requests:       3,
+		requestTimeout: time.Millisecond * 2500,
+	}
+}
+
+func TestBuildServiceSelectsDeployment(t *testing.T) {
+	cfg := proxyConfig()
+	deployment := buildDeployment(cfg)
+	service := buildService(cfg)
+	for key, value := range service.Spec.Selector {
+		if deployment.Spec.Template.Labels[key] != value {
+			t.Fatalf("expected the service selector %v to select the pods %v", service.Spec.Selector, deployment.Spec.Template.Labels)
+		}
+	}
+	if *deployment.Spec.Replicas != 2 {
+		t.Fatalf("expected two replicas of the server but got %d", *deployment.Spec.Replicas)
+	}
+	if service.Spec.Type != v1.ServiceTypeClusterIP || service.Spec.Ports[0].TargetPort.IntVal != cfg.containerPort {
+		t.Fatalf("expected a cluster IP service that targets port %d but got %+v", cfg.containerPort, service.Spec)
+	}
+}
+
+func TestWaitForServer(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	cfg := proxyConfig()
+
+	service := buildService(cfg)
+	service.Spec.ClusterIP = "10.96.12.34"
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: cfg.name, Namespace: cfg.namespace},
+		Subsets: []v1.EndpointSubset{{
+			Addresses:         []v1.EndpointAddress{{IP: "10.244.1.5"}},
+			NotReadyAddresses: []v1.EndpointAddress{{IP: "10.244.2.7"}},
+		}},
+	}
+
+	// a replica that is not ready yet keeps the check waiting
+	client := fake.NewSimpleClientset(service, endpoints)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := waitForServer(ctx, client, cfg)
+	if err == nil {
+		t.Fatal("expected a service with a replica that is not ready to time out")
+	}
+
+	endpoints.Subsets[0].Addresses = append(endpoints.Subsets[0].Addresses, endpoints.Subsets[0].NotReadyAddresses...)
+	endpoints.Subsets[0].NotReadyAddresses = nil
+	client = fake.NewSimpleClientset(service, endpoints)
+	ip, err := waitForServer(context.Background(), client, cfg)
+	if err != nil {
+		t.Fatalf("expected the server to be ready but got: %s", err)
+	}
+	if ip != "10.96.12.34" {
+		t.Fatalf("expected the cluster IP of the service but got %s", ip)
+	}
+}
+
+// TestRunCheckWithoutEndpoints ensures that a Service whose endpoints never become ready fails the check before any
+// client is started, because kube-proxy has nothing to route the requests of the clients to
+func TestRunCheckWithoutEndpoints(t *testing.T) {
+	resourcecheck.PollInterval = time.Millisecond
+	node := testNode("node-a", v1.ConditionTrue)
+	client := fake.NewSimpleClientset(&node)
+	cfg := proxyConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	problems := runCheck(ctx, client, cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0], "service kube-proxy-check did not get 2 ready endpoints") {
+		t.Fatalf("expected the check to fail on the missing endpoints but got %v", problems)
+	}
+	if !strings.Contains(problems[0], "not found") {
+		t.Fatalf("expected the failure to say that the endpoints were not found but got %q", problems[0])
+	}
+	pods, err := client.CoreV1().Pods(cfg.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 0 {
+		t.Fatalf("expected no client pods without ready endpoints but got %d", len(pods.Items))
+	}
+}
@@ -0,0 +1,253 @@
+// Package main implements kubectl-kuberhealthy, a kubectl plugin for operating Kuberhealthy checks.
+// When installed on the PATH, it can be invoked as `kubectl kuberhealthy`.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/integrii/flaggy"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+// checkNameLabel is the label Kuberhealthy puts on checker pods with the name of their check
+const checkNameLabel = "kuberhealthy-check-name"
+
+var kubeConfigFile = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+var namespace = "kuberhealthy"
+var checkName string
+var followLogs bool
+
+func main() {
+
+	flaggy.SetName("kubectl-kuberhealthy")
+	flaggy.SetDescription("Operate Kuberhealthy checks from the command line.")
+	flaggy.String(&kubeConfigFile, "", "kubeconfig", "Path to the kubeconfig file to use.")
+	flaggy.String(&namespace, "n", "namespace", "The namespace of the checks to operate on.")
+
+	listCmd := flaggy.NewSubcommand("list")
+	listCmd.Description = "List checks along with their latest state."
+	flaggy.AttachSubcommand(listCmd, 1)
+
+	runCmd := flaggy.NewSubcommand("run")
+	runCmd.Description = "Run a check right away instead of waiting for its next run."
+	runCmd.AddPositionalValue(&checkName, "check", 1, true, "The name of the check.")
+	flaggy.AttachSubcommand(runCmd, 1)
+
+	logsCmd := flaggy.NewSubcommand("logs")
+	logsCmd.Description = "Show the logs of the most recent checker pod of a check."
+	logsCmd.AddPositionalValue(&checkName, "check", 1, true, "The name of the check.")
+	logsCmd.Bool(&followLogs, "f", "follow", "Stream the logs as they are written.")
+	flaggy.AttachSubcommand(logsCmd, 1)
+
+	pauseCmd := flaggy.NewSubcommand("pause")
+	pauseCmd.Description = "Pause a check so that it is not run until it is resumed."
+	pauseCmd.AddPositionalValue(&checkName, "check", 1, true, "The name of the check.")
+	flaggy.AttachSubcommand(pauseCmd, 1)
+
+	resumeCmd := flaggy.NewSubcommand("resume")
+	resumeCmd.Description = "Resume a paused check."
+	resumeCmd.AddPositionalValue(&checkName, "check", 1, true, "The name of the check.")
+	flaggy.AttachSubcommand(resumeCmd, 1)
+
+	flaggy.Parse()
+
+	var err error
+	switch {
+	case listCmd.Used:
+		err = listChecks(os.Stdout)
+	case runCmd.Used:
+		err = annotateCheck(checkName, khcheckv1.RunNowAnnotation, time.Now().Format(time.RFC3339))
+		if err == nil {
+			fmt.Println("Requested a run of check", checkName, "in namespace", namespace)
+		}
+	case logsCmd.Used:
+		err = checkLogs(os.Stdout, checkName, followLogs)
+	case pauseCmd.Used:
+		err = setCheckPaused(checkName, true)
+		if err == nil {
+			fmt.Println("Paused check", checkName, "in namespace", namespace)
+		}
+	case resumeCmd.Used:
+		err = setCheckPaused(checkName, false)
+		if err == nil {
+			fmt.Println("Resumed check", checkName, "in namespace", namespace)
+		}
+	default:
+		flaggy.ShowHelpAndExit("A command is required.")
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// listChecks writes a table of the checks in the namespace and their latest state to the supplied writer
+func listChecks(w io.Writer) error {
+	checkClient, err := khcheckv1.Client(kubeConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to create khcheck client: %w", err)
+	}
+	stateClient, err := khstatev1.Client(kubeConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to create khstate client: %w", err)
+	}
+
+	checks, err := checkClient.KuberhealthyChecks(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list khchecks: %w", err)
+	}
+	states, err := stateClient.KuberhealthyStates(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list khstates: %w", err)
+	}
+
+	stateByName := make(map[string]khstatev1.WorkloadDetails)
+	for _, s := range states.Items {
+		stateByName[s.Name] = s.Spec
+	}
+
+	sort.Slice(checks.Items, func(i, j int) bool {
+		return checks.Items[i].Name < checks.Items[j].Name
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tLAST RUN\tDURATION\tPAUSED\tERRORS")
+	for _, c := range checks.Items {
+		fmt.Fprintln(tw, checkRow(c, stateByName[c.Name], time.Now()))
+	}
+	return tw.Flush()
+}
+
+// checkRow formats a single check and its latest state as a tab separated table row
+func checkRow(c khcheckv1.KuberhealthyCheck, state khstatev1.WorkloadDetails, now time.Time) string {
+	status := "Unknown"
+	lastRun := "<never>"
+	if state.LastRun != nil && !state.LastRun.IsZero() {
+		status = "Failing"
+		if state.OK {
+			status = "OK"
+		}
+		lastRun = now.Sub(state.LastRun.Time).Round(time.Second).String() + " ago"
+	}
+
+	paused := "false"
+	if c.IsPaused() {
+		paused = "true"
+	}
+
+	return strings.Join([]string{c.Name, status, lastRun, state.RunDuration, paused, strings.Join(state.Errors, "; ")}, "\t")
+}
+
+// annotateCheck sets an annotation on the named khcheck.  An empty value removes the annotation.
+func annotateCheck(name string, annotation string, value string) error {
+	checkClient, err := khcheckv1.Client(kubeConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to create khcheck client: %w", err)
+	}
+
+	khCheck, err := checkClient.KuberhealthyChecks(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get khcheck %s in namespace %s: %w", name, namespace, err)
+	}
+
+	if khCheck.Annotations == nil {
+		khCheck.Annotations = make(map[string]string)
+	}
+	if len(value) == 0 {
+		delete(khCheck.Annotations, annotation)
+	} else {
+		khCheck.Annotations[annotation] = value
+	}
+
+	_, err = checkClient.KuberhealthyChecks(namespace).Update(&khCheck)
+	if err != nil {
+		return fmt.Errorf("failed to update khcheck %s in namespace %s: %w", name, namespace, err)
+	}
+	return nil
+}
+
+// setCheckPaused pauses or resumes the named check by setting the paused field of its spec.  Resuming a check also
+// removes the paused annotation, so that checks paused either way are resumed.
+func setCheckPaused(name string, paused bool) error {
+	checkClient, err := khcheckv1.Client(kubeConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to create khcheck client: %w", err)
+	}
+
+	khCheck, err := checkClient.KuberhealthyChecks(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get khcheck %s in namespace %s: %w", name, namespace, err)
+	}
+
+	khCheck.Spec.Paused = paused
+	if !paused {
+		delete(khCheck.Annotations, khcheckv1.PausedAnnotation)
+	}
+
+	_, err = checkClient.KuberhealthyChecks(namespace).Update(&khCheck)
+	if err != nil {
+		return fmt.Errorf("failed to update khcheck %s in namespace %s: %w", name, namespace, err)
+	}
+	return nil
+}
+
+// checkLogs writes the logs of the most recent checker pod of the named check to the supplied writer
+func checkLogs(w io.Writer, name string, follow bool) error {
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	ctx := context.Background()
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: checkNameLabel + "=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list checker pods: %w", err)
+	}
+
+	pod, err := newestPod(pods.Items)
+	if err != nil {
+		return fmt.Errorf("no checker pods found for check %s in namespace %s: %w", name, namespace, err)
+	}
+	fmt.Fprintln(os.Stderr, "Showing logs of checker pod", pod.Name)
+
+	stream, err := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Follow: follow}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get logs of checker pod %s: %w", pod.Name, err)
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// newestPod returns the most recently created pod from the supplied list
+func newestPod(pods []v1.Pod) (v1.Pod, error) {
+	if len(pods) == 0 {
+		return v1.Pod{}, errors.New("pod list is empty")
+	}
+
+	newest := pods[0]
+	for _, p := range pods[1:] {
+		if p.CreationTimestamp.After(newest.CreationTimestamp.Time) {
+			newest = p
+		}
+	}
+	return newest, nil
+}
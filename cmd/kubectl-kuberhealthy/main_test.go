@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestNewestPod ensures that the most recently created checker pod is picked
+func TestNewestPod(t *testing.T) {
+	now := time.Now()
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "old", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "new", CreationTimestamp: metav1.NewTime(now)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "older", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour * 2))}},
+	}
+
+	pod, err := newestPod(pods)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if pod.Name != "new" {
+		t.Fatalf("Expected the newest pod to be new but got %s", pod.Name)
+	}
+
+	_, err = newestPod(nil)
+	if err == nil {
+		t.Fatal("Expected an error when there are no pods")
+	}
+}
+
+// TestCheckRow ensures that checks are formatted with their latest state
+func TestCheckRow(t *testing.T) {
+	now := time.Now()
+	check := khcheckv1.KuberhealthyCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "dns-status",
+			Annotations: map[string]string{khcheckv1.PausedAnnotation: "true"},
+		},
+	}
+	lastRun := metav1.NewTime(now.Add(-time.Minute))
+	state := khstatev1.WorkloadDetails{
+		OK:          false,
+		Errors:      []string{"lookup failed"},
+		RunDuration: "2s",
+		LastRun:     &lastRun,
+	}
+
+	row := strings.Split(checkRow(check, state, now), "\t")
+	expected := []string{"dns-status", "Failing", "1m0s ago", "2s", "true", "lookup failed"}
+	if strings.Join(row, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected row %v but got %v", expected, row)
+	}
+
+	check = khcheckv1.KuberhealthyCheck{Spec: khcheckv1.CheckConfig{Paused: true}}
+	row = strings.Split(checkRow(check, state, now), "\t")
+	if row[4] != "true" {
+		t.Fatalf("Expected a check paused by its spec to be shown as paused but got %v", row)
+	}
+
+	row = strings.Split(checkRow(khcheckv1.KuberhealthyCheck{}, khstatev1.WorkloadDetails{}, now), "\t")
+	if row[1] != "Unknown" || row[2] != "<never>" {
+		t.Fatalf("Expected a check that never ran to have an unknown status but got %v", row)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// authorizeCheckRequest ensures that the caller of an endpoint that acts on a khcheck is allowed to do so.  The
+// bearer token of the request is authenticated with a TokenReview, and a SubjectAccessReview ensures that its user
+// may perform the supplied verb on the khcheck, so that callers need the same permissions as they would to change the
+// khcheck with kubectl.  The name of the user is returned for the audit log.  When the caller is not allowed, a
+// *reportError with the status code that the request is rejected with is returned.
+func authorizeCheckRequest(ctx context.Context, client kubernetes.Interface, r *http.Request, verb string, checkName string, checkNamespace string) (string, error) {
+	token, err := bearerToken(r.Header.Get("Authorization"))
+	if err != nil {
+		return "", &reportError{statusCode: http.StatusUnauthorized, err: errors.New("request " + err.Error())}
+	}
+
+	tokenReview := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	tokenReview, err = client.AuthenticationV1().TokenReviews().Create(ctx, tokenReview, metav1.CreateOptions{})
+	if err != nil {
+		return "", &reportError{statusCode: http.StatusServiceUnavailable, err: fmt.Errorf("failed to review request token: %w", err)}
+	}
+	if !tokenReview.Status.Authenticated {
+		return "", &reportError{statusCode: http.StatusUnauthorized, err: errors.New("request token was not authenticated")}
+	}
+	user := tokenReview.Status.User
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: checkNamespace,
+				Verb:      verb,
+				Group:     checkCRDGroup,
+				Resource:  checkCRDResource,
+				Name:      checkName,
+			},
+		},
+	}
+	accessReview, err = client.AuthorizationV1().SubjectAccessReviews().Create(ctx, accessReview, metav1.CreateOptions{})
+	if err != nil {
+		return user.Username, &reportError{statusCode: http.StatusServiceUnavailable, err: fmt.Errorf("failed to review request access: %w", err)}
+	}
+	if !accessReview.Status.Allowed {
+		return user.Username, &reportError{statusCode: http.StatusForbidden, err: fmt.Errorf("%s is not allowed to %s khcheck %s/%s", user.Username, verb, checkNamespace, checkName)}
+	}
+	return user.Username, nil
+}
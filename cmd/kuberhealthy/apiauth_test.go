@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// authClient returns a client whose API server authenticates the token "valid-token" as the user jane, who is only
+// allowed to update the khcheck kuberhealthy/dns
+func authClient() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "valid-token" {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "jane", Groups: []string{"system:authenticated"}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "jane" && attributes.Verb == "update" && attributes.Group == checkCRDGroup &&
+			attributes.Resource == checkCRDResource && attributes.Namespace == "kuberhealthy" && attributes.Name == "dns"
+		return true, review, nil
+	})
+	return client
+}
+
+// TestAuthorizeCheckRequest ensures that only authenticated callers that may update a khcheck are allowed to act on it
+func TestAuthorizeCheckRequest(t *testing.T) {
+	client := authClient()
+
+	testCases := []struct {
+		description  string
+		authHeader   string
+		checkName    string
+		expectedCode int
+	}{
+		{description: "no token", authHeader: "", checkName: "dns", expectedCode: http.StatusUnauthorized},
+		{description: "blank token", authHeader: "Bearer ", checkName: "dns", expectedCode: http.StatusUnauthorized},
+		{description: "invalid token", authHeader: "Bearer invalid-token", checkName: "dns", expectedCode: http.StatusUnauthorized},
+		{description: "not allowed", authHeader: "Bearer valid-token", checkName: "deployment", expectedCode: http.StatusForbidden},
+		{description: "allowed", authHeader: "Bearer valid-token", checkName: "dns", expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/checks/kuberhealthy/"+tc.checkName+"/pause", nil)
+		if len(tc.authHeader) > 0 {
+			req.Header.Set("Authorization", tc.authHeader)
+		}
+
+		user, err := authorizeCheckRequest(context.Background(), client, req, "update", tc.checkName, "kuberhealthy")
+		if tc.expectedCode == http.StatusOK {
+			if err != nil {
+				t.Fatalf("%s: expected the request to be allowed but got: %s", tc.description, err)
+			}
+			if user != "jane" {
+				t.Fatalf("%s: expected the request to be made by jane but got %q", tc.description, user)
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatalf("%s: expected the request to be rejected", tc.description)
+		}
+		if reportStatusCode(err) != tc.expectedCode {
+			t.Fatalf("%s: expected status code %d but got %d: %s", tc.description, tc.expectedCode, reportStatusCode(err), err)
+		}
+	}
+}
+
+// TestSetCheckPausedHandlerUnauthenticated ensures that checks can not be paused or resumed without a token
+func TestSetCheckPausedHandlerUnauthenticated(t *testing.T) {
+	kh := &Kuberhealthy{}
+	for _, paused := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/checks/kuberhealthy/dns/pause", nil)
+		req.SetPathValue("namespace", "kuberhealthy")
+		req.SetPathValue("name", "dns")
+		recorder := httptest.NewRecorder()
+
+		err := kh.setCheckPausedHandler(paused)(recorder, req)
+		if err != nil {
+			t.Fatalf("unexpected error from pause check handler: %s", err)
+		}
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("expected an unauthenticated request with paused %v to be rejected with %d but got %d", paused, http.StatusUnauthorized, recorder.Code)
+		}
+	}
+}
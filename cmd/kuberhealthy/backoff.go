@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// backoffRunInterval returns the run interval of a check that has failed the supplied number of runs in a row.  The
+// interval is multiplied for every failed run, up to the maximum interval.  Checks without a multiplier of at least
+// two do not back off.
+func backoffRunInterval(interval time.Duration, maxInterval time.Duration, multiplier int, failures int) time.Duration {
+	if multiplier < 2 || maxInterval <= interval {
+		return interval
+	}
+
+	effective := interval
+	for i := 0; i < failures; i++ {
+		if effective > maxInterval/time.Duration(multiplier) {
+			return maxInterval
+		}
+		effective *= time.Duration(multiplier)
+	}
+	return effective
+}
+
+// effectiveRunInterval returns the run interval of a check from the number of failed runs in a row recorded on its
+// khstate.  The effective run interval is recorded on the khstate whenever it changes.
+func (k *Kuberhealthy) effectiveRunInterval(c *external.Checker) time.Duration {
+	if c.RunIntervalBackoff == 0 || len(c.RunSchedule) > 0 {
+		return c.Interval()
+	}
+
+	name := sanitizeResourceName(c.Name())
+	khState, err := khStateClient.KuberhealthyStates(c.CheckNamespace()).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			log.Errorln("Error fetching khstate to back off the run interval of check", c.Name(), "in namespace", c.CheckNamespace()+":", err)
+		}
+		return c.Interval()
+	}
+
+	interval := backoffRunInterval(c.Interval(), c.MaxRunInterval, c.RunIntervalBackoff, khState.Spec.ConsecutiveFailures)
+	if khState.Spec.EffectiveRunInterval == interval.String() {
+		return interval
+	}
+
+	// the effective run interval is written without touching the last run time, so it is not mistaken for a report
+	log.Infoln("Setting effective run interval of check", c.Name(), "in namespace", c.CheckNamespace(), "to", interval, "after", khState.Spec.ConsecutiveFailures, "failed runs in a row")
+	_, err = stateWrites.write(c.CheckNamespace(), name, func(khState *khstatev1.KuberhealthyState) error {
+		khState.Spec.EffectiveRunInterval = interval.String()
+		return nil
+	})
+	if err != nil {
+		log.Errorln("Error storing effective run interval of check", c.Name(), "in namespace", c.CheckNamespace()+":", err)
+	}
+	return interval
+}
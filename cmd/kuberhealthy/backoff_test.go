@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
+)
+
+// TestBackoffRunInterval ensures that the run interval grows with each failed run in a row up to the maximum
+func TestBackoffRunInterval(t *testing.T) {
+	testCases := []struct {
+		description string
+		maxInterval time.Duration
+		multiplier  int
+		failures    int
+		expected    time.Duration
+	}{
+		{description: "passing", maxInterval: time.Hour, multiplier: 2, failures: 0, expected: time.Minute * 5},
+		{description: "one failure", maxInterval: time.Hour, multiplier: 2, failures: 1, expected: time.Minute * 10},
+		{description: "three failures", maxInterval: time.Hour, multiplier: 2, failures: 3, expected: time.Minute * 40},
+		{description: "capped", maxInterval: time.Hour, multiplier: 2, failures: 4, expected: time.Hour},
+		{description: "triple", maxInterval: time.Hour, multiplier: 3, failures: 2, expected: time.Minute * 45},
+		{description: "many failures", maxInterval: time.Hour * 24, multiplier: 10, failures: 1000, expected: time.Hour * 24},
+		{description: "no multiplier", maxInterval: time.Hour, multiplier: 0, failures: 3, expected: time.Minute * 5},
+		{description: "maximum below interval", maxInterval: time.Minute, multiplier: 2, failures: 3, expected: time.Minute * 5},
+	}
+
+	for _, tc := range testCases {
+		interval := backoffRunInterval(time.Minute*5, tc.maxInterval, tc.multiplier, tc.failures)
+		if interval != tc.expected {
+			t.Fatalf("%s: expected a run interval of %s but got %s", tc.description, tc.expected, interval)
+		}
+	}
+}
+
+// TestJobBackoffLimit ensures that a negative backoff limit still runs a job once rather than never
+func TestJobBackoffLimit(t *testing.T) {
+	testCases := map[int32]int32{
+		-5: 0,
+		-1: 0,
+		0:  0,
+		3:  3,
+	}
+	for backoffLimit, expected := range testCases {
+		job := khjobv1.KuberhealthyJob{Spec: khjobv1.JobConfig{BackoffLimit: backoffLimit}}
+		limit := jobBackoffLimit(job)
+		if limit != expected {
+			t.Fatalf("expected a backoff limit of %d to be used as %d but got %d", backoffLimit, expected, limit)
+		}
+	}
+}
+
+// TestJobBackoffDelay ensures that the delay before retrying a failed job doubles with each retry up to the maximum
+func TestJobBackoffDelay(t *testing.T) {
+	testCases := map[int32]time.Duration{
+		1:  jobBackoffBaseDelay,
+		2:  jobBackoffBaseDelay * 2,
+		3:  jobBackoffBaseDelay * 4,
+		50: maxJobBackoffDelay,
+	}
+	for attempt, expected := range testCases {
+		delay := jobBackoffDelay(attempt)
+		if delay != expected {
+			t.Fatalf("expected a delay of %s before retry %d but got %s", expected, attempt, delay)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certReloader serves a TLS certificate and key from disk and reloads them whenever either file changes.  This
+// allows certificates mounted from a secret to be rotated without restarting Kuberhealthy.
+type certReloader struct {
+	certFile    string
+	keyFile     string
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	sync.RWMutex
+}
+
+// newCertReloader creates a certReloader and loads the supplied certificate and key
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	err := cr.reload()
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// reload loads the certificate and key from disk
+func (cr *certReloader) reload() error {
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate %s and key %s: %w", cr.certFile, cr.keyFile, err)
+	}
+
+	cr.Lock()
+	defer cr.Unlock()
+	cr.cert = &cert
+	cr.certModTime = certModTime
+	cr.keyModTime = keyModTime
+	return nil
+}
+
+// modTimes returns the last modification times of the certificate and key files
+func (cr *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat TLS certificate %s: %w", cr.certFile, err)
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat TLS key %s: %w", cr.keyFile, err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// changed indicates if the certificate or key have changed on disk since they were last loaded
+func (cr *certReloader) changed() bool {
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		log.Errorln("Error checking TLS certificate for changes:", err)
+		return false
+	}
+
+	cr.RLock()
+	defer cr.RUnlock()
+	return !certModTime.Equal(cr.certModTime) || !keyModTime.Equal(cr.keyModTime)
+}
+
+// GetCertificate returns the current certificate for use in a tls.Config.  If the certificate or key have changed
+// on disk, they are reloaded first.  If reloading fails, the previously loaded certificate is served.
+func (cr *certReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cr.changed() {
+		log.Infoln("TLS certificate", cr.certFile, "has changed. Reloading.")
+		err := cr.reload()
+		if err != nil {
+			log.Errorln("Error reloading TLS certificate. Continuing to serve the previous certificate:", err)
+		}
+	}
+
+	cr.RLock()
+	defer cr.RUnlock()
+	return cr.cert, nil
+}
+
+// tlsConfig returns a tls.Config that serves the reloader's current certificate
+func (cr *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.GetCertificate,
+	}
+}
+
+// tlsConfigWithClientCAs returns a tls.Config that serves the reloader's current certificate and verifies the
+// client certificates that clients present against the CAs returned by clientCAs.  Clients without a certificate
+// are still let in, so that the status page and metrics can be reached without one.  The CAs are loaded again for
+// every connection so that they can be rotated without restarting Kuberhealthy.
+func (cr *certReloader) tlsConfigWithClientCAs(clientCAs func() (*x509.CertPool, error)) *tls.Config {
+	config := cr.tlsConfig()
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		pool, err := clientCAs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA certificates: %w", err)
+		}
+		clientConfig := cr.tlsConfig()
+		clientConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		clientConfig.ClientCAs = pool
+		return clientConfig, nil
+	}
+	return config
+}
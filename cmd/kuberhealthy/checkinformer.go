@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// khCheckResyncPeriod is how often the khcheck informer re-delivers every cached khcheck to its handlers
+const khCheckResyncPeriod = time.Minute * 5
+
+// newKHCheckInformer creates a shared informer that caches khcheck resources in the supplied namespace.  To
+// include all namespaces, pass a blank namespace.
+func newKHCheckInformer(namespace string) cache.SharedIndexInformer {
+	return khcheckv1.NewKuberhealthyCheckInformer(khCheckClient, namespace, khCheckResyncPeriod)
+}
+
+// cachedKHChecks lists khchecks from the khcheck informer cache.  If the informer has not synced yet, the khchecks
+// are listed from the API server instead.
+func (k *Kuberhealthy) cachedKHChecks() (khcheckv1.KuberhealthyCheckList, error) {
+	if k.khCheckInformer == nil || !k.khCheckInformer.HasSynced() {
+		log.Debugln("khcheck informer has not synced. Listing khchecks from the API server.")
+		return k.listKHChecks(k.TargetNamespace)
+	}
+
+	cached, err := k.khCheckLister.List(labels.Everything())
+	if err != nil {
+		return khcheckv1.KuberhealthyCheckList{}, err
+	}
+	khChecks := khcheckv1.KuberhealthyCheckList{}
+	for _, kc := range cached {
+		khChecks.Items = append(khChecks.Items, *kc.DeepCopy())
+	}
+	return khChecks, nil
+}
+
+// cachedKHCheck gets the named khcheck from the khcheck informer cache.  False is returned if the khcheck is not
+// cached.  The khcheck is shared with the cache and must not be modified.
+func (k *Kuberhealthy) cachedKHCheck(name string, namespace string) (*khcheckv1.KuberhealthyCheck, bool) {
+	if k.khCheckLister == nil {
+		return nil, false
+	}
+	kc, err := k.khCheckLister.KuberhealthyChecks(namespace).Get(name)
+	if err != nil {
+		return nil, false
+	}
+	return kc, true
+}
+
+// checkSeverity returns the severity set on the named khcheck in the khcheck informer cache.  A blank severity is
+// returned if the khcheck is not cached or has no severity.
+func (k *Kuberhealthy) checkSeverity(name string, namespace string) string {
+	kc, ok := k.cachedKHCheck(name, namespace)
+	if !ok {
+		return ""
+	}
+	return string(kc.Spec.Severity)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// TestCachedKHCheck ensures that khchecks are looked up in the khcheck informer cache by name and namespace
+func TestCachedKHCheck(t *testing.T) {
+	kh := &Kuberhealthy{}
+	if _, ok := kh.cachedKHCheck("test-check", "kuberhealthy"); ok {
+		t.Fatalf("expected no khcheck to be found without an informer cache")
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	err := indexer.Add(&khcheckv1.KuberhealthyCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-check", Namespace: "kuberhealthy"},
+		Spec:       khcheckv1.CheckConfig{Paused: true, Severity: khcheckv1.SeverityWarning},
+	})
+	if err != nil {
+		t.Fatalf("failed to add khcheck to the indexer: %s", err)
+	}
+	kh.khCheckLister = khcheckv1.NewKuberhealthyCheckLister(indexer)
+
+	kc, ok := kh.cachedKHCheck("test-check", "kuberhealthy")
+	if !ok {
+		t.Fatalf("expected the cached khcheck to be found")
+	}
+	if kc.Name != "test-check" || !kc.Spec.Paused {
+		t.Fatalf("expected the cached khcheck to be returned but got %+v", kc)
+	}
+	if !kh.checkPaused("test-check", "kuberhealthy") {
+		t.Fatalf("expected the cached khcheck to be paused")
+	}
+	if severity := kh.checkSeverity("test-check", "kuberhealthy"); severity != "warning" {
+		t.Fatalf("expected the severity of the cached khcheck to be warning but got %q", severity)
+	}
+	if severity := kh.checkSeverity("missing-check", "kuberhealthy"); len(severity) != 0 {
+		t.Fatalf("expected no severity for a missing khcheck but got %q", severity)
+	}
+	if _, ok := kh.cachedKHCheck("test-check", "other"); ok {
+		t.Fatalf("expected khchecks in other namespaces not to be found")
+	}
+	if _, ok := kh.cachedKHCheck("missing-check", "kuberhealthy"); ok {
+		t.Fatalf("expected a missing khcheck not to be found")
+	}
+}
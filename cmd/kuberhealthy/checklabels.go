@@ -0,0 +1,43 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+)
+
+// selectCheckLabels returns the named labels of the supplied khcheck metadata, keyed by their Prometheus label name.
+// Annotations are used for names that are not labels.  Names that are neither are left out.
+func selectCheckLabels(meta metav1.ObjectMeta, names []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	selected := make(map[string]string)
+	for _, name := range names {
+		value, ok := meta.Labels[name]
+		if !ok {
+			value, ok = meta.Annotations[name]
+		}
+		if !ok || len(name) == 0 {
+			continue
+		}
+		selected[metrics.LabelName(name)] = value
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// checkLabels returns the labels and annotations of the named khcheck that the checkLabels setting selects
+func (k *Kuberhealthy) checkLabels(name string, namespace string) map[string]string {
+	if len(cfg.CheckLabels) == 0 {
+		return nil
+	}
+	kc, ok := k.cachedKHCheck(name, namespace)
+	if !ok {
+		return nil
+	}
+	return selectCheckLabels(kc.ObjectMeta, cfg.CheckLabels)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestSelectCheckLabels ensures that the configured labels are selected from the labels of a khcheck before its
+// annotations, and that their names are turned into valid Prometheus label names
+func TestSelectCheckLabels(t *testing.T) {
+	meta := metav1.ObjectMeta{
+		Labels:      map[string]string{"team": "payments", "app.kubernetes.io/component": "api"},
+		Annotations: map[string]string{"team": "ignored", "tier": "gold"},
+	}
+
+	selected := selectCheckLabels(meta, []string{"team", "tier", "app.kubernetes.io/component", "missing"})
+	expected := map[string]string{"team": "payments", "tier": "gold", "app_kubernetes_io_component": "api"}
+	if !reflect.DeepEqual(selected, expected) {
+		t.Fatalf("expected labels %v but got %v", expected, selected)
+	}
+
+	if selected := selectCheckLabels(meta, nil); selected != nil {
+		t.Fatalf("expected no labels when none are configured but got %v", selected)
+	}
+	if selected := selectCheckLabels(meta, []string{"missing"}); selected != nil {
+		t.Fatalf("expected no labels when the khcheck has none of them but got %v", selected)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// maxKHCheckEventRetries is how many times a khcheck event that failed to be handled is retried before it is dropped
+const maxKHCheckEventRetries = 5
+
+// khCheckEventKind is the kind of work a khcheck event asks for
+type khCheckEventKind int
+
+const (
+	khCheckChanged      khCheckEventKind = iota // the khcheck was added, removed, paused, resumed or had its spec changed
+	khCheckRunRequested                         // a run of the khcheck was requested with the run now annotation
+)
+
+// String returns the name of the event kind for logging
+func (kind khCheckEventKind) String() string {
+	switch kind {
+	case khCheckChanged:
+		return "change"
+	case khCheckRunRequested:
+		return "run request"
+	}
+	return "unknown"
+}
+
+// khCheckEvent is an item in the khcheck work queue.  Events are comparable, so that repeated events for the same
+// khcheck are collapsed into one while they wait in the queue.
+type khCheckEvent struct {
+	kind      khCheckEventKind
+	namespace string
+	name      string
+}
+
+// newKHCheckQueue creates a work queue for khcheck events that retries failed events with the per-item exponential
+// backoff and overall rate limit used by Kubernetes controllers
+func newKHCheckQueue() workqueue.RateLimitingInterface {
+	return workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{
+		Name: "khchecks",
+	})
+}
+
+// processKHCheckEvents handles events from the queue until the queue is shut down.  Events that fail to be handled
+// are put back on the queue with a rate limited delay until they run out of retries.
+func processKHCheckEvents(queue workqueue.RateLimitingInterface, handle func(khCheckEvent) error) {
+	for processNextKHCheckEvent(queue, handle) {
+	}
+}
+
+// processNextKHCheckEvent handles the next event from the queue.  False is returned when the queue has been shut
+// down.
+func processNextKHCheckEvent(queue workqueue.RateLimitingInterface, handle func(khCheckEvent) error) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	event, ok := item.(khCheckEvent)
+	if !ok {
+		log.Warningln("khcheck queue contained an item that was not a khcheck event:", item)
+		queue.Forget(item)
+		return true
+	}
+
+	err := handle(event)
+	if err == nil {
+		queue.Forget(item)
+		return true
+	}
+	if queue.NumRequeues(item) < maxKHCheckEventRetries {
+		log.Debugln("Retrying khcheck", event.kind, "for", event.namespace+"/"+event.name, "after error:", err)
+		queue.AddRateLimited(item)
+		return true
+	}
+	log.Errorln("Dropping khcheck", event.kind, "for", event.namespace+"/"+event.name, "after", maxKHCheckEventRetries, "retries:", err)
+	queue.Forget(item)
+	return true
+}
+
+// handleKHCheckEvent signals the notify channel when a khcheck changed and triggers requested runs.  Run requests
+// for checks that are not loaded yet are retried, because the check may be reloading after a change.
+func (k *Kuberhealthy) handleKHCheckEvent(event khCheckEvent, notify chan struct{}) error {
+	switch event.kind {
+	case khCheckChanged:
+		// signal a change without blocking. if a signal is already queued, the checks will be reloaded anyway.
+		select {
+		case notify <- struct{}{}:
+		default:
+			log.Debugln("Skipping khcheck change signal because one is already queued")
+		}
+	case khCheckRunRequested:
+		if !k.runsChecks() {
+			log.Debugln("Not triggering a run of check", event.name, "in namespace", event.namespace, "because this instance is not master")
+			return nil
+		}
+		if !k.ownsCheck(event.name, event.namespace) {
+			log.Debugln("Not triggering a run of check", event.name, "in namespace", event.namespace, "because it is run by another shard member")
+			return nil
+		}
+		if _, err := k.getCheck(event.name, event.namespace); err != nil {
+			return fmt.Errorf("check is not loaded: %w", err)
+		}
+		k.triggerCheckRun(event.name, event.namespace)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestProcessNextKHCheckEvent ensures that queued khcheck events are collapsed, and that failed events are retried
+// until they run out of retries
+func TestProcessNextKHCheckEvent(t *testing.T) {
+	queue := newKHCheckQueue()
+	defer queue.ShutDown()
+
+	event := khCheckEvent{kind: khCheckChanged, namespace: "kuberhealthy", name: "test-check"}
+	queue.Add(event)
+	queue.Add(event)
+	if queue.Len() != 1 {
+		t.Fatalf("expected repeated events to be collapsed into 1 but the queue has %d", queue.Len())
+	}
+
+	handled := 0
+	handle := func(khCheckEvent) error {
+		handled++
+		return errors.New("failed")
+	}
+	for i := 0; i <= maxKHCheckEventRetries; i++ {
+		if !processNextKHCheckEvent(queue, handle) {
+			t.Fatalf("expected the queue to still be running")
+		}
+	}
+	if handled != maxKHCheckEventRetries+1 {
+		t.Fatalf("expected the event to be handled %d times but it was handled %d times", maxKHCheckEventRetries+1, handled)
+	}
+	if queue.NumRequeues(event) != 0 {
+		t.Fatalf("expected the event to be forgotten after running out of retries")
+	}
+	if queue.Len() != 0 {
+		t.Fatalf("expected the event to be dropped after running out of retries but the queue has %d", queue.Len())
+	}
+
+	queue.ShutDown()
+	if processNextKHCheckEvent(queue, handle) {
+		t.Fatalf("expected processing to stop after the queue is shut down")
+	}
+}
+
+// TestHandleKHCheckEvent ensures that changes signal a reload without blocking and that run requests are retried
+// until the check is loaded
+func TestHandleKHCheckEvent(t *testing.T) {
+	previousIsMaster := isMaster
+	defer func() {
+		isMaster = previousIsMaster
+	}()
+	isMaster = true
+
+	c := &external.Checker{
+		CheckName: "test-check",
+		Namespace: "kuberhealthy",
+		RunNow:    make(chan struct{}, 1),
+	}
+	kh := &Kuberhealthy{}
+	notify := make(chan struct{}, 1)
+
+	changed := khCheckEvent{kind: khCheckChanged, namespace: "kuberhealthy", name: "test-check"}
+	for i := 0; i < 2; i++ {
+		err := kh.handleKHCheckEvent(changed, notify)
+		if err != nil {
+			t.Fatalf("unexpected error handling a change: %s", err)
+		}
+	}
+	if len(notify) != 1 {
+		t.Fatalf("expected 1 queued change signal but got %d", len(notify))
+	}
+
+	runRequested := khCheckEvent{kind: khCheckRunRequested, namespace: "kuberhealthy", name: "test-check"}
+	err := kh.handleKHCheckEvent(runRequested, notify)
+	if err == nil {
+		t.Fatalf("expected a run request for a check that is not loaded to fail so that it is retried")
+	}
+
+	kh.Checks = []*external.Checker{c}
+	err = kh.handleKHCheckEvent(runRequested, notify)
+	if err != nil {
+		t.Fatalf("unexpected error handling a run request: %s", err)
+	}
+	if len(c.RunNow) != 1 {
+		t.Fatalf("expected a run of the check to be triggered")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+)
+
+// runLimiter limits the number of checker pods that run at the same time, both across the cluster and within
+// each namespace, and the total resources that the checker pods of a namespace request.  Runs that would go over a
+// limit are queued and started in the order they were requested as soon as a running check finishes.  A limit of
+// zero or less is unlimited.
+type runLimiter struct {
+	maxRunning             int                           // the maximum number of runs across all namespaces
+	maxRunningPerNamespace int                           // the maximum number of runs within a single namespace
+	namespaceLimits        map[string]namespaceLimit     // the quotas of each namespace. the default quota is under a blank name
+	running                int                           // the number of runs in progress
+	runningPerNamespace    map[string]int                // the number of runs in progress in each namespace
+	requestedPerNamespace  map[string]apiv1.ResourceList // the resources requested by the runs in progress in each namespace
+	waiting                []*runWaiter                  // runs waiting for a free slot, oldest first
+	sync.Mutex
+}
+
+// runWaiter is a run that is queued in a runLimiter
+type runWaiter struct {
+	namespace string
+	requests  apiv1.ResourceList // the resources requested by the checker pod of the run
+	ready     chan struct{}      // closed when the run may start
+}
+
+// newRunLimiter creates a runLimiter with the supplied limits
+func newRunLimiter(maxRunning int, maxRunningPerNamespace int) *runLimiter {
+	return &runLimiter{
+		maxRunning:             maxRunning,
+		maxRunningPerNamespace: maxRunningPerNamespace,
+		namespaceLimits:        make(map[string]namespaceLimit),
+		runningPerNamespace:    make(map[string]int),
+		requestedPerNamespace:  make(map[string]apiv1.ResourceList),
+	}
+}
+
+// setLimits changes the limits of the runLimiter.  Queued runs are started right away if the new limits allow it.
+func (rl *runLimiter) setLimits(maxRunning int, maxRunningPerNamespace int) {
+	rl.Lock()
+	defer rl.Unlock()
+	rl.maxRunning = maxRunning
+	rl.maxRunningPerNamespace = maxRunningPerNamespace
+	rl.dispatch()
+}
+
+// setQuotas changes the quotas of each namespace.  Queued runs are started right away if the new quotas allow it.
+func (rl *runLimiter) setQuotas(quotas NamespaceQuotas) {
+	rl.Lock()
+	defer rl.Unlock()
+	rl.namespaceLimits = newNamespaceLimits(quotas)
+	rl.dispatch()
+}
+
+// acquire blocks until a run in the supplied namespace that requests the supplied resources is allowed to start.
+// The returned func must be called when the run is done to let the next queued run start.  When the run is queued,
+// onQueued is called with the reason it is waiting, unless it is nil.  An error is returned if the context is
+// canceled before the run could start.
+func (rl *runLimiter) acquire(ctx context.Context, namespace string, name string, requests apiv1.ResourceList, onQueued func(reason string)) (func(), error) {
+	w := &runWaiter{
+		namespace: namespace,
+		requests:  requests,
+		ready:     make(chan struct{}),
+	}
+
+	rl.Lock()
+	rl.waiting = append(rl.waiting, w)
+	rl.dispatch()
+	queued := len(rl.waiting)
+	reason := rl.blockedBy(w)
+	rl.Unlock()
+
+	release := rl.releaseFunc(namespace, requests)
+
+	select {
+	case <-w.ready:
+		return release, nil
+	default:
+	}
+
+	log.Infoln("Run of check", name, "in namespace", namespace, "is queued,", reason+".", queued, "runs are queued")
+	if onQueued != nil {
+		onQueued(reason)
+	}
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	// the run may have been started while we were canceled, in which case its slot is freed again
+	rl.Lock()
+	defer rl.Unlock()
+	select {
+	case <-w.ready:
+		rl.finish(namespace, requests)
+	default:
+		rl.remove(w)
+	}
+	return nil, ctx.Err()
+}
+
+// releaseFunc returns a func that frees the slot and the requested resources of a run in the supplied namespace.
+// Calling it more than once has no effect.
+func (rl *runLimiter) releaseFunc(namespace string, requests apiv1.ResourceList) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			rl.Lock()
+			defer rl.Unlock()
+			rl.finish(namespace, requests)
+		})
+	}
+}
+
+// finish frees the slot and the requested resources of a run in the supplied namespace and starts any queued runs
+// that now fit.  The caller must hold the lock.
+func (rl *runLimiter) finish(namespace string, requests apiv1.ResourceList) {
+	rl.running--
+	rl.runningPerNamespace[namespace]--
+	inUse := rl.requestedPerNamespace[namespace]
+	for name, quantity := range requests {
+		total := inUse[name]
+		total.Sub(quantity)
+		inUse[name] = total
+	}
+	if rl.runningPerNamespace[namespace] <= 0 {
+		delete(rl.runningPerNamespace, namespace)
+		delete(rl.requestedPerNamespace, namespace)
+		metrics.CheckerPodsRunning.Delete(namespace)
+	} else {
+		metrics.CheckerPodsRunning.Set(float64(rl.runningPerNamespace[namespace]), namespace)
+	}
+	rl.dispatch()
+}
+
+// dispatch starts queued runs in order for as long as the limits allow.  Runs that are held back by the limit of
+// their namespace do not hold back runs in other namespaces.  The caller must hold the lock.
+func (rl *runLimiter) dispatch() {
+	var stillWaiting []*runWaiter
+	for _, w := range rl.waiting {
+		if len(rl.blockedBy(w)) > 0 {
+			stillWaiting = append(stillWaiting, w)
+			continue
+		}
+		rl.running++
+		rl.runningPerNamespace[w.namespace]++
+		inUse := rl.requestedPerNamespace[w.namespace]
+		if inUse == nil {
+			inUse = make(apiv1.ResourceList)
+			rl.requestedPerNamespace[w.namespace] = inUse
+		}
+		for name, quantity := range w.requests {
+			total := inUse[name]
+			total.Add(quantity)
+			inUse[name] = total
+		}
+		metrics.CheckerPodsRunning.Set(float64(rl.runningPerNamespace[w.namespace]), w.namespace)
+		close(w.ready)
+	}
+	rl.waiting = stillWaiting
+}
+
+// blockedBy describes the limit that keeps a queued run from starting, or returns a blank string when the run fits
+// within the limits.  A run that requests more than the quota of its namespace on its own may still start when
+// nothing else runs in its namespace, so that it is not queued forever.  The caller must hold the lock.
+func (rl *runLimiter) blockedBy(w *runWaiter) string {
+	if rl.maxRunning > 0 && rl.running >= rl.maxRunning {
+		return delayReason(maxRunningReason(rl.maxRunning), "")
+	}
+
+	limit, ok := rl.namespaceLimits[w.namespace]
+	if !ok {
+		limit = rl.namespaceLimits[""]
+	}
+	maxRunning := limit.maxRunning
+	if maxRunning <= 0 {
+		maxRunning = rl.maxRunningPerNamespace
+	}
+	running := rl.runningPerNamespace[w.namespace]
+	if maxRunning > 0 && running >= maxRunning {
+		return delayReason(maxRunningReason(maxRunning), w.namespace)
+	}
+
+	if running == 0 {
+		return ""
+	}
+	exceeded := exceededRequest(limit.requests, rl.requestedPerNamespace[w.namespace], w.requests)
+	if len(exceeded) > 0 {
+		return delayReason("the "+string(exceeded)+" quota", w.namespace)
+	}
+	return ""
+}
+
+// remove takes a waiter out of the queue.  The caller must hold the lock.
+func (rl *runLimiter) remove(w *runWaiter) {
+	for i, waiter := range rl.waiting {
+		if waiter == w {
+			rl.waiting = append(rl.waiting[:i], rl.waiting[i+1:]...)
+			return
+		}
+	}
+}
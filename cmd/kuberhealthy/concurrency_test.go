@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestRunLimiter ensures that runs over the global and per namespace limits are queued until a slot frees up
+func TestRunLimiter(t *testing.T) {
+	rl := newRunLimiter(2, 1)
+	ctx := context.Background()
+
+	releaseA, err := rl.acquire(ctx, "team-a", "check-1", nil, nil)
+	if err != nil {
+		t.Fatal("Expected the first run to start right away:", err)
+	}
+
+	// a second run in the same namespace is held back by the namespace limit
+	started := make(chan func())
+	go func() {
+		release, err := rl.acquire(ctx, "team-a", "check-2", nil, nil)
+		if err != nil {
+			t.Error("Expected the queued run to start:", err)
+		}
+		started <- release
+	}()
+
+	// runs in other namespaces are not held back by the queued run
+	releaseB, err := rl.acquire(ctx, "team-b", "check-3", nil, nil)
+	if err != nil {
+		t.Fatal("Expected a run in another namespace to start right away:", err)
+	}
+
+	select {
+	case <-started:
+		t.Fatal("Expected the second run in team-a to be queued")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// the global limit of two holds back runs in new namespaces
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+	_, err = rl.acquire(timeoutCtx, "team-c", "check-4", nil, nil)
+	if err == nil {
+		t.Fatal("Expected a run over the global limit to be queued until its context expired")
+	}
+
+	releaseA()
+	releaseA() // releasing twice must not free a second slot
+	select {
+	case release := <-started:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued run to start after a slot was freed")
+	}
+	releaseB()
+
+	if rl.running != 0 || len(rl.runningPerNamespace) != 0 || len(rl.waiting) != 0 {
+		t.Fatalf("Expected the limiter to be empty but %d runs are running and %d are waiting", rl.running, len(rl.waiting))
+	}
+}
+
+// TestRunLimiterUnlimited ensures that a limiter without limits never queues runs
+func TestRunLimiterUnlimited(t *testing.T) {
+	rl := newRunLimiter(0, 0)
+	for i := 0; i < 10; i++ {
+		_, err := rl.acquire(context.Background(), "kuberhealthy", "check", nil, nil)
+		if err != nil {
+			t.Fatal("Expected runs to never be queued without limits:", err)
+		}
+	}
+}
+
+// TestRunLimiterQuotas ensures that runs are queued when they would go over the resource quota of their namespace,
+// and that a run larger than the quota still starts when nothing else runs in its namespace
+func TestRunLimiterQuotas(t *testing.T) {
+	rl := newRunLimiter(0, 0)
+	rl.setQuotas(NamespaceQuotas{
+		Default: NamespaceQuota{MaxRunning: 3},
+		Namespaces: map[string]NamespaceQuota{
+			"team-a": {Requests: map[string]string{"cpu": "1", "memory": "1Gi"}},
+		},
+	})
+	ctx := context.Background()
+	cpu := func(quantity string) apiv1.ResourceList {
+		return apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse(quantity)}
+	}
+
+	releaseA, err := rl.acquire(ctx, "team-a", "check-1", cpu("600m"), nil)
+	if err != nil {
+		t.Fatal("Expected the first run to start right away:", err)
+	}
+
+	// a second run that would go over the cpu quota is queued and told why
+	reasons := make(chan string, 1)
+	started := make(chan func())
+	go func() {
+		release, err := rl.acquire(ctx, "team-a", "check-2", cpu("600m"), func(reason string) {
+			reasons <- reason
+		})
+		if err != nil {
+			t.Error("Expected the queued run to start:", err)
+		}
+		started <- release
+	}()
+	select {
+	case reason := <-reasons:
+		if reason != "waiting for the cpu quota of namespace team-a" {
+			t.Fatalf("Unexpected reason for the queued run: %s", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the run over the cpu quota to be queued")
+	}
+
+	// a small run still fits within the quota, so it is not held back by the run queued before it
+	releaseSmall, err := rl.acquire(ctx, "team-a", "check-3", cpu("100m"), nil)
+	if err != nil {
+		t.Fatal("Expected a run within the quota to start right away:", err)
+	}
+	releaseSmall()
+
+	releaseA()
+	select {
+	case release := <-started:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued run to start after the first run finished")
+	}
+
+	// a run larger than the quota starts when nothing else runs in its namespace
+	releaseLarge, err := rl.acquire(ctx, "team-a", "check-4", cpu("2"), nil)
+	if err != nil {
+		t.Fatal("Expected a run larger than the quota to start on its own:", err)
+	}
+	releaseLarge()
+
+	if rl.running != 0 || len(rl.requestedPerNamespace) != 0 || len(rl.waiting) != 0 {
+		t.Fatalf("Expected the limiter to be empty but %d runs are running and %d are waiting", rl.running, len(rl.waiting))
+	}
+}
+
+// TestRunLimiterDefaultQuota ensures that namespaces without a quota of their own are limited by the default quota
+func TestRunLimiterDefaultQuota(t *testing.T) {
+	rl := newRunLimiter(0, 5)
+	rl.setQuotas(NamespaceQuotas{Default: NamespaceQuota{MaxRunning: 1}})
+
+	_, err := rl.acquire(context.Background(), "team-b", "check-1", nil, nil)
+	if err != nil {
+		t.Fatal("Expected the first run to start right away:", err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err = rl.acquire(timeoutCtx, "team-b", "check-2", nil, nil)
+	if err == nil {
+		t.Fatal("Expected the default quota to take precedence over maxConcurrentChecksPerNamespace")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// checkRunResult is the outcome of a check run that was supervised by the concurrency policy of its check
+type checkRunResult struct {
+	err      error // the error returned by the run
+	replaced bool  // the run was stopped because the next run came due while it was in flight
+	nextDue  bool  // the next run came due while the run was in flight and starts right away
+}
+
+// runWithConcurrencyPolicy executes a run of a check and applies the concurrency policy of the check to the ticks
+// that arrive while the run is in flight.  Checks with the Forbid policy skip those runs, checks with the Replace
+// policy cancel the run in flight and start the next run right away, and checks with the Queue policy start the
+// next run as soon as the run in flight finishes.
+func runWithConcurrencyPolicy(ctx context.Context, c *external.Checker, ticks <-chan time.Time, run func(context.Context) error) checkRunResult {
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run(runCtx)
+	}()
+
+	var result checkRunResult
+	for {
+		select {
+		case result.err = <-done:
+			return result
+		case <-ticks:
+		}
+
+		switch c.ConcurrencyPolicy {
+		case khcheckv1.ConcurrencyPolicyForbid:
+			log.Infoln("Skipping run of check", c.Name(), "in namespace", c.CheckNamespace(), "because its previous run is still in flight")
+		case khcheckv1.ConcurrencyPolicyReplace:
+			log.Infoln("Replacing run of check", c.Name(), "in namespace", c.CheckNamespace(), "that is still in flight with its next run")
+			cancelRun()
+			<-done
+			result.err = external.ErrRunReplaced
+			result.replaced = true
+			result.nextDue = true
+			return result
+		default:
+			log.Infoln("Next run of check", c.Name(), "in namespace", c.CheckNamespace(), "is due while its previous run is still in flight. It starts once the previous run finishes.")
+			result.nextDue = true
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestRunWithConcurrencyPolicy ensures that runs that come due while a run is in flight are skipped, replace the
+// run in flight or start once it finishes, depending on the concurrency policy of the check
+func TestRunWithConcurrencyPolicy(t *testing.T) {
+	testCases := []struct {
+		policy          khcheckv1.ConcurrencyPolicy
+		expectCancelled bool
+		expectReplaced  bool
+		expectNextDue   bool
+	}{
+		{policy: khcheckv1.ConcurrencyPolicyForbid},
+		{policy: khcheckv1.ConcurrencyPolicyReplace, expectCancelled: true, expectReplaced: true, expectNextDue: true},
+		{policy: khcheckv1.ConcurrencyPolicyQueue, expectNextDue: true},
+	}
+
+	for _, tc := range testCases {
+		c := &external.Checker{CheckName: "slow-check", Namespace: "kuberhealthy", ConcurrencyPolicy: tc.policy}
+		ticks := make(chan time.Time, 1)
+		ticks <- time.Now()
+
+		// the run finishes on its own after a while unless it is cancelled first
+		var cancelled bool
+		result := runWithConcurrencyPolicy(context.Background(), c, ticks, func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				cancelled = true
+			case <-time.After(time.Millisecond * 100):
+			}
+			return nil
+		})
+
+		if cancelled != tc.expectCancelled {
+			t.Fatalf("%s: expected the run to be cancelled %t but got %t", tc.policy, tc.expectCancelled, cancelled)
+		}
+		if result.replaced != tc.expectReplaced || result.nextDue != tc.expectNextDue {
+			t.Fatalf("%s: unexpected result %+v", tc.policy, result)
+		}
+		if tc.expectReplaced && !errors.Is(result.err, external.ErrRunReplaced) {
+			t.Fatalf("%s: expected the replaced run to fail with %v but got %v", tc.policy, external.ErrRunReplaced, result.err)
+		}
+		if !tc.expectReplaced && result.err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.policy, result.err)
+		}
+	}
+}
+
+// TestRunWithConcurrencyPolicyNoOverlap ensures that runs that finish before their next run is due are left alone
+func TestRunWithConcurrencyPolicyNoOverlap(t *testing.T) {
+	c := &external.Checker{CheckName: "fast-check", Namespace: "kuberhealthy", ConcurrencyPolicy: khcheckv1.ConcurrencyPolicyReplace}
+	runErr := errors.New("check failed")
+
+	result := runWithConcurrencyPolicy(context.Background(), c, make(chan time.Time), func(ctx context.Context) error {
+		return runErr
+	})
+	if result.replaced || result.nextDue || !errors.Is(result.err, runErr) {
+		t.Fatalf("Unexpected result for a run without overlap: %+v", result)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// newStateStatus builds the status of a khstate from its existing status and the state being written to it.
+// The Ready condition follows the reported state of the workload, while the LastRunSucceeded and PodScheduled
+// conditions, along with the last success time, are only changed when a completed run is recorded.
+func newStateStatus(existing khstatev1.StateStatus, state khstatev1.WorkloadDetails, run *khstatev1.RunRecord, generation int64, now metav1.Time) khstatev1.StateStatus {
+	status := *existing.DeepCopy()
+	status.ObservedGeneration = generation
+	status.ConsecutiveFailures = state.ConsecutiveFailures
+
+	reported := reportedState(state)
+	ready := metav1.Condition{
+		Type:               khstatev1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		LastTransitionTime: now,
+		Reason:             "CheckPassing",
+		Message:            "The check is reported as healthy",
+	}
+	if !reported.OK {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = "CheckFailing"
+		ready.Message = strings.Join(reported.Errors, "; ")
+	}
+	meta.SetStatusCondition(&status.Conditions, ready)
+
+	if run == nil {
+		return status
+	}
+
+	lastRun := metav1.Condition{
+		Type:               khstatev1.ConditionLastRunSucceeded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		LastTransitionTime: now,
+		Reason:             "RunSucceeded",
+		Message:            "The last run " + run.UUID + " succeeded",
+	}
+	if run.OK {
+		successTime := now
+		status.LastSuccessTime = &successTime
+	} else {
+		lastRun.Status = metav1.ConditionFalse
+		lastRun.Reason = "RunFailed"
+		lastRun.Message = strings.Join(run.Errors, "; ")
+	}
+	meta.SetStatusCondition(&status.Conditions, lastRun)
+
+	podScheduled := metav1.Condition{
+		Type:               khstatev1.ConditionPodScheduled,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		LastTransitionTime: now,
+		Reason:             "PodScheduled",
+		Message:            "Checker pod " + run.Pod + " was scheduled to node " + state.Node,
+	}
+	if len(state.Node) == 0 {
+		podScheduled.Status = metav1.ConditionFalse
+		podScheduled.Reason = "PodNotScheduled"
+		podScheduled.Message = "Checker pod " + run.Pod + " was not scheduled to a node"
+	}
+	meta.SetStatusCondition(&status.Conditions, podScheduled)
+
+	return status
+}
+
+// newCheckStatus builds the status of a khcheck from its existing status and the status of its khstate.  The Ready
+// and LastRunSucceeded conditions are copied from the khstate and marked as observed at the supplied generation of
+// the khcheck, so that kubectl wait does not accept conditions set before the khcheck was last changed.
+func newCheckStatus(existing khcheckv1.CheckStatus, stateStatus khstatev1.StateStatus, generation int64) khcheckv1.CheckStatus {
+	status := *existing.DeepCopy()
+	status.ObservedGeneration = generation
+	for _, conditionType := range []string{khstatev1.ConditionReady, khstatev1.ConditionLastRunSucceeded} {
+		condition := meta.FindStatusCondition(stateStatus.Conditions, conditionType)
+		if condition == nil {
+			continue
+		}
+		mirrored := *condition
+		mirrored.ObservedGeneration = generation
+		meta.SetStatusCondition(&status.Conditions, mirrored)
+	}
+	return status
+}
+
+// setCheckStatus copies the conditions of a khstate onto the status of its khcheck.  khstates of khjobs have no
+// khcheck and are skipped.
+func setCheckStatus(checkName string, checkNamespace string, stateStatus khstatev1.StateStatus) error {
+	kc, err := khCheckClient.KuberhealthyChecks(checkNamespace).Get(checkName, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	kc.Status = newCheckStatus(kc.Status, stateStatus, kc.GetGeneration())
+	_, err = khCheckClient.KuberhealthyChecks(checkNamespace).UpdateStatus(&kc)
+	return err
+}
+
+// checkerPodNode returns the node that a checker pod was scheduled to, or a blank string if the pod was not
+// scheduled or can no longer be found
+func checkerPodNode(namespace string, podName string) string {
+	if len(podName) == 0 || kubernetesClient == nil {
+		return ""
+	}
+	pod, err := kubernetesClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		log.Debugln("Unable to look up the node of checker pod", namespace+"/"+podName+":", err)
+		return ""
+	}
+	return pod.Spec.NodeName
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestNewStateStatus ensures that khstate conditions follow the state and runs of a check
+func TestNewStateStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 1, 1, 2, 0, 0, 0, time.UTC))
+
+	// a successful run sets every condition and the last success time
+	state := khstatev1.WorkloadDetails{OK: true, Node: "node-1"}
+	run := &khstatev1.RunRecord{OK: true, Pod: "test-check-1", UUID: "uuid-1"}
+	status := newStateStatus(khstatev1.StateStatus{}, state, run, 3, now)
+
+	if status.ObservedGeneration != 3 {
+		t.Fatalf("Expected an observed generation of 3 but got %d", status.ObservedGeneration)
+	}
+	for _, conditionType := range []string{khstatev1.ConditionReady, khstatev1.ConditionLastRunSucceeded, khstatev1.ConditionPodScheduled} {
+		if !meta.IsStatusConditionTrue(status.Conditions, conditionType) {
+			t.Fatalf("Expected condition %s to be true", conditionType)
+		}
+	}
+	if status.LastSuccessTime == nil || !status.LastSuccessTime.Equal(&now) {
+		t.Fatal("Expected the last success time to be set")
+	}
+
+	// a failure reported before its run completes only changes the Ready condition
+	later := metav1.NewTime(now.Add(time.Minute))
+	state = khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}}
+	status = newStateStatus(status, state, nil, 3, later)
+	if !meta.IsStatusConditionFalse(status.Conditions, khstatev1.ConditionReady) {
+		t.Fatal("Expected the Ready condition to be false")
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, khstatev1.ConditionLastRunSucceeded) {
+		t.Fatal("Expected the LastRunSucceeded condition to be unchanged")
+	}
+
+	// a failed run that was never scheduled fails its conditions but keeps the last success time
+	run = &khstatev1.RunRecord{OK: false, Errors: []string{"failed"}, Pod: "test-check-2", UUID: "uuid-2"}
+	status = newStateStatus(status, state, run, 3, later)
+	if !meta.IsStatusConditionFalse(status.Conditions, khstatev1.ConditionLastRunSucceeded) {
+		t.Fatal("Expected the LastRunSucceeded condition to be false")
+	}
+	if !meta.IsStatusConditionFalse(status.Conditions, khstatev1.ConditionPodScheduled) {
+		t.Fatal("Expected the PodScheduled condition to be false")
+	}
+	if !status.LastSuccessTime.Equal(&now) {
+		t.Fatal("Expected the last success time to be kept")
+	}
+}
+
+// TestNewCheckStatus ensures that khchecks get the Ready and LastRunSucceeded conditions of their khstate, marked as
+// observed at the generation of the khcheck
+func TestNewCheckStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 1, 1, 2, 0, 0, 0, time.UTC))
+	state := khstatev1.WorkloadDetails{OK: true, Node: "node-1"}
+	run := &khstatev1.RunRecord{OK: true, Pod: "test-check-1", UUID: "uuid-1"}
+	stateStatus := newStateStatus(khstatev1.StateStatus{}, state, run, 7, now)
+
+	status := newCheckStatus(khcheckv1.CheckStatus{}, stateStatus, 2)
+	if status.ObservedGeneration != 2 {
+		t.Fatalf("Expected an observed generation of 2 but got %d", status.ObservedGeneration)
+	}
+	if len(status.Conditions) != 2 {
+		t.Fatalf("Expected the Ready and LastRunSucceeded conditions but got %+v", status.Conditions)
+	}
+	ready := meta.FindStatusCondition(status.Conditions, khstatev1.ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue || ready.ObservedGeneration != 2 {
+		t.Fatalf("Expected a true Ready condition observed at generation 2 but got %+v", ready)
+	}
+
+	// a failing check makes the khcheck not ready
+	later := metav1.NewTime(now.Add(time.Minute))
+	state = khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}}
+	stateStatus = newStateStatus(stateStatus, state, nil, 7, later)
+	status = newCheckStatus(status, stateStatus, 3)
+	ready = meta.FindStatusCondition(status.Conditions, khstatev1.ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.ObservedGeneration != 3 {
+		t.Fatalf("Expected a false Ready condition observed at generation 3 but got %+v", ready)
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, khstatev1.ConditionLastRunSucceeded) {
+		t.Fatal("Expected the LastRunSucceeded condition to be left as it was")
+	}
+}
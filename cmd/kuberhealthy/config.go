@@ -3,36 +3,241 @@ package main
 import (
 	"context"
 	"os"
+	"reflect"
 	"time"
 
 	"github.com/codingsince1985/checksum"
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khconfigv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khconfig/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/archive"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/federation"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/imageverify"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/notifications"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/statestore"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Config holds all configurable options
 type Config struct {
-	kubeConfigFile            string                    `yaml:"kubeConfigFile"`
-	ListenAddress             string                    `yaml:"listenAddress"`
-	EnableForceMaster         bool                      `yaml:"enableForceMaster"`
-	LogLevel                  string                    `yaml:"logLevel"`
-	InfluxUsername            string                    `yaml:"influxUsername"`
-	InfluxPassword            string                    `yaml:"influxPassword"`
-	InfluxURL                 string                    `yaml:"influxURL"`
-	InfluxDB                  string                    `yaml:"influxDB"`
-	EnableInflux              bool                      `yaml:"enableInflux"`
-	ExternalCheckReportingURL string                    `yaml:"externalCheckReportingURL"`
-	MaxKHJobAge               time.Duration             `yaml:"maxKHJobAge"`
-	MaxCheckPodAge            time.Duration             `yaml:"maxCheckPodAge"`
-	MaxCompletedPodCount      int                       `yaml:"maxCompletedPodCount"`
-	MaxErrorPodCount          int                       `yaml:"maxErrorPodCount"`
-	StateMetadata             map[string]string         `yaml:"stateMetadata,omitempty"`
-	PromMetricsConfig         metrics.PromMetricsConfig `yaml:"promMetricsConfig,omitempty"`
-	TargetNamespace           string                    `yaml:"namespace"` // TargetNamespace sets the namespace that Kuberhealthy will operate in.  By default, this is blank, which means
+	kubeConfigFile                  string                          `yaml:"kubeConfigFile"`
+	ListenAddress                   string                          `yaml:"listenAddress"`
+	EnableForceMaster               bool                            `yaml:"enableForceMaster"`
+	LogLevel                        string                          `yaml:"logLevel"`
+	InfluxUsername                  string                          `yaml:"influxUsername"`
+	InfluxPassword                  string                          `yaml:"influxPassword"`
+	InfluxURL                       string                          `yaml:"influxURL"`
+	InfluxDB                        string                          `yaml:"influxDB"`
+	EnableInflux                    bool                            `yaml:"enableInflux"`
+	ExternalCheckReportingURL       string                          `yaml:"externalCheckReportingURL"`
+	DebugListenAddress              string                          `yaml:"debugListenAddress,omitempty"`       // the address to serve pprof, expvar and goroutine dumps on, such as "localhost:6060". blank disables them
+	GRPCListenAddress               string                          `yaml:"grpcListenAddress,omitempty"`        // the address to serve the gRPC reporting API on, such as ":9090". blank disables it
+	ExternalCheckGRPCAddress        string                          `yaml:"externalCheckGRPCAddress,omitempty"` // the address checker pods send gRPC reports to. defaults to the kuberhealthy service on the gRPC port
+	MaxKHJobAge                     time.Duration                   `yaml:"maxKHJobAge"`
+	MaxCheckPodAge                  time.Duration                   `yaml:"maxCheckPodAge"`
+	MaxCompletedPodCount            int                             `yaml:"maxCompletedPodCount"`
+	MaxErrorPodCount                int                             `yaml:"maxErrorPodCount"`
+	CheckReaperRunInterval          time.Duration                   `yaml:"checkReaperRunInterval,omitempty"`  // how often checker pods and khjobs are reaped. overridden by CHECK_REAPER_RUN_INTERVAL
+	MaxFailedPodAge                 time.Duration                   `yaml:"maxFailedPodAge,omitempty"`         // the maximum age of failed checker pods before they are reaped. defaults to maxCheckPodAge
+	ReaperNamespaces                []string                        `yaml:"reaperNamespaces,omitempty"`        // the namespaces the reaper cleans up checker pods and khjobs in. defaults to the target namespace
+	ReaperDryRun                    bool                            `yaml:"reaperDryRun,omitempty"`            // log the checker pods and khjobs the reaper would delete without deleting them
+	KHStateReapGracePeriod          time.Duration                   `yaml:"khStateReapGracePeriod,omitempty"`  // how long khstates of deleted khchecks and khjobs are kept before they are deleted. defaults to 10m
+	StateWriteBatchInterval         time.Duration                   `yaml:"stateWriteBatchInterval,omitempty"` // how long writes to the same khstate are collected into one patch. defaults to 1s. negative values write right away
+	ShutdownDrainTimeout            time.Duration                   `yaml:"shutdownDrainTimeout,omitempty"`    // how long runs in flight may take to finish when kuberhealthy shuts down. defaults to 2m. negative values interrupt them right away
+	StateMetadata                   map[string]string               `yaml:"stateMetadata,omitempty"`
+	PromMetricsConfig               metrics.PromMetricsConfig       `yaml:"promMetricsConfig,omitempty"`
+	Datadog                         metrics.DatadogConfig           `yaml:"datadog,omitempty"`                         // settings for sending check results to Datadog
+	InfluxResults                   metrics.InfluxResultsConfig     `yaml:"influxResults,omitempty"`                   // settings for writing check results to InfluxDB with the line protocol
+	ResultArchive                   archive.Config                  `yaml:"resultArchive,omitempty"`                   // settings for archiving the result of every run to an object storage bucket
+	Notifications                   notifications.Config            `yaml:"notifications,omitempty"`                   // settings for sending notifications when checks change state
+	TLSCertFile                     string                          `yaml:"tlsCertFile,omitempty"`                     // the TLS certificate to serve the status page and reporting endpoint with
+	TLSKeyFile                      string                          `yaml:"tlsKeyFile,omitempty"`                      // the TLS key to serve the status page and reporting endpoint with
+	AdmissionWebhook                AdmissionWebhookConfig          `yaml:"admissionWebhook,omitempty"`                // settings for the khcheck validating admission webhook
+	MaxRunHistory                   int                             `yaml:"maxRunHistory,omitempty"`                   // the number of runs kept in the run history of each khstate. set below zero to disable
+	FailureLogLines                 int                             `yaml:"failureLogLines,omitempty"`                 // the number of lines of checker pod logs attached to the errors of failed runs. set below zero to disable
+	FailureLogMaxBytes              int                             `yaml:"failureLogMaxBytes,omitempty"`              // the maximum size of the checker pod logs attached to the errors of failed runs
+	UnschedulableTimeout            time.Duration                   `yaml:"unschedulableTimeout,omitempty"`            // how long a checker pod may be unschedulable before its run fails. zero fails the run as soon as the scheduler can not place the pod
+	ImagePullGracePeriod            time.Duration                   `yaml:"imagePullGracePeriod,omitempty"`            // how long a checker pod may fail to pull its image before its run fails. defaults to 30s
+	MaintenanceWindows              []khcheckv1.MaintenanceWindow   `yaml:"maintenanceWindows,omitempty"`              // maintenance windows that apply to every check
+	RunIntervalJitterPercent        int                             `yaml:"runIntervalJitterPercent,omitempty"`        // the percentage of its run interval within which the first run of a check is randomly delayed, unless the khcheck sets runIntervalJitter
+	MaxConcurrentChecks             int                             `yaml:"maxConcurrentChecks,omitempty"`             // the maximum number of checker pods that run at once across all namespaces. zero is unlimited
+	MaxConcurrentChecksPerNamespace int                             `yaml:"maxConcurrentChecksPerNamespace,omitempty"` // the maximum number of checker pods that run at once in a single namespace. zero is unlimited
+	NamespaceQuotas                 NamespaceQuotas                 `yaml:"namespaceQuotas,omitempty"`                 // limits on the number of checker pods and the resources they request in each namespace
+	ReportTokenAuth                 bool                            `yaml:"reportTokenAuth,omitempty"`                 // require checker pods of all checks and jobs to authenticate their reports with a service account token
+	ReportClientCerts               external.ClientCertSettings     `yaml:"reportClientCerts,omitempty"`               // settings for issuing client certificates to checker pods and requiring them for reports
+	PodDefaults                     external.PodDefaults            `yaml:"podDefaults,omitempty"`                     // settings merged into every checker pod unless its khcheck overrides them
+	CheckNetworkPolicy              external.NetworkPolicySettings  `yaml:"checkNetworkPolicy,omitempty"`              // settings for the NetworkPolicies created for checker pods
+	AllowedImageRegistries          []string                        `yaml:"allowedImageRegistries,omitempty"`          // the image registries and prefixes that checker pods may use images from. empty allows every image
+	CheckServiceAccounts            external.ServiceAccountSettings `yaml:"checkServiceAccounts,omitempty"`            // settings for the service accounts that checker pods run under
+	HostAccess                      external.HostAccessSettings     `yaml:"hostAccess,omitempty"`                      // settings that gate checker pods which set hostNetwork or hostPID
+	PodSecurity                     external.PodSecuritySettings    `yaml:"podSecurity,omitempty"`                     // settings for hardening checker pods to meet a Pod Security Standards level
+	ImageVerification               imageverify.Config              `yaml:"imageVerification,omitempty"`               // settings for verifying the cosign signatures of checker images before they are run
+	Tracing                         tracing.Config                  `yaml:"tracing,omitempty"`                         // settings for exporting traces of check runs over OTLP
+	Redaction                       redact.Config                   `yaml:"redaction,omitempty"`                       // settings for scrubbing secrets out of reported errors
+	LeaseName                       string                          `yaml:"leaseName,omitempty"`                       // the name of the Lease used for master election
+	LeaseDuration                   time.Duration                   `yaml:"leaseDuration,omitempty"`                   // how long the master lease is valid before another instance may take it over
+	LeaseRenewDeadline              time.Duration                   `yaml:"leaseRenewDeadline,omitempty"`              // how long the master retries renewing its lease before giving up master
+	LeaseRetryPeriod                time.Duration                   `yaml:"leaseRetryPeriod,omitempty"`                // how long to wait between attempts to acquire or renew the master lease
+	Sharding                        ShardingConfig                  `yaml:"sharding,omitempty"`                        // settings for spreading checks across every Kuberhealthy pod. read at startup
+	StateStorage                    statestore.Config               `yaml:"stateStorage,omitempty"`                    // settings for the storage backend that khstates are kept in
+	KubeClient                      kubeClient.Config               `yaml:"kubeClient,omitempty"`                      // the rate limits, timeout and retries of requests to the Kubernetes API. read at startup
+	Federation                      federation.Config               `yaml:"federation,omitempty"`                      // settings for serving the merged status of remote Kuberhealthy instances
+	StatusPush                      federation.PushConfig           `yaml:"statusPush,omitempty"`                      // settings for pushing our status to a central collector
+	ClusterName                     string                          `yaml:"clusterName,omitempty"`                     // the name of the cluster added to every metric, status, notification and exported result
+	Environment                     string                          `yaml:"environment,omitempty"`                     // the environment of the cluster, such as production, added alongside the cluster name
+	CheckLabels                     []string                        `yaml:"checkLabels,omitempty"`                     // the khcheck labels or annotations, such as team, added as labels to the metrics and notifications of each check
+	ConfigResource                  string                          `yaml:"configResource,omitempty"`                  // the name of a cluster scoped KuberhealthyConfig resource whose spec is merged over this config file
+	TargetNamespace                 string                          `yaml:"namespace"`                                 // TargetNamespace sets the namespace that Kuberhealthy will operate in.  By default, this is blank, which means
 	// all namespaces.  However, for multi-tennant environments you may wish to set this.
 }
 
+// tlsEnabled indicates if the web server should be served over TLS
+func (c *Config) tlsEnabled() bool {
+	return len(c.TLSCertFile) > 0 && len(c.TLSKeyFile) > 0
+}
+
+// runHistoryLimit returns the number of runs to keep in the run history of each khstate
+func (c *Config) runHistoryLimit() int {
+	if c.MaxRunHistory == 0 {
+		return defaultMaxRunHistory
+	}
+	return c.MaxRunHistory
+}
+
+// runIntervalJitter returns the window within which the first run of a check with the supplied run interval is
+// randomly delayed
+func (c *Config) runIntervalJitter(runInterval time.Duration) time.Duration {
+	if c.RunIntervalJitterPercent <= 0 {
+		return 0
+	}
+	return runInterval * time.Duration(c.RunIntervalJitterPercent) / 100
+}
+
+// failureLogLines returns the number of lines of checker pod logs to attach to the errors of failed runs
+func (c *Config) failureLogLines() int {
+	if c.FailureLogLines == 0 {
+		return defaultFailureLogLines
+	}
+	return c.FailureLogLines
+}
+
+// imagePullGracePeriod returns how long a checker pod may fail to pull its image before its run fails
+func (c *Config) imagePullGracePeriod() time.Duration {
+	if c.ImagePullGracePeriod <= 0 {
+		return defaultImagePullGracePeriod
+	}
+	return c.ImagePullGracePeriod
+}
+
+// failureLogMaxBytes returns the maximum size of the checker pod logs attached to the errors of failed runs
+func (c *Config) failureLogMaxBytes() int {
+	if c.FailureLogMaxBytes <= 0 {
+		return defaultFailureLogMaxBytes
+	}
+	return c.FailureLogMaxBytes
+}
+
+// maxFailedPodAge returns the maximum age of failed checker pods before they are reaped
+func (c *Config) maxFailedPodAge() time.Duration {
+	if c.MaxFailedPodAge == 0 {
+		return c.MaxCheckPodAge
+	}
+	return c.MaxFailedPodAge
+}
+
+// khStateReapGracePeriod returns how long khstates of deleted khchecks and khjobs are kept before they are deleted
+func (c *Config) khStateReapGracePeriod() time.Duration {
+	if c.KHStateReapGracePeriod <= 0 {
+		return defaultKHStateReapGracePeriod
+	}
+	return c.KHStateReapGracePeriod
+}
+
+// stateWriteBatchInterval returns how long writes to the same khstate are collected before they are written as one
+// patch.  Zero writes right away.
+func (c *Config) stateWriteBatchInterval() time.Duration {
+	if c.StateWriteBatchInterval == 0 {
+		return defaultStateWriteBatchInterval
+	}
+	if c.StateWriteBatchInterval < 0 {
+		return 0
+	}
+	return c.StateWriteBatchInterval
+}
+
+// shutdownDrainTimeout returns how long runs in flight may take to finish when kuberhealthy shuts down.  The timeout
+// is capped so that checks can still be stopped within the termination grace period.
+func (c *Config) shutdownDrainTimeout() time.Duration {
+	if c.ShutdownDrainTimeout == 0 {
+		return defaultShutdownDrainTimeout
+	}
+	if c.ShutdownDrainTimeout < 0 {
+		return 0
+	}
+	if c.ShutdownDrainTimeout > maxShutdownDrainTimeout {
+		return maxShutdownDrainTimeout
+	}
+	return c.ShutdownDrainTimeout
+}
+
+// reaperNamespaces returns the namespaces the reaper cleans up.  When no namespaces are configured, the reaper
+// cleans up the namespace Kuberhealthy targets, which is every namespace when it is blank.
+func (c *Config) reaperNamespaces(targetNamespace string) []string {
+	if len(c.ReaperNamespaces) == 0 {
+		return []string{targetNamespace}
+	}
+	return c.ReaperNamespaces
+}
+
+// liveSettings returns a copy of the config with every setting that is applied without restarting checks cleared.
+// These settings are read each time they are used, or are applied to the running instance when the config is
+// reloaded.
+func (c Config) liveSettings() Config {
+	c.LogLevel = ""
+	c.MaxKHJobAge = 0
+	c.MaxCheckPodAge = 0
+	c.MaxCompletedPodCount = 0
+	c.MaxErrorPodCount = 0
+	c.CheckReaperRunInterval = 0
+	c.MaxFailedPodAge = 0
+	c.ReaperNamespaces = nil
+	c.ReaperDryRun = false
+	c.KHStateReapGracePeriod = 0
+	c.StateWriteBatchInterval = 0
+	c.ShutdownDrainTimeout = 0
+	c.StateMetadata = nil
+	c.PromMetricsConfig = metrics.PromMetricsConfig{}
+	c.Notifications = notifications.Config{}
+	c.MaxRunHistory = 0
+	c.MaintenanceWindows = nil
+	c.MaxConcurrentChecks = 0
+	c.MaxConcurrentChecksPerNamespace = 0
+	c.NamespaceQuotas = NamespaceQuotas{}
+	c.Tracing = tracing.Config{}
+	c.Redaction = redact.Config{}
+	c.ClusterName = ""
+	c.Environment = ""
+	c.CheckLabels = nil
+	return c
+}
+
+// checksNeedRestart indicates if the changes between two configs affect how checks are run, so that the checks
+// must be stopped and started again for the new config to take effect.  Other changes are applied to running
+// checks without interrupting them.
+func checksNeedRestart(previous *Config, current *Config) bool {
+	if previous == nil || current == nil {
+		return true
+	}
+	return !reflect.DeepEqual(previous.liveSettings(), current.liveSettings())
+}
+
 // Load loads file from disk
 func (c *Config) Load(file string) error {
 	b, err := os.ReadFile(file)
@@ -43,6 +248,36 @@ func (c *Config) Load(file string) error {
 	return yaml.Unmarshal(b, c)
 }
 
+// LoadResource merges the spec of the named KuberhealthyConfig resource over the config.  Settings that the spec
+// does not set are left as they are.
+func (c *Config) LoadResource(name string) error {
+	if khConfigClient == nil {
+		restConfig, err := kubeClient.RESTConfig(c.kubeConfigFile, c.KubeClient)
+		if err != nil {
+			return err
+		}
+		client, err := khconfigv1.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+		khConfigClient = client
+	}
+
+	khConfig, err := khConfigClient.KuberhealthyConfigs().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return c.mergeSettings(khConfig.Spec.Raw)
+}
+
+// mergeSettings merges settings in the format of the config file, or their JSON equivalent, over the config
+func (c *Config) mergeSettings(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return yaml.Unmarshal(b, c)
+}
+
 // watchConfig watches the target file (not directory) and notfies the supplied channel with the new md5sum
 // when the content changes.  The interval supplied will be how often the file is polled.  To stop the
 // watcher, close the supplied channel.
@@ -100,6 +335,57 @@ func watchConfig(ctx context.Context, filePath string, interval time.Duration) (
 	return c, nil
 }
 
+// watchConfigResource polls the KuberhealthyConfig resource named in the current config and notifies the returned
+// channel with its new resource version when it changes, is created or is deleted.  The watcher stops when the
+// context ends.
+func watchConfigResource(ctx context.Context, interval time.Duration) chan string {
+	c := make(chan string, 1)
+	version := configResourceVersion()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debugln("watchConfigResource: context closed. shutting down output")
+				return
+			case <-ticker.C:
+			}
+
+			newVersion := configResourceVersion()
+			if newVersion == version {
+				continue
+			}
+			version = newVersion
+			log.Debugln("watchConfigResource: KuberhealthyConfig resource has changed to version", version)
+			select {
+			case c <- version:
+			default:
+				log.Debugln("watchConfigResource: skipping reload notification because config reload already queued")
+			}
+		}
+	}()
+
+	return c
+}
+
+// configResourceVersion returns the name and resource version of the KuberhealthyConfig resource named in the current
+// config, or a blank string when none is named or it does not exist
+func configResourceVersion() string {
+	if cfg == nil || len(cfg.ConfigResource) == 0 || khConfigClient == nil {
+		return ""
+	}
+	khConfig, err := khConfigClient.KuberhealthyConfigs().Get(cfg.ConfigResource, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			log.Errorln("watchConfigResource: Error fetching KuberhealthyConfig", cfg.ConfigResource+":", err)
+		}
+		return cfg.ConfigResource
+	}
+	return cfg.ConfigResource + "/" + khConfig.ResourceVersion
+}
+
 // startConfigReloadMonitoring watches the target filepath for changes and smooths the output so
 // that multiple signals do not come too rapidly.  Call the returned CancelFunc to shutdown
 // all the background routines safely.
@@ -120,6 +406,9 @@ func startConfigReloadMonitoringWithSmoothing(ctx context.Context, filePath stri
 		return outChan, err
 	}
 
+	// begin watching the KuberhealthyConfig resource for changes in the background
+	resourceNotificationChan := watchConfigResource(ctx, configResourcePollInterval)
+
 	// spawn a go routine to watch for notifications and send them every interval
 	go func(ctx context.Context, fsNotificationChan chan string) {
 		for {
@@ -131,6 +420,9 @@ func startConfigReloadMonitoringWithSmoothing(ctx context.Context, filePath stri
 			case <-fsNotificationChan:
 				outChan <- struct{}{}
 				log.Debugln("configReloader: configuration file hash has changed")
+			case <-resourceNotificationChan:
+				outChan <- struct{}{}
+				log.Debugln("configReloader: KuberhealthyConfig resource has changed")
 			default:
 				log.Debugln("configReloader: no configuration reload this tick")
 			}
@@ -140,8 +432,9 @@ func startConfigReloadMonitoringWithSmoothing(ctx context.Context, filePath stri
 	return outChan, nil
 }
 
-// configReloadNotifier watchers for events in file, reloads the configuration, and notifies upstream to restart checks
-func configReloadNotifier(ctx context.Context, notifyChan chan struct{}) {
+// configReloadNotifier watchers for events in file, reloads the configuration, and notifies upstream of the change.
+// true is sent when the checks must be restarted for the new configuration to take effect.
+func configReloadNotifier(ctx context.Context, notifyChan chan bool) {
 
 	outChan, err := startConfigReloadMonitoring(ctx, configPath)
 	if err != nil {
@@ -163,6 +456,7 @@ func configReloadNotifier(ctx context.Context, notifyChan chan struct{}) {
 		log.Debugln("configReloader: loading new configuration")
 
 		// setup config
+		previousCfg := cfg
 		err := setUpConfig()
 		if err != nil {
 			log.Errorln("configReloader: Error reloading and setting up config:", err)
@@ -171,14 +465,11 @@ func configReloadNotifier(ctx context.Context, notifyChan chan struct{}) {
 		log.Debugln("configReloader: loaded new configuration:", cfg)
 
 		// reparse and set logging level
-		parsedLogLevel, err := log.ParseLevel(cfg.LogLevel)
+		err = setLogLevel()
 		if err != nil {
 			log.Warningln("Unable to parse log-level flag: ", err)
-		} else {
-			log.Infoln("Setting log level to:", parsedLogLevel)
-			log.SetLevel(parsedLogLevel)
 		}
-		notifyChan <- struct{}{}
+		notifyChan <- checksNeedRestart(previousCfg, cfg)
 	}
 	log.Infoln("configReloader: shutting down because no more signals are coming from outChan")
 }
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
 )
 
 func TestRenderConfig(t *testing.T) {
@@ -122,3 +124,62 @@ func tempFileWriter(testFile string, t *testing.T) error {
 
 	return nil
 }
+
+// TestChecksNeedRestart ensures that only changes to settings that checks are configured with restart the checks
+func TestChecksNeedRestart(t *testing.T) {
+	previous := &Config{
+		LogLevel:            "info",
+		MaxCheckPodAge:      time.Hour,
+		MaxConcurrentChecks: 5,
+		PodDefaults:         external.PodDefaults{PriorityClassName: "low"},
+	}
+
+	current := *previous
+	current.LogLevel = "debug"
+	current.MaxCheckPodAge = time.Hour * 2
+	current.MaxConcurrentChecks = 10
+	current.Notifications.Slack.WebhookURL = "https://hooks.slack.com/services/example"
+	if checksNeedRestart(previous, &current) {
+		t.Fatal("Expected changes to log level, reaper, concurrency and notification settings to be applied without restarting checks")
+	}
+
+	current.PodDefaults.PriorityClassName = "high"
+	if !checksNeedRestart(previous, &current) {
+		t.Fatal("Expected a change to the pod defaults of checker pods to restart checks")
+	}
+
+	if !checksNeedRestart(nil, &current) {
+		t.Fatal("Expected checks to be restarted when there is no previous config")
+	}
+}
+
+// TestMergeSettings ensures that the spec of a KuberhealthyConfig resource only overrides the settings it sets
+func TestMergeSettings(t *testing.T) {
+	c := Config{
+		LogLevel:         "info",
+		MaxCheckPodAge:   time.Hour,
+		MaxErrorPodCount: 4,
+		PodDefaults:      external.PodDefaults{PriorityClassName: "low"},
+	}
+
+	spec := []byte(`{"logLevel":"debug","maxCheckPodAge":"2h","podDefaults":{"nodeSelector":{"pool":"monitoring"}}}`)
+	err := c.mergeSettings(spec)
+	if err != nil {
+		t.Fatal("Failed to merge settings:", err)
+	}
+
+	if c.LogLevel != "debug" || c.MaxCheckPodAge != time.Hour*2 {
+		t.Fatalf("Expected the settings of the spec to be merged but found logLevel %s and maxCheckPodAge %s", c.LogLevel, c.MaxCheckPodAge)
+	}
+	if c.MaxErrorPodCount != 4 || c.PodDefaults.PriorityClassName != "low" {
+		t.Fatal("Expected settings that the spec does not set to be kept")
+	}
+	if c.PodDefaults.NodeSelector["pool"] != "monitoring" {
+		t.Fatal("Expected nested settings of the spec to be merged")
+	}
+
+	err = c.mergeSettings(nil)
+	if err != nil {
+		t.Fatal("Expected an empty spec to be ignored:", err)
+	}
+}
@@ -15,31 +15,73 @@ import (
 )
 
 // setCheckStateResource puts a check state's state into the specified CRD resource.  It sets the AuthoritativePod
-// to the server's hostname and sets the LastUpdate time to now.
-func setCheckStateResource(checkName string, checkNamespace string, state khstatev1.WorkloadDetails) error {
+// to the server's hostname and sets the LastUpdate time to now.  The run history of the existing state is kept
+// and, if a run record is supplied, the run is added to it.  Unless the settings of the check on the state are known,
+// the ones already on the khstate are kept.
+func setCheckStateResource(checkName string, checkNamespace string, state khstatev1.WorkloadDetails, run *khstatev1.RunRecord, settingsKnown bool) error {
 
 	name := sanitizeResourceName(checkName)
 
-	// we must fetch the existing state to use the current resource version
-	// int found within
-	existingState, err := khStateClient.KuberhealthyStates(checkNamespace).Get(name, metav1.GetOptions{})
-	if err != nil {
-		return errors.New("Error retrieving CRD for: " + name + " " + err.Error())
-	}
-	resourceVersion := existingState.GetResourceVersion()
-
 	// set the pod name that wrote the khstate
 	state.AuthoritativePod = podHostname
 	now := metav1.Now() // set the time the khstate was last
 	state.LastRun = &now
 
-	khState := khstatev1.NewKuberhealthyState(name, state)
-	khState.SetResourceVersion(resourceVersion)
-	// TODO - if "try again" message found in error, then try again
+	// the new state is built from the latest khstate when the batched writes of the khstate are applied
+	var existingState khstatev1.KuberhealthyState
+	updatedState, err := stateWrites.write(checkNamespace, name, func(khState *khstatev1.KuberhealthyState) error {
+		existingState = *khState.DeepCopy()
+		khState.Spec = mergeRunState(existingState.Spec, state, run, settingsKnown)
 
-	log.Debugln(checkNamespace, checkName, "writing khstate with ok:", state.OK, "and errors:", state.Errors, "at last run:", state.LastRun)
-	_, err = khStateClient.KuberhealthyStates(checkNamespace).Update(&khState)
-	return err
+		log.Debugln(checkNamespace, checkName, "writing khstate with ok:", state.OK, "and errors:", state.Errors, "at last run:", state.LastRun)
+		return nil
+	})
+	if err != nil {
+		return errors.New("Error writing khstate for: " + name + " " + err.Error())
+	}
+
+	// publish the conditions of the check on the status subresource.  The state itself has already been written,
+	// so a failure here is logged rather than retried.
+	updatedState.Status = newStateStatus(existingState.Status, updatedState.Spec, run, updatedState.GetGeneration(), now)
+	_, err = khStateClient.KuberhealthyStates(checkNamespace).UpdateStatus(&updatedState)
+	if err != nil {
+		log.Errorln("Error updating khstate status for", checkNamespace+"/"+checkName+":", err)
+	}
+
+	// publish the same conditions on the khcheck, so that kubectl wait can be used on it
+	err = setCheckStatus(checkName, checkNamespace, updatedState.Status)
+	if err != nil {
+		log.Errorln("Error updating khcheck status for", checkNamespace+"/"+checkName+":", err)
+	}
+
+	// let notification sinks know if the reported state of the check has changed between OK and failing
+	var podName string
+	if run != nil {
+		podName = run.Pod
+	}
+	notifyStateTransition(checkName, checkNamespace, reportedState(existingState.Spec), reportedState(updatedState.Spec), podName)
+	return nil
+}
+
+// mergeRunState returns the details of a khstate after the supplied state and run are stored on top of its existing
+// details.  When the check runs on another instance, its settings are not known to the instance storing the state,
+// so the settings already on the khstate are carried forward.
+func mergeRunState(existing khstatev1.WorkloadDetails, details khstatev1.WorkloadDetails, run *khstatev1.RunRecord, settingsKnown bool) khstatev1.WorkloadDetails {
+	if !settingsKnown {
+		details.FailureThreshold = existing.FailureThreshold
+		details.Severity = existing.Severity
+		details.EffectiveRunInterval = existing.EffectiveRunInterval
+	}
+
+	// count the failed runs in a row towards the failure threshold
+	details.ConsecutiveFailures = countConsecutiveFailures(existing, details, run)
+
+	// carry forward the run history and record this run if it has completed
+	details.RunHistory = existing.RunHistory
+	if run != nil {
+		details.RunHistory = appendRunHistory(details.RunHistory, *run, cfg.runHistoryLimit())
+	}
+	return details
 }
 
 // sanitizeResourceName cleans up the check names for use in CRDs.
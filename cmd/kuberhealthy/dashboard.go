@@ -0,0 +1,26 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardPath is the path that the web dashboard is served on
+const dashboardPath = "/ui/"
+
+// dashboardFiles holds the static files of the web dashboard.  The dashboard renders the JSON status page in
+// the browser, so it needs no server side support beyond serving these files.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler returns a handler that serves the web dashboard
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		// the embedded directory always exists, so this can only happen if the embed directive is broken
+		panic("dashboard files are missing from the binary: " + err.Error())
+	}
+	return http.StripPrefix(dashboardPath, http.FileServer(http.FS(files)))
+}
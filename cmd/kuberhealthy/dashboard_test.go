@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDashboardHandler ensures that the embedded dashboard is served
+func TestDashboardHandler(t *testing.T) {
+	server := httptest.NewServer(dashboardHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + dashboardPath)
+	if err != nil {
+		t.Fatal("Failed to fetch dashboard:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("Failed to read dashboard:", err)
+	}
+	if !strings.Contains(string(b), "<title>Kuberhealthy</title>") {
+		t.Fatal("Expected the dashboard page to be served")
+	}
+}
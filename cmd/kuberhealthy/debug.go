@@ -0,0 +1,62 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// goroutineDumpPath is the path of the endpoint that dumps the stack of every goroutine as text
+const goroutineDumpPath = "/debug/goroutines"
+
+// publishDebugVars publishes runtime values that help track down resource growth alongside the memstats and
+// cmdline variables that expvar publishes on its own
+func publishDebugVars(k *Kuberhealthy) {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("checks", expvar.Func(func() interface{} {
+		return len(k.Checks)
+	}))
+}
+
+// newDebugMux creates a mux that serves pprof profiles, expvar variables and goroutine dumps
+func newDebugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc(goroutineDumpPath, goroutineDumpHandler)
+	return mux
+}
+
+// goroutineDumpHandler writes the full stack of every goroutine, in the same format as an unrecovered panic
+func goroutineDumpHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	err := runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	if err != nil {
+		log.Errorln("Error writing goroutine dump:", err)
+	}
+}
+
+// StartDebugServer serves the debug endpoints on their own listener so that they are never exposed on the status
+// page.  The server is restarted any time it exits.
+func StartDebugServer(listenAddress string) {
+	mux := newDebugMux()
+	for {
+		log.Infoln("Starting debug endpoints on", listenAddress)
+		err := http.ListenAndServe(listenAddress, mux)
+		if err != nil {
+			log.Errorln("Debug server ERROR:", err)
+		}
+		time.Sleep(time.Second / 2)
+	}
+}
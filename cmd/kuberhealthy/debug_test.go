@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDebugMux ensures that the debug mux serves pprof, expvar and goroutine dumps
+func TestDebugMux(t *testing.T) {
+	testCases := []struct {
+		path     string
+		contains string
+	}{
+		{path: "/debug/pprof/", contains: "goroutine"},
+		{path: "/debug/vars", contains: "memstats"},
+		{path: goroutineDumpPath, contains: "TestDebugMux"},
+	}
+
+	mux := newDebugMux()
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected %s to return %d but got %d", tc.path, http.StatusOK, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), tc.contains) {
+			t.Fatalf("Expected %s to contain %q but it did not", tc.path, tc.contains)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/federation"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+)
+
+// federationPath is the web server path that serves the merged status of all federated clusters
+const federationPath = "/federation"
+
+// federationMetricsPath is the web server path that serves the metrics of all federated clusters
+const federationMetricsPath = "/federation/metrics"
+
+// federationPushPath is the web server path that federated clusters push their status to
+const federationPushPath = "/federation/clusters/{name}"
+
+// federationMaxPushBytes is the largest status a federated cluster may push
+const federationMaxPushBytes = 32 << 20
+
+// startFederation polls the status pages of the configured remote clusters until the context is canceled
+func (k *Kuberhealthy) startFederation(ctx context.Context) {
+	federator, err := federation.NewFederator(cfg.Federation)
+	if err != nil {
+		log.Errorln("Error setting up federation:", err)
+		return
+	}
+	k.federator = federator
+	go federator.Start(ctx)
+}
+
+// startStatusPush pushes the status of this instance to the configured collector until the context is canceled.
+// Every instance serves the same status, so only the master pushes it.
+func (k *Kuberhealthy) startStatusPush(ctx context.Context) {
+	pusher, err := federation.NewPusher(cfg.StatusPush, func() (health.State, bool) {
+		if !isMaster {
+			return health.State{}, false
+		}
+		return k.getCurrentState([]string{}), true
+	})
+	if err != nil {
+		log.Errorln("Error setting up status push:", err)
+		return
+	}
+	k.statusPusher = pusher
+	go pusher.Start(ctx)
+}
+
+// federationPushHandler accepts the status pushed by a federated cluster that can not be polled
+func (k *Kuberhealthy) federationPushHandler(w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	log.Infoln("Client connected to federation push endpoint for cluster", name, "from", r.RemoteAddr, r.UserAgent())
+	if k.federator == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	state := health.State{}
+	err := json.NewDecoder(io.LimitReader(r.Body, federationMaxPushBytes)).Decode(&state)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("failed to decode status pushed by cluster %s: %w", name, err)
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	err = k.federator.Receive(name, token, state)
+	switch {
+	case errors.Is(err, federation.ErrUnknownCluster):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, federation.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+	if err != nil {
+		return fmt.Errorf("rejected status pushed by cluster %s: %w", name, err)
+	}
+	return nil
+}
+
+// federationHandler writes the merged status of all federated clusters to the caller as JSON
+func (k *Kuberhealthy) federationHandler(w http.ResponseWriter, r *http.Request) error {
+	log.Infoln("Client connected to federation status page from", r.RemoteAddr, r.UserAgent())
+	if k.federator == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	b, err := json.MarshalIndent(k.federator.CurrentState(), "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(b)
+	return err
+}
+
+// federationMetricsHandler writes the metrics of all federated clusters to the caller, with a cluster label on
+// every sample
+func (k *Kuberhealthy) federationMetricsHandler(w http.ResponseWriter, r *http.Request) error {
+	log.Infoln("Client connected to federation metrics endpoint from", r.RemoteAddr, r.UserAgent())
+	if k.federator == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	state := k.federator.CurrentState()
+	clusters := make(map[string]metrics.ClusterStatus, len(state.Clusters))
+	for name, clusterState := range state.Clusters {
+		status := metrics.ClusterStatus{Up: clusterState.Reachable}
+		if !clusterState.LastUpdated.IsZero() {
+			s := clusterState.State
+			status.State = &s
+		}
+		clusters[name] = status
+	}
+
+	var m string
+	if metrics.AcceptsOpenMetrics(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", metrics.OpenMetricsContentType)
+		m = metrics.GenerateClusterOpenMetrics(clusters, cfg.PromMetricsConfig)
+	} else {
+		w.Header().Set("Content-Type", metrics.PrometheusContentType)
+		m = metrics.GenerateClusterMetrics(clusters, cfg.PromMetricsConfig)
+	}
+	_, err := w.Write([]byte(m))
+	return err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/reportpb"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// maxStreamedReportErrors is the maximum number of errors that a streamed report may contain
+const maxStreamedReportErrors = 10000
+
+// reportServer serves the gRPC reporting API.  Reports go through the same authentication and validation as reports
+// posted to the /externalCheckStatus endpoint.
+type reportServer struct {
+	reportpb.UnimplementedReportServiceServer
+	kh *Kuberhealthy
+}
+
+// Report stores the result of a check run that was sent in a single message
+func (s *reportServer) Report(ctx context.Context, req *reportpb.ReportRequest) (*reportpb.ReportResponse, error) {
+	requestID := "grpc: " + uuid.New().String()
+
+	podReport, err := s.kh.authenticateReport(ctx, requestID, grpcReportCredentials(ctx))
+	if err != nil {
+		return nil, reportGRPCError(err)
+	}
+	requestID = requestID + " (" + podReport.Namespace + "/" + podReport.Name + ")"
+
+	err = s.kh.storeReport(requestID, podReport, status.Report{OK: req.GetOk(), Errors: req.GetErrors()})
+	if err != nil {
+		return nil, reportGRPCError(err)
+	}
+	s.kh.externalCheckReportHandlerLog(requestID, "Request completed successfully.")
+	return &reportpb.ReportResponse{}, nil
+}
+
+// StreamReport stores the result of a check run that was sent as a stream of chunks.  The report is authenticated
+// before any chunk is read, so that unauthenticated clients can not stream large payloads into Kuberhealthy.
+func (s *reportServer) StreamReport(stream reportpb.ReportService_StreamReportServer) error {
+	requestID := "grpc: " + uuid.New().String()
+	ctx := stream.Context()
+
+	podReport, err := s.kh.authenticateReport(ctx, requestID, grpcReportCredentials(ctx))
+	if err != nil {
+		return reportGRPCError(err)
+	}
+	requestID = requestID + " (" + podReport.Namespace + "/" + podReport.Name + ")"
+
+	// combine the chunks into a single report. OK is read from the first chunk.
+	var report status.Report
+	var chunks int
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			s.kh.externalCheckReportHandlerLog(requestID, "Failed to receive report chunk:", err)
+			return err
+		}
+		if chunks == 0 {
+			report.OK = chunk.GetOk()
+		}
+		chunks++
+		report.Errors = append(report.Errors, chunk.GetErrors()...)
+		if len(report.Errors) > maxStreamedReportErrors {
+			s.kh.externalCheckReportHandlerLog(requestID, "Client streamed more than", maxStreamedReportErrors, "errors")
+			return grpcstatus.Errorf(codes.InvalidArgument, "report has more than %d errors", maxStreamedReportErrors)
+		}
+	}
+	if chunks == 0 {
+		s.kh.externalCheckReportHandlerLog(requestID, "Client closed the report stream without sending a chunk")
+		return grpcstatus.Error(codes.InvalidArgument, "report stream closed without any chunks")
+	}
+	s.kh.externalCheckReportHandlerLog(requestID, "Received report in", chunks, "chunks")
+
+	err = s.kh.storeReport(requestID, podReport, report)
+	if err != nil {
+		return reportGRPCError(err)
+	}
+	s.kh.externalCheckReportHandlerLog(requestID, "Request completed successfully.")
+	return stream.SendAndClose(&reportpb.ReportResponse{})
+}
+
+// ReportProgress stores an interim status update of a check run that is in flight
+func (s *reportServer) ReportProgress(ctx context.Context, req *reportpb.ProgressRequest) (*reportpb.ProgressResponse, error) {
+	requestID := "grpc: " + uuid.New().String()
+
+	podReport, err := s.kh.authenticateReport(ctx, requestID, grpcReportCredentials(ctx))
+	if err != nil {
+		return nil, reportGRPCError(err)
+	}
+	requestID = requestID + " (" + podReport.Namespace + "/" + podReport.Name + ")"
+
+	progress := status.Progress{
+		Message:    req.GetMessage(),
+		Step:       int(req.GetStep()),
+		TotalSteps: int(req.GetTotalSteps()),
+	}
+	err = s.kh.storeProgress(requestID, podReport, progress)
+	if err != nil {
+		return nil, reportGRPCError(err)
+	}
+	s.kh.externalCheckReportHandlerLog(requestID, "Progress update completed successfully.")
+	return &reportpb.ProgressResponse{}, nil
+}
+
+// grpcReportCredentials gathers the credentials of a report from the metadata and peer of a gRPC call
+func grpcReportCredentials(ctx context.Context) reportCredentials {
+	var creds reportCredentials
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("kh-run-uuid"); len(values) > 0 {
+			creds.runUUID = values[0]
+		}
+		if values := md.Get("authorization"); len(values) > 0 {
+			creds.authorization = values[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			creds.remoteAddr = p.Addr.String()
+		}
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			creds.tls = &tlsInfo.State
+		}
+	}
+	return creds
+}
+
+// reportGRPCError converts an error handling a report into a gRPC status error with the code that matches the HTTP
+// status code that the /externalCheckStatus endpoint responds with
+func reportGRPCError(err error) error {
+	switch reportStatusCode(err) {
+	case http.StatusBadRequest:
+		return grpcstatus.Error(codes.InvalidArgument, err.Error())
+	case http.StatusUnauthorized:
+		return grpcstatus.Error(codes.Unauthenticated, err.Error())
+	case http.StatusServiceUnavailable:
+		return grpcstatus.Error(codes.Unavailable, err.Error())
+	default:
+		return grpcstatus.Error(codes.Internal, err.Error())
+	}
+}
+
+// StartGRPCServer serves the gRPC reporting API on the configured gRPC listen address.  It is served over TLS with
+// the certificate of the web server when TLS is enabled, and restarted any time it exits.
+func (k *Kuberhealthy) StartGRPCServer() {
+	log.Infoln("Configuring gRPC reporting server")
+
+	var opts []grpc.ServerOption
+	if cfg.tlsEnabled() {
+		certs, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalln("Error loading TLS certificate for gRPC server:", err)
+		}
+		tlsConfig := certs.tlsConfig()
+		if clientCertIssuer != nil {
+			tlsConfig = withGRPCNextProtos(certs.tlsConfigWithClientCAs(clientCertIssuer.CertPool))
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	for {
+		server := grpc.NewServer(opts...)
+		reportpb.RegisterReportServiceServer(server, &reportServer{kh: k})
+
+		listener, err := net.Listen("tcp", cfg.GRPCListenAddress)
+		if err == nil {
+			log.Infoln("Starting gRPC reporting services on port", cfg.GRPCListenAddress)
+			err = server.Serve(listener)
+		}
+		if err != nil {
+			log.Errorln("gRPC server ERROR:", err)
+		}
+		time.Sleep(time.Second / 2)
+	}
+}
+
+// withGRPCNextProtos ensures that the TLS configs returned for each client negotiate HTTP/2 with ALPN.  The gRPC
+// credentials only add h2 to the top level config, which is replaced for every client when client certificates
+// are verified.
+func withGRPCNextProtos(config *tls.Config) *tls.Config {
+	getConfigForClient := config.GetConfigForClient
+	if getConfigForClient == nil {
+		return config
+	}
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		clientConfig, err := getConfigForClient(hello)
+		if clientConfig != nil {
+			clientConfig.NextProtos = []string{"h2"}
+		}
+		return clientConfig, err
+	}
+	return config
+}
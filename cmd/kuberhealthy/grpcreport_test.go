@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// TestGRPCReportCredentials ensures that the run UUID, token, address and TLS state of gRPC reports are found
+func TestGRPCReportCredentials(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("kh-run-uuid", "run-uuid", "authorization", "Bearer token"))
+	ctx = peer.NewContext(ctx, &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.ParseIP("10.0.0.12"), Port: 43210},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{ServerName: "kuberhealthy"}},
+	})
+
+	creds := grpcReportCredentials(ctx)
+	if creds.runUUID != "run-uuid" || creds.authorization != "Bearer token" || creds.remoteAddr != "10.0.0.12:43210" {
+		t.Fatalf("Unexpected report credentials: %+v", creds)
+	}
+	if creds.tls == nil || creds.tls.ServerName != "kuberhealthy" {
+		t.Fatalf("Expected the TLS state of the connection but got %+v", creds.tls)
+	}
+
+	creds = grpcReportCredentials(context.Background())
+	if creds != (reportCredentials{}) {
+		t.Fatalf("Expected no report credentials but got %+v", creds)
+	}
+}
+
+// TestReportGRPCError ensures that report errors are returned with the gRPC code that matches their HTTP status
+func TestReportGRPCError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected codes.Code
+	}{
+		{err: &reportError{statusCode: http.StatusBadRequest, err: errors.New("blank error string")}, expected: codes.InvalidArgument},
+		{err: &reportError{statusCode: http.StatusUnauthorized, err: errors.New("no bearer token")}, expected: codes.Unauthenticated},
+		{err: &reportError{statusCode: http.StatusServiceUnavailable, err: errors.New("khchecks have not been loaded yet")}, expected: codes.Unavailable},
+		{err: errors.New("failed to store check state"), expected: codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		code := grpcstatus.Code(reportGRPCError(tc.err))
+		if code != tc.expected {
+			t.Fatalf("Expected %q to have code %s but got %s", tc.err, tc.expected, code)
+		}
+	}
+}
+
+// TestDefaultExternalCheckGRPCAddress ensures that checks report to the kuberhealthy service on the gRPC port
+func TestDefaultExternalCheckGRPCAddress(t *testing.T) {
+	address, err := defaultExternalCheckGRPCAddress("kuberhealthy", ":9090")
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if address != "kuberhealthy.kuberhealthy.svc.cluster.local:9090" {
+		t.Fatal("Unexpected gRPC reporting address:", address)
+	}
+
+	_, err = defaultExternalCheckGRPCAddress("kuberhealthy", "9090")
+	if err == nil {
+		t.Fatal("Expected an error for a listen address without a port")
+	}
+}
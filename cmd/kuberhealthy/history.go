@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// defaultMaxRunHistory is the number of runs kept in the run history of each khstate when maxRunHistory is not set
+const defaultMaxRunHistory = 10
+
+// newRunRecord creates a run history record from the results of a check or job run
+func newRunRecord(details khstatev1.WorkloadDetails, startTime time.Time, podName string) khstatev1.RunRecord {
+	errors := make([]string, len(details.Errors))
+	copy(errors, details.Errors)
+	return khstatev1.RunRecord{
+		StartTime:     metav1.NewTime(startTime),
+		RunDuration:   details.RunDuration,
+		OK:            details.OK,
+		Errors:        errors,
+		Pod:           podName,
+		UUID:          details.CurrentUUID,
+		FailureReason: details.FailureReason,
+	}
+}
+
+// appendRunHistory adds a run record to the end of a run history and drops the oldest records so that no more
+// than max records are kept.  A max of zero or less disables run history entirely.
+func appendRunHistory(history []khstatev1.RunRecord, record khstatev1.RunRecord, max int) []khstatev1.RunRecord {
+	if max <= 0 {
+		return nil
+	}
+
+	history = append(history, record)
+	if len(history) > max {
+		history = history[len(history)-max:]
+	}
+	return history
+}
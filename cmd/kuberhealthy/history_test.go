@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestAppendRunHistory ensures that run history is trimmed to the configured number of runs
+func TestAppendRunHistory(t *testing.T) {
+
+	var history []khstatev1.RunRecord
+	for i := 0; i < 5; i++ {
+		history = appendRunHistory(history, khstatev1.RunRecord{UUID: strconv.Itoa(i)}, 3)
+	}
+
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 runs in history but found %d", len(history))
+	}
+	if history[0].UUID != "2" || history[2].UUID != "4" {
+		t.Fatalf("Expected the oldest runs to be dropped from history but got %+v", history)
+	}
+
+	history = appendRunHistory(history, khstatev1.RunRecord{UUID: "5"}, -1)
+	if len(history) != 0 {
+		t.Fatalf("Expected run history to be disabled but found %d runs", len(history))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,30 +21,48 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
 	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
 	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/archive"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/federation"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/masterCalculation"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
 )
 
 // Kuberhealthy represents the kuberhealthy server and its checks
 type Kuberhealthy struct {
-	Checks             []*external.Checker
-	ListenAddr         string // the listen address, such as ":80"
-	MetricForwarder    metrics.Client
-	overrideKubeClient *kubernetes.Clientset
-	cancelChecksFunc   context.CancelFunc // invalidates the context of all running checks
-	cancelReaperFunc   context.CancelFunc // invalidates the context of the reaper
-	wg                 sync.WaitGroup     // used to track running checks
-	shutdownCtxFunc    context.CancelFunc // used to shutdown the main control select
-	stateReflector     *StateReflector    // a reflector that can cache the current state of the khState resources
-	TargetNamespace    string             // the namespace that this instance will operate on. to include all namespaces, set this to a blank
-	config             *Config            // the config struct loaded at setup
+	Checks                   []*external.Checker
+	ListenAddr               string // the listen address, such as ":80"
+	MetricForwarder          metrics.Client
+	ResultExporters          []metrics.ResultExporter // exporters that the result of every check and job run is sent to
+	resultArchiver           *archive.Archiver        // writes the result of every run to an object storage bucket, when archival is enabled
+	overrideKubeClient       *kubernetes.Clientset
+	cancelChecksFunc         context.CancelFunc                // invalidates the context of all running checks
+	cancelReaperFunc         context.CancelFunc                // invalidates the context of the reaper
+	wg                       sync.WaitGroup                    // used to track running checks
+	shutdownCtxFunc          context.CancelFunc                // used to shutdown the main control select
+	cancelMasterElectionFunc context.CancelFunc                // used to leave master election and release the master lease
+	cancelShardMembership    context.CancelFunc                // used to leave shard membership and hand our checks to the other members
+	stateReflector           *StateReflector                   // a reflector that can cache the current state of the khState resources
+	khCheckInformer          cache.SharedIndexInformer         // an informer that caches khcheck resources and notifies us of changes to them
+	khCheckLister            khcheckv1.KuberhealthyCheckLister // lists khchecks from the khcheck informer cache
+	podWatcher               *external.PodWatcher              // a shared informer that checks wait on their checker pods through
+	runLimiter               *runLimiter                       // limits the number of checker pods that run at the same time
+	runs                     *runTracker                       // counts the check runs in flight, so that shutdown can wait for them
+	shards                   *shardSet                         // the kuberhealthy pods that checks are spread across, when sharding is enabled
+	stateStream              *stateStream                      // streams check state transitions to connected clients
+	federator                *federation.Federator             // polls the status of remote clusters, when federation is enabled
+	statusPusher             *federation.Pusher                // pushes our status to a central collector, when status push is enabled
+	readiness                readinessCache                    // the result of the last readiness checks
+	TargetNamespace          string                            // the namespace that this instance will operate on. to include all namespaces, set this to a blank
+	config                   *Config                           // the config struct loaded at setup
 }
 
 // NewKuberhealthy creates a new kuberhealthy checker instance restricted to the desired
@@ -53,14 +72,34 @@ func NewKuberhealthy(cfg *Config) *Kuberhealthy {
 		TargetNamespace: cfg.TargetNamespace,
 		ListenAddr:      cfg.ListenAddress,
 		config:          cfg,
+		runLimiter:      newRunLimiter(cfg.MaxConcurrentChecks, cfg.MaxConcurrentChecksPerNamespace),
+		stateStream:     newStateStream(),
+		runs:            newRunTracker(),
+	}
+	if cfg.Sharding.Enabled {
+		kh.shards = newShardSet()
 	}
 	kh.stateReflector = NewStateReflector(kh.TargetNamespace)
+	kh.stateReflector.inMaintenance = kh.checkInMaintenance
+	kh.stateReflector.isPaused = kh.checkPaused
+	kh.stateReflector.checkLabels = kh.checkLabels
+	kh.stateReflector.checkSeverity = kh.checkSeverity
+	kh.stateReflector.onChange = kh.stateChanged
 	return kh
 }
 
+// stateChanged is called with the previous and current version of each khstate that changes.  The change is
+// streamed to connected clients and, when status push is enabled, our status is pushed to the collector.
+func (k *Kuberhealthy) stateChanged(previous *khstatev1.KuberhealthyState, current *khstatev1.KuberhealthyState) {
+	k.stateStream.publishStateChange(previous, current)
+	if k.statusPusher != nil {
+		k.statusPusher.Notify()
+	}
+}
+
 // setCheckExecutionError sets an execution error for a check name in
-// its crd status
-func (k *Kuberhealthy) setCheckExecutionError(checkName string, checkNamespace string, exErr error) error {
+// its crd status and records the failed run that started at startTime
+func (k *Kuberhealthy) setCheckExecutionError(checkName string, checkNamespace string, exErr error, startTime time.Time) error {
 	details := khstatev1.NewWorkloadDetails(khstatev1.KHCheck)
 	check, err := k.getCheck(checkName, checkNamespace)
 	if err != nil {
@@ -70,7 +109,8 @@ func (k *Kuberhealthy) setCheckExecutionError(checkName string, checkNamespace s
 		details.Namespace = check.CheckNamespace()
 	}
 	details.OK = false
-	details.Errors = []string{"Check execution error: " + exErr.Error()}
+	details.Errors = appendFailureLogs([]string{"Check execution error: " + exErr.Error()}, check.FailureLogs())
+	details.FailureReason = external.FailureReasonOf(exErr)
 
 	// we need to maintain the current UUID, which means fetching it first
 	khc, err := k.getCheck(checkName, checkNamespace)
@@ -83,18 +123,22 @@ func (k *Kuberhealthy) setCheckExecutionError(checkName string, checkNamespace s
 		return fmt.Errorf("error when setting execution error on check (getting check state for current UUID) %s %s %w", checkName, checkNamespace, err)
 	}
 	details.CurrentUUID = checkState.CurrentUUID
+	details.RunDuration = time.Since(startTime).String()
+	details.Node = checkerPodNode(details.Namespace, khc.PodName())
 	log.Debugln("Setting execution state of check", checkName, "to", details.OK, details.Errors, details.CurrentUUID, details.GetKHWorkload())
 
 	// store the check state with the CRD
-	err = k.storeCheckState(checkName, checkNamespace, details)
+	run := newRunRecord(details, startTime, khc.PodName())
+	err = k.storeCheckRunState(checkName, checkNamespace, details, &run)
 	if err != nil {
 		return fmt.Errorf("unable to write an execution error to the CRD status with error: %w", err)
 	}
 	return nil
 }
 
-// setJobExecutionError sets an execution error for a job name in its crd status
-func (k *Kuberhealthy) setJobExecutionError(jobName string, jobNamespace string, exErr error) error {
+// setJobExecutionError sets an execution error for a job name in its crd status and records the failed
+// run that started at startTime
+func (k *Kuberhealthy) setJobExecutionError(jobName string, jobNamespace string, exErr error, startTime time.Time) error {
 	details := khstatev1.NewWorkloadDetails(khstatev1.KHJob)
 	job, err := k.getJob(jobName, jobNamespace)
 	if err != nil {
@@ -105,6 +149,7 @@ func (k *Kuberhealthy) setJobExecutionError(jobName string, jobNamespace string,
 	}
 	details.OK = false
 	details.Errors = []string{"Job execution error: " + exErr.Error()}
+	details.FailureReason = external.FailureReasonOf(exErr)
 
 	// we need to maintain the current UUID, which means fetching it first
 	khj, err := k.getJob(jobName, jobNamespace)
@@ -116,11 +161,13 @@ func (k *Kuberhealthy) setJobExecutionError(jobName string, jobNamespace string,
 		return fmt.Errorf("error when setting execution error on job (getting job state for current UUID) %s %s %w", jobName, jobNamespace, err)
 	}
 	details.CurrentUUID = jobState.CurrentUUID
+	details.RunDuration = time.Since(startTime).String()
 
 	log.Debugln("Setting execution state of job", jobName, "to", details.OK, details.Errors, details.CurrentUUID, details.GetKHWorkload())
 
 	// store the check state with the CRD
-	err = k.storeCheckState(jobName, jobNamespace, details)
+	run := newRunRecord(details, startTime, khj.PodName())
+	err = k.storeCheckRunState(jobName, jobNamespace, details, &run)
 	if err != nil {
 		return fmt.Errorf("unable to write an execution error to the CRD status with error: %w", err)
 	}
@@ -139,9 +186,30 @@ func (k *Kuberhealthy) Shutdown(doneChan chan struct{}) {
 		log.Infoln("shutdown: aborting control context")
 		k.shutdownCtxFunc() // stop the control system
 	}
-	time.Sleep(5 * time.Second) // help prevent more checks from starting in a race before control system stop happens
-	log.Infoln("shutdown: stopping checks")
-	k.StopChecks() // stop all checks
+
+	// stop new runs from starting and give the runs in flight a chance to report in before they are interrupted
+	drainTimeout := cfg.shutdownDrainTimeout()
+	log.Infoln("shutdown: waiting up to", drainTimeout, "for check runs in flight to finish")
+	if inFlight := k.runs.drain(drainTimeout); inFlight > 0 {
+		log.Warningln("shutdown:", inFlight, "check runs did not finish in time and will be interrupted")
+	}
+	log.Infoln("shutdown: detaching checks")
+	k.DetachChecks() // stop all checks and leave their runs in flight for the next master
+	if inFlight := k.runs.drain(detachTimeout); inFlight > 0 {
+		log.Warningln("shutdown:", inFlight, "check runs did not detach from their checker pods in time")
+	}
+	if k.resultArchiver != nil {
+		log.Infoln("shutdown: writing archived run results")
+		k.resultArchiver.Shutdown() // write the results of the last runs before exiting
+	}
+	if k.cancelShardMembership != nil {
+		log.Infoln("shutdown: leaving shard membership")
+		k.cancelShardMembership() // hand our checks to the other members now that they are stopped
+	}
+	if k.cancelMasterElectionFunc != nil {
+		log.Infoln("shutdown: releasing master lease")
+		k.cancelMasterElectionFunc() // hand off master responsibilities now that checks are stopped
+	}
 	log.Infoln("shutdown: ready for main program shutdown")
 	doneChan <- struct{}{}
 }
@@ -175,9 +243,34 @@ func (k *Kuberhealthy) StopChecks() {
 	log.Infoln("control: all checks stopped.")
 }
 
+// DetachChecks stops the kuberhealthy check group without removing the checker pods of runs in flight, so that
+// the next master can resume them.  It is used when this Kuberhealthy pod shuts down.
+func (k *Kuberhealthy) DetachChecks() {
+
+	log.Infoln("control:", len(k.Checks), "checks detaching...")
+
+	// detach all checks before their contexts are canceled so that their runs leave their checker pods running
+	for _, c := range k.Checks {
+		c.Detach()
+	}
+	if k.cancelChecksFunc != nil {
+		k.cancelChecksFunc()
+	}
+	for range k.Checks {
+		k.wg.Done()
+	}
+
+	log.Infoln("control: all checks detached.")
+}
+
 // Start inits Kuberhealthy checks and master monitoring
 func (k *Kuberhealthy) Start(ctx context.Context) {
 
+	// if status push is enabled, push our status to the collector whenever a khstate changes
+	if cfg.StatusPush.Enabled {
+		k.startStatusPush(ctx)
+	}
+
 	// start the khState reflector
 	go k.stateReflector.Start()
 
@@ -186,30 +279,81 @@ func (k *Kuberhealthy) Start(ctx context.Context) {
 		k.configureInfluxForwarding()
 	}
 
+	// configure the exporters that check results are sent to, such as datadog
+	k.configureResultExporters()
+
+	// if federation is enabled, poll the status of remote clusters so that it can be served with our own
+	if cfg.Federation.Enabled {
+		k.startFederation(ctx)
+	}
+
 	// Start the web server and restart it if it crashes
 	go k.StartWebServer()
 
+	// if the gRPC reporting API is enabled, serve it alongside the web server
+	if len(cfg.GRPCListenAddress) > 0 {
+		go k.StartGRPCServer()
+	}
+
+	// if debug endpoints are enabled, serve them on their own port for profiling
+	if len(cfg.DebugListenAddress) > 0 {
+		publishDebugVars(k)
+		go StartDebugServer(cfg.DebugListenAddress)
+	}
+
+	// if the admission webhook is enabled, serve it on every kuberhealthy pod
+	if cfg.AdmissionWebhook.Enabled {
+		go StartAdmissionWebhookServer(cfg.AdmissionWebhook)
+	}
+
 	// find all the external checks from the khcheckcrd resources on the cluster and keep them in sync.
 	// use rate limiting to avoid reconfiguration spam
 	maxUpdateInterval := time.Second * 10
 	externalChecksUpdateChan := make(chan struct{}, 50)
 	externalChecksUpdateChanLimited := make(chan struct{}, 50)
 	go notifyChanLimiter(maxUpdateInterval, externalChecksUpdateChan, externalChecksUpdateChanLimited)
+	k.khCheckInformer = newKHCheckInformer(k.TargetNamespace)
+	k.khCheckLister = khcheckv1.NewKuberhealthyCheckLister(k.khCheckInformer.GetIndexer())
 	go k.monitorExternalChecks(ctx, externalChecksUpdateChan)
 
-	// we use two channels to indicate when we gain or lose master status. use rate limiting to avoid
-	// reconfiguration spam
+	// watch the checker pods of every check with one shared informer instead of a watch per run
+	podWatcher, err := external.NewPodWatcher(kubernetesClient, k.TargetNamespace)
+	if err != nil {
+		log.Errorln("Error creating checker pod watcher. Checks will watch their own checker pods:", err)
+	} else {
+		k.podWatcher = podWatcher
+		go podWatcher.Run(ctx.Done())
+	}
+
+	// we use two channels to indicate when we gain or lose master status. The master election runs with
+	// its own context so that the master lease is only released after checks have stopped during shutdown.
 	becameMasterChan := make(chan struct{}, 10)
 	lostMasterChan := make(chan struct{}, 10)
-	go k.masterMonitor(ctx, becameMasterChan, lostMasterChan)
+	masterElectionCtx, masterElectionCtxCancel := context.WithCancel(context.Background())
+	k.cancelMasterElectionFunc = masterElectionCtxCancel
+	go k.masterMonitor(masterElectionCtx, becameMasterChan, lostMasterChan)
+
+	// when sharding is enabled, every instance runs its share of the checks and the master only runs the reapers.
+	// shard membership runs with its own context so that our checks are only handed off after they have stopped.
+	shardMembersChan := make(chan struct{}, 1)
+	if k.sharded() {
+		shardMembershipCtx, shardMembershipCtxCancel := context.WithCancel(context.Background())
+		k.cancelShardMembership = shardMembershipCtxCancel
+		go k.shardMembershipMonitor(shardMembershipCtx, shardMembersChan)
+	}
 
 	// monitor for kuberhealthy jobs and trigger when a new job is added
 	go k.monitorKHJobs(ctx)
 
 	// get notified when kuberhealthy configuration is reloaded
-	configReloadChan := make(chan struct{})
+	configReloadChan := make(chan bool)
 	go configReloadNotifier(ctx, configReloadChan)
 
+	// report that the control loop is making progress, so that the liveness probe can tell if it is deadlocked
+	heartbeatTicker := time.NewTicker(controlLoopHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+	controlLoops.beat("control", controlLoopStallTimeout)
+
 	// loop and select channels to do appropriate thing when:
 	// - master kuberhealthy pod changes
 	// - new khchecks are added or modified
@@ -218,43 +362,77 @@ func (k *Kuberhealthy) Start(ctx context.Context) {
 		select {
 		case <-ctx.Done(): // we are shutting down
 			log.Infoln("control: shutting down from context abort...")
+			controlLoops.stop("control")
 			return
+		case <-heartbeatTicker.C:
+			controlLoops.beat("control", controlLoopStallTimeout)
 		case <-becameMasterChan: // we have become the current master instance and should run checks
+			// when checks are sharded, they run on every instance and only the reapers move to the master
+			if k.sharded() {
+				log.Infoln("control: Became master. Starting reapers.")
+				k.StartReaper(ctx)
+				continue
+			}
 			// reset checks and re-add from configuration settings
 			log.Infoln("control: Became master. Reconfiguring and starting checks.")
 			k.StartChecks(ctx)
 			k.StartReaper(ctx)
 		case <-lostMasterChan: // we are no longer master
+			if k.sharded() {
+				log.Infoln("control: Lost master. Stopping reapers.")
+				k.StopReaper()
+				continue
+			}
 			log.Infoln("control: Lost master. Stopping checks.")
 			k.StopChecks()
 			k.StopReaper()
+		case <-shardMembersChan: // kuberhealthy pods joined or left the shard
+			log.Infoln("control: Witnessed a change of shard members...")
+			if k.shardsChanged() {
+				log.Infoln("control: Rebalancing checks across shard members")
+				k.RestartChecks(ctx)
+			}
 		case <-externalChecksUpdateChanLimited: // external check change detected
 			log.Infoln("control: Witnessed a khcheck resource change...")
 
-			// if we are master, stop, reconfigure our khchecks, and start again with the new configuration
-			if isMaster {
+			// if we run checks, stop, reconfigure our khchecks, and start again with the new configuration
+			if k.runsChecks() {
 				log.Infoln("control: Reloading external check configurations due to khcheck update")
 				k.RestartChecks(ctx)
+			}
+			if isMaster {
 				k.RestartReaper(ctx)
 			}
-		case <-configReloadChan:
+		case restartChecks := <-configReloadChan:
 			log.Infoln("control: Witnessed a kuberhealthy configuration change...")
+			if !k.runsChecks() {
+				continue
+			}
 
-			// if we are master, stop, reconfigure our khchecks, and start again with the new configuration
-			if isMaster {
+			// if settings that checks are configured with changed, stop, reconfigure our khchecks, and start again
+			// with the new configuration. otherwise, the new settings are applied without interrupting running checks.
+			if restartChecks {
 				log.Infoln("control: Reloading external check configurations due to kuberhealthy configuration update")
 				k.RestartChecks(ctx)
+			} else {
+				log.Infoln("control: Applying kuberhealthy configuration update without restarting checks")
+				k.runLimiter.setLimits(cfg.MaxConcurrentChecks, cfg.MaxConcurrentChecksPerNamespace)
+				k.runLimiter.setQuotas(cfg.NamespaceQuotas)
+			}
+			if isMaster {
 				k.RestartReaper(ctx)
 			}
 		}
 	}
 }
 
-// StartReaper starts the check reaper
+// StartReaper starts the check reaper and the khState reaper.  Only the master runs the reapers, including when
+// checks are sharded across every instance.
 func (k *Kuberhealthy) StartReaper(ctx context.Context) {
 	reaperCtx, reaperCtxCancel := context.WithCancel(ctx)
 	k.cancelReaperFunc = reaperCtxCancel
 	go reaper(reaperCtx, k.TargetNamespace)
+	go k.khStateResourceReaper(reaperCtx, k.TargetNamespace)
 }
 
 // StopReaper stops the check reaper
@@ -299,8 +477,37 @@ func (k *Kuberhealthy) khStateResourceReaper(ctx context.Context, namespace stri
 
 }
 
-// reapKHStateResources runs a single audit on khState resources.  Any that don't have a matching khCheck are
-// deleted.
+// khStateReapAction is what the khState reaper does with a khState after an audit
+type khStateReapAction int
+
+const (
+	khStateKeep   khStateReapAction = iota // the khState is left as it is
+	khStateOrphan                          // the khState is marked as orphaned
+	khStateAdopt                           // the khState is no longer orphaned and its mark is removed
+	khStateDelete                          // the khState has been orphaned for longer than the grace period
+)
+
+// khStateReapActionFor determines what the khState reaper does with a khState, based on if its khCheck or khJob
+// still exists and how long it has been orphaned for
+func khStateReapActionFor(khState *khstatev1.KuberhealthyState, found bool, now time.Time, gracePeriod time.Duration) khStateReapAction {
+	_, marked := khState.Annotations[khstatev1.OrphanedAnnotation]
+	orphanedAt, ok := khState.OrphanedSince()
+	switch {
+	case found && marked:
+		return khStateAdopt
+	case found:
+		return khStateKeep
+	case !ok:
+		return khStateOrphan
+	case now.Sub(orphanedAt) > gracePeriod:
+		return khStateDelete
+	}
+	return khStateKeep
+}
+
+// reapKHStateResources runs a single audit on khState resources.  Any that don't have a matching khCheck or khJob
+// are marked as orphaned, which hides them from the status page, and are deleted once they have been orphaned for
+// longer than the grace period.
 func (k *Kuberhealthy) reapKHStateResources(ctx context.Context, namespace string) error {
 
 	// list all khStates in the cluster
@@ -348,9 +555,27 @@ func (k *Kuberhealthy) reapKHStateResources(ctx context.Context, namespace strin
 			}
 		}
 
-		// if we didn't find a matching khCheck or khJob, delete the rogue khState
-		if !foundKHCheck && !foundKHJob {
-			log.Infoln("khState reaper: removing khState", khState.GetName(), "in", khState.GetNamespace())
+		// if we didn't find a matching khCheck or khJob, mark the rogue khState and delete it after the grace period
+		switch khStateReapActionFor(&khState, foundKHCheck || foundKHJob, time.Now(), cfg.khStateReapGracePeriod()) {
+		case khStateOrphan:
+			log.Infoln("khState reaper: marking khState", khState.GetName(), "in", khState.GetNamespace(), "as orphaned")
+			if khState.Annotations == nil {
+				khState.Annotations = make(map[string]string)
+			}
+			khState.Annotations[khstatev1.OrphanedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+			_, err := khStateClient.KuberhealthyStates(khState.GetNamespace()).Update(&khState)
+			if err != nil {
+				log.Errorln(fmt.Errorf("khState reaper: error when marking khstate as orphaned: %w", err))
+			}
+		case khStateAdopt:
+			log.Infoln("khState reaper: khState", khState.GetName(), "in", khState.GetNamespace(), "is no longer orphaned")
+			delete(khState.Annotations, khstatev1.OrphanedAnnotation)
+			_, err := khStateClient.KuberhealthyStates(khState.GetNamespace()).Update(&khState)
+			if err != nil {
+				log.Errorln(fmt.Errorf("khState reaper: error when removing orphaned mark from khstate: %w", err))
+			}
+		case khStateDelete:
+			log.Infoln("khState reaper: removing khState", khState.GetName(), "in", khState.GetNamespace(), "that has been orphaned for longer than", cfg.khStateReapGracePeriod())
 			err := khStateClient.KuberhealthyStates(khState.GetNamespace()).Delete(khState.GetName(), &metav1.DeleteOptions{})
 			if err != nil {
 				log.Errorln(fmt.Errorf("khState reaper: error when removing invalid khstate: %w", err))
@@ -449,72 +674,6 @@ func (k *Kuberhealthy) getKHState(namespace string, checkName string) (khstatev1
 	return khStateClient.KuberhealthyStates(namespace).Get(checkName, metav1.GetOptions{})
 }
 
-// watchForKHCheckChanges watches for changes to khcheck objects and returns them through the specified channel
-func (k *Kuberhealthy) watchForKHCheckChanges(ctx context.Context, c chan struct{}) {
-
-	log.Debugln("Spawned watcher for KH check changes")
-
-	for {
-		log.Debugln("Starting a watch for khcheck object changes")
-
-		// wait a second so we don't retry too quickly on error
-		time.Sleep(time.Second)
-
-		// start a watch on khcheck resources
-		watcher, err := khCheckClient.KuberhealthyChecks(k.TargetNamespace).Watch(metav1.ListOptions{})
-		// watcher, err := watchUnstructuredKHChecks(ctx, k.TargetNamespace)
-		if err != nil {
-			log.Errorln("error creating watcher for khcheck objects:", err)
-			continue
-		}
-
-		// watch for the watcher context to end, or the parent context.  If the parent context ends, we close the watcher.
-		// if the watcher context ends, we shut down this go routine to prevent a leak as it restarts
-		watcherCtx, watcherCtxCancel := context.WithCancel(context.Background())
-		go func(watchCtx context.Context, ctx context.Context, watcher watch.Interface) {
-			select {
-			case <-watchCtx.Done():
-				break
-			case <-ctx.Done():
-				watcher.Stop()
-			}
-			log.Debugln("khcheck change monitor watch stopping")
-		}(watcherCtx, ctx, watcher)
-
-		// loop over results and return them to the calling channel until we hit an error, then close and restart
-		for khc := range watcher.ResultChan() {
-			switch khc.Type {
-			case watch.Added:
-				log.Debugln("khcheck monitor saw an added event")
-				c <- struct{}{}
-			case watch.Modified:
-				log.Debugln("khcheck monitor saw a modified event")
-				c <- struct{}{}
-			case watch.Deleted:
-				log.Debugln("khcheck monitor saw a deleted event")
-				c <- struct{}{}
-			case watch.Error:
-				log.Debugln("khcheck monitor saw an error event")
-				e := khc.Object.(*metav1.Status)
-				log.Errorln("Error when watching for khcheck changes:", e.Reason)
-				continue
-			default:
-				log.Warningln("khcheck monitor saw an unknown event type and ignored it:", khc.Type)
-			}
-		}
-
-		// if the watcher breaks, shutdown the parent context monitor go routine
-		watcherCtxCancel()
-
-		select {
-		case <-ctx.Done():
-			log.Debugln("khcheck monitor closing due to context cancellation")
-			return
-		default:
-		}
-	}
-}
-
 func verifyNewKHJob(khJobName string, khJobNamespace string) bool {
 
 	kj, err := khJobClient.KuberhealthyJobs(khJobNamespace).Get(khJobName, metav1.GetOptions{})
@@ -527,119 +686,83 @@ func verifyNewKHJob(khJobName string, khJobNamespace string) bool {
 	return kj.Spec.Phase == ""
 }
 
-// monitorExternalChecks watches for changes to the external check CRDs
+// monitorExternalChecks watches for changes to the external check CRDs using the khcheck informer.  Informer
+// events are put on a rate limited work queue, whose worker signals the notify channel when checks are added,
+// removed or have their spec changed and triggers runs requested with the run now annotation.
 func (k *Kuberhealthy) monitorExternalChecks(ctx context.Context, notify chan struct{}) {
 
-	// make a map of resource versions so we know when things change
-	knownSettings := make(map[string]khcheckv1.CheckConfig)
-
-	// start watching for events to changes in the background
-	c := make(chan struct{})
-	go k.watchForKHCheckChanges(ctx, c)
-
-	// each time  we see a change in our khcheck structs, we should look at every object to see if something has changed
-	for {
-
-		// wait for the change channel to detect a change before scanning again
-		<-c
-		log.Debugln("Change notification received. Scanning for external check changes...")
-
-		khChecks, err := k.listKHChecks(k.TargetNamespace)
-		if err != nil {
-			log.Errorln("error listing unstructured khChecks: %w", err)
-			continue
-		}
-
-		// this bool indicates if we should send a change signal to the channel
-		var foundChange bool
-
-		// if a khcheck has been deleted, then we signal for change and purge it from the knownSettings map.
-		for mapName := range knownSettings {
-			var existsInItems bool // indicates the item exists in the item listing
-
-			for _, kc := range khChecks.Items {
-
-				itemMapName := kc.Namespace + "/" + kc.Name
-				if itemMapName == mapName {
-					existsInItems = true
-					break
-				}
-			}
-			if !existsInItems {
-				log.Debugln("Detected khcheck deletion for", mapName)
-				delete(knownSettings, mapName)
-				foundChange = true
-			}
-		}
-
-		for _, kc := range khChecks.Items {
-			if err != nil {
-				log.Errorln("Error converting unstructured object to khcheck:", err)
-				continue
-			}
-
-			mapName := kc.Namespace + "/" + kc.Name
-
-			log.Debugln("Scanning khcheck CRD", mapName, "for changes since last seen...")
+	queue := newKHCheckQueue()
+	defer queue.ShutDown()
 
-			if len(kc.Namespace) < 1 {
-				log.Warning("Got khcheck update from object with no namespace...")
-				continue
+	_, err := k.khCheckInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			kc, ok := obj.(*khcheckv1.KuberhealthyCheck)
+			if !ok {
+				log.Warningln("khcheck informer saw an added object that was not a khcheck")
+				return
 			}
-			if len(kc.Name) < 1 {
-				log.Warning("Got khcheck update from object with no name...")
-				continue
+			log.Debugln("khcheck informer saw an added event for", kc.Namespace+"/"+kc.Name)
+			queue.Add(khCheckEvent{kind: khCheckChanged, namespace: kc.Namespace, name: kc.Name})
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			oldKC, ok := oldObj.(*khcheckv1.KuberhealthyCheck)
+			if !ok {
+				log.Warningln("khcheck informer saw an updated object that was not a khcheck")
+				return
 			}
-
-			// if we don't know about this check yet, just store the state and continue.  The check is already
-			// loaded on the first check configuration run.
-			_, exists := knownSettings[mapName]
-			if !exists {
-				log.Debugln("First time seeing khcheck of name", mapName)
-				knownSettings[mapName] = kc.Spec
-				foundChange = true
+			newKC, ok := newObj.(*khcheckv1.KuberhealthyCheck)
+			if !ok {
+				log.Warningln("khcheck informer saw an updated object that was not a khcheck")
+				return
 			}
 
-			// check if run interval has changed
-			if knownSettings[mapName].RunInterval != kc.Spec.RunInterval {
-				log.Debugln("The khcheck run interval for", mapName, "has changed.")
-				foundChange = true
+			// a new value in the run now annotation asks for the check to run right away
+			runNow := newKC.Annotations[khcheckv1.RunNowAnnotation]
+			if len(runNow) > 0 && runNow != oldKC.Annotations[khcheckv1.RunNowAnnotation] {
+				log.Infoln("Run requested by annotation for khcheck", newKC.Namespace+"/"+newKC.Name)
+				queue.Add(khCheckEvent{kind: khCheckRunRequested, namespace: newKC.Namespace, name: newKC.Name})
 			}
 
-			// check if run timeout has changed
-			if knownSettings[mapName].Timeout != kc.Spec.Timeout {
-				log.Debugln("The khcheck timeout for", mapName, "has changed.")
-				foundChange = true
+			// pausing or resuming a check requires a reload of the check
+			if oldKC.Annotations[khcheckv1.PausedAnnotation] != newKC.Annotations[khcheckv1.PausedAnnotation] {
+				log.Debugln("The khcheck paused annotation for", newKC.Namespace+"/"+newKC.Name, "has changed.")
+				queue.Add(khCheckEvent{kind: khCheckChanged, namespace: newKC.Namespace, name: newKC.Name})
+				return
 			}
 
-			// check if extraLabels has changed
-			if !foundChange && !reflect.DeepEqual(knownSettings[mapName].ExtraLabels, kc.Spec.ExtraLabels) {
-				log.Debugln("The khcheck extra labels for", mapName, "has changed.")
-				foundChange = true
+			// resyncs and status-only updates do not require a reload of the check
+			if reflect.DeepEqual(oldKC.Spec, newKC.Spec) {
+				return
 			}
-
-			// check if extraAnnotations has changed
-			if !foundChange && !reflect.DeepEqual(knownSettings[mapName].ExtraAnnotations, kc.Spec.ExtraAnnotations) {
-				log.Debugln("The khcheck extra annotations for", mapName, "has changed.")
-				foundChange = true
+			log.Debugln("The khcheck spec for", newKC.Namespace+"/"+newKC.Name, "has changed.")
+			queue.Add(khCheckEvent{kind: khCheckChanged, namespace: newKC.Namespace, name: newKC.Name})
+		},
+		DeleteFunc: func(obj interface{}) {
+			// deletes may be delivered as a tombstone if the informer missed the delete event
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
 			}
-
-			// check if CheckConfig has changed (PodSpec)
-			if !foundChange && !reflect.DeepEqual(knownSettings[mapName].PodSpec, kc.Spec.PodSpec) {
-				log.Debugln("The khcheck for", mapName, "has changed.")
-				foundChange = true
+			kc, ok := obj.(*khcheckv1.KuberhealthyCheck)
+			if !ok {
+				log.Warningln("khcheck informer saw a deleted object that was not a khcheck")
+				return
 			}
+			log.Debugln("Detected khcheck deletion for", kc.Namespace+"/"+kc.Name)
+			queue.Add(khCheckEvent{kind: khCheckChanged, namespace: kc.Namespace, name: kc.Name})
+		},
+	})
+	if err != nil {
+		log.Errorln("Error adding event handler to khcheck informer:", err)
+		return
+	}
 
-			// finally, update known settings before continuing to the next interval
-			knownSettings[mapName] = kc.Spec
-		}
+	go processKHCheckEvents(queue, func(event khCheckEvent) error {
+		return k.handleKHCheckEvent(event, notify)
+	})
 
-		// if a change was detected, we signal the notify channel
-		if foundChange {
-			log.Debugln("Signaling that a change was found in external check configuration")
-			notify <- struct{}{}
-		}
-	}
+	log.Debugln("Starting khcheck informer")
+	k.khCheckInformer.Run(ctx.Done())
+	log.Debugln("khcheck informer stopped due to context cancellation")
 }
 
 // setExternalChecks syncs up the state of the external-checks installed in this
@@ -648,7 +771,7 @@ func (k *Kuberhealthy) addExternalChecks(ctx context.Context) error {
 
 	log.Debugln("Fetching khcheck configurations...")
 
-	khChecks, err := k.listKHChecks(k.TargetNamespace)
+	khChecks, err := k.cachedKHChecks()
 	if err != nil {
 		return err
 	}
@@ -661,6 +784,12 @@ func (k *Kuberhealthy) addExternalChecks(ctx context.Context) error {
 			log.Errorln("Error converting unstructured object to khcheck:", err)
 			continue
 		}
+
+		// when checks are sharded, checks owned by other kuberhealthy pods are left to them
+		if !k.ownsCheck(kc.Name, kc.Namespace) {
+			log.Debugln("Skipping check", kc.Name, "in namespace", kc.Namespace, "because it is run by another shard member")
+			continue
+		}
 		log.Debugln("Loading check CRD:", kc.Name)
 
 		log.Debugf("External check custom resource loaded: %v", kc)
@@ -679,6 +808,93 @@ func (k *Kuberhealthy) addExternalChecks(ctx context.Context) error {
 
 		log.Debugln("RunInterval for check:", c.CheckName, "set to", c.RunInterval)
 
+		// spread out the first runs of checks so that they do not all start their pods at once
+		c.RunIntervalJitter = cfg.runIntervalJitter(c.RunInterval)
+		if len(kc.Spec.RunIntervalJitter) > 0 {
+			c.RunIntervalJitter, err = time.ParseDuration(kc.Spec.RunIntervalJitter)
+			if err != nil {
+				log.Errorln("Error parsing run interval jitter for check", c.CheckName, "in namespace", c.Namespace, err)
+				c.RunIntervalJitter = cfg.runIntervalJitter(c.RunInterval)
+			}
+		}
+
+		// if a cron schedule is specified, it is used instead of the run interval
+		if len(kc.Spec.Schedule) > 0 {
+			_, err = parseCheckSchedule(kc.Spec.Schedule)
+			if err != nil {
+				log.Errorln("Error parsing schedule for check", c.CheckName, "in namespace", c.Namespace, err)
+				log.Errorln("Defaulting check to its run interval of", c.RunInterval)
+			} else {
+				c.RunSchedule = kc.Spec.Schedule
+				log.Debugln("RunSchedule for check:", c.CheckName, "set to", c.RunSchedule)
+			}
+		}
+
+		// failures are only reported once a check fails this many runs in a row
+		c.FailureThreshold = kc.Spec.FailureThreshold
+
+		// only critical failures make the overall health status fail
+		c.Severity = string(khcheckv1.SeverityCritical)
+		if len(kc.Spec.Severity) > 0 {
+			c.Severity = string(kc.Spec.Severity)
+		}
+
+		// capture the logs of checker pods when their runs fail
+		c.FailureLogLines = int64(cfg.failureLogLines())
+		c.FailureLogMaxBytes = cfg.failureLogMaxBytes()
+
+		// fail runs whose checker pod can not be scheduled or pull its image without waiting for the run timeout
+		c.UnschedulableTimeout = cfg.UnschedulableTimeout
+		c.ImagePullGracePeriod = cfg.imagePullGracePeriod()
+
+		// merge the global pod defaults into the checker pods
+		c.PodDefaults = cfg.PodDefaults
+
+		// require checker pods to authenticate their reports if enabled for all checks or for this check
+		c.ReportTokenAuth = cfg.ReportTokenAuth || kc.Spec.ReportTokenAuth
+
+		// issue client certificates to the checker pods if reports must use mutual TLS
+		c.ClientCertIssuer = clientCertIssuer
+
+		// tell the checker pods where the gRPC reporting API is if it is enabled
+		c.KuberhealthyGRPCAddress = cfg.ExternalCheckGRPCAddress
+
+		// run a checker pod on every node if requested
+		c.RunOnAllNodes = kc.Spec.RunOnAllNodes
+
+		// create a network policy for the checker pods if enabled for all checks or for this check
+		c.NetworkPolicySettings = cfg.CheckNetworkPolicy
+		c.NetworkPolicy = kc.Spec.NetworkPolicy
+		if c.NetworkPolicy == nil && cfg.CheckNetworkPolicy.Enabled {
+			c.NetworkPolicy = &khcheckv1.CheckNetworkPolicy{}
+		}
+
+		// only run images from approved registries
+		c.AllowedImageRegistries = cfg.AllowedImageRegistries
+
+		// only run checker pods under service accounts that they are allowed to use
+		c.ServiceAccountSettings = cfg.CheckServiceAccounts
+
+		// only run checker pods with host access in namespaces that are allowed it
+		c.HostAccessSettings = cfg.HostAccess
+
+		// harden checker pods to meet the pod security level of their namespace
+		c.PodSecurity = cfg.PodSecurity
+
+		// only run checker images that are signed by a trusted signer
+		if imageVerifier != nil {
+			c.ImageVerifier = imageVerifier
+		}
+
+		// record events about runs on the khcheck
+		c.EventRecorder = eventRecorder
+
+		// read the khcheck from the khcheck informer cache instead of the API server
+		c.KHCheckLister = k.khCheckLister
+
+		// wait on checker pods through the shared pod informer
+		c.PodWatcher = k.podWatcher
+
 		// parse the user specified timeout if present
 		c.RunTimeout = DefaultTimeout
 		if len(kc.Spec.Timeout) > 0 {
@@ -702,6 +918,36 @@ func (k *Kuberhealthy) addExternalChecks(ctx context.Context) error {
 		}
 		log.Debugln("External check labels and annotations:", c.ExtraLabels, c.ExtraAnnotations)
 
+		// add the environment of the khcheck to every container of the checker pods
+		c.ExtraEnv = kc.Spec.ExtraEnv
+		c.EnvFrom = kc.Spec.EnvFrom
+
+		// grow the run interval of the check while it keeps failing if requested
+		if kc.Spec.IntervalBackoff != nil {
+			c.MaxRunInterval, err = time.ParseDuration(kc.Spec.IntervalBackoff.MaxInterval)
+			if err != nil {
+				log.Errorln("Error parsing maximum interval of the interval backoff of check", c.CheckName, "in namespace", c.Namespace, err)
+				log.Errorln("Check", c.CheckName, "in namespace", c.Namespace, "will not back off its run interval")
+			} else {
+				c.RunIntervalBackoff = kc.Spec.IntervalBackoff.Multiplier
+				if c.RunIntervalBackoff < 2 {
+					c.RunIntervalBackoff = 2
+				}
+			}
+		}
+
+		// decide what happens when the next run is due while a run is still in flight
+		c.ConcurrencyPolicy = khcheckv1.ConcurrencyPolicyQueue
+		if len(kc.Spec.ConcurrencyPolicy) > 0 {
+			c.ConcurrencyPolicy = kc.Spec.ConcurrencyPolicy
+		}
+
+		// checks can be paused with their spec or an annotation
+		if kc.IsPaused() {
+			log.Infoln("External check", c.CheckName, "in namespace", c.Namespace, "is paused")
+			c.Paused = true
+		}
+
 		// add the check into the checker
 		k.AddCheck(c)
 	}
@@ -731,6 +977,47 @@ func (k *Kuberhealthy) configureJob(job khjobv1.KuberhealthyJob) *external.Check
 
 	log.Debugln("RunTimeout for job:", kj.CheckName, "set to", kj.RunTimeout)
 
+	// wait on checker pods through the shared pod informer
+	kj.PodWatcher = k.podWatcher
+
+	// merge the global pod defaults into the checker pods
+	kj.PodDefaults = cfg.PodDefaults
+
+	// require checker pods to authenticate their reports if enabled for all checks
+	kj.ReportTokenAuth = cfg.ReportTokenAuth
+
+	// issue client certificates to the checker pods if reports must use mutual TLS
+	kj.ClientCertIssuer = clientCertIssuer
+
+	// tell the checker pods where the gRPC reporting API is if it is enabled
+	kj.KuberhealthyGRPCAddress = cfg.ExternalCheckGRPCAddress
+
+	// create a network policy for the checker pods if enabled for all checks
+	kj.NetworkPolicySettings = cfg.CheckNetworkPolicy
+	if cfg.CheckNetworkPolicy.Enabled {
+		kj.NetworkPolicy = &khcheckv1.CheckNetworkPolicy{}
+	}
+
+	// only run images from approved registries
+	kj.AllowedImageRegistries = cfg.AllowedImageRegistries
+
+	// only run checker pods under service accounts that they are allowed to use
+	kj.ServiceAccountSettings = cfg.CheckServiceAccounts
+
+	// only run checker pods with host access in namespaces that are allowed it
+	kj.HostAccessSettings = cfg.HostAccess
+
+	// harden checker pods to meet the pod security level of their namespace
+	kj.PodSecurity = cfg.PodSecurity
+
+	// only run checker images that are signed by a trusted signer
+	if imageVerifier != nil {
+		kj.ImageVerifier = imageVerifier
+	}
+
+	// record events about runs on the khjob
+	kj.EventRecorder = eventRecorder
+
 	// add on extra annotations and labels
 	if kj.ExtraAnnotations != nil {
 		log.Debugln("External job setting extra annotations:", kj.ExtraAnnotations)
@@ -761,6 +1048,8 @@ func (k *Kuberhealthy) StartChecks(ctx context.Context) {
 
 	log.Infoln("control: Reloading check configuration...")
 	k.configureChecks(ctx)
+	k.runLimiter.setLimits(cfg.MaxConcurrentChecks, cfg.MaxConcurrentChecksPerNamespace)
+	k.runLimiter.setQuotas(cfg.NamespaceQuotas)
 
 	// sleep to make a more graceful switch-up during lots of master and check changes coming in
 	log.Infoln("control:", len(k.Checks), "checks starting!")
@@ -775,116 +1064,56 @@ func (k *Kuberhealthy) StartChecks(ctx context.Context) {
 		// start the check in its own routine
 		go k.runCheck(checkGroupCtx, c)
 	}
-
-	// spin up the khState reaper with a context after checks have been configured and started
-	log.Infoln("control: reaper starting!")
-	go k.khStateResourceReaper(ctx, k.TargetNamespace)
 }
 
-// masterStatusWatcher watches for master change events and updates the global upcomingMasterState along
-// with the global lastMasterChangeTime
-func (k *Kuberhealthy) masterStatusWatcher(ctx context.Context) {
+// masterMonitor takes part in lease based master election and notifies the supplied channels when
+// this instance gains or loses master status.  The election runs until the supplied context is
+// canceled, at which point the master lease is released for another instance to take over.
+func (k *Kuberhealthy) masterMonitor(ctx context.Context, becameMasterChan chan struct{}, lostMasterChan chan struct{}) {
 
-	// continue reconnecting to the api to resume the pod watch if it ends
-	for {
-		log.Debugln("master status watcher starting up...")
+	// when master is forced on, there is no election to take part in
+	if masterCalculation.IsForcedMaster() {
+		log.Infoln("control: master mode is forced on. Skipping master election.")
+		isMaster = true
+		becameMasterChan <- struct{}{}
+		return
+	}
 
-		// don't retry our watch too fast
-		time.Sleep(time.Second * 5)
+	leaseConfig := masterCalculation.LeaseConfig{
+		LeaseName:     k.config.LeaseName,
+		LeaseDuration: k.config.LeaseDuration,
+		RenewDeadline: k.config.LeaseRenewDeadline,
+		RetryPeriod:   k.config.LeaseRetryPeriod,
+	}
 
-		// setup a pod watching client for kuberhealthy pods
-		watcher, err := kubernetesClient.CoreV1().Pods(podNamespace).Watch(ctx, metav1.ListOptions{
-			LabelSelector: "app=kuberhealthy",
-		})
-		if err != nil {
-			log.Errorln("error when attempting to watch for kuberhealthy pod changes:", err)
-			continue
+	becameMaster := func() {
+		isMaster = true
+		becameMasterChan <- struct{}{}
+	}
+	lostMaster := func() {
+		if !isMaster {
+			return
 		}
+		isMaster = false
+		lostMasterChan <- struct{}{}
+	}
 
-		// watch for the parent context to expire as well as this watch context. if the parent context expires,
-		// then we stop the watcher.  if the watcher context expires, we terminate the go routine to prevent a
-		// goroutine leak
-		watcherCtx, watcherCtxCancel := context.WithCancel(context.Background())
-		go func(watchCtx context.Context, ctx context.Context, watcher watch.Interface) {
-			select {
-			case <-watchCtx.Done():
-				break
-			case <-ctx.Done():
-				watcher.Stop()
-			}
-			log.Debugln("master status monitor watch stopping")
-		}(watcherCtx, ctx, watcher)
-
-		// on each update from the watch, we re-check our master status.
-		for range watcher.ResultChan() {
-
-			// update the time we last saw a master event
-			lastMasterChangeTime = time.Now()
-
-			// determine if we are becoming master or not
-			var err error
-			upcomingMasterState, err = masterCalculation.IAmMaster(kubernetesClient)
-			if err != nil {
-				log.Errorln(err)
-			}
-
-			// update the time we last saw a master event
-			log.Debugln("master status monitor saw a master event")
-			lastMasterChangeTime = time.Now()
+	// continue retrying the election if it fails to start
+	for {
+		err := masterCalculation.RunLeaseElection(ctx, kubernetesClient, leaseConfig, becameMaster, lostMaster)
+		if err != nil {
+			log.Errorln("control: error running master election:", err)
 		}
 
-		// cancel the watcher by revoking its context
-		watcherCtxCancel()
-
-		// if the context has expired, then shut down the master status watcher entirely
 		select {
 		case <-ctx.Done():
-			log.Debugln("master status monitor stopping due to context cancellation")
+			log.Debugln("control: master monitor stopping due to context cancellation")
 			return
-		default:
+		case <-time.After(time.Second * 5):
 		}
 	}
 }
 
-// masterMonitor periodically evaluates the current and upcoming master state
-// and makes it so when appropriate
-func (k *Kuberhealthy) masterMonitor(ctx context.Context, becameMasterChan chan struct{}, lostMasterChan chan struct{}) {
-
-	// watch master pod event changes and recalculate the current master state of this pdo with each
-	go k.masterStatusWatcher(ctx)
-
-	interval := time.Second * 10
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// on each tick, we ensure that enough time has passed since the last master change
-	// event, then we calculate if we should become or lose master.
-	for range ticker.C {
-
-		if time.Since(lastMasterChangeTime) < interval {
-			log.Println("control: waiting for master changes to settle...")
-			continue
-		}
-
-		// dupe the global to prevent races
-		goingToBeMaster := upcomingMasterState
-
-		// stop checks if we are no longer the master
-		if goingToBeMaster && !isMaster {
-			becameMasterChan <- struct{}{}
-		}
-
-		// start checks if we are now master
-		if !goingToBeMaster && isMaster {
-			lostMasterChan <- struct{}{}
-		}
-
-		// refresh global isMaster state
-		isMaster = goingToBeMaster
-	}
-}
-
 // runJob runs the job and sets its status
 func (k *Kuberhealthy) runJob(ctx context.Context, job khjobv1.KuberhealthyJob) {
 
@@ -902,29 +1131,98 @@ func (k *Kuberhealthy) runJob(ctx context.Context, job khjobv1.KuberhealthyJob)
 	default:
 	}
 
-	// Run the job
-	log.Infoln("Running job:", j.Name())
-	// Record job run start time
-	jobStartTime := time.Now()
 	// set KHJob phase to running
 	err := setJobPhase(job.Name, job.Namespace, khjobv1.JobRunning)
 	if err != nil {
 		log.Errorln("Error setting job phase:", err)
 	}
 
+	// run the job, retrying failed runs until the backoff limit is reached
+	var jobOK bool
+	backoffLimit := jobBackoffLimit(job)
+	for attempt := int32(0); attempt <= backoffLimit; attempt++ {
+		if attempt > 0 {
+			delay := jobBackoffDelay(attempt)
+			log.Infoln("Retrying failed job", j.Name(), "in namespace", j.CheckNamespace(), "in", delay, "- retry", attempt, "of", backoffLimit)
+			select {
+			case <-ctx.Done():
+				log.Infoln("Shutting down job run due to context cancellation:", j.Name(), "in namespace", j.CheckNamespace())
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		jobOK = k.runJobOnce(ctx, j)
+		if jobOK {
+			break
+		}
+	}
+
+	// set KHJob phase to its terminal state
+	jobPhase := khjobv1.JobCompleted
+	if !jobOK {
+		jobPhase = khjobv1.JobFailed
+	}
+	err = setJobPhase(j.Name(), j.CheckNamespace(), jobPhase)
+	if err != nil {
+		log.Errorln("Error setting job phase:", err)
+	}
+}
+
+// jobBackoffLimit returns the number of times a failed run of the job is retried.  A negative backoff limit would
+// keep the job from ever running, so it is logged and the job is run once without retries instead.
+func jobBackoffLimit(job khjobv1.KuberhealthyJob) int32 {
+	if job.Spec.BackoffLimit < 0 {
+		log.Errorln("Invalid backoffLimit", job.Spec.BackoffLimit, "for job", job.Name, "in namespace", job.Namespace, "- it must be 0 or more")
+		log.Errorln("Defaulting job to a backoffLimit of 0")
+		return 0
+	}
+	return job.Spec.BackoffLimit
+}
+
+// jobBackoffDelay returns the time to wait before the supplied retry attempt of a failed job. The delay
+// doubles with each attempt up to maxJobBackoffDelay.
+func jobBackoffDelay(attempt int32) time.Duration {
+	delay := jobBackoffBaseDelay
+	for i := int32(1); i < attempt; i++ {
+		delay = delay * 2
+		if delay >= maxJobBackoffDelay {
+			return maxJobBackoffDelay
+		}
+	}
+	return delay
+}
+
+// runJobOnce runs a single attempt of a job and stores its state.  Returns true if the job run
+// completed and reported OK.
+func (k *Kuberhealthy) runJobOnce(ctx context.Context, j *external.Checker) bool {
+
+	// wait for a free slot if too many checker pods are already running or the quota of the namespace is used up
+	release, err := k.acquireRun(ctx, j)
+	if err != nil {
+		log.Infoln("Gave up waiting to run job", j.Name(), "in namespace", j.CheckNamespace()+":", err)
+		return false
+	}
+
+	// Run the job
+	log.Infoln("Running job:", j.Name())
+	// Record job run start time
+	jobStartTime := time.Now()
+
 	err = j.Run(ctx, kubernetesClient)
+	release()
 	if err != nil {
 		log.Errorln("Error running job:", j.Name(), "in namespace", j.CheckNamespace()+":", err)
 		if strings.Contains(err.Error(), "pod deleted expectedly") {
 			log.Infoln("Skipping this job due to expected pod removal before completion")
 		}
 		// set any job run errors in the CRD
-		err = k.setJobExecutionError(j.Name(), j.CheckNamespace(), err)
+		err = k.setJobExecutionError(j.Name(), j.CheckNamespace(), err, jobStartTime)
 		if err != nil {
 			log.Errorln("Error setting job execution error:", err)
 		}
-		// exit out of this runJob
-		return
+		// exit out of this job run
+		return false
 	}
 	log.Debugln("Done running job:", j.Name(), "in namespace", j.CheckNamespace())
 
@@ -942,6 +1240,9 @@ func (k *Kuberhealthy) runJob(ctx context.Context, job khjobv1.KuberhealthyJob)
 	details := khstatev1.NewWorkloadDetails(khstatev1.KHJob)
 	details.Namespace = j.CheckNamespace()
 	details.OK, details.Errors = j.CurrentStatus()
+	if !details.OK {
+		details.FailureReason = khstatev1.FailureReasonReportedFailure
+	}
 	details.RunDuration = jobRunDuration.String()
 	details.CurrentUUID = jobDetails.CurrentUUID
 
@@ -983,19 +1284,19 @@ func (k *Kuberhealthy) runJob(ctx context.Context, job khjobv1.KuberhealthyJob)
 		}
 	}
 
+	// send the result to the result exporters if any are configured
+	k.exportRunResult(j.Name(), j.CheckNamespace(), details)
+
 	log.Infoln("Setting state of job", j.Name(), "in namespace", j.CheckNamespace(), "to", details.OK, details.Errors, details.RunDuration, details.CurrentUUID, details.GetKHWorkload())
 
 	// store the job state with the CRD
-	err = k.storeCheckState(j.Name(), j.CheckNamespace(), details)
+	run := newRunRecord(details, jobStartTime, pod.Name)
+	err = k.storeCheckRunState(j.Name(), j.CheckNamespace(), details, &run)
 	if err != nil {
 		log.Errorln("Error storing CRD state for job:", j.Name(), "in namespace", j.CheckNamespace(), err)
 	}
 
-	// set KHJob phase to running:
-	err = setJobPhase(j.Name(), j.CheckNamespace(), khjobv1.JobCompleted)
-	if err != nil {
-		log.Errorln("Error setting job phase:", err)
-	}
+	return details.OK
 }
 
 // runCheck runs a check on an interval and sets its status each run
@@ -1003,8 +1304,63 @@ func (k *Kuberhealthy) runCheck(ctx context.Context, c *external.Checker) {
 
 	log.Println("Starting check:", c.CheckNamespace(), "/", c.Name())
 
-	// run on an interval specified by the package
-	ticker := time.NewTicker(c.Interval())
+	// a run that a previous master left in flight is resumed right away instead of starting a new run
+	pending := checkPendingRun(c)
+	if pending != nil {
+		log.Infoln("Resuming run of check", c.Name(), "in namespace", c.CheckNamespace(), "with checker pod", pending.run.Pod)
+	}
+
+	// checks that run on an interval start at a random offset so that checks created together do not run together.
+	// checks that another kuberhealthy pod ran recently wait until their next run is due, so that they do not run
+	// twice when the master changes.
+	var delay time.Duration
+	if len(c.RunSchedule) == 0 && pending == nil {
+		delay = checkHandoffDelay(c)
+		if delay > 0 {
+			log.Infoln("Delaying first run of check", c.Name(), "in namespace", c.CheckNamespace(), "by", delay, "because it last ran on another Kuberhealthy pod")
+		} else if c.RunIntervalJitter > 0 {
+			delay = randomStartDelay(c.RunIntervalJitter, c.Interval())
+			log.Infoln("Delaying first run of check", c.Name(), "in namespace", c.CheckNamespace(), "by", delay)
+		}
+	}
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			log.Infoln("Shutting down check run due to context cancellation:", c.Name(), "in namespace", c.CheckNamespace())
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	// run on an interval specified by the package, or on the check's cron schedule if it has one
+	tickChan, stopTicker, setTickerInterval := newCheckTicker(c)
+	defer stopTicker()
+
+	// checks that back off their run interval while failing pick up the interval they were backed off to
+	c.EffectiveRunInterval = 0
+	updateRunInterval := func() {
+		interval := k.effectiveRunInterval(c)
+		if c.RunIntervalBackoff == 0 || interval == c.EffectiveRunInterval {
+			return
+		}
+		if c.EffectiveRunInterval > 0 || interval != c.Interval() {
+			log.Infoln("Check", c.Name(), "in namespace", c.CheckNamespace(), "now runs every", interval)
+			setTickerInterval(interval)
+		}
+		c.EffectiveRunInterval = interval
+	}
+	updateRunInterval()
+
+	// checks with a cron schedule do not run right away. They wait for their first scheduled time.
+	if len(c.RunSchedule) > 0 && pending == nil {
+		log.Infoln("Waiting for first scheduled run of check", c.Name(), "in namespace", c.CheckNamespace(), "with schedule", c.RunSchedule)
+		select {
+		case <-ctx.Done():
+			log.Infoln("Shutting down check run due to context cancellation:", c.Name(), "in namespace", c.CheckNamespace())
+			return
+		case <-tickChan:
+		}
+	}
 
 	// run the check forever and write its results to the kuberhealthy
 	// CRD resource for the check
@@ -1020,27 +1376,115 @@ func (k *Kuberhealthy) runCheck(ctx context.Context, c *external.Checker) {
 		default:
 		}
 
-		// Run the check
+		// paused checks skip their runs until they are resumed
+		if c.Paused {
+			log.Infoln("Skipping run of paused check", c.Name(), "in namespace", c.CheckNamespace())
+			<-tickChan
+			continue
+		}
+
+		// checks in a maintenance window that skips runs wait for the window to close
+		if mode, inMaintenance := k.checkMaintenanceMode(c.Name(), c.CheckNamespace()); inMaintenance && mode == khcheckv1.MaintenanceModeSkip {
+			log.Infoln("Skipping run of check", c.Name(), "in namespace", c.CheckNamespace(), "during its maintenance window")
+			<-tickChan
+			continue
+		}
+
+		// wait for a free slot if too many checker pods are already running or the quota of the namespace is used up
+		release, err := k.acquireRun(ctx, c)
+		if err != nil {
+			log.Infoln("Gave up waiting to run check", c.Name(), "in namespace", c.CheckNamespace()+":", err)
+			continue
+		}
+
+		// no runs start once kuberhealthy is shutting down
+		if !k.runs.start() {
+			release()
+			log.Infoln("Not running check", c.Name(), "in namespace", c.CheckNamespace(), "because Kuberhealthy is shutting down")
+			return
+		}
+
+		// Run the check.  Runs that come due while this run is in flight are handled by its concurrency policy.
 		log.Infoln("Running check:", c.Name())
 		// Record check run start time
 		checkStartTime := time.Now()
-		err := c.Run(ctx, kubernetesClient)
+		resume := pending
+		pending = nil
+		if resume != nil {
+			checkStartTime = resume.run.StartTime.Time
+		}
+		result := runWithConcurrencyPolicy(ctx, c, tickChan, func(runCtx context.Context) error {
+			if resume != nil {
+				err := c.Resume(runCtx, kubernetesClient, resume.run, resume.lastReportTime)
+				if err != external.ErrRunNotResumable {
+					return err
+				}
+				log.Infoln("Checker pod", resume.run.Pod, "of check", c.Name(), "in namespace", c.CheckNamespace(), "can not be resumed. Starting a new run")
+				checkStartTime = time.Now()
+			}
+			return c.Run(runCtx, kubernetesClient)
+		})
+		err = result.err
+
+		// observe the time the checker pod took to be scheduled and start running, whether or not the run passed
+		if c.SchedulingLatency() > 0 {
+			metrics.SchedulingLatencies.Observe(c.CheckNamespace()+"/"+c.Name(), c.CheckNamespace(), c.SchedulingLatency())
+		}
+		if external.FailureReasonOf(err) == khstatev1.FailureReasonImagePullError {
+			metrics.ImagePullFailures.Inc(c.CheckNamespace()+"/"+c.Name(), c.CheckNamespace())
+		}
+
+		// the checker pods of replaced runs are evicted before the next run starts
+		if result.replaced {
+			c.Cleanup(ctx)
+		}
+		release()
+
+		// runs that were stopped by a shutdown have no result to store.  runs that were detached from their checker
+		// pod are left in flight for the next master to resume.
+		if ctx.Err() != nil && k.runs.isDraining() {
+			if c.LeftInFlight() {
+				log.Infoln("Run of check", c.Name(), "in namespace", c.CheckNamespace(), "was left in flight for the next master")
+			} else {
+				log.Infoln("Run of check", c.Name(), "in namespace", c.CheckNamespace(), "was interrupted by shutdown")
+				setRunInterrupted(c.Name(), c.CheckNamespace())
+			}
+			k.runs.done()
+			return
+		}
+
+		// waitForNextRun waits for the next run of the check unless it came due while this run was in flight
+		waitForNextRun := func() {
+			if result.nextDue {
+				result.nextDue = false
+				return
+			}
+			<-tickChan
+		}
+
 		if err != nil {
 			log.Errorln("Error running check:", c.Name(), "in namespace", c.CheckNamespace()+":", err)
 			if strings.Contains(err.Error(), "pod deleted expectedly") {
 				log.Infoln("Skipping this run due to expected pod removal before completion")
-				<-ticker.C
+				waitForNextRun()
 			}
 			// set any check run errors in the CRD
-			err = k.setCheckExecutionError(c.Name(), c.CheckNamespace(), err)
+			err = k.setCheckExecutionError(c.Name(), c.CheckNamespace(), err, checkStartTime)
 			if err != nil {
 				log.Errorln("Error setting check execution error:", err)
 			}
-			<-ticker.C
+			k.runs.done()
+			updateRunInterval()
+			waitForNextRun()
 			continue
 		}
 		log.Debugln("Done running check:", c.Name(), "in namespace", c.CheckNamespace())
 
+		// observe the time the checker pod took to report in
+		if c.ReportDuration() > 0 {
+			metrics.CheckDurations.Observe(c.CheckNamespace()+"/"+c.Name(), c.CheckNamespace(), c.ReportDuration())
+		}
+
 		// Record check run end time
 		// Subtract 10 seconds from run time since there are two 5 second sleeps during the check run where kuberhealthy
 		// waits for all pods to clear before running the check and waits for all pods to exit once the check has finished
@@ -1055,16 +1499,26 @@ func (k *Kuberhealthy) runCheck(ctx context.Context, c *external.Checker) {
 		details := khstatev1.NewWorkloadDetails(khstatev1.KHCheck)
 		details.Namespace = c.CheckNamespace()
 		details.OK, details.Errors = c.CurrentStatus()
+		if !details.OK {
+			details.Errors = appendFailureLogs(details.Errors, c.FailureLogs())
+			details.FailureReason = khstatev1.FailureReasonReportedFailure
+		}
 		details.RunDuration = checkRunDuration.String()
 		details.CurrentUUID = checkDetails.CurrentUUID
 
-		// Fetch node information from running check pod using kh run uuid
-		selector := "kuberhealthy-run-id=" + details.CurrentUUID
-		pod, err := k.fetchPodBySelector(ctx, selector)
-		if err != nil {
-			log.Errorln(err)
+		// Fetch node information from running check pod using kh run uuid.  Checks that run on all nodes record
+		// the nodes that failed instead.
+		var pod v1.Pod
+		if c.RunOnAllNodes {
+			details.FailedNodes = checkDetails.FailedNodes
+		} else {
+			selector := "kuberhealthy-run-id=" + details.CurrentUUID
+			pod, err = k.fetchPodBySelector(ctx, selector)
+			if err != nil {
+				log.Errorln(err)
+			}
+			details.Node = pod.Spec.NodeName
 		}
-		details.Node = pod.Spec.NodeName
 
 		log.Debugln("node name:", details.Node, "nodeName", c.Node)
 
@@ -1096,21 +1550,33 @@ func (k *Kuberhealthy) runCheck(ctx context.Context, c *external.Checker) {
 			}
 		}
 
+		// send the result to the result exporters if any are configured
+		k.exportRunResult(c.Name(), c.CheckNamespace(), details)
+
 		log.Infoln("Setting state of check", c.Name(), "in namespace", c.CheckNamespace(), "to", details.OK, details.Errors, details.RunDuration, details.CurrentUUID, details.GetKHWorkload())
 
 		// store the check state with the CRD
-		err = k.storeCheckState(c.Name(), c.CheckNamespace(), details)
+		run := newRunRecord(details, checkStartTime, pod.Name)
+		err = k.storeCheckRunState(c.Name(), c.CheckNamespace(), details, &run)
 		if err != nil {
 			log.Errorln("Error storing CRD state for check:", c.Name(), "in namespace", c.CheckNamespace(), err)
 		}
+		k.runs.done()
 
+		updateRunInterval()
 		log.Infoln("Waiting for next run of check", c.Name(), "in namespace", c.CheckNamespace())
-		<-ticker.C // wait for next run
+		waitForNextRun()
 	}
 }
 
 // storeCheckState stores the check state in its cluster CRD
 func (k *Kuberhealthy) storeCheckState(checkName string, checkNamespace string, details khstatev1.WorkloadDetails) error {
+	return k.storeCheckRunState(checkName, checkNamespace, details, nil)
+}
+
+// storeCheckRunState stores the check state in its cluster CRD and adds the supplied run to the run history
+// of the check.  If run is nil, the run history is left as it is.
+func (k *Kuberhealthy) storeCheckRunState(checkName string, checkNamespace string, details khstatev1.WorkloadDetails, run *khstatev1.RunRecord) error {
 
 	// ensure the CRD resource exits
 	err := ensureStateResourceExists(checkName, checkNamespace, details.GetKHWorkload())
@@ -1118,8 +1584,21 @@ func (k *Kuberhealthy) storeCheckState(checkName string, checkNamespace string,
 		return err
 	}
 
+	// store the failure threshold and severity of the check so that they can be honored everywhere the state is
+	// reported.  Reports can be received by an instance that does not run the check, which keeps the settings that
+	// are already on the khstate instead.
+	settingsKnown := k.applyCheckSettings(checkName, checkNamespace, &details)
+
+	// scrub secrets out of the errors before they are stored and shown on the status page.  the run is copied so
+	// that the caller's record is left alone.
+	if run != nil {
+		redactedRun := *run
+		run = &redactedRun
+	}
+	redactRunState(&details, run, k.sensitiveValues(checkName, checkNamespace))
+
 	// put the status on the CRD from the check
-	err = setCheckStateResource(checkName, checkNamespace, details)
+	err = setCheckStateResource(checkName, checkNamespace, details, run, settingsKnown)
 
 	//TODO: Make this retry of updating custom resources repeatable
 	//
@@ -1144,7 +1623,7 @@ func (k *Kuberhealthy) storeCheckState(checkName string, checkNamespace string,
 		delay = delay + delay
 
 		// try setting the check state again
-		err = setCheckStateResource(checkName, checkNamespace, details)
+		err = setCheckStateResource(checkName, checkNamespace, details, run, settingsKnown)
 
 		// count how many times we've retried
 		tries++
@@ -1153,10 +1632,31 @@ func (k *Kuberhealthy) storeCheckState(checkName string, checkNamespace string,
 	return err
 }
 
+// applyCheckSettings copies the settings of the named check that are stored on its khstate onto the supplied details,
+// and indicates if the check was found.  Only the instance that runs a check knows its settings.
+func (k *Kuberhealthy) applyCheckSettings(checkName string, checkNamespace string, details *khstatev1.WorkloadDetails) bool {
+	c, err := k.getCheck(checkName, checkNamespace)
+	if err != nil {
+		return false
+	}
+	details.FailureThreshold = c.FailureThreshold
+	details.Severity = c.Severity
+
+	// keep the run interval of checks that back off while failing on their state
+	if c.EffectiveRunInterval > 0 {
+		details.EffectiveRunInterval = c.EffectiveRunInterval.String()
+	}
+	return true
+}
+
 // StartWebServer starts a JSON status web server at the specified listener.
 func (k *Kuberhealthy) StartWebServer() {
 	log.Infoln("Configuring web server")
-	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+
+	// serve from our own mux so that handlers registered on the default mux by imported packages, such as the
+	// debug endpoints, are not exposed on the status page
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
 		err := k.prometheusMetricsHandler(w, r)
 		if err != nil {
 			log.Errorln(err)
@@ -1164,25 +1664,115 @@ func (k *Kuberhealthy) StartWebServer() {
 	})
 
 	// Accept status reports coming from external checker pods
-	http.HandleFunc("/externalCheckStatus", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/externalCheckStatus", func(w http.ResponseWriter, r *http.Request) {
 		err := k.externalCheckReportHandler(w, r)
 		if err != nil {
 			log.Errorln("externalCheckStatus endpoint error:", err)
 		}
 	})
 
+	// Accept progress updates from external checker pods while their run is in flight
+	mux.HandleFunc("POST "+externalCheckProgressPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.externalCheckProgressHandler(w, r)
+		if err != nil {
+			log.Errorln("externalCheckProgress endpoint error:", err)
+		}
+	})
+
+	// Run external checks on demand
+	mux.HandleFunc("POST "+runCheckPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.runCheckHandler(w, r)
+		if err != nil {
+			log.Errorln("run check endpoint error:", err)
+		}
+	})
+
+	// Pause and resume external checks
+	mux.HandleFunc("POST "+pauseCheckPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.setCheckPausedHandler(true)(w, r)
+		if err != nil {
+			log.Errorln("pause check endpoint error:", err)
+		}
+	})
+	mux.HandleFunc("POST "+resumeCheckPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.setCheckPausedHandler(false)(w, r)
+		if err != nil {
+			log.Errorln("resume check endpoint error:", err)
+		}
+	})
+
+	// Stream check state transitions to clients as they happen
+	mux.HandleFunc("GET "+streamPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.streamHandler(w, r)
+		if err != nil {
+			log.Errorln("stream endpoint error:", err)
+		}
+	})
+
+	// Serve the merged status and metrics of federated clusters
+	mux.HandleFunc("GET "+federationMetricsPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.federationMetricsHandler(w, r)
+		if err != nil {
+			log.Errorln("federation metrics endpoint error:", err)
+		}
+	})
+	mux.HandleFunc("POST "+federationPushPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.federationPushHandler(w, r)
+		if err != nil {
+			log.Errorln("federation push endpoint error:", err)
+		}
+	})
+	mux.HandleFunc("GET "+federationPath, func(w http.ResponseWriter, r *http.Request) {
+		err := k.federationHandler(w, r)
+		if err != nil {
+			log.Errorln("federation endpoint error:", err)
+		}
+	})
+
+	// Serve the readiness and liveness probes of this pod
+	mux.HandleFunc("GET "+readyzPath, k.readyzHandler)
+	mux.HandleFunc("GET "+livezPath, livezHandler)
+
+	// Serve a web dashboard that renders the status page for humans
+	mux.Handle(dashboardPath, dashboardHandler())
+
 	// Assign all requests to be handled by the healthCheckHandler function
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		err := k.healthCheckHandler(w, r)
 		if err != nil {
 			log.Errorln(err)
 		}
 	})
 
+	// if a certificate and key are configured, serve over TLS and reload the certificate when it is rotated
+	var certs *certReloader
+	if cfg.tlsEnabled() {
+		var err error
+		certs, err = newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalln("Error loading TLS certificate for web server:", err)
+		}
+	}
+
 	// start web server any time it exits
 	for {
-		log.Infoln("Starting web services on port", k.ListenAddr)
-		err := http.ListenAndServe(k.ListenAddr, nil)
+		var err error
+		if certs != nil {
+			log.Infoln("Starting TLS web services on port", k.ListenAddr)
+			tlsConfig := certs.tlsConfig()
+			if clientCertIssuer != nil {
+				tlsConfig = certs.tlsConfigWithClientCAs(clientCertIssuer.CertPool)
+			}
+			server := &http.Server{
+				Addr:      k.ListenAddr,
+				Handler:   mux,
+				TLSConfig: tlsConfig,
+			}
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			log.Infoln("Starting web services on port", k.ListenAddr)
+			err = http.ListenAndServe(k.ListenAddr, mux)
+		}
 		if err != nil {
 			log.Errorln("Web server ERROR:", err)
 		}
@@ -1195,6 +1785,8 @@ type PodReportInfo struct {
 	Name      string
 	UUID      string
 	Namespace string
+	PodName   string // the name of the checker pod that sent the report
+	NodeName  string // the node of the checker pod that sent the report, for checks that run on all nodes
 }
 
 // validateExternalRequest calls the Kubernetes API to fetch details about a pod using a selector string.
@@ -1261,10 +1853,18 @@ func (k *Kuberhealthy) validateExternalRequest(ctx context.Context, selector str
 		return reportInfo, errors.New("pod uuid was invalid or unset")
 	}
 
+	// the checker pods of checks that run on all nodes each have their own UUID, but report for the run that they
+	// are labeled with
+	if runUUID, ok := pod.Labels[external.KHNodeRunIDLabel]; ok {
+		podUUID = runUUID
+		reportInfo.NodeName = pod.Spec.NodeName
+	}
+
 	// create a report to send back to the function invoker
 	reportInfo.Name = podCheckName
 	reportInfo.Namespace = podCheckNamespace
 	reportInfo.UUID = podUUID
+	reportInfo.PodName = pod.GetName()
 
 	// next, we check the uuid against the check name to see if this uuid is the expected one.  if it isn't,
 	// we return an error
@@ -1348,16 +1948,50 @@ func (k *Kuberhealthy) externalCheckReportHandlerLog(s ...interface{}) {
 	log.Infoln(s...)
 }
 
-// validatePodReportBySourceIP gets the header `kh-run-uuid` value from the request and forms a selector with it to
-// validate that the request is coming from a kuberhealthy check pod
-func (k *Kuberhealthy) validateUsingRequestHeader(ctx context.Context, r *http.Request) (PodReportInfo, bool, error) {
+// reportCredentials is what a checker pod presents to identify and authenticate its report, whichever transport
+// the report is sent over
+type reportCredentials struct {
+	runUUID       string               // the kh-run-uuid of the report
+	remoteAddr    string               // the address that the report was sent from
+	authorization string               // the Authorization header or metadata of the report
+	tls           *tls.ConnectionState // the TLS connection that the report was sent over. nil for plain text
+}
+
+// reportError is an error handling a check report along with the HTTP status code that describes it
+type reportError struct {
+	statusCode int
+	err        error
+}
+
+// Error implements the error interface
+func (e *reportError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *reportError) Unwrap() error {
+	return e.err
+}
+
+// reportStatusCode returns the HTTP status code that describes an error handling a check report
+func reportStatusCode(err error) int {
+	var rErr *reportError
+	if errors.As(err, &rErr) {
+		return rErr.statusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// validateUsingRunUUID forms a selector with the kh-run-uuid of a report to validate that the report is coming
+// from a kuberhealthy check pod
+func (k *Kuberhealthy) validateUsingRunUUID(ctx context.Context, runUUID string) (PodReportInfo, bool, error) {
 
 	var podReport PodReportInfo
 	var err error
-	if len(r.Header.Get("kh-run-uuid")) == 0 {
+	if len(runUUID) == 0 {
 		return podReport, false, nil
 	}
-	selector := "kuberhealthy-run-id=" + r.Header.Get("kh-run-uuid")
+	selector := "kuberhealthy-run-id=" + runUUID
 	podReport, err = k.validateExternalRequest(ctx, selector)
 	if err != nil {
 		return podReport, false, err
@@ -1365,12 +1999,12 @@ func (k *Kuberhealthy) validateUsingRequestHeader(ctx context.Context, r *http.R
 	return podReport, true, nil
 }
 
-// validatePodReportBySourceIP parses the remoteAddr from the request and forms a selector with the remote IP to
-// validate that the request is coming from a kuberhealthy check pod
-func (k *Kuberhealthy) validatePodReportBySourceIP(ctx context.Context, r *http.Request) (PodReportInfo, error) {
+// validatePodReportBySourceIP parses the remote address of a report and forms a selector with the remote IP to
+// validate that the report is coming from a kuberhealthy check pod
+func (k *Kuberhealthy) validatePodReportBySourceIP(ctx context.Context, remoteAddr string) (PodReportInfo, error) {
 
 	var podReport PodReportInfo
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	ip, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
 		return podReport, err
 	}
@@ -1382,6 +2016,60 @@ func (k *Kuberhealthy) validatePodReportBySourceIP(ctx context.Context, r *http.
 	return podReport, nil
 }
 
+// authenticateReport finds the check that a report is for and ensures that the report came from the checker pod of
+// its current run.  The report is looked up by its kh-run-uuid, or by the IP of the calling pod if that fails.
+// Reports must also be authenticated with a service account token and a client certificate when those are
+// required.
+func (k *Kuberhealthy) authenticateReport(ctx context.Context, requestID string, creds reportCredentials) (PodReportInfo, error) {
+
+	// Validate request using the kh-run-uuid. If it doesn't exist, or there's an error with validation,
+	// validate using the pod's remote IP.
+	k.externalCheckReportHandlerLog(requestID, "validating external check status report from its reporting kuberhealthy run uuid:", creds.runUUID)
+	podReport, reportValidated, err := k.validateUsingRunUUID(ctx, creds.runUUID)
+	if err != nil {
+		k.externalCheckReportHandlerLog(requestID, "Failed to look up pod by its kh-run-uuid:", creds.runUUID, err)
+	}
+
+	// If the check uuid is missing, attempt to validate using calling pod's source IP
+	if !reportValidated {
+		k.externalCheckReportHandlerLog(requestID, "validating external check status report from the pod's remote IP:", creds.remoteAddr)
+		podReport, err = k.validatePodReportBySourceIP(ctx, creds.remoteAddr)
+		if err != nil {
+			k.externalCheckReportHandlerLog(requestID, "Failed to look up pod by its IP:", creds.remoteAddr, err)
+			return podReport, &reportError{statusCode: http.StatusBadRequest, err: fmt.Errorf("failed to look up pod by its IP %s: %w", creds.remoteAddr, err)}
+		}
+	}
+	k.externalCheckReportHandlerLog(requestID, "Calling pod is", podReport.Name, "in namespace", podReport.Namespace)
+
+	// checks that require it must also prove that the report came from their checker pod with a service account token.
+	// Reports are rejected when it is not known if they must.
+	tokenAuthRequired, err := k.reportTokenAuthRequired(podReport.Name, podReport.Namespace)
+	if err != nil {
+		k.externalCheckReportHandlerLog(requestID, "Failed to determine if the report must be authenticated with a service account token:", err)
+		return podReport, err
+	}
+	if tokenAuthRequired {
+		err = validateReportToken(ctx, creds.authorization, podReport.PodName, podReport.Namespace)
+		if err != nil {
+			k.externalCheckReportHandlerLog(requestID, "Failed to authenticate report with a service account token:", err)
+			return podReport, &reportError{statusCode: http.StatusUnauthorized, err: err}
+		}
+		k.externalCheckReportHandlerLog(requestID, "Authenticated report with the service account token of pod", podReport.PodName)
+	}
+
+	// when mutual TLS is enabled, reports must come with the client certificate issued to the current run of the check
+	if clientCertIssuer != nil {
+		err = validateReportClientCert(creds.tls, podReport)
+		if err != nil {
+			k.externalCheckReportHandlerLog(requestID, "Failed to authenticate report with a client certificate:", err)
+			return podReport, &reportError{statusCode: http.StatusUnauthorized, err: err}
+		}
+		k.externalCheckReportHandlerLog(requestID, "Authenticated report with the client certificate of run", podReport.UUID)
+	}
+
+	return podReport, nil
+}
+
 // externalCheckReportHandler handles requests coming from external checkers reporting their status.
 // This endpoint checks that the external check report is coming from the correct UUID or pod IP before recording
 // the reported status of the corresponding external check.  This endpoint expects a JSON payload of
@@ -1396,25 +2084,16 @@ func (k *Kuberhealthy) externalCheckReportHandler(w http.ResponseWriter, r *http
 
 	k.externalCheckReportHandlerLog(requestID, "Client connected to check report handler from", r.UserAgent())
 
-	// Validate request using the kh-run-uuid header. If the header doesn't exist, or there's an error with validation,
-	// validate using the pod's remote IP.
-	k.externalCheckReportHandlerLog(requestID, "validating external check status report from its reporting kuberhealthy run uuid:", r.Header.Get("kh-run-uuid"))
-	podReport, reportValidated, err := k.validateUsingRequestHeader(ctx, r)
+	podReport, err := k.authenticateReport(ctx, requestID, reportCredentials{
+		runUUID:       r.Header.Get("kh-run-uuid"),
+		remoteAddr:    r.RemoteAddr,
+		authorization: r.Header.Get("Authorization"),
+		tls:           r.TLS,
+	})
 	if err != nil {
-		k.externalCheckReportHandlerLog(requestID, "Failed to look up pod by its kh-run-uuid header:", r.Header.Get("kh-run-uuid"), err)
-	}
-
-	// If the check uuid header is missing, attempt to validate using calling pod's source IP
-	if !reportValidated {
-		k.externalCheckReportHandlerLog(requestID, "validating external check status report from the pod's remote IP:", r.RemoteAddr)
-		podReport, err = k.validatePodReportBySourceIP(ctx, r)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			k.externalCheckReportHandlerLog(requestID, "Failed to look up pod by its IP:", r.RemoteAddr, err)
-			return nil
-		}
+		w.WriteHeader(reportStatusCode(err))
+		return nil
 	}
-	k.externalCheckReportHandlerLog(requestID, "Calling pod is", podReport.Name, "in namespace", podReport.Namespace)
 
 	// append pod info to request id for easy check tracing in logs
 	requestID = requestID + " (" + podReport.Namespace + "/" + podReport.Name + ")"
@@ -1426,7 +2105,6 @@ func (k *Kuberhealthy) externalCheckReportHandler(w http.ResponseWriter, r *http
 		k.externalCheckReportHandlerLog(requestID, "Failed to read request body:", err.Error(), r.RemoteAddr)
 		return nil
 	}
-	log.Debugln("Check report body:", string(b))
 
 	// decode the bytes into a status struct as used by the client
 	state := status.Report{}
@@ -1436,24 +2114,56 @@ func (k *Kuberhealthy) externalCheckReportHandler(w http.ResponseWriter, r *http
 		k.externalCheckReportHandlerLog(requestID, "Failed to unmarshal state json:", err, r.RemoteAddr)
 		return nil
 	}
+
+	// invalid reports are the fault of the client, while failing to store a report is ours
+	err = k.storeReport(requestID, podReport, state)
+	if err != nil {
+		statusCode := reportStatusCode(err)
+		w.WriteHeader(statusCode)
+		if statusCode == http.StatusInternalServerError {
+			return err
+		}
+		return nil
+	}
+
+	// write ok back to caller
+	w.WriteHeader(http.StatusOK)
+	k.externalCheckReportHandlerLog(requestID, "Request completed successfully.")
+	return nil
+}
+
+// storeReport validates an authenticated check report and stores it as the result of the current run of its check
+func (k *Kuberhealthy) storeReport(requestID string, podReport PodReportInfo, state status.Report) error {
+
+	// scrub secrets out of the reported errors before they are stored anywhere
+	state.Errors = redact.Errors(state.Errors, k.sensitiveValues(podReport.Name, podReport.Namespace))
 	log.Debugf("Check report after unmarshal: +%v\n", state)
 
 	// ensure that if ok is set to false, then an error is provided
 	if !state.OK {
 		if len(state.Errors) == 0 {
-			w.WriteHeader(http.StatusBadRequest)
 			k.externalCheckReportHandlerLog(requestID, "Client attempted to report OK false without any error strings")
-			return nil
+			return &reportError{statusCode: http.StatusBadRequest, err: errors.New("report is not OK but has no errors")}
 		}
 		for _, e := range state.Errors {
 			if len(e) == 0 {
-				w.WriteHeader(http.StatusBadRequest)
 				k.externalCheckReportHandlerLog(requestID, "Client attempted to report a blank error string")
-				return nil
+				return &reportError{statusCode: http.StatusBadRequest, err: errors.New("report has a blank error string")}
 			}
 		}
 	}
 
+	// the checker pods of checks that run on all nodes only report the result of their node.  The check combines
+	// the results of all nodes once the run is done.
+	if len(podReport.NodeName) > 0 {
+		k.externalCheckReportHandlerLog(requestID, "Storing report of node", podReport.NodeName, "for check", podReport.Name, "in namespace", podReport.Namespace, "with 'OK' state:", state.OK)
+		err := storeNodeReport(podReport, state.OK, state.Errors)
+		if err != nil {
+			return fmt.Errorf("failed to store node report for %s: %w", podReport.Name, err)
+		}
+		return nil
+	}
+
 	checkRunDuration := time.Duration(0).String()
 	khWorkload := determineKHWorkload(podReport.Name, podReport.Namespace)
 
@@ -1476,16 +2186,11 @@ func (k *Kuberhealthy) externalCheckReportHandler(w http.ResponseWriter, r *http
 
 	// since the check is validated, we can proceed to update the status now
 	k.externalCheckReportHandlerLog(requestID, "Setting check with name", podReport.Name, "in namespace", podReport.Namespace, "to 'OK' state:", details.OK, "uuid", details.CurrentUUID, details.GetKHWorkload())
-	err = k.storeCheckState(podReport.Name, podReport.Namespace, details)
+	err := k.storeCheckState(podReport.Name, podReport.Namespace, details)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		k.externalCheckReportHandlerLog(requestID, "failed to store check state for %s: %w", podReport.Name, err)
+		k.externalCheckReportHandlerLog(requestID, "failed to store check state for", podReport.Name+":", err)
 		return fmt.Errorf("failed to store check state for %s: %w", podReport.Name, err)
 	}
-
-	// write ok back to caller
-	w.WriteHeader(http.StatusOK)
-	k.externalCheckReportHandlerLog(requestID, "Request completed successfully.")
 	return nil
 }
 
@@ -1507,7 +2212,15 @@ func (k *Kuberhealthy) prometheusMetricsHandler(w http.ResponseWriter, r *http.R
 	log.Infoln("Client connected to prometheus metrics endpoint from", r.RemoteAddr, r.UserAgent())
 	state := k.getCurrentState([]string{})
 
-	m := metrics.GenerateMetrics(state, cfg.PromMetricsConfig)
+	// serve the OpenMetrics format to scrapers that ask for it and the Prometheus text format to everyone else
+	var m string
+	if metrics.AcceptsOpenMetrics(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", metrics.OpenMetricsContentType)
+		m = metrics.GenerateOpenMetrics(state, cfg.PromMetricsConfig)
+	} else {
+		w.Header().Set("Content-Type", metrics.PrometheusContentType)
+		m = metrics.GenerateMetrics(state, cfg.PromMetricsConfig)
+	}
 	// write summarized health check results back to caller
 	_, err := w.Write([]byte(m))
 	if err != nil {
@@ -1517,7 +2230,8 @@ func (k *Kuberhealthy) prometheusMetricsHandler(w http.ResponseWriter, r *http.R
 }
 
 // healthCheckHandler returns the current status of checks loaded into Kuberhealthy
-// as JSON to the client. Respects namespace requests via URL query parameters (i.e. /?namespace=default)
+// as JSON to the client. Respects namespace requests via URL query parameters (i.e. /?namespace=default),
+// along with the check, failedOnly, labelSelector and format parameters of the status filter
 func (k *Kuberhealthy) healthCheckHandler(w http.ResponseWriter, r *http.Request) error {
 	log.Infoln("Client connected to status page from", r.RemoteAddr, r.UserAgent())
 
@@ -1545,11 +2259,20 @@ func (k *Kuberhealthy) healthCheckHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	// narrow the status down to the checks the client asked for
+	filter, err := parseStatusFilter(values)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return err
+	}
+
 	// fetch the current status from our khstate resources
 	state := k.getCurrentState(namespaces)
+	state = filter.apply(state, k.statusLabels)
 
 	// write summarized health check results back to caller
-	err = state.WriteHTTPStatusResponse(w)
+	err = writeStatus(w, state, filter.format)
 	if err != nil {
 		log.Warningln("Error writing health check results to caller:", err)
 	}
@@ -1566,6 +2289,9 @@ func (k *Kuberhealthy) getCurrentState(namespaces []string) health.State {
 	if err != nil {
 		log.Errorln("Failed to calculate master:", err)
 	}
+	if masterCalculation.IsForcedMaster() {
+		currentMaster = podHostname
+	}
 
 	var currentState health.State
 	if len(namespaces) != 0 {
@@ -1575,6 +2301,8 @@ func (k *Kuberhealthy) getCurrentState(namespaces []string) health.State {
 	}
 
 	currentState.CurrentMaster = currentMaster
+	currentState.ClusterName = cfg.ClusterName
+	currentState.Environment = cfg.Environment
 	if len(cfg.StateMetadata) != 0 {
 		currentState.Metadata = cfg.StateMetadata
 	}
@@ -1619,8 +2347,12 @@ func validateCurrentStatusForNamespaces(details map[string]khstatev1.WorkloadDet
 			continue
 		}
 
-		// parse check status from CRD and add it to the global status of errors. Skip blank errors
+		// parse check status from CRD and add it to the global status of errors. Skip blank errors and checks
+		// in a maintenance window
 		for _, e := range checkState.Errors {
+			if checkState.InMaintenance {
+				break
+			}
 			if len(strings.TrimSpace(e)) == 0 {
 				log.Warningln("Skipped an error that was blank when adding check details to current state.")
 				continue
@@ -1652,6 +2384,19 @@ func (k *Kuberhealthy) getCheck(name string, namespace string) (*external.Checke
 	return &external.Checker{}, fmt.Errorf("could not find Kuberhealthy check with name %s", name)
 }
 
+// triggerCheckRun asks the named check to run right away.  Only the master, or the shard member that owns the
+// check, runs it, so requests for checks that are not running on this instance are ignored.
+func (k *Kuberhealthy) triggerCheckRun(name string, namespace string) {
+	c, err := k.getCheck(name, namespace)
+	if err != nil {
+		log.Debugln("Not triggering a run of check", name, "in namespace", namespace+":", err)
+		return
+	}
+	if !c.TriggerRun() {
+		log.Infoln("A run of check", name, "in namespace", namespace, "has already been requested")
+	}
+}
+
 // getJob returns a Kuberhealthy job object from its name, returns an error otherwise
 func (k *Kuberhealthy) getJob(name string, namespace string) (*external.Checker, error) {
 
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -16,12 +17,19 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 
 	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khconfigv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khconfig/v1"
 	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
 	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/imageverify"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/masterCalculation"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/statestore"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
 )
 
 // status represents the current Kuberhealthy OK:Error state
@@ -29,9 +37,7 @@ var cfg *Config
 var configPath = "/etc/config/kuberhealthy.yaml"
 
 var podNamespace = os.Getenv("POD_NAMESPACE")
-var isMaster bool                  // indicates this instance is the master and should be running checks
-var upcomingMasterState bool       // the upcoming master state on next interval
-var lastMasterChangeTime time.Time // indicates the last time a master change was seen
+var isMaster bool // indicates this instance is the master and should be running checks
 // Interval for how often check pods should get reaped. Default is 30s.
 var checkReaperRunInterval = os.Getenv("CHECK_REAPER_RUN_INTERVAL")
 
@@ -43,17 +49,77 @@ var podHostname string
 // KHExternalReportingURL is the environment variable key used to override the URL checks will be asked to report in to
 const KHExternalReportingURL = "KH_EXTERNAL_REPORTING_URL"
 
+// KHTLSCertFile is the environment variable key used to set the TLS certificate the web server is served with
+const KHTLSCertFile = "KH_TLS_CERT_FILE"
+
+// KHTLSKeyFile is the environment variable key used to set the TLS key the web server is served with
+const KHTLSKeyFile = "KH_TLS_KEY_FILE"
+
+// KHClusterName is the environment variable key used to set the name of the cluster, which can be filled in with
+// the downward API from a label or annotation of the kuberhealthy pod
+const KHClusterName = "KH_CLUSTER_NAME"
+
+// KHEnvironment is the environment variable key used to set the environment of the cluster
+const KHEnvironment = "KH_ENVIRONMENT"
+
+// the cluster name and environment set with flags, which take precedence over the config file and env variables
+var clusterNameFlag string
+var environmentFlag string
+
+// settings made with flags, which are applied again every time the config file is reloaded
+var debugFlag bool
+var forceMasterFlag bool
+var tlsCertFileFlag string
+var tlsKeyFileFlag string
+var debugListenAddressFlag string
+var kubeClientQPSFlag float32
+var kubeClientBurstFlag int
+var kubeClientTimeoutFlag time.Duration
+var allowHostAccessFlag bool
+
 // DefaultRunInterval is the default run interval for checks set by kuberhealthy
 const DefaultRunInterval = time.Minute * 10
 
 // DefaultTimeout is the default timeout for external checks
 var DefaultTimeout = time.Minute * 5
 
+// jobBackoffBaseDelay is the time waited before the first retry of a failed khjob
+const jobBackoffBaseDelay = time.Second * 10
+
+// maxJobBackoffDelay is the longest time waited between retries of a failed khjob
+const maxJobBackoffDelay = time.Minute * 6
+
+// defaultFailureLogLines is the number of lines of checker pod logs attached to failed runs when failureLogLines is not set
+const defaultFailureLogLines = 20
+
+// defaultFailureLogMaxBytes is the maximum size of checker pod logs attached to failed runs when failureLogMaxBytes is not set
+const defaultFailureLogMaxBytes = 4096
+
+// defaultImagePullGracePeriod is how long a checker pod may fail to pull its image before its run fails when
+// imagePullGracePeriod is not set
+const defaultImagePullGracePeriod = time.Second * 30
+
+// defaultKHStateReapGracePeriod is how long khstates of deleted khchecks and khjobs are kept when khStateReapGracePeriod is not set
+const defaultKHStateReapGracePeriod = time.Minute * 10
+
+// defaultStateWriteBatchInterval is how long writes to the same khstate are collected when stateWriteBatchInterval is not set
+const defaultStateWriteBatchInterval = time.Second
+
+// defaultShutdownDrainTimeout is how long runs in flight may take to finish during shutdown when shutdownDrainTimeout is not set
+const defaultShutdownDrainTimeout = time.Minute * 2
+
+// detachTimeout is how long runs left in flight during shutdown may take to release their checker pods to the next master
+const detachTimeout = time.Second * 15
+
+// maxShutdownDrainTimeout is the longest that shutdown waits for runs in flight, which leaves time within the
+// termination grace period to stop the checks that are still running
+const maxShutdownDrainTimeout = time.Minute * 3
+
 // KHCheckNameAnnotationKey is the key used in the annotation that holds the check's short name
 const KHCheckNameAnnotationKey = "comcast.github.io/check-name"
 
-// khCheckClient is a client for khstate custom resources
-var khStateClient *khstatev1.KHStateV1Client
+// khStateClient is a client for khstates kept in the configured state storage backend
+var khStateClient khstatev1.KuberhealthyStatesGetter
 
 // khStateClient is a client for khcheck custom resources
 var khCheckClient *khcheckv1.KHCheckV1Client
@@ -61,6 +127,12 @@ var khCheckClient *khcheckv1.KHCheckV1Client
 // khJobClient is a client for khjob custom resources
 var khJobClient *khjobv1.KHJobV1Client
 
+// khConfigClient is a client for the KuberhealthyConfig resource that settings are loaded from, when one is configured
+var khConfigClient *khconfigv1.KHConfigV1Client
+
+// configResourcePollInterval is how often the KuberhealthyConfig resource is checked for changes
+const configResourcePollInterval = time.Second * 15
+
 // constants for using the kuberhealthy status CRD
 // const stateCRDGroup = "comcast.github.io"
 // const stateCRDVersion = "v1"
@@ -74,9 +146,19 @@ const checkCRDResource = "khchecks"
 // kubernetesClient is the global kubernetes client
 var kubernetesClient *kubernetes.Clientset
 
+// eventRecorder records Kubernetes events about check runs and reaped checker pods on khchecks and khjobs
+var eventRecorder record.EventRecorder
+
 // dynamicClient represents the client used to watch and list unstructured khchecks
 var dynamicClient dynamic.Interface
 
+// clientCertIssuer issues the client certificates that checker pods authenticate their reports with when mutual TLS
+// is enabled
+var clientCertIssuer *external.ClientCertIssuer
+
+// imageVerifier verifies the signatures of checker images before they are run when image verification is enabled
+var imageVerifier *imageverify.Verifier
+
 func main() {
 
 	// Initial setup before starting Kuberhealthy. Loading, parsing, and setting flags, config values and environment vars.
@@ -120,6 +202,7 @@ func listenForInterrupts(k *Kuberhealthy) {
 	// wait for checks to be done shutting down before exiting
 	select {
 	case <-doneChan:
+		tracing.Shutdown()
 		log.Infoln("shutdown: Shutdown gracefully completed!")
 		log.Infoln("shutdown: exiting 0")
 		os.Exit(0)
@@ -137,41 +220,52 @@ func listenForInterrupts(k *Kuberhealthy) {
 // initKubernetesClients creates the appropriate CRD clients and kubernetes client to be used in all cases. Issue #181
 func initKubernetesClients() error {
 
-	// make a new kuberhealthy client
-	kc, err := kubeClient.Create(cfg.kubeConfigFile)
+	// every client shares the rate limits, timeout and retries of the kubeClient settings
+	restConfig, err := kubeClient.RESTConfig(cfg.kubeConfigFile, cfg.KubeClient)
+	if err != nil {
+		return err
+	}
+
+	// make a new kuberhealthy client. built-in resources are requested as protobuf, while the crd clients below
+	// use JSON because custom resources are not served as protobuf.
+	kc, err := kubernetes.NewForConfig(kubeClient.BuiltInConfig(restConfig, cfg.KubeClient))
 	if err != nil {
 		return err
 	}
 	kubernetesClient = kc
+	eventRecorder = external.NewEventRecorder(kc)
 
 	// make a new crd check client
-	checkClient, err := khcheckv1.Client(cfg.kubeConfigFile)
+	checkClient, err := khcheckv1.NewForConfig(restConfig)
 	if err != nil {
 		return err
 	}
 	khCheckClient = checkClient
 
-	// make a new crd state client
-	stateClient, err := khstatev1.Client(cfg.kubeConfigFile)
+	// make a new crd state client and keep khstates in the configured state storage backend
+	stateClient, err := khstatev1.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	khStateClient, err = statestore.New(cfg.StateStorage, kc, stateClient)
 	if err != nil {
 		return err
 	}
-	khStateClient = stateClient
 
 	// make a new crd job client
-	jobClient, err := khjobv1.Client(cfg.kubeConfigFile)
+	jobClient, err := khjobv1.NewForConfig(restConfig)
 	if err != nil {
 		return err
 	}
 	khJobClient = jobClient
 
 	// make a dynamicClient for kubernetes unstructured checks
-	restConfig, err := clientcmd.BuildConfigFromFlags(kc.RESTClient().Get().URL().Host, configPath)
+	dynamicConfig, err := clientcmd.BuildConfigFromFlags(kc.RESTClient().Get().URL().Host, configPath)
 	if err != nil {
 		log.Fatalln("Failed to build kubernetes configuration from configuration flags:", err)
 	}
 
-	dynamicClient, err = dynamic.NewForConfig(restConfig)
+	dynamicClient, err = dynamic.NewForConfig(dynamicConfig)
 	if err != nil {
 		log.Fatalln("Failed to create kubernetes dynamic client configuration")
 	}
@@ -193,6 +287,27 @@ func setUpConfig() error {
 		log.Println("WARNING: Failed to read configuration file from disk:", err)
 	}
 
+	// merge the settings of the KuberhealthyConfig resource over the config file
+	if len(cfg.ConfigResource) > 0 {
+		err = cfg.LoadResource(cfg.ConfigResource)
+		if err != nil {
+			log.Errorln("Failed to load settings from KuberhealthyConfig", cfg.ConfigResource+":", err)
+		}
+	}
+
+	// set the TLS certificate and key from env variables if specified
+	if tlsCertFile := os.Getenv(KHTLSCertFile); len(tlsCertFile) > 0 {
+		cfg.TLSCertFile = tlsCertFile
+	}
+	if tlsKeyFile := os.Getenv(KHTLSKeyFile); len(tlsKeyFile) > 0 {
+		cfg.TLSKeyFile = tlsKeyFile
+	}
+	setClusterIdentity()
+	applyFlags()
+
+	// determine the namespace to operate in from the TARGET_NAMESPACE environment variable
+	cfg.TargetNamespace = os.Getenv("TARGET_NAMESPACE")
+
 	// set env variables into config if specified. otherwise set external check URL to default
 	externalCheckURL, err := getEnvVar(KHExternalReportingURL)
 	if err != nil {
@@ -200,18 +315,143 @@ func setUpConfig() error {
 			return errors.New("env KH_EXTERNAL_REPORTING_URL not set and POD_NAMESPACE environment variable was blank")
 		}
 		log.Infoln("KH_EXTERNAL_REPORTING_URL environment variable not set, using default value")
-		externalCheckURL = "http://kuberhealthy." + podNamespace + ".svc.cluster.local/externalCheckStatus"
+		externalCheckURL = defaultExternalCheckReportingURL(podNamespace, cfg.tlsEnabled())
 	}
 	cfg.ExternalCheckReportingURL = externalCheckURL
 	log.Infoln("External check reporting URL set to:", cfg.ExternalCheckReportingURL)
+
+	// checks report over gRPC to the kuberhealthy service unless another address is configured
+	if len(cfg.GRPCListenAddress) > 0 && len(cfg.ExternalCheckGRPCAddress) == 0 && len(podNamespace) > 0 {
+		cfg.ExternalCheckGRPCAddress, err = defaultExternalCheckGRPCAddress(podNamespace, cfg.GRPCListenAddress)
+		if err != nil {
+			return fmt.Errorf("invalid grpcListenAddress %s: %w", cfg.GRPCListenAddress, err)
+		}
+	}
+	if len(cfg.ExternalCheckGRPCAddress) > 0 {
+		log.Infoln("External check gRPC reporting address set to:", cfg.ExternalCheckGRPCAddress)
+	}
+
+	// warn about pod defaults that can not be applied to checker pods
+	err = cfg.PodDefaults.Validate()
+	if err != nil {
+		log.Errorln("Invalid podDefaults resources will be ignored:", err)
+	}
+
+	// warn about a pod security level that is not known. checker pods are held to the restricted level instead
+	err = cfg.PodSecurity.Validate()
+	if err != nil {
+		log.Errorln("Checker pods will be held to the restricted pod security level:", err)
+	}
+
+	// warn about namespace quotas that can not be enforced
+	err = cfg.NamespaceQuotas.Validate()
+	if err != nil {
+		log.Errorln("Invalid namespaceQuotas resources will be ignored:", err)
+	}
+
+	// scrub secrets out of reported errors
+	err = redact.Configure(cfg.Redaction)
+	if err != nil {
+		log.Errorln("Invalid redaction patterns will be ignored:", err)
+	}
+
+	// catch mistakes in notification routing early
+	err = cfg.Notifications.Validate()
+	if err != nil {
+		log.Errorln(err)
+	}
+
+	// export traces of check runs if configured
+	tracing.Configure(cfg.Tracing)
+	return nil
+}
+
+// defaultExternalCheckReportingURL returns the URL checks report in to when none is configured
+func defaultExternalCheckReportingURL(namespace string, useTLS bool) string {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	return scheme + "://kuberhealthy." + namespace + ".svc.cluster.local/externalCheckStatus"
+}
+
+// defaultExternalCheckGRPCAddress returns the address checks send gRPC reports to when none is configured.  The
+// kuberhealthy service is expected to expose the same port that the gRPC reporting API listens on.
+func defaultExternalCheckGRPCAddress(namespace string, listenAddress string) (string, error) {
+	_, port, err := net.SplitHostPort(listenAddress)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort("kuberhealthy."+namespace+".svc.cluster.local", port), nil
+}
+
+// setClusterIdentity sets the cluster name and environment of the config from env variables and flags.  Flags take
+// precedence over env variables, which take precedence over the config file.
+func setClusterIdentity() {
+	if clusterName := os.Getenv(KHClusterName); len(clusterName) > 0 {
+		cfg.ClusterName = clusterName
+	}
+	if environment := os.Getenv(KHEnvironment); len(environment) > 0 {
+		cfg.Environment = environment
+	}
+	if len(clusterNameFlag) > 0 {
+		cfg.ClusterName = clusterNameFlag
+	}
+	if len(environmentFlag) > 0 {
+		cfg.Environment = environmentFlag
+	}
+}
+
+// applyFlags sets the options that were set with flags on the config.  Flags take precedence over the config file
+// and env variables.
+func applyFlags() {
+	if forceMasterFlag {
+		cfg.EnableForceMaster = true
+	}
+	if len(tlsCertFileFlag) > 0 {
+		cfg.TLSCertFile = tlsCertFileFlag
+	}
+	if len(tlsKeyFileFlag) > 0 {
+		cfg.TLSKeyFile = tlsKeyFileFlag
+	}
+	if len(debugListenAddressFlag) > 0 {
+		cfg.DebugListenAddress = debugListenAddressFlag
+	}
+	if kubeClientQPSFlag > 0 {
+		cfg.KubeClient.QPS = kubeClientQPSFlag
+	}
+	if kubeClientBurstFlag > 0 {
+		cfg.KubeClient.Burst = kubeClientBurstFlag
+	}
+	if kubeClientTimeoutFlag > 0 {
+		cfg.KubeClient.Timeout = kubeClientTimeoutFlag
+	}
+	if allowHostAccessFlag {
+		cfg.HostAccess.Enabled = true
+	}
+}
+
+// setLogLevel sets the logging level from the config.  Debug logging is always used when the debug flag is set.
+func setLogLevel() error {
+	if debugFlag {
+		log.SetLevel(log.DebugLevel)
+		return nil
+	}
+
+	parsedLogLevel, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	if parsedLogLevel != log.GetLevel() {
+		log.Infoln("Setting log level to:", parsedLogLevel)
+	}
+	log.SetLevel(parsedLogLevel)
 	return nil
 }
 
 // setUp loads, parses, and sets various Kuberhealthy configurations -- from flags, config values and env vars.
 func setUp() error {
 
-	var useDebugMode bool
-
 	// setup global config struct
 	err := setUpConfig()
 	if err != nil {
@@ -221,26 +461,57 @@ func setUp() error {
 	// setup flaggy
 	flaggy.SetDescription("Kuberhealthy is an in-cluster synthetic health checker for Kubernetes.")
 	flaggy.String(&configPath, "c", "config", "Absolute path to the kuberhealthy config file")
-	flaggy.Bool(&useDebugMode, "d", "debug", "Set to true to enable debug.")
-	flaggy.Bool(&cfg.EnableForceMaster, "", "forceMaster", "Set to force master responsibilities on.")
+	flaggy.Bool(&debugFlag, "d", "debug", "Set to true to enable debug.")
+	flaggy.Bool(&forceMasterFlag, "", "forceMaster", "Set to force master responsibilities on.")
+	flaggy.String(&tlsCertFileFlag, "", "tlsCertFile", "Path to a TLS certificate to serve the web server with.")
+	flaggy.String(&tlsKeyFileFlag, "", "tlsKeyFile", "Path to a TLS key to serve the web server with.")
+	flaggy.String(&clusterNameFlag, "", "clusterName", "The name of the cluster added to all metrics, statuses, notifications and exported results.")
+	flaggy.String(&environmentFlag, "", "environment", "The environment of the cluster, such as production, added alongside the cluster name.")
+	flaggy.String(&debugListenAddressFlag, "", "debugListenAddress", "The address to serve pprof, expvar and goroutine dumps on, such as localhost:6060. Disabled by default.")
+	flaggy.Float32(&kubeClientQPSFlag, "", "kubeClientQPS", "The sustained number of requests per second sent to the Kubernetes API server.")
+	flaggy.Int(&kubeClientBurstFlag, "", "kubeClientBurst", "The number of requests that may be sent to the Kubernetes API server at once above the QPS.")
+	flaggy.Duration(&kubeClientTimeoutFlag, "", "kubeClientTimeout", "How long a single request to the Kubernetes API server may take.")
+	flaggy.Bool(&allowHostAccessFlag, "", "allowHostAccess", "Set to let checker pods in the namespaces listed in hostAccess.namespaces set hostNetwork or hostPID.")
 	flaggy.Parse()
+	setClusterIdentity()
+	applyFlags()
 
-	// parse and set logging level
-	parsedLogLevel, err := log.ParseLevel(cfg.LogLevel)
+	// if TLS was only enabled with flags, checks must still be told to report in over https
+	if cfg.tlsEnabled() && len(os.Getenv(KHExternalReportingURL)) == 0 && len(podNamespace) > 0 {
+		cfg.ExternalCheckReportingURL = defaultExternalCheckReportingURL(podNamespace, true)
+	}
+
+	// issue client certificates to checker pods if reports must use mutual TLS
+	if cfg.ReportClientCerts.Enabled {
+		if !cfg.tlsEnabled() {
+			return errors.New("reportClientCerts requires the web server to be served over TLS with tlsCertFile and tlsKeyFile")
+		}
+		clientCertIssuer, err = external.NewClientCertIssuer(cfg.ReportClientCerts)
+		if err != nil {
+			return fmt.Errorf("failed to set up client certificates for checker pods: %w", err)
+		}
+		log.Infoln("Checker pods must authenticate their reports with client certificates issued from", cfg.ReportClientCerts.CACertFile)
+	}
+
+	// only run signed checker images if image verification is enabled
+	if cfg.ImageVerification.Enabled {
+		imageVerifier, err = imageverify.NewVerifier(cfg.ImageVerification)
+		if err != nil {
+			return fmt.Errorf("failed to set up image signature verification: %w", err)
+		}
+		log.Infoln("Checker images must be signed by a trusted signer")
+	}
+
+	// log to stdout and parse and set logging level. no matter what if user has specified debug leveling, use debug leveling
+	log.SetOutput(os.Stdout)
+	err = setLogLevel()
 	if err != nil {
 		err := fmt.Errorf("unable to parse log-level flag: %s", err)
 		return err
 	}
-
-	// log to stdout and set the level to info by default
-	log.SetOutput(os.Stdout)
-	log.SetLevel(parsedLogLevel)
 	log.Infoln("Startup Arguments:", os.Args)
-
-	// no matter what if user has specified debug leveling, use debug leveling
-	if useDebugMode {
+	if debugFlag {
 		log.Infoln("Setting debug output on because user specified flag")
-		log.SetLevel(log.DebugLevel)
 	}
 
 	// Handle force master mode
@@ -256,9 +527,6 @@ func setUp() error {
 		return err
 	}
 
-	// determine the name of this pod from the POD_NAME environment variable
-	cfg.TargetNamespace = os.Getenv("TARGET_NAMESPACE")
-
 	// setup all clients
 	err = initKubernetesClients()
 	if err != nil {
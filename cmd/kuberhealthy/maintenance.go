@@ -0,0 +1,76 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// maintenanceWindowActive determines if a maintenance window is open at the supplied time.  A window is open
+// when it started within its duration before now.
+func maintenanceWindowActive(window khcheckv1.MaintenanceWindow, now time.Time) (bool, error) {
+	schedule, err := parseCheckSchedule(window.Schedule)
+	if err != nil {
+		return false, err
+	}
+	duration, err := time.ParseDuration(window.Duration)
+	if err != nil {
+		return false, err
+	}
+
+	// the first start of the window since it would have last been able to start
+	start := nextScheduledRun(schedule, now.Add(-duration))
+	if start.IsZero() {
+		return false, nil
+	}
+	return !start.After(now), nil
+}
+
+// activeMaintenanceMode returns the mode of the open maintenance windows in the supplied list.  When several
+// windows are open at once, skipping runs wins over suppressing results.  Returns false if no window is open.
+func activeMaintenanceMode(windows []khcheckv1.MaintenanceWindow, now time.Time) (khcheckv1.MaintenanceMode, bool) {
+	var mode khcheckv1.MaintenanceMode
+	var active bool
+	for _, w := range windows {
+		open, err := maintenanceWindowActive(w, now)
+		if err != nil {
+			log.Errorln("Error evaluating maintenance window with schedule", w.Schedule, "and duration", w.Duration+":", err)
+			continue
+		}
+		if !open {
+			continue
+		}
+
+		active = true
+		if w.Mode != khcheckv1.MaintenanceModeSuppress {
+			return khcheckv1.MaintenanceModeSkip, true
+		}
+		mode = khcheckv1.MaintenanceModeSuppress
+	}
+	return mode, active
+}
+
+// maintenanceWindows returns the global maintenance windows along with the maintenance windows of the named
+// khcheck
+func (k *Kuberhealthy) maintenanceWindows(checkName string, checkNamespace string) []khcheckv1.MaintenanceWindow {
+	windows := append([]khcheckv1.MaintenanceWindow{}, cfg.MaintenanceWindows...)
+	kc, ok := k.cachedKHCheck(checkName, checkNamespace)
+	if !ok {
+		return windows
+	}
+	return append(windows, kc.Spec.MaintenanceWindows...)
+}
+
+// checkMaintenanceMode returns the mode of the maintenance windows that the named check is currently in.  Returns
+// false if the check is not in a maintenance window.
+func (k *Kuberhealthy) checkMaintenanceMode(checkName string, checkNamespace string) (khcheckv1.MaintenanceMode, bool) {
+	return activeMaintenanceMode(k.maintenanceWindows(checkName, checkNamespace), time.Now())
+}
+
+// checkInMaintenance determines if the named check is currently in a maintenance window
+func (k *Kuberhealthy) checkInMaintenance(checkName string, checkNamespace string) bool {
+	_, inMaintenance := k.checkMaintenanceMode(checkName, checkNamespace)
+	return inMaintenance
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// TestMaintenanceWindowActive ensures that maintenance windows are open for their duration after each start
+func TestMaintenanceWindowActive(t *testing.T) {
+	window := khcheckv1.MaintenanceWindow{
+		Schedule: "0 2 * * *",
+		Duration: "1h",
+	}
+
+	testCases := []struct {
+		now      time.Time
+		expected bool
+	}{
+		{now: time.Date(2021, 1, 1, 1, 59, 0, 0, time.UTC), expected: false},
+		{now: time.Date(2021, 1, 1, 2, 0, 0, 0, time.UTC), expected: true},
+		{now: time.Date(2021, 1, 1, 2, 30, 0, 0, time.UTC), expected: true},
+		{now: time.Date(2021, 1, 1, 3, 0, 0, 0, time.UTC), expected: false},
+		{now: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC), expected: false},
+	}
+
+	for _, tc := range testCases {
+		active, err := maintenanceWindowActive(window, tc.now)
+		if err != nil {
+			t.Fatal("Unexpected error evaluating maintenance window:", err)
+		}
+		if active != tc.expected {
+			t.Fatalf("Expected maintenance window active at %s to be %t but got %t", tc.now, tc.expected, active)
+		}
+	}
+
+	_, err := maintenanceWindowActive(khcheckv1.MaintenanceWindow{Schedule: "0 2 * * *", Duration: "forever"}, time.Now())
+	if err == nil {
+		t.Fatal("Expected an error for a maintenance window with an invalid duration")
+	}
+}
+
+// TestActiveMaintenanceMode ensures that skipping runs wins over suppressing results when windows overlap
+func TestActiveMaintenanceMode(t *testing.T) {
+	now := time.Date(2021, 1, 1, 2, 30, 0, 0, time.UTC)
+	suppress := khcheckv1.MaintenanceWindow{Schedule: "0 2 * * *", Duration: "1h", Mode: khcheckv1.MaintenanceModeSuppress}
+	skip := khcheckv1.MaintenanceWindow{Schedule: "15 2 * * *", Duration: "1h"}
+	closed := khcheckv1.MaintenanceWindow{Schedule: "0 12 * * *", Duration: "1h"}
+
+	mode, active := activeMaintenanceMode([]khcheckv1.MaintenanceWindow{suppress}, now)
+	if !active || mode != khcheckv1.MaintenanceModeSuppress {
+		t.Fatalf("Expected an active suppress window but got %q %t", mode, active)
+	}
+
+	mode, active = activeMaintenanceMode([]khcheckv1.MaintenanceWindow{suppress, skip}, now)
+	if !active || mode != khcheckv1.MaintenanceModeSkip {
+		t.Fatalf("Expected an active skip window but got %q %t", mode, active)
+	}
+
+	_, active = activeMaintenanceMode([]khcheckv1.MaintenanceWindow{closed}, now)
+	if active {
+		t.Fatal("Expected no active maintenance window")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// storeNodeReport stores the report of a checker pod of a check that runs on all nodes on the khstate of the
+// check.  Reports for a run that is no longer the current run of the check are rejected.
+func storeNodeReport(podReport PodReportInfo, ok bool, reportErrors []string) error {
+	// the checker pods of other nodes report at the same time, so their reports are batched into one write
+	_, err := stateWrites.write(podReport.Namespace, podReport.Name, func(khState *khstatev1.KuberhealthyState) error {
+		if khState.Spec.CurrentUUID != podReport.UUID {
+			return errors.New("node report is for run " + podReport.UUID + " but the current run is " + khState.Spec.CurrentUUID)
+		}
+		log.Debugln("Storing report of node", podReport.NodeName, "for check", podReport.Name, "in namespace", podReport.Namespace)
+		applyNodeReport(&khState.Spec, podReport.NodeName, khstatev1.NodeReport{
+			OK:     ok,
+			Errors: reportErrors,
+			UUID:   podReport.UUID,
+		})
+		return nil
+	})
+	return err
+}
+
+// applyNodeReport records the report of a node on the supplied khstate spec.  Reports left over from earlier runs
+// are dropped.
+func applyNodeReport(details *khstatev1.WorkloadDetails, nodeName string, report khstatev1.NodeReport) {
+	nodeReports := make(map[string]khstatev1.NodeReport)
+	for n, r := range details.NodeReports {
+		if r.UUID == report.UUID {
+			nodeReports[n] = r
+		}
+	}
+	nodeReports[nodeName] = report
+	details.NodeReports = nodeReports
+}
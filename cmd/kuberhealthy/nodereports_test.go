@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestApplyNodeReport ensures that node reports are added to the reports of the same run and replace those of
+// earlier runs
+func TestApplyNodeReport(t *testing.T) {
+	details := khstatev1.NewWorkloadDetails(khstatev1.KHCheck)
+	details.NodeReports = map[string]khstatev1.NodeReport{
+		"worker-1": {OK: true, UUID: "old-run"},
+		"worker-2": {OK: true, UUID: "current-run"},
+	}
+
+	applyNodeReport(&details, "worker-3", khstatev1.NodeReport{OK: false, Errors: []string{"failed"}, UUID: "current-run"})
+
+	if len(details.NodeReports) != 2 {
+		t.Fatal("Expected two node reports but got", details.NodeReports)
+	}
+	if _, ok := details.NodeReports["worker-1"]; ok {
+		t.Fatal("Expected the report of an earlier run to be dropped")
+	}
+	if r := details.NodeReports["worker-3"]; r.OK || r.UUID != "current-run" {
+		t.Fatalf("Expected the report of worker-3 to be stored but got %+v", r)
+	}
+}
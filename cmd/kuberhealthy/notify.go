@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/notifications"
+)
+
+// notifyStateTransition sends notifications if a check or job has changed between OK and failing states.  Nothing
+// is sent for the first result of a check, except for refreshing notifications that expire on their own.
+// Notifications are delivered in the background.
+func notifyStateTransition(checkName string, checkNamespace string, previous khstatev1.WorkloadDetails, current khstatev1.WorkloadDetails, podName string) {
+
+	workload := current.GetKHWorkload()
+	runDuration, _ := time.ParseDuration(current.RunDuration)
+	transition := notifications.Transition{
+		CheckName:     checkName,
+		Namespace:     checkNamespace,
+		OK:            current.OK,
+		Errors:        current.Errors,
+		PodName:       podName,
+		RunUUID:       current.CurrentUUID,
+		RunDuration:   runDuration,
+		FailureReason: string(current.FailureReason),
+		Severity:      current.Severity,
+		Cluster:       cfg.ClusterName,
+		Environment:   cfg.Environment,
+		Time:          time.Now(),
+	}
+	config := cfg.Notifications
+
+	// checks that have never run before have no state to transition from.  checks that keep failing refresh the
+	// notifications that would otherwise expire, such as alertmanager alerts.
+	if previous.LastRun == nil || previous.OK == current.OK {
+		if !current.OK && config.NeedsRefresh() {
+			go func() {
+				completeTransition(&transition, workload, current.CurrentUUID)
+				notifications.SendRefresh(notifications.RouteNotifiers(config, transition), transition)
+			}()
+		}
+		return
+	}
+
+	log.Infoln("notifications:", workload, checkNamespace+"/"+checkName, "changed from OK", previous.OK, "to OK", current.OK)
+
+	go func() {
+		completeTransition(&transition, workload, current.CurrentUUID)
+		notifications.Send(notifications.RouteNotifiers(config, transition), transition)
+	}()
+}
+
+// completeTransition fills in the annotations and labels of the khcheck or khjob of a transition, the labels
+// selected with the checkLabels setting, and the checker pod that reported it if it is not known yet
+func completeTransition(transition *notifications.Transition, workload khstatev1.KHWorkload, uuid string) {
+	meta := workloadMetadata(transition.CheckName, transition.Namespace, workload)
+	transition.Annotations = meta.GetAnnotations()
+	transition.Labels = meta.GetLabels()
+	if workload == khstatev1.KHCheck {
+		transition.CheckLabels = selectCheckLabels(meta, cfg.CheckLabels)
+	}
+	if len(transition.PodName) == 0 {
+		transition.PodName = checkerPodNameForUUID(transition.Namespace, uuid)
+	}
+}
+
+// workloadMetadata fetches the metadata of the khcheck or khjob with the supplied name.  Empty metadata is returned
+// if the resource can not be fetched.
+func workloadMetadata(name string, namespace string, workload khstatev1.KHWorkload) metav1.ObjectMeta {
+	switch workload {
+	case khstatev1.KHJob:
+		kj, err := khJobClient.KuberhealthyJobs(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			log.Errorln("notifications: failed to fetch khjob", namespace+"/"+name+":", err)
+			return metav1.ObjectMeta{}
+		}
+		return kj.ObjectMeta
+	default:
+		kc, err := khCheckClient.KuberhealthyChecks(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			log.Errorln("notifications: failed to fetch khcheck", namespace+"/"+name+":", err)
+			return metav1.ObjectMeta{}
+		}
+		return kc.ObjectMeta
+	}
+}
+
+// checkerPodNameForUUID looks up the name of the checker pod that was started for the supplied run UUID.  A blank
+// name is returned if the pod can not be found.
+func checkerPodNameForUUID(namespace string, uuid string) string {
+	if len(uuid) == 0 {
+		return ""
+	}
+
+	podList, err := kubernetesClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "kuberhealthy-run-id=" + uuid,
+	})
+	if err != nil {
+		log.Errorln("notifications: failed to look up checker pod for run", uuid+":", err)
+		return ""
+	}
+	if len(podList.Items) == 0 {
+		return ""
+	}
+	return podList.Items[0].Name
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// pauseCheckPath is the web server path used to pause an external check
+const pauseCheckPath = "/api/v1/checks/{namespace}/{name}/pause"
+
+// resumeCheckPath is the web server path used to resume a paused external check
+const resumeCheckPath = "/api/v1/checks/{namespace}/{name}/resume"
+
+// pauseCheckMaxTries is the number of times pausing or resuming a check is attempted when the khcheck is being
+// updated at the same time
+const pauseCheckMaxTries = 5
+
+// pauseCheckResponse is the body written back to callers of the pause and resume endpoints
+type pauseCheckResponse struct {
+	Check  string `json:"check,omitempty"` // the namespace and name of the check
+	Paused bool   `json:"paused"`          // whether the check is now paused
+	Error  string `json:"error,omitempty"` // why the check could not be paused or resumed
+}
+
+// checkPaused determines if the named check is paused from the khcheck informer cache
+func (k *Kuberhealthy) checkPaused(checkName string, checkNamespace string) bool {
+	kc, ok := k.cachedKHCheck(checkName, checkNamespace)
+	if !ok {
+		return false
+	}
+	return kc.IsPaused()
+}
+
+// setCheckPausedHandler returns a handler that pauses or resumes the requested check by setting the paused field of
+// its khcheck spec.  Resuming a check also removes the paused annotation.  The master picks up the change like any
+// other change to the khcheck.  Only callers that are allowed to update the khcheck may pause or resume it.
+func (k *Kuberhealthy) setCheckPausedHandler(paused bool) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		checkNamespace := r.PathValue("namespace")
+		checkName := r.PathValue("name")
+		response := pauseCheckResponse{
+			Check: checkNamespace + "/" + checkName,
+		}
+		log.Infoln("Client connected to pause check endpoint for", response.Check, "with paused", paused, "from", r.RemoteAddr, r.UserAgent())
+
+		user, err := authorizeCheckRequest(r.Context(), kubernetesClient, r, "update", checkName, checkNamespace)
+		if err != nil {
+			log.Warningln("Rejected request to set paused of check", response.Check, "to", paused, "from", r.RemoteAddr+":", err)
+			response.Paused = !paused
+			response.Error = err.Error()
+			return writePauseCheckResponse(w, reportStatusCode(err), response)
+		}
+
+		err = setCheckPaused(checkName, checkNamespace, paused)
+		if err != nil {
+			response.Paused = !paused
+			response.Error = err.Error()
+			statusCode := http.StatusInternalServerError
+			if k8sErrors.IsNotFound(err) {
+				statusCode = http.StatusNotFound
+			}
+			return writePauseCheckResponse(w, statusCode, response)
+		}
+
+		response.Paused = paused
+		log.Infoln("Set paused of check", response.Check, "to", paused, "for", user)
+		return writePauseCheckResponse(w, http.StatusOK, response)
+	}
+}
+
+// setCheckPaused sets the paused field of the spec of a khcheck, retrying when the khcheck is updated at the same time
+func setCheckPaused(checkName string, checkNamespace string, paused bool) error {
+	var err error
+	for tries := 0; tries < pauseCheckMaxTries; tries++ {
+		var kc khcheckv1.KuberhealthyCheck
+		kc, err = khCheckClient.KuberhealthyChecks(checkNamespace).Get(checkName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if kc.IsPaused() == paused {
+			return nil
+		}
+
+		kc.Spec.Paused = paused
+		if !paused {
+			delete(kc.Annotations, khcheckv1.PausedAnnotation)
+		}
+		_, err = khCheckClient.KuberhealthyChecks(checkNamespace).Update(&kc)
+		if !k8sErrors.IsConflict(err) {
+			return err
+		}
+		log.Debugln("Conflict setting paused of check", checkName, "in namespace", checkNamespace+". Retrying.")
+	}
+	return errors.New("khcheck kept changing while setting paused: " + err.Error())
+}
+
+// writePauseCheckResponse writes a pause check response back to the caller as JSON with the supplied status code
+func writePauseCheckResponse(w http.ResponseWriter, statusCode int, response pauseCheckResponse) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(response)
+}
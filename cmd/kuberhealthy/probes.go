@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/masterCalculation"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/statestore"
+)
+
+// readyzPath is the path of the readiness probe, which only succeeds once Kuberhealthy can serve and store check
+// results
+const readyzPath = "/readyz"
+
+// livezPath is the path of the liveness probe, which fails when a control loop stops making progress
+const livezPath = "/livez"
+
+// readinessCacheDuration is how long the result of the readiness checks is reused, so that frequent probes do not
+// put load on the API server
+const readinessCacheDuration = time.Second * 10
+
+// readinessCheckTimeout is how long all readiness checks together may take
+const readinessCheckTimeout = time.Second * 5
+
+// controlLoopHeartbeatInterval is how often the control loop reports that it is making progress
+const controlLoopHeartbeatInterval = time.Second * 30
+
+// controlLoopStallTimeout is how long the control loop may go without making progress before it is considered
+// deadlocked.  Restarting checks waits for every check to stop, which can take a while.
+const controlLoopStallTimeout = time.Minute * 15
+
+// controlLoops records when each control loop last made progress
+var controlLoops = newHeartbeats()
+
+// probeResult is the outcome of a single readiness or liveness check
+type probeResult struct {
+	name string
+	err  error
+}
+
+// heartbeats records when each control loop last made progress and how long it may go without doing so
+type heartbeats struct {
+	sync.Mutex
+	last     map[string]time.Time
+	timeouts map[string]time.Duration
+}
+
+// newHeartbeats creates an empty set of heartbeats
+func newHeartbeats() *heartbeats {
+	return &heartbeats{
+		last:     make(map[string]time.Time),
+		timeouts: make(map[string]time.Duration),
+	}
+}
+
+// beat records that the named loop made progress and may go for the supplied timeout before it does so again
+func (h *heartbeats) beat(name string, timeout time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	h.last[name] = time.Now()
+	h.timeouts[name] = timeout
+}
+
+// stop forgets the named loop when it shuts down on purpose
+func (h *heartbeats) stop(name string) {
+	h.Lock()
+	defer h.Unlock()
+	delete(h.last, name)
+	delete(h.timeouts, name)
+}
+
+// stalled returns the names of the loops that have not made progress within their timeout, in alphabetical order
+func (h *heartbeats) stalled(now time.Time) []string {
+	h.Lock()
+	defer h.Unlock()
+
+	var names []string
+	for name, last := range h.last {
+		if now.Sub(last) > h.timeouts[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readinessCache holds the result of the last readiness checks
+type readinessCache struct {
+	sync.Mutex
+	checkedAt time.Time
+	results   []probeResult
+}
+
+// readinessChecks runs every readiness check, or returns the results of the last run if they are recent enough
+func (k *Kuberhealthy) readinessChecks() []probeResult {
+	k.readiness.Lock()
+	defer k.readiness.Unlock()
+	if time.Since(k.readiness.checkedAt) < readinessCacheDuration {
+		return k.readiness.results
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+	defer cancel()
+	k.readiness.results = []probeResult{
+		{name: "informers", err: k.informersSynced()},
+		{name: "khchecks", err: khChecksReachable(k.TargetNamespace)},
+		{name: "khstates", err: khStatesReachable(k.TargetNamespace)},
+		{name: "khstate-writes", err: khStatesWritable(ctx, k.TargetNamespace)},
+		{name: "master-election", err: masterSettled()},
+	}
+	k.readiness.checkedAt = time.Now()
+	return k.readiness.results
+}
+
+// informersSynced returns an error if the khcheck informer or the khstate reflector have not finished their first
+// listing yet
+func (k *Kuberhealthy) informersSynced() error {
+	if k.khCheckInformer == nil || !k.khCheckInformer.HasSynced() {
+		return errors.New("khcheck informer has not synced")
+	}
+	if !k.stateReflector.HasSynced() {
+		return errors.New("khstate reflector has not synced")
+	}
+	return nil
+}
+
+// khChecksReachable returns an error if khcheck resources can not be listed
+func khChecksReachable(namespace string) error {
+	_, err := khCheckClient.KuberhealthyChecks(namespace).List(metav1.ListOptions{Limit: 1})
+	return err
+}
+
+// khStatesReachable returns an error if khstates can not be listed from the state storage backend
+func khStatesReachable(namespace string) error {
+	_, err := khStateClient.KuberhealthyStates(namespace).List(metav1.ListOptions{Limit: 1})
+	return err
+}
+
+// khStatesWritable returns an error if our service account is not allowed to update khstates in the state storage
+// backend.  Redis does not use Kubernetes permissions, so it is only checked by listing khstates.
+func khStatesWritable(ctx context.Context, namespace string) error {
+	attributes := &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "update",
+		Group:     checkCRDGroup,
+		Resource:  stateCRDResource,
+	}
+	switch cfg.StateStorage.Backend {
+	case statestore.BackendRedis:
+		return nil
+	case statestore.BackendConfigMap:
+		attributes.Group = ""
+		attributes.Resource = "configmaps"
+	}
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+	}
+	review, err := kubernetesClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("not allowed to update %s: %s", attributes.Resource, review.Status.Reason)
+	}
+	return nil
+}
+
+// masterSettled returns an error if master election has not settled on a master yet
+func masterSettled() error {
+	if masterCalculation.IsForcedMaster() || isMaster {
+		return nil
+	}
+	_, err := masterCalculation.CalculateMaster(kubernetesClient)
+	return err
+}
+
+// livenessChecks returns a failed result for every control loop that has stopped making progress
+func livenessChecks() []probeResult {
+	results := []probeResult{}
+	for _, name := range controlLoops.stalled(time.Now()) {
+		results = append(results, probeResult{name: name, err: errors.New("control loop has not made progress")})
+	}
+	if len(results) == 0 {
+		results = append(results, probeResult{name: "control-loops"})
+	}
+	return results
+}
+
+// writeProbeResults writes the results of probe checks in the format of the Kubernetes API server, with one line
+// per check.  The status code is 503 when any check failed.
+func writeProbeResults(w http.ResponseWriter, probe string, results []probeResult) {
+	var b strings.Builder
+	failed := false
+	for _, r := range results {
+		if r.err != nil {
+			failed = true
+			b.WriteString("[-]" + r.name + " failed: " + r.err.Error() + "\n")
+			continue
+		}
+		b.WriteString("[+]" + r.name + " ok\n")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if failed {
+		log.Warningln(probe, "check failed:", strings.TrimSpace(b.String()))
+		b.WriteString(probe + " check failed\n")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		b.WriteString(probe + " check passed\n")
+	}
+	_, err := w.Write([]byte(b.String()))
+	if err != nil {
+		log.Warningln("Error writing", probe, "response:", err)
+	}
+}
+
+// readyzHandler serves the readiness probe
+func (k *Kuberhealthy) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbeResults(w, "readyz", k.readinessChecks())
+}
+
+// livezHandler serves the liveness probe
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbeResults(w, "livez", livenessChecks())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHeartbeatsStalled ensures that only loops that have gone without a heartbeat for longer than their timeout are
+// reported as stalled, and that stopped loops are forgotten
+func TestHeartbeatsStalled(t *testing.T) {
+	h := newHeartbeats()
+	h.beat("control", time.Minute)
+	h.beat("reaper", time.Hour)
+
+	stalled := h.stalled(time.Now())
+	if len(stalled) != 0 {
+		t.Fatalf("Expected no stalled loops right after their heartbeat but found %v", stalled)
+	}
+
+	stalled = h.stalled(time.Now().Add(time.Minute * 2))
+	if len(stalled) != 1 || stalled[0] != "control" {
+		t.Fatalf("Expected only the control loop to be stalled but found %v", stalled)
+	}
+
+	h.stop("control")
+	stalled = h.stalled(time.Now().Add(time.Hour * 2))
+	if len(stalled) != 1 || stalled[0] != "reaper" {
+		t.Fatalf("Expected the stopped control loop to be forgotten but found %v", stalled)
+	}
+}
+
+// TestWriteProbeResults ensures that probes fail with a 503 and list every check when any of them failed
+func TestWriteProbeResults(t *testing.T) {
+	testCases := []struct {
+		description  string
+		results      []probeResult
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			description:  "all checks passed",
+			results:      []probeResult{{name: "informers"}, {name: "khstates"}},
+			expectedCode: http.StatusOK,
+			expectedBody: "[+]informers ok\n[+]khstates ok\nreadyz check passed\n",
+		},
+		{
+			description:  "a check failed",
+			results:      []probeResult{{name: "informers"}, {name: "khstates", err: errors.New("connection refused")}},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "[+]informers ok\n[-]khstates failed: connection refused\nreadyz check failed\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		writeProbeResults(recorder, "readyz", tc.results)
+		if recorder.Code != tc.expectedCode {
+			t.Fatalf("%s: expected status code %d but got %d", tc.description, tc.expectedCode, recorder.Code)
+		}
+		if recorder.Body.String() != tc.expectedBody {
+			t.Fatalf("%s: unexpected body: %q", tc.description, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+)
+
+// externalCheckProgressPath is the endpoint that checker pods send progress updates to while their run is in flight
+const externalCheckProgressPath = "/externalCheckProgress"
+
+// maxProgressMessageLength is the maximum length of the message of a progress update.  Longer messages are cut off.
+const maxProgressMessageLength = 1024
+
+// externalCheckProgressHandler handles progress updates from the checker pods of runs that are still in flight.
+// Progress updates are authenticated the same way as reports and stored on the khstate of the check, where they
+// are shown on the status page until the run completes.
+func (k *Kuberhealthy) externalCheckProgressHandler(w http.ResponseWriter, r *http.Request) error {
+	requestID := "web: " + uuid.New().String()
+
+	podReport, err := k.authenticateReport(r.Context(), requestID, reportCredentials{
+		runUUID:       r.Header.Get("kh-run-uuid"),
+		remoteAddr:    r.RemoteAddr,
+		authorization: r.Header.Get("Authorization"),
+		tls:           r.TLS,
+	})
+	if err != nil {
+		w.WriteHeader(reportStatusCode(err))
+		return nil
+	}
+	requestID = requestID + " (" + podReport.Namespace + "/" + podReport.Name + ")"
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		k.externalCheckReportHandlerLog(requestID, "Failed to read request body:", err.Error(), r.RemoteAddr)
+		return nil
+	}
+	progress := status.Progress{}
+	err = json.Unmarshal(b, &progress)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		k.externalCheckReportHandlerLog(requestID, "Failed to unmarshal progress json:", err, r.RemoteAddr)
+		return nil
+	}
+
+	err = k.storeProgress(requestID, podReport, progress)
+	if err != nil {
+		statusCode := reportStatusCode(err)
+		w.WriteHeader(statusCode)
+		if statusCode == http.StatusInternalServerError {
+			return err
+		}
+		return nil
+	}
+
+	w.WriteHeader(http.StatusOK)
+	k.externalCheckReportHandlerLog(requestID, "Progress update completed successfully.")
+	return nil
+}
+
+// validateProgress ensures that a progress update has a message and sensible step counts
+func validateProgress(progress status.Progress) error {
+	if len(progress.Message) == 0 {
+		return errors.New("progress update has no message")
+	}
+	if progress.Step < 0 || progress.TotalSteps < 0 {
+		return errors.New("progress update has a negative step count")
+	}
+	if progress.TotalSteps > 0 && progress.Step > progress.TotalSteps {
+		return fmt.Errorf("progress update is at step %d of only %d steps", progress.Step, progress.TotalSteps)
+	}
+	return nil
+}
+
+// newRunProgress converts a validated progress update into the progress stored on a khstate.  Secrets are scrubbed
+// out of the message, and long messages are cut off.
+func newRunProgress(progress status.Progress, podReport PodReportInfo, sensitiveValues []string, now time.Time) *khstatev1.RunProgress {
+	message := redact.String(progress.Message, sensitiveValues)
+	if len(message) > maxProgressMessageLength {
+		message = strings.ToValidUTF8(message[:maxProgressMessageLength], "")
+	}
+	return &khstatev1.RunProgress{
+		Message:    message,
+		Step:       progress.Step,
+		TotalSteps: progress.TotalSteps,
+		Node:       podReport.NodeName,
+		UpdateTime: metav1.NewTime(now),
+		UUID:       podReport.UUID,
+	}
+}
+
+// storeProgress stores a progress update on the khstate of its check.  Updates for a run that is no longer the
+// current run of the check are rejected.
+func (k *Kuberhealthy) storeProgress(requestID string, podReport PodReportInfo, progress status.Progress) error {
+	err := validateProgress(progress)
+	if err != nil {
+		k.externalCheckReportHandlerLog(requestID, "Client sent an invalid progress update:", err)
+		return &reportError{statusCode: http.StatusBadRequest, err: err}
+	}
+	runProgress := newRunProgress(progress, podReport, k.sensitiveValues(podReport.Name, podReport.Namespace), time.Now())
+
+	name := sanitizeResourceName(podReport.Name)
+	_, err = stateWrites.write(podReport.Namespace, name, func(khState *khstatev1.KuberhealthyState) error {
+		if khState.Spec.CurrentUUID != podReport.UUID {
+			k.externalCheckReportHandlerLog(requestID, "Client sent progress for run", podReport.UUID, "but the current run is", khState.Spec.CurrentUUID)
+			return &reportError{statusCode: http.StatusBadRequest, err: errors.New("progress is for run " + podReport.UUID + " but the current run is " + khState.Spec.CurrentUUID)}
+		}
+
+		k.externalCheckReportHandlerLog(requestID, "Setting progress of check", podReport.Name, "in namespace", podReport.Namespace, "to step", runProgress.Step, "of", runProgress.TotalSteps, "with message:", runProgress.Message)
+		khState.Spec.Progress = runProgress
+		return nil
+	})
+	var reportErr *reportError
+	if errors.As(err, &reportErr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store progress for %s: %w", podReport.Name, err)
+	}
+	return nil
+}
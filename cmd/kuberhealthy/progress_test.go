@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// TestValidateProgress ensures that progress updates without a message or with impossible step counts are rejected
+func TestValidateProgress(t *testing.T) {
+	testCases := []struct {
+		progress    status.Progress
+		expectError bool
+	}{
+		{progress: status.Progress{Message: "created test deployment", Step: 3, TotalSteps: 7}},
+		{progress: status.Progress{Message: "waiting for pods"}},
+		{progress: status.Progress{Message: "waiting for pods", Step: 4}},
+		{progress: status.Progress{Message: "done", Step: 7, TotalSteps: 7}},
+		{progress: status.Progress{Step: 1, TotalSteps: 7}, expectError: true},
+		{progress: status.Progress{Message: "rewinding", Step: -1}, expectError: true},
+		{progress: status.Progress{Message: "overtime", Step: 8, TotalSteps: 7}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		err := validateProgress(tc.progress)
+		if tc.expectError != (err != nil) {
+			t.Fatalf("%+v: expected error %t but got %v", tc.progress, tc.expectError, err)
+		}
+	}
+}
+
+// TestNewRunProgress ensures that stored progress has secrets scrubbed out and long messages cut off
+func TestNewRunProgress(t *testing.T) {
+	now := time.Now()
+	podReport := PodReportInfo{Name: "deployment", Namespace: "kuberhealthy", UUID: "run-uuid", NodeName: "node-a"}
+
+	runProgress := newRunProgress(status.Progress{Message: "logged in with hunter2", Step: 2, TotalSteps: 5}, podReport, []string{"hunter2"}, now)
+	if strings.Contains(runProgress.Message, "hunter2") {
+		t.Fatal("Expected the secret to be scrubbed from the progress message but got:", runProgress.Message)
+	}
+	if runProgress.Step != 2 || runProgress.TotalSteps != 5 || runProgress.UUID != "run-uuid" || runProgress.Node != "node-a" || !runProgress.UpdateTime.Time.Equal(now) {
+		t.Fatalf("Unexpected run progress: %+v", runProgress)
+	}
+
+	runProgress = newRunProgress(status.Progress{Message: strings.Repeat("a", maxProgressMessageLength*2)}, podReport, nil, now)
+	if len(runProgress.Message) != maxProgressMessageLength {
+		t.Fatalf("Expected the progress message to be cut off at %d bytes but it is %d bytes", maxProgressMessageLength, len(runProgress.Message))
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// NamespaceQuota limits the checker pods that run at the same time in a single namespace
+type NamespaceQuota struct {
+	MaxRunning int               `yaml:"maxRunning,omitempty"` // the maximum number of checker pods that run at once. zero falls back to maxConcurrentChecksPerNamespace
+	Requests   map[string]string `yaml:"requests,omitempty"`   // the total resource requests of the checker pods that run at once, keyed by resource name, such as cpu: "2"
+}
+
+// NamespaceQuotas holds the checker pod quotas of every namespace
+type NamespaceQuotas struct {
+	Default    NamespaceQuota            `yaml:"default,omitempty"`    // the quota of namespaces that do not have one of their own
+	Namespaces map[string]NamespaceQuota `yaml:"namespaces,omitempty"` // the quotas of individual namespaces, by namespace name
+}
+
+// Validate returns an error if any of the quota quantities can not be parsed
+func (q NamespaceQuotas) Validate() error {
+	_, err := parseQuotaRequests(q.Default.Requests)
+	if err != nil {
+		return fmt.Errorf("default namespace quota: %w", err)
+	}
+	for namespace, quota := range q.Namespaces {
+		_, err = parseQuotaRequests(quota.Requests)
+		if err != nil {
+			return fmt.Errorf("namespace quota of %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// namespaceLimit is the parsed quota of a namespace
+type namespaceLimit struct {
+	maxRunning int
+	requests   apiv1.ResourceList
+}
+
+// parseQuotaRequests parses the resource requests of a quota.  Quantities that can not be parsed are left out.
+func parseQuotaRequests(requests map[string]string) (apiv1.ResourceList, error) {
+	var firstErr error
+	list := make(apiv1.ResourceList)
+	for name, value := range requests {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid quantity %q for %s: %w", value, name, err)
+			}
+			continue
+		}
+		list[apiv1.ResourceName(name)] = quantity
+	}
+	return list, firstErr
+}
+
+// newNamespaceLimits parses the quotas of every namespace.  The default quota is stored under a blank name.
+func newNamespaceLimits(quotas NamespaceQuotas) map[string]namespaceLimit {
+	limits := make(map[string]namespaceLimit)
+	requests, _ := parseQuotaRequests(quotas.Default.Requests)
+	limits[""] = namespaceLimit{maxRunning: quotas.Default.MaxRunning, requests: requests}
+	for namespace, quota := range quotas.Namespaces {
+		requests, _ := parseQuotaRequests(quota.Requests)
+		limits[namespace] = namespaceLimit{maxRunning: quota.MaxRunning, requests: requests}
+	}
+	return limits
+}
+
+// exceededRequest returns the name of the first resource, in alphabetical order, whose quota would be exceeded by
+// adding the requests of a run to the requests already in use.  A blank name is returned when the run fits.
+func exceededRequest(quota apiv1.ResourceList, inUse apiv1.ResourceList, requests apiv1.ResourceList) apiv1.ResourceName {
+	names := make([]string, 0, len(quota))
+	for name := range quota {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		total := inUse[apiv1.ResourceName(name)].DeepCopy()
+		total.Add(requests[apiv1.ResourceName(name)])
+		if total.Cmp(quota[apiv1.ResourceName(name)]) > 0 {
+			return apiv1.ResourceName(name)
+		}
+	}
+	return ""
+}
+
+// delayReason describes why a run is waiting, such as "waiting for the cpu quota of namespace payments"
+func delayReason(limit string, namespace string) string {
+	if len(namespace) == 0 {
+		return "waiting for " + limit
+	}
+	return "waiting for " + limit + " of namespace " + namespace
+}
+
+// maxRunningReason describes a limit on the number of checker pods running at once
+func maxRunningReason(limit int) string {
+	return "the limit of " + strconv.Itoa(limit) + " checker pods running at once"
+}
+
+// setRunDelayed records on the khstate of a check or job why its next run is waiting to start.  A blank reason
+// clears it.  The khstate is written without touching the last run time, so it is not mistaken for a report.
+func (k *Kuberhealthy) setRunDelayed(name string, namespace string, reason string) {
+	khState, err := khStateClient.KuberhealthyStates(namespace).Get(sanitizeResourceName(name), metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			log.Errorln("Error fetching khstate to mark the run of", name, "in namespace", namespace, "as delayed:", err)
+		}
+		return
+	}
+	if khState.Spec.Delayed == reason {
+		return
+	}
+
+	_, err = stateWrites.write(namespace, sanitizeResourceName(name), func(khState *khstatev1.KuberhealthyState) error {
+		khState.Spec.Delayed = reason
+		return nil
+	})
+	if err != nil {
+		log.Errorln("Error marking the run of", name, "in namespace", namespace, "as delayed:", err)
+	}
+}
+
+// acquireRun waits until the checker pod of a check or job may start within the concurrency limits and the quota of
+// its namespace.  While the run is queued, its khstate is marked as delayed.  The returned func must be called when
+// the run is done.
+func (k *Kuberhealthy) acquireRun(ctx context.Context, c *external.Checker) (func(), error) {
+	var delayed bool
+	release, err := k.runLimiter.acquire(ctx, c.CheckNamespace(), c.Name(), c.ResourceRequests(), func(reason string) {
+		delayed = true
+		k.setRunDelayed(c.Name(), c.CheckNamespace(), reason)
+	})
+	if delayed {
+		k.setRunDelayed(c.Name(), c.CheckNamespace(), "")
+	}
+	return release, err
+}
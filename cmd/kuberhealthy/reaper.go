@@ -10,6 +10,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
 
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -54,10 +56,15 @@ func parseDurationOrUseDefault(d string, defaultDuration time.Duration) (time.Du
 // namespace, set the namespace string to ""
 func reaper(ctx context.Context, namespace string) {
 
-	reaperRunInterval, err := parseDurationOrUseDefault(checkReaperRunInterval, checkReaperRunIntervalDefault)
+	// the CHECK_REAPER_RUN_INTERVAL env variable takes precedence over the config file
+	defaultRunInterval := checkReaperRunIntervalDefault
+	if cfg.CheckReaperRunInterval > 0 {
+		defaultRunInterval = cfg.CheckReaperRunInterval
+	}
+	reaperRunInterval, err := parseDurationOrUseDefault(checkReaperRunInterval, defaultRunInterval)
 	if err != nil {
 		log.Errorln("checkReaper: Error occurred attempting to parse checkReaperRunInterval:", err)
-		log.Infoln("checkReaper: Using default checkReaperRunInterval:", checkReaperRunIntervalDefault)
+		log.Infoln("checkReaper: Using default checkReaperRunInterval:", defaultRunInterval)
 	}
 
 	// Parse configs when reaper starts up.
@@ -65,14 +72,23 @@ func reaper(ctx context.Context, namespace string) {
 	log.Infoln("checkReaper: run interval:", reaperRunInterval)
 	log.Infoln("checkReaper: max khjob age:", cfg.MaxKHJobAge)
 	log.Infoln("checkReaper: max khcheck pod age:", cfg.MaxCheckPodAge)
+	log.Infoln("checkReaper: max failed khcheck pod age:", cfg.maxFailedPodAge())
 	log.Infoln("checkReaper: max completed check pod count:", cfg.MaxCompletedPodCount)
 	log.Infoln("checkReaper: max error check pod count:", cfg.MaxErrorPodCount)
+	namespaces := cfg.reaperNamespaces(namespace)
+	log.Infoln("checkReaper: namespaces:", namespaces)
+	if cfg.ReaperDryRun {
+		log.Infoln("checkReaper: dry run enabled. checker pods and khjobs will not be deleted")
+	}
 
 	// set MaxCheckPodAge to minCheckPodAge before getting reaped if no maxCheckPodAge is set
 	// Want to make sure the completed pod is around for at least 30s before getting reaped
 	if cfg.MaxCheckPodAge < minCheckPodAge {
 		cfg.MaxCheckPodAge = minCheckPodAge
 	}
+	if cfg.MaxFailedPodAge != 0 && cfg.MaxFailedPodAge < minCheckPodAge {
+		cfg.MaxFailedPodAge = minCheckPodAge
+	}
 
 	// set MaxKHJobAge to minKHJobAge before getting reaped if no maxCheckPodAge is set
 	// Want to make sure the completed job is around for at least 5m before getting reaped
@@ -84,6 +100,12 @@ func reaper(ctx context.Context, namespace string) {
 	t := time.NewTicker(reaperRunInterval)
 	defer t.Stop()
 
+	// report that the reaper is making progress, so that the liveness probe can tell if it is stuck. each run may
+	// take up to its timeout of 3 minutes.
+	reaperStallTimeout := reaperRunInterval*2 + time.Minute*5
+	controlLoops.beat("reaper", reaperStallTimeout)
+	defer controlLoops.stop("reaper")
+
 	// iterate until our context expires and run reaper operations
 	keepGoing := true
 	for keepGoing {
@@ -93,9 +115,12 @@ func reaper(ctx context.Context, namespace string) {
 		runCtx, runCtxCancel := context.WithTimeout(ctx, time.Minute*3)
 		defer runCtxCancel()
 
-		// run our check and job reapers
-		runCheckReap(runCtx, namespace)
-		runJobReap(runCtx, namespace)
+		// run our check and job reapers in every namespace they clean up
+		for _, ns := range namespaces {
+			runCheckReap(runCtx, ns)
+			runJobReap(runCtx, ns)
+		}
+		controlLoops.beat("reaper", reaperStallTimeout)
 
 		// check if the parent context has expired
 		select {
@@ -137,14 +162,9 @@ func runCheckReap(ctx context.Context, namespace string) {
 
 // runJobReap runs a process to reap jobs that need deleted (those that were created by a khjob)
 func runJobReap(ctx context.Context, namespace string) {
-	jobClient, err := khjobv1.Client(cfg.kubeConfigFile)
-	if err != nil {
-		log.Errorln("checkReaper: Unable to create khJob client", err)
-	}
-
 	log.Infoln("checkReaper: Beginning to search for khjobs.")
 	// fetch and delete khjobs that meet criteria
-	err = khJobDelete(jobClient, namespace)
+	err := khJobDelete(khJobClient, namespace)
 	if err != nil {
 		log.Errorln("checkReaper: Failed to reap khjobs with error: ", err)
 	}
@@ -198,9 +218,9 @@ func (k *KubernetesAPI) deleteFilteredCheckerPods(ctx context.Context, client *k
 			delete(reapCheckerPods, n)
 		}
 
-		// Delete failed pods (status Failed) older than maxCheckPodAge
-		if v.Status.Phase == v1.PodFailed && time.Since(podTerminatedTime) > cfg.MaxCheckPodAge {
-			log.Infoln("checkReaper: Found completed pod older than:", cfg.MaxCheckPodAge, "in status `Failed`. Deleting pod:", n)
+		// Delete failed pods (status Failed) older than maxFailedPodAge
+		if v.Status.Phase == v1.PodFailed && time.Since(podTerminatedTime) > cfg.maxFailedPodAge() {
+			log.Infoln("checkReaper: Found completed pod older than:", cfg.maxFailedPodAge(), "in status `Failed`. Deleting pod:", n)
 
 			err = k.deletePod(ctx, v)
 			if err != nil {
@@ -285,13 +305,41 @@ func getAllCompletedPodsWithCheckName(reapCheckerPods map[string]v1.Pod, pod v1.
 	return allCheckPods
 }
 
-// deletePod deletes a given pod
+// deletePod deletes a given pod.  In dry run mode, the pod is only logged.
 func (k *KubernetesAPI) deletePod(ctx context.Context, pod v1.Pod) error {
 
+	if cfg.ReaperDryRun {
+		log.Infoln("checkReaper: Dry run. Would delete Pod:", pod.Name, "in namespace:", pod.Namespace)
+		metrics.ReaperDryRunDeletions.Inc(pod.Namespace, "pod")
+		return nil
+	}
+
 	log.Infoln("checkReaper: Deleting Pod: ", pod.Name, " in namespace: ", pod.Namespace)
 	propagationForeground := metav1.DeletePropagationForeground
 	options := metav1.DeleteOptions{PropagationPolicy: &propagationForeground}
-	return k.Client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, options)
+	err := k.Client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, options)
+	if err == nil {
+		metrics.ReaperPodsDeleted.Inc(pod.Namespace, string(pod.Status.Phase))
+		recordReapedPodEvent(pod)
+	}
+	return err
+}
+
+// recordReapedPodEvent records an event on the khcheck or khjob that owns a reaped checker pod
+func recordReapedPodEvent(pod v1.Pod) {
+	if eventRecorder == nil {
+		return
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "KuberhealthyCheck" && owner.Kind != "KuberhealthyJob" {
+			continue
+		}
+		ref := external.EventReference(&owner, pod.Namespace)
+		if ref == nil {
+			continue
+		}
+		eventRecorder.Eventf(ref, v1.EventTypeNormal, external.EventReasonCheckerPodReaped, "Deleted checker pod %s in phase %s", pod.Name, pod.Status.Phase)
+	}
 }
 
 // jobConditions returns true if conditions are met to be deleted for khjob
@@ -320,7 +368,12 @@ func khJobDelete(client *khjobv1.KHJobV1Client, namespace string) error {
 
 	// Range over list and delete khjobs
 	for _, j := range list.Items {
-		if jobConditions(j, cfg.MaxKHJobAge, "Completed") {
+		if jobConditions(j, cfg.MaxKHJobAge, khjobv1.JobCompleted) || jobConditions(j, cfg.MaxKHJobAge, khjobv1.JobFailed) {
+			if cfg.ReaperDryRun {
+				log.Infoln("checkReaper: Dry run. Would delete khjob", j.Name, "in namespace:", j.Namespace)
+				metrics.ReaperDryRunDeletions.Inc(j.Namespace, "khjob")
+				continue
+			}
 			log.Infoln("checkReaper: Deleting khjob", j.Name)
 			err := client.KuberhealthyJobs(j.Namespace).Delete(j.Name, &del)
 			if err != nil {
@@ -328,6 +381,7 @@ func khJobDelete(client *khjobv1.KHJobV1Client, namespace string) error {
 				return err
 
 			}
+			metrics.ReaperKHJobsDeleted.Inc(j.Namespace, string(j.Spec.Phase))
 		}
 	}
 	return nil
@@ -10,6 +10,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
 )
 
 // TestParseConfigs ensures that all checkReaper configs are properly parsed and that there are no 0 duration values
@@ -234,4 +236,120 @@ func TestGetAllPodsWithCheckName(t *testing.T) {
 	t.Logf("getAllPodsWithCheckName successfully listed all pods from the same khcheck")
 }
 
-//TODO: TestDeleteFilteredCheckerPods
+// TestDeleteFilteredCheckerPods ensures that succeeded and failed checker pods are reaped after their own maximum
+// age and that nothing is deleted in dry run mode
+func TestDeleteFilteredCheckerPods(t *testing.T) {
+	previousCfg := cfg
+	defer func() { cfg = previousCfg }()
+
+	completedPod := func(name string, phase v1.PodPhase, age time.Duration) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "foo",
+				Labels: map[string]string{
+					"kuberhealthy-check-name": name,
+				},
+			},
+			Status: v1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []v1.ContainerStatus{{
+					State: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{FinishedAt: metav1.NewTime(time.Now().Add(-age))},
+					},
+				}},
+			},
+		}
+	}
+	pods := []v1.Pod{
+		completedPod("old-succeeded", v1.PodSucceeded, time.Hour*2),
+		completedPod("recent-failed", v1.PodFailed, time.Hour*2),
+		completedPod("old-failed", v1.PodFailed, time.Hour*4),
+	}
+
+	var testCases = []struct {
+		description string
+		dryRun      bool
+		remaining   []string
+	}{
+		{"Pods older than their maximum age are deleted", false, []string{"recent-failed"}},
+		{"Pods are not deleted in dry run mode", true, []string{"old-succeeded", "recent-failed", "old-failed"}},
+	}
+
+	for _, test := range testCases {
+		t.Log(test.description)
+
+		cfg = &Config{
+			MaxCheckPodAge:       time.Hour,
+			MaxFailedPodAge:      time.Hour * 3,
+			MaxCompletedPodCount: 5,
+			MaxErrorPodCount:     5,
+			ReaperDryRun:         test.dryRun,
+		}
+		api := KubernetesAPI{
+			Client: fake.NewSimpleClientset(),
+		}
+
+		ctx, cancelCtx := context.WithCancel(context.Background())
+		defer cancelCtx()
+		reapCheckerPods := make(map[string]v1.Pod)
+		for _, p := range pods {
+			_, err := api.Client.CoreV1().Pods(p.Namespace).Create(ctx, &p, metav1.CreateOptions{})
+			if err != nil {
+				t.Fatalf("Error creating test pods: %s", err)
+			}
+			reapCheckerPods[p.Name] = p
+		}
+
+		err := api.deleteFilteredCheckerPods(ctx, nil, reapCheckerPods)
+		if err != nil {
+			t.Fatalf("Error deleting filtered checker pods: %s", err)
+		}
+
+		remaining, err := api.Client.CoreV1().Pods("foo").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Error listing test pods: %s", err)
+		}
+		if len(remaining.Items) != len(test.remaining) {
+			t.Fatalf("Expected %d pods to remain but found %d", len(test.remaining), len(remaining.Items))
+		}
+		for _, name := range test.remaining {
+			_, err := api.Client.CoreV1().Pods("foo").Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Expected pod %s to remain but it was deleted: %s", name, err)
+			}
+		}
+	}
+}
+
+// TestKHStateReapActionFor ensures that khStates without a khCheck or khJob are marked as orphaned first, and only
+// deleted once they have been orphaned for longer than the grace period
+func TestKHStateReapActionFor(t *testing.T) {
+	now := time.Now()
+	orphanedAt := func(d time.Duration) map[string]string {
+		return map[string]string{khstatev1.OrphanedAnnotation: now.Add(-d).Format(time.RFC3339)}
+	}
+
+	var testCases = []struct {
+		description string
+		annotations map[string]string
+		found       bool
+		expected    khStateReapAction
+	}{
+		{"khState with a khCheck is kept", nil, true, khStateKeep},
+		{"khState without a khCheck is marked as orphaned", nil, false, khStateOrphan},
+		{"Recently orphaned khState is kept", orphanedAt(time.Minute), false, khStateKeep},
+		{"khState orphaned for longer than the grace period is deleted", orphanedAt(time.Hour), false, khStateDelete},
+		{"Orphaned khState whose khCheck was recreated is adopted", orphanedAt(time.Hour), true, khStateAdopt},
+		{"khState with an invalid orphaned time is marked again", map[string]string{khstatev1.OrphanedAnnotation: "yesterday"}, false, khStateOrphan},
+	}
+
+	for _, test := range testCases {
+		t.Log(test.description)
+		khState := &khstatev1.KuberhealthyState{ObjectMeta: metav1.ObjectMeta{Name: "check", Namespace: "foo", Annotations: test.annotations}}
+		action := khStateReapActionFor(khState, test.found, now, time.Minute*10)
+		if action != test.expected {
+			t.Fatalf("Expected khState reap action %d but got %d", test.expected, action)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+)
+
+// sensitiveValues returns the values of the sensitive environment variables of the named check or job, which are
+// redacted from its errors
+func (k *Kuberhealthy) sensitiveValues(name string, namespace string) []string {
+	if kc, ok := k.cachedKHCheck(name, namespace); ok {
+		markedNames := sensitiveEnvVarNames(kc.Annotations)
+		values := redact.SensitiveEnvValues(kc.Spec.PodSpec, markedNames)
+
+		// the extra environment of the khcheck ends up in every container of the checker pod
+		extraEnv := apiv1.PodSpec{Containers: []apiv1.Container{{Env: kc.Spec.ExtraEnv}}}
+		return append(values, redact.SensitiveEnvValues(extraEnv, markedNames)...)
+	}
+
+	// jobs and checks that are not in the informer yet are looked up among the running checks
+	if c, err := k.getCheck(name, namespace); err == nil {
+		return redact.SensitiveEnvValues(c.OriginalPodSpec, nil)
+	}
+	return nil
+}
+
+// sensitiveEnvVarNames returns the names of the environment variables that a khcheck marks as sensitive with the
+// sensitive env vars annotation
+func sensitiveEnvVarNames(annotations map[string]string) []string {
+	var names []string
+	for _, name := range strings.Split(annotations[khcheckv1.SensitiveEnvVarsAnnotation], ",") {
+		name = strings.TrimSpace(name)
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// redactRunState scrubs secrets out of the errors of the supplied details and run before they are stored
+func redactRunState(details *khstatev1.WorkloadDetails, run *khstatev1.RunRecord, sensitiveValues []string) {
+	details.Errors = redact.Errors(details.Errors, sensitiveValues)
+	if run != nil {
+		run.Errors = redact.Errors(run.Errors, sensitiveValues)
+	}
+}
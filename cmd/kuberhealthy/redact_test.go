@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestSensitiveEnvVarNames ensures that the names listed in the sensitive env vars annotation are parsed
+func TestSensitiveEnvVarNames(t *testing.T) {
+	names := sensitiveEnvVarNames(map[string]string{khcheckv1.SensitiveEnvVarsAnnotation: " DB_HOST, ,INTERNAL_URL "})
+	if strings.Join(names, ",") != "DB_HOST,INTERNAL_URL" {
+		t.Fatalf("Expected the names DB_HOST and INTERNAL_URL but got %v", names)
+	}
+	if names := sensitiveEnvVarNames(nil); len(names) != 0 {
+		t.Fatalf("Expected no names without the annotation but got %v", names)
+	}
+}
+
+// TestRedactRunState ensures that the errors of both the details and the run record are redacted
+func TestRedactRunState(t *testing.T) {
+	details := khstatev1.WorkloadDetails{Errors: []string{"password=hunter22"}}
+	run := khstatev1.RunRecord{Errors: []string{"connecting to db.internal failed"}}
+
+	redactRunState(&details, &run, []string{"db.internal"})
+	if strings.Contains(details.Errors[0], "hunter22") {
+		t.Fatalf("Expected the password to be redacted but got %q", details.Errors[0])
+	}
+	if strings.Contains(run.Errors[0], "db.internal") {
+		t.Fatalf("Expected the sensitive value to be redacted but got %q", run.Errors[0])
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"time"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 
 	log "github.com/sirupsen/logrus"
 
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
 	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -23,6 +25,11 @@ type StateReflector struct {
 	reflectorSigChan chan struct{} // the channel that indicates when the cache sync should stop
 	resyncPeriod     time.Duration // the period for full API re-syncs
 	store            cache.Store
+	inMaintenance    func(name string, namespace string) bool                                          // determines if a check is in a maintenance window
+	isPaused         func(name string, namespace string) bool                                          // determines if a check is paused
+	checkLabels      func(name string, namespace string) map[string]string                             // returns the labels of a check that are added to its metrics
+	checkSeverity    func(name string, namespace string) string                                        // returns the severity set on a khcheck
+	onChange         func(previous *khstatev1.KuberhealthyState, current *khstatev1.KuberhealthyState) // called when a khstate in the cache is updated
 }
 
 // NewStateReflector creates a new StateReflector for watching the state of khstate resources on the server
@@ -32,8 +39,23 @@ func NewStateReflector(namespace string) *StateReflector {
 	sr.resyncPeriod = time.Minute * 5
 
 	// structure the reflector and its required elements
-	khStateListWatch := cache.NewListWatchFromClient(khStateClient.RESTClient(), stateCRDResource, namespace, fields.Everything())
-	sr.store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	khStateListWatch := &cache.ListWatch{
+		ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+			list, err := khStateClient.KuberhealthyStates(namespace).List(options)
+			return &list, err
+		},
+		WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+			return khStateClient.KuberhealthyStates(namespace).Watch(options)
+		},
+	}
+	sr.store = &notifyingStore{
+		Store: cache.NewStore(cache.MetaNamespaceKeyFunc),
+		onChange: func(previous *khstatev1.KuberhealthyState, current *khstatev1.KuberhealthyState) {
+			if sr.onChange != nil {
+				sr.onChange(previous, current)
+			}
+		},
+	}
 	sr.reflector = cache.NewReflector(khStateListWatch, &khstatev1.KuberhealthyState{}, sr.store, sr.resyncPeriod)
 
 	return &sr
@@ -53,6 +75,11 @@ func (sr *StateReflector) Start() {
 	sr.reflector.Run(sr.reflectorSigChan)
 }
 
+// HasSynced indicates if the reflector has finished listing khstates for the first time
+func (sr *StateReflector) HasSynced() bool {
+	return len(sr.reflector.LastSyncResourceVersion()) > 0
+}
+
 // CurrentStatus returns the current summary of checks as known by the cache.
 func (sr *StateReflector) CurrentStatus() health.State {
 	log.Infoln("khState reflector fetching current status")
@@ -83,23 +110,65 @@ func (sr *StateReflector) CurrentStatus() health.State {
 			continue
 		}
 
+		// skip the check if its khcheck or khjob has been deleted.  The khState reaper deletes it after a grace
+		// period.
+		if _, orphaned := khState.Annotations[khstatev1.OrphanedAnnotation]; orphaned {
+			log.Debugln("Output for", khState.GetName(), khState.GetNamespace(), "hidden from status page because it is orphaned")
+			continue
+		}
+
+		// failures are hidden until the failure threshold of the check is reached
+		details := reportedState(khState.Spec)
+
+		khWorkload := determineKHWorkload(khState.Name, khState.Namespace)
+		if khWorkload == khstatev1.KHCheck && sr.checkLabels != nil {
+			details.Labels = sr.checkLabels(khState.GetName(), khState.GetNamespace())
+		}
+
+		// khstates that were written without the severity of their check take it from the khcheck, so that
+		// warnings are not mistaken for critical failures
+		if khWorkload == khstatev1.KHCheck && len(details.Severity) == 0 && sr.checkSeverity != nil {
+			details.Severity = sr.checkSeverity(khState.GetName(), khState.GetNamespace())
+		}
+
+		// paused checks keep showing their last known state, but are left out of the overall health status
+		if khWorkload == khstatev1.KHCheck && sr.isPaused != nil && sr.isPaused(khState.GetName(), khState.GetNamespace()) {
+			log.Debugln("Status page: check", khState.GetName(), khState.GetNamespace(), "is paused")
+			details.Paused = true
+			state.CheckDetails[khState.GetNamespace()+"/"+khState.GetName()] = details
+			continue
+		}
+
+		// checks in a maintenance window are left out of the overall health status
+		if khWorkload == khstatev1.KHCheck && sr.inMaintenance != nil && sr.inMaintenance(khState.GetName(), khState.GetNamespace()) {
+			log.Debugln("Status page: check", khState.GetName(), khState.GetNamespace(), "is in maintenance")
+			details.InMaintenance = true
+			state.CheckDetails[khState.GetNamespace()+"/"+khState.GetName()] = details
+			continue
+		}
+
 		// parse check status from CRD and add it to the global status of errors. Skip blank errors
-		for _, e := range khState.Spec.Errors {
+		for _, e := range details.Errors {
 			if len(strings.TrimSpace(e)) == 0 {
 				log.Warningln("Skipped an error that was blank when adding check details to current state.")
 				continue
 			}
+
+			// failures of checks that are not critical are shown as warnings without failing the global OK state
+			if !isCriticalSeverity(details.Severity) {
+				state.AddWarning(e)
+				continue
+			}
 			state.AddError(e)
 			log.Debugln("Status page: Setting global OK state to false due to check details not being OK")
 			state.OK = false
 		}
 
-		khWorkload := determineKHWorkload(khState.Name, khState.Namespace)
 		switch khWorkload {
 		case khstatev1.KHCheck:
-			state.CheckDetails[khState.GetNamespace()+"/"+khState.GetName()] = khState.Spec
+			state.CheckDetails[khState.GetNamespace()+"/"+khState.GetName()] = details
 		case khstatev1.KHJob:
-			state.JobDetails[khState.GetNamespace()+"/"+khState.GetName()] = khState.Spec
+			state.JobDetails[khState.GetNamespace()+"/"+khState.GetName()] = details
 		}
 	}
 
@@ -107,6 +176,12 @@ func (sr *StateReflector) CurrentStatus() health.State {
 	return state
 }
 
+// isCriticalSeverity determines if failures of the supplied severity make the overall health status fail.  Workloads
+// without a severity are critical.
+func isCriticalSeverity(severity string) bool {
+	return len(severity) == 0 || severity == string(khcheckv1.SeverityCritical)
+}
+
 // determineKHWorkload uses the name and namespace of the kuberhealthy resource to determine whether its a khjob or khcheck
 // This function is necessary for the CurrentStatus() function as getting the KHWorkload from the state spec returns a blank kh workload.
 func determineKHWorkload(name string, namespace string) khstatev1.KHWorkload {
@@ -91,3 +91,18 @@ func TestStop(t *testing.T) {
 		break
 	}
 }
+
+// TestIsCriticalSeverity ensures that only critical and unset severities are treated as critical
+func TestIsCriticalSeverity(t *testing.T) {
+	testCases := map[string]bool{
+		"":         true,
+		"critical": true,
+		"warning":  false,
+		"info":     false,
+	}
+	for severity, expected := range testCases {
+		if isCriticalSeverity(severity) != expected {
+			t.Fatalf("Expected severity %q to be critical: %t", severity, expected)
+		}
+	}
+}
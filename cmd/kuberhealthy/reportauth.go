@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// podNameExtraKey is the extra user info that bound service account tokens carry with the name of their pod
+const podNameExtraKey = "authentication.kubernetes.io/pod-name"
+
+// serviceAccountUserPrefix is the prefix of the user names of service accounts
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// reportTokenAuthRequired indicates if reports for the named check or job must be authenticated with a service
+// account token, either because it is required for all checks or because the khcheck requires it.  When this can not
+// be determined, because the khcheck informer has not synced yet or there is no such khcheck or khjob, an error with
+// the status code that the report is rejected with is returned instead.
+func (k *Kuberhealthy) reportTokenAuthRequired(checkName string, checkNamespace string) (bool, error) {
+	if cfg.ReportTokenAuth {
+		return true, nil
+	}
+	if k.khCheckInformer == nil || !k.khCheckInformer.HasSynced() {
+		return false, &reportError{statusCode: http.StatusServiceUnavailable, err: errors.New("khchecks have not been loaded yet")}
+	}
+	kc, ok := k.cachedKHCheck(checkName, checkNamespace)
+	if ok {
+		return kc.Spec.ReportTokenAuth, nil
+	}
+
+	// khjobs are not cached, and only require token authentication when all reports do
+	_, err := khJobClient.KuberhealthyJobs(checkNamespace).Get(checkName, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return false, &reportError{statusCode: http.StatusUnauthorized, err: fmt.Errorf("report is for %s/%s, which is not a known khcheck or khjob", checkNamespace, checkName)}
+	}
+	if err != nil {
+		return false, &reportError{statusCode: http.StatusServiceUnavailable, err: fmt.Errorf("failed to look up khjob %s/%s: %w", checkNamespace, checkName, err)}
+	}
+	return false, nil
+}
+
+// validateReportToken validates the bearer token in the authorization header of a report with a TokenReview and
+// ensures that it was issued to the supplied checker pod
+func validateReportToken(ctx context.Context, authHeader string, podName string, podNamespace string) error {
+	token, err := bearerToken(authHeader)
+	if err != nil {
+		return errors.New("report " + err.Error())
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{external.ReportTokenAudience},
+		},
+	}
+	result, err := kubernetesClient.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review report token: %w", err)
+	}
+	return tokenReviewMatchesPod(result.Status, podName, podNamespace)
+}
+
+// bearerToken returns the bearer token in the supplied authorization header
+func bearerToken(authHeader string) (string, error) {
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", errors.New("has no bearer token")
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	if len(token) == 0 {
+		return "", errors.New("has a blank bearer token")
+	}
+	return token, nil
+}
+
+// tokenReviewMatchesPod returns an error unless the reviewed token is authenticated for the Kuberhealthy audience
+// and was issued to a service account of the supplied pod
+func tokenReviewMatchesPod(status authenticationv1.TokenReviewStatus, podName string, podNamespace string) error {
+	if !status.Authenticated {
+		if len(status.Error) > 0 {
+			return errors.New("report token was not authenticated: " + status.Error)
+		}
+		return errors.New("report token was not authenticated")
+	}
+
+	var audienceFound bool
+	for _, a := range status.Audiences {
+		if a == external.ReportTokenAudience {
+			audienceFound = true
+		}
+	}
+	if !audienceFound {
+		return errors.New("report token was not issued for the " + external.ReportTokenAudience + " audience")
+	}
+
+	if !strings.HasPrefix(status.User.Username, serviceAccountUserPrefix+podNamespace+":") {
+		return errors.New("report token belongs to " + status.User.Username + ", which is not a service account in namespace " + podNamespace)
+	}
+
+	tokenPodNames := status.User.Extra[podNameExtraKey]
+	if len(tokenPodNames) == 0 || tokenPodNames[0] != podName {
+		return errors.New("report token was not issued to checker pod " + podName)
+	}
+	return nil
+}
+
+// validateReportClientCert ensures that a report was sent over mutual TLS with the client certificate that was
+// issued to the current run of the reporting check.  The certificate chain has already been verified against the
+// client CA during the TLS handshake.
+func validateReportClientCert(connState *tls.ConnectionState, podReport PodReportInfo) error {
+	if connState == nil {
+		return errors.New("report was not sent over TLS")
+	}
+	if len(connState.VerifiedChains) == 0 || len(connState.VerifiedChains[0]) == 0 {
+		return errors.New("report has no verified client certificate")
+	}
+
+	identity, err := external.ClientCertIdentityFromCert(connState.VerifiedChains[0][0])
+	if err != nil {
+		return err
+	}
+	if identity.Namespace != podReport.Namespace || identity.Name != podReport.Name {
+		return fmt.Errorf("client certificate was issued to check %s/%s, not %s/%s", identity.Namespace, identity.Name, podReport.Namespace, podReport.Name)
+	}
+	if identity.RunUUID != podReport.UUID {
+		return fmt.Errorf("client certificate was issued to run %s, not the current run %s", identity.RunUUID, podReport.UUID)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// syncInformer is a khcheck informer that has or has not synced yet
+type syncInformer struct {
+	cache.SharedIndexInformer
+	synced bool
+}
+
+// HasSynced indicates if the informer has synced
+func (i syncInformer) HasSynced() bool {
+	return i.synced
+}
+
+// TestReportTokenAuthRequired ensures that reports are rejected when it is not known if they must be authenticated
+// with a service account token, instead of being accepted without one
+func TestReportTokenAuthRequired(t *testing.T) {
+	previousCfg, previousJobClient := cfg, khJobClient
+	defer func() { cfg, khJobClient = previousCfg, previousJobClient }()
+	cfg = &Config{}
+
+	// the API server only knows the nightly khjob
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/khjobs/nightly") {
+			w.Write([]byte(`{"apiVersion":"comcast.github.io/v1","kind":"KuberhealthyJob","metadata":{"name":"nightly","namespace":"kuberhealthy"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+	var err error
+	khJobClient, err = khjobv1.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kh := &Kuberhealthy{}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, kc := range []*khcheckv1.KuberhealthyCheck{
+		{ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "kuberhealthy"}, Spec: khcheckv1.CheckConfig{ReportTokenAuth: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "deployment", Namespace: "kuberhealthy"}},
+	} {
+		err := indexer.Add(kc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	kh.khCheckLister = khcheckv1.NewKuberhealthyCheckLister(indexer)
+
+	testCases := []struct {
+		description string
+		synced      bool
+		check       string
+		required    bool
+		statusCode  int
+	}{
+		{description: "informer not synced", synced: false, check: "dns", statusCode: http.StatusServiceUnavailable},
+		{description: "khcheck requiring tokens", synced: true, check: "dns", required: true},
+		{description: "khcheck not requiring tokens", synced: true, check: "deployment"},
+		{description: "khjob", synced: true, check: "nightly"},
+		{description: "unknown check", synced: true, check: "spoofed", statusCode: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		kh.khCheckInformer = syncInformer{synced: tc.synced}
+		required, err := kh.reportTokenAuthRequired(tc.check, "kuberhealthy")
+		if tc.statusCode != 0 {
+			if err == nil || reportStatusCode(err) != tc.statusCode {
+				t.Fatalf("%s: expected the report to be rejected with status %d but got %v", tc.description, tc.statusCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.description, err)
+		}
+		if required != tc.required {
+			t.Fatalf("%s: expected token authentication to be required: %t", tc.description, tc.required)
+		}
+	}
+
+	// requiring tokens for all reports needs no lookups
+	cfg = &Config{ReportTokenAuth: true}
+	kh.khCheckInformer = nil
+	required, err := kh.reportTokenAuthRequired("spoofed", "kuberhealthy")
+	if err != nil || !required {
+		t.Fatalf("expected token authentication to be required for all reports but got %t and %v", required, err)
+	}
+}
+
+// TestTokenReviewMatchesPod ensures that only tokens issued to the reporting checker pod are accepted
+func TestTokenReviewMatchesPod(t *testing.T) {
+	validStatus := func() authenticationv1.TokenReviewStatus {
+		return authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			Audiences:     []string{external.ReportTokenAudience},
+			User: authenticationv1.UserInfo{
+				Username: "system:serviceaccount:kuberhealthy:default",
+				Extra: map[string]authenticationv1.ExtraValue{
+					podNameExtraKey: {"test-check-1600000000"},
+				},
+			},
+		}
+	}
+
+	unauthenticated := validStatus()
+	unauthenticated.Authenticated = false
+
+	wrongAudience := validStatus()
+	wrongAudience.Audiences = []string{"https://kubernetes.default.svc"}
+
+	wrongNamespace := validStatus()
+	wrongNamespace.User.Username = "system:serviceaccount:other:default"
+
+	wrongPod := validStatus()
+	wrongPod.User.Extra[podNameExtraKey] = authenticationv1.ExtraValue{"other-pod"}
+
+	unboundToken := validStatus()
+	unboundToken.User.Extra = nil
+
+	testCases := []struct {
+		name   string
+		status authenticationv1.TokenReviewStatus
+		valid  bool
+	}{
+		{name: "valid", status: validStatus(), valid: true},
+		{name: "unauthenticated", status: unauthenticated},
+		{name: "wrong audience", status: wrongAudience},
+		{name: "wrong namespace", status: wrongNamespace},
+		{name: "wrong pod", status: wrongPod},
+		{name: "unbound token", status: unboundToken},
+	}
+
+	for _, tc := range testCases {
+		err := tokenReviewMatchesPod(tc.status, "test-check-1600000000", "kuberhealthy")
+		if tc.valid && err != nil {
+			t.Fatalf("Expected %s token to be accepted but got: %s", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("Expected %s token to be rejected", tc.name)
+		}
+	}
+}
+
+// TestValidateReportClientCert ensures that only the client certificate of the current run of a check is accepted
+func TestValidateReportClientCert(t *testing.T) {
+	podReport := PodReportInfo{Name: "deployment", Namespace: "kuberhealthy", UUID: "current-run"}
+	certFor := func(identity string) *x509.Certificate {
+		u, err := url.Parse(identity)
+		if err != nil {
+			t.Fatal("Failed to parse identity:", err)
+		}
+		return &x509.Certificate{URIs: []*url.URL{u}}
+	}
+
+	testCases := []struct {
+		description string
+		tls         *tls.ConnectionState
+		expectError bool
+	}{
+		{description: "plain http", tls: nil, expectError: true},
+		{description: "no client certificate", tls: &tls.ConnectionState{}, expectError: true},
+		{description: "current run", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certFor("kuberhealthy://kuberhealthy/deployment/current-run")}}}},
+		{description: "previous run", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certFor("kuberhealthy://kuberhealthy/deployment/previous-run")}}}, expectError: true},
+		{description: "other check", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certFor("kuberhealthy://kuberhealthy/daemonset/current-run")}}}, expectError: true},
+		{description: "no identity", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		err := validateReportClientCert(tc.tls, podReport)
+		if tc.expectError != (err != nil) {
+			t.Fatalf("%s: expected error %t but got %v", tc.description, tc.expectError, err)
+		}
+	}
+}
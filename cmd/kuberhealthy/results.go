@@ -0,0 +1,89 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/archive"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+)
+
+// configureResultExporters sets up the exporters that the result of every check and job run is sent to
+func (k *Kuberhealthy) configureResultExporters() {
+	k.ResultExporters = nil
+
+	if cfg.Datadog.Enabled {
+		exporter, err := metrics.NewDatadogExporter(cfg.Datadog)
+		if err != nil {
+			log.Errorln("Error setting up datadog exporter:", err)
+		} else {
+			k.ResultExporters = append(k.ResultExporters, exporter)
+		}
+	}
+
+	if cfg.InfluxResults.Enabled {
+		exporter, err := metrics.NewInfluxResultsExporter(cfg.InfluxResults)
+		if err != nil {
+			log.Errorln("Error setting up influxdb results exporter:", err)
+		} else {
+			k.ResultExporters = append(k.ResultExporters, exporter)
+		}
+	}
+
+	if k.resultArchiver != nil {
+		k.resultArchiver.Shutdown()
+		k.resultArchiver = nil
+	}
+	if cfg.ResultArchive.Enabled {
+		archiver, err := archive.NewArchiver(cfg.ResultArchive)
+		if err != nil {
+			log.Errorln("Error setting up result archival:", err)
+		} else {
+			k.resultArchiver = archiver
+			k.ResultExporters = append(k.ResultExporters, archiver)
+		}
+	}
+}
+
+// exportRunResult sends the result of a check or job run to every result exporter in the background, so that a
+// slow backend does not delay the next run
+func (k *Kuberhealthy) exportRunResult(name string, namespace string, details khstatev1.WorkloadDetails) {
+	if len(k.ResultExporters) == 0 {
+		return
+	}
+
+	runDuration, err := time.ParseDuration(details.RunDuration)
+	if err != nil {
+		log.Errorln("Error parsing run duration", err)
+	}
+	result := metrics.RunResult{
+		Name:          name,
+		Namespace:     namespace,
+		Workload:      "check",
+		OK:            details.OK,
+		Errors:        redact.Errors(details.Errors, k.sensitiveValues(name, namespace)),
+		RunDuration:   runDuration,
+		FailureReason: string(details.FailureReason),
+		Node:          details.Node,
+		Cluster:       cfg.ClusterName,
+		Environment:   cfg.Environment,
+		Time:          time.Now(),
+	}
+	if details.GetKHWorkload() == khstatev1.KHJob {
+		result.Workload = "job"
+	}
+
+	exporters := k.ResultExporters
+	go func() {
+		result.Labels = workloadMetadata(name, namespace, details.GetKHWorkload()).Labels
+		for _, e := range exporters {
+			err := e.Export(result)
+			if err != nil {
+				log.Errorln("Error exporting result of", namespace+"/"+name, "to", e.Name()+":", err)
+			}
+		}
+	}()
+}
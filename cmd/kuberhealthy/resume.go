@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// pendingRun is a run of a check that a previous master left in flight when it shut down
+type pendingRun struct {
+	run            khstatev1.InFlightRun
+	lastReportTime metav1.Time // the time of the last report before the run was left in flight
+}
+
+// checkPendingRun returns the run of a check that a previous master left in flight, or nil if there is no run to
+// resume.  Checks that run on all nodes are not resumed.
+func checkPendingRun(c *external.Checker) *pendingRun {
+	if c.RunOnAllNodes {
+		return nil
+	}
+	khState, err := khStateClient.KuberhealthyStates(c.CheckNamespace()).Get(sanitizeResourceName(c.Name()), metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return pendingInFlightRun(khState.Spec, time.Now())
+}
+
+// pendingInFlightRun returns the run in flight recorded on the supplied khstate if its deadline has not passed yet
+func pendingInFlightRun(state khstatev1.WorkloadDetails, now time.Time) *pendingRun {
+	if state.InFlightRun == nil || len(state.InFlightRun.Pod) == 0 || !now.Before(state.InFlightRun.Deadline.Time) {
+		return nil
+	}
+	pending := &pendingRun{run: *state.InFlightRun}
+	if state.LastRun != nil {
+		pending.lastReportTime = *state.LastRun
+	}
+	return pending
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestPendingInFlightRun ensures that runs left in flight are only resumed before their deadline, and that they
+// carry the time of the last report before they were left in flight
+func TestPendingInFlightRun(t *testing.T) {
+	now := time.Now()
+	lastRun := metav1.NewTime(now.Add(-time.Minute * 5))
+	run := &khstatev1.InFlightRun{Pod: "check-1234", UUID: "1234", StartTime: metav1.NewTime(now.Add(-time.Minute)), Deadline: metav1.NewTime(now.Add(time.Minute))}
+	expiredRun := run.DeepCopy()
+	expiredRun.Deadline = metav1.NewTime(now.Add(-time.Second))
+
+	if pendingInFlightRun(khstatev1.WorkloadDetails{LastRun: &lastRun}, now) != nil {
+		t.Fatalf("expected no pending run without a run in flight")
+	}
+	if pendingInFlightRun(khstatev1.WorkloadDetails{LastRun: &lastRun, InFlightRun: expiredRun}, now) != nil {
+		t.Fatalf("expected no pending run after the deadline of the run in flight")
+	}
+
+	pending := pendingInFlightRun(khstatev1.WorkloadDetails{LastRun: &lastRun, InFlightRun: run}, now)
+	if pending == nil {
+		t.Fatalf("expected the run in flight to be pending")
+	}
+	if pending.run.Pod != run.Pod || !pending.lastReportTime.Equal(&lastRun) {
+		t.Fatalf("expected pending run of pod %s with last report at %s but got %+v", run.Pod, lastRun, pending)
+	}
+
+	pending = pendingInFlightRun(khstatev1.WorkloadDetails{InFlightRun: run}, now)
+	if pending == nil || !pending.lastReportTime.IsZero() {
+		t.Fatalf("expected a pending run with no last report for a check that never reported in but got %+v", pending)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runCheckPath is the web server path used to run an external check on demand
+const runCheckPath = "/api/v1/checks/{namespace}/{name}/run"
+
+// runCheckResponse is the body written back to callers of the run check endpoint
+type runCheckResponse struct {
+	Check string `json:"check,omitempty"` // the namespace and name of the check
+	UUID  string `json:"uuid,omitempty"`  // the UUID of the requested run
+	Error string `json:"error,omitempty"` // why the run could not be requested
+}
+
+// runCheckHandler schedules a run of the requested external check right away, out of its normal cycle, and
+// writes the UUID of the requested run back to the caller.  Only the master runs checks, so requests sent to
+// any other instance are rejected.  When checks are sharded, requests must be sent to the instance that owns the
+// check.  Only callers that are allowed to update the khcheck may run it.
+func (k *Kuberhealthy) runCheckHandler(w http.ResponseWriter, r *http.Request) error {
+	checkNamespace := r.PathValue("namespace")
+	checkName := r.PathValue("name")
+	response := runCheckResponse{
+		Check: checkNamespace + "/" + checkName,
+	}
+	log.Infoln("Client connected to run check endpoint for", response.Check, "from", r.RemoteAddr, r.UserAgent())
+
+	user, err := authorizeCheckRequest(r.Context(), kubernetesClient, r, "update", checkName, checkNamespace)
+	if err != nil {
+		log.Warningln("Rejected request to run check", response.Check, "from", r.RemoteAddr+":", err)
+		response.Error = err.Error()
+		return writeRunCheckResponse(w, reportStatusCode(err), response)
+	}
+
+	if !k.runsChecks() {
+		response.Error = "this Kuberhealthy instance is not the master. send the request to the master instance instead"
+		return writeRunCheckResponse(w, http.StatusServiceUnavailable, response)
+	}
+	if !k.ownsCheck(checkName, checkNamespace) {
+		response.Error = "checks have not been spread across the Kuberhealthy instances yet"
+		if owner := k.shards.owner(checkName, checkNamespace); len(owner) > 0 {
+			response.Error = "this check is run by Kuberhealthy instance " + owner + ". send the request to that instance instead"
+		}
+		return writeRunCheckResponse(w, http.StatusServiceUnavailable, response)
+	}
+
+	c, err := k.getCheck(checkName, checkNamespace)
+	if err != nil {
+		response.Error = err.Error()
+		return writeRunCheckResponse(w, http.StatusNotFound, response)
+	}
+
+	if c.Paused {
+		response.Error = "check is paused"
+		return writeRunCheckResponse(w, http.StatusConflict, response)
+	}
+
+	response.UUID = c.RequestRun()
+	log.Infoln("Requested a run of check", response.Check, "with uuid", response.UUID, "for", user)
+	return writeRunCheckResponse(w, http.StatusAccepted, response)
+}
+
+// writeRunCheckResponse writes a run check response back to the caller as JSON with the supplied status code
+func writeRunCheckResponse(w http.ResponseWriter, statusCode int, response runCheckResponse) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(response)
+}
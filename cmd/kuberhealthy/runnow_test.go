@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// authAPIServer starts an API server that authenticates the token "valid-token" and allows it to update any khcheck,
+// and points the global kubernetes client at it.  The returned func restores the client and stops the server.
+func authAPIServer(t *testing.T) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tokenreviews"):
+			review := authenticationv1.TokenReview{}
+			err := json.NewDecoder(r.Body).Decode(&review)
+			if err != nil {
+				t.Error("Failed to decode token review:", err)
+			}
+			review.APIVersion, review.Kind = "authentication.k8s.io/v1", "TokenReview"
+			if review.Spec.Token == "valid-token" {
+				review.Status.Authenticated = true
+				review.Status.User.Username = "jane"
+			}
+			json.NewEncoder(w).Encode(review)
+		case strings.HasSuffix(r.URL.Path, "/subjectaccessreviews"):
+			review := authorizationv1.SubjectAccessReview{}
+			err := json.NewDecoder(r.Body).Decode(&review)
+			if err != nil {
+				t.Error("Failed to decode subject access review:", err)
+			}
+			review.APIVersion, review.Kind = "authorization.k8s.io/v1", "SubjectAccessReview"
+			review.Status.Allowed = review.Spec.User == "jane" && review.Spec.ResourceAttributes.Verb == "update" && review.Spec.ResourceAttributes.Resource == checkCRDResource
+			json.NewEncoder(w).Encode(review)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	previousClient := kubernetesClient
+	var err error
+	kubernetesClient, err = kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		kubernetesClient = previousClient
+		server.Close()
+	}
+}
+
+// TestRunCheckHandler ensures that runs of checks can be requested from the master by name
+func TestRunCheckHandler(t *testing.T) {
+	previousIsMaster := isMaster
+	defer func() {
+		isMaster = previousIsMaster
+	}()
+	defer authAPIServer(t)()
+
+	c := &external.Checker{
+		CheckName: "test-check",
+		Namespace: "kuberhealthy",
+		RunNow:    make(chan struct{}, 1),
+	}
+	kh := &Kuberhealthy{
+		Checks: []*external.Checker{c},
+	}
+
+	testCases := []struct {
+		description  string
+		master       bool
+		name         string
+		token        string
+		expectedCode int
+	}{
+		{description: "no token", master: true, name: "test-check", expectedCode: http.StatusUnauthorized},
+		{description: "invalid token", master: true, name: "test-check", token: "invalid-token", expectedCode: http.StatusUnauthorized},
+		{description: "not master", master: false, name: "test-check", token: "valid-token", expectedCode: http.StatusServiceUnavailable},
+		{description: "unknown check", master: true, name: "missing-check", token: "valid-token", expectedCode: http.StatusNotFound},
+		{description: "known check", master: true, name: "test-check", token: "valid-token", expectedCode: http.StatusAccepted},
+	}
+
+	for _, tc := range testCases {
+		isMaster = tc.master
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/checks/kuberhealthy/"+tc.name+"/run", nil)
+		req.SetPathValue("namespace", "kuberhealthy")
+		req.SetPathValue("name", tc.name)
+		if len(tc.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		recorder := httptest.NewRecorder()
+
+		err := kh.runCheckHandler(recorder, req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error from run check handler: %s", tc.description, err)
+		}
+		if recorder.Code != tc.expectedCode {
+			t.Fatalf("%s: expected status code %d but got %d", tc.description, tc.expectedCode, recorder.Code)
+		}
+
+		response := runCheckResponse{}
+		err = json.NewDecoder(recorder.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("%s: failed to decode response: %s", tc.description, err)
+		}
+		if tc.expectedCode == http.StatusAccepted && len(response.UUID) == 0 {
+			t.Fatalf("%s: expected a run uuid in the response", tc.description)
+		}
+	}
+
+	// the requested run must be signaled to the check
+	select {
+	case <-c.RunNow:
+	default:
+		t.Fatal("Expected a run of the check to be requested")
+	}
+}
+
+// TestRunCheckHandlerUnauthenticated ensures that requests without a token are rejected before a run is requested
+func TestRunCheckHandlerUnauthenticated(t *testing.T) {
+	previousIsMaster := isMaster
+	defer func() {
+		isMaster = previousIsMaster
+	}()
+	isMaster = true
+
+	c := &external.Checker{
+		CheckName: "test-check",
+		Namespace: "kuberhealthy",
+		RunNow:    make(chan struct{}, 1),
+	}
+	kh := &Kuberhealthy{
+		Checks: []*external.Checker{c},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checks/kuberhealthy/test-check/run", nil)
+	req.SetPathValue("namespace", "kuberhealthy")
+	req.SetPathValue("name", "test-check")
+	recorder := httptest.NewRecorder()
+
+	err := kh.runCheckHandler(recorder, req)
+	if err != nil {
+		t.Fatalf("unexpected error from run check handler: %s", err)
+	}
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an unauthenticated request to be rejected with %d but got %d", http.StatusUnauthorized, recorder.Code)
+	}
+
+	select {
+	case <-c.RunNow:
+		t.Fatal("Expected no run of the check to be requested")
+	default:
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// scheduleTicker behaves like a time.Ticker, but sends a tick on C each time the supplied cron
+// schedule fires instead of on a fixed interval.  Just like a time.Ticker, ticks are dropped
+// if the reader is not keeping up.
+type scheduleTicker struct {
+	C        chan time.Time
+	schedule *cronexpr.Expression
+	stopChan chan struct{}
+}
+
+// newScheduleTicker creates a scheduleTicker for the supplied cron schedule and starts it in the background
+func newScheduleTicker(schedule *cronexpr.Expression) *scheduleTicker {
+	t := &scheduleTicker{
+		C:        make(chan time.Time, 1),
+		schedule: schedule,
+		stopChan: make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// run sends ticks on the ticker's channel at each scheduled time until the ticker is stopped
+func (t *scheduleTicker) run() {
+	for {
+		nextRun := nextScheduledRun(t.schedule, time.Now())
+		if nextRun.IsZero() {
+			log.Warningln("scheduleTicker: schedule has no future run times. No more runs will be scheduled.")
+			return
+		}
+		log.Debugln("scheduleTicker: next scheduled run is at", nextRun)
+
+		timer := time.NewTimer(time.Until(nextRun))
+		select {
+		case <-t.stopChan:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			select {
+			case t.C <- now:
+			default:
+				log.Debugln("scheduleTicker: skipping scheduled run because the previous run is still in progress")
+			}
+		}
+	}
+}
+
+// Stop turns off the ticker.  No more ticks will be sent after Stop is called.
+func (t *scheduleTicker) Stop() {
+	close(t.stopChan)
+}
+
+// nextScheduledRun returns the next time after the supplied time that the cron schedule fires.  A zero time
+// is returned if the schedule will never fire again.
+func nextScheduledRun(schedule *cronexpr.Expression, from time.Time) time.Time {
+	return schedule.Next(from)
+}
+
+// parseCheckSchedule parses a cron expression from a khcheck spec
+func parseCheckSchedule(s string) (*cronexpr.Expression, error) {
+	return cronexpr.Parse(s)
+}
+
+// newCheckTicker returns a channel that receives a value every time the supplied check is due to run,
+// along with a func to stop it and a func to change the run interval.  Checks with a cron schedule are run at each
+// scheduled time and all others are run on their run interval.  Either way, the channel also receives a value
+// whenever a run of the check is requested with TriggerRun.  Changing the run interval of a check with a cron
+// schedule has no effect.
+func newCheckTicker(c *external.Checker) (<-chan time.Time, func(), func(time.Duration)) {
+
+	// checks without a schedule simply run on their interval
+	if len(c.RunSchedule) == 0 {
+		ticker := time.NewTicker(c.Interval())
+		tickChan, stop := withRunRequests(c, ticker.C, ticker.Stop)
+		return tickChan, stop, ticker.Reset
+	}
+
+	schedule, err := parseCheckSchedule(c.RunSchedule)
+	if err != nil {
+		log.Errorln("Error parsing schedule", c.RunSchedule, "for check", c.Name(), "in namespace", c.CheckNamespace(), err)
+		log.Errorln("Falling back to the run interval of", c.Interval())
+		ticker := time.NewTicker(c.Interval())
+		tickChan, stop := withRunRequests(c, ticker.C, ticker.Stop)
+		return tickChan, stop, ticker.Reset
+	}
+
+	ticker := newScheduleTicker(schedule)
+	tickChan, stop := withRunRequests(c, ticker.C, ticker.Stop)
+	return tickChan, stop, func(time.Duration) {}
+}
+
+// withRunRequests merges the run requests of a check into its tick channel.  The returned func stops both the
+// supplied ticker and the merging.
+func withRunRequests(c *external.Checker, ticks <-chan time.Time, stopTicker func()) (<-chan time.Time, func()) {
+	if c.RunNow == nil {
+		return ticks, stopTicker
+	}
+
+	out := make(chan time.Time, 1)
+	stopChan := make(chan struct{})
+	go func() {
+		for {
+			var t time.Time
+			select {
+			case <-stopChan:
+				return
+			case t = <-ticks:
+			case <-c.RunNow:
+				log.Infoln("Run requested for check", c.Name(), "in namespace", c.CheckNamespace())
+				t = time.Now()
+			}
+
+			// like a time.Ticker, drop the tick if a run is already waiting
+			select {
+			case out <- t:
+			default:
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return out, func() {
+		stopOnce.Do(func() {
+			stopTicker()
+			close(stopChan)
+		})
+	}
+}
+
+// randomStartDelay returns a random delay within the supplied jitter window to offset the first run of a check by.
+// The window is capped at the run interval of the check.
+func randomStartDelay(jitter time.Duration, interval time.Duration) time.Duration {
+	if interval > 0 && jitter > interval {
+		jitter = interval
+	}
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestNextScheduledRun ensures that the next run time of a check is resolved from its cron schedule
+func TestNextScheduledRun(t *testing.T) {
+
+	schedule, err := parseCheckSchedule("*/15 2-4 * * *")
+	if err != nil {
+		t.Fatal("Failed to parse schedule:", err)
+	}
+
+	testCases := []struct {
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			from:     time.Date(2021, 1, 1, 2, 0, 0, 0, time.UTC),
+			expected: time.Date(2021, 1, 1, 2, 15, 0, 0, time.UTC),
+		},
+		{
+			from:     time.Date(2021, 1, 1, 4, 45, 0, 0, time.UTC),
+			expected: time.Date(2021, 1, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			from:     time.Date(2021, 1, 1, 12, 3, 0, 0, time.UTC),
+			expected: time.Date(2021, 1, 2, 2, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		nextRun := nextScheduledRun(schedule, tc.from)
+		if !nextRun.Equal(tc.expected) {
+			t.Fatalf("Expected next run from %s to be %s but got %s", tc.from, tc.expected, nextRun)
+		}
+	}
+}
+
+// TestParseCheckScheduleInvalid ensures that invalid cron expressions are rejected
+func TestParseCheckScheduleInvalid(t *testing.T) {
+	_, err := parseCheckSchedule("not a schedule")
+	if err == nil {
+		t.Fatal("Expected an error when parsing an invalid schedule")
+	}
+}
+
+// TestWithRunRequests ensures that requested runs of a check are sent on its tick channel
+func TestWithRunRequests(t *testing.T) {
+	c := &external.Checker{
+		CheckName: "test-check",
+		Namespace: "kuberhealthy",
+		RunNow:    make(chan struct{}, 1),
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	tickChan, stop := withRunRequests(c, ticker.C, ticker.Stop)
+	defer stop()
+
+	if !c.TriggerRun() {
+		t.Fatal("Expected the first run request to be accepted")
+	}
+
+	select {
+	case <-tickChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Expected a tick after a run was requested")
+	}
+
+	// stopping more than once must be safe
+	stop()
+}
+
+// TestRandomStartDelay ensures that start delays stay within the jitter window and the run interval
+func TestRandomStartDelay(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		delay := randomStartDelay(time.Minute, time.Hour)
+		if delay < 0 || delay >= time.Minute {
+			t.Fatalf("Expected a delay within one minute but got %s", delay)
+		}
+		delay = randomStartDelay(time.Hour, time.Minute)
+		if delay < 0 || delay >= time.Minute {
+			t.Fatalf("Expected the delay to be capped at the run interval but got %s", delay)
+		}
+	}
+
+	if randomStartDelay(0, time.Minute) != 0 {
+		t.Fatal("Expected no delay without a jitter window")
+	}
+
+	cfg := &Config{RunIntervalJitterPercent: 10}
+	if jitter := cfg.runIntervalJitter(time.Minute * 10); jitter != time.Minute {
+		t.Fatalf("Expected a jitter window of 10%% of the run interval but got %s", jitter)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/masterCalculation"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/sharding"
+)
+
+// ShardingConfig holds the settings for spreading checks across every Kuberhealthy pod instead of running them all
+// on the master
+type ShardingConfig struct {
+	Enabled       bool          `yaml:"enabled"`                 // set to true to run checks on every Kuberhealthy pod, each running its share of them
+	LeaseDuration time.Duration `yaml:"leaseDuration,omitempty"` // how long a pod keeps its share of the checks after it last renewed its membership lease. defaults to 30s
+	RenewInterval time.Duration `yaml:"renewInterval,omitempty"` // how often pods renew their membership lease and look for pods that joined or left. defaults to 10s
+}
+
+// shardSet tracks the Kuberhealthy pods that checks are sharded across
+type shardSet struct {
+	mu   sync.RWMutex
+	ring *sharding.Ring // nil until the members are known
+}
+
+// newShardSet creates a shardSet with no known members
+func newShardSet() *shardSet {
+	return &shardSet{}
+}
+
+// setMembers replaces the Kuberhealthy pods that checks are sharded across
+func (s *shardSet) setMembers(members []string) {
+	ring := sharding.NewRing(members)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ring = ring
+}
+
+// owner returns the Kuberhealthy pod that runs the named check.  A blank owner is returned until the members are
+// known.
+func (s *shardSet) owner(name string, namespace string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ring == nil {
+		return ""
+	}
+	return s.ring.Owner(namespace + "/" + name)
+}
+
+// sharded indicates if checks are spread across every Kuberhealthy pod instead of running on the master
+func (k *Kuberhealthy) sharded() bool {
+	return k.shards != nil
+}
+
+// runsChecks indicates if this instance runs checks.  Without sharding, only the master runs checks.
+func (k *Kuberhealthy) runsChecks() bool {
+	return isMaster || k.sharded()
+}
+
+// ownsCheck indicates if this instance runs the named check when it runs checks.  Without sharding, the master
+// runs every check.
+func (k *Kuberhealthy) ownsCheck(name string, namespace string) bool {
+	if !k.sharded() {
+		return true
+	}
+	return k.shards.owner(name, namespace) == podHostname
+}
+
+// shardsChanged indicates if the checks this instance owns differ from the checks it is running, so that they
+// must be restarted to rebalance the checks across the members
+func (k *Kuberhealthy) shardsChanged() bool {
+	khChecks, err := k.cachedKHChecks()
+	if err != nil {
+		log.Errorln("control: error listing khchecks to rebalance:", err)
+		return true
+	}
+
+	owned := make(map[string]bool)
+	for _, kc := range khChecks.Items {
+		if k.ownsCheck(kc.Name, kc.Namespace) {
+			owned[kc.Namespace+"/"+kc.Name] = true
+		}
+	}
+	if len(owned) != len(k.Checks) {
+		return true
+	}
+	for _, c := range k.Checks {
+		if !owned[c.CheckNamespace()+"/"+c.Name()] {
+			return true
+		}
+	}
+	return false
+}
+
+// shardMembershipMonitor takes part in shard membership and notifies the supplied channel when the Kuberhealthy
+// pods that checks are sharded across change.  Membership runs until the supplied context is canceled, at which
+// point the checks of this instance are handed to the other members.
+func (k *Kuberhealthy) shardMembershipMonitor(ctx context.Context, membersChangedChan chan struct{}) {
+
+	membershipConfig := masterCalculation.MembershipConfig{
+		Group:         k.config.LeaseName,
+		LeaseDuration: k.config.Sharding.LeaseDuration,
+		RenewInterval: k.config.Sharding.RenewInterval,
+	}
+
+	membersChanged := func(members []string) {
+		k.shards.setMembers(members)
+
+		// signal a change without blocking. if a signal is already queued, the checks will be rebalanced anyway.
+		select {
+		case membersChangedChan <- struct{}{}:
+		default:
+		}
+	}
+
+	// continue retrying membership if it fails to start
+	for {
+		err := masterCalculation.RunShardMembership(ctx, kubernetesClient, membershipConfig, membersChanged)
+		if err != nil {
+			log.Errorln("control: error running shard membership:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debugln("control: shard membership monitor stopping due to context cancellation")
+			return
+		case <-time.After(time.Second * 5):
+		}
+	}
+}
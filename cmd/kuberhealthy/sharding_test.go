@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestOwnsCheck ensures that every check is run by exactly one shard member, that no checks are run before the
+// members are known and that every check is run without sharding
+func TestOwnsCheck(t *testing.T) {
+	previousHostname := podHostname
+	defer func() {
+		podHostname = previousHostname
+	}()
+
+	unsharded := &Kuberhealthy{}
+	if !unsharded.ownsCheck("check", "kuberhealthy") {
+		t.Fatalf("expected every check to be run without sharding")
+	}
+
+	k := &Kuberhealthy{shards: newShardSet()}
+	podHostname = "kuberhealthy-a"
+	if k.ownsCheck("check", "kuberhealthy") {
+		t.Fatalf("expected no checks to be run before the shard members are known")
+	}
+
+	members := []string{"kuberhealthy-a", "kuberhealthy-b"}
+	k.shards.setMembers(members)
+	for _, name := range []string{"check-a", "check-b", "check-c", "check-d"} {
+		owners := 0
+		for _, member := range members {
+			podHostname = member
+			if k.ownsCheck(name, "kuberhealthy") {
+				owners++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("expected check %s to be run by exactly one member but it is run by %d", name, owners)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// runTracker counts the check runs in flight, so that shutdown can stop new runs from starting and wait for the
+// runs in flight to finish
+type runTracker struct {
+	mu       sync.Mutex
+	draining bool          // set when shutdown begins. no runs start once it is set
+	inFlight int           // the number of runs in flight
+	drained  chan struct{} // closed once draining and no runs are in flight
+}
+
+// newRunTracker creates a runTracker with no runs in flight
+func newRunTracker() *runTracker {
+	return &runTracker{
+		drained: make(chan struct{}),
+	}
+}
+
+// start records that a run is starting.  False is returned if the run must not start because shutdown has begun.
+func (t *runTracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.inFlight++
+	return true
+}
+
+// done records that a run that was started has finished and stored its result
+func (t *runTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight--
+	if t.draining && t.inFlight == 0 {
+		close(t.drained)
+	}
+}
+
+// isDraining returns true once shutdown has begun
+func (t *runTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// drain stops new runs from starting and waits up to the supplied timeout for the runs in flight to finish.  The
+// number of runs still in flight when the timeout passes is returned.
+func (t *runTracker) drain(timeout time.Duration) int {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		if t.inFlight == 0 {
+			close(t.drained)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.drained:
+		return 0
+	case <-time.After(timeout):
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inFlight
+}
+
+// setRunInterrupted records on the khstate of a check that its run in flight was stopped by the shutdown of this
+// Kuberhealthy pod.  The interrupted run has no result, so the result of the previous run is kept.
+func setRunInterrupted(name string, namespace string) {
+	_, err := stateWrites.write(namespace, sanitizeResourceName(name), func(khState *khstatev1.KuberhealthyState) error {
+		khState.Spec.Interrupted = "run was interrupted by the shutdown of Kuberhealthy pod " + podHostname
+		khState.Spec.Progress = nil
+		return nil
+	})
+	if err != nil {
+		log.Errorln("Error marking the run of", name, "in namespace", namespace, "as interrupted:", err)
+	}
+}
+
+// checkHandoffDelay returns how long the first run of a check waits after this instance starts its checks, so that
+// a check that another Kuberhealthy pod ran recently is not run again right away when the master changes
+func checkHandoffDelay(c *external.Checker) time.Duration {
+	khState, err := khStateClient.KuberhealthyStates(c.CheckNamespace()).Get(sanitizeResourceName(c.Name()), metav1.GetOptions{})
+	if err != nil {
+		return 0
+	}
+	return handoffDelay(khState.Spec, c.Interval(), podHostname, time.Now())
+}
+
+// handoffDelay returns the time left until the next run of a check is due when its last run was stored by another
+// Kuberhealthy pod.  Runs that were interrupted by a shutdown are due right away, because they have no result.
+func handoffDelay(state khstatev1.WorkloadDetails, interval time.Duration, hostname string, now time.Time) time.Duration {
+	if state.LastRun == nil || state.AuthoritativePod == hostname || len(state.Interrupted) > 0 {
+		return 0
+	}
+
+	// checks that back off while failing are due after the interval they backed off to
+	if effective, err := time.ParseDuration(state.EffectiveRunInterval); err == nil && effective > 0 {
+		interval = effective
+	}
+
+	// a last run that is in the future is the result of clock skew and is ignored
+	remaining := state.LastRun.Add(interval).Sub(now)
+	if remaining <= 0 || remaining > interval {
+		return 0
+	}
+	return remaining
+}
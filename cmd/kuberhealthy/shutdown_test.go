@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestRunTracker ensures that draining stops new runs from starting and waits for the runs in flight to finish
+func TestRunTracker(t *testing.T) {
+	tracker := newRunTracker()
+	if !tracker.start() || !tracker.start() {
+		t.Fatalf("expected runs to start before draining")
+	}
+
+	inFlight := tracker.drain(time.Millisecond * 10)
+	if inFlight != 2 {
+		t.Fatalf("expected 2 runs to still be in flight after the timeout but got %d", inFlight)
+	}
+	if tracker.start() {
+		t.Fatalf("expected runs not to start while draining")
+	}
+
+	tracker.done()
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		tracker.done()
+	}()
+	inFlight = tracker.drain(time.Second * 5)
+	if inFlight != 0 {
+		t.Fatalf("expected every run to finish before the timeout but %d are in flight", inFlight)
+	}
+}
+
+// TestHandoffDelay ensures that checks last run by another kuberhealthy pod wait until their next run is due, and
+// that interrupted runs and runs of this pod do not delay the first run
+func TestHandoffDelay(t *testing.T) {
+	now := time.Now()
+	lastRun := metav1.NewTime(now.Add(-time.Minute))
+	futureRun := metav1.NewTime(now.Add(time.Hour))
+
+	testCases := []struct {
+		name     string
+		state    khstatev1.WorkloadDetails
+		expected time.Duration
+	}{
+		{name: "never run", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-b"}},
+		{name: "ran on another pod", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-b", LastRun: &lastRun}, expected: time.Minute * 4},
+		{name: "ran on this pod", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-a", LastRun: &lastRun}},
+		{name: "interrupted", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-b", LastRun: &lastRun, Interrupted: "run was interrupted"}},
+		{name: "backed off", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-b", LastRun: &lastRun, EffectiveRunInterval: "10m"}, expected: time.Minute * 9},
+		{name: "clock skew", state: khstatev1.WorkloadDetails{AuthoritativePod: "kuberhealthy-b", LastRun: &futureRun}},
+	}
+
+	for _, tc := range testCases {
+		delay := handoffDelay(tc.state, time.Minute*5, "kuberhealthy-a", now)
+		if delay != tc.expected {
+			t.Fatalf("%s: expected a delay of %s but got %s", tc.name, tc.expected, delay)
+		}
+	}
+}
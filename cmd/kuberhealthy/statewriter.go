@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// stateWrites coalesces the khstate writes of this Kuberhealthy instance
+var stateWrites = newStateWriter(func() khstatev1.KuberhealthyStatesGetter { return khStateClient }, func() time.Duration {
+	return cfg.stateWriteBatchInterval()
+})
+
+// stateMutation changes a khstate that is about to be written.  A mutation that returns an error is left out of
+// the write, and the error is returned to the caller that queued it.
+type stateMutation func(khState *khstatev1.KuberhealthyState) error
+
+// stateWrite is a mutation waiting to be written along with the channel that its result is sent on
+type stateWrite struct {
+	mutate stateMutation
+	result chan stateWriteResult
+}
+
+// stateWriteResult is the khstate after a batch of writes, or the error that stopped a write
+type stateWriteResult struct {
+	khState khstatev1.KuberhealthyState
+	err     error
+}
+
+// stateWriteBatch is the set of writes queued for a single khstate
+type stateWriteBatch struct {
+	namespace string
+	name      string
+	writes    []stateWrite
+}
+
+// stateWriter batches the writes that are made to the same khstate in quick succession, such as progress updates,
+// node reports and the final result of a run, into a single JSON merge patch.  Patches only carry the fields that
+// changed and are applied to the latest khstate, so writes to different fields do not conflict with each other.
+type stateWriter struct {
+	client   func() khstatev1.KuberhealthyStatesGetter // the client for the configured state storage backend
+	interval func() time.Duration                      // how long writes are collected before they are written. zero writes right away
+	mu       sync.Mutex                                // guards pending and flushing
+	pending  map[string]*stateWriteBatch               // the batches waiting to be written by namespace and name
+	flushing map[string]*sync.Mutex                    // held while the batch of a khstate is written, so batches of a khstate are written in order
+}
+
+// newStateWriter creates a stateWriter that writes khstates with the supplied client after the supplied interval
+func newStateWriter(client func() khstatev1.KuberhealthyStatesGetter, interval func() time.Duration) *stateWriter {
+	return &stateWriter{
+		client:   client,
+		interval: interval,
+		pending:  make(map[string]*stateWriteBatch),
+		flushing: make(map[string]*sync.Mutex),
+	}
+}
+
+// write queues a mutation of the named khstate and waits for it to be written along with the other mutations
+// queued within the batch interval.  The khstate is returned as it was written.
+func (w *stateWriter) write(namespace string, name string, mutate stateMutation) (khstatev1.KuberhealthyState, error) {
+	key := namespace + "/" + name
+	write := stateWrite{mutate: mutate, result: make(chan stateWriteResult, 1)}
+
+	w.mu.Lock()
+	batch, exists := w.pending[key]
+	if !exists {
+		batch = &stateWriteBatch{namespace: namespace, name: name}
+		w.pending[key] = batch
+	}
+	batch.writes = append(batch.writes, write)
+	w.mu.Unlock()
+
+	// the first write of a batch flushes it once the batch interval has passed
+	if !exists {
+		interval := w.interval()
+		if interval <= 0 {
+			w.flush(key)
+		} else {
+			time.AfterFunc(interval, func() { w.flush(key) })
+		}
+	}
+
+	result := <-write.result
+	return result.khState, result.err
+}
+
+// flush writes the batch of the supplied khstate key, if there is one
+func (w *stateWriter) flush(key string) {
+	w.mu.Lock()
+	batch := w.pending[key]
+	delete(w.pending, key)
+	flushing, exists := w.flushing[key]
+	if !exists {
+		flushing = &sync.Mutex{}
+		w.flushing[key] = flushing
+	}
+	w.mu.Unlock()
+	if batch == nil {
+		return
+	}
+
+	flushing.Lock()
+	defer flushing.Unlock()
+	if len(batch.writes) > 1 {
+		log.Debugln("Writing", len(batch.writes), "batched changes to khstate", key)
+	}
+	w.writeBatch(batch)
+}
+
+// writeBatch applies the mutations of a batch to the latest khstate and writes the changes as one merge patch.  The
+// result is sent to every caller in the batch.
+func (w *stateWriter) writeBatch(batch *stateWriteBatch) {
+	states := w.client().KuberhealthyStates(batch.namespace)
+	existing, err := states.Get(batch.name, metav1.GetOptions{})
+	if err != nil {
+		for _, write := range batch.writes {
+			write.result <- stateWriteResult{err: err}
+		}
+		return
+	}
+
+	// mutations that fail are left out and get their own error back
+	mutated := existing.DeepCopy()
+	var applied []stateWrite
+	for _, write := range batch.writes {
+		candidate := mutated.DeepCopy()
+		err := write.mutate(candidate)
+		if err != nil {
+			write.result <- stateWriteResult{err: err}
+			continue
+		}
+		mutated = candidate
+		applied = append(applied, write)
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	written, err := patchKHState(states, existing, *mutated)
+	for _, write := range applied {
+		write.result <- stateWriteResult{khState: written, err: err}
+	}
+}
+
+// patchKHState writes the difference between two versions of a khstate as a JSON merge patch.  The status is
+// written separately through the status subresource, so changes to it are not part of the patch.  Nothing is
+// written when the khstate did not change.
+func patchKHState(states khstatev1.KuberhealthyStateInterface, existing khstatev1.KuberhealthyState, mutated khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	patch, err := stateMergePatch(existing, mutated)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	if string(patch) == "{}" {
+		return existing, nil
+	}
+	return states.Patch(existing.GetName(), types.MergePatchType, patch)
+}
+
+// stateMergePatch creates a JSON merge patch that changes the metadata and spec of one khstate into those of another
+func stateMergePatch(existing khstatev1.KuberhealthyState, mutated khstatev1.KuberhealthyState) ([]byte, error) {
+	mutated.Status = existing.Status
+	original, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := json.Marshal(mutated)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(original, modified)
+}
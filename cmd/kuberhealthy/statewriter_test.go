@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// fakeStates keeps a single khstate in memory and counts the patches applied to it
+type fakeStates struct {
+	khstatev1.KuberhealthyStateInterface
+	sync.Mutex
+	state   khstatev1.KuberhealthyState
+	patches []string
+}
+
+// KuberhealthyStates returns the fake for every namespace
+func (f *fakeStates) KuberhealthyStates(namespace string) khstatev1.KuberhealthyStateInterface {
+	return f
+}
+
+// Get returns the khstate
+func (f *fakeStates) Get(name string, options metav1.GetOptions) (khstatev1.KuberhealthyState, error) {
+	f.Lock()
+	defer f.Unlock()
+	return *f.state.DeepCopy(), nil
+}
+
+// Patch applies a merge patch to the khstate
+func (f *fakeStates) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (khstatev1.KuberhealthyState, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.patches = append(f.patches, string(data))
+	original, err := json.Marshal(f.state)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	patched, err := jsonpatch.MergePatch(original, data)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	f.state = khstatev1.KuberhealthyState{}
+	err = json.Unmarshal(patched, &f.state)
+	return *f.state.DeepCopy(), err
+}
+
+// TestStateWriter ensures that writes to a khstate made within the batch interval are written as one merge patch,
+// and that failed mutations are left out of it
+func TestStateWriter(t *testing.T) {
+	states := &fakeStates{
+		state: khstatev1.NewKuberhealthyState("dns", khstatev1.WorkloadDetails{OK: true, CurrentUUID: "1234", Errors: []string{}}),
+	}
+	writer := newStateWriter(func() khstatev1.KuberhealthyStatesGetter { return states }, func() time.Duration {
+		return time.Millisecond * 100
+	})
+
+	mutations := []stateMutation{
+		func(khState *khstatev1.KuberhealthyState) error {
+			khState.Spec.Progress = &khstatev1.RunProgress{Message: "resolving", Step: 1, TotalSteps: 2}
+			return nil
+		},
+		func(khState *khstatev1.KuberhealthyState) error {
+			khState.Spec.Delayed = "waiting"
+			return nil
+		},
+		func(khState *khstatev1.KuberhealthyState) error {
+			khState.Spec.OK = false
+			return errors.New("stale report")
+		},
+	}
+
+	errs := make([]error, len(mutations))
+	var wg sync.WaitGroup
+	for i, mutate := range mutations {
+		wg.Add(1)
+		go func(i int, mutate stateMutation) {
+			defer wg.Done()
+			_, errs[i] = writer.write("kuberhealthy", "dns", mutate)
+		}(i, mutate)
+	}
+	wg.Wait()
+
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors from batched writes: %v", errs)
+	}
+	if errs[2] == nil {
+		t.Fatalf("expected the failed mutation to return its error")
+	}
+	if len(states.patches) != 1 {
+		t.Fatalf("expected the writes to be batched into 1 patch but got %d: %v", len(states.patches), states.patches)
+	}
+	if states.state.Spec.Progress == nil || states.state.Spec.Delayed != "waiting" || !states.state.Spec.OK || states.state.Spec.CurrentUUID != "1234" {
+		t.Fatalf("unexpected khstate after batched writes: %+v", states.state.Spec)
+	}
+
+	// writes that do not change the khstate are not sent
+	_, err := writer.write("kuberhealthy", "dns", func(khState *khstatev1.KuberhealthyState) error {
+		khState.Spec.Delayed = "waiting"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from a write without changes: %s", err)
+	}
+	if len(states.patches) != 1 {
+		t.Fatalf("expected a write without changes not to be sent but got patches %v", states.patches)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+// status page formats
+const (
+	statusFormatJSON  = "json"
+	statusFormatYAML  = "yaml"
+	statusFormatText  = "text"
+	statusFormatJUnit = "junit"
+)
+
+// statusFilter narrows the status page down to the checks and jobs that a client asked for with query parameters
+type statusFilter struct {
+	checks     []string        // names of the checks and jobs to show, either as name or namespace/name
+	failedOnly bool            // only show checks and jobs that are failing
+	selector   labels.Selector // only show checks and jobs whose labels match
+	format     string          // the format that the status page is written in
+}
+
+// parseStatusFilter reads the filter of the status page from the supplied query parameters
+func parseStatusFilter(values url.Values) (statusFilter, error) {
+	f := statusFilter{format: statusFormatJSON}
+
+	for _, c := range strings.Split(values.Get("check"), ",") {
+		c = strings.TrimSpace(c)
+		if len(c) > 0 {
+			f.checks = append(f.checks, c)
+		}
+	}
+
+	if v := values.Get("failedOnly"); len(v) > 0 {
+		failedOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid failedOnly value %q: %w", v, err)
+		}
+		f.failedOnly = failedOnly
+	}
+
+	if v := values.Get("labelSelector"); len(v) > 0 {
+		selector, err := labels.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid labelSelector %q: %w", v, err)
+		}
+		f.selector = selector
+	}
+
+	if v := strings.ToLower(values.Get("format")); len(v) > 0 {
+		switch v {
+		case statusFormatJSON, statusFormatYAML, statusFormatText, statusFormatJUnit:
+			f.format = v
+		default:
+			return f, fmt.Errorf("unknown format %q. Use json, yaml, text or junit", v)
+		}
+	}
+	return f, nil
+}
+
+// filters indicates if the filter leaves out any checks or jobs
+func (f statusFilter) filters() bool {
+	return len(f.checks) > 0 || f.failedOnly || f.selector != nil
+}
+
+// apply returns the supplied state with only the checks and jobs that match the filter.  The overall OK state,
+// errors and warnings are worked out again from the checks and jobs that are left, so that a client asking about
+// a few checks is not told about the failures of others.
+func (f statusFilter) apply(state health.State, labelsOf func(name string, namespace string, workload khstatev1.KHWorkload) map[string]string) health.State {
+	if !f.filters() {
+		return state
+	}
+
+	filtered := health.NewState()
+	filtered.CurrentMaster = state.CurrentMaster
+	filtered.Metadata = state.Metadata
+	filtered.ClusterName = state.ClusterName
+	filtered.Environment = state.Environment
+
+	for _, workload := range []khstatev1.KHWorkload{khstatev1.KHCheck, khstatev1.KHJob} {
+		details := state.CheckDetails
+		filteredDetails := filtered.CheckDetails
+		if workload == khstatev1.KHJob {
+			details = state.JobDetails
+			filteredDetails = filtered.JobDetails
+		}
+
+		// sort the keys so that errors are always listed in the same order
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			d := details[key]
+			namespace, name := splitStatusKey(key, d.Namespace)
+			if !f.matches(name, namespace, d, workload, labelsOf) {
+				continue
+			}
+			filteredDetails[key] = d
+			addStatusErrors(&filtered, d)
+		}
+	}
+	return filtered
+}
+
+// matches indicates if a single check or job matches the filter
+func (f statusFilter) matches(name string, namespace string, details khstatev1.WorkloadDetails, workload khstatev1.KHWorkload, labelsOf func(name string, namespace string, workload khstatev1.KHWorkload) map[string]string) bool {
+	if len(f.checks) > 0 && !containsString(name, f.checks) && !containsString(namespace+"/"+name, f.checks) {
+		return false
+	}
+	if f.failedOnly && details.OK {
+		return false
+	}
+	if f.selector != nil && !f.selector.Matches(labels.Set(labelsOf(name, namespace, workload))) {
+		return false
+	}
+	return true
+}
+
+// splitStatusKey splits the namespace/name key of a check on the status page.  Keys without a namespace use the
+// namespace of the check details.
+func splitStatusKey(key string, namespace string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return namespace, key
+}
+
+// addStatusErrors adds the errors of a check or job to the overall errors or warnings of the state, the same way
+// that the state reflector does
+func addStatusErrors(state *health.State, details khstatev1.WorkloadDetails) {
+	if details.InMaintenance {
+		return
+	}
+	for _, e := range details.Errors {
+		if len(strings.TrimSpace(e)) == 0 {
+			continue
+		}
+		if !isCriticalSeverity(details.Severity) {
+			state.AddWarning(e)
+			continue
+		}
+		state.AddError(e)
+		state.OK = false
+	}
+}
+
+// statusLabels returns the labels of the khcheck or khjob of a status page entry.  Checks are looked up in the
+// khcheck informer to avoid a call to the API server for each check.
+func (k *Kuberhealthy) statusLabels(name string, namespace string, workload khstatev1.KHWorkload) map[string]string {
+	if workload == khstatev1.KHCheck {
+		if kc, ok := k.cachedKHCheck(name, namespace); ok {
+			return kc.GetLabels()
+		}
+	}
+	return workloadMetadata(name, namespace, workload).Labels
+}
+
+// writeStatus writes the state to the client in the format of the filter
+func writeStatus(w http.ResponseWriter, state health.State, format string) error {
+	switch format {
+	case statusFormatYAML:
+		b, err := yaml.Marshal(state)
+		if err != nil {
+			log.Warningln("Error marshaling health check yaml for caller:", err)
+			return err
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, err = w.Write(b)
+		return err
+	case statusFormatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err := w.Write([]byte(statusText(state)))
+		return err
+	case statusFormatJUnit:
+		return state.WriteJUnitResponse(w)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		return state.WriteHTTPStatusResponse(w)
+	}
+}
+
+// statusText renders the state as plain text with one line for each check and job, which is easy to read in a
+// terminal and to grep
+func statusText(state health.State) string {
+	var b strings.Builder
+	overall := "OK"
+	if !state.OK {
+		overall = "FAILING"
+	}
+	fmt.Fprintf(&b, "%s\t%d errors\t%d warnings\n", overall, len(state.Errors), len(state.Warnings))
+
+	for _, workload := range []khstatev1.KHWorkload{khstatev1.KHCheck, khstatev1.KHJob} {
+		details := state.CheckDetails
+		kind := "check"
+		if workload == khstatev1.KHJob {
+			details = state.JobDetails
+			kind = "job"
+		}
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			d := details[key]
+			status := "OK"
+			switch {
+			case d.Paused:
+				status = "PAUSED"
+			case d.InMaintenance:
+				status = "MAINTENANCE"
+			case !d.OK:
+				status = "FAILING"
+			}
+			fmt.Fprintf(&b, "%s\t%s\t%s", status, kind, key)
+			if len(d.Errors) > 0 {
+				fmt.Fprintf(&b, "\t%s", strings.Join(d.Errors, "; "))
+			}
+			if d.Progress != nil {
+				fmt.Fprintf(&b, "\t%s", progressText(d.Progress))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// progressText formats the progress of a run that is in flight for the text status page
+func progressText(progress *khstatev1.RunProgress) string {
+	if progress.TotalSteps > 0 {
+		return fmt.Sprintf("in progress (step %d/%d): %s", progress.Step, progress.TotalSteps, progress.Message)
+	}
+	if progress.Step > 0 {
+		return fmt.Sprintf("in progress (step %d): %s", progress.Step, progress.Message)
+	}
+	return "in progress: " + progress.Message
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+// TestParseStatusFilter ensures that status page query parameters are parsed and that invalid values are rejected
+func TestParseStatusFilter(t *testing.T) {
+	testCases := []struct {
+		description string
+		query       string
+		expectError bool
+	}{
+		{description: "no parameters", query: ""},
+		{description: "every parameter", query: "check=dns,kuberhealthy/deployment&failedOnly=true&labelSelector=team%3Dpayments&format=YAML"},
+		{description: "invalid failedOnly", query: "failedOnly=maybe", expectError: true},
+		{description: "invalid label selector", query: "labelSelector=team%20in%20(payments", expectError: true},
+		{description: "junit format", query: "format=junit"},
+		{description: "unknown format", query: "format=xml", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		values, err := url.ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("%s: failed to parse query: %v", tc.description, err)
+		}
+		_, err = parseStatusFilter(values)
+		if tc.expectError != (err != nil) {
+			t.Fatalf("%s: expected error %t but got %v", tc.description, tc.expectError, err)
+		}
+	}
+
+	values, _ := url.ParseQuery("check=dns,%20,kuberhealthy/deployment&format=YAML")
+	f, err := parseStatusFilter(values)
+	if err != nil {
+		t.Fatal("Unexpected error parsing status filter:", err)
+	}
+	if len(f.checks) != 2 || f.checks[1] != "kuberhealthy/deployment" || f.format != statusFormatYAML {
+		t.Fatalf("Unexpected status filter: %+v", f)
+	}
+}
+
+// TestStatusFilterApply ensures that filtered status pages only contain matching checks and that the overall
+// status is worked out again from them
+func TestStatusFilterApply(t *testing.T) {
+	state := health.NewState()
+	state.OK = false
+	state.CheckDetails["kuberhealthy/dns"] = khstatev1.WorkloadDetails{OK: true, Namespace: "kuberhealthy"}
+	state.CheckDetails["payments/api"] = khstatev1.WorkloadDetails{OK: false, Namespace: "payments", Errors: []string{"api timed out"}}
+	state.CheckDetails["payments/batch"] = khstatev1.WorkloadDetails{OK: false, Namespace: "payments", Errors: []string{"batch failed"}, Severity: "warning"}
+	state.JobDetails["kuberhealthy/upgrade"] = khstatev1.WorkloadDetails{OK: false, Namespace: "kuberhealthy", Errors: []string{"upgrade failed"}}
+
+	labelsOf := func(name string, namespace string, workload khstatev1.KHWorkload) map[string]string {
+		if namespace == "payments" {
+			return map[string]string{"team": "payments"}
+		}
+		return map[string]string{"team": "platform"}
+	}
+
+	testCases := []struct {
+		description    string
+		query          string
+		expectedChecks int
+		expectedJobs   int
+		expectedOK     bool
+		expectedErrors int
+	}{
+		{description: "no filter", query: "", expectedChecks: 3, expectedJobs: 1, expectedOK: false},
+		{description: "check by name", query: "check=dns", expectedChecks: 1, expectedOK: true},
+		{description: "check by namespace and name", query: "check=payments/api", expectedChecks: 1, expectedOK: false, expectedErrors: 1},
+		{description: "failed only", query: "failedOnly=true", expectedChecks: 2, expectedJobs: 1, expectedOK: false, expectedErrors: 2},
+		{description: "warnings only", query: "check=batch", expectedChecks: 1, expectedOK: true},
+		{description: "label selector", query: "labelSelector=team%3Dplatform", expectedChecks: 1, expectedJobs: 1, expectedOK: false, expectedErrors: 1},
+		{description: "label selector and failed only", query: "labelSelector=team%3Dpayments&failedOnly=true", expectedChecks: 2, expectedOK: false, expectedErrors: 1},
+	}
+
+	for _, tc := range testCases {
+		values, _ := url.ParseQuery(tc.query)
+		f, err := parseStatusFilter(values)
+		if err != nil {
+			t.Fatalf("%s: unexpected error parsing status filter: %v", tc.description, err)
+		}
+		filtered := f.apply(state, labelsOf)
+		if len(filtered.CheckDetails) != tc.expectedChecks || len(filtered.JobDetails) != tc.expectedJobs {
+			t.Fatalf("%s: expected %d checks and %d jobs but got %d and %d", tc.description, tc.expectedChecks, tc.expectedJobs, len(filtered.CheckDetails), len(filtered.JobDetails))
+		}
+		if filtered.OK != tc.expectedOK {
+			t.Fatalf("%s: expected OK %t but got %t", tc.description, tc.expectedOK, filtered.OK)
+		}
+		if f.filters() && len(filtered.Errors) != tc.expectedErrors {
+			t.Fatalf("%s: expected %d errors but got %v", tc.description, tc.expectedErrors, filtered.Errors)
+		}
+	}
+}
+
+// TestStatusText ensures that the text status page has a summary line and a line for each check
+func TestStatusText(t *testing.T) {
+	state := health.NewState()
+	state.OK = false
+	state.Errors = []string{"api timed out"}
+	state.CheckDetails["payments/api"] = khstatev1.WorkloadDetails{OK: false, Errors: []string{"api timed out"}}
+	state.CheckDetails["kuberhealthy/dns"] = khstatev1.WorkloadDetails{OK: true}
+	state.CheckDetails["kuberhealthy/pod-restarts"] = khstatev1.WorkloadDetails{OK: false, Paused: true, Errors: []string{"too many restarts"}}
+	state.CheckDetails["kuberhealthy/deployment"] = khstatev1.WorkloadDetails{OK: true, Progress: &khstatev1.RunProgress{Message: "created test deployment", Step: 3, TotalSteps: 7}}
+
+	lines := strings.Split(strings.TrimSpace(statusText(state)), "\n")
+	expected := []string{
+		"FAILING\t1 errors\t0 warnings",
+		"OK\tcheck\tkuberhealthy/deployment\tin progress (step 3/7): created test deployment",
+		"OK\tcheck\tkuberhealthy/dns",
+		"PAUSED\tcheck\tkuberhealthy/pod-restarts\ttoo many restarts",
+		"FAILING\tcheck\tpayments/api\tapi timed out",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines but got %q", len(expected), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("Expected line %d to be %q but got %q", i, expected[i], lines[i])
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/cache"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// streamPath is the path that check state transitions are streamed on as server-sent events
+const streamPath = "/api/v1/stream"
+
+// streamKeepAliveInterval is how often a comment is sent to idle stream clients to keep their connection open
+const streamKeepAliveInterval = time.Second * 30
+
+// streamSubscriberBuffer is the number of events buffered for each stream client before events are dropped
+const streamSubscriberBuffer = 32
+
+// stateTransition is a check or job changing between OK and failing, as sent to stream clients
+type stateTransition struct {
+	Name       string     `json:"name"`
+	Namespace  string     `json:"namespace"`
+	OK         bool       `json:"ok"`
+	PreviousOK bool       `json:"previousOK"`
+	Errors     []string   `json:"errors"`
+	LastRun    *time.Time `json:"lastRun,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// stateStream fans out check state transitions to all connected stream clients
+type stateStream struct {
+	subscribers map[chan stateTransition]struct{}
+	sync.Mutex
+}
+
+// newStateStream creates a stateStream without any subscribers
+func newStateStream() *stateStream {
+	return &stateStream{
+		subscribers: make(map[chan stateTransition]struct{}),
+	}
+}
+
+// subscribe returns a channel that receives every published transition and a func that unsubscribes it
+func (s *stateStream) subscribe() (chan stateTransition, func()) {
+	c := make(chan stateTransition, streamSubscriberBuffer)
+	s.Lock()
+	s.subscribers[c] = struct{}{}
+	s.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			s.Lock()
+			delete(s.subscribers, c)
+			s.Unlock()
+		})
+	}
+}
+
+// publish sends a transition to every subscriber.  Subscribers that are not keeping up miss the transition
+// instead of holding up the others.
+func (s *stateStream) publish(t stateTransition) {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.subscribers {
+		select {
+		case c <- t:
+		default:
+			log.Warningln("stream: dropped transition of", t.Namespace+"/"+t.Name, "for a client that is not keeping up")
+		}
+	}
+}
+
+// publishStateChange publishes a transition if the reported state of a khstate changed between OK and failing.
+// Nothing is published for khstates that are new or have never been written by Kuberhealthy.
+func (s *stateStream) publishStateChange(previous *khstatev1.KuberhealthyState, current *khstatev1.KuberhealthyState) {
+	if previous == nil || current == nil || len(current.Spec.AuthoritativePod) == 0 {
+		return
+	}
+	previousState := reportedState(previous.Spec)
+	currentState := reportedState(current.Spec)
+	if previousState.OK == currentState.OK || previous.Spec.LastRun == nil {
+		return
+	}
+
+	t := stateTransition{
+		Name:       current.GetName(),
+		Namespace:  current.GetNamespace(),
+		OK:         currentState.OK,
+		PreviousOK: previousState.OK,
+		Errors:     currentState.Errors,
+		Time:       time.Now(),
+	}
+	if currentState.LastRun != nil {
+		lastRun := currentState.LastRun.Time
+		t.LastRun = &lastRun
+	}
+	s.publish(t)
+}
+
+// streamHandler streams check state transitions to the client as server-sent events until the client disconnects.
+// The namespace query parameter can be used to only receive the transitions of some namespaces.
+func (k *Kuberhealthy) streamHandler(w http.ResponseWriter, r *http.Request) error {
+	log.Infoln("Client connected to state stream from", r.RemoteAddr, r.UserAgent())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return errors.New("streaming is not supported by the response writer")
+	}
+
+	namespaces := make(map[string]bool)
+	for _, ns := range strings.Split(r.URL.Query().Get("namespace"), ",") {
+		if len(ns) > 0 {
+			namespaces[ns] = true
+		}
+	}
+
+	transitions, unsubscribe := k.stateStream.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Infoln("Client disconnected from state stream:", r.RemoteAddr)
+			return nil
+		case <-keepAlive.C:
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			if err != nil {
+				return fmt.Errorf("failed to write keep-alive to stream client: %w", err)
+			}
+		case t := <-transitions:
+			if len(namespaces) > 0 && !namespaces[t.Namespace] {
+				continue
+			}
+			b, err := json.Marshal(t)
+			if err != nil {
+				return fmt.Errorf("failed to marshal state transition: %w", err)
+			}
+			_, err = fmt.Fprintf(w, "event: transition\ndata: %s\n\n", b)
+			if err != nil {
+				return fmt.Errorf("failed to write state transition to stream client: %w", err)
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// notifyingStore is a cache.Store that calls onChange with the previous and current version of each khstate that
+// is updated in the store
+type notifyingStore struct {
+	cache.Store
+	onChange func(previous *khstatev1.KuberhealthyState, current *khstatev1.KuberhealthyState)
+}
+
+// Update stores the updated object and reports the change
+func (ns *notifyingStore) Update(obj interface{}) error {
+	previous := ns.existing(obj)
+	err := ns.Store.Update(obj)
+	if err == nil {
+		ns.changed(previous, obj)
+	}
+	return err
+}
+
+// Add stores the added object and reports the change if the object was already in the store
+func (ns *notifyingStore) Add(obj interface{}) error {
+	previous := ns.existing(obj)
+	err := ns.Store.Add(obj)
+	if err == nil {
+		ns.changed(previous, obj)
+	}
+	return err
+}
+
+// Replace replaces the contents of the store and reports the change of every object that was already in the store
+func (ns *notifyingStore) Replace(list []interface{}, resourceVersion string) error {
+	previous := make([]*khstatev1.KuberhealthyState, len(list))
+	for i, obj := range list {
+		previous[i] = ns.existing(obj)
+	}
+	err := ns.Store.Replace(list, resourceVersion)
+	if err == nil {
+		for i, obj := range list {
+			ns.changed(previous[i], obj)
+		}
+	}
+	return err
+}
+
+// existing returns the version of the supplied object that is currently in the store, or nil if there is none
+func (ns *notifyingStore) existing(obj interface{}) *khstatev1.KuberhealthyState {
+	item, exists, err := ns.Store.Get(obj)
+	if err != nil || !exists {
+		return nil
+	}
+	khState, ok := item.(*khstatev1.KuberhealthyState)
+	if !ok {
+		return nil
+	}
+	return khState
+}
+
+// changed calls onChange if it is set and the supplied object is a khstate
+func (ns *notifyingStore) changed(previous *khstatev1.KuberhealthyState, obj interface{}) {
+	if ns.onChange == nil {
+		return
+	}
+	current, ok := obj.(*khstatev1.KuberhealthyState)
+	if !ok {
+		return
+	}
+	ns.onChange(previous, current)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// testKHState creates a khstate that was last run by an authoritative pod with the supplied result
+func testKHState(name string, namespace string, ok bool) *khstatev1.KuberhealthyState {
+	details := khstatev1.NewWorkloadDetails(khstatev1.KHCheck)
+	details.OK = ok
+	details.AuthoritativePod = "kuberhealthy-test"
+	details.LastRun = &metav1.Time{Time: time.Now()}
+	if !ok {
+		details.Errors = []string{"check failed"}
+		details.ConsecutiveFailures = 1
+	}
+	khState := khstatev1.NewKuberhealthyState(name, details)
+	khState.Namespace = namespace
+	return &khState
+}
+
+// TestPublishStateChange ensures that only changes between OK and failing are published
+func TestPublishStateChange(t *testing.T) {
+	s := newStateStream()
+	transitions, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	// new khstates and runs with an unchanged result are not transitions
+	s.publishStateChange(nil, testKHState("check", "kuberhealthy", true))
+	s.publishStateChange(testKHState("check", "kuberhealthy", true), testKHState("check", "kuberhealthy", true))
+	select {
+	case tr := <-transitions:
+		t.Fatal("Expected no transition to be published but got", tr)
+	default:
+	}
+
+	s.publishStateChange(testKHState("check", "kuberhealthy", true), testKHState("check", "kuberhealthy", false))
+	select {
+	case tr := <-transitions:
+		if tr.OK || !tr.PreviousOK || tr.Name != "check" || tr.Namespace != "kuberhealthy" || len(tr.Errors) != 1 {
+			t.Fatalf("Unexpected transition published: %+v", tr)
+		}
+	default:
+		t.Fatal("Expected a transition to be published")
+	}
+}
+
+// TestStreamHandler ensures that transitions are written to stream clients as server-sent events
+func TestStreamHandler(t *testing.T) {
+	k := &Kuberhealthy{stateStream: newStateStream()}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := k.streamHandler(w, r)
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+streamPath+"?namespace=kuberhealthy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Failed to connect to stream:", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal("Expected an event stream but got content type", resp.Header.Get("Content-Type"))
+	}
+
+	// wait for the handler to subscribe before publishing
+	for {
+		k.stateStream.Lock()
+		subscribed := len(k.stateStream.subscribers) > 0
+		k.stateStream.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// transitions of other namespaces are filtered out
+	k.stateStream.publish(stateTransition{Name: "other", Namespace: "other"})
+	k.stateStream.publish(stateTransition{Name: "check", Namespace: "kuberhealthy", PreviousOK: true, Errors: []string{"check failed"}})
+
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			event = strings.TrimPrefix(line, "event: ")
+			continue
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		if event != "transition" {
+			t.Fatal("Expected a transition event but got", event)
+		}
+		var tr stateTransition
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &tr)
+		if err != nil {
+			t.Fatal("Failed to unmarshal transition:", err)
+		}
+		if tr.Name != "check" || tr.Namespace != "kuberhealthy" {
+			t.Fatalf("Expected the transition of kuberhealthy/check but got %+v", tr)
+		}
+		return
+	}
+	t.Fatal("Stream ended without a transition:", scanner.Err())
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// countConsecutiveFailures returns the number of failed runs in a row for a khstate that is being changed from
+// the existing state to the new state.  Only completed runs are counted, so a failure reported by a checker pod
+// is counted once its run is recorded, and any passing report resets the count.
+func countConsecutiveFailures(existing khstatev1.WorkloadDetails, state khstatev1.WorkloadDetails, run *khstatev1.RunRecord) int {
+	if state.OK {
+		return 0
+	}
+	if run == nil {
+		return existing.ConsecutiveFailures
+	}
+	return existing.ConsecutiveFailures + 1
+}
+
+// reportedState returns the state of a khstate as it should be reported on the status page, in metrics and to
+// notification sinks.  Failures are not reported until the workload has failed as many runs in a row as its
+// failure threshold.  Workloads without a failure threshold report their failures right away.
+func reportedState(details khstatev1.WorkloadDetails) khstatev1.WorkloadDetails {
+	if details.OK || details.FailureThreshold <= 1 || details.ConsecutiveFailures >= details.FailureThreshold {
+		return details
+	}
+
+	details.OK = true
+	details.Errors = []string{}
+	return details
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestCountConsecutiveFailures ensures that only completed failed runs are counted and that passing runs reset the count
+func TestCountConsecutiveFailures(t *testing.T) {
+	existing := khstatev1.WorkloadDetails{ConsecutiveFailures: 2}
+	failing := khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}}
+	passing := khstatev1.WorkloadDetails{OK: true}
+	run := &khstatev1.RunRecord{}
+
+	testCases := []struct {
+		description string
+		state       khstatev1.WorkloadDetails
+		run         *khstatev1.RunRecord
+		expected    int
+	}{
+		{description: "completed failed run", state: failing, run: run, expected: 3},
+		{description: "failure reported before the run completed", state: failing, run: nil, expected: 2},
+		{description: "completed passing run", state: passing, run: run, expected: 0},
+		{description: "passing report", state: passing, run: nil, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		count := countConsecutiveFailures(existing, tc.state, tc.run)
+		if count != tc.expected {
+			t.Fatalf("%s: expected %d consecutive failures but got %d", tc.description, tc.expected, count)
+		}
+	}
+}
+
+// TestReportedState ensures that failures are hidden until the failure threshold is reached
+func TestReportedState(t *testing.T) {
+	testCases := []struct {
+		description string
+		details     khstatev1.WorkloadDetails
+		expectOK    bool
+	}{
+		{
+			description: "no threshold",
+			details:     khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}},
+			expectOK:    false,
+		},
+		{
+			description: "below threshold",
+			details:     khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}, ConsecutiveFailures: 2, FailureThreshold: 3},
+			expectOK:    true,
+		},
+		{
+			description: "threshold reached",
+			details:     khstatev1.WorkloadDetails{OK: false, Errors: []string{"failed"}, ConsecutiveFailures: 3, FailureThreshold: 3},
+			expectOK:    false,
+		},
+		{
+			description: "passing",
+			details:     khstatev1.WorkloadDetails{OK: true, Errors: []string{}, FailureThreshold: 3},
+			expectOK:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		reported := reportedState(tc.details)
+		if reported.OK != tc.expectOK {
+			t.Fatalf("%s: expected reported OK to be %t but got %t", tc.description, tc.expectOK, reported.OK)
+		}
+		if reported.OK && len(reported.Errors) > 0 {
+			t.Fatalf("%s: expected no errors to be reported when OK but got %v", tc.description, reported.Errors)
+		}
+	}
+}
+
+// TestStoreReportOfUnownedCheck ensures that storing a report of a check that runs on another instance keeps the
+// failure threshold, severity and effective run interval that are already on its khstate
+func TestStoreReportOfUnownedCheck(t *testing.T) {
+	previousCfg := cfg
+	defer func() { cfg = previousCfg }()
+	cfg = &Config{}
+
+	states := &fakeStates{
+		state: khstatev1.NewKuberhealthyState("dns", khstatev1.WorkloadDetails{OK: true, Errors: []string{}, FailureThreshold: 3, Severity: "warning", EffectiveRunInterval: "20m0s"}),
+	}
+	writer := newStateWriter(func() khstatev1.KuberhealthyStatesGetter { return states }, func() time.Duration { return 0 })
+
+	// the check is not run by this instance
+	k := &Kuberhealthy{Checks: []*external.Checker{{CheckName: "other", Namespace: "kuberhealthy", FailureThreshold: 5}}}
+	details := khstatev1.WorkloadDetails{OK: false, Errors: []string{"lookup failed"}}
+	settingsKnown := k.applyCheckSettings("dns", "kuberhealthy", &details)
+	if settingsKnown {
+		t.Fatal("expected the settings of a check that is not run by this instance to be unknown")
+	}
+
+	_, err := writer.write("kuberhealthy", "dns", func(khState *khstatev1.KuberhealthyState) error {
+		khState.Spec = mergeRunState(khState.Spec, details, &khstatev1.RunRecord{}, settingsKnown)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := states.state.Spec
+	if spec.FailureThreshold != 3 || spec.ConsecutiveFailures != 1 {
+		t.Fatalf("expected the failure threshold of 3 to be kept with 1 failure but got %d and %d", spec.FailureThreshold, spec.ConsecutiveFailures)
+	}
+	if spec.Severity != "warning" || spec.EffectiveRunInterval != "20m0s" {
+		t.Fatalf("expected the warning severity and backed off run interval to be kept but got %q and %q", spec.Severity, spec.EffectiveRunInterval)
+	}
+	if !reportedState(spec).OK {
+		t.Fatal("expected a single failure below the failure threshold not to be reported")
+	}
+
+	// the instance that runs the check stores its own settings
+	k.Checks = append(k.Checks, &external.Checker{CheckName: "dns", Namespace: "kuberhealthy", FailureThreshold: 2})
+	details = khstatev1.WorkloadDetails{OK: false, Errors: []string{"lookup failed"}}
+	settingsKnown = k.applyCheckSettings("dns", "kuberhealthy", &details)
+	merged := mergeRunState(spec, details, &khstatev1.RunRecord{}, settingsKnown)
+	if !settingsKnown || merged.FailureThreshold != 2 || reportedState(merged).OK {
+		t.Fatalf("expected the failure threshold of the check to be stored and reached but got %+v", merged)
+	}
+}
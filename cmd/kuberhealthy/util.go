@@ -69,3 +69,12 @@ func containsString(s string, list []string) bool {
 	}
 	return false
 }
+
+// appendFailureLogs adds the checker pod logs captured from a failed run to the errors of the run.  The errors
+// are left as they are if no logs were captured.
+func appendFailureLogs(errors []string, logs string) []string {
+	if len(strings.TrimSpace(logs)) == 0 {
+		return errors
+	}
+	return append(errors, "Checker pod logs:\n"+logs)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// defaultAdmissionWebhookListenAddress is the address the admission webhook listens on when none is configured
+const defaultAdmissionWebhookListenAddress = ":8443"
+
+// khCheckValidationPath is the URL path that khcheck validation requests are served on
+const khCheckValidationPath = "/validate-khcheck"
+
+// AdmissionWebhookConfig holds the settings for the optional khcheck validating admission webhook
+type AdmissionWebhookConfig struct {
+	Enabled       bool   `yaml:"enabled"`                 // set to true to serve the admission webhook
+	ListenAddress string `yaml:"listenAddress,omitempty"` // the address to listen on for admission requests, such as ":8443"
+	CertFile      string `yaml:"certFile,omitempty"`      // the TLS certificate to serve the webhook with
+	KeyFile       string `yaml:"keyFile,omitempty"`       // the TLS key to serve the webhook with
+}
+
+// reservedCheckEnvVars are environment variables that Kuberhealthy injects into checker pods and that
+// khchecks are not allowed to set themselves
+var reservedCheckEnvVars = []string{
+	external.KHReportingURL,
+	external.KHReportingGRPCAddress,
+	external.KHRunUUID,
+	external.KHDeadline,
+	external.KHPodNamespace,
+	external.KHNodeName,
+}
+
+// StartAdmissionWebhookServer serves the khcheck validating admission webhook over TLS and restarts it if it
+// crashes.  Admission webhooks must be served over HTTPS, so a certificate and key are required.
+func StartAdmissionWebhookServer(config AdmissionWebhookConfig) {
+	listenAddress := config.ListenAddress
+	if len(listenAddress) == 0 {
+		listenAddress = defaultAdmissionWebhookListenAddress
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(khCheckValidationPath, func(w http.ResponseWriter, r *http.Request) {
+		err := khCheckValidationHandler(w, r)
+		if err != nil {
+			log.Errorln("admission webhook error:", err)
+		}
+	})
+
+	// reload the certificate whenever it is rotated
+	certs, err := newCertReloader(config.CertFile, config.KeyFile)
+	if err != nil {
+		log.Errorln("Error loading TLS certificate for admission webhook. The admission webhook will not be served:", err)
+		return
+	}
+
+	for {
+		log.Infoln("Starting khcheck admission webhook on", listenAddress)
+		server := &http.Server{
+			Addr:      listenAddress,
+			Handler:   mux,
+			TLSConfig: certs.tlsConfig(),
+		}
+		err := server.ListenAndServeTLS("", "")
+		if err != nil {
+			log.Errorln("Admission webhook ERROR:", err)
+		}
+		time.Sleep(time.Second / 2)
+	}
+}
+
+// khCheckValidationHandler handles AdmissionReview requests for khcheck resources and rejects khchecks with
+// invalid specs
+func khCheckValidationHandler(w http.ResponseWriter, r *http.Request) error {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("failed to read admission request body: %w", err)
+	}
+
+	review := admissionv1.AdmissionReview{}
+	err = json.Unmarshal(b, &review)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("failed to unmarshal admission review: %w", err)
+	}
+	if review.Request == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return fmt.Errorf("admission review contained no request")
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+
+	kc := khcheckv1.KuberhealthyCheck{}
+	err = json.Unmarshal(review.Request.Object.Raw, &kc)
+	if err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Message: "failed to decode khcheck: " + err.Error()}
+	} else if validationErrors := validateKHCheck(kc, review.Request.Namespace); len(validationErrors) > 0 {
+		log.Infoln("admission webhook: rejecting khcheck", kc.Namespace+"/"+kc.Name+":", validationErrors)
+		response.Allowed = false
+		response.Result = &metav1.Status{Message: "invalid khcheck: " + strings.Join(validationErrors, "; ")}
+	}
+
+	review.Response = response
+	review.Request = nil
+	review.APIVersion = "admission.k8s.io/v1"
+	review.Kind = "AdmissionReview"
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(review)
+}
+
+// validateKHCheck validates a khcheck in the supplied namespace and returns a list of every problem found with it
+func validateKHCheck(kc khcheckv1.KuberhealthyCheck, namespace string) []string {
+	if len(namespace) == 0 {
+		namespace = kc.Namespace
+	}
+	validationErrors := validateKHCheckSpec(kc.Spec)
+
+	// checker pods may only run under the service accounts they are allowed to use
+	var settings external.ServiceAccountSettings
+	var hostAccess external.HostAccessSettings
+	var podSecurity external.PodSecuritySettings
+	if cfg != nil {
+		settings = cfg.CheckServiceAccounts
+		hostAccess = cfg.HostAccess
+		podSecurity = cfg.PodSecurity
+	}
+	if problem := external.ServiceAccountProblem(kc.Spec.PodSpec, namespace, settings); len(problem) > 0 {
+		validationErrors = append(validationErrors, problem)
+	}
+
+	// checker pods may only share the network or process namespace of their node where a cluster operator allowed it
+	if problem := external.HostAccessProblem(kc.Spec.PodSpec, namespace, hostAccess); len(problem) > 0 {
+		validationErrors = append(validationErrors, problem)
+	}
+
+	// checker pods must meet the pod security level once kuberhealthy has hardened them
+	if problem := external.PodSecurityProblem(kc.Spec.PodSpec, podSecurity); len(problem) > 0 {
+		validationErrors = append(validationErrors, problem)
+	}
+	return validationErrors
+}
+
+// validateKHCheckSpec validates a khcheck spec and returns a list of every problem found with it
+func validateKHCheckSpec(spec khcheckv1.CheckConfig) []string {
+	var validationErrors []string
+
+	// a run interval is required unless the check runs on a schedule
+	if len(spec.RunInterval) == 0 && len(spec.Schedule) == 0 {
+		validationErrors = append(validationErrors, "runInterval must be set")
+	}
+	if len(spec.RunInterval) > 0 {
+		runInterval, err := time.ParseDuration(spec.RunInterval)
+		if err != nil {
+			validationErrors = append(validationErrors, "runInterval is not a valid duration: "+err.Error())
+		} else if runInterval <= 0 {
+			validationErrors = append(validationErrors, "runInterval must be greater than zero")
+		}
+	}
+
+	if len(spec.RunIntervalJitter) > 0 {
+		jitter, err := time.ParseDuration(spec.RunIntervalJitter)
+		if err != nil {
+			validationErrors = append(validationErrors, "runIntervalJitter is not a valid duration: "+err.Error())
+		} else if jitter < 0 {
+			validationErrors = append(validationErrors, "runIntervalJitter must not be negative")
+		}
+	}
+
+	if len(spec.Timeout) > 0 {
+		timeout, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			validationErrors = append(validationErrors, "timeout is not a valid duration: "+err.Error())
+		} else if timeout <= 0 {
+			validationErrors = append(validationErrors, "timeout must be greater than zero")
+		}
+	}
+
+	if len(spec.Schedule) > 0 {
+		_, err := parseCheckSchedule(spec.Schedule)
+		if err != nil {
+			validationErrors = append(validationErrors, "schedule is not a valid cron expression: "+err.Error())
+		}
+	}
+
+	if spec.FailureThreshold < 0 {
+		validationErrors = append(validationErrors, "failureThreshold must not be negative")
+	}
+
+	switch spec.Severity {
+	case "", khcheckv1.SeverityCritical, khcheckv1.SeverityWarning, khcheckv1.SeverityInfo:
+	default:
+		validationErrors = append(validationErrors, "severity must be critical, warning or info")
+	}
+
+	for i, w := range spec.MaintenanceWindows {
+		field := "maintenanceWindows[" + strconv.Itoa(i) + "]"
+		_, err := parseCheckSchedule(w.Schedule)
+		if err != nil {
+			validationErrors = append(validationErrors, field+".schedule is not a valid cron expression: "+err.Error())
+		}
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			validationErrors = append(validationErrors, field+".duration is not a valid duration: "+err.Error())
+		} else if duration <= 0 {
+			validationErrors = append(validationErrors, field+".duration must be greater than zero")
+		}
+		if len(w.Mode) > 0 && w.Mode != khcheckv1.MaintenanceModeSkip && w.Mode != khcheckv1.MaintenanceModeSuppress {
+			validationErrors = append(validationErrors, field+".mode must be skip or suppress")
+		}
+	}
+
+	// the extra environment is added to every container, so it can not set the variables of Kuberhealthy either
+	for _, env := range spec.ExtraEnv {
+		if isReservedCheckEnvVar(env.Name) {
+			validationErrors = append(validationErrors, "extraEnv can not set the reserved environment variable "+env.Name)
+		}
+	}
+
+	var allowedRegistries []string
+	if cfg != nil {
+		allowedRegistries = cfg.AllowedImageRegistries
+	}
+
+	return append(validationErrors, validateCheckPodSpec(spec.PodSpec, allowedRegistries)...)
+}
+
+// isReservedCheckEnvVar indicates if the named environment variable is one that Kuberhealthy injects into checker pods
+func isReservedCheckEnvVar(name string) bool {
+	for _, reserved := range reservedCheckEnvVars {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCheckPodSpec validates the pod spec of a khcheck and returns a list of every problem found with it.  Images
+// must come from one of the allowed registries, unless none are set.
+func validateCheckPodSpec(podSpec apiv1.PodSpec, allowedRegistries []string) []string {
+	var validationErrors []string
+
+	if len(podSpec.Containers) == 0 {
+		return append(validationErrors, "podSpec must have at least one container")
+	}
+
+	// checker pods must exit when they are done so that the next run can start
+	if podSpec.RestartPolicy == apiv1.RestartPolicyAlways {
+		validationErrors = append(validationErrors, "podSpec restartPolicy can not be Always")
+	}
+
+	for _, c := range podSpec.Containers {
+		if len(c.Image) == 0 {
+			validationErrors = append(validationErrors, "container "+c.Name+" has no image")
+		} else if !external.ImageAllowed(c.Image, allowedRegistries) {
+			validationErrors = append(validationErrors, "container "+c.Name+" image "+c.Image+" is not from an allowed image registry")
+		}
+
+		// these variables are always set by Kuberhealthy
+		for _, env := range c.Env {
+			if isReservedCheckEnvVar(env.Name) {
+				validationErrors = append(validationErrors, "container "+c.Name+" can not set the reserved environment variable "+env.Name)
+			}
+		}
+	}
+
+	return validationErrors
+}
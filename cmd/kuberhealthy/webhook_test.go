@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestValidateKHCheckSpec ensures that invalid khcheck specs are rejected and valid ones are not
+func TestValidateKHCheckSpec(t *testing.T) {
+
+	validSpec := khcheckv1.CheckConfig{
+		RunInterval: "5m",
+		Timeout:     "2m",
+		PodSpec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "main", Image: "kuberhealthy/test-check:latest"}},
+		},
+	}
+
+	testCases := map[string]struct {
+		modify      func(spec *khcheckv1.CheckConfig)
+		expectValid bool
+	}{
+		"valid spec": {
+			modify:      func(spec *khcheckv1.CheckConfig) {},
+			expectValid: true,
+		},
+		"empty pod spec": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.PodSpec = apiv1.PodSpec{} },
+			expectValid: false,
+		},
+		"missing image": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.PodSpec.Containers[0].Image = "" },
+			expectValid: false,
+		},
+		"invalid run interval": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.RunInterval = "five minutes" },
+			expectValid: false,
+		},
+		"invalid timeout": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.Timeout = "-1m" },
+			expectValid: false,
+		},
+		"warning severity": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.Severity = khcheckv1.SeverityWarning },
+			expectValid: true,
+		},
+		"invalid severity": {
+			modify:      func(spec *khcheckv1.CheckConfig) { spec.Severity = "urgent" },
+			expectValid: false,
+		},
+		"reserved environment variable": {
+			modify: func(spec *khcheckv1.CheckConfig) {
+				spec.PodSpec.Containers[0].Env = []apiv1.EnvVar{{Name: "KH_RUN_UUID", Value: "1234"}}
+			},
+			expectValid: false,
+		},
+		"extra environment variable": {
+			modify: func(spec *khcheckv1.CheckConfig) {
+				spec.ExtraEnv = []apiv1.EnvVar{{Name: "TARGET_URL", Value: "https://example.com"}}
+			},
+			expectValid: true,
+		},
+		"reserved extra environment variable": {
+			modify: func(spec *khcheckv1.CheckConfig) {
+				spec.ExtraEnv = []apiv1.EnvVar{{Name: "KH_REPORTING_URL", Value: "https://example.com"}}
+			},
+			expectValid: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		spec := *validSpec.DeepCopy()
+		tc.modify(&spec)
+		validationErrors := validateKHCheckSpec(spec)
+		if tc.expectValid && len(validationErrors) > 0 {
+			t.Fatalf("%s: expected spec to be valid but got errors: %v", name, validationErrors)
+		}
+		if !tc.expectValid && len(validationErrors) == 0 {
+			t.Fatalf("%s: expected spec to be rejected", name)
+		}
+	}
+}
+
+// TestValidateCheckPodSpecRegistries ensures that images from registries that are not allowed are rejected
+func TestValidateCheckPodSpecRegistries(t *testing.T) {
+	podSpec := apiv1.PodSpec{
+		Containers: []apiv1.Container{{Name: "main", Image: "kuberhealthy/test-check:latest"}},
+	}
+
+	if validationErrors := validateCheckPodSpec(podSpec, nil); len(validationErrors) > 0 {
+		t.Fatalf("Expected every image to be allowed without an allowlist but got errors: %v", validationErrors)
+	}
+	if validationErrors := validateCheckPodSpec(podSpec, []string{"docker.io/kuberhealthy"}); len(validationErrors) > 0 {
+		t.Fatalf("Expected the image to be allowed but got errors: %v", validationErrors)
+	}
+	if validationErrors := validateCheckPodSpec(podSpec, []string{"quay.io"}); len(validationErrors) != 1 {
+		t.Fatalf("Expected the image to be rejected but got errors: %v", validationErrors)
+	}
+}
+
+// TestValidateKHCheckServiceAccount ensures that khchecks in the kuberhealthy namespace can not run under the
+// service account of kuberhealthy
+func TestValidateKHCheckServiceAccount(t *testing.T) {
+	kc := khcheckv1.KuberhealthyCheck{}
+	kc.Spec.RunInterval = "5m"
+	kc.Spec.PodSpec = apiv1.PodSpec{
+		ServiceAccountName: "kuberhealthy",
+		Containers:         []apiv1.Container{{Name: "main", Image: "kuberhealthy/test-check:latest"}},
+	}
+
+	if validationErrors := validateKHCheck(kc, "kuberhealthy"); len(validationErrors) != 1 {
+		t.Fatalf("Expected the service account of kuberhealthy to be rejected but got errors: %v", validationErrors)
+	}
+	if validationErrors := validateKHCheck(kc, "payments"); len(validationErrors) > 0 {
+		t.Fatalf("Expected a service account of the check namespace to be allowed but got errors: %v", validationErrors)
+	}
+}
+
+// TestValidateKHCheckHostAccess ensures that khchecks may only set hostNetwork or hostPID in namespaces that are
+// allowed host access
+func TestValidateKHCheckHostAccess(t *testing.T) {
+	kc := khcheckv1.KuberhealthyCheck{}
+	kc.Spec.RunInterval = "5m"
+	kc.Spec.PodSpec = apiv1.PodSpec{
+		HostNetwork: true,
+		Containers:  []apiv1.Container{{Name: "main", Image: "kuberhealthy/test-check:latest"}},
+	}
+
+	previousCfg := cfg
+	defer func() { cfg = previousCfg }()
+	cfg = &Config{}
+
+	if validationErrors := validateKHCheck(kc, "node-diagnostics"); len(validationErrors) != 1 {
+		t.Fatalf("Expected host network to be rejected when host access is disabled but got errors: %v", validationErrors)
+	}
+
+	cfg.HostAccess = external.HostAccessSettings{Enabled: true, Namespaces: []string{"node-diagnostics"}}
+	if validationErrors := validateKHCheck(kc, "node-diagnostics"); len(validationErrors) > 0 {
+		t.Fatalf("Expected host network to be allowed in an allowed namespace but got errors: %v", validationErrors)
+	}
+	if validationErrors := validateKHCheck(kc, "payments"); len(validationErrors) != 1 {
+		t.Fatalf("Expected host network to be rejected in another namespace but got errors: %v", validationErrors)
+	}
+}
+
+// TestValidateKHCheckPodSecurity ensures that khchecks whose checker pods would violate the pod security level
+// are rejected once hardened
+func TestValidateKHCheckPodSecurity(t *testing.T) {
+	kc := khcheckv1.KuberhealthyCheck{}
+	kc.Spec.RunInterval = "5m"
+	kc.Spec.PodSpec = apiv1.PodSpec{
+		Containers: []apiv1.Container{{Name: "main", Image: "kuberhealthy/test-check:latest"}},
+	}
+
+	previousCfg := cfg
+	defer func() { cfg = previousCfg }()
+	cfg = &Config{PodSecurity: external.PodSecuritySettings{Level: external.PodSecurityRestricted}}
+
+	if validationErrors := validateKHCheck(kc, "payments"); len(validationErrors) > 0 {
+		t.Fatalf("Expected a checker pod that can be hardened to be allowed but got errors: %v", validationErrors)
+	}
+
+	privileged := true
+	kc.Spec.PodSpec.Containers[0].SecurityContext = &apiv1.SecurityContext{Privileged: &privileged}
+	if validationErrors := validateKHCheck(kc, "payments"); len(validationErrors) != 1 {
+		t.Fatalf("Expected a privileged checker pod to be rejected but got errors: %v", validationErrors)
+	}
+}
+
+// TestKHCheckValidationHandler ensures that admission reviews are answered with the result of validation
+func TestKHCheckValidationHandler(t *testing.T) {
+
+	kc := khcheckv1.KuberhealthyCheck{}
+	kc.Spec.RunInterval = "5m"
+	kcBytes, err := json.Marshal(kc)
+	if err != nil {
+		t.Fatal("Failed to marshal khcheck:", err)
+	}
+
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: kcBytes},
+		},
+	}
+	reviewBytes, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", khCheckValidationPath, bytes.NewReader(reviewBytes))
+	err = khCheckValidationHandler(w, r)
+	if err != nil {
+		t.Fatal("Failed to handle admission review:", err)
+	}
+
+	result := admissionv1.AdmissionReview{}
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatal("Failed to unmarshal admission review response:", err)
+	}
+	if result.Response == nil || result.Response.UID != "test-uid" {
+		t.Fatalf("Expected a response for the request uid but got %+v", result.Response)
+	}
+	if result.Response.Allowed {
+		t.Fatal("Expected a khcheck without containers to be rejected")
+	}
+}
@@ -0,0 +1,186 @@
+// Package main implements a network latency check for Kuberhealthy.  It measures the round trip latency and packet
+// loss of UDP probes sent from the checker pod to echo server pods on other nodes, and fails when either exceeds its
+// threshold.  The same image runs the echo servers when started with MODE=server.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultServerPort is the UDP port that echo servers listen on when SERVER_PORT is not set
+	defaultServerPort = 8088
+
+	// defaultServerSelector selects the echo server pods when SERVER_SELECTOR is not set
+	defaultServerSelector = "app=network-latency-server"
+
+	// defaultProbeCount is the number of probes sent to each target when PROBE_COUNT is not set
+	defaultProbeCount = 10
+
+	// defaultProbeTimeout is the time a probe waits for its echo when PROBE_TIMEOUT is not set
+	defaultProbeTimeout = time.Second
+
+	// defaultMaxLatency is the highest average round trip time that passes when MAX_LATENCY is not set
+	defaultMaxLatency = time.Millisecond * 50
+
+	// defaultMaxPacketLoss is the highest percentage of lost probes that passes when MAX_PACKET_LOSS_PERCENT is not set
+	defaultMaxPacketLoss = 10.0
+
+	// targetModeNode probes the echo server on every other node
+	targetModeNode = "node"
+
+	// targetModeZone probes one echo server in every zone
+	targetModeZone = "zone"
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile       = os.Getenv("KUBECONFIG")
+	mode                 = os.Getenv("MODE")
+	serverPortEnv        = os.Getenv("SERVER_PORT")
+	serverNamespace      = os.Getenv("SERVER_NAMESPACE")
+	serverSelector       = os.Getenv("SERVER_SELECTOR")
+	targetModeEnv        = os.Getenv("TARGET_MODE")
+	probeCountEnv        = os.Getenv("PROBE_COUNT")
+	probeTimeoutEnv      = os.Getenv("PROBE_TIMEOUT")
+	maxLatencyEnv        = os.Getenv("MAX_LATENCY")
+	maxPacketLossEnv     = os.Getenv("MAX_PACKET_LOSS_PERCENT")
+	nodeName             = os.Getenv("NODE_NAME")
+	kuberhealthyNodeName = os.Getenv("KH_NODE_NAME")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	port          int
+	namespace     string
+	selector      string
+	targetMode    string
+	probeCount    int
+	probeTimeout  time.Duration
+	maxLatency    time.Duration
+	maxPacketLoss float64
+	nodeName      string
+}
+
+func main() {
+	cfg, err := parseConfig()
+
+	// echo servers run until they are stopped and never report to Kuberhealthy
+	if mode == "server" {
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Fatalln(runServer(cfg.port))
+	}
+
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	// the echo servers are deployed on their own, so the check has nothing to tear down and only stops early
+	// enough to report in before its deadline
+	resourcecheck.Run(resourcecheck.Check{
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: time.Second * 5,
+		Run: func(ctx context.Context) []string {
+			targets, err := findTargets(ctx, client, cfg)
+			if err != nil {
+				return []string{err.Error()}
+			}
+			if len(targets) == 0 {
+				return []string{fmt.Sprintf("found no echo server pods on other nodes with selector %q in namespace %s", cfg.selector, cfg.namespace)}
+			}
+			return probeTargets(ctx, targets, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		port:          defaultServerPort,
+		namespace:     os.Getenv("KH_POD_NAMESPACE"),
+		selector:      defaultServerSelector,
+		targetMode:    targetModeNode,
+		probeCount:    defaultProbeCount,
+		probeTimeout:  defaultProbeTimeout,
+		maxLatency:    defaultMaxLatency,
+		maxPacketLoss: defaultMaxPacketLoss,
+		nodeName:      nodeName,
+	}
+	var err error
+
+	// checks that run on all nodes are told which node they run on
+	if len(kuberhealthyNodeName) > 0 {
+		cfg.nodeName = kuberhealthyNodeName
+	}
+	if len(serverNamespace) > 0 {
+		cfg.namespace = serverNamespace
+	}
+	if len(serverSelector) > 0 {
+		cfg.selector = serverSelector
+	}
+
+	if len(serverPortEnv) > 0 {
+		cfg.port, err = strconv.Atoi(serverPortEnv)
+		if err != nil || cfg.port <= 0 || cfg.port > 65535 {
+			return cfg, fmt.Errorf("SERVER_PORT must be a port number, but was %q", serverPortEnv)
+		}
+	}
+
+	if len(targetModeEnv) > 0 {
+		if targetModeEnv != targetModeNode && targetModeEnv != targetModeZone {
+			return cfg, fmt.Errorf("TARGET_MODE must be %s or %s, but was %q", targetModeNode, targetModeZone, targetModeEnv)
+		}
+		cfg.targetMode = targetModeEnv
+	}
+
+	if len(probeCountEnv) > 0 {
+		cfg.probeCount, err = strconv.Atoi(probeCountEnv)
+		if err != nil || cfg.probeCount <= 0 {
+			return cfg, fmt.Errorf("PROBE_COUNT must be a positive number, but was %q", probeCountEnv)
+		}
+	}
+
+	if len(probeTimeoutEnv) > 0 {
+		cfg.probeTimeout, err = time.ParseDuration(probeTimeoutEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing PROBE_TIMEOUT: %w", err)
+		}
+	}
+
+	if len(maxLatencyEnv) > 0 {
+		cfg.maxLatency, err = time.ParseDuration(maxLatencyEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing MAX_LATENCY: %w", err)
+		}
+	}
+
+	if len(maxPacketLossEnv) > 0 {
+		cfg.maxPacketLoss, err = strconv.ParseFloat(maxPacketLossEnv, 64)
+		if err != nil || cfg.maxPacketLoss < 0 || cfg.maxPacketLoss > 100 {
+			return cfg, fmt.Errorf("MAX_PACKET_LOSS_PERCENT must be a percentage, but was %q", maxPacketLossEnv)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// probeSize is the size of each probe packet.  It holds the sequence number of the probe.
+const probeSize = 8
+
+// probeInterval is the pause between the probes sent to a target
+const probeInterval = time.Millisecond * 100
+
+// zoneLabel is the well known node label that holds the zone of a node
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// target is an echo server pod that probes are sent to
+type target struct {
+	podName  string
+	nodeName string
+	zone     string
+	ip       string
+}
+
+// probeResult holds the round trip times of the probes sent to a target that were echoed in time
+type probeResult struct {
+	sent int
+	rtts []time.Duration
+}
+
+// averageRTT returns the average round trip time of the probes that were echoed
+func (r probeResult) averageRTT() time.Duration {
+	if len(r.rtts) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, rtt := range r.rtts {
+		total += rtt
+	}
+	return total / time.Duration(len(r.rtts))
+}
+
+// lossPercent returns the percentage of probes that were not echoed in time
+func (r probeResult) lossPercent() float64 {
+	if r.sent == 0 {
+		return 0
+	}
+	return float64(r.sent-len(r.rtts)) / float64(r.sent) * 100
+}
+
+// describe returns a readable name for the target that includes its node and zone
+func (t target) describe() string {
+	s := "pod " + t.podName + " on node " + t.nodeName
+	if len(t.zone) > 0 {
+		s += " in zone " + t.zone
+	}
+	return s
+}
+
+// findTargets lists the echo server pods and selects the ones to probe
+func findTargets(ctx context.Context, client kubernetes.Interface, cfg checkConfig) ([]target, error) {
+	pods, err := client.CoreV1().Pods(cfg.namespace).List(ctx, metav1.ListOptions{LabelSelector: cfg.selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list echo server pods with selector %q in namespace %s: %w", cfg.selector, cfg.namespace, err)
+	}
+
+	zones := make(map[string]string)
+	if cfg.targetMode == targetModeZone {
+		nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes to find their zones: %w", err)
+		}
+		for _, node := range nodes.Items {
+			zones[node.Name] = node.Labels[zoneLabel]
+		}
+	}
+
+	return selectTargets(pods.Items, zones, cfg.nodeName, cfg.targetMode), nil
+}
+
+// selectTargets picks the running echo server pods to probe.  Pods on the node of the checker pod are skipped so that
+// only latency across nodes is measured.  In zone mode, only the first pod of each zone is picked.
+func selectTargets(pods []v1.Pod, zones map[string]string, ownNode string, targetMode string) []target {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	var targets []target
+	seenZones := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodRunning || len(pod.Status.PodIP) == 0 {
+			continue
+		}
+		if len(ownNode) > 0 && pod.Spec.NodeName == ownNode {
+			continue
+		}
+		t := target{
+			podName:  pod.Name,
+			nodeName: pod.Spec.NodeName,
+			zone:     zones[pod.Spec.NodeName],
+			ip:       pod.Status.PodIP,
+		}
+		if targetMode == targetModeZone {
+			if seenZones[t.zone] {
+				continue
+			}
+			seenZones[t.zone] = true
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// probeTargets probes every target at the same time and returns a problem for every target whose latency or packet
+// loss exceeds its threshold
+func probeTargets(ctx context.Context, targets []target, cfg checkConfig) []string {
+	results := make([][]string, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			address := net.JoinHostPort(t.ip, strconv.Itoa(cfg.port))
+			result, err := probe(ctx, address, cfg.probeCount, cfg.probeTimeout)
+			if err != nil {
+				results[i] = []string{t.describe() + ": " + err.Error()}
+				return
+			}
+			results[i] = evaluateResult(t, result, cfg)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var problems []string
+	for _, r := range results {
+		problems = append(problems, r...)
+	}
+	return problems
+}
+
+// evaluateResult returns a problem for the latency and packet loss of a target that exceed their thresholds
+func evaluateResult(t target, result probeResult, cfg checkConfig) []string {
+	loss := result.lossPercent()
+	avg := result.averageRTT()
+	log.Infoln(t.describe()+":", len(result.rtts), "of", result.sent, "probes echoed with an average round trip time of", avg)
+
+	var problems []string
+	if loss > cfg.maxPacketLoss {
+		problems = append(problems, fmt.Sprintf("%s: packet loss of %.1f%% exceeds %.1f%%", t.describe(), loss, cfg.maxPacketLoss))
+	}
+	if len(result.rtts) > 0 && avg > cfg.maxLatency {
+		problems = append(problems, fmt.Sprintf("%s: average round trip time of %s exceeds %s", t.describe(), avg, cfg.maxLatency))
+	}
+	return problems
+}
+
+// probe sends the supplied number of probes to a UDP echo server and measures the round trip time of each probe
+// that is echoed before the timeout
+func probe(ctx context.Context, address string, count int, timeout time.Duration) (probeResult, error) {
+	result := probeResult{}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return result, fmt.Errorf("failed to open UDP connection to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	packet := make([]byte, probeSize)
+	reply := make([]byte, probeSize)
+	for seq := uint64(0); seq < uint64(count); seq++ {
+		if ctx.Err() != nil {
+			return result, errors.New("ran out of time while probing " + address)
+		}
+		if seq > 0 {
+			time.Sleep(probeInterval)
+		}
+
+		binary.BigEndian.PutUint64(packet, seq)
+		start := time.Now()
+		_, err = conn.Write(packet)
+		if err != nil {
+			return result, fmt.Errorf("failed to send probe to %s: %w", address, err)
+		}
+		result.sent++
+
+		// wait for the echo of this probe, skipping late echoes of earlier probes
+		err = conn.SetReadDeadline(start.Add(timeout))
+		if err != nil {
+			return result, err
+		}
+		for {
+			n, err := conn.Read(reply)
+			if err != nil {
+				// timeouts and refused packets count as lost probes
+				break
+			}
+			if n == probeSize && binary.BigEndian.Uint64(reply) == seq {
+				result.rtts = append(result.rtts, time.Since(start))
+				break
+			}
+		}
+	}
+	return result, nil
+}
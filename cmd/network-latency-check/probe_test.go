@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serverPod creates an echo server pod on the supplied node
+func serverPod(name string, nodeName string, phase v1.PodPhase) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1.PodSpec{NodeName: nodeName},
+		Status:     v1.PodStatus{Phase: phase, PodIP: "10.0.0." + name[len(name)-1:]},
+	}
+}
+
+func TestSelectTargets(t *testing.T) {
+	pods := []v1.Pod{
+		serverPod("server-4", "node-d", v1.PodRunning),
+		serverPod("server-1", "node-a", v1.PodRunning),
+		serverPod("server-2", "node-b", v1.PodRunning),
+		serverPod("server-3", "node-c", v1.PodPending),
+		serverPod("server-5", "node-e", v1.PodRunning),
+	}
+	zones := map[string]string{"node-a": "zone-1", "node-b": "zone-1", "node-c": "zone-2", "node-d": "zone-2", "node-e": "zone-3"}
+
+	// the pod on the own node and pods that are not running are skipped
+	targets := selectTargets(pods, zones, "node-a", targetModeNode)
+	if len(targets) != 3 {
+		t.Fatalf("expected three targets but got %v", targets)
+	}
+	if targets[0].podName != "server-2" || targets[0].zone != "zone-1" {
+		t.Fatalf("expected targets to be sorted by pod name but got %v", targets)
+	}
+
+	// one target is picked from each zone
+	targets = selectTargets(pods, zones, "node-a", targetModeZone)
+	if len(targets) != 3 {
+		t.Fatalf("expected one target in each zone but got %v", targets)
+	}
+	for i, zone := range []string{"zone-1", "zone-2", "zone-3"} {
+		if targets[i].zone != zone {
+			t.Errorf("expected target %d to be in %s but got %v", i, zone, targets[i])
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go serveEcho(conn)
+
+	result, err := probe(context.Background(), conn.LocalAddr().String(), 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.sent != 3 || len(result.rtts) != 3 {
+		t.Fatalf("expected all three probes to be echoed but got %d of %d", len(result.rtts), result.sent)
+	}
+	if result.lossPercent() != 0 {
+		t.Fatalf("expected no packet loss but got %.1f%%", result.lossPercent())
+	}
+}
+
+func TestProbeLoss(t *testing.T) {
+	// a listener that never echoes loses every probe
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	result, err := probe(context.Background(), conn.LocalAddr().String(), 2, time.Millisecond*50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.lossPercent() != 100 {
+		t.Fatalf("expected all probes to be lost but got %.1f%% loss", result.lossPercent())
+	}
+}
+
+func TestEvaluateResult(t *testing.T) {
+	cfg := checkConfig{maxLatency: time.Millisecond * 10, maxPacketLoss: 10}
+	tgt := target{podName: "server-1", nodeName: "node-a"}
+
+	ok := probeResult{sent: 10, rtts: []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}}
+	if problems := evaluateResult(tgt, ok, cfg); len(problems) != 0 {
+		t.Fatalf("expected no problems but got %v", problems)
+	}
+
+	slowAndLossy := probeResult{sent: 4, rtts: []time.Duration{time.Millisecond * 20, time.Millisecond * 30}}
+	if problems := evaluateResult(tgt, slowAndLossy, cfg); len(problems) != 2 {
+		t.Fatalf("expected a latency and a packet loss problem but got %v", problems)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runServer echoes every UDP packet received on the supplied port back to its sender until an error occurs
+func runServer(port int) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	log.Infoln("Echo server listening on UDP port", strconv.Itoa(port))
+	return serveEcho(conn)
+}
+
+// serveEcho echoes every packet received on the supplied connection back to its sender
+func serveEcho(conn *net.UDPConn) error {
+	buf := make([]byte, probeSize)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		_, err = conn.WriteToUDP(buf[:n], addr)
+		if err != nil {
+			log.Warningln("Error echoing probe to", addr.String()+":", err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// historyKey is the key of the ConfigMap data that holds the node transitions
+const historyKey = "history"
+
+// transition is a change of the Ready condition of a node
+type transition struct {
+	Time    time.Time `json:"time"`
+	Ready   bool      `json:"ready"`
+	Reason  string    `json:"reason,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// nodeHistory holds the transitions of the Ready condition of nodes by their name, oldest first
+type nodeHistory map[string][]transition
+
+// loadHistory reads the node transitions from their ConfigMap, or returns an empty history if it does not exist yet
+func loadHistory(ctx context.Context, client kubernetes.Interface, namespace string, name string) (nodeHistory, error) {
+	history := make(nodeHistory)
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cm.Data[historyKey]) == 0 {
+		return history, nil
+	}
+	err = json.Unmarshal([]byte(cm.Data[historyKey]), &history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// saveHistory writes the node transitions to their ConfigMap, creating it if it does not exist yet
+func saveHistory(ctx context.Context, client kubernetes.Interface, namespace string, name string, history nodeHistory) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{historyKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[historyKey] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// readyCondition returns the Ready condition of a node, or nil if it has none yet
+func readyCondition(node v1.Node) *v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == v1.NodeReady {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// record adds the current Ready condition of a node to its transitions when it changed since it was last recorded
+func (h nodeHistory) record(node v1.Node) {
+	condition := readyCondition(node)
+	if condition == nil {
+		return
+	}
+	t := transition{
+		Time:    condition.LastTransitionTime.Time.UTC(),
+		Ready:   condition.Status == v1.ConditionTrue,
+		Reason:  condition.Reason,
+		Message: condition.Message,
+	}
+
+	transitions := h[node.Name]
+	if len(transitions) > 0 {
+		last := transitions[len(transitions)-1]
+		if !t.Time.After(last.Time) && t.Ready == last.Ready {
+			return
+		}
+	}
+	log.Infoln("Node", node.Name, "transitioned to ready", t.Ready, "at", t.Time, t.Reason)
+	h[node.Name] = append(transitions, t)
+}
+
+// flaps returns how many times a node went from Ready to NotReady within the window before now
+func (h nodeHistory) flaps(name string, now time.Time, window time.Duration) int {
+	windowStart := now.Add(-window)
+	var flaps int
+	for i, t := range h[name] {
+		if t.Ready || !t.Time.After(windowStart) {
+			continue
+		}
+		// a change of the reason of a node that was already NotReady is not a flap
+		if i > 0 && !h[name][i-1].Ready {
+			continue
+		}
+		flaps++
+	}
+	return flaps
+}
+
+// lastNotReady returns the last transition of a node to NotReady, if it has any
+func (h nodeHistory) lastNotReady(name string) (transition, bool) {
+	transitions := h[name]
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if !transitions[i].Ready {
+			return transitions[i], true
+		}
+	}
+	return transition{}, false
+}
+
+// prune drops the transitions of nodes that no longer exist, and the transitions that are older than the window
+// except for the last one of every node
+func (h nodeHistory) prune(nodes []v1.Node, now time.Time, window time.Duration) {
+	live := make(map[string]bool)
+	for _, node := range nodes {
+		live[node.Name] = true
+	}
+	windowStart := now.Add(-window)
+	for name, transitions := range h {
+		if !live[name] {
+			delete(h, name)
+			continue
+		}
+		first := 0
+		for i, t := range transitions {
+			if t.Time.After(windowStart) {
+				break
+			}
+			first = i
+		}
+		h[name] = transitions[first:]
+	}
+}
+
+// evaluateNodes returns a problem for every node that flapped more than the maximum number of times within the
+// window, or that has been NotReady for longer than the maximum duration
+func evaluateNodes(nodes []v1.Node, h nodeHistory, now time.Time, cfg checkConfig) []string {
+	sorted := append([]v1.Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var problems []string
+	for _, node := range sorted {
+		condition := readyCondition(node)
+		if condition == nil {
+			log.Infoln("Node", node.Name, "has no Ready condition yet")
+			continue
+		}
+
+		notReadyFor := now.Sub(condition.LastTransitionTime.Time)
+		if condition.Status != v1.ConditionTrue && cfg.maxNotReady > 0 && notReadyFor > cfg.maxNotReady {
+			problems = append(problems, fmt.Sprintf("node %s has been NotReady for %s: %s", node.Name, notReadyFor.Round(time.Second), describeReason(condition.Reason, condition.Message)))
+		}
+
+		flaps := h.flaps(node.Name, now, cfg.flapWindow)
+		if flaps <= cfg.maxFlaps {
+			continue
+		}
+		problem := fmt.Sprintf("node %s flapped to NotReady %d times in the last %s", node.Name, flaps, cfg.flapWindow)
+		if last, ok := h.lastNotReady(node.Name); ok {
+			problem += ", last at " + last.Time.Format(time.RFC3339) + ": " + describeReason(last.Reason, last.Message)
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// describeReason joins the reason and message of a condition
+func describeReason(reason string, message string) string {
+	if len(reason) == 0 {
+		reason = "no reason given"
+	}
+	if len(message) == 0 {
+		return reason
+	}
+	return reason + ": " + message
+}
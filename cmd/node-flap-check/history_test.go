@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testNow is the time that the tests evaluate nodes at
+var testNow = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testNode creates a node whose Ready condition last transitioned the supplied time before testNow
+func testNode(name string, status v1.ConditionStatus, ago time.Duration, reason string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(testNow.Add(-ago)),
+			Reason:             reason,
+			Message:            "message of " + reason,
+		}}},
+	}
+}
+
+// testCheckConfig returns the default settings of the check
+func testCheckConfig() checkConfig {
+	return checkConfig{
+		maxFlaps:       defaultMaxFlaps,
+		flapWindow:     defaultFlapWindow,
+		maxNotReady:    defaultMaxNotReady,
+		stateNamespace: "kuberhealthy",
+		stateConfigMap: defaultStateConfigMap,
+	}
+}
+
+func TestRecordAndFlaps(t *testing.T) {
+	h := make(nodeHistory)
+
+	// a node that flaps twice within the window, and once before it
+	h.record(testNode("node-a", v1.ConditionFalse, time.Minute*90, "KubeletNotReady"))
+	h.record(testNode("node-a", v1.ConditionTrue, time.Minute*50, "KubeletReady"))
+	h.record(testNode("node-a", v1.ConditionFalse, time.Minute*40, "KubeletNotReady"))
+	h.record(testNode("node-a", v1.ConditionTrue, time.Minute*30, "KubeletReady"))
+	h.record(testNode("node-a", v1.ConditionFalse, time.Minute*10, "KubeletNotReady"))
+	h.record(testNode("node-a", v1.ConditionUnknown, time.Minute*5, "NodeStatusUnknown"))
+
+	// recording the same condition again changes nothing
+	h.record(testNode("node-a", v1.ConditionUnknown, time.Minute*5, "NodeStatusUnknown"))
+	if len(h["node-a"]) != 6 {
+		t.Fatalf("expected six transitions but got %v", h["node-a"])
+	}
+
+	// going from NotReady to Unknown 5 minutes ago is not another flap
+	if flaps := h.flaps("node-a", testNow, time.Hour); flaps != 2 {
+		t.Fatalf("expected two flaps within the window but got %d", flaps)
+	}
+	last, ok := h.lastNotReady("node-a")
+	if !ok || last.Reason != "NodeStatusUnknown" {
+		t.Fatalf("expected the last NotReady transition to be the unknown status but got %v", last)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	h := nodeHistory{
+		"node-a": {
+			{Time: testNow.Add(-time.Minute * 90)},
+			{Time: testNow.Add(-time.Minute * 70), Ready: true},
+			{Time: testNow.Add(-time.Minute * 30)},
+		},
+		"node-gone": {{Time: testNow.Add(-time.Minute * 30)}},
+	}
+	h.prune([]v1.Node{testNode("node-a", v1.ConditionTrue, 0, "")}, testNow, time.Hour)
+	if _, ok := h["node-gone"]; ok {
+		t.Fatal("expected the transitions of deleted nodes to be dropped")
+	}
+	if len(h["node-a"]) != 2 || !h["node-a"][0].Ready {
+		t.Fatalf("expected only the last transition from before the window to be kept but got %v", h["node-a"])
+	}
+}
+
+func TestEvaluateNodes(t *testing.T) {
+	cfg := testCheckConfig()
+	nodes := []v1.Node{
+		testNode("node-c", v1.ConditionTrue, time.Hour*24, "KubeletReady"),
+		testNode("node-b", v1.ConditionUnknown, time.Minute*12, "NodeStatusUnknown"),
+		testNode("node-a", v1.ConditionTrue, time.Minute, "KubeletReady"),
+		testNode("node-d", v1.ConditionFalse, time.Minute*2, "KubeletNotReady"),
+	}
+	h := nodeHistory{
+		"node-a": {
+			{Time: testNow.Add(-time.Minute * 40), Reason: "KubeletNotReady", Message: "PLEG is not healthy"},
+			{Time: testNow.Add(-time.Minute * 35), Ready: true},
+			{Time: testNow.Add(-time.Minute * 30), Reason: "KubeletNotReady", Message: "PLEG is not healthy"},
+			{Time: testNow.Add(-time.Minute * 25), Ready: true},
+			{Time: testNow.Add(-time.Minute * 20), Reason: "KubeletNotReady", Message: "PLEG is not healthy"},
+			{Time: testNow.Add(-time.Minute * 15), Ready: true},
+			{Time: testNow.Add(-time.Minute * 10), Reason: "KubeletNotReady", Message: "container runtime is down"},
+			{Time: testNow.Add(-time.Minute), Ready: true},
+		},
+	}
+
+	problems := evaluateNodes(nodes, h, testNow, cfg)
+	if len(problems) != 2 {
+		t.Fatalf("expected two problems but got %v", problems)
+	}
+	expected := "node node-a flapped to NotReady 4 times in the last 1h0m0s, last at 2023-06-01T11:50:00Z: KubeletNotReady: container runtime is down"
+	if problems[0] != expected {
+		t.Fatalf("expected %q but got %q", expected, problems[0])
+	}
+	if !strings.HasPrefix(problems[1], "node node-b has been NotReady for 12m0s: NodeStatusUnknown") {
+		t.Fatalf("expected node-b to be NotReady for too long but got %q", problems[1])
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	h, err := loadHistory(ctx, client, "kuberhealthy", defaultStateConfigMap)
+	if err != nil || len(h) != 0 {
+		t.Fatalf("expected an empty history before it was saved but got %v, %v", h, err)
+	}
+
+	h.record(testNode("node-a", v1.ConditionFalse, time.Minute, "KubeletNotReady"))
+	for i := 0; i < 2; i++ {
+		err = saveHistory(ctx, client, "kuberhealthy", defaultStateConfigMap, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := loadHistory(ctx, client, "kuberhealthy", defaultStateConfigMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded["node-a"]) != 1 || loaded["node-a"][0].Reason != "KubeletNotReady" || !loaded["node-a"][0].Time.Equal(testNow.Add(-time.Minute)) {
+		t.Fatalf("expected the history to survive a round trip but got %v", loaded)
+	}
+}
@@ -0,0 +1,174 @@
+// Package main implements a node readiness flap check for Kuberhealthy.  It observes the Ready condition of nodes
+// and fails when a node flaps from Ready to NotReady too many times within a window, or stays NotReady for too long.
+// The transitions of every node are kept in a ConfigMap between runs, and nodes are watched for a while during every
+// run to catch flaps that happen in between.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultMaxFlaps is the highest number of flaps within the window that passes when MAX_FLAPS is not set
+	defaultMaxFlaps = 3
+
+	// defaultFlapWindow is the window that flaps are counted in when FLAP_WINDOW is not set
+	defaultFlapWindow = time.Hour
+
+	// defaultMaxNotReady is how long a node may stay NotReady when MAX_NOT_READY_DURATION is not set
+	defaultMaxNotReady = time.Minute * 5
+
+	// defaultWatchDuration is how long nodes are watched during a run when WATCH_DURATION is not set
+	defaultWatchDuration = time.Second * 30
+
+	// defaultStateConfigMap is the name of the ConfigMap that keeps the transitions when STATE_CONFIGMAP is not set
+	defaultStateConfigMap = "node-flap-check-state"
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile   = os.Getenv("KUBECONFIG")
+	nodeSelector     = os.Getenv("NODE_SELECTOR")
+	maxFlapsEnv      = os.Getenv("MAX_FLAPS")
+	flapWindowEnv    = os.Getenv("FLAP_WINDOW")
+	maxNotReadyEnv   = os.Getenv("MAX_NOT_READY_DURATION")
+	watchDurationEnv = os.Getenv("WATCH_DURATION")
+	stateConfigMap   = os.Getenv("STATE_CONFIGMAP")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	nodeSelector   string
+	maxFlaps       int
+	flapWindow     time.Duration
+	maxNotReady    time.Duration
+	watchDuration  time.Duration
+	stateNamespace string
+	stateConfigMap string
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	history, err := loadHistory(ctx, client, cfg.stateNamespace, cfg.stateConfigMap)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("failed to load the node transitions from configmap %s/%s: %w", cfg.stateNamespace, cfg.stateConfigMap, err))
+	}
+
+	nodes, err := observeNodes(ctx, client, cfg, history)
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	now := time.Now()
+	history.prune(nodes, now, cfg.flapWindow)
+	err = saveHistory(ctx, client, cfg.stateNamespace, cfg.stateConfigMap, history)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("failed to save the node transitions to configmap %s/%s: %w", cfg.stateNamespace, cfg.stateConfigMap, err))
+	}
+
+	problems := evaluateNodes(nodes, history, now, cfg)
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		nodeSelector:   nodeSelector,
+		maxFlaps:       defaultMaxFlaps,
+		flapWindow:     defaultFlapWindow,
+		maxNotReady:    defaultMaxNotReady,
+		watchDuration:  defaultWatchDuration,
+		stateNamespace: os.Getenv("KH_POD_NAMESPACE"),
+		stateConfigMap: defaultStateConfigMap,
+	}
+	if len(cfg.stateNamespace) == 0 {
+		return cfg, fmt.Errorf("the namespace of the checker pod must be known to keep the node transitions")
+	}
+	if len(stateConfigMap) > 0 {
+		cfg.stateConfigMap = stateConfigMap
+	}
+
+	var err error
+	if len(maxFlapsEnv) > 0 {
+		cfg.maxFlaps, err = strconv.Atoi(maxFlapsEnv)
+		if err != nil || cfg.maxFlaps < 0 {
+			return cfg, fmt.Errorf("MAX_FLAPS must be zero or a positive number, but was %q", maxFlapsEnv)
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"FLAP_WINDOW", flapWindowEnv, &cfg.flapWindow},
+		{"MAX_NOT_READY_DURATION", maxNotReadyEnv, &cfg.maxNotReady},
+		{"WATCH_DURATION", watchDurationEnv, &cfg.watchDuration},
+	}
+	for _, d := range durations {
+		if len(d.value) == 0 {
+			continue
+		}
+		*d.into, err = time.ParseDuration(d.value)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing %s: %w", d.name, err)
+		}
+	}
+	if cfg.flapWindow <= 0 {
+		return cfg, fmt.Errorf("FLAP_WINDOW must be longer than zero")
+	}
+
+	return cfg, nil
+}
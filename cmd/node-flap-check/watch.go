@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// observeNodes lists the nodes that match the node selector and records their Ready condition, and then watches
+// them for the watch duration to record the transitions that happen in the meantime.  The nodes as last observed
+// are returned.
+func observeNodes(ctx context.Context, client kubernetes.Interface, cfg checkConfig, h nodeHistory) ([]v1.Node, error) {
+	list, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: cfg.nodeSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodes := make(map[string]v1.Node)
+	for _, node := range list.Items {
+		h.record(node)
+		nodes[node.Name] = node
+	}
+
+	if cfg.watchDuration > 0 {
+		watchNodes(ctx, client, cfg, list.ResourceVersion, h, nodes)
+	}
+
+	var observed []v1.Node
+	for _, node := range nodes {
+		observed = append(observed, node)
+	}
+	return observed, nil
+}
+
+// watchNodes records the changes of nodes from the supplied resource version on until the watch duration is over.
+// Failing to watch is logged and not reported, as the transitions are also recorded on every run.
+func watchNodes(ctx context.Context, client kubernetes.Interface, cfg checkConfig, resourceVersion string, h nodeHistory, nodes map[string]v1.Node) {
+	watchCtx, cancel := context.WithTimeout(ctx, cfg.watchDuration)
+	defer cancel()
+
+	log.Infoln("Watching nodes for", cfg.watchDuration)
+	watcher, err := client.CoreV1().Nodes().Watch(watchCtx, metav1.ListOptions{
+		LabelSelector:   cfg.nodeSelector,
+		ResourceVersion: resourceVersion,
+	})
+	if err != nil {
+		log.Errorln("Failed to watch nodes:", err)
+		return
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-watchCtx.Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				log.Infoln("The watch of the nodes was closed")
+				return
+			}
+			node, isNode := event.Object.(*v1.Node)
+			if !isNode {
+				continue
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				h.record(*node)
+				nodes[node.Name] = *node
+			case watch.Deleted:
+				delete(nodes, node.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestObserveNodes(t *testing.T) {
+	nodeA := testNode("node-a", v1.ConditionTrue, time.Hour, "KubeletReady")
+	nodeB := testNode("node-b", v1.ConditionTrue, time.Hour, "KubeletReady")
+	client := fake.NewSimpleClientset(&nodeA, &nodeB)
+
+	// node-a flaps while it is watched and node-b is deleted
+	watcher := watch.NewFake()
+	client.PrependWatchReactor("nodes", k8stesting.DefaultWatchReactor(watcher, nil))
+	go func() {
+		watcher.Modify(&v1.Node{ObjectMeta: nodeA.ObjectMeta, Status: testNode("node-a", v1.ConditionFalse, time.Minute*2, "KubeletNotReady").Status})
+		watcher.Modify(&v1.Node{ObjectMeta: nodeA.ObjectMeta, Status: testNode("node-a", v1.ConditionTrue, time.Minute, "KubeletReady").Status})
+		watcher.Delete(&nodeB)
+		watcher.Stop()
+	}()
+
+	cfg := testCheckConfig()
+	cfg.watchDuration = time.Second * 10
+	h := make(nodeHistory)
+	nodes, err := observeNodes(context.Background(), client, cfg, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Name != "node-a" {
+		t.Fatalf("expected only node-a to be observed but got %v", nodes)
+	}
+	if len(h["node-a"]) != 3 {
+		t.Fatalf("expected the transitions during the watch to be recorded but got %v", h["node-a"])
+	}
+	if flaps := h.flaps("node-a", testNow, time.Hour); flaps != 1 {
+		t.Fatalf("expected one flap but got %d", flaps)
+	}
+}
@@ -0,0 +1,129 @@
+// Package main implements a node image pull check for Kuberhealthy.  It runs a pod of a configurable image on
+// selected nodes of every node pool, so that the image is pulled by the kubelet of each node, and fails when a pull
+// errors or takes longer than its threshold.  This catches regressions of registry credentials, registry mirrors and
+// proxies that only affect some nodes.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultImage is the image that is pulled when CHECK_IMAGE is not set
+	defaultImage = "registry.k8s.io/pause:3.9"
+
+	// defaultNodesPerPool is the number of nodes of each pool that pull the image when NODES_PER_POOL is not set
+	defaultNodesPerPool = 1
+
+	// defaultMaxPullDuration is the longest pull that passes when MAX_PULL_DURATION is not set
+	defaultMaxPullDuration = time.Minute * 2
+
+	// cleanUpTimeout is how long deleting the pods of the check may take, even after the deadline of the check
+	cleanUpTimeout = time.Second * 30
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile     = os.Getenv("KUBECONFIG")
+	checkNamespace     = os.Getenv("CHECK_NAMESPACE")
+	checkImage         = os.Getenv("CHECK_IMAGE")
+	pullSecret         = os.Getenv("PULL_SECRET")
+	nodeSelector       = os.Getenv("NODE_SELECTOR")
+	nodePoolLabel      = os.Getenv("NODE_POOL_LABEL")
+	nodesPerPoolEnv    = os.Getenv("NODES_PER_POOL")
+	maxPullDurationEnv = os.Getenv("MAX_PULL_DURATION")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	namespace       string
+	image           string
+	pullSecret      string
+	nodeSelector    string
+	nodePoolLabel   string
+	nodesPerPool    int
+	maxPullDuration time.Duration
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "pull pods",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: cleanUpTimeout,
+		Run: func(ctx context.Context) []string {
+			targets, err := listTargets(ctx, client, cfg)
+			if err != nil {
+				return []string{err.Error()}
+			}
+			if len(targets) == 0 {
+				return []string{fmt.Sprintf("no ready nodes match the node selector %q", cfg.nodeSelector)}
+			}
+			return pullOnTargets(ctx, client, cfg, targets)
+		},
+		CleanUp: func(ctx context.Context) error {
+			return deletePullPods(ctx, client, cfg)
+		},
+	})
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:       os.Getenv("KH_POD_NAMESPACE"),
+		image:           defaultImage,
+		pullSecret:      pullSecret,
+		nodeSelector:    nodeSelector,
+		nodePoolLabel:   strings.TrimSpace(nodePoolLabel),
+		nodesPerPool:    defaultNodesPerPool,
+		maxPullDuration: defaultMaxPullDuration,
+	}
+
+	if len(checkNamespace) > 0 {
+		cfg.namespace = checkNamespace
+	}
+	if len(cfg.namespace) == 0 {
+		return cfg, fmt.Errorf("CHECK_NAMESPACE must be set when the namespace of the checker pod is not known")
+	}
+	if len(checkImage) > 0 {
+		cfg.image = checkImage
+	}
+
+	var err error
+	if len(nodesPerPoolEnv) > 0 {
+		cfg.nodesPerPool, err = strconv.Atoi(nodesPerPoolEnv)
+		if err != nil || cfg.nodesPerPool < 0 {
+			return cfg, fmt.Errorf("NODES_PER_POOL must be zero or a positive number, but was %q", nodesPerPoolEnv)
+		}
+	}
+	if len(maxPullDurationEnv) > 0 {
+		cfg.maxPullDuration, err = time.ParseDuration(maxPullDurationEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing MAX_PULL_DURATION: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// target is a node that pulls the image
+type target struct {
+	nodeName string
+	pool     string
+}
+
+// String describes the node and pool of a target for problems and logs
+func (t target) String() string {
+	if len(t.pool) == 0 {
+		return "node " + t.nodeName
+	}
+	return "node " + t.nodeName + " in pool " + t.pool
+}
+
+// listTargets lists the nodes that match the node selector and selects the ones that pull the image
+func listTargets(ctx context.Context, client kubernetes.Interface, cfg checkConfig) ([]target, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: cfg.nodeSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	targets := selectTargets(nodes.Items, cfg.nodePoolLabel, cfg.nodesPerPool)
+	log.Infoln("Selected", len(targets), "of", len(nodes.Items), "nodes to pull the image")
+	return targets, nil
+}
+
+// selectTargets selects the ready and schedulable nodes that pull the image.  Without a pool label, every node is
+// selected.  With a pool label, the supplied number of nodes is selected from every value of the label, or all of
+// them when the number is zero.  Nodes are selected in the order of their names, so that runs are comparable.
+func selectTargets(nodes []v1.Node, poolLabel string, perPool int) []target {
+	sorted := append([]v1.Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var targets []target
+	selected := make(map[string]int)
+	for _, node := range sorted {
+		if !nodeReady(node) {
+			log.Infoln("Skipping node", node.Name, "as it is not ready or not schedulable")
+			continue
+		}
+		t := target{nodeName: node.Name}
+		if len(poolLabel) > 0 {
+			t.pool = node.Labels[poolLabel]
+			if perPool > 0 && selected[t.pool] >= perPool {
+				continue
+			}
+			selected[t.pool]++
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// nodeReady indicates if a node is ready and schedulable
+func nodeReady(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// poolNode creates a node in the supplied pool
+func poolNode(name string, pool string, ready bool, unschedulable bool) v1.Node {
+	status := v1.ConditionTrue
+	if !ready {
+		status = v1.ConditionFalse
+	}
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}},
+		Spec:       v1.NodeSpec{Unschedulable: unschedulable},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}}},
+	}
+}
+
+func TestSelectTargets(t *testing.T) {
+	nodes := []v1.Node{
+		poolNode("node-d", "gpu", true, false),
+		poolNode("node-a", "default", false, false),
+		poolNode("node-b", "default", true, false),
+		poolNode("node-c", "default", true, false),
+		poolNode("node-e", "gpu", true, true),
+		poolNode("node-f", "spot", true, false),
+	}
+
+	// nodes that are not ready or not schedulable are skipped
+	targets := selectTargets(nodes, "", 1)
+	if len(targets) != 4 {
+		t.Fatalf("expected every ready node to be selected without a pool label but got %v", targets)
+	}
+
+	// the first ready node of every pool is selected
+	targets = selectTargets(nodes, "pool", 1)
+	expected := []target{{"node-b", "default"}, {"node-d", "gpu"}, {"node-f", "spot"}}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, targets)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("expected target %d to be %v but got %v", i, expected[i], targets[i])
+		}
+	}
+
+	targets = selectTargets(nodes, "pool", 0)
+	if len(targets) != 4 {
+		t.Fatalf("expected every ready node of every pool to be selected but got %v", targets)
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	if s := (target{nodeName: "node-a"}).String(); s != "node node-a" {
+		t.Fatalf("unexpected description: %s", s)
+	}
+	if s := (target{nodeName: "node-a", pool: "gpu"}).String(); s != "node node-a in pool gpu" {
+		t.Fatalf("unexpected description: %s", s)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// pullPodLabel is the label that marks the pods created by the check
+const pullPodLabel = "kuberhealthy-node-image-pull-check"
+
+// pullPodPrefix is the prefix of the names of the pods created by the check
+const pullPodPrefix = "node-image-pull-"
+
+// pullFailureReasons are the reasons of waiting containers whose image could not be pulled
+var pullFailureReasons = map[string]bool{
+	"ErrImagePull":        true,
+	"ImagePullBackOff":    true,
+	"InvalidImageName":    true,
+	"ErrImageNeverPull":   true,
+	"RegistryUnavailable": true,
+}
+
+// pollInterval is how often the status of the pods is polled
+var pollInterval = time.Second
+
+// pullPodName returns the name of the pod that pulls the image on a node
+func pullPodName(nodeName string) string {
+	name := pullPodPrefix + nodeName
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return strings.TrimRight(name, ".-")
+}
+
+// buildPullPod returns the pod that pulls the image on a node.  The pod is bound to the node directly, bypassing the
+// scheduler, and tolerates every taint so that it also runs on tainted node pools.
+func buildPullPod(cfg checkConfig, nodeName string) *v1.Pod {
+	automount := false
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pullPodName(nodeName),
+			Namespace: cfg.namespace,
+			Labels:    map[string]string{pullPodLabel: "true", "source": "kuberhealthy"},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{
+				Name:            "pull",
+				Image:           cfg.image,
+				ImagePullPolicy: v1.PullAlways,
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("1m"),
+						v1.ResourceMemory: resource.MustParse("8Mi"),
+					},
+				},
+			}},
+			RestartPolicy:                 v1.RestartPolicyNever,
+			AutomountServiceAccountToken:  &automount,
+			TerminationGracePeriodSeconds: new(int64),
+			Tolerations:                   []v1.Toleration{{Operator: v1.TolerationOpExists}},
+		},
+	}
+	if len(cfg.pullSecret) > 0 {
+		pod.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: cfg.pullSecret}}
+	}
+	return pod
+}
+
+// pullState indicates if the image of a pull pod was pulled, and returns the reason when pulling it failed
+func pullState(pod *v1.Pod) (bool, string) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil || status.State.Terminated != nil {
+			return true, ""
+		}
+		waiting := status.State.Waiting
+		if waiting != nil && pullFailureReasons[waiting.Reason] {
+			return false, waiting.Reason + ": " + waiting.Message
+		}
+	}
+
+	// the kubelet may reject the pod before it pulls anything, such as when the node is out of pods
+	if pod.Status.Phase == v1.PodFailed {
+		return false, "pod failed: " + pod.Status.Reason + ": " + pod.Status.Message
+	}
+	return false, ""
+}
+
+// pullOnTarget runs the pull pod of a target and returns how long it took until the image was pulled
+func pullOnTarget(ctx context.Context, client kubernetes.Interface, cfg checkConfig, t target) (time.Duration, error) {
+	pods := client.CoreV1().Pods(cfg.namespace)
+	name := pullPodName(t.nodeName)
+	_, err := pods.Create(ctx, buildPullPod(cfg, t.nodeName), metav1.CreateOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create pod %s: %w", name, err)
+	}
+	start := time.Now()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return time.Since(start), fmt.Errorf("image was not pulled after %s", time.Since(start).Round(time.Second))
+		case <-ticker.C:
+		}
+
+		pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.Infoln("Failed to get pod", name+":", err)
+			continue
+		}
+		pulled, failure := pullState(pod)
+		if len(failure) > 0 {
+			return time.Since(start), errors.New(failure)
+		}
+		if pulled {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// pullOnTargets pulls the image on every target in parallel and returns a problem for every pull that failed or
+// took longer than the maximum duration
+func pullOnTargets(ctx context.Context, client kubernetes.Interface, cfg checkConfig, targets []target) []string {
+	problems := make([]string, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			duration, err := pullOnTarget(ctx, client, cfg, t)
+			if err != nil {
+				problems[i] = fmt.Sprintf("%s: pulling image %s failed: %s", t, cfg.image, err)
+				return
+			}
+			log.Infoln("Pulling image", cfg.image, "on", t, "took", duration)
+			if cfg.maxPullDuration > 0 && duration > cfg.maxPullDuration {
+				problems[i] = fmt.Sprintf("%s: pulling image %s took %s, which exceeds %s", t, cfg.image, duration.Round(time.Second), cfg.maxPullDuration)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	var reported []string
+	for _, p := range problems {
+		if len(p) > 0 {
+			reported = append(reported, p)
+		}
+	}
+	return reported
+}
+
+// deletePullPods deletes every pod created by the check
+func deletePullPods(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	pods := client.CoreV1().Pods(cfg.namespace)
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: pullPodLabel + "=true"})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	var errs []error
+	for _, pod := range list.Items {
+		err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: new(int64)})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete pod %s: %w", pod.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
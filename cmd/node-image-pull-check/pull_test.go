@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// pullConfig returns the settings of a check that pulls a private registry image on each node, allowing a minute
+// for each pull
+func pullConfig() checkConfig {
+	return checkConfig{
+		namespace:       "kuberhealthy",
+		image:           "registry.example.com/team/pause:3.9",
+		maxPullDuration: time.Minute,
+	}
+}
+
+func TestBuildPullPod(t *testing.T) {
+	cfg := pullConfig()
+	pod := buildPullPod(cfg, "node-a")
+	if pod.Spec.NodeName != "node-a" || pod.Spec.Containers[0].ImagePullPolicy != v1.PullAlways {
+		t.Fatalf("expected the pod to be bound to the node and always pull but got %+v", pod.Spec)
+	}
+	if len(pod.Spec.ImagePullSecrets) != 0 {
+		t.Fatalf("expected no pull secret by default but got %v", pod.Spec.ImagePullSecrets)
+	}
+
+	cfg.pullSecret = "registry-credentials"
+	pod = buildPullPod(cfg, "node-a")
+	if len(pod.Spec.ImagePullSecrets) != 1 || pod.Spec.ImagePullSecrets[0].Name != "registry-credentials" {
+		t.Fatalf("expected the pull secret to be used but got %v", pod.Spec.ImagePullSecrets)
+	}
+
+	if name := pullPodName(strings.Repeat("a", 300)); len(name) > 253 {
+		t.Fatalf("expected the pod name to be truncated but it has %d characters", len(name))
+	}
+}
+
+func TestPullState(t *testing.T) {
+	waiting := func(reason string) *v1.Pod {
+		return &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason, Message: "unauthorized"}},
+		}}}}
+	}
+
+	pulled, failure := pullState(waiting("ContainerCreating"))
+	if pulled || len(failure) > 0 {
+		t.Fatalf("expected a creating container to still be pulling but got %v %q", pulled, failure)
+	}
+	pulled, failure = pullState(waiting("ErrImagePull"))
+	if pulled || failure != "ErrImagePull: unauthorized" {
+		t.Fatalf("expected the pull error to be reported but got %v %q", pulled, failure)
+	}
+
+	running := &v1.Pod{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+		State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+	}}}}
+	if pulled, _ := pullState(running); !pulled {
+		t.Fatal("expected the image of a running container to be pulled")
+	}
+
+	rejected := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed, Reason: "OutOfpods", Message: "node is full"}}
+	if _, failure := pullState(rejected); failure != "pod failed: OutOfpods: node is full" {
+		t.Fatalf("expected the rejection to be reported but got %q", failure)
+	}
+}
+
+func TestPullOnTargets(t *testing.T) {
+	pollInterval = time.Millisecond
+	client := fake.NewSimpleClientset()
+
+	// the image pulls on node-a and fails to pull on node-b
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+		state := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+		if strings.HasSuffix(name, "node-b") {
+			state = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "401 Unauthorized"}}
+		}
+		return true, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kuberhealthy"},
+			Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{State: state}}},
+		}, nil
+	})
+
+	cfg := pullConfig()
+	targets := []target{{"node-a", "default"}, {"node-b", "gpu"}}
+	problems := pullOnTargets(context.Background(), client, cfg, targets)
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem but got %v", problems)
+	}
+	expected := "node node-b in pool gpu: pulling image registry.example.com/team/pause:3.9 failed: ImagePullBackOff: 401 Unauthorized"
+	if problems[0] != expected {
+		t.Fatalf("expected %q but got %q", expected, problems[0])
+	}
+
+	err := deletePullPods(context.Background(), client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods, err := client.CoreV1().Pods("kuberhealthy").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 0 {
+		t.Fatalf("expected the pods to be deleted but got %d", len(pods.Items))
+	}
+}
@@ -5,8 +5,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,8 +19,9 @@ import (
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 
-	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 
 	checkclient "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
@@ -26,6 +29,9 @@ import (
 
 const defaultMaxFailuresAllowed = 10
 const defaultCheckTimeout = 10 * time.Minute
+const defaultRestartWindow = time.Hour
+const defaultStateConfigMap = "pod-restarts-check-state"
+const defaultStateNamespace = "kuberhealthy"
 
 // KubeConfigFile is a variable containing file path of Kubernetes config files
 var KubeConfigFile = filepath.Join(os.Getenv("HOME"), ".kube", "config")
@@ -39,12 +45,31 @@ var CheckTimeout time.Duration
 // MaxFailuresAllowed is a variable for how many times the pod should retry before stopping.
 var MaxFailuresAllowed int32
 
+// RestartThresholds are the thresholds of restarts by namespace and owner kind, which default to MaxFailuresAllowed.
+var RestartThresholds Thresholds
+
+// ExcludeSelectors select the pods that are never reported.
+var ExcludeSelectors []labels.Selector
+
+// RestartWindow is the sliding window that restarts are counted in, or zero to count all restarts of a pod.
+var RestartWindow time.Duration
+
+// StateNamespace and StateConfigMap locate the ConfigMap that keeps the restart history between runs.
+var StateNamespace string
+var StateConfigMap string
+
 // Checker represents a long running pod restart checker.
 type Checker struct {
 	Namespace          string
 	MaxFailuresAllowed int32
-	BadPods            map[string]string
-	client             *kubernetes.Clientset
+	Thresholds         Thresholds
+	ExcludeSelectors   []labels.Selector
+	RestartWindow      time.Duration
+	StateNamespace     string
+	StateConfigMap     string
+	BadPods            map[string]string // the offending pods, grouped by their owner
+	client             kubernetes.Interface
+	now                func() time.Time
 }
 
 func init() {
@@ -75,11 +100,67 @@ func init() {
 		MaxFailuresAllowed = int32(conversion)
 		if err != nil {
 			log.Errorln("Error converting maxFailuresAllowed:", maxFailuresAllowed, "to int, err:", err)
-			return
+			MaxFailuresAllowed = defaultMaxFailuresAllowed
+		}
+	}
+
+	err = parseRestartSettings()
+	if err != nil {
+		log.Errorln(err)
+		err = reportKHFailure([]string{err.Error()})
+		if err != nil {
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 }
 
+// parseRestartSettings parses the thresholds, exclusions and window of the check from their environment variables
+func parseRestartSettings() error {
+	var err error
+	RestartThresholds = Thresholds{Default: MaxFailuresAllowed}
+	RestartThresholds.Namespaces, err = parseThresholds(os.Getenv("NAMESPACE_THRESHOLDS"))
+	if err != nil {
+		return fmt.Errorf("error parsing NAMESPACE_THRESHOLDS: %w", err)
+	}
+	RestartThresholds.OwnerKinds, err = parseThresholds(os.Getenv("OWNER_KIND_THRESHOLDS"))
+	if err != nil {
+		return fmt.Errorf("error parsing OWNER_KIND_THRESHOLDS: %w", err)
+	}
+
+	// selectors are separated by semicolons, as a single selector may hold commas
+	for _, value := range strings.Split(os.Getenv("EXCLUDE_SELECTORS"), ";") {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return fmt.Errorf("error parsing EXCLUDE_SELECTORS: %w", err)
+		}
+		ExcludeSelectors = append(ExcludeSelectors, selector)
+	}
+
+	RestartWindow = defaultRestartWindow
+	restartWindow := os.Getenv("RESTART_WINDOW")
+	if len(restartWindow) != 0 {
+		RestartWindow, err = time.ParseDuration(restartWindow)
+		if err != nil {
+			return fmt.Errorf("error parsing RESTART_WINDOW: %w", err)
+		}
+	}
+
+	StateConfigMap = os.Getenv("STATE_CONFIGMAP")
+	if len(StateConfigMap) == 0 {
+		StateConfigMap = defaultStateConfigMap
+	}
+	StateNamespace = os.Getenv("KH_POD_NAMESPACE")
+	if len(StateNamespace) == 0 {
+		StateNamespace = defaultStateNamespace
+	}
+	return nil
+}
+
 func main() {
 
 	// Create client
@@ -102,12 +183,18 @@ func main() {
 }
 
 // New creates a new pod restart checker for a specific namespace, ready to use.
-func New(client *kubernetes.Clientset) *Checker {
+func New(client kubernetes.Interface) *Checker {
 	return &Checker{
 		Namespace:          Namespace,
 		MaxFailuresAllowed: MaxFailuresAllowed,
+		Thresholds:         RestartThresholds,
+		ExcludeSelectors:   ExcludeSelectors,
+		RestartWindow:      RestartWindow,
+		StateNamespace:     StateNamespace,
+		StateConfigMap:     StateConfigMap,
 		BadPods:            make(map[string]string),
 		client:             client,
+		now:                time.Now,
 	}
 }
 
@@ -143,9 +230,7 @@ func (prc *Checker) Run() error {
 				log.Error(err)
 				errorMessages = append(errorMessages, err.Error())
 			}
-			for _, msg := range prc.BadPods {
-				errorMessages = append(errorMessages, msg)
-			}
+			errorMessages = append(errorMessages, prc.badPodMessages()...)
 			return reportKHFailure(errorMessages)
 
 		}
@@ -153,63 +238,102 @@ func (prc *Checker) Run() error {
 	}
 }
 
-// doChecks grabs all events in a given namespace, then checks for pods with event type "Warning" with reason "BackOff",
-// and an event count greater than the MaxFailuresAllowed. If any of these pods are found, an error message is appended
-// to Checker struct errorMessages.
+// doChecks lists the pods in the namespace of the checker and counts their container restarts, within the restart
+// window when one is set.  Pods whose restarts exceed the threshold of their namespace or owner kind are added to the
+// bad pods of the Checker, grouped by their owner.
 func (prc *Checker) doChecks(ctx context.Context) error {
 
-	log.Infoln("Checking for pod BackOff events for all pods in the namespace:", prc.Namespace)
+	log.Infoln("Checking for pod restarts for all pods in the namespace:", prc.Namespace)
 
-	podWarningEvents, err := prc.client.CoreV1().Events(prc.Namespace).List(ctx, metav1.ListOptions{FieldSelector: "type=Warning"})
+	pods, err := prc.client.CoreV1().Pods(prc.Namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
 
-	if len(podWarningEvents.Items) != 0 {
-		log.Infoln("Found `Warning` events in the namespace:", prc.Namespace)
-
-		for _, event := range podWarningEvents.Items {
+	now := prc.now()
+	var history restartHistory
+	if prc.RestartWindow > 0 {
+		history, err = loadHistory(ctx, prc.client, prc.StateNamespace, prc.StateConfigMap)
+		if err != nil {
+			return fmt.Errorf("failed to load the restart history from configmap %s/%s: %w", prc.StateNamespace, prc.StateConfigMap, err)
+		}
+	}
 
-			// Checks for pods with BackOff events greater than the MaxFailuresAllowed
-			if event.InvolvedObject.Kind == "Pod" && event.Reason == "BackOff" && event.Count > prc.MaxFailuresAllowed {
-				errorMessage := "Found: " + strconv.FormatInt(int64(event.Count), 10) + " `BackOff` events for pod: " + event.InvolvedObject.Name + " in namespace: " + event.Namespace
+	offenders := make(map[owner][]string)
+	live := make(map[types.UID]bool)
+	for _, pod := range pods.Items {
+		if prc.excluded(pod) {
+			continue
+		}
+		restarts := podRestarts(pod)
+		if prc.RestartWindow > 0 {
+			live[pod.UID] = true
+			total := restarts
+			restarts = history.restartsInWindow(pod, total, now, prc.RestartWindow)
+			history.record(pod.UID, total, now, prc.RestartWindow)
+		}
 
-				log.Infoln(errorMessage)
+		o := podOwner(pod)
+		if restarts > prc.Thresholds.For(pod.Namespace, o.kind) {
+			log.Infoln("Pod", pod.Namespace+"/"+pod.Name, "of", o, "restarted", restarts, "times")
+			offenders[o] = append(offenders[o], pod.Name+" ("+strconv.FormatInt(int64(restarts), 10)+" restarts)")
+		}
+	}
 
-				// We could be checking for pods in all namespaces so prefix the namespace
-				prc.BadPods[event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name] = errorMessage
-			}
+	for o, offendingPods := range offenders {
+		sort.Strings(offendingPods)
+		message := o.String() + ": pods " + strings.Join(offendingPods, ", ") + " exceed " + strconv.FormatInt(int64(prc.Thresholds.For(o.namespace, o.kind)), 10) + " restarts"
+		if prc.RestartWindow > 0 {
+			message += " in the last " + prc.RestartWindow.String()
 		}
+		prc.BadPods[o.String()] = message
 	}
 
-	for pod := range prc.BadPods {
-		err := prc.verifyBadPodRestartExists(ctx, pod)
+	if prc.RestartWindow > 0 {
+		history.prune(live)
+		err = saveHistory(ctx, prc.client, prc.StateNamespace, prc.StateConfigMap, history)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to save the restart history to configmap %s/%s: %w", prc.StateNamespace, prc.StateConfigMap, err)
 		}
 	}
-	return err
+	return nil
 }
 
-// verifyBadPodRestartExists removes the bad pod found from the events list if the pod no longer exists
-func (prc *Checker) verifyBadPodRestartExists(ctx context.Context, pod string) error {
+// excluded indicates if a pod matches one of the exclude selectors
+func (prc *Checker) excluded(pod v1.Pod) bool {
+	for _, selector := range prc.ExcludeSelectors {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Pod is in the form namespace/pod_name
-	parts := strings.Split(pod, "/")
-	namespace := parts[0]
-	podName := parts[1]
+// badPodMessages returns the messages of the bad pods, sorted by their owner
+func (prc *Checker) badPodMessages() []string {
+	var owners []string
+	for o := range prc.BadPods {
+		owners = append(owners, o)
+	}
+	sort.Strings(owners)
 
-	_, err := prc.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
-	if err != nil {
-		if k8sErrors.IsNotFound(err) || strings.Contains(err.Error(), "not found") {
-			log.Infoln("Bad Pod:", podName, "no longer exists. Removing from bad pods map")
-			delete(prc.BadPods, podName)
-		} else {
-			log.Infoln("Error getting bad pod:", podName, err)
-			return err
-		}
+	var messages []string
+	for _, o := range owners {
+		messages = append(messages, prc.BadPods[o])
 	}
-	return nil
+	return messages
+}
+
+// podRestarts returns the sum of the restart counts of the init and regular containers of a pod
+func podRestarts(pod v1.Pod) int32 {
+	var restarts int32
+	for _, status := range pod.Status.InitContainerStatuses {
+		restarts += status.RestartCount
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
 }
 
 // reportKHSuccess reports success to Kuberhealthy servers and verifies the report successfully went through
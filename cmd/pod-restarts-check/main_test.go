@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"testing"
+	"time"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func pod(podName string, containerName string, restartCount int32) *v1.Pod {
@@ -35,3 +41,89 @@ func makeRestartObservationsMap(podName string, containerName string, restartCou
 
 	return restartObservationsMap
 }
+
+func TestDoChecks(t *testing.T) {
+	controller := true
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	web1 := pod("web-7d9f8-a", "web", 12)
+	web2 := pod("web-7d9f8-b", "web", 15)
+	for _, p := range []*v1.Pod{web1, web2} {
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f8", Controller: &controller}}
+		p.Labels = map[string]string{"pod-template-hash": "7d9f8"}
+	}
+	batch := pod("report-abcde", "report", 50)
+	batch.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: "report", Controller: &controller}}
+	flaky := pod("flaky", "flaky", 99)
+	flaky.Labels = map[string]string{"restarts": "expected"}
+	calm := pod("calm", "calm", 2)
+
+	client := fake.NewSimpleClientset(web1, web2, batch, flaky, calm)
+	exclude, err := labels.Parse("restarts=expected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prc := &Checker{
+		Namespace:        "test-namespace",
+		Thresholds:       Thresholds{Default: 10, OwnerKinds: map[string]int32{"Job": 100}},
+		ExcludeSelectors: []labels.Selector{exclude},
+		BadPods:          make(map[string]string),
+		client:           client,
+		now:              func() time.Time { return now },
+	}
+
+	err = prc.doChecks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := prc.badPodMessages()
+	expected := "Deployment test-namespace/web: pods web-7d9f8-a (12 restarts), web-7d9f8-b (15 restarts) exceed 10 restarts"
+	if len(messages) != 1 || messages[0] != expected {
+		t.Fatalf("expected the pods of the deployment to be reported together but got %v", messages)
+	}
+}
+
+func TestDoChecksWindow(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := pod("old", "old", 50)
+	old.UID = "uid-old"
+	old.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour * 24))
+
+	client := fake.NewSimpleClientset(old)
+	prc := &Checker{
+		Namespace:      "test-namespace",
+		Thresholds:     Thresholds{Default: 10},
+		RestartWindow:  time.Hour,
+		StateNamespace: "kuberhealthy",
+		StateConfigMap: defaultStateConfigMap,
+		BadPods:        make(map[string]string),
+		client:         client,
+		now:            func() time.Time { return now },
+	}
+
+	// the restarts of the pod happened before it was first observed
+	err := prc.doChecks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prc.BadPods) != 0 {
+		t.Fatalf("expected restarts from before the first observation to be ignored but got %v", prc.BadPods)
+	}
+
+	// the pod restarts 11 more times within the window
+	old.Status.ContainerStatuses[0].RestartCount = 61
+	_, err = client.CoreV1().Pods("test-namespace").UpdateStatus(context.Background(), old, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute * 30)
+	err = prc.doChecks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := prc.badPodMessages()
+	expected := "Pod test-namespace/old: pods old (11 restarts) exceed 10 restarts in the last 1h0m0s"
+	if len(messages) != 1 || messages[0] != expected {
+		t.Fatalf("expected the restarts within the window to be reported but got %v", messages)
+	}
+}
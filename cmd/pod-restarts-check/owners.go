@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// owner is the workload that a pod belongs to, which offending pods are grouped by
+type owner struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// String describes an owner for error messages, such as Deployment kube-system/coredns
+func (o owner) String() string {
+	return o.kind + " " + o.namespace + "/" + o.name
+}
+
+// podOwner returns the owner of a pod.  Pods of a ReplicaSet that was created by a Deployment are owned by the
+// Deployment, which is recognized by the pod-template-hash label that Deployments add to their ReplicaSets.  Pods
+// without a controller are their own owner.
+func podOwner(pod v1.Pod) owner {
+	ref := metav1.GetControllerOf(&pod)
+	if ref == nil {
+		return owner{kind: "Pod", namespace: pod.Namespace, name: pod.Name}
+	}
+
+	o := owner{kind: ref.Kind, namespace: pod.Namespace, name: ref.Name}
+	hash := pod.Labels["pod-template-hash"]
+	if ref.Kind == "ReplicaSet" && len(hash) > 0 && strings.HasSuffix(ref.Name, "-"+hash) {
+		o.kind = "Deployment"
+		o.name = strings.TrimSuffix(ref.Name, "-"+hash)
+	}
+	return o
+}
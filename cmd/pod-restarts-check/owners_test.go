@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodOwner(t *testing.T) {
+	controller := true
+
+	p := pod("coredns-5d78c9869d-abcde", "coredns", 0)
+	if o := podOwner(*p); o.String() != "Pod test-namespace/coredns-5d78c9869d-abcde" {
+		t.Fatalf("expected a pod without a controller to own itself but got %s", o)
+	}
+
+	// pods of a ReplicaSet of a Deployment are grouped by the Deployment
+	p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "coredns-5d78c9869d", Controller: &controller}}
+	p.Labels = map[string]string{"pod-template-hash": "5d78c9869d"}
+	if o := podOwner(*p); o.String() != "Deployment test-namespace/coredns" {
+		t.Fatalf("expected the pod to be owned by the deployment but got %s", o)
+	}
+
+	// ReplicaSets without a template hash are not created by Deployments
+	p.Labels = nil
+	if o := podOwner(*p); o.String() != "ReplicaSet test-namespace/coredns-5d78c9869d" {
+		t.Fatalf("expected the pod to be owned by the replicaset but got %s", o)
+	}
+
+	p = pod("fluentd-x7k2p", "fluentd", 0)
+	p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "fluentd", Controller: &controller}}
+	if o := podOwner(*p); o.kind != "DaemonSet" || o.name != "fluentd" {
+		t.Fatalf("expected the pod to be owned by the daemonset but got %s", o)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Thresholds holds the highest number of restarts that pods may have before they are reported, by the kind of
+// their owner and by their namespace
+type Thresholds struct {
+	Default    int32
+	Namespaces map[string]int32
+	OwnerKinds map[string]int32
+}
+
+// For returns the threshold of a pod in the supplied namespace with an owner of the supplied kind.  Thresholds of
+// owner kinds take precedence over the ones of namespaces, which take precedence over the default.
+func (t Thresholds) For(namespace string, ownerKind string) int32 {
+	if threshold, ok := t.OwnerKinds[ownerKind]; ok {
+		return threshold
+	}
+	if threshold, ok := t.Namespaces[namespace]; ok {
+		return threshold
+	}
+	return t.Default
+}
+
+// parseThresholds parses a comma separated list of thresholds by name, such as kube-system=3,batch=50
+func parseThresholds(value string) (map[string]int32, error) {
+	thresholds := make(map[string]int32)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		name, count, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || len(name) == 0 {
+			return nil, fmt.Errorf("threshold %q is not in the form name=count", entry)
+		}
+		threshold, err := strconv.ParseInt(strings.TrimSpace(count), 10, 32)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("threshold %q must have a count of zero or more", entry)
+		}
+		thresholds[name] = int32(threshold)
+	}
+	return thresholds, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := parseThresholds(" kube-system=3, DaemonSet = 5,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thresholds) != 2 || thresholds["kube-system"] != 3 || thresholds["DaemonSet"] != 5 {
+		t.Fatalf("unexpected thresholds: %v", thresholds)
+	}
+
+	for _, invalid := range []string{"kube-system", "=3", "kube-system=many", "kube-system=-1"} {
+		_, err := parseThresholds(invalid)
+		if err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestThresholdsFor(t *testing.T) {
+	thresholds := Thresholds{
+		Default:    10,
+		Namespaces: map[string]int32{"kube-system": 3},
+		OwnerKinds: map[string]int32{"Job": 100},
+	}
+	tests := []struct {
+		namespace string
+		ownerKind string
+		expected  int32
+	}{
+		{"default", "Deployment", 10},
+		{"kube-system", "Deployment", 3},
+		{"kube-system", "Job", 100},
+		{"default", "Job", 100},
+	}
+	for _, test := range tests {
+		if threshold := thresholds.For(test.namespace, test.ownerKind); threshold != test.expected {
+			t.Errorf("expected a threshold of %d for a %s in %s but got %d", test.expected, test.ownerKind, test.namespace, threshold)
+		}
+	}
+}
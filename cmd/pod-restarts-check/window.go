@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// historyKey is the key of the ConfigMap data that holds the restart history
+const historyKey = "history"
+
+// observation is the restart count of a pod at the time of a run of the check
+type observation struct {
+	Time     time.Time `json:"time"`
+	Restarts int32     `json:"restarts"`
+}
+
+// restartHistory holds the observations of the restart counts of pods by their UID.  It is kept in a ConfigMap
+// between runs, so that restarts can be counted within a sliding window.
+type restartHistory map[types.UID][]observation
+
+// loadHistory reads the restart history from its ConfigMap, or returns an empty history if it does not exist yet
+func loadHistory(ctx context.Context, client kubernetes.Interface, namespace string, name string) (restartHistory, error) {
+	history := make(restartHistory)
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cm.Data[historyKey]) == 0 {
+		return history, nil
+	}
+	err = json.Unmarshal([]byte(cm.Data[historyKey]), &history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// saveHistory writes the restart history to its ConfigMap, creating it if it does not exist yet
+func saveHistory(ctx context.Context, client kubernetes.Interface, namespace string, name string, history restartHistory) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	configMaps := client.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{historyKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[historyKey] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// restartsInWindow returns how many times a pod restarted within the window before now.  Pods that were created
+// within the window count all of their restarts.  Other pods count the restarts since their last observation at the
+// start of the window, or since their first observation if they were not observed that long ago.  Pods that were
+// never observed before count no restarts, as it is unknown when their restarts happened.
+func (h restartHistory) restartsInWindow(pod v1.Pod, restarts int32, now time.Time, window time.Duration) int32 {
+	windowStart := now.Add(-window)
+	if pod.CreationTimestamp.Time.After(windowStart) {
+		return restarts
+	}
+
+	observations := h[pod.UID]
+	if len(observations) == 0 {
+		return 0
+	}
+	baseline := observations[0]
+	for _, o := range observations {
+		if o.Time.After(windowStart) {
+			break
+		}
+		baseline = o
+	}
+	if restarts < baseline.Restarts {
+		return 0
+	}
+	return restarts - baseline.Restarts
+}
+
+// record adds an observation of a pod and drops the observations that are no longer needed, which are all but the
+// last one from before the start of the window
+func (h restartHistory) record(uid types.UID, restarts int32, now time.Time, window time.Duration) {
+	observations := append(h[uid], observation{Time: now, Restarts: restarts})
+	windowStart := now.Add(-window)
+	first := 0
+	for i, o := range observations {
+		if o.Time.After(windowStart) {
+			break
+		}
+		first = i
+	}
+	h[uid] = observations[first:]
+}
+
+// prune drops the history of pods that no longer exist
+func (h restartHistory) prune(live map[types.UID]bool) {
+	for uid := range h {
+		if !live[uid] {
+			delete(h, uid)
+		}
+	}
+}
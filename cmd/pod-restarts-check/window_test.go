@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRestartsInWindow(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Hour
+
+	p := pod("web-1", "web", 0)
+	p.UID = "uid-web-1"
+	p.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour * 24))
+
+	history := make(restartHistory)
+
+	// a pod that was never observed counts no restarts, as it is unknown when they happened
+	if restarts := history.restartsInWindow(*p, 40, now, window); restarts != 0 {
+		t.Fatalf("expected no restarts for an unobserved pod but got %d", restarts)
+	}
+
+	history[p.UID] = []observation{
+		{Time: now.Add(-time.Minute * 90), Restarts: 30},
+		{Time: now.Add(-time.Minute * 65), Restarts: 32},
+		{Time: now.Add(-time.Minute * 30), Restarts: 35},
+	}
+	if restarts := history.restartsInWindow(*p, 40, now, window); restarts != 8 {
+		t.Fatalf("expected the restarts since the start of the window to count but got %d", restarts)
+	}
+
+	// pods created within the window count all of their restarts
+	p.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute * 10))
+	if restarts := history.restartsInWindow(*p, 40, now, window); restarts != 40 {
+		t.Fatalf("expected all restarts of a new pod to count but got %d", restarts)
+	}
+}
+
+func TestRecordAndPrune(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := restartHistory{
+		"uid-web-1": {
+			{Time: now.Add(-time.Minute * 90), Restarts: 30},
+			{Time: now.Add(-time.Minute * 65), Restarts: 32},
+			{Time: now.Add(-time.Minute * 30), Restarts: 35},
+		},
+		"uid-gone": {{Time: now.Add(-time.Minute * 30), Restarts: 1}},
+	}
+
+	// only the last observation from before the window is kept
+	history.record("uid-web-1", 40, now, time.Hour)
+	observations := history["uid-web-1"]
+	if len(observations) != 3 || observations[0].Restarts != 32 || observations[2].Restarts != 40 {
+		t.Fatalf("unexpected observations: %v", observations)
+	}
+
+	history.prune(map[types.UID]bool{"uid-web-1": true})
+	if _, ok := history["uid-gone"]; ok || len(history) != 1 {
+		t.Fatalf("expected the history of deleted pods to be dropped but got %v", history)
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	history, err := loadHistory(ctx, client, "kuberhealthy", defaultStateConfigMap)
+	if err != nil || len(history) != 0 {
+		t.Fatalf("expected an empty history before it was saved but got %v, %v", history, err)
+	}
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	history.record("uid-web-1", 3, now, time.Hour)
+	for i := 0; i < 2; i++ {
+		err = saveHistory(ctx, client, "kuberhealthy", defaultStateConfigMap, history)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := loadHistory(ctx, client, "kuberhealthy", defaultStateConfigMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded["uid-web-1"]) != 1 || loaded["uid-web-1"][0].Restarts != 3 || !loaded["uid-web-1"][0].Time.Equal(now) {
+		t.Fatalf("expected the history to survive a round trip but got %v", loaded)
+	}
+}
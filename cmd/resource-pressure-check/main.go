@@ -0,0 +1,199 @@
+// Package main implements a resource pressure check for Kuberhealthy.  It compares the resources requested on nodes
+// with their allocatable resources, and the usage of namespace ResourceQuotas with their hard limits, and fails when
+// they reach their thresholds or when a pod of a configurable size could no longer be scheduled or created.  This
+// catches a cluster that is running out of room before pods get stuck pending.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultPodCPU is the CPU request of the pod that must fit when POD_CPU is not set
+	defaultPodCPU = "100m"
+
+	// defaultPodMemory is the memory request of the pod that must fit when POD_MEMORY is not set
+	defaultPodMemory = "128Mi"
+
+	// defaultMinSchedulableNodes is the number of nodes that must fit the pod when MIN_SCHEDULABLE_NODES is not set
+	defaultMinSchedulableNodes = 1
+
+	// defaultNodeThreshold is the share of the allocatable resources of the nodes that may be requested when
+	// NODE_THRESHOLD is not set
+	defaultNodeThreshold = 0.9
+
+	// defaultQuotaThreshold is the share of the hard limits of quotas that may be used when QUOTA_THRESHOLD is not set
+	defaultQuotaThreshold = 0.9
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile         = os.Getenv("KUBECONFIG")
+	podCPUEnv              = os.Getenv("POD_CPU")
+	podMemoryEnv           = os.Getenv("POD_MEMORY")
+	nodeSelector           = os.Getenv("NODE_SELECTOR")
+	minSchedulableNodesEnv = os.Getenv("MIN_SCHEDULABLE_NODES")
+	nodeThresholdEnv       = os.Getenv("NODE_THRESHOLD")
+	quotaThresholdEnv      = os.Getenv("QUOTA_THRESHOLD")
+	namespacesEnv          = os.Getenv("NAMESPACES")
+	excludeNamespacesEnv   = os.Getenv("EXCLUDE_NAMESPACES")
+)
+
+// checkConfig holds the parsed settings of the check
+type checkConfig struct {
+	podSize             v1.ResourceList
+	nodeSelector        string
+	minSchedulableNodes int
+	nodeThreshold       float64
+	quotaThreshold      float64
+	namespaces          []string
+	excludeNamespaces   []string
+}
+
+func main() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	var problems []string
+	nodes, err := listNodeHeadroom(ctx, client, cfg.nodeSelector)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		problems = append(problems, evaluateNodes(nodes, cfg.podSize, cfg.minSchedulableNodes, cfg.nodeThreshold)...)
+	}
+
+	quotas, err := listQuotas(ctx, client, cfg.namespaces, cfg.excludeNamespaces)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		problems = append(problems, evaluateQuotas(quotas, cfg.podSize, cfg.quotaThreshold)...)
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		nodeSelector:        nodeSelector,
+		minSchedulableNodes: defaultMinSchedulableNodes,
+		nodeThreshold:       defaultNodeThreshold,
+		quotaThreshold:      defaultQuotaThreshold,
+		namespaces:          splitList(namespacesEnv),
+		excludeNamespaces:   splitList(excludeNamespacesEnv),
+	}
+	var err error
+
+	podCPU := defaultPodCPU
+	if len(podCPUEnv) > 0 {
+		podCPU = podCPUEnv
+	}
+	podMemory := defaultPodMemory
+	if len(podMemoryEnv) > 0 {
+		podMemory = podMemoryEnv
+	}
+	cpu, err := resource.ParseQuantity(podCPU)
+	if err != nil {
+		return cfg, fmt.Errorf("error parsing POD_CPU: %w", err)
+	}
+	memory, err := resource.ParseQuantity(podMemory)
+	if err != nil {
+		return cfg, fmt.Errorf("error parsing POD_MEMORY: %w", err)
+	}
+	cfg.podSize = v1.ResourceList{v1.ResourceCPU: cpu, v1.ResourceMemory: memory}
+
+	if len(minSchedulableNodesEnv) > 0 {
+		cfg.minSchedulableNodes, err = strconv.Atoi(minSchedulableNodesEnv)
+		if err != nil || cfg.minSchedulableNodes < 0 {
+			return cfg, fmt.Errorf("MIN_SCHEDULABLE_NODES must be zero or a positive number, but was %q", minSchedulableNodesEnv)
+		}
+	}
+
+	cfg.nodeThreshold, err = parseThreshold("NODE_THRESHOLD", nodeThresholdEnv, defaultNodeThreshold)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.quotaThreshold, err = parseThreshold("QUOTA_THRESHOLD", quotaThresholdEnv, defaultQuotaThreshold)
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// parseThreshold parses a share between 0 and 1 from an environment variable, where 0 disables the threshold
+func parseThreshold(name string, value string, defaultValue float64) (float64, error) {
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		return 0, fmt.Errorf("%s must be a number between 0 and 1, but was %q", name, value)
+	}
+	return threshold, nil
+}
+
+// splitList splits a comma separated list and drops its empty entries
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeResources are the resources of nodes that are evaluated, in the order that they are reported
+var nodeResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourcePods}
+
+// nodeHeadroom holds the allocatable resources of a node and the resources requested by the pods on it.  The pods
+// resource counts the pods on the node.
+type nodeHeadroom struct {
+	name        string
+	allocatable v1.ResourceList
+	requested   v1.ResourceList
+}
+
+// free returns the amount of a resource of the node that is not requested yet
+func (n nodeHeadroom) free(name v1.ResourceName) resource.Quantity {
+	allocatable := n.allocatable[name]
+	free := allocatable.DeepCopy()
+	free.Sub(n.requested[name])
+	return free
+}
+
+// fits indicates if a pod of the supplied size fits on the node, and returns the resources that it is short of
+// otherwise
+func (n nodeHeadroom) fits(podSize v1.ResourceList) (bool, []v1.ResourceName) {
+	var short []v1.ResourceName
+	for _, name := range nodeResources {
+		need, ok := podSize[name]
+		if name == v1.ResourcePods {
+			need, ok = *resource.NewQuantity(1, resource.DecimalSI), true
+		}
+		if !ok {
+			continue
+		}
+		free := n.free(name)
+		if free.Cmp(need) < 0 {
+			short = append(short, name)
+		}
+	}
+	return len(short) == 0, short
+}
+
+// listNodeHeadroom returns the headroom of the schedulable nodes that match the node selector, in the order of their
+// names
+func listNodeHeadroom(ctx context.Context, client kubernetes.Interface, nodeSelector string) ([]nodeHeadroom, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: nodeSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	headroom := make(map[string]*nodeHeadroom)
+	for _, node := range nodes.Items {
+		if !schedulable(node) {
+			log.Infoln("Skipping node", node.Name, "as it is not ready, cordoned or tainted")
+			continue
+		}
+		headroom[node.Name] = &nodeHeadroom{
+			name:        node.Name,
+			allocatable: node.Status.Allocatable,
+			requested:   v1.ResourceList{},
+		}
+	}
+
+	// pods that are done do not hold on to their requests
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		n, ok := headroom[pod.Spec.NodeName]
+		if !ok || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		addResources(n.requested, podRequests(pod))
+		addResources(n.requested, v1.ResourceList{v1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)})
+	}
+
+	var list []nodeHeadroom
+	for _, n := range headroom {
+		list = append(list, *n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, nil
+}
+
+// schedulable indicates if a node is ready and accepts pods that do not tolerate any taints
+func schedulable(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podRequests returns the resources requested by a pod the way the scheduler counts them: the larger of the sum of
+// the requests of its containers and the largest request of an init container, plus the overhead of the pod
+func podRequests(pod v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	addResources(requests, pod.Spec.Overhead)
+	return requests
+}
+
+// addResources adds the supplied resources to a resource list
+func addResources(list v1.ResourceList, add v1.ResourceList) {
+	for name, quantity := range add {
+		current := list[name]
+		current.Add(quantity)
+		list[name] = current
+	}
+}
+
+// evaluateNodes returns a problem when fewer nodes than the minimum fit a pod of the supplied size, and for every
+// resource of which the nodes have a larger share of their allocatable amount requested than the threshold.  A minimum
+// or threshold of zero disables its evaluation.
+func evaluateNodes(nodes []nodeHeadroom, podSize v1.ResourceList, minSchedulable int, threshold float64) []string {
+	var problems []string
+
+	if minSchedulable > 0 {
+		fitting := 0
+		shortOf := make(map[v1.ResourceName]int)
+		for _, n := range nodes {
+			fits, short := n.fits(podSize)
+			if fits {
+				fitting++
+			}
+			for _, name := range short {
+				shortOf[name]++
+			}
+			freeCPU := n.free(v1.ResourceCPU)
+			freeMemory := n.free(v1.ResourceMemory)
+			freePods := n.free(v1.ResourcePods)
+			log.Infoln("Node", n.name, "has", freeCPU.String(), "cpu,", freeMemory.String(), "memory and", freePods.String(), "pods left, and fits the pod:", fits)
+		}
+		if fitting < minSchedulable {
+			var reasons []string
+			for _, name := range nodeResources {
+				if shortOf[name] > 0 {
+					reasons = append(reasons, fmt.Sprintf("%d short of %s", shortOf[name], name))
+				}
+			}
+			problem := fmt.Sprintf("only %d of %d schedulable nodes fit a pod requesting %s, which is fewer than %d", fitting, len(nodes), describeSize(podSize), minSchedulable)
+			if len(reasons) > 0 {
+				problem += " (" + strings.Join(reasons, ", ") + ")"
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	if threshold > 0 {
+		for _, name := range nodeResources {
+			var allocatable, requested resource.Quantity
+			for _, n := range nodes {
+				allocatable.Add(n.allocatable[name])
+				requested.Add(n.requested[name])
+			}
+			if allocatable.IsZero() {
+				continue
+			}
+			share := float64(requested.MilliValue()) / float64(allocatable.MilliValue())
+			log.Infoln("Nodes have", fmt.Sprintf("%.1f%%", share*100), "of their allocatable", name, "requested")
+			if share >= threshold {
+				problems = append(problems, fmt.Sprintf("nodes have %.0f%% of their allocatable %s requested, which reaches the threshold of %.0f%%", share*100, name, threshold*100))
+			}
+		}
+	}
+
+	return problems
+}
+
+// describeSize describes the resources of a pod size, such as cpu 100m and memory 128Mi
+func describeSize(podSize v1.ResourceList) string {
+	var parts []string
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		if quantity, ok := podSize[name]; ok {
+			parts = append(parts, string(name)+" "+quantity.String())
+		}
+	}
+	return strings.Join(parts, " and ")
+}
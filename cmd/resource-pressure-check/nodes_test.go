@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testPodSize is the size of the pod that must fit in the tests
+var testPodSize = v1.ResourceList{
+	v1.ResourceCPU:    resource.MustParse("500m"),
+	v1.ResourceMemory: resource.MustParse("1Gi"),
+}
+
+// resources returns a resource list of the supplied cpu, memory and pods
+func resources(cpu string, memory string, pods int64) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(memory),
+		v1.ResourcePods:   *resource.NewQuantity(pods, resource.DecimalSI),
+	}
+}
+
+// testNode returns a ready node with the supplied allocatable resources
+func testNode(name string, allocatable v1.ResourceList) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{
+			Allocatable: allocatable,
+			Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	}
+}
+
+// testPod returns a pod on a node with a container of the supplied requests
+func testPod(name string, nodeName string, phase v1.PodPhase, cpu string, memory string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(memory),
+			}}}},
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	pod := testPod("web", "node-a", v1.PodRunning, "200m", "256Mi")
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU: resource.MustParse("100m"),
+	}}})
+	pod.Spec.InitContainers = []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1"),
+		v1.ResourceMemory: resource.MustParse("64Mi"),
+	}}}}
+	pod.Spec.Overhead = v1.ResourceList{v1.ResourceMemory: resource.MustParse("16Mi")}
+
+	// the init container requests more cpu than the containers together, but less memory
+	requests := podRequests(*pod)
+	cpu := requests[v1.ResourceCPU]
+	memory := requests[v1.ResourceMemory]
+	if cpu.String() != "1" || memory.String() != "272Mi" {
+		t.Fatalf("expected requests of cpu 1 and memory 272Mi but got cpu %s and memory %s", cpu.String(), memory.String())
+	}
+}
+
+func TestSchedulable(t *testing.T) {
+	tainted := testNode("node-b", nil)
+	tainted.Spec.Taints = []v1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: v1.TaintEffectNoSchedule}}
+	preferred := testNode("node-c", nil)
+	preferred.Spec.Taints = []v1.Taint{{Key: "spot", Effect: v1.TaintEffectPreferNoSchedule}}
+	cordoned := testNode("node-d", nil)
+	cordoned.Spec.Unschedulable = true
+	notReady := testNode("node-e", nil)
+	notReady.Status.Conditions[0].Status = v1.ConditionUnknown
+
+	tests := map[*v1.Node]bool{
+		testNode("node-a", nil): true,
+		tainted:                 false,
+		preferred:               true,
+		cordoned:                false,
+		notReady:                false,
+	}
+	for node, expected := range tests {
+		if schedulable(*node) != expected {
+			t.Errorf("%s: expected schedulable to be %v", node.Name, expected)
+		}
+	}
+}
+
+func TestListNodeHeadroom(t *testing.T) {
+	tainted := testNode("node-c", resources("4", "16Gi", 110))
+	tainted.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+	client := fake.NewSimpleClientset(
+		testNode("node-b", resources("4", "16Gi", 110)),
+		testNode("node-a", resources("2", "8Gi", 110)),
+		tainted,
+		testPod("web-1", "node-a", v1.PodRunning, "1500m", "2Gi"),
+		testPod("web-2", "node-a", v1.PodPending, "200m", "1Gi"),
+		testPod("job-1", "node-a", v1.PodSucceeded, "2", "8Gi"),
+		testPod("gpu-1", "node-c", v1.PodRunning, "1", "1Gi"),
+		testPod("unscheduled", "", v1.PodPending, "4", "16Gi"),
+	)
+
+	nodes, err := listNodeHeadroom(context.Background(), client, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].name != "node-a" || nodes[1].name != "node-b" {
+		t.Fatalf("expected the untainted nodes in order but got %v", nodes)
+	}
+	cpu := nodes[0].free(v1.ResourceCPU)
+	memory := nodes[0].free(v1.ResourceMemory)
+	pods := nodes[0].free(v1.ResourcePods)
+	if cpu.String() != "300m" || memory.String() != "5Gi" || pods.Value() != 108 {
+		t.Fatalf("expected 300m cpu, 5Gi memory and 108 pods to be left on node-a but got %s, %s and %s", cpu.String(), memory.String(), pods.String())
+	}
+
+	fits, short := nodes[0].fits(testPodSize)
+	if fits || !reflect.DeepEqual(short, []v1.ResourceName{v1.ResourceCPU}) {
+		t.Fatalf("expected node-a to be short of cpu but got %v %v", fits, short)
+	}
+	if fits, _ := nodes[1].fits(testPodSize); !fits {
+		t.Fatal("expected the pod to fit on the empty node-b")
+	}
+}
+
+func TestEvaluateNodes(t *testing.T) {
+	nodes := []nodeHeadroom{
+		{name: "node-a", allocatable: resources("2", "8Gi", 110), requested: resources("1800m", "6Gi", 30)},
+		{name: "node-b", allocatable: resources("2", "8Gi", 110), requested: resources("1", "7800Mi", 30)},
+		{name: "node-c", allocatable: resources("2", "8Gi", 110), requested: resources("1900m", "7900Mi", 30)},
+	}
+
+	// no node fits the pod, and 78% of the cpu and 89% of the memory of the nodes is requested
+	problems := evaluateNodes(nodes, testPodSize, 1, 0.75)
+	expected := []string{
+		"only 0 of 3 schedulable nodes fit a pod requesting cpu 500m and memory 1Gi, which is fewer than 1 (2 short of cpu, 2 short of memory)",
+		"nodes have 78% of their allocatable cpu requested, which reaches the threshold of 75%",
+		"nodes have 89% of their allocatable memory requested, which reaches the threshold of 75%",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("expected problems %v but got %v", expected, problems)
+	}
+
+	// with room for the pod on node-b and the evaluations of the shares disabled, nothing is reported
+	nodes[1].requested = resources("1", "4Gi", 30)
+	if problems := evaluateNodes(nodes, testPodSize, 1, 0); len(problems) != 0 {
+		t.Fatalf("expected no problems but got %v", problems)
+	}
+	if problems := evaluateNodes(nodes, testPodSize, 2, 0); len(problems) != 1 {
+		t.Fatalf("expected a single fitting node to fall short of two but got %v", problems)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// quotaPodResources maps the quota resources that limit the creation of a pod to the resource of the pod that they
+// count.  Limits are assumed to equal the requests of the pod.
+var quotaPodResources = map[v1.ResourceName]v1.ResourceName{
+	v1.ResourceCPU:            v1.ResourceCPU,
+	v1.ResourceRequestsCPU:    v1.ResourceCPU,
+	v1.ResourceLimitsCPU:      v1.ResourceCPU,
+	v1.ResourceMemory:         v1.ResourceMemory,
+	v1.ResourceRequestsMemory: v1.ResourceMemory,
+	v1.ResourceLimitsMemory:   v1.ResourceMemory,
+	v1.ResourcePods:           v1.ResourcePods,
+	"count/pods":              v1.ResourcePods,
+}
+
+// listQuotas lists the ResourceQuotas of the checked namespaces in the order of their namespaces and names.  Without
+// namespaces, the quotas of every namespace that is not excluded are listed.
+func listQuotas(ctx context.Context, client kubernetes.Interface, namespaces []string, excludeNamespaces []string) ([]v1.ResourceQuota, error) {
+	quotas, err := client.CoreV1().ResourceQuotas("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+
+	var list []v1.ResourceQuota
+	for _, quota := range quotas.Items {
+		if len(namespaces) > 0 && !contains(namespaces, quota.Namespace) {
+			continue
+		}
+		if contains(excludeNamespaces, quota.Namespace) {
+			continue
+		}
+		list = append(list, quota)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return list[i].Name < list[j].Name
+	})
+	log.Infoln("Evaluating", len(list), "of", len(quotas.Items), "resource quotas")
+	return list, nil
+}
+
+// contains indicates if a list contains the supplied value
+func contains(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateQuotas returns a problem for every quota that leaves too little room to create a pod of the supplied size in
+// its namespace.  For the other quotas, a problem is returned for every resource of which a larger share of the hard
+// limit is used than the threshold.  A threshold of zero disables its evaluation.  Scoped quotas only apply to some
+// pods and hard limits of zero forbid pods on purpose, so neither is evaluated for the pod.
+func evaluateQuotas(quotas []v1.ResourceQuota, podSize v1.ResourceList, threshold float64) []string {
+	var problems []string
+	for _, quota := range quotas {
+		names := make([]string, 0, len(quota.Status.Hard))
+		for name := range quota.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+
+		var short []string
+		scoped := len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil
+		for _, name := range names {
+			podResource, ok := quotaPodResources[v1.ResourceName(name)]
+			if !ok || scoped {
+				continue
+			}
+			hard := quota.Status.Hard[v1.ResourceName(name)]
+			if hard.IsZero() {
+				continue
+			}
+			need, ok := podSize[podResource]
+			if podResource == v1.ResourcePods {
+				need, ok = *resource.NewQuantity(1, resource.DecimalSI), true
+			}
+			if !ok {
+				continue
+			}
+			left := quotaLeft(quota, v1.ResourceName(name))
+			if left.Cmp(need) < 0 {
+				short = append(short, left.String()+" of "+name)
+			}
+		}
+		if len(short) > 0 {
+			problems = append(problems, fmt.Sprintf("namespace %s can no longer create a pod requesting %s, as resource quota %s has only %s left", quota.Namespace, describeSize(podSize), quota.Name, strings.Join(short, ", ")))
+			continue
+		}
+
+		if threshold <= 0 {
+			continue
+		}
+		for _, name := range names {
+			hard := quota.Status.Hard[v1.ResourceName(name)]
+			used := quota.Status.Used[v1.ResourceName(name)]
+			if hard.IsZero() {
+				continue
+			}
+			share := float64(used.MilliValue()) / float64(hard.MilliValue())
+			if share >= threshold {
+				problems = append(problems, fmt.Sprintf("namespace %s uses %.0f%% of %s of resource quota %s (%s of %s), which reaches the threshold of %.0f%%", quota.Namespace, share*100, name, quota.Name, used.String(), hard.String(), threshold*100))
+			}
+		}
+	}
+	return problems
+}
+
+// quotaLeft returns the amount of a resource of a quota that is not used yet
+func quotaLeft(quota v1.ResourceQuota, name v1.ResourceName) resource.Quantity {
+	hard := quota.Status.Hard[name]
+	left := hard.DeepCopy()
+	left.Sub(quota.Status.Used[name])
+	return left
+}
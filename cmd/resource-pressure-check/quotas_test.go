@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testQuota returns a quota with the supplied hard limits and usage
+func testQuota(namespace string, name string, hard v1.ResourceList, used v1.ResourceList) *v1.ResourceQuota {
+	return &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     v1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func TestListQuotas(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testQuota("team-b", "compute", nil, nil),
+		testQuota("team-a", "objects", nil, nil),
+		testQuota("team-a", "compute", nil, nil),
+		testQuota("kube-system", "compute", nil, nil),
+	)
+
+	quotas, err := listQuotas(context.Background(), client, nil, []string{"kube-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, quota := range quotas {
+		names = append(names, quota.Namespace+"/"+quota.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"team-a/compute", "team-a/objects", "team-b/compute"}) {
+		t.Fatalf("expected the quotas of the namespaces that are not excluded in order but got %v", names)
+	}
+
+	quotas, err = listQuotas(context.Background(), client, []string{"team-b"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotas) != 1 || quotas[0].Namespace != "team-b" {
+		t.Fatalf("expected only the quota of team-b but got %v", quotas)
+	}
+}
+
+func TestEvaluateQuotas(t *testing.T) {
+	full := testQuota("team-a", "compute",
+		v1.ResourceList{v1.ResourceRequestsCPU: resource.MustParse("4"), v1.ResourceRequestsMemory: resource.MustParse("8Gi"), v1.ResourcePods: resource.MustParse("20")},
+		v1.ResourceList{v1.ResourceRequestsCPU: resource.MustParse("3800m"), v1.ResourceRequestsMemory: resource.MustParse("6Gi"), v1.ResourcePods: resource.MustParse("12")},
+	)
+	busy := testQuota("team-b", "compute",
+		v1.ResourceList{v1.ResourceLimitsMemory: resource.MustParse("16Gi"), v1.ResourceServices: resource.MustParse("10")},
+		v1.ResourceList{v1.ResourceLimitsMemory: resource.MustParse("12Gi"), v1.ResourceServices: resource.MustParse("10")},
+	)
+	blocked := testQuota("team-c", "no-pods",
+		v1.ResourceList{v1.ResourcePods: resource.MustParse("0")},
+		v1.ResourceList{v1.ResourcePods: resource.MustParse("0")},
+	)
+	scoped := testQuota("team-d", "critical",
+		v1.ResourceList{v1.ResourcePods: resource.MustParse("2")},
+		v1.ResourceList{v1.ResourcePods: resource.MustParse("2")},
+	)
+	scoped.Spec.ScopeSelector = &v1.ScopeSelector{MatchExpressions: []v1.ScopedResourceSelectorRequirement{{
+		ScopeName: v1.ResourceQuotaScopePriorityClass, Operator: v1.ScopeSelectorOpIn, Values: []string{"critical"},
+	}}}
+
+	problems := evaluateQuotas([]v1.ResourceQuota{*full, *busy, *blocked, *scoped}, testPodSize, 0.9)
+	expected := []string{
+		"namespace team-a can no longer create a pod requesting cpu 500m and memory 1Gi, as resource quota compute has only 200m of requests.cpu left",
+		"namespace team-b uses 100% of services of resource quota compute (10 of 10), which reaches the threshold of 90%",
+		"namespace team-d uses 100% of pods of resource quota critical (2 of 2), which reaches the threshold of 90%",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("expected problems %v but got %v", expected, problems)
+	}
+
+	// without a threshold, only the quota that can not fit the pod is reported
+	if problems := evaluateQuotas([]v1.ResourceQuota{*full, *busy, *blocked, *scoped}, testPodSize, 0); len(problems) != 1 {
+		t.Fatalf("expected a single problem but got %v", problems)
+	}
+}
@@ -0,0 +1,133 @@
+// Package main implements a storage provisioning check for Kuberhealthy.  It creates a PVC from a configurable
+// StorageClass, mounts it in a pod that writes and reads back a file, and then cleans everything up.  Provisioning
+// timeouts, attach and mount errors and IO errors are reported as failures.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/resourcecheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+const (
+	// defaultCheckNamespace is the namespace that the PVC and pod are created in when CHECK_NAMESPACE is not set
+	defaultCheckNamespace = "kuberhealthy"
+
+	// defaultStorageSize is the size of the PVC that is requested when STORAGE_SIZE is not set
+	defaultStorageSize = "1Gi"
+
+	// defaultCheckImage is the image of the pod that writes and reads the test file when CHECK_IMAGE is not set
+	defaultCheckImage = "busybox:1.36"
+
+	// defaultCleanupTimeout is the time allowed for deleting the PVC and pod after the check has run
+	defaultCleanupTimeout = time.Minute
+)
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile = os.Getenv("KUBECONFIG")
+
+	// StorageClass to provision the PVC from.  The default StorageClass of the cluster is used when empty.
+	storageClass = os.Getenv("STORAGE_CLASS")
+
+	// Size of the PVC to request
+	storageSizeEnv = os.Getenv("STORAGE_SIZE")
+
+	// Access mode of the PVC to request [default = ReadWriteOnce]
+	accessModeEnv = os.Getenv("ACCESS_MODE")
+
+	// Namespace that the PVC and pod are created in
+	checkNamespaceEnv = os.Getenv("CHECK_NAMESPACE")
+
+	// Image of the pod that writes and reads the test file
+	checkImageEnv = os.Getenv("CHECK_IMAGE")
+)
+
+func init() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+}
+
+func main() {
+	cfg, err := parseConfig()
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+
+	client, err := kubeClient.Create(kubeConfigFile)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+	}
+
+	resourcecheck.Run(resourcecheck.Check{
+		Resources:      "storage check resources",
+		DefaultRunTime: time.Minute * 5,
+		CleanUpTimeout: defaultCleanupTimeout,
+		Run: func(ctx context.Context) []string {
+			err := runStorageCheck(ctx, client, cfg)
+			if err != nil {
+				return []string{err.Error()}
+			}
+			return nil
+		},
+		CleanUp: func(ctx context.Context) error {
+			return cleanUp(ctx, client, cfg.namespace)
+		},
+	})
+}
+
+// checkConfig holds the settings of a storage check run
+type checkConfig struct {
+	namespace    string
+	storageClass string
+	size         resource.Quantity
+	accessMode   v1.PersistentVolumeAccessMode
+	image        string
+}
+
+// parseConfig builds the settings of the check from its environment variables
+func parseConfig() (checkConfig, error) {
+	cfg := checkConfig{
+		namespace:    defaultCheckNamespace,
+		storageClass: strings.TrimSpace(storageClass),
+		accessMode:   v1.ReadWriteOnce,
+		image:        defaultCheckImage,
+	}
+	if len(checkNamespaceEnv) > 0 {
+		cfg.namespace = checkNamespaceEnv
+	}
+	if len(checkImageEnv) > 0 {
+		cfg.image = checkImageEnv
+	}
+
+	size := defaultStorageSize
+	if len(storageSizeEnv) > 0 {
+		size = storageSizeEnv
+	}
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return cfg, fmt.Errorf("error parsing STORAGE_SIZE %q: %w", size, err)
+	}
+	cfg.size = quantity
+
+	if len(accessModeEnv) > 0 {
+		switch mode := v1.PersistentVolumeAccessMode(accessModeEnv); mode {
+		case v1.ReadWriteOnce, v1.ReadWriteMany, v1.ReadWriteOncePod:
+			cfg.accessMode = mode
+		default:
+			return cfg, fmt.Errorf("ACCESS_MODE must be one of %s, %s or %s, but was %q", v1.ReadWriteOnce, v1.ReadWriteMany, v1.ReadWriteOncePod, accessModeEnv)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// checkLabel is set on every resource created by the check so that they can be cleaned up
+	checkLabel = "kuberhealthy-storage-check"
+
+	// dataMountPath is the path that the volume is mounted at in the check pod
+	dataMountPath = "/data"
+
+	// checkTokenEnv is the environment variable holding the content that the check pod writes and reads back
+	checkTokenEnv = "CHECK_TOKEN"
+
+	// pollInterval is how often the check pod is looked at while waiting for it to finish
+	pollInterval = time.Second * 2
+)
+
+// checkScript writes the check token to a file on the volume, flushes it to disk and reads it back
+const checkScript = `set -e
+echo "$` + checkTokenEnv + `" > ` + dataMountPath + `/kh-storage-check
+sync
+content="$(cat ` + dataMountPath + `/kh-storage-check)"
+if [ "$content" != "$` + checkTokenEnv + `" ]; then
+  echo "read back unexpected content from the test file"
+  exit 1
+fi
+rm ` + dataMountPath + `/kh-storage-check`
+
+// volumeEventReasons are the reasons of the pod events that tell why a volume could not be attached or mounted
+var volumeEventReasons = []string{"FailedAttachVolume", "FailedMount"}
+
+// runStorageCheck provisions a PVC, runs a pod that writes and reads a file on it and reports what went wrong.  The
+// created resources are left for cleanUp to delete.
+func runStorageCheck(ctx context.Context, client kubernetes.Interface, cfg checkConfig) error {
+	name := "kh-storage-check-" + strconv.FormatInt(time.Now().Unix(), 10)
+	token := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	log.Infoln("Creating PVC", name, "in namespace", cfg.namespace, "from storage class", storageClassName(cfg.storageClass))
+	_, err := client.CoreV1().PersistentVolumeClaims(cfg.namespace).Create(ctx, newPVC(name, cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create PVC %s from storage class %s: %w", name, storageClassName(cfg.storageClass), err)
+	}
+
+	log.Infoln("Creating pod", name, "to write and read a file on the volume")
+	_, err = client.CoreV1().Pods(cfg.namespace).Create(ctx, newCheckPod(name, name, token, cfg), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create check pod %s: %w", name, err)
+	}
+
+	pod, err := waitForPod(ctx, client, cfg.namespace, name)
+	if err != nil {
+		// the check ran out of time, so figure out where it got stuck
+		diagnoseCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		return diagnoseTimeout(diagnoseCtx, client, cfg, name, name)
+	}
+
+	if pod.Status.Phase == v1.PodFailed {
+		return fmt.Errorf("IO error on volume provisioned from storage class %s: %s", storageClassName(cfg.storageClass), podFailureMessage(pod))
+	}
+	log.Infoln("Pod", name, "wrote and read a file on the volume successfully")
+	return nil
+}
+
+// newPVC creates the PVC that is provisioned by the check
+func newPVC(name string, cfg checkConfig) *v1.PersistentVolumeClaim {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.namespace,
+			Labels:    map[string]string{checkLabel: "true", "source": "kuberhealthy"},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{cfg.accessMode},
+			Resources: v1.VolumeResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: cfg.size},
+			},
+		},
+	}
+	if len(cfg.storageClass) > 0 {
+		pvc.Spec.StorageClassName = &cfg.storageClass
+	}
+	return pvc
+}
+
+// newCheckPod creates the pod that mounts the PVC and writes and reads back the supplied token
+func newCheckPod(name string, pvcName string, token string, cfg checkConfig) *v1.Pod {
+	user := int64(999)
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.namespace,
+			Labels:    map[string]string{checkLabel: "true", "source": "kuberhealthy"},
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			SecurityContext: &v1.PodSecurityContext{
+				RunAsUser: &user,
+				FSGroup:   &user,
+			},
+			Containers: []v1.Container{
+				{
+					Name:                     "storage-check",
+					Image:                    cfg.image,
+					Command:                  []string{"sh", "-c", checkScript},
+					Env:                      []v1.EnvVar{{Name: checkTokenEnv, Value: token}},
+					TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "data", MountPath: dataMountPath},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+					},
+				},
+			},
+		},
+	}
+}
+
+// waitForPod waits for the check pod to succeed or fail.  An error is returned if the context ends first.
+func waitForPod(ctx context.Context, client kubernetes.Interface, namespace string, name string) (*v1.Pod, error) {
+	for {
+		pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.Warningln("Error fetching check pod", name+":", err)
+		}
+		if err == nil && (pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed) {
+			return pod, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// diagnoseTimeout returns an error that tells whether a check that ran out of time was waiting for the volume to be
+// provisioned, waiting for it to be attached and mounted, or waiting for the pod to finish
+func diagnoseTimeout(ctx context.Context, client kubernetes.Interface, cfg checkConfig, pvcName string, podName string) error {
+	pvc, err := client.CoreV1().PersistentVolumeClaims(cfg.namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for the storage check and failed to fetch PVC %s: %w", pvcName, err)
+	}
+	if pvc.Status.Phase != v1.ClaimBound {
+		msg := fmt.Sprintf("provisioning timeout: PVC %s from storage class %s was not bound in time", pvcName, storageClassName(cfg.storageClass))
+		if event := latestEvent(ctx, client, cfg.namespace, "PersistentVolumeClaim", pvcName, []string{"ProvisioningFailed"}); event != nil {
+			msg += ": " + event.Message
+		}
+		return errors.New(msg)
+	}
+
+	if event := latestEvent(ctx, client, cfg.namespace, "Pod", podName, volumeEventReasons); event != nil {
+		return fmt.Errorf("attach error: volume of PVC %s could not be attached or mounted in pod %s: %s", pvcName, podName, event.Message)
+	}
+
+	phase := "unknown"
+	pod, err := client.CoreV1().Pods(cfg.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err == nil {
+		phase = string(pod.Status.Phase)
+	}
+	return fmt.Errorf("timed out waiting for pod %s to write and read a file on the volume of PVC %s. Pod phase: %s", podName, pvcName, phase)
+}
+
+// latestEvent returns the most recent event of an object with one of the supplied reasons, or nil if there is none
+func latestEvent(ctx context.Context, client kubernetes.Interface, namespace string, kind string, name string, reasons []string) *v1.Event {
+	events, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.name=" + name,
+	})
+	if err != nil {
+		log.Warningln("Error listing events of", kind, name+":", err)
+		return nil
+	}
+
+	var latest *v1.Event
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.InvolvedObject.Kind != kind || event.InvolvedObject.Name != name || !containsString(reasons, event.Reason) {
+			continue
+		}
+		if latest == nil || latest.LastTimestamp.Before(&event.LastTimestamp) {
+			latest = event
+		}
+	}
+	return latest
+}
+
+// podFailureMessage returns the termination message of the container of a failed check pod
+func podFailureMessage(pod *v1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		msg := strings.TrimSpace(terminated.Message)
+		if len(msg) == 0 {
+			msg = terminated.Reason
+		}
+		return fmt.Sprintf("pod %s exited with code %d: %s", pod.Name, terminated.ExitCode, msg)
+	}
+	return fmt.Sprintf("pod %s failed: %s", pod.Name, pod.Status.Message)
+}
+
+// cleanUp deletes the pods and PVCs created by the check
+func cleanUp(ctx context.Context, client kubernetes.Interface, namespace string) error {
+	listOptions := metav1.ListOptions{LabelSelector: checkLabel + "=true"}
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list check pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		log.Infoln("Deleting check pod", pod.Name)
+		err = client.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete check pod %s: %w", pod.Name, err)
+		}
+	}
+
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list check PVCs: %w", err)
+	}
+	for _, pvc := range pvcs.Items {
+		log.Infoln("Deleting check PVC", pvc.Name)
+		err = client.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvc.Name, metav1.DeleteOptions{})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete check PVC %s: %w", pvc.Name, err)
+		}
+	}
+	return nil
+}
+
+// storageClassName returns a printable name for the configured storage class
+func storageClassName(storageClass string) string {
+	if len(storageClass) == 0 {
+		return "(default)"
+	}
+	return storageClass
+}
+
+// containsString determines if a slice of strings contains the supplied string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
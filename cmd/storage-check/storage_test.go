@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testConfig returns the settings of a check run for tests
+func testConfig() checkConfig {
+	return checkConfig{
+		namespace:    "kuberhealthy",
+		storageClass: "fast",
+		size:         resource.MustParse("1Gi"),
+		accessMode:   v1.ReadWriteOnce,
+		image:        defaultCheckImage,
+	}
+}
+
+func TestNewPVC(t *testing.T) {
+	cfg := testConfig()
+	pvc := newPVC("test", cfg)
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != "fast" {
+		t.Fatalf("expected storage class fast but got %v", pvc.Spec.StorageClassName)
+	}
+	if pvc.Labels[checkLabel] != "true" {
+		t.Fatalf("expected the PVC to be labeled for clean up")
+	}
+
+	// the default storage class is used when none is configured
+	cfg.storageClass = ""
+	pvc = newPVC("test", cfg)
+	if pvc.Spec.StorageClassName != nil {
+		t.Fatalf("expected no storage class but got %s", *pvc.Spec.StorageClassName)
+	}
+}
+
+func TestNewCheckPod(t *testing.T) {
+	pod := newCheckPod("test", "test-pvc", "token", testConfig())
+	if pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != "test-pvc" {
+		t.Fatalf("expected the pod to mount the PVC")
+	}
+	if pod.Spec.Containers[0].Env[0].Value != "token" {
+		t.Fatalf("expected the pod to be given the check token")
+	}
+	if pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+		t.Fatalf("expected the pod to not be restarted")
+	}
+}
+
+func TestWaitForPod(t *testing.T) {
+	pod := newCheckPod("test", "test", "token", testConfig())
+	pod.Status.Phase = v1.PodSucceeded
+	client := fake.NewSimpleClientset(pod)
+
+	p, err := waitForPod(context.Background(), client, "kuberhealthy", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status.Phase != v1.PodSucceeded {
+		t.Fatalf("expected a succeeded pod but got phase %s", p.Status.Phase)
+	}
+
+	// pods that do not finish in time return an error
+	pod.Status.Phase = v1.PodPending
+	client = fake.NewSimpleClientset(pod)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	_, err = waitForPod(ctx, client, "kuberhealthy", "test")
+	if err == nil {
+		t.Fatalf("expected an error waiting for a pending pod")
+	}
+}
+
+func TestDiagnoseTimeout(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name     string
+		pvcPhase v1.PersistentVolumeClaimPhase
+		events   []runtime.Object
+		expected string
+	}{
+		{
+			name:     "unbound PVC",
+			pvcPhase: v1.ClaimPending,
+			events: []runtime.Object{
+				testEvent("PersistentVolumeClaim", "ProvisioningFailed", "no capacity"),
+			},
+			expected: "provisioning timeout",
+		},
+		{
+			name:     "attach error",
+			pvcPhase: v1.ClaimBound,
+			events: []runtime.Object{
+				testEvent("Pod", "FailedAttachVolume", "attachment timed out"),
+			},
+			expected: "attach error",
+		},
+		{
+			name:     "slow pod",
+			pvcPhase: v1.ClaimBound,
+			expected: "timed out waiting for pod",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvc := newPVC("test", cfg)
+			pvc.Status.Phase = test.pvcPhase
+			pod := newCheckPod("test", "test", "token", cfg)
+			pod.Status.Phase = v1.PodPending
+			client := fake.NewSimpleClientset(append(test.events, pvc, pod)...)
+
+			err := diagnoseTimeout(context.Background(), client, cfg, "test", "test")
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Fatalf("expected an error containing %q but got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+// testEvent creates an event about the check resource of the supplied kind
+func testEvent(kind string, reason string, message string) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kuberhealthy",
+			Name:      "test." + reason,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      kind,
+			Namespace: "kuberhealthy",
+			Name:      "test",
+		},
+		Reason:        reason,
+		Message:       message,
+		LastTimestamp: metav1.Now(),
+	}
+}
+
+func TestPodFailureMessage(t *testing.T) {
+	pod := newCheckPod("test", "test", "token", testConfig())
+	pod.Status.Phase = v1.PodFailed
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  "sh: can't create /data/kh-storage-check: Read-only file system\n",
+				},
+			},
+		},
+	}
+	msg := podFailureMessage(pod)
+	if !strings.Contains(msg, "Read-only file system") || !strings.Contains(msg, "code 1") {
+		t.Fatalf("expected the termination message and exit code but got %q", msg)
+	}
+}
+
+func TestCleanUp(t *testing.T) {
+	cfg := testConfig()
+	other := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kuberhealthy", Name: "other"}}
+	client := fake.NewSimpleClientset(newPVC("test", cfg), newCheckPod("test", "test", "token", cfg), other)
+
+	err := cleanUp(context.Background(), client, "kuberhealthy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods, _ := client.CoreV1().Pods("kuberhealthy").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 1 || pods.Items[0].Name != "other" {
+		t.Fatalf("expected only the unrelated pod to be left but got %v", pods.Items)
+	}
+	pvcs, _ := client.CoreV1().PersistentVolumeClaims("kuberhealthy").List(context.Background(), metav1.ListOptions{})
+	if len(pvcs.Items) != 0 {
+		t.Fatalf("expected the check PVC to be deleted but got %v", pvcs.Items)
+	}
+}
@@ -0,0 +1,258 @@
+// Package main implements a TLS certificate expiry check for Kuberhealthy.  It connects to a list of host:port
+// endpoints and inspects kubernetes.io/tls secrets, and reports a failure for every certificate that has expired
+// or expires within the configured number of days.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
+)
+
+// defaultExpiryDays is the number of days before expiry that certificates are reported when EXPIRY_DAYS is not set
+const defaultExpiryDays = 30
+
+// dialTimeout is the time allowed for connecting to each endpoint and completing the TLS handshake
+const dialTimeout = time.Second * 10
+
+var (
+	// Environment Variables fetched from spec file
+	kubeConfigFile      = os.Getenv("KUBECONFIG")
+	targets             = os.Getenv("TARGETS")
+	checkSecrets        = os.Getenv("CHECK_SECRETS")
+	secretNamespaces    = os.Getenv("SECRET_NAMESPACES")
+	secretLabelSelector = os.Getenv("SECRET_LABEL_SELECTOR")
+	expiryDays          = os.Getenv("EXPIRY_DAYS")
+	insecureSkipVerify  = os.Getenv("INSECURE_SKIP_VERIFY")
+)
+
+func init() {
+	// set debug mode for nodeCheck pkg
+	nodeCheck.EnableDebugOutput()
+}
+
+func main() {
+	// the check must finish before its deadline
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(time.Minute * 5)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-time.Second*5))
+	defer cancel()
+
+	threshold, err := parseExpiryDays(expiryDays)
+	if err != nil {
+		kh.ReportFailureAndExit(err)
+	}
+	checkSecretsBool, err := parseBool(checkSecrets)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("error parsing CHECK_SECRETS: %w", err))
+	}
+	insecure, err := parseBool(insecureSkipVerify)
+	if err != nil {
+		kh.ReportFailureAndExit(fmt.Errorf("error parsing INSECURE_SKIP_VERIFY: %w", err))
+	}
+
+	endpoints := splitList(targets)
+	if len(endpoints) == 0 && !checkSecretsBool {
+		kh.ReportFailureAndExit(errors.New("no TARGETS were specified and CHECK_SECRETS is not enabled. There is nothing to check"))
+	}
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err = nodeCheck.WaitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	now := time.Now()
+	var problems []string
+	for _, endpoint := range endpoints {
+		log.Infoln("Checking certificates served by", endpoint)
+		problems = append(problems, checkEndpoint(ctx, endpoint, insecure, threshold, now)...)
+	}
+
+	if checkSecretsBool {
+		client, err := kubeClient.Create(kubeConfigFile)
+		if err != nil {
+			kh.ReportFailureAndExit(fmt.Errorf("unable to create kubernetes client: %w", err))
+		}
+		secretProblems, err := checkTLSSecrets(ctx, client, splitList(secretNamespaces), secretLabelSelector, threshold, now)
+		if err != nil {
+			kh.ReportFailureAndExit(err)
+		}
+		problems = append(problems, secretProblems...)
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// parseExpiryDays parses the number of days before expiry that certificates are reported as a duration
+func parseExpiryDays(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return time.Hour * 24 * defaultExpiryDays, nil
+	}
+	days, err := strconv.Atoi(s)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("EXPIRY_DAYS must be a number of days, but was %q", s)
+	}
+	return time.Hour * 24 * time.Duration(days), nil
+}
+
+// parseBool parses an optional boolean setting that defaults to false
+func parseBool(s string) (bool, error) {
+	if len(s) == 0 {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// splitList splits a comma separated list and drops blank entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// checkEndpoint connects to a host:port endpoint and returns a problem for every certificate it serves that has
+// expired or expires within the threshold.  Endpoints that can not be connected to are also reported.
+func checkEndpoint(ctx context.Context, endpoint string, insecure bool, threshold time.Duration, now time.Time) []string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: invalid endpoint, expected host:port: %s", endpoint, err)}
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: insecure,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: TLS connection failed: %s", endpoint, err)}
+	}
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return []string{fmt.Sprintf("%s: connection is not a TLS connection", endpoint)}
+	}
+	return certificateProblems(endpoint, tlsConn.ConnectionState().PeerCertificates, threshold, now)
+}
+
+// checkTLSSecrets inspects the certificates of kubernetes.io/tls secrets in the supplied namespaces, or in all
+// namespaces if none are supplied, and returns a problem for every certificate that has expired or expires within
+// the threshold
+func checkTLSSecrets(ctx context.Context, client kubernetes.Interface, namespaces []string, labelSelector string, threshold time.Duration, now time.Time) ([]string, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	var problems []string
+	for _, namespace := range namespaces {
+		secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "type=" + string(v1.SecretTypeTLS),
+			LabelSelector: labelSelector,
+		})
+		if err != nil {
+			return problems, fmt.Errorf("error listing TLS secrets in namespace %q: %w", namespace, err)
+		}
+		log.Infoln("Checking certificates of", len(secrets.Items), "TLS secrets in namespace", namespace)
+
+		for _, secret := range secrets.Items {
+			if secret.Type != v1.SecretTypeTLS {
+				continue
+			}
+			source := "secret " + secret.Namespace + "/" + secret.Name
+			certs, err := parseCertificates(secret.Data[v1.TLSCertKey])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", source, err))
+				continue
+			}
+			problems = append(problems, certificateProblems(source, certs, threshold, now)...)
+		}
+	}
+	return problems, nil
+}
+
+// parseCertificates parses all PEM encoded certificates in the supplied data
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found in " + v1.TLSCertKey)
+	}
+	return certs, nil
+}
+
+// certificateProblems returns a problem for every certificate that has expired or expires within the threshold
+func certificateProblems(source string, certs []*x509.Certificate, threshold time.Duration, now time.Time) []string {
+	var problems []string
+	for _, cert := range certs {
+		expiry := cert.NotAfter.UTC().Format(time.RFC3339)
+		if now.After(cert.NotAfter) {
+			problems = append(problems, fmt.Sprintf("%s: certificate %q expired on %s", source, cert.Subject.String(), expiry))
+			continue
+		}
+		remaining := cert.NotAfter.Sub(now)
+		if remaining < threshold {
+			problems = append(problems, fmt.Sprintf("%s: certificate %q expires in %d days on %s", source, cert.Subject.String(), int(remaining.Hours()/24), expiry))
+			continue
+		}
+		log.Infoln(source+": certificate", cert.Subject.String(), "is valid until", expiry)
+	}
+	return problems
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCertificate creates a self signed certificate that is valid until the supplied time
+func testCertificate(t *testing.T, notAfter time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-time.Hour * 24 * 365),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// tlsSecret creates a kubernetes.io/tls secret holding the supplied certificate
+func tlsSecret(namespace string, name string, cert *x509.Certificate) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		},
+	}
+}
+
+func TestCertificateProblems(t *testing.T) {
+	now := time.Now()
+	threshold := time.Hour * 24 * 30
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		problems int
+	}{
+		{name: "valid", notAfter: now.Add(time.Hour * 24 * 90), problems: 0},
+		{name: "expiring", notAfter: now.Add(time.Hour * 24 * 10), problems: 1},
+		{name: "expired", notAfter: now.Add(-time.Hour), problems: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert := testCertificate(t, test.notAfter)
+			problems := certificateProblems("test", []*x509.Certificate{cert}, threshold, now)
+			if len(problems) != test.problems {
+				t.Fatalf("expected %d problems but got %d: %v", test.problems, len(problems), problems)
+			}
+		})
+	}
+}
+
+func TestParseExpiryDays(t *testing.T) {
+	d, err := parseExpiryDays("")
+	if err != nil || d != time.Hour*24*defaultExpiryDays {
+		t.Fatalf("expected the default threshold but got %s, %v", d, err)
+	}
+	d, err = parseExpiryDays("7")
+	if err != nil || d != time.Hour*24*7 {
+		t.Fatalf("expected a threshold of 7 days but got %s, %v", d, err)
+	}
+	for _, s := range []string{"-1", "seven"} {
+		_, err = parseExpiryDays(s)
+		if err == nil {
+			t.Fatalf("expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert := server.Certificate()
+	ctx := context.Background()
+
+	// the test server certificate is self signed, so verification is skipped
+	problems := checkEndpoint(ctx, u.Host, true, time.Hour, serverCert.NotAfter.Add(-time.Hour*24))
+	if len(problems) != 0 {
+		t.Fatalf("expected no problems but got %v", problems)
+	}
+	problems = checkEndpoint(ctx, u.Host, true, time.Hour*24*2, serverCert.NotAfter.Add(-time.Hour*24))
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem for an expiring certificate but got %v", problems)
+	}
+
+	// without skipping verification the handshake fails
+	problems = checkEndpoint(ctx, u.Host, false, time.Hour, time.Now())
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem for an untrusted certificate but got %v", problems)
+	}
+
+	problems = checkEndpoint(ctx, "no-port", true, time.Hour, time.Now())
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem for an invalid endpoint but got %v", problems)
+	}
+}
+
+func TestCheckTLSSecrets(t *testing.T) {
+	now := time.Now()
+	objects := []runtime.Object{
+		tlsSecret("default", "valid", testCertificate(t, now.Add(time.Hour*24*90))),
+		tlsSecret("default", "expiring", testCertificate(t, now.Add(time.Hour*24*5))),
+		tlsSecret("other", "expired", testCertificate(t, now.Add(-time.Hour))),
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "opaque"},
+			Type:       v1.SecretTypeOpaque,
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "broken"},
+			Type:       v1.SecretTypeTLS,
+			Data:       map[string][]byte{v1.TLSCertKey: []byte("not a certificate")},
+		},
+	}
+	client := fake.NewSimpleClientset(objects...)
+
+	problems, err := checkTLSSecrets(context.Background(), client, []string{"default"}, "", time.Hour*24*30, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("expected problems for the expiring and broken secrets but got %v", problems)
+	}
+
+	problems, err = checkTLSSecrets(context.Background(), client, nil, "", time.Hour*24*30, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 3 {
+		t.Fatalf("expected problems for the secrets of all namespaces but got %v", problems)
+	}
+}
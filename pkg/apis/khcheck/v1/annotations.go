@@ -0,0 +1,18 @@
+package v1
+
+// RunNowAnnotation is the khcheck annotation used to request an immediate run of a check.  Each time its value
+// changes, the check is run right away instead of waiting for its next run.
+const RunNowAnnotation = "comcast.github.io/run-now"
+
+// PausedAnnotation is the khcheck annotation used to pause a check.  Checks with this annotation set to "true"
+// skip their runs until the annotation is removed, just like checks with the paused field of their spec set.
+const PausedAnnotation = "comcast.github.io/paused"
+
+// SensitiveEnvVarsAnnotation is the khcheck annotation used to mark environment variables of the checker pod as
+// sensitive.  It holds a comma separated list of variable names whose values are redacted from reported errors.
+const SensitiveEnvVarsAnnotation = "comcast.github.io/sensitive-env-vars"
+
+// IsPaused determines if a check is paused with either the paused field of its spec or the paused annotation
+func (kc *KuberhealthyCheck) IsPaused() bool {
+	return kc.Spec.Paused || kc.Annotations[PausedAnnotation] == "true"
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewKuberhealthyCheckInformer creates a shared informer that caches the khchecks in the supplied namespace with
+// the typed client.  To include all namespaces, pass a blank namespace.  The informer is indexed by namespace, so
+// that its indexer can back a KuberhealthyCheckLister.
+func NewKuberhealthyCheckInformer(client KuberhealthyChecksGetter, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := client.KuberhealthyChecks(namespace).List(options)
+			return &list, err
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.KuberhealthyChecks(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &KuberhealthyCheck{}, resyncPeriod, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+}
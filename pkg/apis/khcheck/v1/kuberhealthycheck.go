@@ -38,6 +38,7 @@ type KuberhealthyChecksGetter interface {
 type KuberhealthyCheckInterface interface {
 	Create(*KuberhealthyCheck) (KuberhealthyCheck, error)
 	Update(*KuberhealthyCheck) (KuberhealthyCheck, error)
+	UpdateStatus(*KuberhealthyCheck) (KuberhealthyCheck, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
 	Get(name string, options metav1.GetOptions) (KuberhealthyCheck, error)
@@ -130,6 +131,20 @@ func (c *kuberhealthyChecks) Update(kuberhealthyCheck *KuberhealthyCheck) (resul
 	return
 }
 
+// UpdateStatus takes the representation of a kuberhealthyCheck and updates its status subresource. Returns the server's representation of the kuberhealthyCheck, and an error, if there is any.
+func (c *kuberhealthyChecks) UpdateStatus(kuberhealthyCheck *KuberhealthyCheck) (result KuberhealthyCheck, err error) {
+	result = KuberhealthyCheck{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("khchecks").
+		Name(kuberhealthyCheck.Name).
+		SubResource("status").
+		Body(kuberhealthyCheck).
+		Do(context.TODO()).
+		Into(&result)
+	return
+}
+
 // Delete takes name of the kuberhealthyCheck and deletes it. Returns an error if one occurs.
 func (c *kuberhealthyChecks) Delete(name string, options *metav1.DeleteOptions) error {
 	return c.client.Delete().
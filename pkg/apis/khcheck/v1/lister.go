@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KuberhealthyCheckLister lists khchecks from an informer cache.  The khchecks returned are shared with the cache
+// and must be treated as read-only.
+type KuberhealthyCheckLister interface {
+	// List lists all khchecks in the cache
+	List(selector labels.Selector) ([]*KuberhealthyCheck, error)
+	// KuberhealthyChecks returns a lister for the khchecks in the supplied namespace
+	KuberhealthyChecks(namespace string) KuberhealthyCheckNamespaceLister
+}
+
+// KuberhealthyCheckNamespaceLister lists and gets the khchecks of a single namespace from an informer cache
+type KuberhealthyCheckNamespaceLister interface {
+	// List lists all khchecks of the namespace in the cache
+	List(selector labels.Selector) ([]*KuberhealthyCheck, error)
+	// Get gets the named khcheck of the namespace from the cache.  A NotFound error is returned if it is not cached.
+	Get(name string) (*KuberhealthyCheck, error)
+}
+
+// kuberhealthyCheckLister implements KuberhealthyCheckLister
+type kuberhealthyCheckLister struct {
+	indexer cache.Indexer
+}
+
+// NewKuberhealthyCheckLister returns a lister for the khchecks in the supplied indexer
+func NewKuberhealthyCheckLister(indexer cache.Indexer) KuberhealthyCheckLister {
+	return &kuberhealthyCheckLister{indexer: indexer}
+}
+
+// List lists all khchecks in the cache
+func (l *kuberhealthyCheckLister) List(selector labels.Selector) ([]*KuberhealthyCheck, error) {
+	var ret []*KuberhealthyCheck
+	err := cache.ListAll(l.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*KuberhealthyCheck))
+	})
+	return ret, err
+}
+
+// KuberhealthyChecks returns a lister for the khchecks in the supplied namespace
+func (l *kuberhealthyCheckLister) KuberhealthyChecks(namespace string) KuberhealthyCheckNamespaceLister {
+	return kuberhealthyCheckNamespaceLister{indexer: l.indexer, namespace: namespace}
+}
+
+// kuberhealthyCheckNamespaceLister implements KuberhealthyCheckNamespaceLister
+type kuberhealthyCheckNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all khchecks of the namespace in the cache
+func (l kuberhealthyCheckNamespaceLister) List(selector labels.Selector) ([]*KuberhealthyCheck, error) {
+	var ret []*KuberhealthyCheck
+	err := cache.ListAllByNamespace(l.indexer, l.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*KuberhealthyCheck))
+	})
+	return ret, err
+}
+
+// Get gets the named khcheck of the namespace from the cache
+func (l kuberhealthyCheckNamespaceLister) Get(name string) (*KuberhealthyCheck, error) {
+	obj, exists, err := l.indexer.GetByKey(l.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(SchemeGroupVersion.WithResource("khchecks").GroupResource(), name)
+	}
+	return obj.(*KuberhealthyCheck), nil
+}
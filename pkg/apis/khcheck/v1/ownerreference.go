@@ -0,0 +1,15 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OwnerReference returns a reference to the khcheck for resources that should be garbage collected along with it
+func (kc *KuberhealthyCheck) OwnerReference() metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "comcast.github.io/v1",
+		Kind:       "KuberhealthyCheck",
+		Name:       kc.GetName(),
+		UID:        kc.GetUID(),
+	}
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -11,9 +12,16 @@ import (
 // KuberhealthyCheck represents the data in the CRD for configuring an
 // external check for Kuberhealthy
 // +k8s:openapi-gen=true
+// +kubebuilder:printcolumn:name="Interval",type=string,JSONPath=`.spec.runInterval`,description="Run interval"
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`,description="Run schedule"
+// +kubebuilder:printcolumn:name="Timeout",type=string,JSONPath=`.spec.timeout`,description="Run timeout"
+// +kubebuilder:printcolumn:name="Paused",type=boolean,JSONPath=`.spec.paused`,description="Paused"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Ready"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="Age"
 // +kubebuilder:resource:path="khchecks"
 // +kubebuilder:resource:singular="khcheck"
 // +kubebuilder:resource:shortName="khc"
+// +kubebuilder:subresource:status
 type KuberhealthyCheck struct {
 	metav1.TypeMeta `json:",inline" yaml:",inline"`
 	// +optional
@@ -22,6 +30,22 @@ type KuberhealthyCheck struct {
 	// Spec holds the desired state of the KuberhealthyCheck (from the client).
 	// +optional
 	Spec CheckConfig `json:"spec,omitempty" yaml:"spec,omitempty"`
+
+	// Status holds the conditions of the check, so that tools like kubectl wait can tell when it is ready.
+	// +optional
+	Status CheckStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// CheckStatus contains the conditions of a kuberhealthy check.  The conditions are copied from the khstate of the
+// check after every run.
+// +k8s:openapi-gen=true
+type CheckStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"` // the generation of the khcheck that the last run was made with
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"` // the current conditions of the check, such as Ready
 }
 
 // CheckConfig represents a configuration for a kuberhealthy external
@@ -30,15 +54,116 @@ type KuberhealthyCheck struct {
 // endpoint.
 // +k8s:openapi-gen=true
 type CheckConfig struct {
-	RunInterval string        `json:"runInterval" yaml:"runInterval"` // the interval at which the check runs
-	Timeout     string        `json:"timeout" yaml:"timeout"`         // the maximum time the pod is allowed to run before a failure is assumed
-	PodSpec     apiv1.PodSpec `json:"podSpec" yaml:"podSpec"`         // a spec for the external checker
+	RunInterval string `json:"runInterval" yaml:"runInterval"` // the interval at which the check runs
+	// +optional
+	RunIntervalJitter string `json:"runIntervalJitter,omitempty" yaml:"runIntervalJitter,omitempty"` // the window within which the first run of the check is randomly delayed
+	// +optional
+	Schedule string        `json:"schedule,omitempty" yaml:"schedule,omitempty"` // a cron expression that, when set, determines when the check runs instead of the run interval
+	Timeout  string        `json:"timeout" yaml:"timeout"`                       // the maximum time the pod is allowed to run before a failure is assumed
+	PodSpec  apiv1.PodSpec `json:"podSpec" yaml:"podSpec"`                       // a spec for the external checker
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	FailureThreshold int `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"` // the number of consecutive failed runs before the check is reported as unhealthy
+	// +optional
+	IntervalBackoff *IntervalBackoff `json:"intervalBackoff,omitempty" yaml:"intervalBackoff,omitempty"` // grow the run interval of the check while it keeps failing
+	// +optional
+	// +kubebuilder:default=critical
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"` // how failures of the check affect the overall health status. defaults to critical
+	// +optional
+	RunOnAllNodes bool `json:"runOnAllNodes,omitempty" yaml:"runOnAllNodes,omitempty"` // run a checker pod on every schedulable node and aggregate their results
+	// +optional
+	ReportTokenAuth bool `json:"reportTokenAuth,omitempty" yaml:"reportTokenAuth,omitempty"` // checker pods must authenticate their reports with a projected service account token
+	// +optional
+	Paused bool `json:"paused,omitempty" yaml:"paused,omitempty"` // paused checks are not run until they are resumed. their last known state stays on the status page
+	// +optional
+	// +kubebuilder:default=Queue
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty" yaml:"concurrencyPolicy,omitempty"` // what happens when the next run of the check is due while a run is still in flight. defaults to Queue
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty" yaml:"maintenanceWindows,omitempty"` // recurring periods of time during which the check is under maintenance
+	// +optional
+	NetworkPolicy *CheckNetworkPolicy `json:"networkPolicy,omitempty" yaml:"networkPolicy,omitempty"` // create a NetworkPolicy that lets the checker pods reach kuberhealthy and the declared targets
 	// +optional
 	ExtraAnnotations map[string]string `json:"extraAnnotations" yaml:"extraAnnotations"` // a map of extra annotations that will be applied to the pod
 	// +optional
 	ExtraLabels map[string]string `json:"extraLabels" yaml:"extraLabels"` // a map of extra labels that will be applied to the pod
+	// +optional
+	ExtraEnv []apiv1.EnvVar `json:"extraEnv,omitempty" yaml:"extraEnv,omitempty"` // environment variables added to every container of the pod. variables set by a container take precedence
+	// +optional
+	EnvFrom []apiv1.EnvFromSource `json:"envFrom,omitempty" yaml:"envFrom,omitempty"` // secrets and configmaps whose keys are added as environment variables to every container of the pod
 }
 
+// MaintenanceWindow is a recurring period of time during which a check is under maintenance.  Checks under
+// maintenance are reported as such on the status page instead of as OK or failed.
+// +k8s:openapi-gen=true
+type MaintenanceWindow struct {
+	Schedule string `json:"schedule" yaml:"schedule"` // a cron expression for the start of each window
+	Duration string `json:"duration" yaml:"duration"` // how long each window lasts
+	// +optional
+	// +kubebuilder:default=skip
+	Mode MaintenanceMode `json:"mode,omitempty" yaml:"mode,omitempty"` // what happens to runs of the check during the window. defaults to skip
+}
+
+// IntervalBackoff makes the run interval of a check grow with each failed run in a row, up to a maximum, so that a
+// check that keeps failing puts less load on the cluster and sends less noise.  The run interval goes back to the
+// configured run interval after the first successful run.
+// +k8s:openapi-gen=true
+type IntervalBackoff struct {
+	MaxInterval string `json:"maxInterval" yaml:"maxInterval"` // the longest that the run interval grows to
+	// +optional
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:default=2
+	Multiplier int `json:"multiplier,omitempty" yaml:"multiplier,omitempty"` // how many times longer the run interval gets with each failed run in a row. defaults to 2
+}
+
+// CheckNetworkPolicy configures the NetworkPolicy that is created for the checker pods of a check.  The policy always
+// allows egress to Kuberhealthy and to DNS, in addition to the declared egress rules.
+// +k8s:openapi-gen=true
+type CheckNetworkPolicy struct {
+	// +optional
+	Egress []networkingv1.NetworkPolicyEgressRule `json:"egress,omitempty" yaml:"egress,omitempty"` // the targets that the checker pods are allowed to reach
+}
+
+// MaintenanceMode describes what happens to the runs of a check during a maintenance window
+// +kubebuilder:validation:Enum=skip;suppress
+type MaintenanceMode string
+
+const (
+	// MaintenanceModeSkip skips the runs of a check during a maintenance window
+	MaintenanceModeSkip MaintenanceMode = "skip"
+	// MaintenanceModeSuppress keeps running a check during a maintenance window, but leaves its results out of
+	// the overall health status
+	MaintenanceModeSuppress MaintenanceMode = "suppress"
+)
+
+// ConcurrencyPolicy describes what happens when the next run of a check is due while its previous run is still in
+// flight, like the concurrency policy of a CronJob
+// +kubebuilder:validation:Enum=Queue;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyPolicyQueue does not skip runs that are due while a run is in flight.  Runs of a check never
+	// overlap, because the khstate of a check tracks a single run at a time, so the next run is queued and starts as
+	// soon as the run in flight finishes.
+	ConcurrencyPolicyQueue ConcurrencyPolicy = "Queue"
+	// ConcurrencyPolicyForbid skips the runs that are due while a run is in flight
+	ConcurrencyPolicyForbid ConcurrencyPolicy = "Forbid"
+	// ConcurrencyPolicyReplace stops the run in flight, evicts its checker pod and starts the next run in its place
+	ConcurrencyPolicyReplace ConcurrencyPolicy = "Replace"
+)
+
+// Severity describes how the failures of a check affect the overall health status
+// +kubebuilder:validation:Enum=critical;warning;info
+type Severity string
+
+const (
+	// SeverityCritical failures make the overall health status fail
+	SeverityCritical Severity = "critical"
+	// SeverityWarning failures are reported as warnings without making the overall health status fail
+	SeverityWarning Severity = "warning"
+	// SeverityInfo failures are reported as warnings without making the overall health status fail
+	SeverityInfo Severity = "info"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // KuberhealthyCheckList is a list of KuberhealthyCheck resources
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KuberhealthyConfig represents the data in the cluster scoped CRD that holds
+// the global settings of Kuberhealthy.  The spec has the same keys as the
+// kuberhealthy.yaml configmap and is merged over it.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path="khconfigs"
+// +kubebuilder:resource:singular="khconfig"
+// +kubebuilder:resource:shortName="khconfig"
+// +kubebuilder:resource:scope="Cluster"
+type KuberhealthyConfig struct {
+	metav1.TypeMeta `json:",inline" yaml:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	// Spec holds Kuberhealthy settings with the same keys as the kuberhealthy.yaml configmap, such as logLevel,
+	// podDefaults or notifications.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Spec runtime.RawExtension `json:"spec,omitempty" yaml:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KuberhealthyConfigList is a list of KuberhealthyConfig resources
+type KuberhealthyConfigList struct {
+	metav1.TypeMeta `json:",inline" yaml:",inline"`
+	metav1.ListMeta `json:"metadata" yaml:"metadata"`
+
+	Items []KuberhealthyConfig `json:"items" yaml:"items"`
+}
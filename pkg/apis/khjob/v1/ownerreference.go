@@ -0,0 +1,15 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OwnerReference returns a reference to the khjob for resources that should be garbage collected along with it
+func (kj *KuberhealthyJob) OwnerReference() metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "comcast.github.io/v1",
+		Kind:       "KuberhealthyJob",
+		Name:       kj.GetName(),
+		UID:        kj.GetUID(),
+	}
+}
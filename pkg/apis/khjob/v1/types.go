@@ -35,6 +35,9 @@ type JobConfig struct {
 	Timeout string        `json:"timeout" yaml:"timeout"` // the maximum time the pod is allowed to run before a failure is assumed
 	PodSpec apiv1.PodSpec `json:"podSpec" yaml:"podSpec"` // a spec for the external job
 	// +optional
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit int32 `json:"backoffLimit,omitempty" yaml:"backoffLimit,omitempty"` // the number of times a failed job run is retried before the job is marked as failed
+	// +optional
 	ExtraAnnotations map[string]string `json:"extraAnnotations" yaml:"extraAnnotations"` // a map of extra annotations that will be applied to the pod
 	// +optional
 	ExtraLabels map[string]string `json:"extraLabels" yaml:"extraLabels"` // a map of extra labels that will be applied to the pod
@@ -47,6 +50,7 @@ type JobPhase string
 const (
 	JobRunning   JobPhase = "Running"
 	JobCompleted JobPhase = "Completed"
+	JobFailed    JobPhase = "Failed"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,22 @@
+package v1
+
+import "time"
+
+// OrphanedAnnotation is the khstate annotation set by Kuberhealthy when the khcheck or khjob of a khstate no longer
+// exists.  It holds the time the khstate was first found orphaned in RFC3339 format.  Orphaned khstates are hidden
+// from the status page and deleted once they have been orphaned for the khstate reap grace period.
+const OrphanedAnnotation = "comcast.github.io/orphaned-at"
+
+// OrphanedSince returns the time the khstate was first found orphaned.  False is returned when the khstate is not
+// orphaned, or the time in its annotation can not be parsed.
+func (kh *KuberhealthyState) OrphanedSince() (time.Time, bool) {
+	value, ok := kh.Annotations[OrphanedAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	orphanedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return orphanedAt, true
+}
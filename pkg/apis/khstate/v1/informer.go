@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewKuberhealthyStateInformer creates a shared informer that caches the khstates in the supplied namespace with
+// the typed client.  To include all namespaces, pass a blank namespace.  The informer is indexed by namespace, so
+// that its indexer can back a KuberhealthyStateLister.
+func NewKuberhealthyStateInformer(client KuberhealthyStatesGetter, namespace string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := client.KuberhealthyStates(namespace).List(options)
+			return &list, err
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.KuberhealthyStates(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &KuberhealthyState{}, resyncPeriod, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+}
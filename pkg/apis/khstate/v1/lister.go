@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KuberhealthyStateLister lists khstates from an informer cache.  The khstates returned are shared with the cache
+// and must be treated as read-only.
+type KuberhealthyStateLister interface {
+	// List lists all khstates in the cache
+	List(selector labels.Selector) ([]*KuberhealthyState, error)
+	// KuberhealthyStates returns a lister for the khstates in the supplied namespace
+	KuberhealthyStates(namespace string) KuberhealthyStateNamespaceLister
+}
+
+// KuberhealthyStateNamespaceLister lists and gets the khstates of a single namespace from an informer cache
+type KuberhealthyStateNamespaceLister interface {
+	// List lists all khstates of the namespace in the cache
+	List(selector labels.Selector) ([]*KuberhealthyState, error)
+	// Get gets the named khcheck of the namespace from the cache.  A NotFound error is returned if it is not cached.
+	Get(name string) (*KuberhealthyState, error)
+}
+
+// kuberhealthyStateLister implements KuberhealthyStateLister
+type kuberhealthyStateLister struct {
+	indexer cache.Indexer
+}
+
+// NewKuberhealthyStateLister returns a lister for the khstates in the supplied indexer
+func NewKuberhealthyStateLister(indexer cache.Indexer) KuberhealthyStateLister {
+	return &kuberhealthyStateLister{indexer: indexer}
+}
+
+// List lists all khstates in the cache
+func (l *kuberhealthyStateLister) List(selector labels.Selector) ([]*KuberhealthyState, error) {
+	var ret []*KuberhealthyState
+	err := cache.ListAll(l.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*KuberhealthyState))
+	})
+	return ret, err
+}
+
+// KuberhealthyStates returns a lister for the khstates in the supplied namespace
+func (l *kuberhealthyStateLister) KuberhealthyStates(namespace string) KuberhealthyStateNamespaceLister {
+	return kuberhealthyStateNamespaceLister{indexer: l.indexer, namespace: namespace}
+}
+
+// kuberhealthyStateNamespaceLister implements KuberhealthyStateNamespaceLister
+type kuberhealthyStateNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all khstates of the namespace in the cache
+func (l kuberhealthyStateNamespaceLister) List(selector labels.Selector) ([]*KuberhealthyState, error) {
+	var ret []*KuberhealthyState
+	err := cache.ListAllByNamespace(l.indexer, l.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*KuberhealthyState))
+	})
+	return ret, err
+}
+
+// Get gets the named khcheck of the namespace from the cache
+func (l kuberhealthyStateNamespaceLister) Get(name string) (*KuberhealthyState, error) {
+	obj, exists, err := l.indexer.GetByKey(l.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(SchemeGroupVersion.WithResource("khstates").GroupResource(), name)
+	}
+	return obj.(*KuberhealthyState), nil
+}
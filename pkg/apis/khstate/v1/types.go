@@ -12,10 +12,13 @@ import (
 // +k8s:openapi-gen=true
 // +kubebuilder:printcolumn:name="OK",type=string,JSONPath=`.spec.OK`,description="OK status"
 // +kubebuilder:printcolumn:name="Age LastRun",type=date,JSONPath=`.spec.LastRun`,description="Last Run"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Ready condition"
+// +kubebuilder:printcolumn:name="Failures",type=integer,JSONPath=`.spec.ConsecutiveFailures`,description="Consecutive failures"
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="Age"
 // +kubebuilder:resource:path="khstates"
 // +kubebuilder:resource:singular="khstate"
 // +kubebuilder:resource:shortName="khs"
+// +kubebuilder:subresource:status
 type KuberhealthyState struct {
 	metav1.TypeMeta `json:",inline" yaml:",inline"`
 	// +optional
@@ -24,8 +27,38 @@ type KuberhealthyState struct {
 	// Spec holds the desired state of the KuberhealthyState (from the client).
 	// +optional
 	Spec WorkloadDetails `json:"spec" yaml:"spec"`
+
+	// Status holds the conditions of the khWorkload for other controllers and tools to consume.
+	// +optional
+	Status StateStatus `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
+// StateStatus contains the conditions of a kuberhealthy check or job along with a summary of its recent runs
+// +k8s:openapi-gen=true
+type StateStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"` // the generation of the khstate that the status was written for
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"` // the current conditions of the khWorkload
+	// +optional
+	// +nullable
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty" yaml:"lastSuccessTime,omitempty"` // the time the khWorkload last completed a run successfully
+	// +optional
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty" yaml:"consecutiveFailures,omitempty"` // the number of failed runs of the khWorkload in a row
+}
+
+// Condition types set on the status of khstates
+const (
+	// ConditionReady is true when the khWorkload is reported as healthy
+	ConditionReady = "Ready"
+	// ConditionLastRunSucceeded is true when the last completed run of the khWorkload succeeded
+	ConditionLastRunSucceeded = "LastRunSucceeded"
+	// ConditionPodScheduled is true when the checker pod of the last completed run was scheduled to a node
+	ConditionPodScheduled = "PodScheduled"
+)
+
 // WorkloadDetails contains details about a single kuberhealthy check or job's current status
 // +k8s:openapi-gen=true
 // +nullable:name="LastRun"
@@ -39,10 +72,113 @@ type WorkloadDetails struct {
 	LastRun          *metav1.Time `json:"LastRun,omitempty" yaml:"LastRun,omitempty"` // the time the khWorkload was last run
 	AuthoritativePod string       `json:"AuthoritativePod" yaml:"AuthoritativePod"`   // the main kuberhealthy pod creating and updating the khstate
 	CurrentUUID      string       `json:"uuid" yaml:"uuid"`                           // the UUID that is authorized to report statuses into the kuberhealthy endpoint
+	// +optional
+	RunHistory []RunRecord `json:"RunHistory,omitempty" yaml:"RunHistory,omitempty"` // the most recent runs of the khWorkload, oldest first
+	// +optional
+	ConsecutiveFailures int `json:"ConsecutiveFailures,omitempty" yaml:"ConsecutiveFailures,omitempty"` // the number of failed runs of the khWorkload in a row
+	// +optional
+	FailureThreshold int `json:"FailureThreshold,omitempty" yaml:"FailureThreshold,omitempty"` // the number of failed runs in a row before the khWorkload is reported as unhealthy
+	// +optional
+	Severity string `json:"Severity,omitempty" yaml:"Severity,omitempty"` // the severity of the khWorkload's failures. only critical failures make the overall health status fail
+	// +optional
+	FailureReason FailureReason `json:"FailureReason,omitempty" yaml:"FailureReason,omitempty"` // the reason that the last run of the khWorkload failed
+	// +optional
+	FailedNodes []string `json:"FailedNodes,omitempty" yaml:"FailedNodes,omitempty"` // the nodes that failed the last run of a khWorkload that runs on all nodes
+	// +optional
+	NodeReports map[string]NodeReport `json:"NodeReports,omitempty" yaml:"NodeReports,omitempty"` // the reports received so far from the checker pods of a khWorkload that runs on all nodes, by node name
+	// +optional
+	InMaintenance bool `json:"InMaintenance,omitempty" yaml:"InMaintenance,omitempty"` // true when the khWorkload is in a maintenance window and left out of the overall health status
+	// +optional
+	Paused bool `json:"Paused,omitempty" yaml:"Paused,omitempty"` // true when the khWorkload is paused and left out of the overall health status
+	// +optional
+	Labels map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty"` // the labels and annotations of the khWorkload selected with the checkLabels setting, added to its metrics and notifications
+	// +optional
+	EffectiveRunInterval string `json:"EffectiveRunInterval,omitempty" yaml:"EffectiveRunInterval,omitempty"` // the run interval that the khWorkload currently runs on, for checks that back off their run interval while failing
+	// +optional
+	Delayed string `json:"Delayed,omitempty" yaml:"Delayed,omitempty"` // why the next run of the khWorkload is queued instead of starting, such as the quota of its namespace. cleared when the run starts
+	// +optional
+	Interrupted string `json:"Interrupted,omitempty" yaml:"Interrupted,omitempty"` // why the last run of the khWorkload was stopped before it completed, such as a shutdown of Kuberhealthy. cleared when the next run completes
+	// +optional
+	// +nullable
+	Progress *RunProgress `json:"Progress,omitempty" yaml:"Progress,omitempty"` // the latest progress reported by the checker pod of the run in flight. cleared when the run completes
+	// +optional
+	// +nullable
+	InFlightRun *InFlightRun `json:"InFlightRun,omitempty" yaml:"InFlightRun,omitempty"` // the checker pod of the run in flight, so that another Kuberhealthy pod can resume the run. cleared when the run completes
 	// +nullable
 	khWorkload *KHWorkload `json:"khWorkload,omitempty" yaml:"khWorkload,omitempty"`
 }
 
+// RunRecord contains the result of a single run of a kuberhealthy check or job
+// +k8s:openapi-gen=true
+type RunRecord struct {
+	StartTime   metav1.Time `json:"StartTime" yaml:"StartTime"`     // the time the run started
+	RunDuration string      `json:"RunDuration" yaml:"RunDuration"` // the time it took for the run to complete
+	OK          bool        `json:"OK" yaml:"OK"`                   // true or false status of the run
+	Errors      []string    `json:"Errors" yaml:"Errors"`           // the list of errors reported from the run
+	Pod         string      `json:"Pod" yaml:"Pod"`                 // the name of the checker pod used for the run
+	UUID        string      `json:"uuid" yaml:"uuid"`               // the UUID of the run
+	// +optional
+	FailureReason FailureReason `json:"FailureReason,omitempty" yaml:"FailureReason,omitempty"` // the reason that the run failed
+}
+
+// FailureReason describes why a run of a khWorkload failed, so that problems with the cluster running the checker
+// pods can be told apart from failures reported by the checker pods themselves
+// +kubebuilder:validation:Enum=Timeout;PodSchedulingFailed;ImagePullError;ReportedFailure;OOMKilled;Evicted;ReaperKilled;ExecutionError
+type FailureReason string
+
+// The reasons that a run of a khWorkload can fail for
+const (
+	// FailureReasonTimeout is set when the checker pod did not report in or exit before the timeout
+	FailureReasonTimeout FailureReason = "Timeout"
+	// FailureReasonPodSchedulingFailed is set when the checker pod could not be scheduled to a node before the timeout
+	FailureReasonPodSchedulingFailed FailureReason = "PodSchedulingFailed"
+	// FailureReasonImagePullError is set when the image of the checker pod could not be pulled
+	FailureReasonImagePullError FailureReason = "ImagePullError"
+	// FailureReasonReportedFailure is set when the checker pod reported a failure
+	FailureReasonReportedFailure FailureReason = "ReportedFailure"
+	// FailureReasonOOMKilled is set when a container of the checker pod ran out of memory before the pod reported in
+	FailureReasonOOMKilled FailureReason = "OOMKilled"
+	// FailureReasonEvicted is set when the checker pod was evicted from its node before it reported in
+	FailureReasonEvicted FailureReason = "Evicted"
+	// FailureReasonReaperKilled is set when the checker pod was deleted before it reported in
+	FailureReasonReaperKilled FailureReason = "ReaperKilled"
+	// FailureReasonExecutionError is set when the run failed for any other reason
+	FailureReasonExecutionError FailureReason = "ExecutionError"
+)
+
+// NodeReport contains the result reported by the checker pod on a single node for a run of a khWorkload that runs
+// on all nodes
+// +k8s:openapi-gen=true
+type NodeReport struct {
+	OK     bool     `json:"OK" yaml:"OK"`         // true or false status reported from the node
+	Errors []string `json:"Errors" yaml:"Errors"` // the list of errors reported from the node
+	UUID   string   `json:"uuid" yaml:"uuid"`     // the UUID of the run the report belongs to
+}
+
+// RunProgress contains an interim status update reported by the checker pod of a run that is still in flight
+// +k8s:openapi-gen=true
+type RunProgress struct {
+	Message string `json:"Message" yaml:"Message"` // a description of the progress of the run, such as "created deployment"
+	// +optional
+	Step int `json:"Step,omitempty" yaml:"Step,omitempty"` // the number of steps of the run that are complete
+	// +optional
+	TotalSteps int `json:"TotalSteps,omitempty" yaml:"TotalSteps,omitempty"` // the number of steps in the run
+	// +optional
+	Node       string      `json:"Node,omitempty" yaml:"Node,omitempty"` // the node of the checker pod that reported the progress, for khWorkloads that run on all nodes
+	UpdateTime metav1.Time `json:"UpdateTime" yaml:"UpdateTime"`         // the time the progress was reported
+	UUID       string      `json:"uuid" yaml:"uuid"`                     // the UUID of the run the progress belongs to
+}
+
+// InFlightRun identifies the checker pod of a run that is in flight, so that a Kuberhealthy pod that takes over as
+// master can wait for the run to complete instead of starting a new one
+// +k8s:openapi-gen=true
+type InFlightRun struct {
+	Pod       string      `json:"Pod" yaml:"Pod"`             // the name of the checker pod of the run
+	UUID      string      `json:"uuid" yaml:"uuid"`           // the UUID of the run
+	StartTime metav1.Time `json:"StartTime" yaml:"StartTime"` // the time the run started
+	Deadline  metav1.Time `json:"Deadline" yaml:"Deadline"`   // the time the run times out
+}
+
 // KHWorkload is used to describe the different types of kuberhealthy workloads: KhCheck or KHJob
 type KHWorkload string
 
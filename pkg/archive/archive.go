@@ -0,0 +1,325 @@
+// Package archive batches the results of check and job runs and writes them as gzip compressed JSON lines to an
+// object storage bucket, so that months of check history can be kept for SLO reporting without storing it in etcd.
+package archive // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/archive"
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+)
+
+// defaultFlushInterval is how often archived run records are written to the bucket when no interval is configured
+const defaultFlushInterval = time.Minute * 15
+
+// defaultMaxBatchSize is the number of run records that causes a batch to be written before the flush interval
+// when no size is configured
+const defaultMaxBatchSize = 5000
+
+// defaultMaxBufferedRecords is the number of run records kept in memory while the bucket can not be written to
+// when no limit is configured.  The oldest records are dropped beyond this.
+const defaultMaxBufferedRecords = 50000
+
+// uploadTimeout is how long writing a single batch to the bucket may take
+const uploadTimeout = time.Minute * 2
+
+// Supported object storage providers
+const (
+	ProviderS3  = "s3"
+	ProviderGCS = "gcs"
+)
+
+// Config holds the settings for archiving run results to an object storage bucket
+type Config struct {
+	Enabled            bool          `yaml:"enabled"`                      // set to true to archive the result of every check and job run
+	Provider           string        `yaml:"provider"`                     // the object storage provider of the bucket, s3 or gcs
+	Bucket             string        `yaml:"bucket"`                       // the bucket that batches of run records are written to
+	Prefix             string        `yaml:"prefix,omitempty"`             // prepended to the key of every object written, such as kuberhealthy/prod
+	FlushInterval      time.Duration `yaml:"flushInterval,omitempty"`      // how often batches are written to the bucket. Defaults to 15m
+	MaxBatchSize       int           `yaml:"maxBatchSize,omitempty"`       // the number of run records that causes a batch to be written before the flush interval. Defaults to 5000
+	MaxBufferedRecords int           `yaml:"maxBufferedRecords,omitempty"` // the number of run records kept while the bucket can not be written to. Defaults to 50000
+	Region             string        `yaml:"region,omitempty"`             // the region of an s3 bucket. The region of the environment is used when blank
+	Endpoint           string        `yaml:"endpoint,omitempty"`           // overrides the API endpoint, such as an S3 compatible store like MinIO
+	ForcePathStyle     bool          `yaml:"forcePathStyle,omitempty"`     // set to true to address s3 buckets by path instead of by virtual host, which most S3 compatible stores need
+	CredentialsFile    string        `yaml:"credentialsFile,omitempty"`    // a file holding the service account key of a gcs bucket, usually mounted from a secret. Application default credentials are used when blank
+}
+
+// Validate returns an error describing the first problem found with the configuration
+func (c Config) Validate() error {
+	if len(c.Bucket) == 0 {
+		return fmt.Errorf("result archival requires a bucket")
+	}
+	switch c.Provider {
+	case ProviderS3, ProviderGCS:
+	default:
+		return fmt.Errorf("unsupported result archival provider %q. expected %s or %s", c.Provider, ProviderS3, ProviderGCS)
+	}
+	if c.FlushInterval < 0 || c.MaxBatchSize < 0 || c.MaxBufferedRecords < 0 {
+		return fmt.Errorf("result archival flushInterval, maxBatchSize and maxBufferedRecords can not be negative")
+	}
+	return nil
+}
+
+// flushInterval returns how often batches are written to the bucket
+func (c Config) flushInterval() time.Duration {
+	if c.FlushInterval == 0 {
+		return defaultFlushInterval
+	}
+	return c.FlushInterval
+}
+
+// maxBatchSize returns the number of run records that causes a batch to be written early
+func (c Config) maxBatchSize() int {
+	if c.MaxBatchSize == 0 {
+		return defaultMaxBatchSize
+	}
+	return c.MaxBatchSize
+}
+
+// maxBufferedRecords returns the number of run records kept while the bucket can not be written to
+func (c Config) maxBufferedRecords() int {
+	if c.MaxBufferedRecords == 0 {
+		return defaultMaxBufferedRecords
+	}
+	if c.MaxBufferedRecords < c.maxBatchSize() {
+		return c.maxBatchSize()
+	}
+	return c.MaxBufferedRecords
+}
+
+// objectStore is implemented by the object storage providers that batches are written to
+type objectStore interface {
+	put(ctx context.Context, key string, body []byte) error
+}
+
+// Record is a single archived run of a check or job.  Every line of an archived object is one record.
+type Record struct {
+	Name               string            `json:"name"`
+	Namespace          string            `json:"namespace"`
+	Workload           string            `json:"workload"`
+	OK                 bool              `json:"ok"`
+	Errors             []string          `json:"errors,omitempty"`
+	RunDurationSeconds float64           `json:"runDurationSeconds"`
+	FailureReason      string            `json:"failureReason,omitempty"`
+	Node               string            `json:"node,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Cluster            string            `json:"cluster,omitempty"`
+	Environment        string            `json:"environment,omitempty"`
+	Time               time.Time         `json:"time"`
+}
+
+// newRecord creates the archived record of a run result
+func newRecord(r metrics.RunResult) Record {
+	return Record{
+		Name:               r.Name,
+		Namespace:          r.Namespace,
+		Workload:           r.Workload,
+		OK:                 r.OK,
+		Errors:             r.Errors,
+		RunDurationSeconds: r.RunDuration.Seconds(),
+		FailureReason:      r.FailureReason,
+		Node:               r.Node,
+		Labels:             r.Labels,
+		Cluster:            r.Cluster,
+		Environment:        r.Environment,
+		Time:               r.Time.UTC(),
+	}
+}
+
+// Archiver is a result exporter that buffers the result of every run and writes them to an object storage bucket
+// in batches.  Batches are written every flush interval, when enough records are buffered, and on shutdown.
+type Archiver struct {
+	config   Config
+	store    objectStore
+	source   string // identifies the kuberhealthy instance in object keys so that replicas do not overwrite each other
+	mu       sync.Mutex
+	records  []Record
+	flushNow chan struct{}
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewArchiver creates an Archiver for the supplied configuration and starts writing batches in the background.
+// Call Shutdown to write the remaining records and stop it.
+func NewArchiver(config Config) (*Archiver, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var store objectStore
+	switch config.Provider {
+	case ProviderS3:
+		store, err = newS3Store(config)
+	case ProviderGCS:
+		store, err = newGCSStore(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newArchiver(config, store), nil
+}
+
+// newArchiver creates an Archiver that writes to the supplied store and starts it
+func newArchiver(config Config, store objectStore) *Archiver {
+	source, err := os.Hostname()
+	if err != nil || len(source) == 0 {
+		source = "kuberhealthy"
+	}
+	if podName := os.Getenv("POD_NAME"); len(podName) > 0 {
+		source = podName
+	}
+
+	a := &Archiver{
+		config:   config,
+		store:    store,
+		source:   source,
+		flushNow: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+// Name returns the name of this exporter
+func (a *Archiver) Name() string {
+	return "archive"
+}
+
+// Export buffers the supplied run result until the next batch is written
+func (a *Archiver) Export(r metrics.RunResult) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	a.records = append(a.records, newRecord(r))
+	full := len(a.records) >= a.config.maxBatchSize()
+	a.mu.Unlock()
+
+	if full {
+		select {
+		case a.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Shutdown writes any buffered records to the bucket and stops the archiver
+func (a *Archiver) Shutdown() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+	a.wg.Wait()
+}
+
+// run writes batches to the bucket until the archiver is shut down
+func (a *Archiver) run() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.config.flushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			a.flush()
+			return
+		case <-ticker.C:
+		case <-a.flushNow:
+		}
+		a.flush()
+	}
+}
+
+// flush writes every buffered record to the bucket in batches of at most the max batch size.  Records that could not
+// be written are kept for the next flush, up to the max number of buffered records.
+func (a *Archiver) flush() {
+	a.mu.Lock()
+	records := a.records
+	a.records = nil
+	a.mu.Unlock()
+
+	for len(records) > 0 {
+		n := a.config.maxBatchSize()
+		if n > len(records) {
+			n = len(records)
+		}
+
+		err := a.write(records[:n])
+		if err != nil {
+			log.Errorln("archive: failed to write", n, "run records to bucket", a.config.Bucket+":", err)
+			a.requeue(records)
+			return
+		}
+		log.Debugln("archive: wrote", n, "run records to bucket", a.config.Bucket)
+		records = records[n:]
+	}
+}
+
+// requeue puts records that could not be written back in front of the buffered records, dropping the oldest
+// records beyond the max number of buffered records
+func (a *Archiver) requeue(records []Record) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.records = append(records, a.records...)
+	limit := a.config.maxBufferedRecords()
+	if len(a.records) > limit {
+		dropped := len(a.records) - limit
+		log.Warningln("archive: dropping", dropped, "run records that could not be written to bucket", a.config.Bucket)
+		a.records = a.records[dropped:]
+	}
+}
+
+// write encodes a batch of records and writes it to the bucket as a single object
+func (a *Archiver) write(records []Record) error {
+	body, err := encode(records)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	return a.store.put(ctx, objectKey(a.config.Prefix, a.source, records[0].Time), body)
+}
+
+// encode writes records as gzip compressed JSON lines
+func encode(records []Record) ([]byte, error) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	encoder := json.NewEncoder(gz)
+	for _, r := range records {
+		err := encoder.Encode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode run record of %s/%s: %w", r.Namespace, r.Name, err)
+		}
+	}
+	err := gz.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress run records: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// objectKey returns the key a batch is written to.  Keys are partitioned by the day of the first record in the
+// batch with a dt= path segment, which query engines such as Athena and BigQuery can prune by.
+func objectKey(prefix string, source string, first time.Time) string {
+	first = first.UTC()
+	now := time.Now().UTC()
+	name := "kuberhealthy-" + source + "-" + now.Format("20060102T150405.000000000Z") + ".jsonl.gz"
+	return path.Join(strings.Trim(prefix, "/"), "dt="+first.Format("2006-01-02"), name)
+}
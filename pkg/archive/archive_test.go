@@ -0,0 +1,186 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
+)
+
+// fakeStore keeps the objects written to it in memory and fails writes while failing is set
+type fakeStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	failing bool
+}
+
+func (f *fakeStore) put(ctx context.Context, key string, body []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing {
+		return errors.New("bucket unavailable")
+	}
+	if f.objects == nil {
+		f.objects = make(map[string][]byte)
+	}
+	f.objects[key] = body
+	return nil
+}
+
+// records decodes every record written to the store
+func (f *fakeStore) records(t *testing.T) []Record {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var records []Record
+	for key, body := range f.objects {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("object %s is not gzip compressed: %v", key, err)
+		}
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			r := Record{}
+			err = json.Unmarshal(scanner.Bytes(), &r)
+			if err != nil {
+				t.Fatalf("object %s has an invalid line: %v", key, err)
+			}
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func testRunResult(name string) metrics.RunResult {
+	return metrics.RunResult{
+		Name:          name,
+		Namespace:     "kuberhealthy",
+		Workload:      "check",
+		OK:            false,
+		Errors:        []string{"lookup failed"},
+		RunDuration:   time.Second * 3,
+		FailureReason: "ReportedFailure",
+		Labels:        map[string]string{"team": "platform"},
+		Time:          time.Unix(1600000000, 0),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"s3", Config{Provider: ProviderS3, Bucket: "history"}, true},
+		{"gcs", Config{Provider: ProviderGCS, Bucket: "history"}, true},
+		{"no bucket", Config{Provider: ProviderS3}, false},
+		{"unknown provider", Config{Provider: "azure", Bucket: "history"}, false},
+		{"negative interval", Config{Provider: ProviderS3, Bucket: "history", FlushInterval: -time.Minute}, false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.config.Validate()
+		if tc.valid != (err == nil) {
+			t.Fatalf("%s: expected valid %t, got error %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestArchiverBatches(t *testing.T) {
+	store := &fakeStore{}
+	a := newArchiver(Config{Bucket: "history", FlushInterval: time.Hour, MaxBatchSize: 2}, store)
+
+	for _, name := range []string{"dns-check", "http-check", "pod-check"} {
+		err := a.Export(testRunResult(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first two records fill a batch and are written before the flush interval
+	deadline := time.Now().Add(time.Second * 5)
+	for len(store.records(t)) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a full batch to be written before the flush interval")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the remaining record is written on shutdown
+	a.Shutdown()
+	records := store.records(t)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 archived records, got %d", len(records))
+	}
+	for _, r := range records {
+		if r.RunDurationSeconds != 3 || r.FailureReason != "ReportedFailure" || r.Labels["team"] != "platform" {
+			t.Fatalf("unexpected archived record %+v", r)
+		}
+	}
+}
+
+func TestArchiverKeepsRecordsWhenWriteFails(t *testing.T) {
+	store := &fakeStore{failing: true}
+	a := &Archiver{config: Config{Bucket: "history", MaxBatchSize: 2, MaxBufferedRecords: 3}, store: store, source: "kuberhealthy-0"}
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_ = a.Export(testRunResult(name))
+	}
+	a.flush()
+
+	// only the newest records up to the buffer limit are kept
+	if len(a.records) != 3 || a.records[0].Name != "b" {
+		t.Fatalf("expected the 3 newest records to be kept, got %+v", a.records)
+	}
+
+	store.failing = false
+	a.flush()
+	if len(a.records) != 0 {
+		t.Fatalf("expected no records to be kept after a successful flush, got %d", len(a.records))
+	}
+	if len(store.records(t)) != 3 {
+		t.Fatalf("expected 3 archived records, got %d", len(store.records(t)))
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	key := objectKey("/kuberhealthy/prod/", "kuberhealthy-0", time.Date(2026, 3, 4, 23, 59, 0, 0, time.UTC))
+	if !strings.HasPrefix(key, "kuberhealthy/prod/dt=2026-03-04/kuberhealthy-kuberhealthy-0-") || !strings.HasSuffix(key, ".jsonl.gz") {
+		t.Fatalf("unexpected object key %s", key)
+	}
+}
+
+func TestGCSPut(t *testing.T) {
+	var gotPath, gotName, gotContentType string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotName = r.URL.Query().Get("name")
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	g := &gcsStore{bucket: "history", endpoint: server.URL, client: server.Client()}
+	err := g.put(context.Background(), "dt=2026-03-04/batch.jsonl.gz", []byte("batch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/upload/storage/v1/b/history/o" || gotName != "dt=2026-03-04/batch.jsonl.gz" {
+		t.Fatalf("unexpected upload of %s to %s", gotName, gotPath)
+	}
+	if gotContentType != archiveContentType || string(gotBody) != "batch" {
+		t.Fatalf("unexpected upload body %q with content type %s", gotBody, gotContentType)
+	}
+}
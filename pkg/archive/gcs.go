@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+)
+
+// defaultGCSEndpoint is the Cloud Storage JSON API that objects are uploaded to when no endpoint is configured
+const defaultGCSEndpoint = "https://storage.googleapis.com"
+
+// gcsScope is the OAuth scope needed to write objects to a bucket
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsStore writes batches to a Cloud Storage bucket with the JSON API
+type gcsStore struct {
+	bucket   string
+	endpoint string
+	client   *http.Client
+}
+
+// newGCSStore creates a gcsStore for the bucket in the supplied configuration.  The service account key in the
+// credentials file is used when one is configured, and application default credentials, such as workload
+// identity, are used otherwise.
+func newGCSStore(config Config) (*gcsStore, error) {
+	ctx := context.Background()
+
+	var client *http.Client
+	if len(config.CredentialsFile) > 0 {
+		b, err := os.ReadFile(config.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gcs credentials file %s: %w", config.CredentialsFile, err)
+		}
+		jwtConfig, err := google.JWTConfigFromJSON(b, gcsScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse gcs credentials file %s: %w", config.CredentialsFile, err)
+		}
+		client = jwtConfig.Client(ctx)
+	} else {
+		var err error
+		client, err = google.DefaultClient(ctx, gcsScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find gcs credentials: %w", err)
+		}
+	}
+
+	endpoint := config.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = defaultGCSEndpoint
+	}
+	return &gcsStore{
+		bucket:   config.Bucket,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   client,
+	}, nil
+}
+
+// put uploads an object to the bucket with a simple media upload
+func (g *gcsStore) put(ctx context.Context, key string, body []byte) error {
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", key)
+	uploadURL := g.endpoint + "/upload/storage/v1/b/" + url.PathEscape(g.bucket) + "/o?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create gcs upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", archiveContentType)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write gcs object %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write gcs object %s: status code %d: %s", key, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// archiveContentType is the content type of archived objects
+const archiveContentType = "application/gzip"
+
+// s3Store writes batches to an S3 bucket, or to a bucket of an S3 compatible store when an endpoint is configured.
+// Credentials are found with the default AWS credential chain, which includes IAM roles for service accounts.
+type s3Store struct {
+	bucket string
+	client *s3.S3
+}
+
+// newS3Store creates an s3Store for the bucket in the supplied configuration
+func newS3Store(config Config) (*s3Store, error) {
+	awsConfig := aws.NewConfig().WithCredentialsChainVerboseErrors(true).WithS3ForcePathStyle(config.ForcePathStyle)
+	if len(config.Region) > 0 {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+	if len(config.Endpoint) > 0 {
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws session for result archival: %w", err)
+	}
+	return &s3Store{
+		bucket: config.Bucket,
+		client: s3.New(sess),
+	}, nil
+}
+
+// put writes an object to the bucket
+func (s *s3Store) put(ctx context.Context, key string, body []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(archiveContentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write s3 object %s: %w", key, err)
+	}
+	return nil
+}
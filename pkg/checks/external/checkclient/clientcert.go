@@ -0,0 +1,50 @@
+package checkclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// reportTLSConfig returns the TLS settings that reports are sent with when Kuberhealthy issued this pod a client
+// certificate for mutual TLS.  The client certificate is presented to the reporting endpoint and the endpoint is
+// verified with the CA that Kuberhealthy provided.  Nil is returned when no client certificate was issued.
+func reportTLSConfig() (*tls.Config, error) {
+	certFile := os.Getenv(external.KHClientCertFile)
+	keyFile := os.Getenv(external.KHClientKeyFile)
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		writeLog("ERROR: unable to load kuberhealthy client certificate", certFile+": "+err.Error())
+		return nil, fmt.Errorf("failed to load client certificate %s and key %s: %w", certFile, keyFile, err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	// verify the reporting endpoint with the CA from kuberhealthy, in addition to the system roots
+	caFile := os.Getenv(external.KHCACertFile)
+	if len(caFile) > 0 {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			writeLog("ERROR: unable to read kuberhealthy CA certificate", caFile+": "+err.Error())
+			return nil, fmt.Errorf("failed to read CA certificate %s: %w", caFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA certificate %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
@@ -0,0 +1,130 @@
+package checkclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// testCert creates a certificate from the supplied template, signed by the supplied parent or self signed, and
+// returns it with its key
+func testCert(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Failed to generate key:", err)
+	}
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal("Failed to create certificate:", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("Failed to parse certificate:", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("Failed to encode key:", err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// TestSendReportWithClientCert ensures that reports are sent with the client certificate from kuberhealthy and
+// that the reporting endpoint is verified with the CA from kuberhealthy
+func TestSendReportWithClientCert(t *testing.T) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(time.Hour)
+	ca, caKey, caPEM, _ := testCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kuberhealthy test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	_, _, serverPEM, serverKeyPEM := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	_, _, clientPEM, clientKeyPEM := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "kuberhealthy/test-check"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.crt": caPEM, "tls.crt": clientPEM, "tls.key": clientKeyPEM}
+	for name, b := range files {
+		err := os.WriteFile(filepath.Join(dir, name), b, 0600)
+		if err != nil {
+			t.Fatal("Failed to write", name+":", err)
+		}
+	}
+
+	serverCert, err := tls.X509KeyPair(serverPEM, serverKeyPEM)
+	if err != nil {
+		t.Fatal("Failed to load server certificate:", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	var clientName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	os.Setenv(external.KHReportingURL, server.URL)
+	os.Setenv(external.KHRunUUID, "test-uuid")
+	os.Setenv(external.KHClientCertFile, filepath.Join(dir, "tls.crt"))
+	os.Setenv(external.KHClientKeyFile, filepath.Join(dir, "tls.key"))
+	os.Setenv(external.KHCACertFile, filepath.Join(dir, "ca.crt"))
+	defer os.Unsetenv(external.KHClientCertFile)
+	defer os.Unsetenv(external.KHClientKeyFile)
+	defer os.Unsetenv(external.KHCACertFile)
+
+	err = ReportSuccess()
+	if err != nil {
+		t.Fatal("Failed to send report over mutual TLS:", err)
+	}
+	if clientName != "kuberhealthy/test-check" {
+		t.Fatalf("Expected the report to be sent with the client certificate but the server saw %q", clientName)
+	}
+
+	// a client certificate that can not be loaded fails the report right away
+	os.Setenv(external.KHClientKeyFile, filepath.Join(dir, "missing.key"))
+	err = ReportSuccess()
+	if err == nil {
+		t.Fatal("Expected an error when the client certificate can not be loaded")
+	}
+}
@@ -0,0 +1,45 @@
+package checkclient
+
+import (
+	"context"
+	"time"
+)
+
+// DeadlineMargin is how long before the deadline of the check run the context returned by Context is cancelled.
+// This leaves the check time to report a result before Kuberhealthy times the run out.
+var DeadlineMargin = time.Second * 15
+
+// Context returns a context that is cancelled shortly before Kuberhealthy times out the current check run, so that
+// checks can stop their work and report a partial error instead of being timed out.  The context is cancelled
+// DeadlineMargin before the deadline, or half way to the deadline if less than twice the margin is left.  If the
+// deadline can not be read, the error is returned along with a context that is only cancelled by its cancel func.
+func Context() (context.Context, context.CancelFunc, error) {
+	deadline, err := GetDeadline()
+	if err != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		return ctx, cancel, err
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), contextDeadline(deadline, time.Now()))
+	return ctx, cancel, nil
+}
+
+// TimeRemaining returns how much time is left before Kuberhealthy times out the current check run
+func TimeRemaining() (time.Duration, error) {
+	deadline, err := GetDeadline()
+	if err != nil {
+		return 0, err
+	}
+	return time.Until(deadline), nil
+}
+
+// contextDeadline returns the time at which the context of a check run with the supplied deadline is cancelled
+func contextDeadline(deadline time.Time, now time.Time) time.Time {
+	margin := DeadlineMargin
+	remaining := deadline.Sub(now)
+	if remaining < margin*2 {
+		margin = remaining / 2
+	}
+	writeLog("DEBUG: Cancelling check context ", margin, " before the deadline of ", deadline)
+	return deadline.Add(-margin)
+}
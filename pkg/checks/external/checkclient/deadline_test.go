@@ -0,0 +1,59 @@
+package checkclient
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+)
+
+// TestContextDeadline ensures that check contexts are cancelled ahead of the deadline of the run
+func TestContextDeadline(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	var testCases = []struct {
+		remaining time.Duration
+		expected  time.Duration
+	}{
+		{time.Minute * 5, time.Minute*5 - DeadlineMargin},
+		{DeadlineMargin * 2, DeadlineMargin},
+		{time.Second * 10, time.Second * 5},
+		{-time.Second * 10, -time.Second * 5},
+	}
+
+	for _, tc := range testCases {
+		result := contextDeadline(now.Add(tc.remaining), now)
+		if result.Sub(now) != tc.expected {
+			t.Fatalf("contextDeadline with %s remaining resulted in %s but expected %s", tc.remaining, result.Sub(now), tc.expected)
+		}
+	}
+}
+
+// TestContext ensures that the context of a check run carries a deadline ahead of KH_CHECK_RUN_DEADLINE
+func TestContext(t *testing.T) {
+	defer os.Unsetenv(external.KHDeadline)
+
+	deadline := time.Now().Add(time.Minute * 5).Truncate(time.Second)
+	os.Setenv(external.KHDeadline, strconv.FormatInt(deadline.Unix(), 10))
+	ctx, cancel, err := Context()
+	defer cancel()
+	if err != nil {
+		t.Fatal("Failed to create check context:", err)
+	}
+	ctxDeadline, ok := ctx.Deadline()
+	if !ok || !ctxDeadline.Equal(deadline.Add(-DeadlineMargin)) {
+		t.Fatalf("Expected the context to be cancelled at %s but got %s", deadline.Add(-DeadlineMargin), ctxDeadline)
+	}
+
+	os.Setenv(external.KHDeadline, "")
+	ctx, cancel, err = Context()
+	defer cancel()
+	if err == nil {
+		t.Fatal("Expected an error without a deadline")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("Expected a context without a deadline when the deadline can not be read")
+	}
+}
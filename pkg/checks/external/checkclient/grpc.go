@@ -0,0 +1,164 @@
+package checkclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cenkalti/backoff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/reportpb"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// Transport is how reports are sent to Kuberhealthy
+type Transport string
+
+const (
+	// TransportAuto sends reports over gRPC when Kuberhealthy set the KH_REPORTING_GRPC_ADDRESS environment variable
+	// and over HTTP otherwise
+	TransportAuto Transport = ""
+
+	// TransportHTTP always posts reports to the KH_REPORTING_URL
+	TransportHTTP Transport = "http"
+
+	// TransportGRPC always sends reports to the gRPC reporting API at KH_REPORTING_GRPC_ADDRESS
+	TransportGRPC Transport = "grpc"
+)
+
+// getKuberhealthyGRPCAddress fetches the address of the gRPC reporting API from the environment variables and
+// indicates if reports should be sent to it with the selected ReportTransport
+func getKuberhealthyGRPCAddress() (string, bool, error) {
+	address := os.Getenv(external.KHReportingGRPCAddress)
+
+	switch ReportTransport {
+	case TransportHTTP:
+		return "", false, nil
+	case TransportGRPC:
+		if len(address) == 0 {
+			writeLog("ERROR: kuberhealthy gRPC reporting address from environment variable", external.KHReportingGRPCAddress, "was blank")
+			return "", false, fmt.Errorf("fetched %s environment variable but it was blank", external.KHReportingGRPCAddress)
+		}
+		return address, true, nil
+	case TransportAuto:
+		return address, len(address) > 0, nil
+	default:
+		return "", false, fmt.Errorf("unknown report transport %q", ReportTransport)
+	}
+}
+
+// grpcTransportCredentials returns the credentials that the connection to the gRPC reporting API is made with.
+// Kuberhealthy serves gRPC over TLS whenever it serves its reporting URL over https.
+func grpcTransportCredentials(tlsConfig *tls.Config) credentials.TransportCredentials {
+	if tlsConfig != nil {
+		return credentials.NewTLS(tlsConfig)
+	}
+	if strings.HasPrefix(os.Getenv(external.KHReportingURL), "https://") {
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	return insecure.NewCredentials()
+}
+
+// sendGRPCReport sends a report to the gRPC reporting API, retrying with exponential backoff and jitter.  Reports
+// with errors larger than ReportChunkBytes are streamed in chunks.
+func sendGRPCReport(address string, s status.Report, uuid string, token string, tlsConfig *tls.Config) error {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(grpcTransportCredentials(tlsConfig)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to kuberhealthy grpc reporting address %s: %w", address, err)
+	}
+	defer conn.Close()
+	client := reportpb.NewReportServiceClient(conn)
+
+	chunks := chunkErrors(s.Errors, ReportChunkBytes)
+	err = backoff.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), ReportTimeout)
+		defer cancel()
+		ctx = metadata.AppendToOutgoingContext(ctx, "kh-run-uuid", uuid)
+		if len(token) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+
+		if len(chunks) > 1 {
+			writeLog("DEBUG: Streaming report to kuberhealthy in ", len(chunks), " chunks")
+			return grpcReportError(uuid, streamReport(ctx, client, s.OK, chunks))
+		}
+		writeLog("DEBUG: Sending report to kuberhealthy over gRPC")
+		_, err := client.Report(ctx, &reportpb.ReportRequest{Ok: s.OK, Errors: s.Errors})
+		return grpcReportError(uuid, err)
+	}, newReportBackOff())
+	if err != nil {
+		writeLog("ERROR: got an error sending report to kuberhealthy over gRPC:", err)
+		return fmt.Errorf("failed to send report to kuberhealthy grpc reporting address: %w", err)
+	}
+
+	writeLog("INFO: Got a good response from kuberhealthy gRPC reporting address:", address)
+	return nil
+}
+
+// streamReport makes a single attempt at streaming a report in chunks
+func streamReport(ctx context.Context, client reportpb.ReportServiceClient, ok bool, chunks [][]string) error {
+	stream, err := client.StreamReport(ctx)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		err = stream.Send(&reportpb.ReportChunk{Ok: ok, Errors: chunk})
+		if errors.Is(err, io.EOF) {
+			// the server ended the stream early. its reason is returned by CloseAndRecv.
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// grpcReportError returns reports that Kuberhealthy rejected as a permanent ErrReportRejected error so that they
+// are not retried.  All other errors are retried.
+func grpcReportError(uuid string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch grpcstatus.Code(err) {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied:
+		writeLog("ERROR: kuberhealthy rejected the report for run UUID ", uuid, ": ", err)
+		return backoff.Permanent(fmt.Errorf("%w for run uuid %s: %s", ErrReportRejected, uuid, err))
+	default:
+		writeLog("ERROR: got an error from kuberhealthy gRPC reporting address:", err)
+		return err
+	}
+}
+
+// chunkErrors splits errors into chunks whose errors add up to at most maxBytes.  Errors that are larger than
+// maxBytes on their own are sent in a chunk of their own.
+func chunkErrors(errs []string, maxBytes int) [][]string {
+	var chunks [][]string
+	var chunk []string
+	var chunkBytes int
+	for _, e := range errs {
+		if len(chunk) > 0 && chunkBytes+len(e) > maxBytes {
+			chunks = append(chunks, chunk)
+			chunk = nil
+			chunkBytes = 0
+		}
+		chunk = append(chunk, e)
+		chunkBytes += len(e)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
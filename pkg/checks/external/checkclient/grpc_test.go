@@ -0,0 +1,182 @@
+package checkclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/reportpb"
+)
+
+// fakeReportServer records the reports sent to the gRPC reporting API
+type fakeReportServer struct {
+	reportpb.UnimplementedReportServiceServer
+	runUUIDs []string
+	chunks   int
+	ok       bool
+	errors   []string
+	reject   bool
+	progress *reportpb.ProgressRequest
+}
+
+func (f *fakeReportServer) Report(ctx context.Context, req *reportpb.ReportRequest) (*reportpb.ReportResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.runUUIDs = append(f.runUUIDs, md.Get("kh-run-uuid")...)
+	if f.reject {
+		return nil, grpcstatus.Error(codes.Unauthenticated, "report has no bearer token")
+	}
+	f.ok = req.GetOk()
+	f.errors = req.GetErrors()
+	return &reportpb.ReportResponse{}, nil
+}
+
+func (f *fakeReportServer) StreamReport(stream reportpb.ReportService_StreamReportServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	f.runUUIDs = append(f.runUUIDs, md.Get("kh-run-uuid")...)
+	f.errors = nil
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&reportpb.ReportResponse{})
+		}
+		if err != nil {
+			return err
+		}
+		f.chunks++
+		f.ok = chunk.GetOk()
+		f.errors = append(f.errors, chunk.GetErrors()...)
+	}
+}
+
+func (f *fakeReportServer) ReportProgress(ctx context.Context, req *reportpb.ProgressRequest) (*reportpb.ProgressResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.runUUIDs = append(f.runUUIDs, md.Get("kh-run-uuid")...)
+	f.progress = req
+	return &reportpb.ProgressResponse{}, nil
+}
+
+// TestSendGRPCReport ensures that reports are sent over gRPC when Kuberhealthy serves it, that large reports are
+// streamed in chunks and that rejected reports are not retried
+func TestSendGRPCReport(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingGRPCAddress)
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+	defer func(interval time.Duration) { ReportInitialInterval = interval }(ReportInitialInterval)
+	ReportInitialInterval = time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	fake := &fakeReportServer{}
+	server := grpc.NewServer()
+	reportpb.RegisterReportServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	os.Setenv(external.KHReportingGRPCAddress, listener.Addr().String())
+	os.Setenv(external.KHReportingURL, "http://kuberhealthy.kuberhealthy.svc.cluster.local/externalCheckStatus")
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	err = ReportFailure([]string{"target is down"})
+	if err != nil {
+		t.Fatal("Expected the report to succeed but got:", err)
+	}
+	if fake.ok || len(fake.errors) != 1 || fake.errors[0] != "target is down" || fake.chunks != 0 {
+		t.Fatalf("Unexpected report received: ok %t errors %q chunks %d", fake.ok, fake.errors, fake.chunks)
+	}
+	if len(fake.runUUIDs) != 1 || fake.runUUIDs[0] != "some-random-uuid" {
+		t.Fatalf("Expected the run UUID in the report metadata but got %q", fake.runUUIDs)
+	}
+
+	// large reports are streamed in chunks
+	defer func(chunkBytes int) { ReportChunkBytes = chunkBytes }(ReportChunkBytes)
+	ReportChunkBytes = 1024
+	largeErrors := []string{strings.Repeat("a", 1000), strings.Repeat("b", 1000), strings.Repeat("c", 2000)}
+	err = ReportFailure(largeErrors)
+	if err != nil {
+		t.Fatal("Expected the streamed report to succeed but got:", err)
+	}
+	if fake.chunks != 3 || len(fake.errors) != 3 || fake.errors[2] != largeErrors[2] {
+		t.Fatalf("Expected the report to be streamed in 3 chunks but got %d chunks with %d errors", fake.chunks, len(fake.errors))
+	}
+
+	// rejected reports are not retried
+	fake.reject = true
+	fake.runUUIDs = nil
+	err = ReportSuccess()
+	if !errors.Is(err, ErrReportRejected) {
+		t.Fatalf("Expected the report to be rejected but got %v", err)
+	}
+	if len(fake.runUUIDs) != 1 {
+		t.Fatalf("Expected a rejected report to be sent once but it was sent %d times", len(fake.runUUIDs))
+	}
+
+	// http can still be selected when kuberhealthy serves grpc
+	defer func(transport Transport) { ReportTransport = transport }(ReportTransport)
+	ReportTransport = TransportHTTP
+	_, useGRPC, err := getKuberhealthyGRPCAddress()
+	if err != nil || useGRPC {
+		t.Fatalf("Expected the http transport to be used but got %t and %v", useGRPC, err)
+	}
+}
+
+// TestGetKuberhealthyGRPCAddress ensures that the gRPC transport is only used when it can be
+func TestGetKuberhealthyGRPCAddress(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingGRPCAddress)
+	defer func(transport Transport) { ReportTransport = transport }(ReportTransport)
+
+	testCases := []struct {
+		transport   Transport
+		address     string
+		expectGRPC  bool
+		expectError bool
+	}{
+		{transport: TransportAuto, address: "", expectGRPC: false},
+		{transport: TransportAuto, address: "kuberhealthy.kuberhealthy.svc.cluster.local:9090", expectGRPC: true},
+		{transport: TransportHTTP, address: "kuberhealthy.kuberhealthy.svc.cluster.local:9090", expectGRPC: false},
+		{transport: TransportGRPC, address: "", expectError: true},
+		{transport: TransportGRPC, address: "kuberhealthy.kuberhealthy.svc.cluster.local:9090", expectGRPC: true},
+		{transport: "carrier-pigeon", address: "", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		ReportTransport = tc.transport
+		os.Setenv(external.KHReportingGRPCAddress, tc.address)
+		_, useGRPC, err := getKuberhealthyGRPCAddress()
+		if tc.expectError != (err != nil) {
+			t.Fatalf("%q with address %q: expected error %t but got %v", tc.transport, tc.address, tc.expectError, err)
+		}
+		if useGRPC != tc.expectGRPC {
+			t.Fatalf("%q with address %q: expected grpc %t but got %t", tc.transport, tc.address, tc.expectGRPC, useGRPC)
+		}
+	}
+}
+
+// TestChunkErrors ensures that errors are split into chunks of at most the maximum size
+func TestChunkErrors(t *testing.T) {
+	chunks := chunkErrors([]string{"aaaa", "bbbb", "cc", "dddddddddd", "e"}, 8)
+	expected := [][]string{{"aaaa", "bbbb"}, {"cc"}, {"dddddddddd"}, {"e"}}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks but got %q", len(expected), chunks)
+	}
+	for i := range expected {
+		if strings.Join(chunks[i], ",") != strings.Join(expected[i], ",") {
+			t.Fatalf("Expected chunk %d to be %q but got %q", i, expected[i], chunks[i])
+		}
+	}
+
+	if len(chunkErrors(nil, 8)) != 0 {
+		t.Fatal("Expected no chunks without errors")
+	}
+}
@@ -6,12 +6,15 @@ package checkclient
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -23,10 +26,34 @@ import (
 var (
 	// Debug can be used to enable output logging from the checkClient
 	Debug bool
+
+	// ReportMaxRetries is the maximum number of times sending a report is retried.  Zero retries until
+	// ReportMaxElapsedTime has passed.
+	ReportMaxRetries uint64
+
+	// ReportMaxElapsedTime is how long sending a report is retried for before giving up
+	ReportMaxElapsedTime = time.Second * 30
+
+	// ReportInitialInterval is the time waited before the first retry of a report.  The wait grows exponentially
+	// with random jitter for every following retry.
+	ReportInitialInterval = time.Millisecond * 500
+
+	// ReportTimeout is the time allowed for a single attempt at sending a report
+	ReportTimeout = time.Second * 10
+
+	// ReportTransport selects whether reports are sent over HTTP or gRPC.  By default, reports are sent over gRPC
+	// when Kuberhealthy serves its gRPC reporting API and over HTTP otherwise.
+	ReportTransport = TransportAuto
+
+	// ReportChunkBytes is the maximum size of the errors sent in a single message over gRPC.  Reports with larger
+	// errors are streamed to Kuberhealthy in chunks.
+	ReportChunkBytes = 1024 * 1024
 )
 
-// Use exponential backoff for retries
-const maxElapsedTime = time.Second * 30
+// ErrReportRejected is returned when Kuberhealthy rejects a report, usually because the run UUID of the check is
+// no longer the current run of the check or the report could not be authenticated.  Rejected reports are not
+// retried.
+var ErrReportRejected = errors.New("kuberhealthy rejected the report")
 
 // ReportSuccess reports a successful check run to the Kuberhealthy service. We
 // do not return an error here because failures will cause the managing
@@ -56,6 +83,17 @@ func ReportFailure(errorMessages []string) error {
 	return sendReport(newReport)
 }
 
+// ReportFailureAndExit logs and reports an error to Kuberhealthy and then exits the program.  If the error can not
+// be reported, the program exits with the error that reporting it failed with instead.
+func ReportFailureAndExit(err error) {
+	log.Println(err)
+	reportErr := ReportFailure([]string{err.Error()})
+	if reportErr != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", reportErr.Error())
+	}
+	os.Exit(0)
+}
+
 // writeLog writes a log entry if debugging is enabled
 func writeLog(i ...interface{}) {
 	if Debug {
@@ -70,6 +108,21 @@ func sendReport(s status.Report) error {
 	writeLog("DEBUG: Sending report with error length of:", len(s.Errors))
 	writeLog("DEBUG: Sending report with ok state of:", s.OK)
 
+	uuid, token, tlsConfig, err := getReportCredentials()
+	if err != nil {
+		return err
+	}
+
+	// send the report over gRPC instead of HTTP if it is selected or Kuberhealthy serves it
+	address, useGRPC, err := getKuberhealthyGRPCAddress()
+	if err != nil {
+		return fmt.Errorf("failed to fetch the kuberhealthy grpc address: %w", err)
+	}
+	if useGRPC {
+		writeLog("INFO: Using kuberhealthy gRPC reporting address: ", address)
+		return sendGRPCReport(address, s, uuid, token, tlsConfig)
+	}
+
 	// marshal the request body
 	b, err := json.Marshal(s)
 	if err != nil {
@@ -84,50 +137,113 @@ func sendReport(s status.Report) error {
 	}
 	writeLog("INFO: Using kuberhealthy reporting URL: ", url)
 
+	// send to the server, retrying with exponential backoff and jitter
+	client := newReportClient()
+	if tlsConfig != nil {
+		client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	}
+	err = backoff.Retry(func() error {
+		writeLog("DEBUG: Making POST request to kuberhealthy:")
+		return postReport(client, url, uuid, token, b)
+	}, newReportBackOff())
+	if err != nil {
+		writeLog("ERROR: got an error sending POST to kuberhealthy:", err)
+		return fmt.Errorf("bad POST request to kuberhealthy status reporting url: %w", err)
+	}
+
+	writeLog("INFO: Got a good http return status code from kuberhealthy URL:", url)
+
+	return nil
+}
+
+// getReportCredentials fetches the run UUID, service account token and client certificate that reports and
+// progress updates are authenticated with
+func getReportCredentials() (string, string, *tls.Config, error) {
+
 	// fetch the kh run UUID
 	uuid, err := getKuberhealthyRunUUID()
 	if err != nil {
-		return fmt.Errorf("failed to fetch the kuberhealthy run uuid: %w", err)
+		return "", "", nil, fmt.Errorf("failed to fetch the kuberhealthy run uuid: %w", err)
 	}
 	writeLog("INFO: Using kuberhealthy run UUID: ", uuid)
 
-	// create the Kuberhealthy post request with the kh-run-uuid header
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(b))
+	// authenticate the report with the service account token of this pod if Kuberhealthy provided one
+	token, err := getReportToken()
 	if err != nil {
-		return fmt.Errorf("error creating http request: %w", err)
+		return "", "", nil, fmt.Errorf("failed to read the kuberhealthy report token: %w", err)
+	}
+	if len(token) > 0 {
+		writeLog("INFO: Authenticating report with the token from ", os.Getenv(external.KHReportTokenFile))
+	}
+
+	// present the client certificate of this pod if Kuberhealthy issued one for mutual TLS
+	tlsConfig, err := reportTLSConfig()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to load the kuberhealthy client certificate: %w", err)
+	}
+	if tlsConfig != nil {
+		writeLog("INFO: Authenticating report with the client certificate from ", os.Getenv(external.KHClientCertFile))
+	}
+
+	return uuid, token, tlsConfig, nil
+}
+
+// postReport makes a single attempt at sending a report.  Reports that Kuberhealthy rejects are returned as a
+// permanent ErrReportRejected error so that they are not retried.
+func postReport(client *http.Client, url string, uuid string, token string, body []byte) error {
+
+	// create the Kuberhealthy post request with the kh-run-uuid header.  the request is created for every attempt
+	// so that retries send the whole body.
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(fmt.Errorf("error creating http request: %w", err))
 	}
 	req.Header.Set("kh-run-uuid", uuid)
 	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
-	exponentialBackOff := backoff.NewExponentialBackOff()
-	exponentialBackOff.MaxElapsedTime = maxElapsedTime
+	// retry on any errors
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	client := &http.Client{}
-	// send to the server
-	var resp *http.Response
-	err = backoff.Retry(func() error {
-		var err error
-		writeLog("DEBUG: Making POST request to kuberhealthy:")
-		resp, err = client.Do(req)
-		// retry on any errors
-		if err != nil {
-			return err
-		}
-		// retry on status codes that do not return a 200 or 400
-		if !(resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusBadRequest) {
-			writeLog("ERROR: got a bad status code from kuberhealthy:", resp.StatusCode, resp.Status)
-			return fmt.Errorf("bad status code from kuberhealthy status reporting url: [%d] %s ", resp.StatusCode, resp.Status)
-		}
+	switch {
+	case resp.StatusCode == http.StatusOK:
 		return nil
-	}, exponentialBackOff)
-	if err != nil {
-		writeLog("ERROR: got an error sending POST to kuberhealthy:", err)
-		return fmt.Errorf("bad POST request to kuberhealthy status reporting url: %w", err)
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		writeLog("ERROR: kuberhealthy rejected the report for run UUID ", uuid, ": ", resp.Status)
+		return backoff.Permanent(fmt.Errorf("%w for run uuid %s: [%d] %s", ErrReportRejected, uuid, resp.StatusCode, resp.Status))
+	default:
+		// retry on all other status codes
+		writeLog("ERROR: got a bad status code from kuberhealthy:", resp.StatusCode, resp.Status)
+		return fmt.Errorf("bad status code from kuberhealthy status reporting url: [%d] %s ", resp.StatusCode, resp.Status)
 	}
+}
 
-	writeLog("INFO: Got a good http return status code from kuberhealthy URL:", url)
+// newReportClient creates the http client that reports are sent with.  Proxies are used as set in the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func newReportClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return &http.Client{
+		Transport: transport,
+		Timeout:   ReportTimeout,
+	}
+}
 
-	return err
+// newReportBackOff creates the backoff that sending a report is retried with
+func newReportBackOff() backoff.BackOff {
+	exponentialBackOff := backoff.NewExponentialBackOff()
+	exponentialBackOff.InitialInterval = ReportInitialInterval
+	exponentialBackOff.MaxElapsedTime = ReportMaxElapsedTime
+	if ReportMaxRetries > 0 {
+		return backoff.WithMaxRetries(exponentialBackOff, ReportMaxRetries)
+	}
+	return exponentialBackOff
 }
 
 // getKuberhealthyURL fetches the URL that we need to send our external checker
@@ -160,6 +276,22 @@ func getKuberhealthyRunUUID() (string, error) {
 	return khRunUUID, nil
 }
 
+// getReportToken reads the service account token that reports are authenticated with from the file named in the
+// KH_REPORT_TOKEN_FILE environment variable.  A blank token is returned if Kuberhealthy did not provide one.
+func getReportToken() (string, error) {
+	tokenFile := os.Getenv(external.KHReportTokenFile)
+	if len(tokenFile) == 0 {
+		return "", nil
+	}
+
+	b, err := os.ReadFile(tokenFile)
+	if err != nil {
+		writeLog("ERROR: unable to read kuberhealthy report token file", tokenFile+": "+err.Error())
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
 // GetDeadline fetches the KH_CHECK_RUN_DEADLINE environment variable and returns it.
 // Checks are given up to the deadline to complete their check runs.
 func GetDeadline() (time.Time, error) {
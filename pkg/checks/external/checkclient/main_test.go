@@ -1,7 +1,12 @@
 package checkclient
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -103,4 +108,104 @@ func TestGetDeadline(t *testing.T) {
 	}
 }
 
-//TODO: TestSendReport
+// TestSendReport ensures that reports are retried with their whole body and that rejected reports are not retried
+func TestSendReport(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+	defer func(interval time.Duration) { ReportInitialInterval = interval }(ReportInitialInterval)
+	ReportInitialInterval = time.Millisecond
+
+	statusCodes := []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK}
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("Failed to read report body:", err)
+		}
+		bodies = append(bodies, string(b))
+		w.WriteHeader(statusCodes[len(bodies)-1])
+	}))
+	defer server.Close()
+
+	os.Setenv(external.KHReportingURL, server.URL)
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	err := ReportFailure([]string{"target is down"})
+	if err != nil {
+		t.Fatal("Expected the report to succeed after retries but got:", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("Expected the report to be sent 3 times but it was sent %d times", len(bodies))
+	}
+	for _, b := range bodies {
+		if !strings.Contains(b, "target is down") {
+			t.Fatalf("Expected every attempt to send the whole report but got %q", b)
+		}
+	}
+
+	// rejected reports are not retried
+	bodies = nil
+	statusCodes = []int{http.StatusBadRequest, http.StatusOK}
+	err = ReportSuccess()
+	if !errors.Is(err, ErrReportRejected) {
+		t.Fatalf("Expected the report to be rejected but got %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("Expected a rejected report to be sent once but it was sent %d times", len(bodies))
+	}
+
+	// retries stop after the maximum number of retries
+	defer func(retries uint64) { ReportMaxRetries = retries }(ReportMaxRetries)
+	ReportMaxRetries = 1
+	bodies = nil
+	statusCodes = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK}
+	err = ReportSuccess()
+	if err == nil || errors.Is(err, ErrReportRejected) {
+		t.Fatalf("Expected the report to fail after its retries but got %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("Expected the report to be sent twice but it was sent %d times", len(bodies))
+	}
+}
+
+// TestNewReportClient ensures that reports are sent through proxies set in the environment
+func TestNewReportClient(t *testing.T) {
+	client := newReportClient()
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatal("Expected the report client to use proxies from the environment")
+	}
+	if client.Timeout != ReportTimeout {
+		t.Fatalf("Expected the report client to time out after %s but got %s", ReportTimeout, client.Timeout)
+	}
+}
+
+// TestGetReportToken ensures that the report token is read from the file in the KH_REPORT_TOKEN_FILE env var
+func TestGetReportToken(t *testing.T) {
+	defer os.Unsetenv(external.KHReportTokenFile)
+
+	os.Setenv(external.KHReportTokenFile, "")
+	token, err := getReportToken()
+	if err != nil || len(token) > 0 {
+		t.Fatalf("Expected no token and no error without a token file but got `%s` and %v", token, err)
+	}
+
+	tokenFile := t.TempDir() + "/token"
+	err = os.WriteFile(tokenFile, []byte("eyJhbGciOi.test.token\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write token file:", err)
+	}
+	os.Setenv(external.KHReportTokenFile, tokenFile)
+	token, err = getReportToken()
+	if err != nil {
+		t.Fatal("Failed to read token file:", err)
+	}
+	if token != "eyJhbGciOi.test.token" {
+		t.Fatalf("getReportToken resulted in `%s` but expected the trimmed token", token)
+	}
+
+	os.Setenv(external.KHReportTokenFile, tokenFile+".missing")
+	_, err = getReportToken()
+	if err == nil {
+		t.Fatal("Expected an error when the token file does not exist")
+	}
+}
@@ -0,0 +1,123 @@
+package checkclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cenkalti/backoff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/reportpb"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// ReportProgress sends an interim status update to Kuberhealthy while a check run is in flight.  The update is
+// shown on the status page until the result of the run is reported with ReportSuccess or ReportFailure.  Step is
+// the number of steps that are complete, and totalSteps may be zero if the number of steps is not known.  Failing
+// to send a progress update does not fail the run, so callers may ignore the returned error.
+func ReportProgress(step int, totalSteps int, message string) error {
+	writeLog("DEBUG: Reporting progress of step ", step, "/", totalSteps, ": ", message)
+
+	progress := status.Progress{
+		Message:    message,
+		Step:       step,
+		TotalSteps: totalSteps,
+	}
+
+	uuid, token, tlsConfig, err := getReportCredentials()
+	if err != nil {
+		return err
+	}
+
+	// send the update over gRPC instead of HTTP if it is selected or Kuberhealthy serves it
+	address, useGRPC, err := getKuberhealthyGRPCAddress()
+	if err != nil {
+		return fmt.Errorf("failed to fetch the kuberhealthy grpc address: %w", err)
+	}
+	if useGRPC {
+		writeLog("INFO: Using kuberhealthy gRPC reporting address: ", address)
+		return sendGRPCProgress(address, progress, uuid, token, tlsConfig)
+	}
+
+	b, err := json.Marshal(progress)
+	if err != nil {
+		writeLog("ERROR: Failed to marshal progress JSON:", err)
+		return fmt.Errorf("error mashaling progress json: %w", err)
+	}
+
+	progressURL, err := getKuberhealthyProgressURL()
+	if err != nil {
+		return fmt.Errorf("failed to fetch the kuberhealthy progress url: %w", err)
+	}
+	writeLog("INFO: Using kuberhealthy progress URL: ", progressURL)
+
+	client := newReportClient()
+	if tlsConfig != nil {
+		client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	}
+	err = backoff.Retry(func() error {
+		writeLog("DEBUG: Making progress POST request to kuberhealthy:")
+		return postReport(client, progressURL, uuid, token, b)
+	}, newReportBackOff())
+	if err != nil {
+		writeLog("ERROR: got an error sending progress POST to kuberhealthy:", err)
+		return fmt.Errorf("bad POST request to kuberhealthy progress url: %w", err)
+	}
+
+	writeLog("INFO: Got a good http return status code from kuberhealthy progress URL:", progressURL)
+	return nil
+}
+
+// getKuberhealthyProgressURL derives the URL that progress updates are posted to from the reporting URL.  The
+// /externalCheckProgress endpoint is served beside the /externalCheckStatus endpoint.
+func getKuberhealthyProgressURL() (string, error) {
+	reportingURL, err := getKuberhealthyURL()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(reportingURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kuberhealthy reporting url %s: %w", reportingURL, err)
+	}
+	return u.ResolveReference(&url.URL{Path: "externalCheckProgress"}).String(), nil
+}
+
+// sendGRPCProgress sends a progress update to the gRPC reporting API, retrying with exponential backoff and jitter
+func sendGRPCProgress(address string, progress status.Progress, uuid string, token string, tlsConfig *tls.Config) error {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(grpcTransportCredentials(tlsConfig)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to kuberhealthy grpc reporting address %s: %w", address, err)
+	}
+	defer conn.Close()
+	client := reportpb.NewReportServiceClient(conn)
+
+	err = backoff.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), ReportTimeout)
+		defer cancel()
+		ctx = metadata.AppendToOutgoingContext(ctx, "kh-run-uuid", uuid)
+		if len(token) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+
+		writeLog("DEBUG: Sending progress to kuberhealthy over gRPC")
+		_, err := client.ReportProgress(ctx, &reportpb.ProgressRequest{
+			Message:    progress.Message,
+			Step:       int32(progress.Step),
+			TotalSteps: int32(progress.TotalSteps),
+		})
+		return grpcReportError(uuid, err)
+	}, newReportBackOff())
+	if err != nil {
+		writeLog("ERROR: got an error sending progress to kuberhealthy over gRPC:", err)
+		return fmt.Errorf("failed to send progress to kuberhealthy grpc reporting address: %w", err)
+	}
+
+	writeLog("INFO: Got a good response from kuberhealthy gRPC reporting address:", address)
+	return nil
+}
@@ -0,0 +1,111 @@
+package checkclient
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/reportpb"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// TestGetKuberhealthyProgressURL ensures that progress updates are sent beside the reporting URL
+func TestGetKuberhealthyProgressURL(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingURL)
+
+	testCases := []struct {
+		reportingURL string
+		expected     string
+	}{
+		{"http://kuberhealthy.kuberhealthy.svc.cluster.local/externalCheckStatus", "http://kuberhealthy.kuberhealthy.svc.cluster.local/externalCheckProgress"},
+		{"https://kuberhealthy.kuberhealthy.svc.cluster.local:8443/externalCheckStatus", "https://kuberhealthy.kuberhealthy.svc.cluster.local:8443/externalCheckProgress"},
+		{"http://proxy.example.com/kuberhealthy/externalCheckStatus", "http://proxy.example.com/kuberhealthy/externalCheckProgress"},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv(external.KHReportingURL, tc.reportingURL)
+		progressURL, err := getKuberhealthyProgressURL()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.reportingURL, err)
+		}
+		if progressURL != tc.expected {
+			t.Fatalf("%s: expected progress url %s but got %s", tc.reportingURL, tc.expected, progressURL)
+		}
+	}
+
+	os.Setenv(external.KHReportingURL, "")
+	_, err := getKuberhealthyProgressURL()
+	if err == nil {
+		t.Fatal("Expected an error without a reporting url")
+	}
+}
+
+// TestReportProgress ensures that progress updates are posted to the progress endpoint with the run UUID
+func TestReportProgress(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+
+	var paths []string
+	var runUUIDs []string
+	var received status.Progress
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		runUUIDs = append(runUUIDs, r.Header.Get("kh-run-uuid"))
+		err := json.NewDecoder(r.Body).Decode(&received)
+		if err != nil {
+			t.Fatal("Failed to decode progress body:", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv(external.KHReportingURL, server.URL+"/externalCheckStatus")
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	err := ReportProgress(3, 7, "created test deployment")
+	if err != nil {
+		t.Fatal("Expected the progress update to succeed but got:", err)
+	}
+	if len(paths) != 1 || paths[0] != "/externalCheckProgress" || runUUIDs[0] != "some-random-uuid" {
+		t.Fatalf("Expected one progress update to /externalCheckProgress but got paths %q with run UUIDs %q", paths, runUUIDs)
+	}
+	if received.Step != 3 || received.TotalSteps != 7 || received.Message != "created test deployment" {
+		t.Fatalf("Unexpected progress received: %+v", received)
+	}
+}
+
+// TestReportProgressGRPC ensures that progress updates are sent over gRPC when Kuberhealthy serves it
+func TestReportProgressGRPC(t *testing.T) {
+	defer os.Unsetenv(external.KHReportingGRPCAddress)
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	fake := &fakeReportServer{}
+	server := grpc.NewServer()
+	reportpb.RegisterReportServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	os.Setenv(external.KHReportingGRPCAddress, listener.Addr().String())
+	os.Setenv(external.KHReportingURL, "http://kuberhealthy.kuberhealthy.svc.cluster.local/externalCheckStatus")
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	err = ReportProgress(2, 0, "waiting for pods")
+	if err != nil {
+		t.Fatal("Expected the progress update to succeed but got:", err)
+	}
+	if fake.progress == nil || fake.progress.GetStep() != 2 || fake.progress.GetTotalSteps() != 0 || fake.progress.GetMessage() != "waiting for pods" {
+		t.Fatalf("Unexpected progress received: %v", fake.progress)
+	}
+	if len(fake.runUUIDs) != 1 || fake.runUUIDs[0] != "some-random-uuid" {
+		t.Fatalf("Expected the run UUID in the progress metadata but got %q", fake.runUUIDs)
+	}
+}
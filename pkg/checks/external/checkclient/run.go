@@ -0,0 +1,46 @@
+package checkclient
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// exit ends the check process.  It is replaced in tests.
+var exit = os.Exit
+
+// Run runs the supplied check function and reports its result to Kuberhealthy.  A nil error is reported as a
+// success and any other error as a failure.  If the check panics, the panic and its stack trace are reported as a
+// failure and the process exits with a nonzero exit code, so that the run fails right away with a useful error
+// instead of timing out.  The process also exits nonzero if the result can not be reported.
+func Run(check func() error) {
+	panicked, err := runRecovered(check)
+
+	if err == nil {
+		err = ReportSuccess()
+	} else {
+		writeLog("ERROR: check failed: ", err)
+		err = ReportFailure([]string{err.Error()})
+	}
+	if err != nil {
+		writeLog("ERROR: failed to report check result: ", err)
+		exit(1)
+		return
+	}
+
+	if panicked {
+		exit(1)
+	}
+}
+
+// runRecovered runs the supplied check function and turns a panic into an error holding the panic and its stack
+// trace
+func runRecovered(check func() error) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v\n%s", r, debug.Stack())
+			panicked = true
+		}
+	}()
+	return false, check()
+}
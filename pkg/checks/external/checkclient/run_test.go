@@ -0,0 +1,84 @@
+package checkclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// TestRun ensures that check results and panics are reported and that panics exit nonzero
+func TestRun(t *testing.T) {
+	var reports []status.Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report status.Report
+		err := json.NewDecoder(r.Body).Decode(&report)
+		if err != nil {
+			t.Fatal("Failed to decode report:", err)
+		}
+		reports = append(reports, report)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv(external.KHReportingURL, server.URL)
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+
+	var testCases = []struct {
+		check        func() error
+		expectOK     bool
+		expectError  string
+		expectedExit int
+	}{
+		{func() error { return nil }, true, "", 0},
+		{func() error { return errors.New("target is down") }, false, "target is down", 0},
+		{func() error { panic("nil map") }, false, "check panicked: nil map", 1},
+	}
+
+	for _, tc := range testCases {
+		reports = nil
+		exitCode = 0
+		Run(tc.check)
+
+		if len(reports) != 1 {
+			t.Fatalf("Expected a single report but got %d", len(reports))
+		}
+		if reports[0].OK != tc.expectOK {
+			t.Fatalf("Expected a report with OK %t but got %+v", tc.expectOK, reports[0])
+		}
+		if len(tc.expectError) > 0 && (len(reports[0].Errors) != 1 || !strings.HasPrefix(reports[0].Errors[0], tc.expectError)) {
+			t.Fatalf("Expected a report with the error %q but got %+v", tc.expectError, reports[0].Errors)
+		}
+		if exitCode != tc.expectedExit {
+			t.Fatalf("Expected exit code %d but got %d", tc.expectedExit, exitCode)
+		}
+	}
+}
+
+// TestRunRecovered ensures that panics are turned into errors with a stack trace
+func TestRunRecovered(t *testing.T) {
+	panicked, err := runRecovered(func() error { panic("nil map") })
+	if !panicked || err == nil {
+		t.Fatal("Expected the panic to be recovered as an error")
+	}
+	if !strings.Contains(err.Error(), "runtime/debug.Stack") {
+		t.Fatalf("Expected the error to hold a stack trace but got %q", err.Error())
+	}
+
+	panicked, err = runRecovered(func() error { return nil })
+	if panicked || err != nil {
+		t.Fatalf("Expected no panic and no error but got %t and %v", panicked, err)
+	}
+}
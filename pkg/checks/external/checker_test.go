@@ -282,14 +282,14 @@ func TestSanityCheck(t *testing.T) {
 	}
 
 	// by default with the test checker, the sanity test should pass
-	err = c.sanityCheck()
+	err = c.sanityCheck(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// if we blank the namespace, it should fail
 	c.Namespace = ""
-	err = c.sanityCheck()
+	err = c.sanityCheck(context.Background())
 	if err == nil {
 		t.Fatal(err)
 	}
@@ -297,7 +297,7 @@ func TestSanityCheck(t *testing.T) {
 	// fix the namespace, then try blanking the PodName
 	c.Namespace = defaultNamespace
 	c.CheckName = ""
-	err = c.sanityCheck()
+	err = c.sanityCheck(context.Background())
 	if err == nil {
 		t.Fatal(err)
 	}
@@ -305,7 +305,7 @@ func TestSanityCheck(t *testing.T) {
 	// fix the pod name and try KubeClient
 	c.CheckName = "kuberhealthy"
 	c.KubeClient = nil
-	err = c.sanityCheck()
+	err = c.sanityCheck(context.Background())
 	if err == nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,305 @@
+package external
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KHClientCertFile is the environment variable that holds the path to the client certificate checker pods
+// authenticate their reports with when mutual TLS is enabled
+const KHClientCertFile = "KH_CLIENT_CERT_FILE"
+
+// KHClientKeyFile is the environment variable that holds the path to the key of the client certificate
+const KHClientKeyFile = "KH_CLIENT_KEY_FILE"
+
+// KHCACertFile is the environment variable that holds the path to the CA certificate checker pods verify the
+// reporting endpoint with when mutual TLS is enabled
+const KHCACertFile = "KH_CA_CERT_FILE"
+
+// clientCertSecretNameSuffix is appended to the check name to name the secret that holds the client certificate
+// of its checker pods
+const clientCertSecretNameSuffix = "-kh-client-cert"
+
+// clientCertVolumeName is the name of the volume that holds the client certificate of checker pods
+const clientCertVolumeName = "kh-client-cert"
+
+// clientCertMountPath is the directory that the client certificate volume is mounted to in checker containers
+const clientCertMountPath = "/var/run/secrets/kuberhealthy-client-cert"
+
+// clientCertIdentityScheme is the scheme of the URI SAN that identifies the check and run of a client certificate
+const clientCertIdentityScheme = "kuberhealthy"
+
+// clientCertValidityMargin is how long client certificates stay valid after the deadline of their run, and how
+// far back they are valid from to allow for clock skew
+const clientCertValidityMargin = time.Minute * 5
+
+// ClientCertSettings holds settings for mutual TLS between checker pods and the reporting endpoint.  When enabled,
+// a short-lived client certificate signed by the CA is issued for every run and mounted into its checker pods, and
+// the reporting endpoint only accepts reports that are authenticated with the certificate of their run.
+type ClientCertSettings struct {
+	Enabled    bool   `yaml:"enabled"`              // issue client certificates to checker pods and require them for reports
+	CACertFile string `yaml:"caCertFile,omitempty"` // the CA certificate that client certificates are issued from and verified with
+	CAKeyFile  string `yaml:"caKeyFile,omitempty"`  // the key of the CA certificate
+}
+
+// ClientCertIssuer issues client certificates for checker pods from a CA on disk.  The CA is read again for every
+// certificate, so that a CA mounted from a secret can be rotated without restarting Kuberhealthy.
+type ClientCertIssuer struct {
+	caCertFile string
+	caKeyFile  string
+}
+
+// ClientCertIdentity is the check and run that a client certificate was issued to
+type ClientCertIdentity struct {
+	Namespace string
+	Name      string
+	RunUUID   string
+}
+
+// NewClientCertIssuer creates a ClientCertIssuer and ensures that its CA can be loaded
+func NewClientCertIssuer(settings ClientCertSettings) (*ClientCertIssuer, error) {
+	if len(settings.CACertFile) == 0 || len(settings.CAKeyFile) == 0 {
+		return nil, errors.New("client certificates require both a caCertFile and a caKeyFile")
+	}
+	issuer := &ClientCertIssuer{caCertFile: settings.CACertFile, caKeyFile: settings.CAKeyFile}
+	_, _, _, err := issuer.loadCA()
+	if err != nil {
+		return nil, err
+	}
+	return issuer, nil
+}
+
+// loadCA reads the CA certificate and key from disk
+func (i *ClientCertIssuer) loadCA() (*x509.Certificate, crypto.Signer, []byte, error) {
+	caPEM, err := os.ReadFile(i.caCertFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read CA certificate %s: %w", i.caCertFile, err)
+	}
+	keyPEM, err := os.ReadFile(i.caKeyFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read CA key %s: %w", i.caKeyFile, err)
+	}
+	pair, err := tls.X509KeyPair(caPEM, keyPEM)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load CA certificate %s and key %s: %w", i.caCertFile, i.caKeyFile, err)
+	}
+	caCert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse CA certificate %s: %w", i.caCertFile, err)
+	}
+	if !caCert.IsCA {
+		return nil, nil, nil, fmt.Errorf("certificate %s is not a CA certificate", i.caCertFile)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("CA key %s can not sign certificates", i.caKeyFile)
+	}
+	return caCert, signer, caPEM, nil
+}
+
+// CertPool returns a pool with the CA certificate to verify client certificates with
+func (i *ClientCertIssuer) CertPool() (*x509.CertPool, error) {
+	caCert, _, _, err := i.loadCA()
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, nil
+}
+
+// Issue creates a client certificate for a run of a check that is valid until shortly after the supplied deadline.
+// The PEM encoded certificate, key and CA certificate are returned.
+func (i *ClientCertIssuer) Issue(identity ClientCertIdentity, deadline time.Time) ([]byte, []byte, []byte, error) {
+	caCert, caKey, caPEM, err := i.loadCA()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate client key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate certificate serial number: %w", err)
+	}
+
+	notAfter := deadline.Add(clientCertValidityMargin)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: identity.Namespace + "/" + identity.Name},
+		URIs:         []*url.URL{identity.uri()},
+		NotBefore:    time.Now().Add(-clientCertValidityMargin),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to sign client certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode client key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, caPEM, nil
+}
+
+// uri returns the URI SAN that identifies the check and run of a client certificate
+func (id ClientCertIdentity) uri() *url.URL {
+	return &url.URL{Scheme: clientCertIdentityScheme, Host: id.Namespace, Path: "/" + id.Name + "/" + id.RunUUID}
+}
+
+// ClientCertIdentityFromCert returns the check and run that a verified client certificate was issued to
+func ClientCertIdentityFromCert(cert *x509.Certificate) (ClientCertIdentity, error) {
+	for _, u := range cert.URIs {
+		if u.Scheme != clientCertIdentityScheme {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		if len(parts) != 2 || len(u.Host) == 0 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return ClientCertIdentity{}, fmt.Errorf("malformed kuberhealthy identity %s in client certificate", u.String())
+		}
+		return ClientCertIdentity{Namespace: u.Host, Name: parts[0], RunUUID: parts[1]}, nil
+	}
+	return ClientCertIdentity{}, errors.New("client certificate has no kuberhealthy identity")
+}
+
+// clientCertSecretName returns the name of the secret that holds the client certificate of this check
+func (ext *Checker) clientCertSecretName() string {
+	return ext.CheckName + clientCertSecretNameSuffix
+}
+
+// ensureClientCertSecret issues a client certificate for the current run and stores it in the secret that is
+// mounted into the checker pods, if client certificates are enabled
+func (ext *Checker) ensureClientCertSecret(ctx context.Context, deadline time.Time) error {
+	if ext.ClientCertIssuer == nil {
+		return nil
+	}
+
+	identity := ClientCertIdentity{Namespace: ext.Namespace, Name: ext.CheckName, RunUUID: ext.currentCheckUUID}
+	certPEM, keyPEM, caPEM, err := ext.ClientCertIssuer.Issue(identity, deadline)
+	if err != nil {
+		return err
+	}
+
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ext.clientCertSecretName(),
+			Namespace: ext.Namespace,
+			Labels: map[string]string{
+				kuberhealthyCheckNameLabel: ext.CheckName,
+			},
+		},
+		Type: apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       certPEM,
+			apiv1.TLSPrivateKeyKey: keyPEM,
+			"ca.crt":               caPEM,
+		},
+	}
+
+	// the secret is garbage collected along with its khcheck or khjob
+	if ext.OwnerReference != nil {
+		secret.OwnerReferences = []metav1.OwnerReference{*ext.OwnerReference}
+	}
+
+	client := ext.KubeClient.CoreV1().Secrets(ext.Namespace)
+	existing, err := client.Get(ctx, secret.Name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		ext.log("creating client certificate secret", secret.Name)
+		_, err = client.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	ext.log("updating client certificate secret", secret.Name)
+	existing.Labels = secret.Labels
+	existing.OwnerReferences = secret.OwnerReferences
+	existing.Type = secret.Type
+	existing.Data = secret.Data
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteClientCertSecret removes the client certificate of the checker pods of this check, if one was issued
+func (ext *Checker) deleteClientCertSecret(ctx context.Context) {
+	if ext.ClientCertIssuer == nil {
+		return
+	}
+
+	name := ext.clientCertSecretName()
+	ext.log("deleting client certificate secret", name)
+	err := ext.KubeClient.CoreV1().Secrets(ext.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		ext.log("error deleting client certificate secret", name+":", err)
+	}
+}
+
+// addClientCertVolume mounts the client certificate secret of the check into every container of the supplied pod
+// spec and points the KH_CLIENT_CERT_FILE, KH_CLIENT_KEY_FILE and KH_CA_CERT_FILE environment variables at it.
+// Any volume, mount or environment variable with the same name is replaced.
+func addClientCertVolume(spec *apiv1.PodSpec, secretName string) {
+	volume := apiv1.Volume{
+		Name: clientCertVolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			Secret: &apiv1.SecretVolumeSource{SecretName: secretName},
+		},
+	}
+
+	var volumes []apiv1.Volume
+	for _, v := range spec.Volumes {
+		if v.Name != clientCertVolumeName {
+			volumes = append(volumes, v)
+		}
+	}
+	spec.Volumes = append(volumes, volume)
+
+	for i := range spec.Containers {
+		var mounts []apiv1.VolumeMount
+		for _, m := range spec.Containers[i].VolumeMounts {
+			if m.Name != clientCertVolumeName {
+				mounts = append(mounts, m)
+			}
+		}
+		spec.Containers[i].VolumeMounts = append(mounts, apiv1.VolumeMount{
+			Name:      clientCertVolumeName,
+			MountPath: clientCertMountPath,
+			ReadOnly:  true,
+		})
+
+		spec.Containers[i].Env = resetInjectedContainerEnvVars(spec.Containers[i].Env, []string{KHClientCertFile, KHClientKeyFile, KHCACertFile})
+		spec.Containers[i].Env = append(spec.Containers[i].Env,
+			apiv1.EnvVar{Name: KHClientCertFile, Value: clientCertMountPath + "/" + apiv1.TLSCertKey},
+			apiv1.EnvVar{Name: KHClientKeyFile, Value: clientCertMountPath + "/" + apiv1.TLSPrivateKeyKey},
+			apiv1.EnvVar{Name: KHCACertFile, Value: clientCertMountPath + "/ca.crt"},
+		)
+	}
+}
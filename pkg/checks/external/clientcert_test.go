@@ -0,0 +1,134 @@
+package external
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// writeTestCA writes a self signed CA certificate and key to a temporary directory and returns their paths
+func writeTestCA(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Failed to generate CA key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kuberhealthy test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour * 24),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Failed to create CA certificate:", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("Failed to encode CA key:", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "ca.crt")
+	keyFile := filepath.Join(dir, "ca.key")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal("Failed to write CA certificate:", err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatal("Failed to write CA key:", err)
+	}
+	return certFile, keyFile
+}
+
+// TestClientCertIssuer ensures that issued client certificates verify against the CA and carry the identity of
+// their check and run
+func TestClientCertIssuer(t *testing.T) {
+	caCertFile, caKeyFile := writeTestCA(t)
+	issuer, err := NewClientCertIssuer(ClientCertSettings{Enabled: true, CACertFile: caCertFile, CAKeyFile: caKeyFile})
+	if err != nil {
+		t.Fatal("Failed to create client certificate issuer:", err)
+	}
+
+	deadline := time.Now().Add(time.Minute * 10)
+	identity := ClientCertIdentity{Namespace: "kuberhealthy", Name: "deployment", RunUUID: "a718b969-421c-47a8-a379-106d234ad9d8"}
+	certPEM, keyPEM, caPEM, err := issuer.Issue(identity, deadline)
+	if err != nil {
+		t.Fatal("Failed to issue client certificate:", err)
+	}
+	if len(keyPEM) == 0 || len(caPEM) == 0 {
+		t.Fatal("Expected a key and CA certificate to be returned with the client certificate")
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal("Failed to parse issued client certificate:", err)
+	}
+	if cert.NotAfter.After(deadline.Add(clientCertValidityMargin)) {
+		t.Fatalf("Expected the client certificate to expire shortly after the run deadline but it expires at %s", cert.NotAfter)
+	}
+
+	pool, err := issuer.CertPool()
+	if err != nil {
+		t.Fatal("Failed to load CA cert pool:", err)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	if err != nil {
+		t.Fatal("Issued client certificate does not verify against the CA:", err)
+	}
+
+	parsed, err := ClientCertIdentityFromCert(cert)
+	if err != nil {
+		t.Fatal("Failed to read identity from client certificate:", err)
+	}
+	if parsed != identity {
+		t.Fatalf("Expected identity %+v but got %+v", identity, parsed)
+	}
+
+	_, err = ClientCertIdentityFromCert(&x509.Certificate{})
+	if err == nil {
+		t.Fatal("Expected an error for a certificate without a kuberhealthy identity")
+	}
+
+	_, err = NewClientCertIssuer(ClientCertSettings{Enabled: true, CACertFile: caCertFile})
+	if err == nil {
+		t.Fatal("Expected an error for an issuer without a CA key")
+	}
+}
+
+// TestAddClientCertVolume ensures that the client certificate is mounted into every checker container exactly once
+func TestAddClientCertVolume(t *testing.T) {
+	spec := apiv1.PodSpec{
+		Containers: []apiv1.Container{{Name: "main"}, {Name: "sidecar"}},
+	}
+
+	// adding the volume more than once must not duplicate it
+	addClientCertVolume(&spec, "deployment"+clientCertSecretNameSuffix)
+	addClientCertVolume(&spec, "deployment"+clientCertSecretNameSuffix)
+
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Secret == nil || spec.Volumes[0].Secret.SecretName != "deployment-kh-client-cert" {
+		t.Fatalf("Expected a single client certificate secret volume but got %+v", spec.Volumes)
+	}
+	for _, c := range spec.Containers {
+		if len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != clientCertMountPath {
+			t.Fatalf("Expected container %s to mount the client certificate once but got %+v", c.Name, c.VolumeMounts)
+		}
+		if len(c.Env) != 3 || c.Env[0].Name != KHClientCertFile || c.Env[1].Name != KHClientKeyFile || c.Env[2].Name != KHCACertFile {
+			t.Fatalf("Expected container %s to have the client certificate environment variables but got %+v", c.Name, c.Env)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package external
+
+import (
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// PodDefaults holds settings that are merged into the pod spec of every checker pod.  Settings that are already
+// present in the pod spec of a khcheck or khjob are left untouched.
+type PodDefaults struct {
+	Tolerations       []TolerationDefault `yaml:"tolerations,omitempty"`       // tolerations added to pods that do not already tolerate the same key and effect
+	NodeSelector      map[string]string   `yaml:"nodeSelector,omitempty"`      // node selector terms added to pods that do not already select on the same key
+	PriorityClassName string              `yaml:"priorityClassName,omitempty"` // the priority class of pods that do not set one
+	Resources         ResourceDefaults    `yaml:"resources,omitempty"`         // resource requests and limits for containers and init containers that do not set them
+	Labels            map[string]string   `yaml:"labels,omitempty"`            // labels added to pods.  extraLabels of the khcheck take precedence
+	Annotations       map[string]string   `yaml:"annotations,omitempty"`       // annotations added to pods.  extraAnnotations of the khcheck take precedence
+}
+
+// TolerationDefault is a toleration added to checker pods by PodDefaults
+type TolerationDefault struct {
+	Key               string `yaml:"key,omitempty"`
+	Operator          string `yaml:"operator,omitempty"`
+	Value             string `yaml:"value,omitempty"`
+	Effect            string `yaml:"effect,omitempty"`
+	TolerationSeconds *int64 `yaml:"tolerationSeconds,omitempty"`
+}
+
+// ResourceDefaults are the resource requests and limits of checker containers, keyed by resource name
+type ResourceDefaults struct {
+	Requests map[string]string `yaml:"requests,omitempty"` // such as cpu: 10m
+	Limits   map[string]string `yaml:"limits,omitempty"`   // such as memory: 64Mi
+}
+
+// toleration converts a TolerationDefault into a kubernetes toleration
+func (td TolerationDefault) toleration() apiv1.Toleration {
+	return apiv1.Toleration{
+		Key:               td.Key,
+		Operator:          apiv1.TolerationOperator(td.Operator),
+		Value:             td.Value,
+		Effect:            apiv1.TaintEffect(td.Effect),
+		TolerationSeconds: td.TolerationSeconds,
+	}
+}
+
+// Validate returns an error if any of the default resource quantities can not be parsed
+func (pd PodDefaults) Validate() error {
+	_, err := parseResourceList(pd.Resources.Requests)
+	if err != nil {
+		return err
+	}
+	_, err = parseResourceList(pd.Resources.Limits)
+	return err
+}
+
+// applySpec merges the defaults into the supplied pod spec without overriding any of its settings
+func (pd PodDefaults) applySpec(spec *apiv1.PodSpec) {
+
+	for _, td := range pd.Tolerations {
+		if !toleratesKeyAndEffect(spec.Tolerations, td.Key, apiv1.TaintEffect(td.Effect)) {
+			spec.Tolerations = append(spec.Tolerations, td.toleration())
+		}
+	}
+
+	if len(pd.NodeSelector) > 0 && spec.NodeSelector == nil {
+		spec.NodeSelector = make(map[string]string)
+	}
+	for k, v := range pd.NodeSelector {
+		if _, ok := spec.NodeSelector[k]; !ok {
+			spec.NodeSelector[k] = v
+		}
+	}
+
+	if len(spec.PriorityClassName) == 0 {
+		spec.PriorityClassName = pd.PriorityClassName
+	}
+
+	requests, err := parseResourceList(pd.Resources.Requests)
+	if err != nil {
+		log.Errorln("Ignoring default resource requests of checker pods:", err)
+	}
+	limits, err := parseResourceList(pd.Resources.Limits)
+	if err != nil {
+		log.Errorln("Ignoring default resource limits of checker pods:", err)
+	}
+	// init containers count towards the requests of the pod and can run out of memory just like containers
+	for i := range spec.InitContainers {
+		applyDefaultResources(&spec.InitContainers[i].Resources, requests, limits)
+	}
+	for i := range spec.Containers {
+		applyDefaultResources(&spec.Containers[i].Resources, requests, limits)
+	}
+}
+
+// applyMetadata merges the default labels and annotations into the supplied maps without overriding any of
+// their values
+func (pd PodDefaults) applyMetadata(labels map[string]string, annotations map[string]string) {
+	for k, v := range pd.Labels {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	for k, v := range pd.Annotations {
+		if _, ok := annotations[k]; !ok {
+			annotations[k] = v
+		}
+	}
+}
+
+// applyDefaultResources sets the default requests and limits of a container for each resource it does not
+// already set.  Defaults that would leave a request above its limit are skipped.
+func applyDefaultResources(resources *apiv1.ResourceRequirements, requests apiv1.ResourceList, limits apiv1.ResourceList) {
+	for name, quantity := range limits {
+		if _, ok := resources.Limits[name]; ok {
+			continue
+		}
+		if request, ok := resources.Requests[name]; ok && request.Cmp(quantity) > 0 {
+			continue
+		}
+		if resources.Limits == nil {
+			resources.Limits = make(apiv1.ResourceList)
+		}
+		resources.Limits[name] = quantity
+	}
+	for name, quantity := range requests {
+		if _, ok := resources.Requests[name]; ok {
+			continue
+		}
+		if limit, ok := resources.Limits[name]; ok && limit.Cmp(quantity) < 0 {
+			continue
+		}
+		if resources.Requests == nil {
+			resources.Requests = make(apiv1.ResourceList)
+		}
+		resources.Requests[name] = quantity
+	}
+}
+
+// parseResourceList parses a map of resource names and quantities into a kubernetes resource list
+func parseResourceList(resources map[string]string) (apiv1.ResourceList, error) {
+	list := make(apiv1.ResourceList)
+	for name, value := range resources {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, err
+		}
+		list[apiv1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+// toleratesKeyAndEffect indicates if any of the supplied tolerations has the supplied key and effect
+func toleratesKeyAndEffect(tolerations []apiv1.Toleration, key string, effect apiv1.TaintEffect) bool {
+	for _, t := range tolerations {
+		if t.Key == key && t.Effect == effect {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestPodDefaultsApplySpec ensures that pod defaults are merged into a pod spec without overriding its settings
+func TestPodDefaultsApplySpec(t *testing.T) {
+	defaults := PodDefaults{
+		Tolerations: []TolerationDefault{
+			{Key: "dedicated", Operator: "Equal", Value: "monitoring", Effect: "NoSchedule"},
+			{Key: "spot", Operator: "Exists", Effect: "NoSchedule"},
+		},
+		NodeSelector:      map[string]string{"kubernetes.io/os": "linux", "pool": "monitoring"},
+		PriorityClassName: "kuberhealthy-checks",
+		Resources: ResourceDefaults{
+			Requests: map[string]string{"cpu": "10m", "memory": "32Mi"},
+			Limits:   map[string]string{"memory": "64Mi"},
+		},
+	}
+
+	spec := apiv1.PodSpec{
+		Tolerations:  []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "batch", Effect: apiv1.TaintEffectNoSchedule}},
+		NodeSelector: map[string]string{"pool": "batch"},
+		InitContainers: []apiv1.Container{{
+			Name: "setup",
+			Resources: apiv1.ResourceRequirements{
+				Limits: apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+		}},
+		Containers: []apiv1.Container{{
+			Name: "main",
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("100m")},
+			},
+		}},
+	}
+	defaults.applySpec(&spec)
+
+	if len(spec.Tolerations) != 2 || spec.Tolerations[0].Value != "batch" || spec.Tolerations[1].Key != "spot" {
+		t.Fatalf("Expected the spot toleration to be added without overriding the dedicated toleration but got %+v", spec.Tolerations)
+	}
+	if spec.NodeSelector["pool"] != "batch" || spec.NodeSelector["kubernetes.io/os"] != "linux" {
+		t.Fatalf("Expected the node selector to be merged without overriding the pool but got %v", spec.NodeSelector)
+	}
+	if spec.PriorityClassName != "kuberhealthy-checks" {
+		t.Fatalf("Expected the default priority class but got %q", spec.PriorityClassName)
+	}
+
+	resources := spec.Containers[0].Resources
+	cpu := resources.Requests[apiv1.ResourceCPU]
+	if cpu.String() != "100m" {
+		t.Fatalf("Expected the cpu request of the container to be kept but got %s", cpu.String())
+	}
+	memoryRequest := resources.Requests[apiv1.ResourceMemory]
+	memoryLimit := resources.Limits[apiv1.ResourceMemory]
+	if memoryRequest.String() != "32Mi" || memoryLimit.String() != "64Mi" {
+		t.Fatalf("Expected the default memory request and limit but got %s and %s", memoryRequest.String(), memoryLimit.String())
+	}
+
+	initResources := spec.InitContainers[0].Resources
+	initCPU := initResources.Requests[apiv1.ResourceCPU]
+	initMemoryRequest := initResources.Requests[apiv1.ResourceMemory]
+	initMemoryLimit := initResources.Limits[apiv1.ResourceMemory]
+	if initCPU.String() != "10m" || initMemoryRequest.String() != "32Mi" {
+		t.Fatalf("Expected the default requests on the init container but got %s and %s", initCPU.String(), initMemoryRequest.String())
+	}
+	if initMemoryLimit.String() != "128Mi" {
+		t.Fatalf("Expected the memory limit of the init container to be kept but got %s", initMemoryLimit.String())
+	}
+}
+
+// TestPodDefaultsApplyMetadata ensures that default labels and annotations do not override those of the khcheck
+func TestPodDefaultsApplyMetadata(t *testing.T) {
+	defaults := PodDefaults{
+		Labels:      map[string]string{"team": "platform", "cost-center": "monitoring"},
+		Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+	}
+
+	labels := map[string]string{"team": "storage"}
+	annotations := map[string]string{}
+	defaults.applyMetadata(labels, annotations)
+
+	if labels["team"] != "storage" || labels["cost-center"] != "monitoring" {
+		t.Fatalf("Expected default labels to be merged without overriding the team label but got %v", labels)
+	}
+	if annotations["sidecar.istio.io/inject"] != "false" {
+		t.Fatalf("Expected the default annotation to be added but got %v", annotations)
+	}
+}
+
+// TestPodDefaultsValidate ensures that invalid resource quantities are rejected
+func TestPodDefaultsValidate(t *testing.T) {
+	defaults := PodDefaults{Resources: ResourceDefaults{Limits: map[string]string{"memory": "lots"}}}
+	if defaults.Validate() == nil {
+		t.Fatal("Expected an invalid memory limit to be rejected")
+	}
+}
@@ -0,0 +1,43 @@
+package external
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// addExtraEnv adds the supplied environment variables and environment sources of a khcheck to every container and
+// init container of the supplied pod spec.  Variables that a container already sets are left as they are, and the
+// environment sources of a container take precedence over the added ones.
+func addExtraEnv(spec *apiv1.PodSpec, env []apiv1.EnvVar, envFrom []apiv1.EnvFromSource) {
+	if len(env) == 0 && len(envFrom) == 0 {
+		return
+	}
+	for i := range spec.InitContainers {
+		addContainerExtraEnv(&spec.InitContainers[i], env, envFrom)
+	}
+	for i := range spec.Containers {
+		addContainerExtraEnv(&spec.Containers[i], env, envFrom)
+	}
+}
+
+// addContainerExtraEnv adds the supplied environment variables and environment sources to a single container
+func addContainerExtraEnv(container *apiv1.Container, env []apiv1.EnvVar, envFrom []apiv1.EnvFromSource) {
+	set := make(map[string]bool, len(container.Env))
+	for _, e := range container.Env {
+		set[e.Name] = true
+	}
+	for _, e := range env {
+		if set[e.Name] {
+			continue
+		}
+		container.Env = append(container.Env, *e.DeepCopy())
+	}
+
+	// later sources win when they define the same key, so the sources of the container go last
+	if len(envFrom) > 0 {
+		sources := make([]apiv1.EnvFromSource, 0, len(envFrom)+len(container.EnvFrom))
+		for _, source := range envFrom {
+			sources = append(sources, *source.DeepCopy())
+		}
+		container.EnvFrom = append(sources, container.EnvFrom...)
+	}
+}
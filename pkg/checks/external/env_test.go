@@ -0,0 +1,41 @@
+package external
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestAddExtraEnv ensures that the extra environment of a khcheck is added to every container without replacing
+// the variables and sources that a container sets itself
+func TestAddExtraEnv(t *testing.T) {
+	spec := apiv1.PodSpec{
+		InitContainers: []apiv1.Container{{Name: "init"}},
+		Containers: []apiv1.Container{
+			{Name: "main", Env: []apiv1.EnvVar{{Name: "TARGET", Value: "from-container"}}},
+			{Name: "sidecar", EnvFrom: []apiv1.EnvFromSource{{ConfigMapRef: &apiv1.ConfigMapEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "sidecar"}}}}},
+		},
+	}
+	env := []apiv1.EnvVar{{Name: "TARGET", Value: "from-khcheck"}, {Name: "REGION", Value: "us-east-1"}}
+	envFrom := []apiv1.EnvFromSource{{SecretRef: &apiv1.SecretEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "credentials"}}}}
+
+	addExtraEnv(&spec, env, envFrom)
+
+	expectedEnv := map[string][]apiv1.EnvVar{
+		"init":    env,
+		"main":    {{Name: "TARGET", Value: "from-container"}, {Name: "REGION", Value: "us-east-1"}},
+		"sidecar": env,
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		if !reflect.DeepEqual(c.Env, expectedEnv[c.Name]) {
+			t.Fatalf("expected container %s to have env %v but got %v", c.Name, expectedEnv[c.Name], c.Env)
+		}
+		if len(c.EnvFrom) == 0 || c.EnvFrom[0].SecretRef == nil || c.EnvFrom[0].SecretRef.Name != "credentials" {
+			t.Fatalf("expected container %s to have the credentials secret as its first env source but got %v", c.Name, c.EnvFrom)
+		}
+	}
+	if sidecar := spec.Containers[1]; len(sidecar.EnvFrom) != 2 || sidecar.EnvFrom[1].ConfigMapRef.Name != "sidecar" {
+		t.Fatalf("expected the env source of the sidecar to take precedence but got %v", sidecar.EnvFrom)
+	}
+}
@@ -0,0 +1,89 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// EventComponent is the source component of the events Kuberhealthy records
+const EventComponent = "kuberhealthy"
+
+// The reasons of the events recorded on khchecks and khjobs
+const (
+	EventReasonRunStarted        = "RunStarted"        // a run of the check started
+	EventReasonRunResumed        = "RunResumed"        // a run left in flight by a previous master was resumed
+	EventReasonRunSucceeded      = "RunSucceeded"      // the checker pod reported success
+	EventReasonRunFailed         = "RunFailed"         // the checker pod reported failure or the run could not complete
+	EventReasonCheckerPodTimeout = "CheckerPodTimeout" // the checker pod did not start, report in or exit within the timeout
+	EventReasonCheckerPodReaped  = "CheckerPodReaped"  // the reaper deleted a completed checker pod
+)
+
+// NewEventRecorder creates an event recorder that writes events to the Kubernetes API with the supplied client.
+// Events are aggregated and rate limited like the events of Kubernetes controllers.
+func NewEventRecorder(client kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: EventComponent})
+}
+
+// EventReference returns a reference to the khcheck or khjob behind the supplied owner reference for events to be
+// recorded on.  Nil is returned when the owner is not known.
+func EventReference(owner *metav1.OwnerReference, namespace string) *apiv1.ObjectReference {
+	if owner == nil || len(owner.UID) == 0 {
+		return nil
+	}
+	return &apiv1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Name:       owner.Name,
+		Namespace:  namespace,
+		UID:        owner.UID,
+	}
+}
+
+// recordEvent records an event on the khcheck or khjob of this checker, so that it shows up when the khcheck or
+// khjob is described
+func (ext *Checker) recordEvent(eventType string, reason string, messageFmt string, args ...interface{}) {
+	if ext.EventRecorder == nil {
+		return
+	}
+	ref := EventReference(ext.OwnerReference, ext.Namespace)
+	if ref == nil {
+		return
+	}
+	ext.EventRecorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// recordRunResult records an event with the outcome of a run that ended with the supplied error.  Runs that were
+// stopped on purpose do not get an event.
+func (ext *Checker) recordRunResult(ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPodRemovedExpectedly) || ctx.Err() != nil:
+		return
+	case err != nil && FailureReasonOf(err) == khstatev1.FailureReasonTimeout:
+		ext.recordEvent(apiv1.EventTypeWarning, EventReasonCheckerPodTimeout, "Run %s timed out: %s", ext.currentCheckUUID, err)
+	case err != nil:
+		ext.recordEvent(apiv1.EventTypeWarning, EventReasonRunFailed, "Run %s failed with reason %s: %s", ext.currentCheckUUID, FailureReasonOf(err), err)
+	case ext.reportDuration == 0:
+		// the check was shut down before its checker pod reported in
+		return
+	default:
+		ok, errs := ext.CurrentStatus()
+		if !ok {
+			ext.recordEvent(apiv1.EventTypeWarning, EventReasonRunFailed, "Run %s failed: %s", ext.currentCheckUUID, strings.Join(errs, "; "))
+			return
+		}
+		ext.recordEvent(apiv1.EventTypeNormal, EventReasonRunSucceeded, "Run %s succeeded after %s", ext.currentCheckUUID, ext.reportDuration.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,73 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestRecordRunResult ensures that failed and timed out runs are recorded as warnings on the khcheck, and that runs
+// that were stopped on purpose are not recorded
+func TestRecordRunResult(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expected string
+	}{
+		{name: "timeout", ctx: context.Background(), err: &runError{reason: khstatev1.FailureReasonTimeout, err: errors.New("timed out waiting for checker pod to report in")}, expected: "Warning " + EventReasonCheckerPodTimeout},
+		{name: "failure", ctx: context.Background(), err: errors.New("failed to create pod"), expected: "Warning " + EventReasonRunFailed},
+		{name: "removed", ctx: context.Background(), err: ErrPodRemovedExpectedly},
+		{name: "stopped", ctx: canceledCtx, err: errors.New("failed to create pod")},
+		{name: "shut down", ctx: context.Background()},
+	}
+
+	for _, tc := range testCases {
+		recorder := record.NewFakeRecorder(1)
+		ext := &Checker{
+			CheckName:      "test-check",
+			Namespace:      "kuberhealthy",
+			OwnerReference: &metav1.OwnerReference{Kind: "KuberhealthyCheck", Name: "test-check", UID: "1234"},
+			EventRecorder:  recorder,
+		}
+		ext.recordRunResult(tc.ctx, tc.err)
+
+		select {
+		case event := <-recorder.Events:
+			if len(tc.expected) == 0 {
+				t.Fatalf("%s: expected no event but got %q", tc.name, event)
+			}
+			if !strings.HasPrefix(event, tc.expected) {
+				t.Fatalf("%s: expected an event starting with %q but got %q", tc.name, tc.expected, event)
+			}
+		default:
+			if len(tc.expected) > 0 {
+				t.Fatalf("%s: expected a %q event but none was recorded", tc.name, tc.expected)
+			}
+		}
+	}
+}
+
+// TestEventReference ensures that events are only recorded on owners with a UID, which is how kubectl describe
+// finds the events of an object
+func TestEventReference(t *testing.T) {
+	if EventReference(nil, "kuberhealthy") != nil {
+		t.Fatal("Expected no event reference without an owner")
+	}
+	if EventReference(&metav1.OwnerReference{Kind: "KuberhealthyCheck", Name: "test-check"}, "kuberhealthy") != nil {
+		t.Fatal("Expected no event reference for an owner without a UID")
+	}
+	ref := EventReference(&metav1.OwnerReference{APIVersion: "comcast.github.io/v1", Kind: "KuberhealthyCheck", Name: "test-check", UID: "1234"}, "kuberhealthy")
+	if ref == nil || ref.Namespace != "kuberhealthy" || ref.UID != "1234" || ref.Kind != "KuberhealthyCheck" {
+		t.Fatalf("Expected a reference to the khcheck in its namespace but got %+v", ref)
+	}
+}
@@ -0,0 +1,98 @@
+package external
+
+import (
+	"context"
+	"errors"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// imagePullWaitingReasons are the waiting reasons of containers whose image can not be pulled
+var imagePullWaitingReasons = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull"}
+
+// runError is an error from a check run that carries the reason that the run failed
+type runError struct {
+	reason khstatev1.FailureReason
+	err    error
+}
+
+// Error returns the message of the underlying error
+func (e *runError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *runError) Unwrap() error {
+	return e.err
+}
+
+// newReasonError returns an error like newError that also carries the reason that the run failed
+func (ext *Checker) newReasonError(reason khstatev1.FailureReason, s string) error {
+	return &runError{reason: reason, err: ext.newError(s)}
+}
+
+// FailureReasonOf returns the reason that a check run failed with the supplied error.  Checker pods that were
+// removed while running count as killed, runs replaced by the next run count as timed out, and errors that do not
+// carry a reason are execution errors.
+func FailureReasonOf(err error) khstatev1.FailureReason {
+	var re *runError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	if errors.Is(err, ErrPodRemovedUnexpectedly) || errors.Is(err, ErrPodDeletedBeforeRunning) {
+		return khstatev1.FailureReasonReaperKilled
+	}
+	if errors.Is(err, ErrRunReplaced) {
+		return khstatev1.FailureReasonTimeout
+	}
+	return khstatev1.FailureReasonExecutionError
+}
+
+// podStartFailureReason returns why a checker pod did not start running in time.  Pods that could not be scheduled
+// and pods whose image can not be pulled are told apart from pods that were simply slow to start.
+func podStartFailureReason(pod *apiv1.Pod) khstatev1.FailureReason {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodScheduled && condition.Status == apiv1.ConditionFalse {
+			return khstatev1.FailureReasonPodSchedulingFailed
+		}
+	}
+	statuses := append([]apiv1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting != nil && isImagePullReason(cs.State.Waiting.Reason) {
+			return khstatev1.FailureReasonImagePullError
+		}
+	}
+	return khstatev1.FailureReasonTimeout
+}
+
+// isImagePullReason determines if a container waiting reason means that its image can not be pulled
+func isImagePullReason(reason string) bool {
+	for _, r := range imagePullWaitingReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// startFailureReason returns why the checker pod of the current run did not start running in time
+func (ext *Checker) startFailureReason(ctx context.Context) khstatev1.FailureReason {
+	pods, err := ext.KubeClient.CoreV1().Pods(ext.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: kuberhealthyRunIDLabel + "=" + ext.currentCheckUUID,
+	})
+	if err != nil {
+		ext.log("error listing checker pods to find why they did not start:", err)
+		return khstatev1.FailureReasonTimeout
+	}
+	for i := range pods.Items {
+		reason := podStartFailureReason(&pods.Items[i])
+		if reason != khstatev1.FailureReasonTimeout {
+			return reason
+		}
+	}
+	return khstatev1.FailureReasonTimeout
+}
@@ -0,0 +1,79 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestFailureReasonOf ensures that the reason carried by run errors is found, even when they are wrapped
+func TestFailureReasonOf(t *testing.T) {
+	ext := &Checker{CheckName: "test-check", Namespace: "kuberhealthy"}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected khstatev1.FailureReason
+	}{
+		{name: "timeout", err: ext.newReasonError(khstatev1.FailureReasonTimeout, "timed out"), expected: khstatev1.FailureReasonTimeout},
+		{name: "wrapped", err: fmt.Errorf("run failed: %w", ext.newReasonError(khstatev1.FailureReasonImagePullError, "ErrImagePull")), expected: khstatev1.FailureReasonImagePullError},
+		{name: "pod removed", err: ErrPodRemovedUnexpectedly, expected: khstatev1.FailureReasonReaperKilled},
+		{name: "oom killed", err: &runError{reason: khstatev1.FailureReasonOOMKilled, err: errors.New("container main was OOMKilled")}, expected: khstatev1.FailureReasonOOMKilled},
+		{name: "replaced", err: ErrRunReplaced, expected: khstatev1.FailureReasonTimeout},
+		{name: "other", err: errors.New("failed to create pod"), expected: khstatev1.FailureReasonExecutionError},
+	}
+
+	for _, tc := range testCases {
+		reason := FailureReasonOf(tc.err)
+		if reason != tc.expected {
+			t.Errorf("%s: expected failure reason %s but got %s", tc.name, tc.expected, reason)
+		}
+	}
+}
+
+// TestPodStartFailureReason ensures that pods that could not be scheduled or pull their image are told apart from
+// pods that were slow to start
+func TestPodStartFailureReason(t *testing.T) {
+	unschedulable := &apiv1.Pod{}
+	unschedulable.Status.Phase = apiv1.PodPending
+	unschedulable.Status.Conditions = []apiv1.PodCondition{{
+		Type:   apiv1.PodScheduled,
+		Status: apiv1.ConditionFalse,
+		Reason: apiv1.PodReasonUnschedulable,
+	}}
+
+	imagePull := &apiv1.Pod{}
+	imagePull.Status.Phase = apiv1.PodPending
+	imagePull.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name:  "main",
+		State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}}
+
+	slow := &apiv1.Pod{}
+	slow.Status.Phase = apiv1.PodPending
+	slow.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name:  "main",
+		State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}}
+
+	testCases := []struct {
+		name     string
+		pod      *apiv1.Pod
+		expected khstatev1.FailureReason
+	}{
+		{name: "unschedulable", pod: unschedulable, expected: khstatev1.FailureReasonPodSchedulingFailed},
+		{name: "image pull", pod: imagePull, expected: khstatev1.FailureReasonImagePullError},
+		{name: "slow", pod: slow, expected: khstatev1.FailureReasonTimeout},
+	}
+
+	for _, tc := range testCases {
+		reason := podStartFailureReason(tc.pod)
+		if reason != tc.expected {
+			t.Errorf("%s: expected failure reason %s but got %s", tc.name, tc.expected, reason)
+		}
+	}
+}
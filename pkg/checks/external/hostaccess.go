@@ -0,0 +1,46 @@
+package external
+
+import (
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// HostAccessSettings holds the settings that gate checker pods which share the network or process namespace of
+// their node.  These pods can see every connection and process on the node, so they are refused unless a cluster
+// operator turns them on for specific namespaces.
+type HostAccessSettings struct {
+	Enabled    bool     `yaml:"enabled,omitempty"`    // set to true to let checker pods set hostNetwork or hostPID
+	Namespaces []string `yaml:"namespaces,omitempty"` // the namespaces whose checker pods may set hostNetwork or hostPID. empty allows no namespace
+}
+
+// hostAccessFields returns the host namespace fields that a pod spec sets
+func hostAccessFields(spec apiv1.PodSpec) []string {
+	var fields []string
+	if spec.HostNetwork {
+		fields = append(fields, "hostNetwork")
+	}
+	if spec.HostPID {
+		fields = append(fields, "hostPID")
+	}
+	return fields
+}
+
+// HostAccessProblem returns a description of why a checker pod in the supplied namespace may not set the
+// hostNetwork or hostPID of its pod spec, or a blank string when it may
+func HostAccessProblem(spec apiv1.PodSpec, namespace string, settings HostAccessSettings) string {
+	fields := hostAccessFields(spec)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	if !settings.Enabled {
+		return "podSpec can not set " + strings.Join(fields, " or ") + " because host access is not enabled for checks"
+	}
+	for _, allowed := range settings.Namespaces {
+		if allowed == namespace {
+			return ""
+		}
+	}
+	return "podSpec can not set " + strings.Join(fields, " or ") + " because host access is not allowed in namespace " + namespace
+}
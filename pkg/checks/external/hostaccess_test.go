@@ -0,0 +1,39 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestHostAccessProblem ensures that checker pods may only set hostNetwork or hostPID when host access is enabled
+// for their namespace
+func TestHostAccessProblem(t *testing.T) {
+	enabled := HostAccessSettings{Enabled: true, Namespaces: []string{"node-diagnostics"}}
+
+	testCases := []struct {
+		name          string
+		spec          apiv1.PodSpec
+		namespace     string
+		settings      HostAccessSettings
+		expectProblem bool
+	}{
+		{name: "no host access", namespace: "payments"},
+		{name: "host network when disabled", spec: apiv1.PodSpec{HostNetwork: true}, namespace: "node-diagnostics", expectProblem: true},
+		{name: "host pid when disabled", spec: apiv1.PodSpec{HostPID: true}, namespace: "node-diagnostics", expectProblem: true},
+		{name: "host network in allowed namespace", spec: apiv1.PodSpec{HostNetwork: true}, namespace: "node-diagnostics", settings: enabled},
+		{name: "host pid in allowed namespace", spec: apiv1.PodSpec{HostNetwork: true, HostPID: true}, namespace: "node-diagnostics", settings: enabled},
+		{name: "host network in another namespace", spec: apiv1.PodSpec{HostNetwork: true}, namespace: "payments", settings: enabled, expectProblem: true},
+		{name: "enabled without namespaces", spec: apiv1.PodSpec{HostPID: true}, namespace: "payments", settings: HostAccessSettings{Enabled: true}, expectProblem: true},
+	}
+
+	for _, tc := range testCases {
+		problem := HostAccessProblem(tc.spec, tc.namespace, tc.settings)
+		if tc.expectProblem && len(problem) == 0 {
+			t.Fatalf("%s: expected host access to be refused", tc.name)
+		}
+		if !tc.expectProblem && len(problem) > 0 {
+			t.Fatalf("%s: expected host access to be allowed but got: %s", tc.name, problem)
+		}
+	}
+}
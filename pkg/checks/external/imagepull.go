@@ -0,0 +1,142 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// permanentImagePullReasons are the waiting reasons of containers whose image will never be pulled, no matter how
+// long the kubelet retries
+var permanentImagePullReasons = []string{"InvalidImageName", "ErrImageNeverPull"}
+
+// imagePullFailure is a container of a checker pod that is waiting on an image that can not be pulled
+type imagePullFailure struct {
+	container string
+	image     string
+	reason    string // the waiting reason of the container, such as ImagePullBackOff
+	message   string // the waiting message of the container from the kubelet
+}
+
+// Error describes the failed image pull, including the image that could not be pulled
+func (f imagePullFailure) Error() string {
+	msg := fmt.Sprintf("%s: container %s can not pull image %s", f.reason, f.container, f.image)
+	if len(f.message) > 0 {
+		msg += ": " + f.message
+	}
+	return msg
+}
+
+// permanent indicates if the image will never be pulled, so that there is no point in waiting for a retry
+func (f imagePullFailure) permanent() bool {
+	for _, r := range permanentImagePullReasons {
+		if r == f.reason {
+			return true
+		}
+	}
+	return false
+}
+
+// podImagePullFailures returns the containers and init containers of the supplied checker pod that are waiting on an
+// image that can not be pulled
+func podImagePullFailures(pod *apiv1.Pod) []imagePullFailure {
+	var failures []imagePullFailure
+	statuses := append([]apiv1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting == nil || !isImagePullReason(cs.State.Waiting.Reason) {
+			continue
+		}
+		failures = append(failures, imagePullFailure{
+			container: cs.Name,
+			image:     cs.Image,
+			reason:    cs.State.Waiting.Reason,
+			message:   cs.State.Waiting.Message,
+		})
+	}
+	return failures
+}
+
+// imagePullTracker tracks how long each container of a checker pod has failed to pull its image, so that a pull
+// that the kubelet retries successfully does not fail the run
+type imagePullTracker struct {
+	gracePeriod time.Duration
+	firstSeen   map[string]time.Time // when each failing container was first seen failing, by container name
+}
+
+// newImagePullTracker creates an imagePullTracker that fails pulls after the supplied grace period
+func newImagePullTracker(gracePeriod time.Duration) *imagePullTracker {
+	return &imagePullTracker{
+		gracePeriod: gracePeriod,
+		firstSeen:   make(map[string]time.Time),
+	}
+}
+
+// observe records the image pull failures of the supplied checker pod seen at the supplied time.  An error is
+// returned once a container has failed to pull its image for longer than the grace period, or right away if the
+// image will never be pulled.
+func (t *imagePullTracker) observe(pod *apiv1.Pod, now time.Time) error {
+	failing := make(map[string]bool)
+	for _, f := range podImagePullFailures(pod) {
+		failing[f.container] = true
+		first, ok := t.firstSeen[f.container]
+		if !ok {
+			first = now
+			t.firstSeen[f.container] = now
+		}
+		if f.permanent() || now.Sub(first) >= t.gracePeriod {
+			return f
+		}
+	}
+
+	// containers that pulled their image after all start over if they fail again
+	for container := range t.firstSeen {
+		if !failing[container] {
+			delete(t.firstSeen, container)
+		}
+	}
+	return nil
+}
+
+// waitForImagePullFailure returns a channel that receives an error if a container of the checker pod fails to pull
+// its image for longer than the image pull grace period of the check, before the supplied context is canceled
+func (ext *Checker) waitForImagePullFailure(ctx context.Context) chan error {
+
+	outChan := make(chan error, 1)
+	podClient := ext.KubeClient.CoreV1().Pods(ext.Namespace)
+	tracker := newImagePullTracker(ext.ImagePullGracePeriod)
+
+	ext.wg.Add(1)
+	go func() {
+		defer ext.wg.Done()
+
+		for {
+			pods, err := podClient.List(ctx, metav1.ListOptions{
+				LabelSelector: kuberhealthyRunIDLabel + "=" + ext.currentCheckUUID,
+			})
+			if err != nil && ctx.Err() == nil {
+				ext.log("error listing checker pods when watching for image pull failures:", err)
+			}
+			if err == nil {
+				for i := range pods.Items {
+					err := tracker.observe(&pods.Items[i], time.Now())
+					if err != nil {
+						outChan <- err
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second * 5): // sleep between polls
+			}
+		}
+	}()
+
+	return outChan
+}
@@ -0,0 +1,52 @@
+package external
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// imagePullPod returns a checker pod whose container is waiting with the supplied reason
+func imagePullPod(reason string) *apiv1.Pod {
+	pod := &apiv1.Pod{}
+	pod.Status.Phase = apiv1.PodPending
+	pod.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name:  "main",
+		Image: "registry.example.com/check:missing",
+		State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: reason, Message: "manifest unknown"}},
+	}}
+	return pod
+}
+
+// TestImagePullTracker ensures that image pulls only fail the run once they have failed for the grace period, and
+// that images that can never be pulled fail the run right away
+func TestImagePullTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newImagePullTracker(time.Second * 30)
+
+	if err := tracker.observe(imagePullPod("ErrImagePull"), now); err != nil {
+		t.Fatalf("expected a new image pull failure to be given time to recover but got: %v", err)
+	}
+	if err := tracker.observe(imagePullPod("ContainerCreating"), now.Add(time.Second*10)); err != nil {
+		t.Fatalf("expected a recovered image pull not to fail but got: %v", err)
+	}
+	if err := tracker.observe(imagePullPod("ImagePullBackOff"), now.Add(time.Second*20)); err != nil {
+		t.Fatalf("expected a recovered image pull to start its grace period over but got: %v", err)
+	}
+
+	err := tracker.observe(imagePullPod("ImagePullBackOff"), now.Add(time.Second*50))
+	if err == nil {
+		t.Fatalf("expected an image pull failing past the grace period to fail")
+	}
+	expected := "ImagePullBackOff: container main can not pull image registry.example.com/check:missing: manifest unknown"
+	if err.Error() != expected {
+		t.Fatalf("expected error %q but got %q", expected, err.Error())
+	}
+
+	err = newImagePullTracker(time.Minute).observe(imagePullPod("InvalidImageName"), now)
+	if err == nil || !strings.HasPrefix(err.Error(), "InvalidImageName:") {
+		t.Fatalf("expected an invalid image name to fail right away but got: %v", err)
+	}
+}
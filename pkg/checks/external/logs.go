@@ -0,0 +1,57 @@
+package external
+
+import (
+	"context"
+	"io"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// truncatedLogsPrefix is put in front of checker pod logs that were cut down to fit within the size limit
+const truncatedLogsPrefix = "...(truncated)\n"
+
+// FailureLogs returns the logs captured from the checker pod of the last run if that run failed
+func (ext *Checker) FailureLogs() string {
+	return ext.failureLogs
+}
+
+// captureFailureLogs fetches the last lines of logs from the checker pod of the current run so that they can be
+// attached to the errors of a failed run before the pod is cleaned up.  Returns an empty string if log capture
+// is disabled or the logs can not be fetched.
+func (ext *Checker) captureFailureLogs(ctx context.Context) string {
+	if ext.FailureLogLines <= 0 || ext.KubeClient == nil {
+		return ""
+	}
+
+	stream, err := ext.KubeClient.CoreV1().Pods(ext.Namespace).GetLogs(ext.podName(), ext.failureLogOptions()).Stream(ctx)
+	if err != nil {
+		ext.log("failed to fetch logs of checker pod", ext.podName()+":", err)
+		return ""
+	}
+	defer stream.Close()
+
+	b, err := io.ReadAll(stream)
+	if err != nil {
+		ext.log("failed to read logs of checker pod", ext.podName()+":", err)
+	}
+	return truncateLogs(string(b), ext.FailureLogMaxBytes)
+}
+
+// failureLogOptions returns the options used to fetch the logs of a failed checker pod.  Only the number of lines is
+// limited, as the API server limits the size of logs from their start and would cut off the most recent output.  The
+// logs are cut down to their size limit by truncateLogs instead.
+func (ext *Checker) failureLogOptions() *apiv1.PodLogOptions {
+	tailLines := ext.FailureLogLines
+	return &apiv1.PodLogOptions{
+		TailLines: &tailLines,
+	}
+}
+
+// truncateLogs cuts logs down to at most maxBytes by dropping the oldest output.  A maxBytes of zero or less
+// leaves the logs as they are.
+func truncateLogs(logs string, maxBytes int) string {
+	if maxBytes <= 0 || len(logs) <= maxBytes {
+		return logs
+	}
+	return truncatedLogsPrefix + logs[len(logs)-maxBytes:]
+}
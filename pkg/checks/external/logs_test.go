@@ -0,0 +1,65 @@
+package external
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestFailureLogOptions ensures that the logs of failed checker pods are only limited by their number of lines, so
+// that the API server does not cut off their most recent output
+func TestFailureLogOptions(t *testing.T) {
+	c := &Checker{FailureLogLines: 20, FailureLogMaxBytes: 100}
+	options := c.failureLogOptions()
+	if options.TailLines == nil || *options.TailLines != 20 {
+		t.Fatalf("Expected the last 20 lines of logs to be fetched but got %v", options.TailLines)
+	}
+	if options.LimitBytes != nil {
+		t.Fatalf("Expected the size of the logs not to be limited by the API server but got a limit of %d bytes", *options.LimitBytes)
+	}
+}
+
+// TestCaptureFailureLogs ensures that captured logs keep their most recent output when they are too large
+func TestCaptureFailureLogs(t *testing.T) {
+	c := &Checker{
+		CheckName:          "test-check",
+		Namespace:          "kuberhealthy",
+		checkPodName:       "test-check-pod",
+		KubeClient:         fake.NewSimpleClientset(),
+		FailureLogLines:    20,
+		FailureLogMaxBytes: 4,
+	}
+
+	// the fake API server answers every log request with "fake logs"
+	logs := c.captureFailureLogs(context.Background())
+	if logs != truncatedLogsPrefix+"logs" {
+		t.Fatalf("Expected the most recent output of the logs to be kept but got %q", logs)
+	}
+
+	c.FailureLogLines = 0
+	logs = c.captureFailureLogs(context.Background())
+	if logs != "" {
+		t.Fatalf("Expected no logs to be captured when log capture is disabled but got %q", logs)
+	}
+}
+
+// TestTruncateLogs ensures that captured logs are cut down to their size limit by dropping the oldest output
+func TestTruncateLogs(t *testing.T) {
+	testCases := []struct {
+		logs     string
+		maxBytes int
+		expected string
+	}{
+		{logs: "line one\nline two\n", maxBytes: 0, expected: "line one\nline two\n"},
+		{logs: "line one\nline two\n", maxBytes: 100, expected: "line one\nline two\n"},
+		{logs: "line one\nline two\n", maxBytes: 9, expected: truncatedLogsPrefix + "line two\n"},
+	}
+
+	for _, tc := range testCases {
+		truncated := truncateLogs(tc.logs, tc.maxBytes)
+		if truncated != tc.expected {
+			t.Fatalf("Expected logs truncated to %d bytes to be %q but got %q", tc.maxBytes, tc.expected, truncated)
+		}
+	}
+}
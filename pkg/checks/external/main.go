@@ -19,6 +19,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
@@ -28,11 +29,16 @@ import (
 	khjobv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khjob/v1"
 	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/util"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
 )
 
 // KHReportingURL is the environment variable used to tell external checks where to send their status updates
 const KHReportingURL = "KH_REPORTING_URL"
 
+// KHReportingGRPCAddress is the environment variable used to tell external checks the address of the gRPC reporting
+// API when it is enabled.  Checks may report over gRPC instead of posting to KH_REPORTING_URL.
+const KHReportingGRPCAddress = "KH_REPORTING_GRPC_ADDRESS"
+
 // KHRunUUID is the environment variable used to tell external checks their check's UUID so that they
 // can be de-duplicated on the server side.
 const KHRunUUID = "KH_RUN_UUID"
@@ -47,6 +53,18 @@ const KHCheckNameAnnotationKey = "comcast.github.io/check-name"
 // checks in.
 const KHPodNamespace = "KH_POD_NAMESPACE"
 
+// KHReportTokenFile is the environment variable that holds the path of the service account token that checker pods
+// authenticate their reports with when report token authentication is enabled
+const KHReportTokenFile = "KH_REPORT_TOKEN_FILE"
+
+// KHNodeName is the environment variable that tells the checker pods of checks that run on all nodes which node
+// they are running on
+const KHNodeName = "KH_NODE_NAME"
+
+// KHNodeRunIDLabel is the label that holds the UUID of the run that a checker pod belongs to for checks that run
+// on all nodes.  Each of their checker pods has its own kuberhealthy-run-id so that their reports can be told apart.
+const KHNodeRunIDLabel = "kuberhealthy-node-run-id"
+
 // DefaultKuberhealthyReportingURL is the default location that external checks
 // are expected to report into.
 const DefaultKuberhealthyReportingURL = "http://kuberhealthy.kuberhealthy.svc.cluster.local/externalCheckStatus"
@@ -72,6 +90,10 @@ var ErrPodRemovedUnexpectedly = errors.New("pod deleted unexpectedly")
 // ErrPodDeletedBeforeRunning is a constant for the error when a pod is deleted before the check pod running
 var ErrPodDeletedBeforeRunning = errors.New("the khcheck check pod is deleted, waiting for start failed")
 
+// ErrRunReplaced is a constant for the error when a run is stopped because the next run of a check with the Replace
+// concurrency policy came due while it was still in flight
+var ErrRunReplaced = errors.New("run was replaced by the next run because it was still running when the next run was due")
+
 // DefaultName is used when no check name is supplied
 var DefaultName = "external-check"
 
@@ -86,16 +108,48 @@ var kuberhealthyNamespace = "kuberhealthy"
 type Checker struct {
 	CheckName                string // the name of this checker
 	Namespace                string
-	RunInterval              time.Duration // how often this check runs a loop
-	RunTimeout               time.Duration // time check must run completely within
+	RunInterval              time.Duration                 // how often this check runs a loop
+	RunSchedule              string                        // an optional cron expression that determines when this check runs instead of RunInterval
+	RunIntervalJitter        time.Duration                 // the window within which the first run of this check is randomly delayed
+	RunIntervalBackoff       int                           // how many times longer the run interval gets with each failed run in a row. zero disables backoff
+	MaxRunInterval           time.Duration                 // the longest that the run interval grows to when backing off
+	EffectiveRunInterval     time.Duration                 // the run interval that this check currently runs on after backing off
+	FailureThreshold         int                           // the number of consecutive failed runs before this check is reported as unhealthy
+	Severity                 string                        // the severity of this check's failures. only critical failures make the overall health status fail
+	RunTimeout               time.Duration                 // time check must run completely within
+	UnschedulableTimeout     time.Duration                 // how long the checker pod may be unschedulable before the run fails. zero fails the run as soon as the pod is unschedulable
+	ImagePullGracePeriod     time.Duration                 // how long a container of the checker pod may fail to pull its image before the run fails
+	RunNow                   chan struct{}                 // signaled when the check should run right away instead of waiting for its next run
+	Paused                   bool                          // paused checks skip their runs until they are resumed
+	ConcurrencyPolicy        khcheckv1.ConcurrencyPolicy   // what happens when the next run of this check is due while a run is still in flight
+	FailureLogLines          int64                         // the number of lines of checker pod logs captured when a run fails. zero disables log capture
+	FailureLogMaxBytes       int                           // the maximum size of the checker pod logs captured when a run fails
+	PodDefaults              PodDefaults                   // settings merged into the checker pod unless the khcheck overrides them
+	ExtraEnv                 []apiv1.EnvVar                // environment variables added to every container of the checker pod that does not set them
+	EnvFrom                  []apiv1.EnvFromSource         // secrets and configmaps whose keys are added as environment variables to every container of the checker pod
+	OwnerReference           *metav1.OwnerReference        // a reference to the khcheck or khjob that owns the checker pods of this check
+	ReportTokenAuth          bool                          // checker pods must authenticate their reports with a service account token
+	ClientCertIssuer         *ClientCertIssuer             // when set, checker pods are issued a client certificate to authenticate their reports with over mutual TLS
+	RunOnAllNodes            bool                          // run a checker pod on every schedulable node and aggregate their results
+	NetworkPolicy            *khcheckv1.CheckNetworkPolicy // when set, a NetworkPolicy that lets the checker pods reach kuberhealthy and the declared targets is created for each run
+	NetworkPolicySettings    NetworkPolicySettings         // settings for the NetworkPolicies created for checker pods
+	AllowedImageRegistries   []string                      // the image registries and prefixes that checker pods may use images from. empty allows all images
+	ServiceAccountSettings   ServiceAccountSettings        // settings for the service accounts that checker pods run under
+	HostAccessSettings       HostAccessSettings            // settings that gate checker pods which set hostNetwork or hostPID
+	PodSecurity              PodSecuritySettings           // settings for hardening checker pods to meet a Pod Security Standards level
+	ImageVerifier            ImageVerifier                 // when set, checker images must be signed by a trusted signer and are pinned to the verified digest
+	EventRecorder            record.EventRecorder          // records events about runs on the khcheck or khjob. nil disables events
 	KubeClient               *kubernetes.Clientset
 	KHJobClient              *khjobv1.KHJobV1Client
 	KHCheckClient            *khcheckv1.KHCheckV1Client
-	KHStateClient            *khstatev1.KHStateV1Client
+	KHCheckLister            khcheckv1.KuberhealthyCheckLister // when set, the khcheck is read from an informer cache instead of the API server
+	KHStateClient            khstatev1.KuberhealthyStatesGetter
+	PodWatcher               *PodWatcher   // when set and synced, runs wait on their checker pods through this shared informer instead of their own watches
 	PodSpec                  apiv1.PodSpec // the current pod spec we are using after enforcement of settings
 	OriginalPodSpec          apiv1.PodSpec // the user-provided spec of the pod
 	RunID                    string        // the uuid of the current run
 	KuberhealthyReportingURL string        // the URL that the check should want to report results back to
+	KuberhealthyGRPCAddress  string        // the address of the gRPC reporting API. blank when it is disabled
 	ExtraAnnotations         map[string]string
 	ExtraLabels              map[string]string
 	Node                     string             // the node the checker pod runs on
@@ -106,6 +160,16 @@ type Checker struct {
 	wg                       sync.WaitGroup     // used to track background workers and processes
 	hostname                 string             // hostname cache
 	checkPodName             string             // the current unique checker pod name
+	podStartTime             time.Time          // the time the checker pod of the current run started running
+	podCreateTime            time.Time          // the time the checker pod of the current run was created. zero if it was created by another Kuberhealthy pod after it started
+	schedulingLatency        time.Duration      // the time from checker pod creation to the pod running in the last run
+	reportDuration           time.Duration      // the time from checker pod start to report receipt in the last run
+	failureLogs              string             // the checker pod logs captured when the last run failed
+	requestedRunUUID         string             // the UUID handed out for a requested run that has not started yet
+	runRequestMu             sync.Mutex         // guards requestedRunUUID
+	detached                 bool               // set when the checker is detached from its run in flight by a shutdown
+	inFlight                 bool               // the checker pod of the current run is recorded on the khstate
+	detachMu                 sync.Mutex         // guards detached and inFlight
 	KHWorkload               khstatev1.KHWorkload
 }
 
@@ -117,12 +181,12 @@ func init() {
 }
 
 // New creates a new external checker
-func New(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyCheck, khCheckClient *khcheckv1.KHCheckV1Client, khStateClient *khstatev1.KHStateV1Client, reportingURL string) *Checker {
+func New(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyCheck, khCheckClient *khcheckv1.KHCheckV1Client, khStateClient khstatev1.KuberhealthyStatesGetter, reportingURL string) *Checker {
 
 	return NewCheck(client, checkConfig, khCheckClient, khStateClient, reportingURL)
 }
 
-func NewCheck(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyCheck, khCheckClient *khcheckv1.KHCheckV1Client, khStateClient *khstatev1.KHStateV1Client, reportingURL string) *Checker {
+func NewCheck(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyCheck, khCheckClient *khcheckv1.KHCheckV1Client, khStateClient khstatev1.KuberhealthyStatesGetter, reportingURL string) *Checker {
 
 	if len(checkConfig.Namespace) == 0 {
 		checkConfig.Namespace = "kuberhealthy"
@@ -131,6 +195,7 @@ func NewCheck(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyC
 	// build the checker object
 	log.Debugf("Creating external check from check config: %+v \n", checkConfig)
 	return &Checker{
+		OwnerReference:           workloadOwnerReference(checkConfig.OwnerReference()),
 		Namespace:                checkConfig.Namespace,
 		KHCheckClient:            khCheckClient,
 		KHStateClient:            khStateClient,
@@ -143,10 +208,11 @@ func NewCheck(client *kubernetes.Clientset, checkConfig *khcheckv1.KuberhealthyC
 		PodSpec:                  checkConfig.Spec.PodSpec,
 		KubeClient:               client,
 		KHWorkload:               khstatev1.KHCheck,
+		RunNow:                   make(chan struct{}, 1),
 	}
 }
 
-func NewJob(client *kubernetes.Clientset, jobConfig *khjobv1.KuberhealthyJob, khJobClient *khjobv1.KHJobV1Client, khStateClient *khstatev1.KHStateV1Client, reportingURL string) *Checker {
+func NewJob(client *kubernetes.Clientset, jobConfig *khjobv1.KuberhealthyJob, khJobClient *khjobv1.KHJobV1Client, khStateClient khstatev1.KuberhealthyStatesGetter, reportingURL string) *Checker {
 
 	if len(jobConfig.Namespace) == 0 {
 		jobConfig.Namespace = "kuberhealthy"
@@ -155,6 +221,7 @@ func NewJob(client *kubernetes.Clientset, jobConfig *khjobv1.KuberhealthyJob, kh
 	// build the checker object
 	log.Debugf("Creating kuberhealthy job from job config: %+v \n", jobConfig)
 	return &Checker{
+		OwnerReference:           workloadOwnerReference(jobConfig.OwnerReference()),
 		Namespace:                jobConfig.Namespace,
 		KHJobClient:              khJobClient,
 		KHStateClient:            khStateClient,
@@ -169,6 +236,15 @@ func NewJob(client *kubernetes.Clientset, jobConfig *khjobv1.KuberhealthyJob, kh
 	}
 }
 
+// workloadOwnerReference returns the supplied owner reference, or nil if it does not refer to a resource that
+// exists in the cluster
+func workloadOwnerReference(ref metav1.OwnerReference) *metav1.OwnerReference {
+	if len(ref.UID) == 0 {
+		return nil
+	}
+	return &ref
+}
+
 // regeneratePodName regenerates the name of this checker pod with a new name string
 func (ext *Checker) regeneratePodName() {
 	var err error
@@ -194,6 +270,55 @@ func (ext *Checker) podName() string {
 	return ext.checkPodName
 }
 
+// TriggerRun asks the check to run right away instead of waiting for its next run.  Returns false if a run
+// has already been requested and has not started yet.
+func (ext *Checker) TriggerRun() bool {
+	select {
+	case ext.RunNow <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// RequestRun asks the check to run right away and returns the UUID that the requested run will use.  If a run
+// has already been requested and has not started yet, the UUID of that run is returned.
+func (ext *Checker) RequestRun() string {
+	ext.runRequestMu.Lock()
+	defer ext.runRequestMu.Unlock()
+
+	if len(ext.requestedRunUUID) == 0 {
+		ext.requestedRunUUID = uuid.New().String()
+	}
+	ext.TriggerRun()
+	return ext.requestedRunUUID
+}
+
+// nextRunUUID returns the UUID for a new run of the check.  The UUID handed out by RequestRun is used if there
+// is one, otherwise a new UUID is generated.
+func (ext *Checker) nextRunUUID() string {
+	ext.runRequestMu.Lock()
+	defer ext.runRequestMu.Unlock()
+
+	if len(ext.requestedRunUUID) > 0 {
+		runUUID := ext.requestedRunUUID
+		ext.requestedRunUUID = ""
+		return runUUID
+	}
+	return uuid.New().String()
+}
+
+// PodName returns the name of the checker pod used for the current run
+func (ext *Checker) PodName() string {
+	return ext.podName()
+}
+
+// ReportDuration returns the time from checker pod start to report receipt in the last run.  Zero is returned if
+// the checker pod did not report in during the last run.
+func (ext *Checker) ReportDuration() time.Duration {
+	return ext.reportDuration
+}
+
 // CurrentStatus returns the status of the check as of right now.  For the external checker, this means checking
 // the khstatus resources on the cluster.
 func (ext *Checker) CurrentStatus() (bool, []string) {
@@ -254,7 +379,13 @@ func (ext *Checker) Run(ctx context.Context, client *kubernetes.Clientset) error
 
 	// run a check iteration
 	ext.log("Running external check iteration")
-	err = ext.RunOnce(ctx)
+	ext.recordEvent(apiv1.EventTypeNormal, EventReasonRunStarted, "Started run %s", ext.currentCheckUUID)
+	if ext.RunOnAllNodes {
+		err = ext.RunOnAllNodesOnce(ctx)
+	} else {
+		err = ext.RunOnce(ctx)
+	}
+	ext.recordRunResult(ctx, err)
 
 	// if the pod was removed, we skip this run gracefully
 	if err != nil && err.Error() == ErrPodRemovedExpectedly.Error() {
@@ -271,9 +402,18 @@ func (ext *Checker) Run(ctx context.Context, client *kubernetes.Clientset) error
 	return nil
 }
 
-// getCheck gets the CRD information for this check from the kubernetes API.
+// getCheck gets the CRD information for this check from the khcheck informer cache, or from the kubernetes API if
+// the khcheck is not cached.
 func (ext *Checker) getCheck() (*khcheckv1.KuberhealthyCheck, error) {
 
+	if ext.KHCheckLister != nil {
+		checkConfig, err := ext.KHCheckLister.KuberhealthyChecks(ext.Namespace).Get(ext.CheckName)
+		if err == nil {
+			return checkConfig.DeepCopy(), nil
+		}
+		log.Debugln("Check", ext.CheckName, "in namespace", ext.Namespace, "is not cached:", err)
+	}
+
 	// get the item in question and return it along with any errors
 	log.Debugln("Fetching check", ext.CheckName, "in namespace", ext.Namespace)
 	checkConfig, err := ext.KHCheckClient.KuberhealthyChecks(ext.Namespace).Get(ext.CheckName, metav1.GetOptions{})
@@ -321,6 +461,47 @@ func (ext *Checker) cleanup(ctx context.Context) {
 		}
 	}
 	wg.Wait()
+
+	// remove the network policy of the checker pods
+	ext.deleteNetworkPolicy(ctx)
+
+	// remove the client certificate of the checker pods
+	ext.deleteClientCertSecret(ctx)
+}
+
+// cleanupRun cleans up after the current run, unless the run was left in flight for another Kuberhealthy pod to
+// resume
+func (ext *Checker) cleanupRun(ctx context.Context, trace *runTrace) {
+	if ext.LeftInFlight() {
+		ext.log("leaving checker pod", ext.podName(), "running for the next master to resume")
+		err := ext.releaseCheckerPod()
+		if err != nil {
+			ext.log("error releasing checker pod", ext.podName(), "for the next master:", err)
+		}
+		return
+	}
+	trace.startPhase("cleanup")
+	ext.cleanup(ctx)
+}
+
+// keepFailureLogs captures the logs of the checker pod when the supplied result of a run is a failure, before the
+// pod is cleaned up
+func (ext *Checker) keepFailureLogs(ctx context.Context, err error) {
+	if err != nil && err.Error() == ErrPodRemovedExpectedly.Error() {
+		return
+	}
+	if err == nil {
+		if ok, _ := ext.CurrentStatus(); ok {
+			return
+		}
+	}
+	ext.failureLogs = ext.captureFailureLogs(ctx)
+}
+
+// Cleanup evicts the checker pods of this check that are still running and removes the resources created for them.
+// Runs clean up after themselves, but a run whose context was cancelled can not, so its pods are cleaned up with this.
+func (ext *Checker) Cleanup(ctx context.Context) {
+	ext.cleanup(ctx)
 }
 
 // evictPod evicts a pod in a namespace. If eviction fails, it will check if the pod still exists and if so, attempt to kill and then return any errors.
@@ -384,8 +565,9 @@ func (ext *Checker) setUUID(uuid string) error {
 		return nil
 	}
 
-	// assign the new uuid to the fetched checkState
+	// assign the new uuid to the fetched checkState and drop progress left over from an earlier run
 	checkState.Spec.CurrentUUID = uuid
+	checkState.Spec.Progress = nil
 	ext.log("Updating khstate to CurrentUUID:", checkState.Spec.CurrentUUID)
 	_, err = ext.KHStateClient.KuberhealthyStates(ext.CheckNamespace()).Update(&checkState)
 	if err != nil {
@@ -425,6 +607,7 @@ func (ext *Checker) setUUID(uuid string) error {
 			log.Errorln("failed to fetch khstate for check", checkState.Namespace, checkState.Name, "with error:", err)
 		}
 		checkState.Spec.CurrentUUID = uuid
+		checkState.Spec.Progress = nil
 		_, err = ext.KHStateClient.KuberhealthyStates(ext.CheckNamespace()).Update(&checkState)
 		if err != nil {
 			log.Errorln("failed to update khstate CurrentUUID for check", checkState.Namespace, checkState.Name, "with error:", err)
@@ -440,6 +623,11 @@ func (ext *Checker) setUUID(uuid string) error {
 // the context can be used to shutdown this checker gracefully.
 func (ext *Checker) watchForCheckerPodDelete(ctx context.Context) chan error {
 
+	// use the shared pod informer instead of opening a watch for this run when it is available
+	if ext.podWatcherSynced() {
+		return ext.waitForCachedPodDelete(ctx)
+	}
+
 	ext.wg.Add(1)
 	defer ext.wg.Done()
 
@@ -541,15 +729,36 @@ func (ext *Checker) newError(s string) error {
 
 // RunOnce runs one check loop.  This creates a checker pod and ensures it starts,
 // then ensures it changes to Running properly
-func (ext *Checker) RunOnce(ctx context.Context) error {
+func (ext *Checker) RunOnce(ctx context.Context) (err error) {
+
+	// regenerate the checker pod name with a new timestamp
+	ext.regeneratePodName()
+
+	// trace the phases of this run
+	trace := ext.newRunTrace(ctx)
+	trace.run.SetAttributes(tracing.String("kuberhealthy.pod", ext.podName()))
+	defer func() {
+		trace.end(err)
+	}()
 
 	// create a context for this run
 	ext.shutdownCTX, ext.shutdownCTXFunc = context.WithCancel(ctx)
 	defer ext.shutdownCTXFunc()
-	defer ext.cleanup(ctx)
+	defer func() {
+		ext.cleanupRun(ctx, trace)
+	}()
 
-	// regenerate the checker pod name with a new timestamp
-	ext.regeneratePodName()
+	ext.setInFlight(false)
+	ext.reportDuration = 0
+	ext.schedulingLatency = 0
+	ext.failureLogs = ""
+	ext.podStartTime = time.Time{}
+	ext.podCreateTime = time.Time{}
+
+	// capture the logs of the checker pod when the run fails, before the pod is cleaned up
+	defer func() {
+		ext.keepFailureLogs(ctx, err)
+	}()
 
 	// fetch the currently known lastReportTime for this check.  We will use this to know when the pod has
 	// fully reported back with a status before exiting
@@ -579,18 +788,25 @@ func (ext *Checker) RunOnce(ctx context.Context) error {
 
 	// sanity check our settings
 	ext.log("Running sanity check on check parameters")
-	err = ext.sanityCheck()
+	err = ext.sanityCheck(ctx)
 	if err != nil {
 		return err
 	}
 
+	// make sure the checker pod runs under a service account of its own namespace that exists
+	err = ext.validateServiceAccount(ctx)
+	if err != nil {
+		return ext.newError(err.Error())
+	}
+
 	// waiting until all checker pods are gone...
 	ext.log("Waiting for all existing pods to clean up")
+	trace.startPhase("wait for previous pods to clear")
 	select {
 	case <-timeoutChan:
 		ext.log("timed out waiting for all existing pods to clean up")
 		errorMessage := "failed to see pod cleanup within timeout"
-		return ext.newError(errorMessage)
+		return ext.newReasonError(khstatev1.FailureReasonTimeout, errorMessage)
 	case err = <-ext.waitForAllPodsToClear(ctx):
 		if err != nil {
 			errorMessage := "error waiting for pod to clean up: " + err.Error()
@@ -611,21 +827,64 @@ func (ext *Checker) RunOnce(ctx context.Context) error {
 	podDeletedChan := ext.watchForCheckerPodDelete(podShutdownWatchCtx)
 	defer podShutdownWatchCtxCancel()
 
+	// let the checker pod reach kuberhealthy in clusters with default-deny network policies
+	err = ext.ensureNetworkPolicy(ctx)
+	if err != nil {
+		return ext.newError("failed to create network policy for checker pod: " + err.Error())
+	}
+
+	// give the checker pod a client certificate for this run if reports must use mutual TLS
+	err = ext.ensureClientCertSecret(ctx, deadline)
+	if err != nil {
+		return ext.newError("failed to issue client certificate for checker pod: " + err.Error())
+	}
+
 	// Spawn kubernetes pod to run our external check
 	ext.log("creating pod for external check:", ext.CheckName)
 	ext.log("checker pod annotations and labels:", ext.ExtraAnnotations, ext.ExtraLabels)
+	trace.startPhase("create pod")
 	createdPod, err := ext.createPod(ctx)
 	if err != nil {
 		ext.log("error creating pod")
 		return ext.newError("failed to create pod for checker: " + err.Error())
 	}
 	ext.log("Check", ext.Name(), "created pod", createdPod.Name, "in namespace", createdPod.Namespace)
+	ext.podCreateTime = time.Now()
+
+	// record the checker pod on the khstate, so that the run can be resumed if kuberhealthy restarts before it completes
+	err = ext.setInFlightRun(time.Now(), deadline)
+	if err != nil {
+		ext.log("error recording run in flight on khstate:", err)
+	} else {
+		ext.setInFlight(true)
+	}
+
+	return ext.superviseRun(ctx, trace, timeoutChan, lastReportTime, podDeletedChan, podShutdownWatchCtxCancel)
+}
+
+// superviseRun waits for the checker pod of the current run to start, report in and exit.  The run fails if the
+// timeout channel fires first, or if the checker pod is removed or killed by kubernetes.
+func (ext *Checker) superviseRun(ctx context.Context, trace *runTrace, timeoutChan <-chan time.Time, lastReportTime metav1.Time, podDeletedChan chan error, podShutdownWatchCtxCancel context.CancelFunc) error {
+
+	// watch for the scheduler being unable to place the checker pod, so that the run fails without waiting for
+	// the timeout
+	podSchedulingCtx, podSchedulingCtxCancel := context.WithCancel(ctx)
+	podUnschedulableChan := ext.waitForPodUnschedulable(podSchedulingCtx)
+	imagePullFailedChan := ext.waitForImagePullFailure(podSchedulingCtx)
+	defer podSchedulingCtxCancel()
 
 	// watch for pod to start with a timeout (include time for a new node to be created)
+	trace.startPhase("wait for pod scheduling")
 	select {
 	case <-timeoutChan: // were out of time
 		ext.log("timed out waiting for pod to startup")
-		return ext.newError("failed to see pod running within timeout")
+		return ext.newReasonError(ext.startFailureReason(ctx), "failed to see pod running within timeout")
+	case err := <-podUnschedulableChan: // pod can not be scheduled
+		ext.log(err.Error())
+		return ext.newReasonError(khstatev1.FailureReasonPodSchedulingFailed, err.Error())
+	case err := <-imagePullFailedChan: // pod can not pull its image
+		ext.log(err.Error())
+		return ext.newReasonError(khstatev1.FailureReasonImagePullError, err.Error())
 	case err := <-podDeletedChan: // pod removed unexpectedly
 		if err != nil {
 			ext.log("error from pod shutdown watcher when watching for checker pod to start:", err.Error())
@@ -634,28 +893,54 @@ func (ext *Checker) RunOnce(ctx context.Context) error {
 		}
 		ext.log("pod removed expectedly. pod shutdown monitor shutting down")
 		return ErrPodRemovedExpectedly
-	case err = <-ext.waitForPodStart(ctx): // pod started
+	case err := <-ext.waitForPodStart(ctx): // pod started
 		if err != nil {
 			ext.cleanup(ctx)
 			errorMessage := "error when waiting for pod to start: " + err.Error()
 			ext.log(errorMessage)
-			return ext.newError(errorMessage)
+			reason := khstatev1.FailureReasonExecutionError
+			if errors.Is(err, ErrPodDeletedBeforeRunning) {
+				reason = khstatev1.FailureReasonReaperKilled
+			}
+			return ext.newReasonError(reason, errorMessage)
 		}
-		// flag the pod as running until this run ends
+		// flag the pod as running until this run ends.  resumed runs keep the time their pod started.
 		ext.log("External check pod is running:", ext.podName())
+		if ext.podStartTime.IsZero() {
+			ext.podStartTime = time.Now()
+		}
+		if !ext.podCreateTime.IsZero() {
+			ext.schedulingLatency = time.Since(ext.podCreateTime)
+			ext.log("External check pod started running", ext.schedulingLatency, "after it was created")
+		}
 	case <-ext.shutdownCTX.Done(): // shutdown signal
 		ext.log("shutting down check. aborting watch for pod to start")
 		return nil
 	}
 
+	podSchedulingCtxCancel()
+
+	// watch for the checker pod being evicted or running out of memory, which keeps it from ever reporting in
+	podTerminationCtx, podTerminationCtxCancel := context.WithCancel(ctx)
+	podTerminatedChan := ext.waitForPodTermination(podTerminationCtx)
+	defer podTerminationCtxCancel()
+
 	// validate that the pod was able to update its khstate
 	ext.log("Waiting for pod status to be reported from pod", ext.podName(), "in namespace", ext.Namespace)
+	trace.startPhase("wait for report")
 	select {
 	case <-timeoutChan: // out of time
 		ext.log("timed out waiting for pod status to be reported")
-		errorMessage := "timed out waiting for checker pod to report in"
+		reason, details := ext.terminationDetails(ctx)
+		if len(details) == 0 {
+			reason = khstatev1.FailureReasonTimeout
+		}
+		errorMessage := "timed out waiting for checker pod to report in" + details
 		ext.log(errorMessage)
-		return ext.newError(errorMessage)
+		return ext.newReasonError(reason, errorMessage)
+	case err := <-podTerminatedChan: // pod was killed by kubernetes
+		ext.log(err.Error())
+		return ext.newReasonError(FailureReasonOf(err), err.Error())
 	case err := <-podDeletedChan: // pod was removed
 		if err != nil {
 			ext.log("error from pod shutdown watcher when watching for checker pod to report results:", err.Error())
@@ -664,13 +949,14 @@ func (ext *Checker) RunOnce(ctx context.Context) error {
 		}
 		ext.log("pod removed expectedly. pod shutdown monitor shutting down")
 		return ErrPodRemovedExpectedly
-	case err = <-ext.waitForPodStatusUpdate(lastReportTime): // pod reported in
+	case err := <-ext.waitForPodStatusUpdate(lastReportTime): // pod reported in
 		if err != nil {
 			errorMessage := "found an error when waiting for pod status to update: " + err.Error()
 			ext.log(errorMessage)
 			return ext.newError(errorMessage)
 		}
-		ext.log("External check pod has reported status for this check iteration:", ext.podName())
+		ext.reportDuration = time.Since(ext.podStartTime)
+		ext.log("External check pod has reported status for this check iteration:", ext.podName(), "after", ext.reportDuration)
 	case <-ext.shutdownCTX.Done(): // shutdown signal
 		ext.log("shutting down check. aborting wait for pod status to update")
 		return nil
@@ -678,14 +964,17 @@ func (ext *Checker) RunOnce(ctx context.Context) error {
 
 	// after the pod reports in, we no longer want to watch for it to be removed, so we shut that waiter down
 	podShutdownWatchCtxCancel()
+	podTerminationCtxCancel()
 
 	// validate that the pod stopped running properly (wait for the pod to exit)
+	trace.startPhase("wait for pod exit")
 	select {
 	case <-timeoutChan: // out of time
-		errorMessage := "timed out waiting for pod to exit"
+		_, details := ext.terminationDetails(ctx)
+		errorMessage := "timed out waiting for pod to exit" + details
 		ext.log(errorMessage)
-		return ext.newError(errorMessage)
-	case err = <-ext.waitForPodExit(ctx): // pod stopped running
+		return ext.newReasonError(khstatev1.FailureReasonTimeout, errorMessage)
+	case err := <-ext.waitForPodExit(ctx): // pod stopped running
 		ext.log("External check pod is done running:", ext.podName())
 		if err != nil {
 			errorMessage := "found an error when waiting for pod to exit: " + err.Error()
@@ -707,7 +996,7 @@ func (ext *Checker) log(s ...interface{}) {
 }
 
 // sanityCheck runs a basic sanity check on the checker before running
-func (ext *Checker) sanityCheck() error {
+func (ext *Checker) sanityCheck(ctx context.Context) error {
 	if ext.Namespace == "" {
 		return errors.New("check namespace can not be empty")
 	}
@@ -720,6 +1009,30 @@ func (ext *Checker) sanityCheck() error {
 		return errors.New("pod has no configured containers")
 	}
 
+	// only images from approved registries may be run
+	images := disallowedImages(ext.PodSpec, ext.AllowedImageRegistries)
+	if len(images) > 0 {
+		return errors.New("pod uses images that are not from an allowed image registry: " + strings.Join(images, ", "))
+	}
+
+	// only namespaces that a cluster operator opted in may run pods in the host network or process namespace
+	if problem := HostAccessProblem(ext.PodSpec, ext.Namespace, ext.HostAccessSettings); len(problem) > 0 {
+		return errors.New(problem)
+	}
+
+	// pods that would be refused by the pod security admission of their namespace are never created
+	if problem := PodSecurityProblem(ext.PodSpec, ext.PodSecurity); len(problem) > 0 {
+		return errors.New(problem)
+	}
+
+	// only images signed by a trusted signer may be run
+	if ext.ImageVerifier != nil {
+		err := ext.verifyImages(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -940,6 +1253,11 @@ func (ext *Checker) waitForPodStart(ctx context.Context) chan error {
 
 	ext.log("waiting for pod to be running")
 
+	// use the shared pod informer instead of opening a watch for this run when it is available
+	if ext.podWatcherSynced() {
+		return ext.waitForCachedPodStart()
+	}
+
 	// make the output channel we will return
 	outChan := make(chan error, 50)
 
@@ -995,21 +1313,9 @@ func (ext *Checker) waitForPodStart(ctx context.Context) chan error {
 					continue
 				}
 
-				// catch when the pod has an error image pull and return it as an error #201
-				for _, containerStat := range p.Status.ContainerStatuses {
-					if containerStat.State.Waiting == nil {
-						continue
-					}
-					if containerStat.State.Waiting.Reason == "ErrImagePull" {
-						ext.log("pod had an error image pull")
-						outChan <- errors.New(containerStat.State.Waiting.Reason)
-						watcher.Stop()
-						return
-					}
-				}
 				// read the status of this pod (its ours)
 				ext.log("pod state is now:", string(p.Status.Phase))
-				if p.Status.Phase == apiv1.PodRunning || p.Status.Phase == apiv1.PodFailed || p.Status.Phase == apiv1.PodSucceeded {
+				if podStarted(p) {
 					ext.log("pod is now either running, failed, or succeeded")
 					outChan <- nil
 					watcher.Stop()
@@ -1033,6 +1339,12 @@ func (ext *Checker) waitForPodStart(ctx context.Context) chan error {
 	return outChan
 }
 
+// podStarted returns true if the supplied checker pod has advanced beyond 'Pending'.  Pods that fail to pull their
+// image are caught by waitForImagePullFailure.
+func podStarted(p *apiv1.Pod) bool {
+	return p.Status.Phase == apiv1.PodRunning || p.Status.Phase == apiv1.PodFailed || p.Status.Phase == apiv1.PodSucceeded
+}
+
 // validatePodSpec validates the user specified pod spec to ensure it looks like it
 // has all the default configuration required
 func (ext *Checker) validatePodSpec() error {
@@ -1070,6 +1382,17 @@ func (ext *Checker) createPod(ctx context.Context) (*apiv1.Pod, error) {
 	// enforce various labels and annotations on all checker pods created
 	ext.addKuberhealthyLabels(p)
 
+	err := ext.addOwnerReferences(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return ext.KubeClient.CoreV1().Pods(ext.Namespace).Create(ctx, p, metav1.CreateOptions{})
+}
+
+// addOwnerReferences sets the owner references of a checker pod
+func (ext *Checker) addOwnerReferences(p *apiv1.Pod) error {
+
 	// only set ownerReference for pods in the kuberhealthy namespace
 	// as cross-namespace owner references are disabled by design
 	if p.Namespace == kuberhealthyNamespace {
@@ -1077,14 +1400,20 @@ func (ext *Checker) createPod(ctx context.Context) (*apiv1.Pod, error) {
 		// Get ownerReference for the kuberhealthy pod
 		ownerRef, err := util.GetOwnerRef(ext.KubeClient, kuberhealthyNamespace)
 		if err != nil {
-			return nil, errors.New("Failed to getOwnerReference for pod: " + p.Name + ", err: " + err.Error())
+			return errors.New("Failed to getOwnerReference for pod: " + p.Name + ", err: " + err.Error())
 		}
 
 		// Set ownerReference on checker pods in kuberhealthy namespace
 		p.OwnerReferences = ownerRef
 	}
 
-	return ext.KubeClient.CoreV1().Pods(ext.Namespace).Create(ctx, p, metav1.CreateOptions{})
+	// checker pods are also owned by their khcheck or khjob, which is always in the same namespace, so that they
+	// are garbage collected when it is deleted
+	if ext.OwnerReference != nil {
+		p.OwnerReferences = append(p.OwnerReferences, *ext.OwnerReference)
+	}
+
+	return nil
 }
 
 // configureUserPodSpec configures a user-specified pod spec with
@@ -1094,7 +1423,17 @@ func (ext *Checker) createPod(ctx context.Context) (*apiv1.Pod, error) {
 func (ext *Checker) configureUserPodSpec(deadline time.Time) error {
 
 	// start with a fresh spec each time we regenerate the spec
-	ext.PodSpec = ext.OriginalPodSpec
+	ext.PodSpec = *ext.OriginalPodSpec.DeepCopy()
+
+	// merge in the global pod defaults that the user has not overridden
+	ext.PodDefaults.applySpec(&ext.PodSpec)
+
+	// harden the pod to run in namespaces that enforce the pod security standards
+	ext.PodSecurity.applySpec(&ext.PodSpec)
+
+	// add the environment of the khcheck to every container.  variables that kuberhealthy sets are applied after
+	// it, so they can not be replaced.
+	addExtraEnv(&ext.PodSpec, ext.ExtraEnv, ext.EnvFrom)
 
 	// specify environment variables that need applied.  We apply environment
 	// variables that set the report-in URL of kuberhealthy along with
@@ -1122,12 +1461,30 @@ func (ext *Checker) configureUserPodSpec(deadline time.Time) error {
 		},
 	}
 
+	// tell checker pods where the gRPC reporting API is if it is enabled
+	if len(ext.KuberhealthyGRPCAddress) > 0 {
+		overwriteEnvVars = append(overwriteEnvVars, apiv1.EnvVar{
+			Name:  KHReportingGRPCAddress,
+			Value: ext.KuberhealthyGRPCAddress,
+		})
+	}
+
 	// apply overwrite env vars on every container in the pod
 	for i := range ext.PodSpec.Containers {
-		ext.PodSpec.Containers[i].Env = resetInjectedContainerEnvVars(ext.PodSpec.Containers[i].Env, []string{KHReportingURL, KHRunUUID, KHPodNamespace, KHDeadline})
+		ext.PodSpec.Containers[i].Env = resetInjectedContainerEnvVars(ext.PodSpec.Containers[i].Env, []string{KHReportingURL, KHReportingGRPCAddress, KHRunUUID, KHPodNamespace, KHDeadline})
 		ext.PodSpec.Containers[i].Env = append(ext.PodSpec.Containers[i].Env, overwriteEnvVars...)
 	}
 
+	// give checker pods a service account token to authenticate their reports with
+	if ext.ReportTokenAuth {
+		addReportTokenVolume(&ext.PodSpec)
+	}
+
+	// give checker pods a client certificate to authenticate their reports with over mutual TLS
+	if ext.ClientCertIssuer != nil {
+		addClientCertVolume(&ext.PodSpec, ext.clientCertSecretName())
+	}
+
 	// enforce restart policy of never
 	ext.PodSpec.RestartPolicy = apiv1.RestartPolicyNever
 
@@ -1168,6 +1525,9 @@ func (ext *Checker) addKuberhealthyLabels(pod *apiv1.Pod) {
 		pod.ObjectMeta.Annotations[k] = v
 	}
 
+	// fill in the global default labels and annotations that were not set by the khcheck
+	ext.PodDefaults.applyMetadata(pod.ObjectMeta.Labels, pod.ObjectMeta.Annotations)
+
 	// overwrite the check name annotation for use with calling pod validation
 	pod.ObjectMeta.Annotations[KHCheckNameAnnotationKey] = ext.CheckName
 
@@ -1175,8 +1535,7 @@ func (ext *Checker) addKuberhealthyLabels(pod *apiv1.Pod) {
 
 // createCheckUUID creates a UUID that represents a single run of the external check
 func (ext *Checker) setNewCheckUUID() error {
-	uniqueID := uuid.New()
-	ext.currentCheckUUID = uniqueID.String()
+	ext.currentCheckUUID = ext.nextRunUUID()
 	log.Debugln("Generated new UUID for external check:", ext.currentCheckUUID)
 
 	// set whitelist in check configuration CRD so only this
@@ -125,3 +125,24 @@ func TestShutdown(t *testing.T) {
 		t.Log("Check shutdown properly and without error")
 	}
 }
+
+// TestNewCheckOwnerReference ensures that checker pods are owned by their khcheck once it exists in the cluster
+func TestNewCheckOwnerReference(t *testing.T) {
+	kc := &khcheckv1.KuberhealthyCheck{}
+	kc.Name = "test-check"
+	kc.Namespace = "kuberhealthy"
+
+	c := NewCheck(nil, kc, nil, nil, "")
+	if c.OwnerReference != nil {
+		t.Fatal("Expected no owner reference for a khcheck without a UID")
+	}
+
+	kc.UID = "5a8b5b8e-0e8b-4f0e-9f3c-6c1c3f0f8d1a"
+	c = NewCheck(nil, kc, nil, nil, "")
+	if c.OwnerReference == nil {
+		t.Fatal("Expected an owner reference for a khcheck with a UID")
+	}
+	if c.OwnerReference.Kind != "KuberhealthyCheck" || c.OwnerReference.Name != "test-check" || c.OwnerReference.UID != kc.UID {
+		t.Fatalf("Expected the owner reference to refer to the khcheck but got %+v", c.OwnerReference)
+	}
+}
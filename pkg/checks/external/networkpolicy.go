@@ -0,0 +1,136 @@
+package external
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// networkPolicyNameSuffix is appended to the check name to name the NetworkPolicy of its checker pods
+const networkPolicyNameSuffix = "-kh-egress"
+
+// namespaceNameLabel is the label that kubernetes sets on every namespace to its name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// defaultKuberhealthyPodLabels are the labels of the kuberhealthy pods that checker pods report in to
+var defaultKuberhealthyPodLabels = map[string]string{"app": "kuberhealthy"}
+
+// NetworkPolicySettings holds settings for the NetworkPolicies that are created for checker pods so that they can
+// report in to Kuberhealthy in clusters with default-deny network policies.
+type NetworkPolicySettings struct {
+	Enabled               bool              `yaml:"enabled"`                         // create a NetworkPolicy for the checker pods of every check and job
+	KuberhealthyPodLabels map[string]string `yaml:"kuberhealthyPodLabels,omitempty"` // the labels of the kuberhealthy pods. defaults to app: kuberhealthy
+}
+
+// kuberhealthyPodLabels returns the labels that select the kuberhealthy pods checker pods report in to
+func (s NetworkPolicySettings) kuberhealthyPodLabels() map[string]string {
+	if len(s.KuberhealthyPodLabels) == 0 {
+		return defaultKuberhealthyPodLabels
+	}
+	return s.KuberhealthyPodLabels
+}
+
+// networkPolicyName returns the name of the NetworkPolicy created for the checker pods of this check
+func (ext *Checker) networkPolicyName() string {
+	return ext.CheckName + networkPolicyNameSuffix
+}
+
+// newNetworkPolicy builds the NetworkPolicy for the checker pods of this check.  The policy allows egress to the
+// kuberhealthy pods and to DNS, along with the egress rules declared by the khcheck.
+func (ext *Checker) newNetworkPolicy() *networkingv1.NetworkPolicy {
+	udp := apiv1.ProtocolUDP
+	tcp := apiv1.ProtocolTCP
+	dnsPort := intstr.FromInt32(53)
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: []networkingv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: kuberhealthyNamespace},
+				},
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: ext.NetworkPolicySettings.kuberhealthyPodLabels(),
+				},
+			}},
+		},
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		},
+	}
+	if ext.NetworkPolicy != nil {
+		for _, rule := range ext.NetworkPolicy.Egress {
+			egress = append(egress, *rule.DeepCopy())
+		}
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ext.networkPolicyName(),
+			Namespace: ext.Namespace,
+			Labels: map[string]string{
+				kuberhealthyCheckNameLabel: ext.CheckName,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{kuberhealthyCheckNameLabel: ext.CheckName},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}
+
+	// the policy is garbage collected along with its khcheck or khjob
+	if ext.OwnerReference != nil {
+		np.OwnerReferences = []metav1.OwnerReference{*ext.OwnerReference}
+	}
+
+	return np
+}
+
+// ensureNetworkPolicy creates or updates the NetworkPolicy for the checker pods of this check, if one is wanted
+func (ext *Checker) ensureNetworkPolicy(ctx context.Context) error {
+	if ext.NetworkPolicy == nil {
+		return nil
+	}
+
+	np := ext.newNetworkPolicy()
+	client := ext.KubeClient.NetworkingV1().NetworkPolicies(ext.Namespace)
+	existing, err := client.Get(ctx, np.Name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		ext.log("creating network policy", np.Name)
+		_, err = client.Create(ctx, np, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	ext.log("updating network policy", np.Name)
+	existing.Labels = np.Labels
+	existing.OwnerReferences = np.OwnerReferences
+	existing.Spec = np.Spec
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteNetworkPolicy removes the NetworkPolicy for the checker pods of this check, if one was wanted
+func (ext *Checker) deleteNetworkPolicy(ctx context.Context) {
+	if ext.NetworkPolicy == nil {
+		return
+	}
+
+	name := ext.networkPolicyName()
+	ext.log("deleting network policy", name)
+	err := ext.KubeClient.NetworkingV1().NetworkPolicies(ext.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		ext.log("error deleting network policy", name+":", err)
+	}
+}
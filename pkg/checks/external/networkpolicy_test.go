@@ -0,0 +1,64 @@
+package external
+
+import (
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	khcheckv1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khcheck/v1"
+)
+
+// TestNewNetworkPolicy ensures that the network policy of a check selects its checker pods and allows egress to
+// kuberhealthy, DNS and the declared targets
+func TestNewNetworkPolicy(t *testing.T) {
+	targetPort := intstr.FromInt32(443)
+	declared := networkingv1.NetworkPolicyEgressRule{
+		Ports: []networkingv1.NetworkPolicyPort{{Port: &targetPort}},
+	}
+	ext := &Checker{
+		CheckName:      "test-check",
+		Namespace:      "checks",
+		OwnerReference: &metav1.OwnerReference{Kind: "KuberhealthyCheck", Name: "test-check"},
+		NetworkPolicy: &khcheckv1.CheckNetworkPolicy{
+			Egress: []networkingv1.NetworkPolicyEgressRule{declared},
+		},
+	}
+
+	np := ext.newNetworkPolicy()
+	if np.Name != "test-check-kh-egress" || np.Namespace != "checks" {
+		t.Fatalf("Expected the network policy checks/test-check-kh-egress but got %s/%s", np.Namespace, np.Name)
+	}
+	if np.Spec.PodSelector.MatchLabels[kuberhealthyCheckNameLabel] != "test-check" {
+		t.Fatalf("Expected the network policy to select the checker pods but got %+v", np.Spec.PodSelector)
+	}
+	if len(np.Spec.PolicyTypes) != 1 || np.Spec.PolicyTypes[0] != networkingv1.PolicyTypeEgress {
+		t.Fatalf("Expected an egress only network policy but got %+v", np.Spec.PolicyTypes)
+	}
+	if len(np.OwnerReferences) != 1 || np.OwnerReferences[0].Name != "test-check" {
+		t.Fatalf("Expected the network policy to be owned by its khcheck but got %+v", np.OwnerReferences)
+	}
+
+	if len(np.Spec.Egress) != 3 {
+		t.Fatalf("Expected 3 egress rules but got %d", len(np.Spec.Egress))
+	}
+	peer := np.Spec.Egress[0].To[0]
+	if peer.NamespaceSelector.MatchLabels[namespaceNameLabel] != kuberhealthyNamespace || peer.PodSelector.MatchLabels["app"] != "kuberhealthy" {
+		t.Fatalf("Expected the first egress rule to allow the kuberhealthy pods but got %+v", peer)
+	}
+	if len(np.Spec.Egress[1].Ports) != 2 || np.Spec.Egress[1].Ports[0].Port.IntValue() != 53 {
+		t.Fatalf("Expected the second egress rule to allow DNS but got %+v", np.Spec.Egress[1])
+	}
+	if np.Spec.Egress[2].Ports[0].Port.IntValue() != 443 {
+		t.Fatalf("Expected the declared egress rule to be added but got %+v", np.Spec.Egress[2])
+	}
+
+	// custom kuberhealthy pod labels are used instead of the default
+	ext.NetworkPolicySettings.KuberhealthyPodLabels = map[string]string{"app.kubernetes.io/name": "kuberhealthy"}
+	np = ext.newNetworkPolicy()
+	labels := np.Spec.Egress[0].To[0].PodSelector.MatchLabels
+	if len(labels) != 1 || labels["app.kubernetes.io/name"] != "kuberhealthy" {
+		t.Fatalf("Expected the custom kuberhealthy pod labels but got %+v", labels)
+	}
+}
@@ -0,0 +1,322 @@
+package external
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// nodeReportPollInterval is how often the khstate is checked for reports from the checker pods of a run on all nodes
+const nodeReportPollInterval = time.Second * 5
+
+// nodeResultsMaxTries is the number of times storing the aggregated result of a run on all nodes is attempted
+const nodeResultsMaxTries = 5
+
+// RunOnAllNodesOnce runs one check loop of a check that runs on all nodes.  A checker pod is created on every
+// schedulable node and the reports of all of them are aggregated into the result of the check.  Nodes whose checker
+// pod fails or does not report in are recorded as failed nodes on the khstate of the check.
+func (ext *Checker) RunOnAllNodesOnce(ctx context.Context) error {
+
+	// regenerate the checker pod name with a new timestamp
+	ext.regeneratePodName()
+
+	// create a context for this run
+	ext.shutdownCTX, ext.shutdownCTXFunc = context.WithCancel(ctx)
+	defer ext.shutdownCTXFunc()
+	defer ext.cleanup(ctx)
+
+	ext.reportDuration = 0
+	ext.schedulingLatency = 0
+	ext.failureLogs = ""
+
+	// validate the pod spec
+	ext.log("Validating pod spec of external check")
+	err := ext.validatePodSpec()
+	if err != nil {
+		return err
+	}
+
+	// condition the spec with the required labels and environment variables
+	ext.log("Timeout set to", ext.RunTimeout.String())
+	deadline := time.Now().Add(ext.RunTimeout)
+	err = ext.configureUserPodSpec(deadline)
+	if err != nil {
+		return ext.newError("failed to configure pod spec for Kubernetes from user specified pod spec: " + err.Error())
+	}
+
+	// sanity check our settings
+	err = ext.sanityCheck(ctx)
+	if err != nil {
+		return err
+	}
+
+	// find the nodes to run on
+	nodeList, err := ext.KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ext.newError("failed to list nodes to run checker pods on: " + err.Error())
+	}
+	nodes := schedulableNodes(nodeList.Items, ext.PodSpec)
+	if len(nodes) == 0 {
+		return ext.newError("found no schedulable nodes to run checker pods on")
+	}
+
+	// let the checker pods reach kuberhealthy in clusters with default-deny network policies
+	err = ext.ensureNetworkPolicy(ctx)
+	if err != nil {
+		return ext.newError("failed to create network policy for checker pods: " + err.Error())
+	}
+
+	// create a checker pod on every node.  nodes that a pod can not be created on are failed right away.
+	ext.log("Creating checker pods on", len(nodes), "nodes")
+	podNames := make(map[string]string)
+	problems := make(map[string]string)
+	for i, nodeName := range nodes {
+		p := ext.newNodePod(nodeName, i)
+		err = ext.addOwnerReferences(p)
+		if err == nil {
+			_, err = ext.KubeClient.CoreV1().Pods(ext.Namespace).Create(ctx, p, metav1.CreateOptions{})
+		}
+		if err != nil {
+			ext.log("failed to create checker pod on node", nodeName+":", err)
+			problems[nodeName] = "failed to create checker pod: " + err.Error()
+			continue
+		}
+		podNames[nodeName] = p.Name
+	}
+	ext.podStartTime = time.Now()
+
+	// wait for all checker pods to report in or finish
+	reports := ext.waitForNodeReports(ctx, deadline, podNames, problems)
+	select {
+	case <-ext.shutdownCTX.Done():
+		ext.log("shutting down check. aborting wait for node reports")
+		return nil
+	default:
+	}
+	ext.reportDuration = time.Since(ext.podStartTime)
+
+	ok, errorMessages, failedNodes := aggregateNodeReports(nodes, reports, problems)
+	ext.log("Run on", len(nodes), "nodes completed with", len(failedNodes), "failed nodes:", failedNodes)
+	return ext.setNodeResults(ok, errorMessages, failedNodes)
+}
+
+// schedulableNodes returns the sorted names of the nodes that checker pods with the supplied spec can run on.  Nodes
+// that are cordoned, not ready, not matched by the node selector of the spec or tainted in a way that the spec does
+// not tolerate are left out.
+func schedulableNodes(nodes []apiv1.Node, podSpec apiv1.PodSpec) []string {
+	var names []string
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !nodeIsReady(node) {
+			continue
+		}
+		if !labels.SelectorFromSet(podSpec.NodeSelector).Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		if !toleratesTaints(node.Spec.Taints, podSpec.Tolerations) {
+			continue
+		}
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodeIsReady determines if the Ready condition of a node is true
+func nodeIsReady(node apiv1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == apiv1.NodeReady {
+			return condition.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// toleratesTaints determines if the supplied tolerations tolerate every taint that keeps pods off of a node
+func toleratesTaints(taints []apiv1.Taint, tolerations []apiv1.Toleration) bool {
+	for i := range taints {
+		if taints[i].Effect == apiv1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(&taints[i]) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// newNodePod creates the checker pod for a single node of a run on all nodes.  Each pod gets its own run UUID so
+// that its report can be told apart from the others, and is labeled with the UUID of the run that it belongs to.
+func (ext *Checker) newNodePod(nodeName string, index int) *apiv1.Pod {
+	p := &apiv1.Pod{}
+	p.Annotations = make(map[string]string)
+	p.Labels = make(map[string]string)
+	p.Namespace = ext.Namespace
+	p.Name = ext.podName() + "-" + strconv.Itoa(index)
+	p.Spec = *ext.PodSpec.DeepCopy()
+	p.Spec.NodeName = nodeName
+
+	// enforce various labels and annotations on all checker pods created
+	ext.addKuberhealthyLabels(p)
+
+	podRunUUID := uuid.New().String()
+	p.Labels[kuberhealthyRunIDLabel] = podRunUUID
+	p.Labels[KHNodeRunIDLabel] = ext.currentCheckUUID
+
+	nodeEnvVars := []apiv1.EnvVar{
+		{
+			Name:  KHRunUUID,
+			Value: podRunUUID,
+		},
+		{
+			Name:  KHNodeName,
+			Value: nodeName,
+		},
+	}
+	for i := range p.Spec.Containers {
+		p.Spec.Containers[i].Env = resetInjectedContainerEnvVars(p.Spec.Containers[i].Env, []string{KHRunUUID, KHNodeName})
+		p.Spec.Containers[i].Env = append(p.Spec.Containers[i].Env, nodeEnvVars...)
+	}
+
+	return p
+}
+
+// waitForNodeReports waits until every checker pod of the current run has reported in or stopped running, the
+// deadline passes or the check is shut down.  The reports received are returned.  Problems with the checker pods
+// of nodes that did not report in are added to the supplied problems.
+func (ext *Checker) waitForNodeReports(ctx context.Context, deadline time.Time, podNames map[string]string, problems map[string]string) map[string]khstatev1.NodeReport {
+
+	ext.log("Waiting for checker pods on", len(podNames), "nodes to report in")
+	reports := make(map[string]khstatev1.NodeReport)
+	for {
+		// find the checker pods that are done.  pods are listed before the reports are collected, because checker pods
+		// report in before they exit.
+		finished := make(map[string]string)
+		pods, err := ext.KubeClient.CoreV1().Pods(ext.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: KHNodeRunIDLabel + "=" + ext.currentCheckUUID,
+		})
+		if err != nil {
+			ext.log("error listing checker pods while waiting for node reports:", err)
+		}
+		if err == nil {
+			for i := range pods.Items {
+				p := &pods.Items[i]
+				_, reason := podTerminationReason(p)
+				if len(reason) == 0 && (p.Status.Phase == apiv1.PodSucceeded || p.Status.Phase == apiv1.PodFailed) {
+					reason = "checker pod " + p.Name + " exited with phase " + string(p.Status.Phase) + " without reporting in"
+				}
+				if len(reason) > 0 {
+					finished[p.Spec.NodeName] = reason
+				}
+			}
+		}
+
+		// collect the reports of the current run
+		state, err := ext.getKHState()
+		if err != nil {
+			ext.log("error fetching khstate while waiting for node reports:", err)
+		}
+		for nodeName, report := range state.Spec.NodeReports {
+			if _, ok := podNames[nodeName]; ok && report.UUID == ext.currentCheckUUID {
+				reports[nodeName] = report
+			}
+		}
+
+		waiting := 0
+		for nodeName := range podNames {
+			if _, ok := reports[nodeName]; !ok {
+				if _, ok := finished[nodeName]; !ok {
+					waiting++
+				}
+			}
+		}
+
+		// record what happened to the nodes that did not report in when we are done waiting
+		timedOut := time.Now().After(deadline)
+		if waiting == 0 || timedOut {
+			for nodeName, podName := range podNames {
+				if _, ok := reports[nodeName]; ok {
+					continue
+				}
+				if reason, ok := finished[nodeName]; ok {
+					problems[nodeName] = reason
+					continue
+				}
+				problems[nodeName] = "timed out waiting for checker pod " + podName + " to report in"
+			}
+			return reports
+		}
+
+		ext.log("Waiting for", waiting, "of", len(podNames), "checker pods to report in")
+		select {
+		case <-ext.shutdownCTX.Done():
+			return reports
+		case <-time.After(nodeReportPollInterval):
+		}
+	}
+}
+
+// aggregateNodeReports combines the reports and problems of every node of a run on all nodes into the result of the
+// check.  The check is only OK if every node reported OK.  Errors are prefixed with the name of their node.
+func aggregateNodeReports(nodes []string, reports map[string]khstatev1.NodeReport, problems map[string]string) (bool, []string, []string) {
+	errorMessages := []string{}
+	failedNodes := []string{}
+	for _, nodeName := range nodes {
+		report, reported := reports[nodeName]
+		if reported && report.OK {
+			continue
+		}
+		failedNodes = append(failedNodes, nodeName)
+		if !reported {
+			errorMessages = append(errorMessages, nodeName+": "+problems[nodeName])
+			continue
+		}
+		for _, e := range report.Errors {
+			errorMessages = append(errorMessages, nodeName+": "+e)
+		}
+	}
+	return len(failedNodes) == 0, errorMessages, failedNodes
+}
+
+// setNodeResults stores the aggregated result of a run on all nodes on the khstate of the check and clears the
+// reports of the individual nodes
+func (ext *Checker) setNodeResults(ok bool, errorMessages []string, failedNodes []string) error {
+	var err error
+	for tries := 0; tries < nodeResultsMaxTries; tries++ {
+		var state khstatev1.KuberhealthyState
+		state, err = ext.getKHState()
+		if err != nil {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		state.Spec.OK = ok
+		state.Spec.Errors = errorMessages
+		state.Spec.FailedNodes = failedNodes
+		state.Spec.NodeReports = nil
+		_, err = ext.KHStateClient.KuberhealthyStates(ext.CheckNamespace()).Update(&state)
+		if err == nil {
+			return nil
+		}
+		if !k8sErrors.IsConflict(err) {
+			break
+		}
+		ext.log("conflict storing the results of the run on all nodes. retrying")
+	}
+	return ext.newError("failed to store the results of the run on all nodes: " + err.Error())
+}
@@ -0,0 +1,138 @@
+package external
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// testNode creates a ready node with the supplied name and labels
+func testNode(name string, labels map[string]string) apiv1.Node {
+	node := apiv1.Node{}
+	node.Name = name
+	node.Labels = labels
+	node.Status.Conditions = []apiv1.NodeCondition{{Type: apiv1.NodeReady, Status: apiv1.ConditionTrue}}
+	return node
+}
+
+// TestSchedulableNodes ensures that only the nodes that a checker pod can run on are selected
+func TestSchedulableNodes(t *testing.T) {
+	cordoned := testNode("cordoned", nil)
+	cordoned.Spec.Unschedulable = true
+
+	notReady := testNode("not-ready", nil)
+	notReady.Status.Conditions[0].Status = apiv1.ConditionFalse
+
+	tainted := testNode("tainted", nil)
+	tainted.Spec.Taints = []apiv1.Taint{{Key: "dedicated", Value: "gpu", Effect: apiv1.TaintEffectNoSchedule}}
+
+	preferNoSchedule := testNode("prefer-no-schedule", nil)
+	preferNoSchedule.Spec.Taints = []apiv1.Taint{{Key: "busy", Effect: apiv1.TaintEffectPreferNoSchedule}}
+
+	nodes := []apiv1.Node{
+		testNode("worker-2", map[string]string{"pool": "workers"}),
+		testNode("worker-1", map[string]string{"pool": "workers"}),
+		testNode("infra-1", map[string]string{"pool": "infra"}),
+		cordoned,
+		notReady,
+		tainted,
+		preferNoSchedule,
+	}
+
+	testCases := map[string]struct {
+		podSpec  apiv1.PodSpec
+		expected []string
+	}{
+		"no node selector": {
+			podSpec:  apiv1.PodSpec{},
+			expected: []string{"infra-1", "prefer-no-schedule", "worker-1", "worker-2"},
+		},
+		"node selector": {
+			podSpec:  apiv1.PodSpec{NodeSelector: map[string]string{"pool": "workers"}},
+			expected: []string{"worker-1", "worker-2"},
+		},
+		"toleration": {
+			podSpec:  apiv1.PodSpec{Tolerations: []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}}},
+			expected: []string{"infra-1", "prefer-no-schedule", "tainted", "worker-1", "worker-2"},
+		},
+	}
+
+	for name, tc := range testCases {
+		actual := schedulableNodes(nodes, tc.podSpec)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("%s: expected nodes %v but got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+// TestNewNodePod ensures that checker pods for a node are pinned to the node and get their own run UUID
+func TestNewNodePod(t *testing.T) {
+	c := &Checker{
+		CheckName:        "node-check",
+		Namespace:        "kuberhealthy",
+		currentCheckUUID: "run-uuid",
+	}
+	c.PodSpec = apiv1.PodSpec{Containers: []apiv1.Container{{
+		Name:  "main",
+		Image: "kuberhealthy/test-check",
+		Env:   []apiv1.EnvVar{{Name: KHRunUUID, Value: "run-uuid"}},
+	}}}
+	c.regeneratePodName()
+
+	p := c.newNodePod("worker-1", 3)
+	if p.Spec.NodeName != "worker-1" {
+		t.Fatal("Expected the checker pod to run on worker-1 but it runs on", p.Spec.NodeName)
+	}
+	if p.Labels[KHNodeRunIDLabel] != "run-uuid" {
+		t.Fatal("Expected the checker pod to be labeled with the run UUID but got", p.Labels[KHNodeRunIDLabel])
+	}
+
+	env := make(map[string]string)
+	for _, e := range p.Spec.Containers[0].Env {
+		if _, exists := env[e.Name]; exists {
+			t.Fatal("Found duplicate environment variable", e.Name)
+		}
+		env[e.Name] = e.Value
+	}
+	if env[KHNodeName] != "worker-1" {
+		t.Fatal("Expected", KHNodeName, "to be worker-1 but got", env[KHNodeName])
+	}
+	if env[KHRunUUID] == "run-uuid" || env[KHRunUUID] != p.Labels[kuberhealthyRunIDLabel] {
+		t.Fatal("Expected the checker pod to have its own run UUID but got", env[KHRunUUID])
+	}
+	if len(c.PodSpec.Containers[0].Env) != 1 || len(c.PodSpec.NodeName) > 0 {
+		t.Fatal("Expected the pod spec of the check to be left unchanged")
+	}
+}
+
+// TestAggregateNodeReports ensures that the reports of all nodes are combined into the result of the check
+func TestAggregateNodeReports(t *testing.T) {
+	nodes := []string{"worker-1", "worker-2", "worker-3"}
+	reports := map[string]khstatev1.NodeReport{
+		"worker-1": {OK: true, Errors: []string{}},
+		"worker-2": {OK: false, Errors: []string{"dns lookup failed"}},
+	}
+	problems := map[string]string{
+		"worker-3": "timed out waiting for checker pod node-check-1-2 to report in",
+	}
+
+	ok, errorMessages, failedNodes := aggregateNodeReports(nodes, reports, problems)
+	if ok {
+		t.Fatal("Expected the check to fail")
+	}
+	expectedErrors := []string{"worker-2: dns lookup failed", "worker-3: timed out waiting for checker pod node-check-1-2 to report in"}
+	if !reflect.DeepEqual(errorMessages, expectedErrors) {
+		t.Fatalf("Expected errors %v but got %v", expectedErrors, errorMessages)
+	}
+	if !reflect.DeepEqual(failedNodes, []string{"worker-2", "worker-3"}) {
+		t.Fatal("Expected worker-2 and worker-3 to fail but got", failedNodes)
+	}
+
+	ok, errorMessages, failedNodes = aggregateNodeReports([]string{"worker-1"}, reports, problems)
+	if !ok || len(errorMessages) > 0 || len(failedNodes) > 0 {
+		t.Fatal("Expected the check to pass when every node reported OK")
+	}
+}
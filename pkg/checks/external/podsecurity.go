@@ -0,0 +1,324 @@
+package external
+
+import (
+	"errors"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// PodSecurityBaseline is the Pod Security Standards level that prevents known privilege escalations
+const PodSecurityBaseline = "baseline"
+
+// PodSecurityRestricted is the Pod Security Standards level that follows current pod hardening best practices
+const PodSecurityRestricted = "restricted"
+
+// baselineCapabilities are the capabilities that containers may add at the baseline level
+var baselineCapabilities = map[apiv1.Capability]bool{
+	"AUDIT_WRITE":      true,
+	"CHOWN":            true,
+	"DAC_OVERRIDE":     true,
+	"FOWNER":           true,
+	"FSETID":           true,
+	"KILL":             true,
+	"MKNOD":            true,
+	"NET_BIND_SERVICE": true,
+	"SETFCAP":          true,
+	"SETGID":           true,
+	"SETPCAP":          true,
+	"SETUID":           true,
+	"SYS_CHROOT":       true,
+}
+
+// safeSysctls are the sysctls that pods may set at the baseline level
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_fin_timeout":            true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+}
+
+// allowedSELinuxTypes are the SELinux types that pods may set at the baseline level
+var allowedSELinuxTypes = map[string]bool{
+	"":                 true,
+	"container_t":      true,
+	"container_init_t": true,
+	"container_kvm_t":  true,
+}
+
+// PodSecuritySettings holds the settings for running checker pods in namespaces that enforce the Pod Security
+// Standards
+type PodSecuritySettings struct {
+	Level     string `yaml:"level,omitempty"`     // the Pod Security Standards level that checker pods must meet, baseline or restricted. empty disables the compliance mode
+	RunAsUser *int64 `yaml:"runAsUser,omitempty"` // the user id that checker pods run as when they do not set one, for images that default to root
+}
+
+// enabled indicates if checker pods are hardened and held to a Pod Security Standards level
+func (ps PodSecuritySettings) enabled() bool {
+	return len(ps.Level) > 0
+}
+
+// restricted indicates if checker pods are held to the restricted level.  Unknown levels are treated as
+// restricted, so that a typo does not weaken the checks.
+func (ps PodSecuritySettings) restricted() bool {
+	return ps.Level != PodSecurityBaseline
+}
+
+// Validate returns an error if the level is not a Pod Security Standards level that checker pods can be held to
+func (ps PodSecuritySettings) Validate() error {
+	if !ps.enabled() || ps.Level == PodSecurityBaseline || ps.Level == PodSecurityRestricted {
+		return nil
+	}
+	return errors.New("unknown pod security level " + ps.Level + ", expected " + PodSecurityBaseline + " or " + PodSecurityRestricted)
+}
+
+// applySpec hardens the supplied pod spec with the securityContext settings of the restricted profile that it
+// does not set itself.  Settings that the pod spec sets are left untouched, so a check can still opt out of the
+// ones it does not need to meet its level, such as readOnlyRootFilesystem.
+func (ps PodSecuritySettings) applySpec(spec *apiv1.PodSpec) {
+	if !ps.enabled() {
+		return
+	}
+
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &apiv1.PodSecurityContext{}
+	}
+	if spec.SecurityContext.RunAsNonRoot == nil {
+		spec.SecurityContext.RunAsNonRoot = boolPtr(true)
+	}
+	if spec.SecurityContext.RunAsUser == nil && ps.RunAsUser != nil {
+		runAsUser := *ps.RunAsUser
+		spec.SecurityContext.RunAsUser = &runAsUser
+	}
+	if spec.SecurityContext.SeccompProfile == nil {
+		spec.SecurityContext.SeccompProfile = &apiv1.SeccompProfile{Type: apiv1.SeccompProfileTypeRuntimeDefault}
+	}
+
+	for i := range spec.InitContainers {
+		hardenContainer(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		hardenContainer(&spec.Containers[i])
+	}
+}
+
+// hardenContainer sets the container securityContext settings of the restricted profile that the container does
+// not set itself
+func hardenContainer(container *apiv1.Container) {
+	if container.SecurityContext == nil {
+		container.SecurityContext = &apiv1.SecurityContext{}
+	}
+	sc := container.SecurityContext
+	if sc.AllowPrivilegeEscalation == nil {
+		sc.AllowPrivilegeEscalation = boolPtr(false)
+	}
+	if sc.ReadOnlyRootFilesystem == nil {
+		sc.ReadOnlyRootFilesystem = boolPtr(true)
+	}
+	if sc.Capabilities == nil {
+		sc.Capabilities = &apiv1.Capabilities{}
+	}
+	if len(sc.Capabilities.Drop) == 0 {
+		sc.Capabilities.Drop = []apiv1.Capability{"ALL"}
+	}
+}
+
+// boolPtr returns a pointer to the supplied bool
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// PodSecurityProblem returns a description of how a checker pod spec violates the configured Pod Security
+// Standards level once it is hardened, or a blank string when it does not
+func PodSecurityProblem(spec apiv1.PodSpec, settings PodSecuritySettings) string {
+	if !settings.enabled() {
+		return ""
+	}
+	hardened := *spec.DeepCopy()
+	settings.applySpec(&hardened)
+
+	violations := baselineViolations(hardened)
+	if settings.restricted() {
+		violations = append(violations, restrictedViolations(hardened)...)
+	}
+	if len(violations) == 0 {
+		return ""
+	}
+	level := PodSecurityRestricted
+	if !settings.restricted() {
+		level = PodSecurityBaseline
+	}
+	return "podSpec violates the " + level + " pod security level: " + strings.Join(violations, ", ")
+}
+
+// podContainers returns every init container and container of a pod spec
+func podContainers(spec apiv1.PodSpec) []apiv1.Container {
+	containers := make([]apiv1.Container, 0, len(spec.InitContainers)+len(spec.Containers))
+	containers = append(containers, spec.InitContainers...)
+	return append(containers, spec.Containers...)
+}
+
+// baselineViolations returns the ways a pod spec violates the baseline level
+func baselineViolations(spec apiv1.PodSpec) []string {
+	var violations []string
+	if spec.HostNetwork {
+		violations = append(violations, "hostNetwork is set")
+	}
+	if spec.HostPID {
+		violations = append(violations, "hostPID is set")
+	}
+	if spec.HostIPC {
+		violations = append(violations, "hostIPC is set")
+	}
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			violations = append(violations, "volume "+volume.Name+" is a hostPath volume")
+		}
+	}
+
+	if psc := spec.SecurityContext; psc != nil {
+		if psc.SeccompProfile != nil && psc.SeccompProfile.Type == apiv1.SeccompProfileTypeUnconfined {
+			violations = append(violations, "the pod seccompProfile is Unconfined")
+		}
+		if !allowedSELinuxOptions(psc.SELinuxOptions) {
+			violations = append(violations, "the pod sets disallowed seLinuxOptions")
+		}
+		if psc.WindowsOptions != nil && psc.WindowsOptions.HostProcess != nil && *psc.WindowsOptions.HostProcess {
+			violations = append(violations, "the pod is a Windows hostProcess pod")
+		}
+		for _, sysctl := range psc.Sysctls {
+			if !safeSysctls[sysctl.Name] {
+				violations = append(violations, "sysctl "+sysctl.Name+" is not allowed")
+			}
+		}
+	}
+
+	for _, container := range podContainers(spec) {
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				violations = append(violations, "container "+container.Name+" uses a hostPort")
+				break
+			}
+		}
+
+		sc := container.SecurityContext
+		if sc == nil {
+			continue
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, "container "+container.Name+" is privileged")
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if !baselineCapabilities[capability] {
+					violations = append(violations, "container "+container.Name+" adds capability "+string(capability))
+				}
+			}
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == apiv1.SeccompProfileTypeUnconfined {
+			violations = append(violations, "container "+container.Name+" seccompProfile is Unconfined")
+		}
+		if !allowedSELinuxOptions(sc.SELinuxOptions) {
+			violations = append(violations, "container "+container.Name+" sets disallowed seLinuxOptions")
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != apiv1.DefaultProcMount {
+			violations = append(violations, "container "+container.Name+" sets procMount "+string(*sc.ProcMount))
+		}
+		if sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			violations = append(violations, "container "+container.Name+" is a Windows hostProcess container")
+		}
+	}
+	return violations
+}
+
+// allowedSELinuxOptions indicates if the supplied SELinux options are allowed at the baseline level
+func allowedSELinuxOptions(options *apiv1.SELinuxOptions) bool {
+	if options == nil {
+		return true
+	}
+	return allowedSELinuxTypes[options.Type] && len(options.User) == 0 && len(options.Role) == 0
+}
+
+// restrictedViolations returns the ways a pod spec violates the restricted level on top of the baseline level
+func restrictedViolations(spec apiv1.PodSpec) []string {
+	var violations []string
+
+	for _, volume := range spec.Volumes {
+		// hostPath volumes are already refused at the baseline level
+		if volume.HostPath == nil && !restrictedVolume(volume.VolumeSource) {
+			violations = append(violations, "volume "+volume.Name+" is not an allowed volume type")
+		}
+	}
+
+	psc := spec.SecurityContext
+	if psc == nil {
+		psc = &apiv1.PodSecurityContext{}
+	}
+	if psc.RunAsUser != nil && *psc.RunAsUser == 0 {
+		violations = append(violations, "the pod runs as user 0")
+	}
+
+	for _, container := range podContainers(spec) {
+		sc := container.SecurityContext
+		if sc == nil {
+			sc = &apiv1.SecurityContext{}
+		}
+
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, "container "+container.Name+" allows privilege escalation")
+		}
+
+		runAsNonRoot := psc.RunAsNonRoot
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		if runAsNonRoot == nil || !*runAsNonRoot {
+			violations = append(violations, "container "+container.Name+" does not set runAsNonRoot")
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, "container "+container.Name+" runs as user 0")
+		}
+
+		seccompProfile := psc.SeccompProfile
+		if sc.SeccompProfile != nil {
+			seccompProfile = sc.SeccompProfile
+		}
+		if seccompProfile == nil || (seccompProfile.Type != apiv1.SeccompProfileTypeRuntimeDefault && seccompProfile.Type != apiv1.SeccompProfileTypeLocalhost) {
+			violations = append(violations, "container "+container.Name+" does not set a RuntimeDefault or Localhost seccompProfile")
+		}
+
+		if sc.Capabilities == nil || !dropsAllCapabilities(sc.Capabilities.Drop) {
+			violations = append(violations, "container "+container.Name+" does not drop ALL capabilities")
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" && baselineCapabilities[capability] {
+					violations = append(violations, "container "+container.Name+" adds capability "+string(capability))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// dropsAllCapabilities indicates if the supplied dropped capabilities include ALL
+func dropsAllCapabilities(drop []apiv1.Capability) bool {
+	for _, capability := range drop {
+		if capability == "ALL" {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedVolume indicates if a volume source is one of the volume types allowed at the restricted level
+func restrictedVolume(source apiv1.VolumeSource) bool {
+	return source.ConfigMap != nil || source.CSI != nil || source.DownwardAPI != nil || source.EmptyDir != nil ||
+		source.Ephemeral != nil || source.PersistentVolumeClaim != nil || source.Projected != nil || source.Secret != nil
+}
@@ -0,0 +1,108 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestPodSecurityApplySpec ensures that checker pods are hardened with the restricted profile without overriding
+// the securityContext settings they set themselves
+func TestPodSecurityApplySpec(t *testing.T) {
+	runAsUser := int64(65534)
+	settings := PodSecuritySettings{Level: PodSecurityRestricted, RunAsUser: &runAsUser}
+
+	spec := apiv1.PodSpec{
+		InitContainers: []apiv1.Container{{Name: "init"}},
+		Containers: []apiv1.Container{{
+			Name:            "main",
+			SecurityContext: &apiv1.SecurityContext{ReadOnlyRootFilesystem: boolPtr(false)},
+		}},
+	}
+	settings.applySpec(&spec)
+
+	psc := spec.SecurityContext
+	if psc == nil || psc.RunAsNonRoot == nil || !*psc.RunAsNonRoot || psc.RunAsUser == nil || *psc.RunAsUser != 65534 {
+		t.Fatalf("Expected the pod to run as non root user 65534 but got %+v", psc)
+	}
+	if psc.SeccompProfile == nil || psc.SeccompProfile.Type != apiv1.SeccompProfileTypeRuntimeDefault {
+		t.Fatalf("Expected the pod to use the RuntimeDefault seccomp profile but got %+v", psc.SeccompProfile)
+	}
+	for _, container := range podContainers(spec) {
+		sc := container.SecurityContext
+		if sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			t.Fatalf("Expected container %s to disallow privilege escalation but got %+v", container.Name, sc)
+		}
+		if sc.Capabilities == nil || !dropsAllCapabilities(sc.Capabilities.Drop) {
+			t.Fatalf("Expected container %s to drop all capabilities but got %+v", container.Name, sc.Capabilities)
+		}
+	}
+	if !*spec.InitContainers[0].SecurityContext.ReadOnlyRootFilesystem {
+		t.Fatal("Expected the init container to get a read only root filesystem")
+	}
+	if *spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem {
+		t.Fatal("Expected the writable root filesystem of the main container to be kept")
+	}
+
+	// nothing is changed when the compliance mode is off
+	untouched := apiv1.PodSpec{Containers: []apiv1.Container{{Name: "main"}}}
+	PodSecuritySettings{}.applySpec(&untouched)
+	if untouched.SecurityContext != nil || untouched.Containers[0].SecurityContext != nil {
+		t.Fatal("Expected the pod spec to be left alone when no pod security level is set")
+	}
+}
+
+// TestPodSecurityProblem ensures that checker pod specs that still violate the configured pod security level once
+// hardened are refused
+func TestPodSecurityProblem(t *testing.T) {
+	baseline := PodSecuritySettings{Level: PodSecurityBaseline}
+	restricted := PodSecuritySettings{Level: PodSecurityRestricted}
+	rootUser := int64(0)
+
+	testCases := []struct {
+		name          string
+		spec          apiv1.PodSpec
+		settings      PodSecuritySettings
+		expectProblem bool
+	}{
+		{name: "disabled", spec: apiv1.PodSpec{HostNetwork: true}},
+		{name: "plain pod restricted", settings: restricted},
+		{name: "plain pod baseline", settings: baseline},
+		{name: "host network baseline", spec: apiv1.PodSpec{HostNetwork: true}, settings: baseline, expectProblem: true},
+		{name: "host path volume", spec: apiv1.PodSpec{Volumes: []apiv1.Volume{{Name: "root", VolumeSource: apiv1.VolumeSource{HostPath: &apiv1.HostPathVolumeSource{Path: "/"}}}}}, settings: baseline, expectProblem: true},
+		{name: "privileged baseline", spec: podWithSecurityContext(&apiv1.SecurityContext{Privileged: boolPtr(true)}), settings: baseline, expectProblem: true},
+		{name: "net admin baseline", spec: podWithSecurityContext(&apiv1.SecurityContext{Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"NET_ADMIN"}}}), settings: baseline, expectProblem: true},
+		{name: "net raw restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"NET_RAW"}}}), settings: restricted, expectProblem: true},
+		{name: "chown baseline", spec: podWithSecurityContext(&apiv1.SecurityContext{Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"CHOWN"}}}), settings: baseline},
+		{name: "chown restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"CHOWN"}}}), settings: restricted, expectProblem: true},
+		{name: "bind service restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"NET_BIND_SERVICE"}}}), settings: restricted},
+		{name: "privilege escalation restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{AllowPrivilegeEscalation: boolPtr(true)}), settings: restricted, expectProblem: true},
+		{name: "privilege escalation baseline", spec: podWithSecurityContext(&apiv1.SecurityContext{AllowPrivilegeEscalation: boolPtr(true)}), settings: baseline},
+		{name: "run as root restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{RunAsUser: &rootUser}), settings: restricted, expectProblem: true},
+		{name: "run as root allowed restricted", spec: podWithSecurityContext(&apiv1.SecurityContext{RunAsNonRoot: boolPtr(false)}), settings: restricted, expectProblem: true},
+		{name: "unconfined seccomp baseline", spec: podWithSecurityContext(&apiv1.SecurityContext{SeccompProfile: &apiv1.SeccompProfile{Type: apiv1.SeccompProfileTypeUnconfined}}), settings: baseline, expectProblem: true},
+		{name: "host port baseline", spec: apiv1.PodSpec{Containers: []apiv1.Container{{Name: "main", Ports: []apiv1.ContainerPort{{ContainerPort: 80, HostPort: 80}}}}}, settings: baseline, expectProblem: true},
+		{name: "unsafe sysctl baseline", spec: apiv1.PodSpec{SecurityContext: &apiv1.PodSecurityContext{Sysctls: []apiv1.Sysctl{{Name: "kernel.msgmax", Value: "1"}}}}, settings: baseline, expectProblem: true},
+		{name: "empty dir restricted", spec: apiv1.PodSpec{Volumes: []apiv1.Volume{{Name: "tmp", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}}}, settings: restricted},
+		{name: "nfs volume restricted", spec: apiv1.PodSpec{Volumes: []apiv1.Volume{{Name: "share", VolumeSource: apiv1.VolumeSource{NFS: &apiv1.NFSVolumeSource{Server: "nfs", Path: "/"}}}}}, settings: restricted, expectProblem: true},
+		{name: "unknown level", spec: podWithSecurityContext(&apiv1.SecurityContext{AllowPrivilegeEscalation: boolPtr(true)}), settings: PodSecuritySettings{Level: "restriced"}, expectProblem: true},
+	}
+
+	for _, tc := range testCases {
+		if len(tc.spec.Containers) == 0 {
+			tc.spec.Containers = []apiv1.Container{{Name: "main"}}
+		}
+		problem := PodSecurityProblem(tc.spec, tc.settings)
+		if tc.expectProblem && len(problem) == 0 {
+			t.Fatalf("%s: expected the pod spec to be refused", tc.name)
+		}
+		if !tc.expectProblem && len(problem) > 0 {
+			t.Fatalf("%s: expected the pod spec to be allowed but got: %s", tc.name, problem)
+		}
+	}
+}
+
+// podWithSecurityContext returns a pod spec with a single container that has the supplied securityContext
+func podWithSecurityContext(sc *apiv1.SecurityContext) apiv1.PodSpec {
+	return apiv1.PodSpec{Containers: []apiv1.Container{{Name: "main", SecurityContext: sc}}}
+}
@@ -0,0 +1,223 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PodWatcher caches the checker pods of every check with a single shared informer, so that runs can wait for their
+// checker pods to start or be removed without opening a watch of their own.  Only pods with the
+// kuberhealthy-check-name label are cached.
+type PodWatcher struct {
+	informer      cache.SharedIndexInformer
+	indexer       cache.Indexer                 // the informer cache of checker pods
+	mu            sync.Mutex                    // guards subscriptions and their deleted flags
+	subscriptions map[*podSubscription]struct{} // the runs waiting on changes to their checker pods
+}
+
+// podSubscription is a run waiting on changes to the checker pods that match its selector
+type podSubscription struct {
+	namespace string
+	selector  labels.Selector
+	changed   chan struct{} // signaled without blocking when a matching pod is added, updated or deleted
+	deleted   bool          // set once a matching pod has been deleted
+}
+
+// NewPodWatcher creates a PodWatcher for the checker pods in the supplied namespace.  To include all namespaces,
+// pass a blank namespace.  Pods are not cached until the watcher is started with Run.
+func NewPodWatcher(client kubernetes.Interface, namespace string) (*PodWatcher, error) {
+	listWatch := cache.NewFilteredListWatchFromClient(client.CoreV1().RESTClient(), "pods", namespace, func(options *metav1.ListOptions) {
+		options.LabelSelector = kuberhealthyCheckNameLabel
+	})
+	informer := cache.NewSharedIndexInformer(listWatch, &apiv1.Pod{}, 0, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	})
+	w := newPodWatcher(informer.GetIndexer())
+	w.informer = informer
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.notify(obj, false)
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			w.notify(newObj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.notify(obj, true)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error adding event handler to checker pod informer: %w", err)
+	}
+	return w, nil
+}
+
+// newPodWatcher creates a PodWatcher that lists checker pods from the supplied cache
+func newPodWatcher(indexer cache.Indexer) *PodWatcher {
+	return &PodWatcher{
+		indexer:       indexer,
+		subscriptions: make(map[*podSubscription]struct{}),
+	}
+}
+
+// Run caches checker pods until the supplied stop channel is closed
+func (w *PodWatcher) Run(stopCh <-chan struct{}) {
+	w.informer.Run(stopCh)
+}
+
+// HasSynced returns true once the checker pods have been listed into the cache
+func (w *PodWatcher) HasSynced() bool {
+	return w.informer != nil && w.informer.HasSynced()
+}
+
+// notify signals the subscriptions that match a pod that was added, updated or deleted
+func (w *PodWatcher) notify(obj interface{}, deleted bool) {
+	// deletes may be delivered as a tombstone if the informer missed the delete event
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*apiv1.Pod)
+	if !ok {
+		log.Warningln("checker pod informer saw an object that was not a pod")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for sub := range w.subscriptions {
+		if sub.namespace != pod.Namespace || !sub.selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if deleted {
+			sub.deleted = true
+		}
+		select {
+		case sub.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribe starts collecting changes to the pods in the supplied namespace that match the selector.  Changes are
+// collected until the subscription is passed to unsubscribe.
+func (w *PodWatcher) subscribe(namespace string, selector labels.Selector) *podSubscription {
+	sub := &podSubscription{
+		namespace: namespace,
+		selector:  selector,
+		changed:   make(chan struct{}, 1),
+	}
+	w.mu.Lock()
+	w.subscriptions[sub] = struct{}{}
+	w.mu.Unlock()
+	return sub
+}
+
+// unsubscribe stops collecting changes for a subscription
+func (w *PodWatcher) unsubscribe(sub *podSubscription) {
+	w.mu.Lock()
+	delete(w.subscriptions, sub)
+	w.mu.Unlock()
+}
+
+// podDeleted returns true if a pod that matches the subscription was deleted since it started
+func (w *PodWatcher) podDeleted(sub *podSubscription) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return sub.deleted
+}
+
+// pods lists the cached pods in the supplied namespace that match the selector.  The pods are shared with the cache
+// and must not be modified.
+func (w *PodWatcher) pods(namespace string, selector labels.Selector) []*apiv1.Pod {
+	var pods []*apiv1.Pod
+	err := cache.ListAllByNamespace(w.indexer, namespace, selector, func(obj interface{}) {
+		if p, ok := obj.(*apiv1.Pod); ok {
+			pods = append(pods, p)
+		}
+	})
+	if err != nil {
+		log.Warningln("Error listing checker pods from the informer cache:", err)
+	}
+	return pods
+}
+
+// podWatcherSynced returns true if the checker pods of this check can be waited on through the shared pod watcher
+func (ext *Checker) podWatcherSynced() bool {
+	return ext.PodWatcher != nil && ext.PodWatcher.HasSynced()
+}
+
+// runPodSelector selects the checker pod of the current run
+func (ext *Checker) runPodSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{kuberhealthyRunIDLabel: ext.currentCheckUUID})
+}
+
+// waitForCachedPodDelete returns a channel that receives an error when the checker pod of the current run is
+// deleted, as seen by the shared pod watcher.  Nothing is sent if the context is canceled first.
+func (ext *Checker) waitForCachedPodDelete(ctx context.Context) chan error {
+
+	outChan := make(chan error, 1)
+	sub := ext.PodWatcher.subscribe(ext.Namespace, ext.runPodSelector())
+
+	go func() {
+		defer ext.PodWatcher.unsubscribe(sub)
+		for {
+			if ext.PodWatcher.podDeleted(sub) {
+				ext.log("pod shutdown monitor witnessed the checker pod being removed")
+				outChan <- fmt.Errorf("pod shutdown monitor witnessed the checker pod being removed")
+				return
+			}
+			select {
+			case <-ctx.Done(): // graceful shutdown signal
+				ext.log("pod shutdown monitor stopping gracefully")
+				return
+			case <-sub.changed:
+			}
+		}
+	}()
+	return outChan
+}
+
+// waitForCachedPodStart returns a channel that notifies when the checker pod of the current run has advanced
+// beyond 'Pending', as seen by the shared pod watcher
+func (ext *Checker) waitForCachedPodStart() chan error {
+
+	outChan := make(chan error, 1)
+	selector := ext.runPodSelector()
+	sub := ext.PodWatcher.subscribe(ext.Namespace, selector)
+
+	ext.wg.Add(1)
+	go func() {
+		defer ext.wg.Done()
+		defer ext.PodWatcher.unsubscribe(sub)
+		for {
+			if ext.PodWatcher.podDeleted(sub) {
+				ext.log("the khcheck check pod is deleted, waiting for start failed!")
+				outChan <- ErrPodDeletedBeforeRunning
+				return
+			}
+			for _, p := range ext.PodWatcher.pods(ext.Namespace, selector) {
+				if podStarted(p) {
+					ext.log("pod is now either running, failed, or succeeded")
+					outChan <- nil
+					return
+				}
+			}
+			select {
+			case <-ext.shutdownCTX.Done():
+				ext.log("external checker pod startup watch aborted due to check context being aborted")
+				outChan <- nil
+				return
+			case <-sub.changed:
+			}
+		}
+	}()
+	return outChan
+}
@@ -0,0 +1,72 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestPodWatcherSubscriptions ensures that subscriptions are only signaled for the pods that match them, and that
+// deletes are remembered until the subscription checks for them
+func TestPodWatcherSubscriptions(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	w := newPodWatcher(indexer)
+
+	selector := labels.SelectorFromSet(labels.Set{kuberhealthyRunIDLabel: "1234"})
+	sub := w.subscribe("kuberhealthy", selector)
+	defer w.unsubscribe(sub)
+
+	runPod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "check-1234", Namespace: "kuberhealthy", Labels: map[string]string{kuberhealthyRunIDLabel: "1234"}}}
+	otherRunPod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "check-5678", Namespace: "kuberhealthy", Labels: map[string]string{kuberhealthyRunIDLabel: "5678"}}}
+	otherNamespacePod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "check-1234", Namespace: "default", Labels: map[string]string{kuberhealthyRunIDLabel: "1234"}}}
+
+	w.notify(otherRunPod, true)
+	w.notify(otherNamespacePod, true)
+	if len(sub.changed) != 0 || w.podDeleted(sub) {
+		t.Fatalf("expected changes to pods that do not match the subscription to be ignored")
+	}
+
+	err := indexer.Add(runPod)
+	if err != nil {
+		t.Fatalf("failed to add pod to the cache: %s", err)
+	}
+	w.notify(runPod, false)
+	w.notify(runPod, false)
+	if len(sub.changed) != 1 {
+		t.Fatalf("expected 1 queued change signal but got %d", len(sub.changed))
+	}
+	if pods := w.pods("kuberhealthy", selector); len(pods) != 1 || pods[0].Name != runPod.Name {
+		t.Fatalf("expected the pod of the run to be listed from the cache but got %v", pods)
+	}
+
+	w.notify(cache.DeletedFinalStateUnknown{Key: "kuberhealthy/check-1234", Obj: runPod}, true)
+	if !w.podDeleted(sub) {
+		t.Fatalf("expected the delete of the pod of the run to be seen")
+	}
+}
+
+// TestPodStarted ensures that checker pods are only seen as started once they leave the pending phase
+func TestPodStarted(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  apiv1.PodStatus
+		started bool
+	}{
+		{name: "pending", status: apiv1.PodStatus{Phase: apiv1.PodPending}},
+		{name: "running", status: apiv1.PodStatus{Phase: apiv1.PodRunning}, started: true},
+		{name: "succeeded", status: apiv1.PodStatus{Phase: apiv1.PodSucceeded}, started: true},
+		{name: "image pull error", status: apiv1.PodStatus{Phase: apiv1.PodPending, ContainerStatuses: []apiv1.ContainerStatus{
+			{Name: "check", State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+		}}},
+	}
+
+	for _, tc := range testCases {
+		started := podStarted(&apiv1.Pod{Status: tc.status})
+		if started != tc.started {
+			t.Fatalf("%s: expected started to be %t but got %t", tc.name, tc.started, started)
+		}
+	}
+}
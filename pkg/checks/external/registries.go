@@ -0,0 +1,67 @@
+package external
+
+import (
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// defaultImageRegistry is the registry that images without a registry in their name are pulled from
+const defaultImageRegistry = "docker.io"
+
+// normalizeImage returns the fully qualified name of the supplied image, including its registry.  Images without a
+// registry are pulled from docker.io, and official images without a repository from its library repository.
+func normalizeImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return defaultImageRegistry + "/library/" + image
+	}
+
+	// the first component is only a registry if it looks like a host name
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return defaultImageRegistry + "/" + image
+	}
+
+	return image
+}
+
+// ImageAllowed indicates if the supplied image is pulled from one of the allowed image registries or prefixes.  Each
+// allowed entry matches a whole registry, repository path or image name, such as docker.io/kuberhealthy or
+// quay.io/team/image.  Images without a registry are matched as docker.io images.  When no registries are
+// allowed, every image is allowed.
+func ImageAllowed(image string, allowedRegistries []string) bool {
+	if len(allowedRegistries) == 0 {
+		return true
+	}
+
+	image = normalizeImage(image)
+	for _, allowed := range allowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if len(allowed) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(image, allowed) {
+			continue
+		}
+
+		// the prefix must end on a path, tag or digest boundary so that quay.io does not match quay.io.example.com
+		rest := image[len(allowed):]
+		if len(rest) == 0 || strings.ContainsAny(rest[:1], "/:@") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// disallowedImages returns the images of the supplied pod spec that are not pulled from the allowed registries
+func disallowedImages(spec apiv1.PodSpec, allowedRegistries []string) []string {
+	var images []string
+	containers := append(append([]apiv1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		if !ImageAllowed(c.Image, allowedRegistries) {
+			images = append(images, c.Image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,50 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestImageAllowed ensures that images are matched against allowed registries and prefixes on whole path components
+func TestImageAllowed(t *testing.T) {
+	allowed := []string{"quay.io", "docker.io/kuberhealthy/", "registry.example.com:5000/team/check"}
+
+	testCases := map[string]bool{
+		"quay.io/comcast/check:1.0":                    true,
+		"quay.io.example.com/comcast/check:1.0":        false,
+		"kuberhealthy/http-check:v1.5.0":               true,
+		"docker.io/kuberhealthy/http-check":            true,
+		"kuberhealthyfake/http-check":                  false,
+		"busybox":                                      false,
+		"registry.example.com:5000/team/check:latest":  true,
+		"registry.example.com:5000/team/check@sha256:": true,
+		"registry.example.com:5000/team/checker":       false,
+	}
+
+	for image, expected := range testCases {
+		if ImageAllowed(image, allowed) != expected {
+			t.Fatalf("Expected ImageAllowed(%s) to be %t", image, expected)
+		}
+	}
+
+	if !ImageAllowed("busybox", nil) {
+		t.Fatal("Expected every image to be allowed without allowed registries")
+	}
+	if !ImageAllowed("busybox:1.36", []string{"docker.io/library/busybox"}) {
+		t.Fatal("Expected official images to be matched in the docker.io library repository")
+	}
+}
+
+// TestDisallowedImages ensures that init containers and containers are both checked against the allowed registries
+func TestDisallowedImages(t *testing.T) {
+	spec := apiv1.PodSpec{
+		InitContainers: []apiv1.Container{{Name: "init", Image: "busybox"}},
+		Containers:     []apiv1.Container{{Name: "main", Image: "quay.io/comcast/check"}},
+	}
+
+	images := disallowedImages(spec, []string{"quay.io"})
+	if len(images) != 1 || images[0] != "busybox" {
+		t.Fatalf("Expected only the init container image to be disallowed but got %v", images)
+	}
+}
@@ -0,0 +1,6 @@
+// Package reportpb holds the protobuf messages and gRPC service of the Kuberhealthy reporting API, which checker
+// pods can send the results of their runs to instead of the /externalCheckStatus endpoint.  The code is generated
+// from report.proto with protoc-gen-go v1.33.0 and protoc-gen-go-grpc v1.3.0.
+package reportpb
+
+//go:generate sh -c "cd ../../../.. && protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pkg/checks/external/reportpb/report.proto"
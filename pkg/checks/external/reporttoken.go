@@ -0,0 +1,70 @@
+package external
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ReportTokenAudience is the audience of the service account tokens that checker pods authenticate their reports with
+const ReportTokenAudience = "kuberhealthy"
+
+// reportTokenVolumeName is the name of the projected volume that holds the report token of checker pods
+const reportTokenVolumeName = "kh-report-token"
+
+// reportTokenMountPath is the directory that the report token volume is mounted to in checker containers
+const reportTokenMountPath = "/var/run/secrets/kuberhealthy"
+
+// reportTokenFileName is the name of the report token file within the report token volume
+const reportTokenFileName = "token"
+
+// reportTokenExpirationSeconds is how long report tokens are valid for.  The kubelet refreshes the token well before
+// it expires.
+const reportTokenExpirationSeconds = int64(3600)
+
+// addReportTokenVolume mounts a projected service account token for the Kuberhealthy audience into every
+// container of the supplied pod spec and points the KH_REPORT_TOKEN_FILE environment variable at it.  Any
+// volume, mount or environment variable with the same name is replaced.
+func addReportTokenVolume(spec *apiv1.PodSpec) {
+	expirationSeconds := reportTokenExpirationSeconds
+	volume := apiv1.Volume{
+		Name: reportTokenVolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			Projected: &apiv1.ProjectedVolumeSource{
+				Sources: []apiv1.VolumeProjection{{
+					ServiceAccountToken: &apiv1.ServiceAccountTokenProjection{
+						Audience:          ReportTokenAudience,
+						ExpirationSeconds: &expirationSeconds,
+						Path:              reportTokenFileName,
+					},
+				}},
+			},
+		},
+	}
+
+	var volumes []apiv1.Volume
+	for _, v := range spec.Volumes {
+		if v.Name != reportTokenVolumeName {
+			volumes = append(volumes, v)
+		}
+	}
+	spec.Volumes = append(volumes, volume)
+
+	for i := range spec.Containers {
+		var mounts []apiv1.VolumeMount
+		for _, m := range spec.Containers[i].VolumeMounts {
+			if m.Name != reportTokenVolumeName {
+				mounts = append(mounts, m)
+			}
+		}
+		spec.Containers[i].VolumeMounts = append(mounts, apiv1.VolumeMount{
+			Name:      reportTokenVolumeName,
+			MountPath: reportTokenMountPath,
+			ReadOnly:  true,
+		})
+
+		spec.Containers[i].Env = resetInjectedContainerEnvVars(spec.Containers[i].Env, []string{KHReportTokenFile})
+		spec.Containers[i].Env = append(spec.Containers[i].Env, apiv1.EnvVar{
+			Name:  KHReportTokenFile,
+			Value: reportTokenMountPath + "/" + reportTokenFileName,
+		})
+	}
+}
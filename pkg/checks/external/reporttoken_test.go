@@ -0,0 +1,35 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestAddReportTokenVolume ensures that the report token is mounted into every checker container exactly once
+func TestAddReportTokenVolume(t *testing.T) {
+	spec := apiv1.PodSpec{
+		Containers: []apiv1.Container{{Name: "main"}, {Name: "sidecar"}},
+	}
+
+	// adding the volume more than once must not duplicate it
+	addReportTokenVolume(&spec)
+	addReportTokenVolume(&spec)
+
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Projected == nil {
+		t.Fatalf("Expected a single projected report token volume but got %+v", spec.Volumes)
+	}
+	token := spec.Volumes[0].Projected.Sources[0].ServiceAccountToken
+	if token == nil || token.Audience != ReportTokenAudience {
+		t.Fatal("Expected the report token to be a service account token for the kuberhealthy audience")
+	}
+
+	for _, c := range spec.Containers {
+		if len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != reportTokenMountPath {
+			t.Fatalf("Expected container %s to mount the report token once but got %+v", c.Name, c.VolumeMounts)
+		}
+		if len(c.Env) != 1 || c.Env[0].Name != KHReportTokenFile || c.Env[0].Value != reportTokenMountPath+"/"+reportTokenFileName {
+			t.Fatalf("Expected container %s to have the report token file environment variable but got %+v", c.Name, c.Env)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package resourcecheck runs checks that create throwaway resources in the cluster, exercise them and tear them down
+// again.  It takes care of the parts that such checks share: finishing before the deadline of the run, waiting for
+// Kuberhealthy to be reachable, cleaning up resources left behind by a run that was killed, tearing the resources
+// down even when the check ran out of time, and reporting the result.
+package resourcecheck
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	kh "github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/checkclient"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/nodeCheck"
+)
+
+// PollInterval is how often Poll checks its condition
+var PollInterval = time.Second * 2
+
+// waitForKuberhealthy waits for the reporting endpoint of Kuberhealthy to be reachable.  It is replaced in tests.
+var waitForKuberhealthy = nodeCheck.WaitForKuberhealthy
+
+// Check is a check that creates throwaway resources, exercises them and tears them down again
+type Check struct {
+	Resources      string                             // what the check creates, such as "canary", for error messages
+	DefaultRunTime time.Duration                      // how long the check may run when the deadline of the run is not known
+	CleanUpTimeout time.Duration                      // how long tearing down the resources may take, even after the deadline of the run
+	Run            func(ctx context.Context) []string // creates and exercises the resources, returning the problems found
+	CleanUp        func(ctx context.Context) error    // deletes the resources, succeeding when there are none. nil when the check creates none
+}
+
+// Run runs the check and reports its result to Kuberhealthy.  The check is given until CleanUpTimeout before the
+// deadline of the run, so that its resources can still be torn down in time.
+func Run(c Check) {
+	deadline, err := kh.GetDeadline()
+	if err != nil {
+		log.Infoln("There was an issue getting the check deadline:", err.Error())
+		deadline = time.Now().Add(c.DefaultRunTime)
+	}
+
+	problems := c.problems(deadline)
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Errorln(p)
+		}
+		err = kh.ReportFailure(problems)
+		if err != nil {
+			log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+		}
+		return
+	}
+
+	err = kh.ReportSuccess()
+	if err != nil {
+		log.Fatalln("error when reporting to kuberhealthy:", err.Error())
+	}
+	log.Infoln("Successfully reported to Kuberhealthy")
+}
+
+// problems runs the check with the supplied deadline and returns the problems that it found, including any
+// problem tearing down its resources
+func (c Check) problems(deadline time.Time) []string {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(-c.CleanUpTimeout))
+	defer cancel()
+
+	// hits kuberhealthy endpoint to see if node is ready
+	err := waitForKuberhealthy(ctx)
+	if err != nil {
+		log.Errorln("Error waiting for kuberhealthy endpoint to be contactable by checker pod with error:" + err.Error())
+	}
+
+	if c.CleanUp == nil {
+		return c.Run(ctx)
+	}
+
+	// resources left behind by a run that was killed would make the creation of new ones fail
+	err = c.CleanUp(ctx)
+	if err != nil {
+		return []string{"failed to clean up the " + c.Resources + " of a previous run: " + err.Error()}
+	}
+
+	problems := c.Run(ctx)
+
+	// the resources are torn down with a context of their own, as the one of the check may have expired
+	cleanUpCtx, cleanUpCancel := context.WithTimeout(context.Background(), c.CleanUpTimeout)
+	defer cleanUpCancel()
+	err = c.CleanUp(cleanUpCtx)
+	if err != nil {
+		problems = append(problems, "failed to tear down the "+c.Resources+": "+err.Error())
+	}
+	return problems
+}
+
+// Poll checks the supplied condition every PollInterval until it is done or the context expires.  The last error
+// of the condition is returned along with the error of the context, so that a timeout says why the condition was not
+// met.
+func Poll(ctx context.Context, condition func() (bool, error)) error {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		done, err := condition()
+		if done {
+			return nil
+		}
+		// a condition that was cut off by the context says nothing about why it was not done before
+		if err != nil && ctx.Err() == nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return errors.Join(ctx.Err(), lastErr)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package resourcecheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/status"
+)
+
+// fakeCheck records the steps of a check and fails the ones it is told to
+type fakeCheck struct {
+	steps        []string
+	cleanUpErrs  []error
+	runProblems  []string
+	runUntilDone bool
+}
+
+// check returns a Check that runs the fake check
+func (f *fakeCheck) check() Check {
+	return Check{
+		Resources:      "canary",
+		DefaultRunTime: time.Minute,
+		CleanUpTimeout: time.Second,
+		Run: func(ctx context.Context) []string {
+			f.steps = append(f.steps, "run")
+			if f.runUntilDone {
+				<-ctx.Done()
+			}
+			return f.runProblems
+		},
+		CleanUp: func(ctx context.Context) error {
+			if ctx.Err() != nil {
+				f.steps = append(f.steps, "clean up expired")
+				return ctx.Err()
+			}
+			f.steps = append(f.steps, "clean up")
+			if len(f.cleanUpErrs) == 0 {
+				return nil
+			}
+			err := f.cleanUpErrs[0]
+			f.cleanUpErrs = f.cleanUpErrs[1:]
+			return err
+		},
+	}
+}
+
+func TestProblems(t *testing.T) {
+	previousWait := waitForKuberhealthy
+	defer func() { waitForKuberhealthy = previousWait }()
+	waitForKuberhealthy = func(ctx context.Context) error { return errors.New("not reachable") }
+
+	testCases := []struct {
+		description      string
+		check            *fakeCheck
+		deadline         time.Duration
+		expectedSteps    []string
+		expectedProblems []string
+	}{
+		{
+			description:   "passing",
+			check:         &fakeCheck{},
+			deadline:      time.Minute,
+			expectedSteps: []string{"clean up", "run", "clean up"},
+		},
+		{
+			description:      "failing",
+			check:            &fakeCheck{runProblems: []string{"canary did not run"}},
+			deadline:         time.Minute,
+			expectedSteps:    []string{"clean up", "run", "clean up"},
+			expectedProblems: []string{"canary did not run"},
+		},
+		{
+			description:      "left behind resources can not be deleted",
+			check:            &fakeCheck{cleanUpErrs: []error{errors.New("forbidden")}},
+			deadline:         time.Minute,
+			expectedSteps:    []string{"clean up"},
+			expectedProblems: []string{"failed to clean up the canary of a previous run: forbidden"},
+		},
+		{
+			description:      "resources can not be torn down",
+			check:            &fakeCheck{runProblems: []string{"canary did not run"}, cleanUpErrs: []error{nil, errors.New("forbidden")}},
+			deadline:         time.Minute,
+			expectedSteps:    []string{"clean up", "run", "clean up"},
+			expectedProblems: []string{"canary did not run", "failed to tear down the canary: forbidden"},
+		},
+		{
+			// the check runs out of time, but its resources are still torn down
+			description:      "out of time",
+			check:            &fakeCheck{runUntilDone: true, runProblems: []string{"timed out"}},
+			deadline:         time.Second + time.Millisecond*50,
+			expectedSteps:    []string{"clean up", "run", "clean up"},
+			expectedProblems: []string{"timed out"},
+		},
+	}
+
+	for _, tc := range testCases {
+		problems := tc.check.check().problems(time.Now().Add(tc.deadline))
+		if strings.Join(tc.check.steps, ", ") != strings.Join(tc.expectedSteps, ", ") {
+			t.Fatalf("%s: expected the steps %v but got %v", tc.description, tc.expectedSteps, tc.check.steps)
+		}
+		if strings.Join(problems, ", ") != strings.Join(tc.expectedProblems, ", ") {
+			t.Fatalf("%s: expected the problems %v but got %v", tc.description, tc.expectedProblems, problems)
+		}
+	}
+}
+
+func TestProblemsWithoutResources(t *testing.T) {
+	previousWait := waitForKuberhealthy
+	defer func() { waitForKuberhealthy = previousWait }()
+	waitForKuberhealthy = func(ctx context.Context) error { return nil }
+
+	// checks that create no resources still stop the clean up timeout before the deadline
+	var remaining time.Duration
+	c := Check{
+		CleanUpTimeout: time.Second * 5,
+		Run: func(ctx context.Context) []string {
+			deadline, _ := ctx.Deadline()
+			remaining = time.Until(deadline)
+			return []string{"echo server did not answer"}
+		},
+	}
+	problems := c.problems(time.Now().Add(time.Minute))
+	if len(problems) != 1 || problems[0] != "echo server did not answer" {
+		t.Fatalf("expected the problems of the check but got %v", problems)
+	}
+	if remaining > time.Second*55 || remaining < time.Second*50 {
+		t.Fatalf("expected the check to stop 5s before its deadline but it had %s left", remaining)
+	}
+}
+
+func TestRun(t *testing.T) {
+	previousWait := waitForKuberhealthy
+	defer func() { waitForKuberhealthy = previousWait }()
+	waitForKuberhealthy = func(ctx context.Context) error { return nil }
+
+	reports := make(chan status.Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report status.Report
+		err := json.NewDecoder(r.Body).Decode(&report)
+		if err != nil {
+			t.Error("Failed to decode report:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reports <- report
+	}))
+	defer server.Close()
+
+	os.Setenv(external.KHReportingURL, server.URL)
+	os.Setenv(external.KHRunUUID, "some-random-uuid")
+	defer os.Unsetenv(external.KHReportingURL)
+	defer os.Unsetenv(external.KHRunUUID)
+
+	Run((&fakeCheck{}).check())
+	report := <-reports
+	if !report.OK {
+		t.Fatalf("expected a passing check to report success but got %+v", report)
+	}
+
+	Run((&fakeCheck{runProblems: []string{"canary did not run"}}).check())
+	report = <-reports
+	if report.OK || len(report.Errors) != 1 || report.Errors[0] != "canary did not run" {
+		t.Fatalf("expected a failing check to report its problems but got %+v", report)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	PollInterval = time.Millisecond
+	defer func() { PollInterval = time.Second * 2 }()
+
+	var polls int
+	err := Poll(context.Background(), func() (bool, error) {
+		polls++
+		return polls == 3, nil
+	})
+	if err != nil || polls != 3 {
+		t.Fatalf("expected polling to stop once the condition is done but got %v after %d polls", err, polls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = Poll(ctx, func() (bool, error) {
+		return false, errors.New("0 of 1 replicas are available")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "0 of 1 replicas are available") {
+		t.Fatalf("expected a timeout that says why the condition was not done but got: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package external
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// PodRequests returns the resources that the scheduler reserves for a pod with the supplied spec.  This is the sum
+// of the requests of its containers, or the largest request of a single init container when that is more.
+// Containers that only set a limit request as much as their limit.
+func PodRequests(spec apiv1.PodSpec) apiv1.ResourceList {
+	requests := make(apiv1.ResourceList)
+	for _, c := range spec.Containers {
+		for name, quantity := range containerRequests(c) {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, quantity := range containerRequests(c) {
+			if total, ok := requests[name]; !ok || quantity.Cmp(total) > 0 {
+				requests[name] = quantity
+			}
+		}
+	}
+	return requests
+}
+
+// containerRequests returns the resource requests of a container, defaulting each request to its limit
+func containerRequests(c apiv1.Container) apiv1.ResourceList {
+	requests := make(apiv1.ResourceList)
+	for name, quantity := range c.Resources.Limits {
+		requests[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range c.Resources.Requests {
+		requests[name] = quantity.DeepCopy()
+	}
+	return requests
+}
+
+// ResourceRequests returns the resources requested by a checker pod of this check, including the pod defaults
+func (ext *Checker) ResourceRequests() apiv1.ResourceList {
+	spec := ext.OriginalPodSpec.DeepCopy()
+	ext.PodDefaults.applySpec(spec)
+	return PodRequests(*spec)
+}
@@ -0,0 +1,48 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// TestPodRequests ensures that the requests of a pod add up its containers and account for init containers and
+// containers that only set limits
+func TestPodRequests(t *testing.T) {
+	spec := apiv1.PodSpec{
+		InitContainers: []apiv1.Container{{
+			Name: "init",
+			Resources: apiv1.ResourceRequirements{Requests: apiv1.ResourceList{
+				apiv1.ResourceCPU:    resource.MustParse("1"),
+				apiv1.ResourceMemory: resource.MustParse("16Mi"),
+			}},
+		}},
+		Containers: []apiv1.Container{
+			{
+				Name: "main",
+				Resources: apiv1.ResourceRequirements{Requests: apiv1.ResourceList{
+					apiv1.ResourceCPU:    resource.MustParse("100m"),
+					apiv1.ResourceMemory: resource.MustParse("64Mi"),
+				}},
+			},
+			{
+				Name: "sidecar",
+				Resources: apiv1.ResourceRequirements{Limits: apiv1.ResourceList{
+					apiv1.ResourceCPU:    resource.MustParse("50m"),
+					apiv1.ResourceMemory: resource.MustParse("32Mi"),
+				}},
+			},
+		},
+	}
+
+	requests := PodRequests(spec)
+	cpu := requests[apiv1.ResourceCPU]
+	if cpu.MilliValue() != 1000 {
+		t.Fatalf("Expected the init container to raise the cpu request to 1 but got %s", cpu.String())
+	}
+	memory := requests[apiv1.ResourceMemory]
+	if memory.Value() != 96*1024*1024 {
+		t.Fatalf("Expected the memory requests of the containers to add up to 96Mi but got %s", memory.String())
+	}
+}
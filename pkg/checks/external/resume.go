@@ -0,0 +1,230 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/checks/external/util"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
+)
+
+// releaseCheckerPodTimeout is how long a detached run waits to release its checker pod for the next master
+const releaseCheckerPodTimeout = time.Second * 10
+
+// ErrRunNotResumable is returned when a run in flight can not be resumed because its checker pod is gone, has
+// already exited or has run past its deadline
+var ErrRunNotResumable = errors.New("run in flight can not be resumed")
+
+// setInFlightRun records the checker pod of the current run on the khstate of the check, so that another
+// Kuberhealthy pod can resume the run if this one shuts down before the run completes.  The record is cleared when
+// the result of the run is stored.
+func (ext *Checker) setInFlightRun(startTime time.Time, deadline time.Time) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"InFlightRun": khstatev1.InFlightRun{
+				Pod:       ext.podName(),
+				UUID:      ext.currentCheckUUID,
+				StartTime: metav1.NewTime(startTime),
+				Deadline:  metav1.NewTime(deadline),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ext.KHStateClient.KuberhealthyStates(ext.Namespace).Patch(ext.CheckName, types.MergePatchType, patch)
+	return err
+}
+
+// resumable returns an error if the supplied checker pod can not be resumed as the run in flight
+func resumable(pod *apiv1.Pod, run khstatev1.InFlightRun, now time.Time) error {
+	if !now.Before(run.Deadline.Time) {
+		return ErrRunNotResumable
+	}
+	if pod.Labels[kuberhealthyRunIDLabel] != run.UUID {
+		return ErrRunNotResumable
+	}
+	if pod.DeletionTimestamp != nil || pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+		return ErrRunNotResumable
+	}
+	return nil
+}
+
+// Resume re-attaches to the checker pod of a run that another Kuberhealthy pod left in flight, and waits for it to
+// report in and exit like a run started with Run.  The supplied last report time is the time of the last report on
+// the khstate of the check before the run was resumed.  ErrRunNotResumable is returned if the run can not be
+// resumed, in which case a new run should be started instead.
+func (ext *Checker) Resume(ctx context.Context, client *kubernetes.Clientset, run khstatev1.InFlightRun, lastReportTime metav1.Time) error {
+
+	// store the client in the checker
+	ext.KubeClient = client
+
+	pod, err := client.CoreV1().Pods(ext.Namespace).Get(ctx, run.Pod, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return ErrRunNotResumable
+	}
+	if err != nil {
+		return err
+	}
+	err = resumable(pod, run, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// take over the run of the checker pod
+	ext.currentCheckUUID = run.UUID
+	ext.checkPodName = run.Pod
+	err = ext.adoptCheckerPod(ctx, pod)
+	if err != nil {
+		ext.log("error taking ownership of checker pod", run.Pod+":", err)
+	}
+	ext.log("Resuming run in flight with checker pod", run.Pod)
+	ext.recordEvent(apiv1.EventTypeNormal, EventReasonRunResumed, "Resumed run %s with checker pod %s", ext.currentCheckUUID, run.Pod)
+	err = ext.resumeOnce(ctx, pod, run.Deadline.Time, lastReportTime)
+	ext.recordRunResult(ctx, err)
+
+	// if the pod was removed, we skip this run gracefully
+	if err != nil && err.Error() == ErrPodRemovedExpectedly.Error() {
+		ext.log("pod was removed during check expectedly. skipping this run")
+		return ErrPodRemovedExpectedly
+	}
+	return err
+}
+
+// resumeOnce supervises the supplied checker pod of a resumed run until it reports in and exits, or the deadline
+// of the run passes
+func (ext *Checker) resumeOnce(ctx context.Context, pod *apiv1.Pod, deadline time.Time, lastReportTime metav1.Time) (err error) {
+
+	// trace the phases of this run
+	trace := ext.newRunTrace(ctx)
+	trace.run.SetAttributes(tracing.String("kuberhealthy.pod", ext.podName()), tracing.String("kuberhealthy.resumed", "true"))
+	defer func() {
+		trace.end(err)
+	}()
+
+	// create a context for this run
+	ext.shutdownCTX, ext.shutdownCTXFunc = context.WithCancel(ctx)
+	defer ext.shutdownCTXFunc()
+	defer func() {
+		ext.cleanupRun(ctx, trace)
+	}()
+
+	ext.setInFlight(true)
+	ext.reportDuration = 0
+	ext.schedulingLatency = 0
+	ext.failureLogs = ""
+	ext.podStartTime = time.Time{}
+	ext.podCreateTime = time.Time{}
+	if pod.Status.StartTime != nil {
+		ext.podStartTime = pod.Status.StartTime.Time
+	}
+
+	// pods that have not started running yet are still waiting to be scheduled, so their scheduling latency is
+	// measured from when they were created
+	if pod.Status.Phase == apiv1.PodPending {
+		ext.podCreateTime = pod.CreationTimestamp.Time
+	}
+
+	// capture the logs of the checker pod when the run fails, before the pod is cleaned up
+	defer func() {
+		ext.keepFailureLogs(ctx, err)
+	}()
+
+	podShutdownWatchCtx, podShutdownWatchCtxCancel := context.WithCancel(ctx)
+	podDeletedChan := ext.watchForCheckerPodDelete(podShutdownWatchCtx)
+	defer podShutdownWatchCtxCancel()
+
+	timeoutChan := time.After(time.Until(deadline))
+	return ext.superviseRun(ctx, trace, timeoutChan, lastReportTime, podDeletedChan, podShutdownWatchCtxCancel)
+}
+
+// releaseCheckerPod removes the owner reference of this Kuberhealthy pod from the checker pod of the run in flight,
+// so that the checker pod is not garbage collected when this Kuberhealthy pod is deleted
+func (ext *Checker) releaseCheckerPod() error {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseCheckerPodTimeout)
+	defer cancel()
+
+	pod, err := ext.KubeClient.CoreV1().Pods(ext.Namespace).Get(ctx, ext.podName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return ext.patchOwnerReferences(ctx, withoutPodOwners(pod.OwnerReferences))
+}
+
+// adoptCheckerPod sets this Kuberhealthy pod as the owner of the supplied checker pod of a resumed run, like the
+// checker pods it creates
+func (ext *Checker) adoptCheckerPod(ctx context.Context, pod *apiv1.Pod) error {
+	ownerRefs := withoutPodOwners(pod.OwnerReferences)
+	if pod.Namespace == kuberhealthyNamespace {
+		kuberhealthyRef, err := util.GetOwnerRef(ext.KubeClient, kuberhealthyNamespace)
+		if err != nil {
+			return err
+		}
+		ownerRefs = append(kuberhealthyRef, ownerRefs...)
+	}
+	return ext.patchOwnerReferences(ctx, ownerRefs)
+}
+
+// patchOwnerReferences replaces the owner references of the checker pod of the current run
+func (ext *Checker) patchOwnerReferences(ctx context.Context, ownerRefs []metav1.OwnerReference) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": ownerRefs,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ext.KubeClient.CoreV1().Pods(ext.Namespace).Patch(ctx, ext.podName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// withoutPodOwners returns the supplied owner references without the references to Kuberhealthy pods
+func withoutPodOwners(ownerRefs []metav1.OwnerReference) []metav1.OwnerReference {
+	kept := make([]metav1.OwnerReference, 0, len(ownerRefs))
+	for _, ref := range ownerRefs {
+		if ref.Kind == "Pod" && ref.APIVersion == "v1" {
+			continue
+		}
+		kept = append(kept, ref)
+	}
+	return kept
+}
+
+// Detach stops the run in flight without removing its checker pod, so that the Kuberhealthy pod that takes over as
+// master can resume it.  It is used instead of Shutdown when Kuberhealthy shuts down.
+func (ext *Checker) Detach() {
+	ext.detachMu.Lock()
+	ext.detached = true
+	ext.detachMu.Unlock()
+
+	if ext.shutdownCTXFunc != nil {
+		ext.log("detaching from run in flight due to shutdown")
+		ext.shutdownCTXFunc()
+	}
+}
+
+// setInFlight records whether the checker pod of the current run is recorded on the khstate for another
+// Kuberhealthy pod to resume
+func (ext *Checker) setInFlight(inFlight bool) {
+	ext.detachMu.Lock()
+	defer ext.detachMu.Unlock()
+	ext.inFlight = inFlight
+}
+
+// LeftInFlight returns true if the checker was detached while the checker pod of its run was recorded on the
+// khstate, so that its run is left running for another Kuberhealthy pod to resume
+func (ext *Checker) LeftInFlight() bool {
+	ext.detachMu.Lock()
+	defer ext.detachMu.Unlock()
+	return ext.detached && ext.inFlight
+}
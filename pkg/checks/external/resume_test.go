@@ -0,0 +1,64 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestResumable ensures that only checker pods of the run in flight that are still running before the deadline of
+// the run are resumed
+func TestResumable(t *testing.T) {
+	now := time.Now()
+	deleted := metav1.NewTime(now)
+	run := khstatev1.InFlightRun{Pod: "check-1234", UUID: "1234", Deadline: metav1.NewTime(now.Add(time.Minute))}
+	expiredRun := run
+	expiredRun.Deadline = metav1.NewTime(now.Add(-time.Minute))
+
+	testCases := []struct {
+		name      string
+		run       khstatev1.InFlightRun
+		runID     string
+		status    apiv1.PodStatus
+		deleted   *metav1.Time
+		resumable bool
+	}{
+		{name: "running", run: run, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodRunning}, resumable: true},
+		{name: "pending", run: run, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodPending}, resumable: true},
+		{name: "past deadline", run: expiredRun, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodRunning}},
+		{name: "other run", run: run, runID: "5678", status: apiv1.PodStatus{Phase: apiv1.PodRunning}},
+		{name: "succeeded", run: run, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodSucceeded}},
+		{name: "failed", run: run, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodFailed}},
+		{name: "deleting", run: run, runID: "1234", status: apiv1.PodStatus{Phase: apiv1.PodRunning}, deleted: &deleted},
+	}
+
+	for _, tc := range testCases {
+		pod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "check-1234", Labels: map[string]string{kuberhealthyRunIDLabel: tc.runID}, DeletionTimestamp: tc.deleted},
+			Status:     tc.status,
+		}
+		err := resumable(pod, tc.run, now)
+		if (err == nil) != tc.resumable {
+			t.Fatalf("%s: expected resumable to be %t but got error: %v", tc.name, tc.resumable, err)
+		}
+	}
+}
+
+// TestWithoutPodOwners ensures that only the owner references to Kuberhealthy pods are removed from checker pods
+func TestWithoutPodOwners(t *testing.T) {
+	ownerRefs := []metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "Pod", Name: "kuberhealthy-a"},
+		{APIVersion: "comcast.github.io/v1", Kind: "KuberhealthyCheck", Name: "check"},
+	}
+	kept := withoutPodOwners(ownerRefs)
+	if len(kept) != 1 || kept[0].Kind != "KuberhealthyCheck" {
+		t.Fatalf("expected only the khcheck owner reference to be kept but got %v", kept)
+	}
+	if kept = withoutPodOwners(nil); kept == nil || len(kept) != 0 {
+		t.Fatalf("expected an empty list of owner references so that the patch removes them but got %v", kept)
+	}
+}
@@ -0,0 +1,75 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podUnschedulableReason returns why the scheduler can not place the supplied checker pod on a node, or a blank
+// string if it can.  Pods are only reported once the scheduler has been unable to place them for longer than the
+// supplied timeout, so that clusters that add nodes for pending pods have time to do so.
+func podUnschedulableReason(pod *apiv1.Pod, now time.Time, timeout time.Duration) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != apiv1.PodScheduled || condition.Status != apiv1.ConditionFalse || condition.Reason != apiv1.PodReasonUnschedulable {
+			continue
+		}
+		if now.Sub(condition.LastTransitionTime.Time) < timeout {
+			return ""
+		}
+		if len(condition.Message) > 0 {
+			return "pod unschedulable: " + condition.Message
+		}
+		return "pod unschedulable: " + condition.Reason
+	}
+	return ""
+}
+
+// waitForPodUnschedulable returns a channel that receives an error if the scheduler can not place the checker pod
+// on a node for longer than the unschedulable timeout of the check, before the supplied context is canceled
+func (ext *Checker) waitForPodUnschedulable(ctx context.Context) chan error {
+
+	outChan := make(chan error, 1)
+	podClient := ext.KubeClient.CoreV1().Pods(ext.Namespace)
+
+	ext.wg.Add(1)
+	go func() {
+		defer ext.wg.Done()
+
+		for {
+			pods, err := podClient.List(ctx, metav1.ListOptions{
+				LabelSelector: kuberhealthyRunIDLabel + "=" + ext.currentCheckUUID,
+			})
+			if err != nil && ctx.Err() == nil {
+				ext.log("error listing checker pods when watching for pod scheduling:", err)
+			}
+			if err == nil {
+				for i := range pods.Items {
+					reason := podUnschedulableReason(&pods.Items[i], time.Now(), ext.UnschedulableTimeout)
+					if len(reason) > 0 {
+						outChan <- errors.New(reason)
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second * 5): // sleep between polls
+			}
+		}
+	}()
+
+	return outChan
+}
+
+// SchedulingLatency returns the time from checker pod creation to the pod running in the last run.  Zero is
+// returned if the checker pod did not start running during the last run, or was created by another Kuberhealthy
+// pod that the run was resumed from after it started.
+func (ext *Checker) SchedulingLatency() time.Duration {
+	return ext.schedulingLatency
+}
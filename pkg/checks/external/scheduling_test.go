@@ -0,0 +1,50 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestPodUnschedulableReason ensures that checker pods are reported as unschedulable once the scheduler has been
+// unable to place them for longer than the timeout
+func TestPodUnschedulableReason(t *testing.T) {
+	now := time.Now()
+	unschedulable := func(since time.Duration, message string) *apiv1.Pod {
+		pod := &apiv1.Pod{}
+		pod.Status.Phase = apiv1.PodPending
+		pod.Status.Conditions = []apiv1.PodCondition{{
+			Type:               apiv1.PodScheduled,
+			Status:             apiv1.ConditionFalse,
+			Reason:             apiv1.PodReasonUnschedulable,
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}
+		return pod
+	}
+	scheduled := &apiv1.Pod{}
+	scheduled.Status.Conditions = []apiv1.PodCondition{{Type: apiv1.PodScheduled, Status: apiv1.ConditionTrue}}
+
+	testCases := []struct {
+		name     string
+		pod      *apiv1.Pod
+		timeout  time.Duration
+		expected string
+	}{
+		{name: "unschedulable", pod: unschedulable(time.Second, "0/3 nodes are available: 3 Insufficient cpu."), expected: "pod unschedulable: 0/3 nodes are available: 3 Insufficient cpu."},
+		{name: "without message", pod: unschedulable(time.Second, ""), expected: "pod unschedulable: Unschedulable"},
+		{name: "within timeout", pod: unschedulable(time.Second, "0/3 nodes are available"), timeout: time.Minute},
+		{name: "past timeout", pod: unschedulable(time.Minute*2, "0/3 nodes are available"), timeout: time.Minute, expected: "pod unschedulable: 0/3 nodes are available"},
+		{name: "scheduled", pod: scheduled},
+		{name: "pending", pod: &apiv1.Pod{}},
+	}
+
+	for _, tc := range testCases {
+		reason := podUnschedulableReason(tc.pod, now, tc.timeout)
+		if reason != tc.expected {
+			t.Fatalf("%s: expected reason %q but got %q", tc.name, tc.expected, reason)
+		}
+	}
+}
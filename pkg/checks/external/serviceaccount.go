@@ -0,0 +1,96 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KHServiceAccountEnv is the environment variable that holds the name of the service account that Kuberhealthy runs
+// under, usually set with the downward API from spec.serviceAccountName
+const KHServiceAccountEnv = "POD_SERVICE_ACCOUNT"
+
+// defaultKuberhealthyServiceAccount is the service account Kuberhealthy is assumed to run under when
+// POD_SERVICE_ACCOUNT is not set, which is the one created by the provided manifests
+const defaultKuberhealthyServiceAccount = "kuberhealthy"
+
+// ServiceAccountSettings holds the settings for the service accounts that checker pods run under
+type ServiceAccountSettings struct {
+	Require bool     `yaml:"require,omitempty"` // checker pods must set a serviceAccountName instead of running as the default service account of their namespace
+	Denied  []string `yaml:"denied,omitempty"`  // service accounts that checker pods may never run under, as a name or namespace/name. The service account of kuberhealthy is always denied
+}
+
+// kuberhealthyServiceAccount returns the name of the service account that Kuberhealthy runs under
+func kuberhealthyServiceAccount() string {
+	name := os.Getenv(KHServiceAccountEnv)
+	if len(name) == 0 {
+		return defaultKuberhealthyServiceAccount
+	}
+	return name
+}
+
+// podServiceAccount returns the service account that a pod spec runs under, or a blank string when it runs as the
+// default service account of its namespace
+func podServiceAccount(spec apiv1.PodSpec) string {
+	if len(spec.ServiceAccountName) > 0 {
+		return spec.ServiceAccountName
+	}
+	return spec.DeprecatedServiceAccount
+}
+
+// ServiceAccountProblem returns a description of why a checker pod in the supplied namespace may not run under the
+// service account of its pod spec, or a blank string when it may.  Checker pods may never run under the service
+// account of Kuberhealthy, because that would give every tenant that can create khchecks in the Kuberhealthy
+// namespace the permissions of Kuberhealthy itself.
+func ServiceAccountProblem(spec apiv1.PodSpec, namespace string, settings ServiceAccountSettings) string {
+	name := podServiceAccount(spec)
+	if len(name) == 0 {
+		if settings.Require {
+			return "podSpec must set a serviceAccountName from namespace " + namespace
+		}
+		return ""
+	}
+
+	if namespace == kuberhealthyNamespace && name == kuberhealthyServiceAccount() {
+		return "podSpec can not run under the service account " + name + " of kuberhealthy"
+	}
+	for _, denied := range settings.Denied {
+		if denied == name || denied == namespace+"/"+name {
+			return "podSpec can not run under the denied service account " + name
+		}
+	}
+	return ""
+}
+
+// validateServiceAccount ensures that the checker pod may run under the service account of its pod spec, and that
+// the service account exists in the namespace of the check.  Without this, a missing service account only shows up
+// as a pod that never gets created.
+func (ext *Checker) validateServiceAccount(ctx context.Context) error {
+	problem := ServiceAccountProblem(ext.PodSpec, ext.Namespace, ext.ServiceAccountSettings)
+	if len(problem) > 0 {
+		return errors.New(problem)
+	}
+
+	name := podServiceAccount(ext.PodSpec)
+	if len(name) == 0 {
+		return nil
+	}
+	_, err := ext.KubeClient.CoreV1().ServiceAccounts(ext.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return errors.New("service account " + name + " does not exist in namespace " + ext.Namespace)
+	}
+	if k8sErrors.IsForbidden(err) {
+		// kuberhealthy may not have been granted access to service accounts yet. the pod is still created and
+		// kubernetes rejects it if the service account is missing
+		ext.log("Not allowed to verify that service account", name, "exists:", err)
+		return nil
+	}
+	if err != nil {
+		return errors.New("failed to look up service account " + name + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package external
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// TestServiceAccountProblem ensures that checker pods can not run under the service account of kuberhealthy or a
+// denied service account, and must name one when required
+func TestServiceAccountProblem(t *testing.T) {
+	t.Setenv(KHServiceAccountEnv, "kuberhealthy-sa")
+	denied := ServiceAccountSettings{Denied: []string{"cluster-admin", "payments/deployer"}}
+	required := ServiceAccountSettings{Require: true}
+
+	testCases := []struct {
+		name           string
+		serviceAccount string
+		namespace      string
+		settings       ServiceAccountSettings
+		expectProblem  bool
+	}{
+		{name: "default service account", namespace: "payments"},
+		{name: "default service account when required", namespace: "payments", settings: required, expectProblem: true},
+		{name: "own service account when required", serviceAccount: "dns-check", namespace: "payments", settings: required},
+		{name: "kuberhealthy service account", serviceAccount: "kuberhealthy-sa", namespace: kuberhealthyNamespace, expectProblem: true},
+		{name: "same name in another namespace", serviceAccount: "kuberhealthy-sa", namespace: "payments"},
+		{name: "denied name", serviceAccount: "cluster-admin", namespace: "payments", settings: denied, expectProblem: true},
+		{name: "denied in namespace", serviceAccount: "deployer", namespace: "payments", settings: denied, expectProblem: true},
+		{name: "denied in another namespace", serviceAccount: "deployer", namespace: "shipping", settings: denied},
+	}
+
+	for _, tc := range testCases {
+		spec := apiv1.PodSpec{ServiceAccountName: tc.serviceAccount}
+		problem := ServiceAccountProblem(spec, tc.namespace, tc.settings)
+		if tc.expectProblem && len(problem) == 0 {
+			t.Fatalf("%s: expected the service account to be refused", tc.name)
+		}
+		if !tc.expectProblem && len(problem) > 0 {
+			t.Fatalf("%s: expected the service account to be allowed but got: %s", tc.name, problem)
+		}
+	}
+
+	// the deprecated serviceAccount field is checked too
+	if len(ServiceAccountProblem(apiv1.PodSpec{DeprecatedServiceAccount: "kuberhealthy-sa"}, kuberhealthyNamespace, ServiceAccountSettings{})) == 0 {
+		t.Fatal("Expected the deprecated serviceAccount field to be checked")
+	}
+}
@@ -0,0 +1,36 @@
+package external
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ImageVerifier verifies the signatures of checker images before they are run
+type ImageVerifier interface {
+	// Verify returns an error if the supplied image is not signed by a trusted signer, or the image pinned to the
+	// digest that was verified
+	Verify(ctx context.Context, image string) (string, error)
+}
+
+// verifyImages ensures that every image of the checker pod is signed by a trusted signer, and pins each image to
+// the digest that was verified so that its tag can not be moved to an unsigned image before it is pulled
+func (ext *Checker) verifyImages(ctx context.Context) error {
+	err := verifyContainerImages(ctx, ext.ImageVerifier, ext.PodSpec.InitContainers)
+	if err != nil {
+		return err
+	}
+	return verifyContainerImages(ctx, ext.ImageVerifier, ext.PodSpec.Containers)
+}
+
+// verifyContainerImages verifies the image of every supplied container and pins it to the digest that was verified
+func verifyContainerImages(ctx context.Context, verifier ImageVerifier, containers []apiv1.Container) error {
+	for i := range containers {
+		pinned, err := verifier.Verify(ctx, containers[i].Image)
+		if err != nil {
+			return err
+		}
+		containers[i].Image = pinned
+	}
+	return nil
+}
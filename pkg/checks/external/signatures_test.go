@@ -0,0 +1,46 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// fakeImageVerifier is an ImageVerifier that trusts the images in its map and pins them to the mapped digest
+type fakeImageVerifier map[string]string
+
+// Verify returns the image pinned to its mapped digest, or an error when it is not in the map
+func (f fakeImageVerifier) Verify(ctx context.Context, image string) (string, error) {
+	digest, ok := f[image]
+	if !ok {
+		return "", errors.New("image " + image + " is not signed")
+	}
+	return image + "@" + digest, nil
+}
+
+// TestVerifyImages ensures that every image of the checker pod is verified and pinned to its verified digest
+func TestVerifyImages(t *testing.T) {
+	ext := &Checker{
+		ImageVerifier: fakeImageVerifier{"quay.io/example/init": "sha256:aaa", "quay.io/example/check": "sha256:bbb"},
+	}
+	ext.PodSpec = apiv1.PodSpec{
+		InitContainers: []apiv1.Container{{Name: "init", Image: "quay.io/example/init"}},
+		Containers:     []apiv1.Container{{Name: "main", Image: "quay.io/example/check"}},
+	}
+
+	err := ext.verifyImages(context.Background())
+	if err != nil {
+		t.Fatal("Expected every image to be verified but got:", err)
+	}
+	if ext.PodSpec.InitContainers[0].Image != "quay.io/example/init@sha256:aaa" || ext.PodSpec.Containers[0].Image != "quay.io/example/check@sha256:bbb" {
+		t.Fatalf("Expected every image to be pinned to its digest but got %s and %s", ext.PodSpec.InitContainers[0].Image, ext.PodSpec.Containers[0].Image)
+	}
+
+	ext.PodSpec.Containers = append(ext.PodSpec.Containers, apiv1.Container{Name: "sidecar", Image: "docker.io/unsigned"})
+	err = ext.verifyImages(context.Background())
+	if err == nil {
+		t.Fatal("Expected the unsigned sidecar image to be refused")
+	}
+}
@@ -24,3 +24,11 @@ func NewReport(errorMessages []string) Report {
 		OK:     ok,
 	}
 }
+
+// Progress is the format expected by the /externalCheckProgress endpoint.  Checker pods of long running checks send
+// it while their run is in flight to show how far along they are.
+type Progress struct {
+	Message    string
+	Step       int
+	TotalSteps int
+}
@@ -0,0 +1,100 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// podEvictedReason is the status reason of pods that were evicted from their node
+const podEvictedReason = "Evicted"
+
+// containerOOMKilledReason is the termination reason of containers that were killed for running out of memory
+const containerOOMKilledReason = "OOMKilled"
+
+// podTerminationReason returns the failure reason and a description of why the supplied checker pod was killed by
+// kubernetes, or a blank description if it was not.  Pods that were evicted or have a container that ran out of
+// memory are reported, because they can not report in and would otherwise only show up as a timeout.
+func podTerminationReason(pod *apiv1.Pod) (khstatev1.FailureReason, string) {
+	if pod.Status.Reason == podEvictedReason {
+		reason := "checker pod " + pod.Name + " was evicted from node " + pod.Spec.NodeName
+		if len(pod.Status.Message) > 0 {
+			reason += ": " + pod.Status.Message
+		}
+		return khstatev1.FailureReasonEvicted, reason
+	}
+
+	statuses := append([]apiv1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Terminated != nil && cs.State.Terminated.Reason == containerOOMKilledReason {
+			return khstatev1.FailureReasonOOMKilled, fmt.Sprintf("checker pod %s container %s was OOMKilled. Consider raising the memory limit of the check", pod.Name, cs.Name)
+		}
+	}
+	return "", ""
+}
+
+// waitForPodTermination returns a channel that receives an error carrying the failure reason if the checker pod is
+// evicted or runs out of memory before the supplied context is canceled
+func (ext *Checker) waitForPodTermination(ctx context.Context) chan error {
+
+	outChan := make(chan error, 1)
+	podClient := ext.KubeClient.CoreV1().Pods(ext.Namespace)
+
+	ext.wg.Add(1)
+	go func() {
+		defer ext.wg.Done()
+
+		for {
+			pods, err := podClient.List(ctx, metav1.ListOptions{
+				LabelSelector: kuberhealthyRunIDLabel + "=" + ext.currentCheckUUID,
+			})
+			if err != nil && ctx.Err() == nil {
+				ext.log("error listing checker pods when watching for pod termination:", err)
+			}
+			if err == nil {
+				for i := range pods.Items {
+					reason, details := podTerminationReason(&pods.Items[i])
+					if len(details) > 0 {
+						outChan <- &runError{reason: reason, err: errors.New(details)}
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second * 5): // sleep between polls
+			}
+		}
+	}()
+
+	return outChan
+}
+
+// terminationDetails returns the failure reason and a description of why the checker pod of the current run was
+// killed by kubernetes, prefixed for appending to an error message.  A blank description is returned if the pod was
+// not killed.
+func (ext *Checker) terminationDetails(ctx context.Context) (khstatev1.FailureReason, string) {
+	pods, err := ext.KubeClient.CoreV1().Pods(ext.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: kuberhealthyRunIDLabel + "=" + ext.currentCheckUUID,
+	})
+	if err != nil {
+		ext.log("error listing checker pods to find their termination reason:", err)
+		return "", ""
+	}
+	for i := range pods.Items {
+		reason, details := podTerminationReason(&pods.Items[i])
+		if len(details) > 0 {
+			return reason, ": " + details
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,63 @@
+package external
+
+import (
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestPodTerminationReason ensures that evicted and OOMKilled checker pods are described distinctly
+func TestPodTerminationReason(t *testing.T) {
+	evicted := &apiv1.Pod{}
+	evicted.Name = "test-check-1"
+	evicted.Spec.NodeName = "node-1"
+	evicted.Status.Phase = apiv1.PodFailed
+	evicted.Status.Reason = podEvictedReason
+	evicted.Status.Message = "The node was low on resource: memory."
+
+	oomKilled := &apiv1.Pod{}
+	oomKilled.Name = "test-check-2"
+	oomKilled.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name: "main",
+		State: apiv1.ContainerState{
+			Terminated: &apiv1.ContainerStateTerminated{Reason: containerOOMKilledReason, ExitCode: 137},
+		},
+	}}
+
+	failed := &apiv1.Pod{}
+	failed.Name = "test-check-3"
+	failed.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name: "main",
+		State: apiv1.ContainerState{
+			Terminated: &apiv1.ContainerStateTerminated{Reason: "Error", ExitCode: 1},
+		},
+	}}
+
+	testCases := []struct {
+		pod            *apiv1.Pod
+		expectedReason khstatev1.FailureReason
+		expected       []string
+	}{
+		{pod: evicted, expectedReason: khstatev1.FailureReasonEvicted, expected: []string{"evicted", "node-1", "low on resource"}},
+		{pod: oomKilled, expectedReason: khstatev1.FailureReasonOOMKilled, expected: []string{"OOMKilled", "main", "memory limit"}},
+		{pod: failed},
+	}
+
+	for _, tc := range testCases {
+		failureReason, reason := podTerminationReason(tc.pod)
+		if len(tc.expected) == 0 && len(reason) > 0 {
+			t.Fatalf("Expected no termination reason for pod %s but got %q", tc.pod.Name, reason)
+		}
+		if failureReason != tc.expectedReason {
+			t.Fatalf("Expected the failure reason of pod %s to be %q but got %q", tc.pod.Name, tc.expectedReason, failureReason)
+		}
+		for _, s := range tc.expected {
+			if !strings.Contains(reason, s) {
+				t.Fatalf("Expected termination reason of pod %s to contain %q but got %q", tc.pod.Name, s, reason)
+			}
+		}
+	}
+}
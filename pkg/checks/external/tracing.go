@@ -0,0 +1,39 @@
+package external
+
+import (
+	"context"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
+)
+
+// runTrace traces a single run of a checker.  The run is recorded as one span with a child span for each phase
+// of the run, such as waiting for the checker pod to be scheduled or to report in.
+type runTrace struct {
+	ctx   context.Context
+	run   *tracing.Span
+	phase *tracing.Span
+}
+
+// newRunTrace starts tracing a run of the checker
+func (ext *Checker) newRunTrace(ctx context.Context) *runTrace {
+	ctx, span := tracing.Start(ctx, "check run",
+		tracing.String("kuberhealthy.check", ext.CheckNamespace()+"/"+ext.Name()),
+		tracing.String("kuberhealthy.workload", string(ext.KHWorkload)),
+		tracing.String("kuberhealthy.run_uuid", ext.currentCheckUUID),
+	)
+	return &runTrace{ctx: ctx, run: span}
+}
+
+// startPhase ends the current phase of the run and starts the next one
+func (t *runTrace) startPhase(name string, attributes ...tracing.Attribute) {
+	t.phase.End()
+	_, t.phase = tracing.Start(t.ctx, name, attributes...)
+}
+
+// end finishes the current phase and the run.  If the run failed, both are marked with the error.
+func (t *runTrace) end(err error) {
+	t.phase.SetError(err)
+	t.phase.End()
+	t.run.SetError(err)
+	t.run.End()
+}
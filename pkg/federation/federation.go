@@ -0,0 +1,374 @@
+// Package federation polls the status pages of remote Kuberhealthy instances and merges them into a single status,
+// so that the checks of many clusters can be watched from one place.
+package federation // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/federation"
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+// defaultPollInterval is how often remote status pages are fetched when no interval is configured
+const defaultPollInterval = time.Second * 30
+
+// defaultTimeout is how long fetching a single remote status page may take when no timeout is configured
+const defaultTimeout = time.Second * 10
+
+// defaultPushTimeout is how long after its last push a pushing cluster is marked unreachable when no timeout is
+// configured
+const defaultPushTimeout = time.Minute * 3
+
+// maxStatusBytes is the largest remote status page that is read
+const maxStatusBytes = 32 << 20
+
+// Config holds the settings for federating the status of remote Kuberhealthy instances
+type Config struct {
+	Enabled      bool            `yaml:"enabled"`                // set to true to poll remote clusters and serve their merged status
+	PollInterval time.Duration   `yaml:"pollInterval,omitempty"` // how often remote status pages are fetched. Defaults to 30s
+	Timeout      time.Duration   `yaml:"timeout,omitempty"`      // how long fetching a single remote status page may take. Defaults to 10s
+	PushTimeout  time.Duration   `yaml:"pushTimeout,omitempty"`  // how long after its last push a pushing cluster is marked unreachable. Defaults to 3m
+	Clusters     []ClusterConfig `yaml:"clusters,omitempty"`     // the remote Kuberhealthy instances to federate
+}
+
+// ClusterConfig holds the settings for fetching the status page of a single remote Kuberhealthy instance
+type ClusterConfig struct {
+	Name               string `yaml:"name"`                         // the name of the cluster, used as the cluster label of its metrics
+	URL                string `yaml:"url,omitempty"`                // the URL of the status page, such as https://kuberhealthy.prod-east.example.com/
+	Push               bool   `yaml:"push,omitempty"`               // set to true when the cluster pushes its status instead of being polled
+	BearerTokenFile    string `yaml:"bearerTokenFile,omitempty"`    // a file holding a bearer token sent with every request, or required with every push from a pushing cluster
+	Username           string `yaml:"username,omitempty"`           // the username sent with basic authentication
+	PasswordFile       string `yaml:"passwordFile,omitempty"`       // a file holding the password sent with basic authentication
+	CAFile             string `yaml:"caFile,omitempty"`             // a file holding the CA certificates that the status page is verified with instead of the system roots
+	CertFile           string `yaml:"certFile,omitempty"`           // a client certificate presented to the status page
+	KeyFile            string `yaml:"keyFile,omitempty"`            // the key of the client certificate
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"` // set to true to skip verifying the certificate of the status page
+}
+
+// Validate returns an error describing the first problem found with the configuration
+func (c Config) Validate() error {
+	if len(c.Clusters) == 0 {
+		return fmt.Errorf("federation requires at least one cluster")
+	}
+	names := make(map[string]bool)
+	for _, cluster := range c.Clusters {
+		if len(cluster.Name) == 0 {
+			return fmt.Errorf("federated cluster with url %s requires a name", cluster.URL)
+		}
+		if names[cluster.Name] {
+			return fmt.Errorf("federated cluster %s is configured more than once", cluster.Name)
+		}
+		names[cluster.Name] = true
+
+		if cluster.Push {
+			if len(cluster.BearerTokenFile) == 0 {
+				return fmt.Errorf("pushing federated cluster %s requires a bearerTokenFile to authenticate pushes with", cluster.Name)
+			}
+			continue
+		}
+
+		u, err := url.Parse(cluster.URL)
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("federated cluster %s has an invalid url %q", cluster.Name, cluster.URL)
+		}
+		if (len(cluster.CertFile) == 0) != (len(cluster.KeyFile) == 0) {
+			return fmt.Errorf("federated cluster %s requires both a certFile and a keyFile", cluster.Name)
+		}
+	}
+	return nil
+}
+
+// State is the merged status of all federated clusters.  It is OK when every cluster could be reached and reports
+// that it is OK.  Errors are prefixed with the name of the cluster they came from.
+type State struct {
+	OK       bool
+	Errors   []string
+	Clusters map[string]ClusterState
+}
+
+// ClusterState is the last known status of a single federated cluster
+type ClusterState struct {
+	URL         string
+	Reachable   bool         // indicates if the last fetch of the status page succeeded, or a pushing cluster pushed recently
+	Error       string       // why the cluster is not reachable
+	LastUpdated time.Time    // when the status page was last fetched or pushed successfully
+	State       health.State // the status page as last fetched or pushed
+}
+
+// errors returned when a pushed status is not accepted
+var (
+	ErrUnknownCluster = errors.New("cluster is not configured to push its status")
+	ErrUnauthorized   = errors.New("invalid bearer token")
+)
+
+// Federator fetches the status pages of remote Kuberhealthy instances on an interval and keeps their last known
+// status
+type Federator struct {
+	config  Config
+	clients map[string]*http.Client
+	mu      sync.RWMutex
+	states  map[string]ClusterState
+}
+
+// NewFederator creates a Federator for the supplied configuration.  Call Start to begin polling.
+func NewFederator(config Config) (*Federator, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.PushTimeout <= 0 {
+		config.PushTimeout = defaultPushTimeout
+	}
+
+	f := &Federator{
+		config:  config,
+		clients: make(map[string]*http.Client),
+		states:  make(map[string]ClusterState),
+	}
+	for _, cluster := range config.Clusters {
+		if cluster.Push {
+			f.states[cluster.Name] = ClusterState{Error: "no status pushed yet"}
+			continue
+		}
+		client, err := newClient(cluster, config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("federated cluster %s: %w", cluster.Name, err)
+		}
+		f.clients[cluster.Name] = client
+		f.states[cluster.Name] = ClusterState{URL: cluster.URL, Error: "not fetched yet"}
+	}
+	return f, nil
+}
+
+// newClient creates the HTTP client that the status page of a cluster is fetched with, using the TLS settings of the
+// cluster
+func newClient(cluster ClusterConfig, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cluster.InsecureSkipVerify}
+	if len(cluster.CAFile) > 0 {
+		caPEM, err := os.ReadFile(cluster.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", cluster.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cluster.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(cluster.CertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(cluster.CertFile, cluster.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s: %w", cluster.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// Start fetches the status pages of all clusters every poll interval until the context is canceled
+func (f *Federator) Start(ctx context.Context) {
+	log.Infoln("federation: polling", len(f.config.Clusters), "clusters every", f.config.PollInterval)
+
+	ticker := time.NewTicker(f.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		f.poll(ctx)
+		select {
+		case <-ctx.Done():
+			log.Infoln("federation: stopped polling clusters")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the status pages of all clusters at the same time
+func (f *Federator) poll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, cluster := range f.config.Clusters {
+		if cluster.Push {
+			continue
+		}
+		wg.Add(1)
+		go func(cluster ClusterConfig) {
+			defer wg.Done()
+			state, err := f.fetch(ctx, cluster)
+			f.update(cluster, state, err)
+		}(cluster)
+	}
+	wg.Wait()
+}
+
+// update records the result of fetching the status page of a cluster.  When the fetch failed, the last known status
+// of the cluster is kept and the cluster is marked unreachable.
+func (f *Federator) update(cluster ClusterConfig, state health.State, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	clusterState := f.states[cluster.Name]
+	clusterState.URL = cluster.URL
+	if err != nil {
+		log.Warningln("federation: failed to fetch status of cluster", cluster.Name+":", err)
+		clusterState.Reachable = false
+		clusterState.Error = err.Error()
+	} else {
+		clusterState.Reachable = true
+		clusterState.Error = ""
+		clusterState.LastUpdated = time.Now()
+		clusterState.State = state
+	}
+	f.states[cluster.Name] = clusterState
+}
+
+// Receive records a status pushed by a cluster.  An error is returned if the cluster is not configured to push or the
+// token does not match its bearer token.
+func (f *Federator) Receive(name string, token string, state health.State) error {
+	var cluster *ClusterConfig
+	for i := range f.config.Clusters {
+		if f.config.Clusters[i].Name == name && f.config.Clusters[i].Push {
+			cluster = &f.config.Clusters[i]
+		}
+	}
+	if cluster == nil {
+		return ErrUnknownCluster
+	}
+
+	expected, err := readSecretFile(cluster.BearerTokenFile)
+	if err != nil {
+		return err
+	}
+	if len(token) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return ErrUnauthorized
+	}
+
+	f.update(*cluster, state, nil)
+	return nil
+}
+
+// fetch gets and decodes the status page of a cluster
+func (f *Federator) fetch(ctx context.Context, cluster ClusterConfig) (health.State, error) {
+	state := health.State{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cluster.URL, nil)
+	if err != nil {
+		return state, fmt.Errorf("failed to create status request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// secret files are read on each fetch so that rotated secrets are picked up without restarting Kuberhealthy
+	if len(cluster.BearerTokenFile) > 0 {
+		token, err := readSecretFile(cluster.BearerTokenFile)
+		if err != nil {
+			return state, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if len(cluster.Username) > 0 {
+		var password string
+		if len(cluster.PasswordFile) > 0 {
+			password, err = readSecretFile(cluster.PasswordFile)
+			if err != nil {
+				return state, err
+			}
+		}
+		req.SetBasicAuth(cluster.Username, password)
+	}
+
+	resp, err := f.clients[cluster.Name].Do(req)
+	if err != nil {
+		return state, fmt.Errorf("failed to fetch status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return state, fmt.Errorf("status page returned status code %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxStatusBytes)).Decode(&state)
+	if err != nil {
+		return state, fmt.Errorf("failed to decode status: %w", err)
+	}
+	return state, nil
+}
+
+// CurrentState returns the merged status of all clusters
+func (f *Federator) CurrentState() State {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	merged := State{
+		OK:       true,
+		Errors:   []string{},
+		Clusters: make(map[string]ClusterState, len(f.states)),
+	}
+
+	names := make([]string, 0, len(f.states))
+	for name := range f.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		clusterState := f.states[name]
+		if f.pushing(name) && clusterState.Reachable && time.Since(clusterState.LastUpdated) > f.config.PushTimeout {
+			clusterState.Reachable = false
+			clusterState.Error = "no status pushed since " + clusterState.LastUpdated.Format(time.RFC3339)
+		}
+		merged.Clusters[name] = clusterState
+
+		if !clusterState.Reachable {
+			merged.OK = false
+			merged.Errors = append(merged.Errors, "["+name+"] cluster unreachable: "+clusterState.Error)
+		}
+		if !clusterState.State.OK && !clusterState.LastUpdated.IsZero() {
+			merged.OK = false
+		}
+		for _, e := range clusterState.State.Errors {
+			merged.Errors = append(merged.Errors, "["+name+"] "+e)
+		}
+	}
+	return merged
+}
+
+// pushing indicates if the named cluster pushes its status instead of being polled
+func (f *Federator) pushing(name string) bool {
+	for _, cluster := range f.config.Clusters {
+		if cluster.Name == name {
+			return cluster.Push
+		}
+	}
+	return false
+}
+
+// readSecretFile reads a secret, such as a token or password, from a file
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,94 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+func TestValidate(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"valid", Config{Clusters: []ClusterConfig{{Name: "east", URL: "https://kh.east.example.com/"}}}, true},
+		{"no clusters", Config{}, false},
+		{"no name", Config{Clusters: []ClusterConfig{{URL: "https://kh.east.example.com/"}}}, false},
+		{"duplicate name", Config{Clusters: []ClusterConfig{{Name: "east", URL: "https://a/"}, {Name: "east", URL: "https://b/"}}}, false},
+		{"relative url", Config{Clusters: []ClusterConfig{{Name: "east", URL: "/status"}}}, false},
+		{"cert without key", Config{Clusters: []ClusterConfig{{Name: "east", URL: "https://a/", CertFile: "tls.crt"}}}, false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.config.Validate()
+		if tc.valid != (err == nil) {
+			t.Fatalf("%s: expected valid %t, got error %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestPoll(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	east := health.NewState()
+	east.OK = false
+	east.Errors = []string{"dns lookup failed"}
+	east.CheckDetails["dns-check"] = khstatev1.WorkloadDetails{OK: false, Namespace: "kuberhealthy"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(east)
+	}))
+	defer server.Close()
+
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unreachable.Close()
+
+	f, err := NewFederator(Config{Clusters: []ClusterConfig{
+		{Name: "east", URL: server.URL, BearerTokenFile: tokenFile},
+		{Name: "west", URL: unreachable.URL},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.poll(context.Background())
+
+	state := f.CurrentState()
+	if state.OK {
+		t.Fatal("Expected the merged state to not be OK")
+	}
+	if !state.Clusters["east"].Reachable || state.Clusters["east"].State.CheckDetails["dns-check"].Namespace != "kuberhealthy" {
+		t.Fatalf("Expected the status of east to be fetched, got %+v", state.Clusters["east"])
+	}
+	if state.Clusters["west"].Reachable || !strings.Contains(state.Clusters["west"].Error, "503") {
+		t.Fatalf("Expected west to be unreachable, got %+v", state.Clusters["west"])
+	}
+	if len(state.Errors) != 2 || state.Errors[0] != "[east] dns lookup failed" || !strings.HasPrefix(state.Errors[1], "[west] cluster unreachable") {
+		t.Fatalf("Unexpected merged errors %v", state.Errors)
+	}
+
+	// the last known status is kept when a cluster becomes unreachable
+	server.Close()
+	f.poll(context.Background())
+	state = f.CurrentState()
+	if state.Clusters["east"].Reachable || len(state.Clusters["east"].State.CheckDetails) != 1 {
+		t.Fatalf("Expected the last known status of east to be kept, got %+v", state.Clusters["east"])
+	}
+}
@@ -0,0 +1,167 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+// defaultPushInterval is how often the status is pushed when nothing changes and no interval is configured
+const defaultPushInterval = time.Minute
+
+// defaultPushMinInterval is the shortest time between two pushes when no interval is configured.  State changes that
+// happen closer together than this are sent in a single push.
+const defaultPushMinInterval = time.Second * 5
+
+// PushConfig holds the settings for pushing the status of this instance to a central collector
+type PushConfig struct {
+	Enabled            bool          `yaml:"enabled"`                      // set to true to push the status page to the collector whenever it changes
+	URL                string        `yaml:"url"`                          // the URL that the status is posted to. For a Kuberhealthy collector, this is https://<collector>/federation/clusters/<cluster name>
+	Interval           time.Duration `yaml:"interval,omitempty"`           // how often the status is pushed when nothing changes, so the collector knows this cluster is alive. Defaults to 1m
+	MinInterval        time.Duration `yaml:"minInterval,omitempty"`        // the shortest time between two pushes. Changes closer together are sent in a single push. Defaults to 5s
+	Timeout            time.Duration `yaml:"timeout,omitempty"`            // how long a single push may take. Defaults to 10s
+	BearerTokenFile    string        `yaml:"bearerTokenFile,omitempty"`    // a file holding a bearer token sent with every push, usually mounted from a secret
+	CAFile             string        `yaml:"caFile,omitempty"`             // a file holding the CA certificates that the collector is verified with instead of the system roots
+	CertFile           string        `yaml:"certFile,omitempty"`           // a client certificate presented to the collector
+	KeyFile            string        `yaml:"keyFile,omitempty"`            // the key of the client certificate
+	InsecureSkipVerify bool          `yaml:"insecureSkipVerify,omitempty"` // set to true to skip verifying the certificate of the collector
+}
+
+// Validate returns an error describing the first problem found with the configuration
+func (c PushConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return fmt.Errorf("status push has an invalid url %q", c.URL)
+	}
+	if (len(c.CertFile) == 0) != (len(c.KeyFile) == 0) {
+		return fmt.Errorf("status push requires both a certFile and a keyFile")
+	}
+	return nil
+}
+
+// Pusher posts the status of this instance to a central collector whenever it changes, and on an interval so that
+// the collector knows the cluster is alive when nothing changes
+type Pusher struct {
+	config  PushConfig
+	client  *http.Client
+	source  func() (health.State, bool) // returns the status to push, or false when this instance should not push
+	changed chan struct{}
+}
+
+// NewPusher creates a Pusher for the supplied configuration that pushes the status returned by source.  Call Start
+// to begin pushing.
+func NewPusher(config PushConfig, source func() (health.State, bool)) (*Pusher, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultPushInterval
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = defaultPushMinInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	client, err := newClient(ClusterConfig{
+		CAFile:             config.CAFile,
+		CertFile:           config.CertFile,
+		KeyFile:            config.KeyFile,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("status push: %w", err)
+	}
+
+	return &Pusher{
+		config:  config,
+		client:  client,
+		source:  source,
+		changed: make(chan struct{}, 1),
+	}, nil
+}
+
+// Notify tells the pusher that the status has changed and should be pushed soon
+func (p *Pusher) Notify() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Start pushes the status whenever it changes and every interval until the context is canceled
+func (p *Pusher) Start(ctx context.Context) {
+	log.Infoln("federation: pushing status to", p.config.URL)
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Infoln("federation: stopped pushing status")
+			return
+		case <-ticker.C:
+		case <-p.changed:
+			// wait a moment so that changes which happen together are sent in a single push
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.MinInterval):
+			}
+		}
+
+		state, ok := p.source()
+		if !ok {
+			continue
+		}
+		err := p.push(ctx, state)
+		if err != nil {
+			log.Errorln("federation: failed to push status to", p.config.URL+":", err)
+			continue
+		}
+		log.Debugln("federation: pushed status to", p.config.URL)
+	}
+}
+
+// push posts a status to the collector
+func (p *Pusher) push(ctx context.Context, state health.State) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(p.config.BearerTokenFile) > 0 {
+		token, err := readSecretFile(p.config.BearerTokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+func TestPusherPushesOnChange(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := make(chan health.State, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		state := health.State{}
+		_ = json.NewDecoder(r.Body).Decode(&state)
+		pushed <- state
+	}))
+	defer server.Close()
+
+	source := func() (health.State, bool) {
+		state := health.NewState()
+		state.CurrentMaster = "kuberhealthy-0"
+		return state, true
+	}
+	p, err := NewPusher(PushConfig{URL: server.URL, BearerTokenFile: tokenFile, Interval: time.Hour, MinInterval: time.Millisecond}, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Start(ctx)
+	p.Notify()
+
+	select {
+	case state := <-pushed:
+		if state.CurrentMaster != "kuberhealthy-0" {
+			t.Fatalf("Unexpected pushed status %+v", state)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Expected the status to be pushed after a change")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFederator(Config{PushTimeout: time.Minute, Clusters: []ClusterConfig{
+		{Name: "edge", Push: true, BearerTokenFile: tokenFile},
+		{Name: "east", URL: "https://kh.east.example.com/"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := health.NewState()
+	if f.Receive("edge", "wrong", state) != ErrUnauthorized {
+		t.Fatal("Expected a push with the wrong token to be rejected")
+	}
+	if f.Receive("east", "s3cret", state) != ErrUnknownCluster {
+		t.Fatal("Expected a push for a polled cluster to be rejected")
+	}
+	err = f.Receive("edge", "s3cret", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.CurrentState().Clusters["edge"].Reachable {
+		t.Fatal("Expected a pushing cluster to be reachable after a push")
+	}
+
+	// a pushing cluster is unreachable when it has not pushed for longer than the push timeout
+	f.mu.Lock()
+	edge := f.states["edge"]
+	edge.LastUpdated = time.Now().Add(-time.Hour)
+	f.states["edge"] = edge
+	f.mu.Unlock()
+	if f.CurrentState().Clusters["edge"].Reachable {
+		t.Fatal("Expected a pushing cluster to be unreachable after the push timeout")
+	}
+}
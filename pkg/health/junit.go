@@ -0,0 +1,161 @@
+package health
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report.  Kuberhealthy reports its checks and its jobs as two
+// test suites, with one test case for each check or job.
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite is a group of test cases in a JUnit XML report
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is the result of the last run of a single check or job
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure describes why a test case failed
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitSkipped describes why a test case was skipped
+type JUnitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnit renders the state as a JUnit XML report, so that CI systems which gate on the health of a cluster can
+// consume it natively.  Only failures that make the overall status fail are reported as failed test cases.  The
+// errors of failing warning and info checks are written to the output of their test case instead, and checks that
+// are in maintenance or have not run yet are skipped.
+func (h *State) JUnit(now time.Time) JUnitTestSuites {
+	report := JUnitTestSuites{Name: "kuberhealthy"}
+
+	suites := []struct {
+		name    string
+		details map[string]khstatev1.WorkloadDetails
+	}{
+		{name: "checks", details: h.CheckDetails},
+		{name: "jobs", details: h.JobDetails},
+	}
+	for _, s := range suites {
+		if len(s.details) == 0 {
+			continue
+		}
+		suite := JUnitTestSuite{Name: s.name, Timestamp: now.UTC().Format(time.RFC3339)}
+
+		// sort the names so that test cases are always listed in the same order
+		names := make([]string, 0, len(s.details))
+		for name := range s.details {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			tc := junitTestCase(name, s.name, s.details[name])
+			suite.Tests++
+			suite.Time += tc.Time
+			if tc.Failure != nil {
+				suite.Failures++
+			}
+			if tc.Skipped != nil {
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Time += suite.Time
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// junitTestCase converts the details of a check or job into a test case
+func junitTestCase(name string, suite string, details khstatev1.WorkloadDetails) JUnitTestCase {
+	tc := JUnitTestCase{Name: name, ClassName: "kuberhealthy." + suite}
+	if namespace := strings.SplitN(name, "/", 2); len(namespace) == 2 {
+		tc.ClassName = "kuberhealthy." + suite + "." + namespace[0]
+	}
+
+	duration, err := time.ParseDuration(details.RunDuration)
+	if err == nil {
+		tc.Time = duration.Seconds()
+	}
+
+	switch {
+	case details.Paused:
+		tc.Skipped = &JUnitSkipped{Message: "paused"}
+	case details.InMaintenance:
+		tc.Skipped = &JUnitSkipped{Message: "in maintenance"}
+	case details.LastRun == nil && details.OK && len(details.Errors) == 0:
+		tc.Skipped = &JUnitSkipped{Message: "has not run yet"}
+	case !details.OK && isCritical(details.Severity):
+		message := "check failed"
+		if len(details.Errors) > 0 {
+			message = details.Errors[0]
+		}
+		tc.Failure = &JUnitFailure{Message: message, Type: string(details.FailureReason), Text: strings.Join(details.Errors, "\n")}
+	case !details.OK:
+		tc.SystemOut = fmt.Sprintf("%s failure: %s", details.Severity, strings.Join(details.Errors, "\n"))
+	}
+	return tc
+}
+
+// isCritical determines if failures of the supplied severity make the overall health status fail
+func isCritical(severity string) bool {
+	return len(severity) == 0 || severity == "critical"
+}
+
+// WriteJUnitResponse writes the state as a JUnit XML report to an http response writer
+func (h *State) WriteJUnitResponse(w http.ResponseWriter) error {
+	b, err := xml.MarshalIndent(h.JUnit(time.Now()), "", "  ")
+	if err != nil {
+		log.Warningln("Error marshaling health check junit xml for caller:", err)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_, err = w.Write(append([]byte(xml.Header), b...))
+	if err != nil {
+		log.Errorln("Error writing response to caller:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package health_test
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+func TestJUnit(t *testing.T) {
+	lastRun := metav1.Now()
+	s := health.NewState()
+	s.CheckDetails["kuberhealthy/dns"] = khstatev1.WorkloadDetails{OK: true, RunDuration: "2.5s", LastRun: &lastRun}
+	s.CheckDetails["kuberhealthy/deployment"] = khstatev1.WorkloadDetails{OK: false, RunDuration: "1m", LastRun: &lastRun, Errors: []string{"deployment did not become ready", "timed out"}, FailureReason: "Timeout"}
+	s.CheckDetails["payments/batch"] = khstatev1.WorkloadDetails{OK: false, RunDuration: "1s", LastRun: &lastRun, Errors: []string{"slow"}, Severity: "warning"}
+	s.CheckDetails["payments/api"] = khstatev1.WorkloadDetails{OK: true, InMaintenance: true}
+	s.CheckDetails["payments/reports"] = khstatev1.WorkloadDetails{OK: false, Paused: true, Errors: []string{"stale"}}
+	s.JobDetails["kuberhealthy/upgrade"] = khstatev1.WorkloadDetails{OK: true}
+
+	report := s.JUnit(time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC))
+	assert.Equal(t, 6, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 3, report.Skipped)
+	assert.InDelta(t, 63.5, report.Time, 0.001)
+	assert.Len(t, report.Suites, 2)
+
+	checks := report.Suites[0]
+	assert.Equal(t, "checks", checks.Name)
+	assert.Equal(t, "2020-09-13T12:26:40Z", checks.Timestamp)
+	assert.Equal(t, "kuberhealthy/deployment", checks.Cases[0].Name)
+	assert.Equal(t, "kuberhealthy.checks.kuberhealthy", checks.Cases[0].ClassName)
+	assert.Equal(t, &health.JUnitFailure{Message: "deployment did not become ready", Type: "Timeout", Text: "deployment did not become ready\ntimed out"}, checks.Cases[0].Failure)
+	assert.Nil(t, checks.Cases[1].Failure)
+	assert.NotNil(t, checks.Cases[2].Skipped)
+	assert.Nil(t, checks.Cases[3].Failure)
+	assert.Equal(t, "warning failure: slow", checks.Cases[3].SystemOut)
+	assert.Equal(t, "paused", checks.Cases[4].Skipped.Message)
+	assert.Equal(t, "has not run yet", report.Suites[1].Cases[0].Skipped.Message)
+}
+
+func TestWriteJUnitResponse(t *testing.T) {
+	s := health.NewState()
+	s.CheckDetails["kuberhealthy/deployment"] = khstatev1.WorkloadDetails{OK: false, Errors: []string{"<failed> & broken"}}
+
+	w := httptest.NewRecorder()
+	err := s.WriteJUnitResponse(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+
+	var report health.JUnitTestSuites
+	err = xml.Unmarshal(w.Body.Bytes(), &report)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, "<failed> & broken", report.Suites[0].Cases[0].Failure.Message)
+}
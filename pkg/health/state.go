@@ -14,10 +14,13 @@ import (
 type State struct {
 	OK            bool
 	Errors        []string
+	Warnings      []string                             // errors of failing checks that are not critical, which do not affect OK
 	CheckDetails  map[string]khstatev1.WorkloadDetails // map of check names to last run timestamp
 	JobDetails    map[string]khstatev1.WorkloadDetails // map of job names to last run timestamp
 	CurrentMaster string
 	Metadata      map[string]string
+	ClusterName   string // the configured name of the cluster, so consumers of several clusters can tell them apart
+	Environment   string // the configured environment of the cluster, such as production
 }
 
 // AddError adds new errors to State
@@ -32,6 +35,18 @@ func (h *State) AddError(s ...string) {
 	}
 }
 
+// AddWarning adds new warnings to State
+func (h *State) AddWarning(s ...string) {
+	for _, str := range s {
+		if len(str) == 0 {
+			log.Warningln("AddWarning was called but the warning was blank so it was skipped.")
+			continue
+		}
+		log.Debugln("Appending warning:", str)
+		h.Warnings = append(h.Warnings, str)
+	}
+}
+
 // WriteHTTPStatusResponse writes a response to an http response writer
 func (h *State) WriteHTTPStatusResponse(w http.ResponseWriter) error {
 
@@ -59,6 +74,7 @@ func NewState() State {
 	s := State{}
 	s.OK = true
 	s.Errors = []string{}
+	s.Warnings = []string{}
 	s.CheckDetails = make(map[string]khstatev1.WorkloadDetails)
 	s.JobDetails = make(map[string]khstatev1.WorkloadDetails)
 	s.Metadata = map[string]string{}
@@ -0,0 +1,90 @@
+package imageverify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// maxLayerSize is the largest signature or attestation layer that is read from a registry
+const maxLayerSize = 4 << 20
+
+// registry fetches image digests and the layers of cosign signature and attestation images
+type registry interface {
+	// digest returns the repository of an image and the digest of the manifest that it points to
+	digest(ctx context.Context, image string) (string, string, error)
+	// layers returns the layers of the image with the supplied tag in a repository, or none if the tag does not exist
+	layers(ctx context.Context, repository string, tag string) ([]layer, error)
+}
+
+// remoteRegistry is a registry that talks to the container registries that images are pulled from.  Credentials
+// are read from the docker config file of Kuberhealthy.
+type remoteRegistry struct{}
+
+// options returns the options for requests to a registry
+func (remoteRegistry) options(ctx context.Context) []remote.Option {
+	return []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+}
+
+// digest returns the repository of an image and the digest of the manifest that it points to
+func (r remoteRegistry) digest(ctx context.Context, image string) (string, string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", "", err
+	}
+	repository := ref.Context().Name()
+	if d, ok := ref.(name.Digest); ok {
+		return repository, d.DigestStr(), nil
+	}
+
+	desc, err := remote.Head(ref, r.options(ctx)...)
+	if err != nil {
+		return "", "", err
+	}
+	return repository, desc.Digest.String(), nil
+}
+
+// layers returns the layers of the image with the supplied tag in a repository, or none if the tag does not exist
+func (r remoteRegistry) layers(ctx context.Context, repository string, tag string) ([]layer, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	img, err := remote.Image(repo.Tag(tag), r.options(ctx)...)
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var layers []layer
+	for _, desc := range manifest.Layers {
+		l, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := l.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := io.ReadAll(io.LimitReader(rc, maxLayerSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer{payload: payload, annotations: desc.Annotations})
+	}
+	return layers, nil
+}
@@ -0,0 +1,322 @@
+package imageverify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the tag suffixes that cosign stores the signatures and attestations of an image digest under
+const (
+	signatureTagSuffix   = ".sig"
+	attestationTagSuffix = ".att"
+)
+
+// the annotations that cosign sets on the layers of signature and attestation images
+const (
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// the extensions of Fulcio certificates that hold the OIDC issuer that authenticated the signer
+var (
+	issuerExtensionOID   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	issuerV2ExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// dssePayloadType is the payload type of the DSSE envelopes that hold in-toto attestations
+const dssePayloadType = "application/vnd.in-toto+json"
+
+// layer is a layer of a cosign signature or attestation image
+type layer struct {
+	payload     []byte
+	annotations map[string]string
+}
+
+// simpleSigningPayload is the payload that cosign signs to sign an image digest
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// dsseEnvelope is a signed DSSE envelope that holds an in-toto attestation
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// inTotoStatement is an in-toto attestation about a set of subjects
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// rekorBundle is the proof that a signature was recorded in the Rekor transparency log
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the log entry that the signed entry timestamp of a rekorBundle signs.  The fields are in the
+// order of their keys so that the entry marshals to its canonical JSON form.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// verifySignatureLayer ensures that a layer of a signature image signs the supplied image digest with one of the
+// configured keys or identities
+func (v *Verifier) verifySignatureLayer(l layer, digest string) error {
+	var payload simpleSigningPayload
+	err := json.Unmarshal(l.payload, &payload)
+	if err != nil {
+		return fmt.Errorf("invalid signature payload: %w", err)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest {
+		return errors.New("signature is for digest " + payload.Critical.Image.DockerManifestDigest)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(l.annotations[signatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return errors.New("signature layer has no signature")
+	}
+	return v.verifyBlob(l.payload, sig, l.annotations)
+}
+
+// verifyAttestationLayer ensures that a layer of an attestation image holds an attestation about the supplied image
+// digest signed with one of the configured keys or identities, and returns its predicate type
+func (v *Verifier) verifyAttestationLayer(l layer, digest string) (string, error) {
+	var envelope dsseEnvelope
+	err := json.Unmarshal(l.payload, &envelope)
+	if err != nil {
+		return "", fmt.Errorf("invalid attestation envelope: %w", err)
+	}
+	if envelope.PayloadType != dssePayloadType {
+		return "", errors.New("attestation has payload type " + envelope.PayloadType)
+	}
+	statementJSON, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid attestation payload: %w", err)
+	}
+
+	signed := false
+	var problems []string
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			problems = append(problems, "invalid attestation signature encoding")
+			continue
+		}
+		err = v.verifyBlob(pae(envelope.PayloadType, statementJSON), sig, l.annotations)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		signed = true
+		break
+	}
+	if !signed {
+		return "", errors.New("attestation has no valid signature: " + strings.Join(problems, "; "))
+	}
+
+	var statement inTotoStatement
+	err = json.Unmarshal(statementJSON, &statement)
+	if err != nil {
+		return "", fmt.Errorf("invalid attestation statement: %w", err)
+	}
+	algorithm, hexDigest, _ := strings.Cut(digest, ":")
+	for _, subject := range statement.Subject {
+		if subject.Digest[algorithm] == hexDigest {
+			return statement.PredicateType, nil
+		}
+	}
+	return "", errors.New("attestation of predicate type " + statement.PredicateType + " is not about digest " + digest)
+}
+
+// pae returns the DSSE pre-authentication encoding of a payload, which is what DSSE signatures sign
+func pae(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("DSSEv1 ")
+	b.WriteString(strconv.Itoa(len(payloadType)))
+	b.WriteString(" ")
+	b.WriteString(payloadType)
+	b.WriteString(" ")
+	b.WriteString(strconv.Itoa(len(payload)))
+	b.WriteString(" ")
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// verifyBlob ensures that the supplied signature signs the supplied data with one of the configured keys, or with
+// a certificate issued to one of the configured keyless identities when the layer carries one
+func (v *Verifier) verifyBlob(signed []byte, sig []byte, annotations map[string]string) error {
+	if certPEM := annotations[certificateAnnotation]; len(certPEM) > 0 && len(v.identities) > 0 {
+		return v.verifyKeyless(signed, sig, annotations)
+	}
+	for _, key := range v.keys {
+		if verifyWithKey(key, signed, sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match any of the public keys")
+}
+
+// verifyKeyless ensures that the supplied signature was made with a certificate that was issued by the configured
+// Fulcio roots to one of the configured identities, and recorded in the Rekor transparency log while the
+// certificate was valid
+func (v *Verifier) verifyKeyless(signed []byte, sig []byte, annotations map[string]string) error {
+	certs, err := parseCertificates([]byte(annotations[certificateAnnotation]))
+	if err != nil {
+		return fmt.Errorf("invalid signing certificate: %w", err)
+	}
+	cert := certs[0]
+
+	// signing certificates are short lived, so they are verified at the time that the log recorded the signature
+	integratedTime, err := v.verifyBundle(annotations[bundleAnnotation], sig)
+	if err != nil {
+		return err
+	}
+
+	intermediates := v.intermediates.Clone()
+	if chainPEM := annotations[chainAnnotation]; len(chainPEM) > 0 {
+		chain, err := parseCertificates([]byte(chainPEM))
+		if err != nil {
+			return fmt.Errorf("invalid signing certificate chain: %w", err)
+		}
+		for _, c := range chain {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted signing certificate: %w", err)
+	}
+
+	issuer := certificateIssuer(cert)
+	subjects := append([]string{}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	matched := false
+	for _, identity := range v.identities {
+		if identity.matches(issuer, subjects) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return errors.New("signing certificate of " + strings.Join(subjects, ", ") + " from issuer " + issuer + " does not match any of the keyless identities")
+	}
+
+	return verifyWithKey(cert.PublicKey, signed, sig)
+}
+
+// verifyBundle ensures that a Rekor bundle was signed by the configured Rekor key and records the supplied
+// signature, and returns the time that the signature was recorded
+func (v *Verifier) verifyBundle(bundleJSON string, sig []byte) (time.Time, error) {
+	if len(bundleJSON) == 0 {
+		return time.Time{}, errors.New("keyless signature was not recorded in the transparency log")
+	}
+	var bundle rekorBundle
+	err := json.Unmarshal([]byte(bundleJSON), &bundle)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log bundle: %w", err)
+	}
+
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = verifyWithKey(v.rekorKey, canonical, bundle.SignedEntryTimestamp)
+	if err != nil {
+		return time.Time{}, errors.New("transparency log bundle is not signed by the Rekor public key")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log entry: %w", err)
+	}
+	if !bodyReferencesSignature(body, sig) {
+		return time.Time{}, errors.New("transparency log entry does not record this signature")
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// bodyReferencesSignature indicates if a Rekor log entry holds the supplied signature.  hashedrekord entries hold
+// it base64 encoded, and intoto entries of attestations hold it base64 encoded twice.
+func bodyReferencesSignature(body []byte, sig []byte) bool {
+	encoded := base64.StdEncoding.EncodeToString(sig)
+	if bytes.Contains(body, []byte(encoded)) {
+		return true
+	}
+	return bytes.Contains(body, []byte(base64.StdEncoding.EncodeToString([]byte(encoded))))
+}
+
+// certificateIssuer returns the OIDC issuer that authenticated the signer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(issuerV2ExtensionOID) {
+			var issuer string
+			_, err := asn1.Unmarshal(ext.Value, &issuer)
+			if err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(issuerExtensionOID) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// verifyWithKey ensures that the supplied signature signs the supplied data with a public key, the way cosign
+// signs with keys of that type
+func verifyWithKey(key crypto.PublicKey, signed []byte, sig []byte) error {
+	hash := sha256.Sum256(signed)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hash[:], sig) {
+			return errors.New("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, signed, sig) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", key)
+}
@@ -0,0 +1,322 @@
+// Package imageverify verifies the cosign signatures and attestations of container images before they are run
+package imageverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheDuration is how long a successful verification of an image is trusted by default
+const DefaultCacheDuration = time.Minute * 10
+
+// Config holds the settings for verifying the cosign signatures of checker images before they are run
+type Config struct {
+	Enabled            bool          `yaml:"enabled"`                      // set to true to refuse to run checker images without a valid signature
+	PublicKeyFiles     []string      `yaml:"publicKeyFiles,omitempty"`     // PEM encoded public keys that checker images may be signed with
+	Keyless            []Identity    `yaml:"keyless,omitempty"`            // the identities that checker images may be signed by with keyless signing
+	RootCertsFile      string        `yaml:"rootCertsFile,omitempty"`      // PEM encoded root and intermediate certificates of the Fulcio instance that issues keyless signing certificates
+	RekorPublicKeyFile string        `yaml:"rekorPublicKeyFile,omitempty"` // PEM encoded public key of the Rekor transparency log that keyless signatures are recorded in
+	Attestations       []string      `yaml:"attestations,omitempty"`       // predicate types of signed attestations that checker images must also carry, such as https://slsa.dev/provenance/v0.2
+	CacheDuration      time.Duration `yaml:"cacheDuration,omitempty"`      // how long a successful verification of an image is trusted before its signatures are checked again. defaults to 10m
+}
+
+// Identity is a keyless signing identity that checker images may be signed by
+type Identity struct {
+	Issuer        string `yaml:"issuer"`                  // the OIDC issuer that authenticated the signer, such as https://token.actions.githubusercontent.com
+	Subject       string `yaml:"subject,omitempty"`       // the email address or URI of the signer
+	SubjectRegExp string `yaml:"subjectRegExp,omitempty"` // a regular expression that matches the whole email address or URI of the signer
+}
+
+// identityMatcher matches the issuer and subject of keyless signing certificates against an Identity
+type identityMatcher struct {
+	issuer  string
+	subject string
+	regexp  *regexp.Regexp
+}
+
+// matches indicates if the supplied issuer and one of the supplied subjects match the identity
+func (im identityMatcher) matches(issuer string, subjects []string) bool {
+	if issuer != im.issuer {
+		return false
+	}
+	for _, subject := range subjects {
+		if len(im.subject) > 0 && subject == im.subject {
+			return true
+		}
+		if im.regexp != nil && im.regexp.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier verifies that images carry a valid cosign signature, and any attestations that are required, before
+// they are run.  Images are verified by the digest that their tag points to, and successful verifications are
+// cached by image and digest.
+type Verifier struct {
+	keys          []crypto.PublicKey
+	identities    []identityMatcher
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	rekorKey      crypto.PublicKey
+	attestations  []string
+	cacheDuration time.Duration
+	registry      registry
+	now           func() time.Time
+
+	mu       sync.Mutex
+	verified map[string]time.Time // the time that each image, pinned to its digest, was last verified
+}
+
+// NewVerifier creates a Verifier from the supplied config and ensures that its keys and certificates can be loaded
+func NewVerifier(config Config) (*Verifier, error) {
+	v := &Verifier{
+		attestations:  config.Attestations,
+		cacheDuration: config.CacheDuration,
+		registry:      remoteRegistry{},
+		now:           time.Now,
+		verified:      make(map[string]time.Time),
+	}
+	if v.cacheDuration == 0 {
+		v.cacheDuration = DefaultCacheDuration
+	}
+
+	for _, file := range config.PublicKeyFiles {
+		key, err := loadPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, key)
+	}
+
+	if len(config.Keyless) > 0 {
+		if len(config.RootCertsFile) == 0 || len(config.RekorPublicKeyFile) == 0 {
+			return nil, errors.New("keyless verification requires both a rootCertsFile and a rekorPublicKeyFile")
+		}
+		var err error
+		v.roots, v.intermediates, err = loadRootCerts(config.RootCertsFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rekorKey, err = loadPublicKey(config.RekorPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, identity := range config.Keyless {
+		if len(identity.Issuer) == 0 || (len(identity.Subject) == 0 && len(identity.SubjectRegExp) == 0) {
+			return nil, errors.New("keyless identities require an issuer and a subject or subjectRegExp")
+		}
+		matcher := identityMatcher{issuer: identity.Issuer, subject: identity.Subject}
+		if len(identity.SubjectRegExp) > 0 {
+			re, err := regexp.Compile("^(?:" + identity.SubjectRegExp + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid keyless subjectRegExp %s: %w", identity.SubjectRegExp, err)
+			}
+			matcher.regexp = re
+		}
+		v.identities = append(v.identities, matcher)
+	}
+
+	if len(v.keys) == 0 && len(v.identities) == 0 {
+		return nil, errors.New("image verification requires at least one public key or keyless identity")
+	}
+	return v, nil
+}
+
+// loadPublicKey reads a PEM encoded public key from disk
+func loadPublicKey(file string) (crypto.PublicKey, error) {
+	keyPEM, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key %s: %w", file, err)
+	}
+	key, err := parsePublicKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", file, err)
+	}
+	return key, nil
+}
+
+// parsePublicKey parses a PEM encoded PKIX public key
+func parsePublicKey(keyPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// loadRootCerts reads PEM encoded certificates from disk.  Self-signed certificates are trusted as roots and the
+// others as intermediates.
+func loadRootCerts(file string) (*x509.CertPool, *x509.CertPool, error) {
+	certsPEM, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read root certificates %s: %w", file, err)
+	}
+	certs, err := parseCertificates(certsPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse root certificates %s: %w", file, err)
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs {
+		if cert.CheckSignatureFrom(cert) == nil {
+			roots.AddCert(cert)
+			continue
+		}
+		intermediates.AddCert(cert)
+	}
+	return roots, intermediates, nil
+}
+
+// parseCertificates parses every PEM encoded certificate in the supplied data
+func parseCertificates(certsPEM []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, certsPEM = pem.Decode(certsPEM)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// Verify ensures that the supplied image carries a valid signature from one of the configured keys or identities,
+// and every required attestation.  The image is returned pinned to the digest that was verified, so that its tag
+// can not be moved to another image before it is pulled.
+func (v *Verifier) Verify(ctx context.Context, image string) (string, error) {
+	repository, digest, err := v.registry.digest(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the digest of image %s: %w", image, err)
+	}
+	pinned := pinImage(image, digest)
+
+	if v.cached(pinned) {
+		return pinned, nil
+	}
+
+	err = v.verifySignatures(ctx, repository, digest)
+	if err != nil {
+		return "", fmt.Errorf("image %s is not signed: %w", image, err)
+	}
+	err = v.verifyAttestations(ctx, repository, digest)
+	if err != nil {
+		return "", fmt.Errorf("image %s is not attested: %w", image, err)
+	}
+
+	v.mu.Lock()
+	v.verified[pinned] = v.now()
+	v.mu.Unlock()
+	return pinned, nil
+}
+
+// cached indicates if the supplied pinned image was successfully verified within the cache duration
+func (v *Verifier) cached(pinned string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	verifiedAt, ok := v.verified[pinned]
+	if !ok {
+		return false
+	}
+	if v.now().Sub(verifiedAt) > v.cacheDuration {
+		delete(v.verified, pinned)
+		return false
+	}
+	return true
+}
+
+// verifySignatures ensures that at least one of the cosign signatures of an image digest is valid
+func (v *Verifier) verifySignatures(ctx context.Context, repository string, digest string) error {
+	layers, err := v.registry.layers(ctx, repository, artifactTag(digest, signatureTagSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to fetch signatures: %w", err)
+	}
+	if len(layers) == 0 {
+		return errors.New("no signatures found")
+	}
+
+	var problems []string
+	for _, l := range layers {
+		err := v.verifySignatureLayer(l, digest)
+		if err == nil {
+			return nil
+		}
+		problems = append(problems, err.Error())
+	}
+	return errors.New("no valid signatures found: " + strings.Join(problems, "; "))
+}
+
+// verifyAttestations ensures that an image digest carries a valid attestation of every required predicate type
+func (v *Verifier) verifyAttestations(ctx context.Context, repository string, digest string) error {
+	if len(v.attestations) == 0 {
+		return nil
+	}
+	layers, err := v.registry.layers(ctx, repository, artifactTag(digest, attestationTagSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to fetch attestations: %w", err)
+	}
+
+	attested := make(map[string]bool)
+	var problems []string
+	for _, l := range layers {
+		predicateType, err := v.verifyAttestationLayer(l, digest)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		attested[predicateType] = true
+	}
+
+	var missing []string
+	for _, predicateType := range v.attestations {
+		if !attested[predicateType] {
+			missing = append(missing, predicateType)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	message := "no valid attestations found of predicate type " + strings.Join(missing, ", ")
+	if len(problems) > 0 {
+		message += ": " + strings.Join(problems, "; ")
+	}
+	return errors.New(message)
+}
+
+// artifactTag returns the tag that cosign stores the signatures or attestations of an image digest under, such
+// as sha256-abc.sig
+func artifactTag(digest string, suffix string) string {
+	return strings.Replace(digest, ":", "-", 1) + suffix
+}
+
+// pinImage returns the supplied image reference with its tag or digest replaced by the supplied digest
+func pinImage(image string, digest string) string {
+	image, _, _ = strings.Cut(image, "@")
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		image = image[:colon]
+	}
+	return image + "@" + digest
+}
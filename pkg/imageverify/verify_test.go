@@ -0,0 +1,359 @@
+package imageverify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDigest is the digest of the image that the tests sign
+const testDigest = "sha256:4c5c4bdfb1f0b2b6cfbe6b8e1c1a1b4e4e6c1f2d1c1a1b4e4e6c1f2d1c1a1b4e"
+
+// fakeRegistry is a registry that serves digests and layers from memory
+type fakeRegistry struct {
+	digests map[string]string
+	tags    map[string][]layer
+}
+
+// digest returns the digest of an image from memory
+func (r *fakeRegistry) digest(ctx context.Context, image string) (string, string, error) {
+	repository, _, _ := strings.Cut(image, ":")
+	return repository, r.digests[image], nil
+}
+
+// layers returns the layers of a tag from memory
+func (r *fakeRegistry) layers(ctx context.Context, repository string, tag string) ([]layer, error) {
+	return r.tags[repository+":"+tag], nil
+}
+
+// newTestKey generates an ECDSA key like the ones cosign generates
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Failed to generate key:", err)
+	}
+	return key
+}
+
+// sign signs data with a key the way cosign does
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	hash := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal("Failed to sign:", err)
+	}
+	return sig
+}
+
+// signatureLayer returns a cosign signature layer that signs the supplied digest with a key
+func signatureLayer(t *testing.T, key *ecdsa.PrivateKey, digest string) layer {
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"quay.io/example/check"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"},"optional":null}`)
+	return layer{
+		payload:     payload,
+		annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sign(t, key, payload))},
+	}
+}
+
+// attestationLayer returns a cosign attestation layer with a statement of the supplied predicate type about the
+// supplied digest, signed with a key
+func attestationLayer(t *testing.T, key *ecdsa.PrivateKey, digest string, predicateType string) layer {
+	algorithm, hexDigest, _ := strings.Cut(digest, ":")
+	statement := []byte(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"` + predicateType + `","subject":[{"name":"quay.io/example/check","digest":{"` + algorithm + `":"` + hexDigest + `"}}],"predicate":{}}`)
+	sig := sign(t, key, pae(dssePayloadType, statement))
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payloadType": dssePayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(statement),
+		"signatures":  []map[string]string{{"keyid": "", "sig": base64.StdEncoding.EncodeToString(sig)}},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal envelope:", err)
+	}
+	return layer{payload: envelope, annotations: map[string]string{signatureAnnotation: ""}}
+}
+
+// newTestVerifier returns a Verifier that trusts the supplied keys and reads from the supplied registry
+func newTestVerifier(registry registry, keys ...*ecdsa.PrivateKey) *Verifier {
+	v := &Verifier{
+		cacheDuration: DefaultCacheDuration,
+		registry:      registry,
+		now:           time.Now,
+		verified:      make(map[string]time.Time),
+	}
+	for _, key := range keys {
+		v.keys = append(v.keys, key.Public())
+	}
+	return v
+}
+
+// TestVerifySignature ensures that images are only verified when they carry a signature of their digest from a
+// trusted key, and that they are pinned to the verified digest
+func TestVerifySignature(t *testing.T) {
+	trusted := newTestKey(t)
+	untrusted := newTestKey(t)
+	otherDigest := "sha256:" + strings.Repeat("0", 64)
+
+	registry := &fakeRegistry{
+		digests: map[string]string{
+			"quay.io/example/signed:v1":    testDigest,
+			"quay.io/example/untrusted:v1": testDigest,
+			"quay.io/example/unsigned:v1":  testDigest,
+			"quay.io/example/replayed:v1":  testDigest,
+		},
+		tags: map[string][]layer{
+			"quay.io/example/signed:" + artifactTag(testDigest, signatureTagSuffix):    {signatureLayer(t, untrusted, testDigest), signatureLayer(t, trusted, testDigest)},
+			"quay.io/example/untrusted:" + artifactTag(testDigest, signatureTagSuffix): {signatureLayer(t, untrusted, testDigest)},
+			"quay.io/example/replayed:" + artifactTag(testDigest, signatureTagSuffix):  {signatureLayer(t, trusted, otherDigest)},
+		},
+	}
+	v := newTestVerifier(registry, trusted)
+
+	pinned, err := v.Verify(context.Background(), "quay.io/example/signed:v1")
+	if err != nil {
+		t.Fatal("Expected the signed image to be verified but got:", err)
+	}
+	if pinned != "quay.io/example/signed@"+testDigest {
+		t.Fatal("Expected the image to be pinned to its digest but got:", pinned)
+	}
+
+	for _, image := range []string{"quay.io/example/untrusted:v1", "quay.io/example/unsigned:v1", "quay.io/example/replayed:v1"} {
+		_, err := v.Verify(context.Background(), image)
+		if err == nil {
+			t.Fatalf("Expected %s to be refused", image)
+		}
+	}
+}
+
+// TestVerifyCache ensures that successful verifications are trusted for the cache duration
+func TestVerifyCache(t *testing.T) {
+	key := newTestKey(t)
+	registry := &fakeRegistry{
+		digests: map[string]string{"quay.io/example/signed:v1": testDigest},
+		tags: map[string][]layer{
+			"quay.io/example/signed:" + artifactTag(testDigest, signatureTagSuffix): {signatureLayer(t, key, testDigest)},
+		},
+	}
+	v := newTestVerifier(registry, key)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	_, err := v.Verify(context.Background(), "quay.io/example/signed:v1")
+	if err != nil {
+		t.Fatal("Expected the signed image to be verified but got:", err)
+	}
+
+	// removing the signature does not matter until the cached verification expires
+	registry.tags = nil
+	_, err = v.Verify(context.Background(), "quay.io/example/signed:v1")
+	if err != nil {
+		t.Fatal("Expected the cached verification to be used but got:", err)
+	}
+	now = now.Add(DefaultCacheDuration + time.Second)
+	_, err = v.Verify(context.Background(), "quay.io/example/signed:v1")
+	if err == nil {
+		t.Fatal("Expected the image to be verified again once the cache expired")
+	}
+}
+
+// TestVerifyAttestations ensures that images must carry a signed attestation of every required predicate type
+// about their digest
+func TestVerifyAttestations(t *testing.T) {
+	key := newTestKey(t)
+	untrusted := newTestKey(t)
+	provenance := "https://slsa.dev/provenance/v0.2"
+	signatureTag := artifactTag(testDigest, signatureTagSuffix)
+	attestationTag := artifactTag(testDigest, attestationTagSuffix)
+
+	registry := &fakeRegistry{
+		digests: map[string]string{
+			"quay.io/example/attested:v1":   testDigest,
+			"quay.io/example/unattested:v1": testDigest,
+			"quay.io/example/untrusted:v1":  testDigest,
+			"quay.io/example/sbom:v1":       testDigest,
+		},
+		tags: map[string][]layer{
+			"quay.io/example/attested:" + signatureTag:    {signatureLayer(t, key, testDigest)},
+			"quay.io/example/attested:" + attestationTag:  {attestationLayer(t, key, testDigest, "https://spdx.dev/Document"), attestationLayer(t, key, testDigest, provenance)},
+			"quay.io/example/unattested:" + signatureTag:  {signatureLayer(t, key, testDigest)},
+			"quay.io/example/untrusted:" + signatureTag:   {signatureLayer(t, key, testDigest)},
+			"quay.io/example/untrusted:" + attestationTag: {attestationLayer(t, untrusted, testDigest, provenance)},
+			"quay.io/example/sbom:" + signatureTag:        {signatureLayer(t, key, testDigest)},
+			"quay.io/example/sbom:" + attestationTag:      {attestationLayer(t, key, testDigest, "https://spdx.dev/Document")},
+		},
+	}
+	v := newTestVerifier(registry, key)
+	v.attestations = []string{provenance}
+
+	_, err := v.Verify(context.Background(), "quay.io/example/attested:v1")
+	if err != nil {
+		t.Fatal("Expected the attested image to be verified but got:", err)
+	}
+	for _, image := range []string{"quay.io/example/unattested:v1", "quay.io/example/untrusted:v1", "quay.io/example/sbom:v1"} {
+		_, err := v.Verify(context.Background(), image)
+		if err == nil {
+			t.Fatalf("Expected %s to be refused without a provenance attestation", image)
+		}
+	}
+}
+
+// keylessFixture is a Fulcio root and Rekor key that keyless signatures are verified against
+type keylessFixture struct {
+	rootKey  *ecdsa.PrivateKey
+	root     *x509.Certificate
+	rekorKey *ecdsa.PrivateKey
+}
+
+// newKeylessFixture generates a Fulcio root and Rekor key
+func newKeylessFixture(t *testing.T) keylessFixture {
+	f := keylessFixture{rootKey: newTestKey(t), rekorKey: newTestKey(t)}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-fulcio-root"},
+		NotBefore:             time.Now().Add(-time.Hour * 24),
+		NotAfter:              time.Now().Add(time.Hour * 24),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, f.rootKey.Public(), f.rootKey)
+	if err != nil {
+		t.Fatal("Failed to create root certificate:", err)
+	}
+	f.root, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("Failed to parse root certificate:", err)
+	}
+	return f
+}
+
+// signingLayer returns a signature layer of the supplied digest signed with a short lived certificate issued to
+// the supplied email address by the supplied issuer, recorded in the log at the supplied time
+func (f keylessFixture) signingLayer(t *testing.T, digest string, email string, issuer string, loggedAt time.Time) layer {
+	key := newTestKey(t)
+	issuerValue, err := asn1.Marshal(issuer)
+	if err != nil {
+		t.Fatal("Failed to marshal issuer:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       loggedAt.Add(-time.Minute),
+		NotAfter:        loggedAt.Add(time.Minute * 9),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{email},
+		ExtraExtensions: []pkix.Extension{{Id: issuerV2ExtensionOID, Value: issuerValue}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, f.root, key.Public(), f.rootKey)
+	if err != nil {
+		t.Fatal("Failed to create signing certificate:", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	l := signatureLayer(t, key, digest)
+	body := `{"kind":"hashedrekord","spec":{"signature":{"content":"` + l.annotations[signatureAnnotation] + `"}}}`
+	entry := rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString([]byte(body)),
+		IntegratedTime: loggedAt.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       42,
+	}
+	canonical, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal("Failed to marshal log entry:", err)
+	}
+	bundle, err := json.Marshal(rekorBundle{SignedEntryTimestamp: sign(t, f.rekorKey, canonical), Payload: entry})
+	if err != nil {
+		t.Fatal("Failed to marshal bundle:", err)
+	}
+
+	l.annotations[certificateAnnotation] = string(certPEM)
+	l.annotations[bundleAnnotation] = string(bundle)
+	return l
+}
+
+// TestVerifyKeyless ensures that keyless signatures are only trusted when their certificate was issued by the
+// Fulcio root to a configured identity and was valid when the signature was recorded in the log
+func TestVerifyKeyless(t *testing.T) {
+	f := newKeylessFixture(t)
+	issuer := "https://token.actions.githubusercontent.com"
+	loggedAt := time.Now().Add(-time.Hour)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(f.root)
+	v := newTestVerifier(nil)
+	v.roots = roots
+	v.intermediates = x509.NewCertPool()
+	v.rekorKey = f.rekorKey.Public()
+	v.identities = []identityMatcher{{issuer: issuer, subject: "release@example.com"}}
+
+	err := v.verifySignatureLayer(f.signingLayer(t, testDigest, "release@example.com", issuer, loggedAt), testDigest)
+	if err != nil {
+		t.Fatal("Expected the keyless signature to be verified but got:", err)
+	}
+
+	err = v.verifySignatureLayer(f.signingLayer(t, testDigest, "someone@example.com", issuer, loggedAt), testDigest)
+	if err == nil {
+		t.Fatal("Expected a signature of another subject to be refused")
+	}
+	err = v.verifySignatureLayer(f.signingLayer(t, testDigest, "release@example.com", "https://accounts.example.com", loggedAt), testDigest)
+	if err == nil {
+		t.Fatal("Expected a signature from another issuer to be refused")
+	}
+
+	// the bundle must be signed by the rekor key and record this signature
+	l := f.signingLayer(t, testDigest, "release@example.com", issuer, loggedAt)
+	v.rekorKey = newTestKey(t).Public()
+	if v.verifySignatureLayer(l, testDigest) == nil {
+		t.Fatal("Expected a bundle that is not signed by the rekor key to be refused")
+	}
+	v.rekorKey = f.rekorKey.Public()
+	delete(l.annotations, bundleAnnotation)
+	if v.verifySignatureLayer(l, testDigest) == nil {
+		t.Fatal("Expected a keyless signature without a bundle to be refused")
+	}
+
+	// identities can be matched with a regular expression
+	v.identities = []identityMatcher{{issuer: issuer, regexp: regexpMustCompile(t, `.*@example\.com`)}}
+	err = v.verifySignatureLayer(f.signingLayer(t, testDigest, "someone@example.com", issuer, loggedAt), testDigest)
+	if err != nil {
+		t.Fatal("Expected the subject to match the regular expression but got:", err)
+	}
+}
+
+// regexpMustCompile compiles a subject regular expression like NewVerifier does
+func regexpMustCompile(t *testing.T, expression string) *regexp.Regexp {
+	re, err := regexp.Compile("^(?:" + expression + ")$")
+	if err != nil {
+		t.Fatal("Failed to compile regular expression:", err)
+	}
+	return re
+}
+
+// TestPinImage ensures that image references are pinned to a digest in place of their tag or digest
+func TestPinImage(t *testing.T) {
+	testCases := map[string]string{
+		"busybox":                          "busybox@" + testDigest,
+		"busybox:1.36":                     "busybox@" + testDigest,
+		"localhost:5000/check":             "localhost:5000/check@" + testDigest,
+		"localhost:5000/check:v1":          "localhost:5000/check@" + testDigest,
+		"quay.io/example/check@sha256:abc": "quay.io/example/check@" + testDigest,
+	}
+	for image, expected := range testCases {
+		pinned := pinImage(image, testDigest)
+		if pinned != expected {
+			t.Fatalf("Expected %s to be pinned as %s but got %s", image, expected, pinned)
+		}
+	}
+}
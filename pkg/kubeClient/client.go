@@ -1,15 +1,59 @@
 package kubeClient // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
 
 import (
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// defaultMaxRetries is how many times requests that are throttled or fail on the server are retried when
+// maxRetries is not set
+const defaultMaxRetries = 3
+
+// Config holds the settings of the clients created for the Kubernetes API.  Zero values keep the defaults of
+// client-go.
+type Config struct {
+	QPS             float32       `yaml:"qps,omitempty"`             // the sustained number of requests per second sent to the API server. defaults to 5
+	Burst           int           `yaml:"burst,omitempty"`           // the number of requests that may be sent at once above the QPS. defaults to 10
+	Timeout         time.Duration `yaml:"timeout,omitempty"`         // how long a single request may take. zero does not time out requests
+	MaxRetries      int           `yaml:"maxRetries,omitempty"`      // how many times throttled and failed requests are retried with exponential backoff. defaults to 3. negative values disable retries
+	DisableProtobuf bool          `yaml:"disableProtobuf,omitempty"` // send and accept JSON instead of protobuf for built-in resources like pods and events
+}
+
+// maxRetries returns how many times throttled and failed requests are retried
+func (c Config) maxRetries() int {
+	if c.MaxRetries == 0 {
+		return defaultMaxRetries
+	}
+	if c.MaxRetries < 0 {
+		return 0
+	}
+	return c.MaxRetries
+}
+
 // Create returns a kubernetes api clientset that enables communication with
 // the kubernetes API via the internal service.
 func Create(kubeConfigFile string) (*kubernetes.Clientset, error) {
+	return CreateWithConfig(kubeConfigFile, Config{})
+}
+
+// CreateWithConfig returns a kubernetes api clientset with the supplied rate limits, timeout and retries
+func CreateWithConfig(kubeConfigFile string, config Config) (*kubernetes.Clientset, error) {
+	kubeconfig, err := RESTConfig(kubeConfigFile, config)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(BuiltInConfig(kubeconfig, config))
+}
+
+// RESTConfig returns the configuration for clients of the Kubernetes API with the supplied rate limits, timeout and
+// retries.  The in-cluster configuration is used when running in a pod, and the kube config file otherwise.
+func RESTConfig(kubeConfigFile string, config Config) (*rest.Config, error) {
 	kubeconfig, err := rest.InClusterConfig()
 	if err != nil {
 		// If not in cluster, use kube config file
@@ -18,5 +62,37 @@ func Create(kubeConfigFile string) (*kubernetes.Clientset, error) {
 			return nil, err
 		}
 	}
-	return kubernetes.NewForConfig(kubeconfig)
+	applyConfig(kubeconfig, config)
+	return kubeconfig, nil
+}
+
+// BuiltInConfig returns a copy of a client configuration for the built-in resources of the Kubernetes API, such as
+// pods and events.  Unless it is disabled, the copy sends protobuf and asks for protobuf in responses, which is much
+// cheaper to decode than JSON.  Custom resources are only served as JSON, so clients for them must keep using the
+// original configuration.
+func BuiltInConfig(kubeconfig *rest.Config, config Config) *rest.Config {
+	builtIn := rest.CopyConfig(kubeconfig)
+	if !config.DisableProtobuf {
+		builtIn.ContentType = runtime.ContentTypeProtobuf
+		builtIn.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	}
+	return builtIn
+}
+
+// applyConfig sets the rate limits, timeout and retries of a client configuration
+func applyConfig(kubeconfig *rest.Config, config Config) {
+	if config.QPS > 0 {
+		kubeconfig.QPS = config.QPS
+	}
+	if config.Burst > 0 {
+		kubeconfig.Burst = config.Burst
+	}
+	if config.Timeout > 0 {
+		kubeconfig.Timeout = config.Timeout
+	}
+	if retries := config.maxRetries(); retries > 0 {
+		kubeconfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return newRetryTransport(rt, retries)
+		})
+	}
 }
@@ -0,0 +1,30 @@
+package kubeClient
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+// TestBuiltInConfig ensures that clients for built-in resources use protobuf unless it is disabled, and that the
+// original configuration is left as JSON for custom resource clients
+func TestBuiltInConfig(t *testing.T) {
+	kubeconfig := &rest.Config{Host: "https://kubernetes.default.svc"}
+
+	builtIn := BuiltInConfig(kubeconfig, Config{})
+	if builtIn.ContentType != runtime.ContentTypeProtobuf {
+		t.Fatalf("expected built-in resources to be sent as protobuf but got %q", builtIn.ContentType)
+	}
+	if builtIn.AcceptContentTypes != runtime.ContentTypeProtobuf+","+runtime.ContentTypeJSON {
+		t.Fatalf("expected protobuf to be accepted with a fallback to JSON but got %q", builtIn.AcceptContentTypes)
+	}
+	if kubeconfig.ContentType != "" || kubeconfig.AcceptContentTypes != "" {
+		t.Fatalf("expected the original configuration to be left unchanged but got %q and %q", kubeconfig.ContentType, kubeconfig.AcceptContentTypes)
+	}
+
+	builtIn = BuiltInConfig(kubeconfig, Config{DisableProtobuf: true})
+	if builtIn.ContentType != "" || builtIn.AcceptContentTypes != "" {
+		t.Fatalf("expected protobuf to be disabled but got %q and %q", builtIn.ContentType, builtIn.AcceptContentTypes)
+	}
+}
@@ -0,0 +1,110 @@
+package kubeClient
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryBaseDelay is how long the first retry of a request waits.  Each retry after it waits twice as long.
+const retryBaseDelay = time.Millisecond * 250
+
+// retryMaxDelay is the longest a retry waits, including the delay asked for by the API server
+const retryMaxDelay = time.Second * 30
+
+// retryTransport retries requests that the API server throttled with a 429 or failed with a 5xx, waiting
+// exponentially longer between attempts.  Throttled requests were not processed, so they are retried for every
+// method.  Server errors are only retried for requests that do not change anything, because a failed write may
+// still have been applied.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	wait       func(ctx context.Context, delay time.Duration) error // waits between attempts. replaced in tests
+}
+
+// newRetryTransport wraps a transport with one that retries throttled and failed requests up to maxRetries times
+func newRetryTransport(next http.RoundTripper, maxRetries int) *retryTransport {
+	return &retryTransport{next: next, maxRetries: maxRetries, wait: waitContext}
+}
+
+// waitContext waits for the supplied delay, or until the context is done
+func waitContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RoundTrip sends the request and retries it with exponential backoff while it is throttled or fails
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || attempt >= t.maxRetries || !retryable(req, resp) {
+			return resp, err
+		}
+
+		// requests with a body can only be retried if the body can be sent again
+		retry := req
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			retry = req.Clone(req.Context())
+			retry.Body = body
+		}
+
+		delay := retryDelay(attempt, resp)
+		drainBody(resp)
+		err = t.wait(req.Context(), delay)
+		if err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// retryable determines if a response to a request should be retried
+func retryable(req *http.Request, resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500:
+		return req.Method == http.MethodGet || req.Method == http.MethodHead
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt of a request.  The delay doubles with each attempt,
+// is jittered so that throttled clients do not retry in lockstep, and is never shorter than the Retry-After header
+// of the response.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	delay := retryBaseDelay << uint(attempt)
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(seconds)*time.Second > delay {
+		delay = time.Duration(seconds) * time.Second
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// drainBody reads and closes the body of a response that is being retried, so that its connection can be reused
+func drainBody(resp *http.Response) {
+	if resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
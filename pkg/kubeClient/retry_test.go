@@ -0,0 +1,102 @@
+package kubeClient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is a transport that answers requests with a function
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip answers the request
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestRetryTransport ensures that throttled requests are retried for every method, that server errors are only
+// retried for reads, and that request bodies are sent again on retries
+func TestRetryTransport(t *testing.T) {
+	testCases := []struct {
+		description      string
+		method           string
+		statuses         []int
+		expectedAttempts int
+		expectedStatus   int
+	}{
+		{description: "success", method: http.MethodGet, statuses: []int{200}, expectedAttempts: 1, expectedStatus: 200},
+		{description: "throttled read", method: http.MethodGet, statuses: []int{429, 429, 200}, expectedAttempts: 3, expectedStatus: 200},
+		{description: "throttled write", method: http.MethodPut, statuses: []int{429, 200}, expectedAttempts: 2, expectedStatus: 200},
+		{description: "failed read", method: http.MethodGet, statuses: []int{503, 500, 200}, expectedAttempts: 3, expectedStatus: 200},
+		{description: "failed write", method: http.MethodPost, statuses: []int{500, 200}, expectedAttempts: 1, expectedStatus: 500},
+		{description: "client error", method: http.MethodGet, statuses: []int{404, 200}, expectedAttempts: 1, expectedStatus: 404},
+		{description: "out of retries", method: http.MethodGet, statuses: []int{429, 429, 429, 429, 200}, expectedAttempts: 4, expectedStatus: 429},
+	}
+
+	for _, tc := range testCases {
+		attempts := 0
+		next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil {
+				body, _ := io.ReadAll(req.Body)
+				if string(body) != "payload" {
+					t.Fatalf("%s: expected the request body to be sent on every attempt but got %q", tc.description, body)
+				}
+			}
+			status := tc.statuses[attempts]
+			attempts++
+			return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+		})
+		transport := newRetryTransport(next, 3)
+		var delays []time.Duration
+		transport.wait = func(ctx context.Context, delay time.Duration) error {
+			delays = append(delays, delay)
+			return nil
+		}
+
+		var body io.Reader
+		if tc.method != http.MethodGet {
+			body = bytes.NewReader([]byte("payload"))
+		}
+		req, err := http.NewRequest(tc.method, "https://kubernetes.default.svc/api/v1/pods", body)
+		if err != nil {
+			t.Fatalf("%s: failed to create request: %s", tc.description, err)
+		}
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.description, err)
+		}
+		if resp.StatusCode != tc.expectedStatus {
+			t.Fatalf("%s: expected status %d but got %d", tc.description, tc.expectedStatus, resp.StatusCode)
+		}
+		if attempts != tc.expectedAttempts {
+			t.Fatalf("%s: expected %d attempts but got %d", tc.description, tc.expectedAttempts, attempts)
+		}
+		if len(delays) != attempts-1 {
+			t.Fatalf("%s: expected to wait before each of the %d retries but waited %d times", tc.description, attempts-1, len(delays))
+		}
+	}
+}
+
+// TestRetryDelay ensures that retry delays grow with each attempt and respect the Retry-After header
+func TestRetryDelay(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	for attempt := 0; attempt < 5; attempt++ {
+		delay := retryDelay(attempt, resp)
+		max := retryBaseDelay << uint(attempt)
+		if delay < max/2 || delay > max {
+			t.Fatalf("expected the delay of attempt %d to be between %s and %s but got %s", attempt, max/2, max, delay)
+		}
+	}
+
+	resp.Header.Set("Retry-After", "5")
+	if delay := retryDelay(0, resp); delay != time.Second*5 {
+		t.Fatalf("expected the Retry-After header to set the delay to 5s but got %s", delay)
+	}
+	resp.Header.Set("Retry-After", "3600")
+	if delay := retryDelay(0, resp); delay != retryMaxDelay {
+		t.Fatalf("expected the delay to be capped at %s but got %s", retryMaxDelay, delay)
+	}
+}
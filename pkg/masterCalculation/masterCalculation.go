@@ -1,15 +1,18 @@
 // Package masterCalculation determines the master pod in multi pod
-// kuberhealthy deployments
+// kuberhealthy deployments by using a coordination.k8s.io Lease for
+// leader election.
 package masterCalculation // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/masterCalculation"
 
 import (
 	"context"
 	"errors"
 	"os"
-	"sort"
 	"strings"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	// blank insert is for handling reverse proxy authN via oidc protocol
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -18,14 +21,40 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// DefaultLeaseName is the name of the Lease resource used for master election when none is configured
+const DefaultLeaseName = "kuberhealthy-master"
+
+// DefaultLeaseDuration is the duration that non-master pods will wait before attempting to take over the lease
+const DefaultLeaseDuration = time.Second * 15
+
+// DefaultRenewDeadline is the duration that the master will retry refreshing the lease before giving it up
+const DefaultRenewDeadline = time.Second * 10
+
+// DefaultRetryPeriod is the duration pods wait between attempts to acquire or renew the lease
+const DefaultRetryPeriod = time.Second * 2
+
 var namespace = os.Getenv("POD_NAMESPACE")
 var enableForceMaster bool // indicates we should always report as master for debugging
+var leaseName = DefaultLeaseName
+
+// LeaseConfig holds the settings used when electing a master with a Lease
+type LeaseConfig struct {
+	LeaseName     string        // the name of the Lease resource in the kuberhealthy namespace
+	LeaseDuration time.Duration // how long a lease is valid before other pods may take it over
+	RenewDeadline time.Duration // how long the master retries renewing the lease before giving it up
+	RetryPeriod   time.Duration // how long pods wait between lease acquisition and renew attempts
+}
 
 // DebugAlwaysMasterOn makes all master queries return true without logic
 func DebugAlwaysMasterOn() {
 	enableForceMaster = true
 }
 
+// IsForcedMaster indicates if master responsibilities have been forced on for debugging
+func IsForcedMaster() bool {
+	return enableForceMaster
+}
+
 // EnableDebug enables debug logging
 func EnableDebug() {
 	log.SetLevel(log.DebugLevel)
@@ -42,35 +71,108 @@ func getEnvVar(v string) (string, error) {
 	return envVar, err
 }
 
-// CalculateMaster determines which kuberhealthy pod should assume the master role
+// withDefaults returns a copy of the LeaseConfig with any unset values filled in with defaults
+func (lc LeaseConfig) withDefaults() LeaseConfig {
+	if len(lc.LeaseName) == 0 {
+		lc.LeaseName = DefaultLeaseName
+	}
+	if lc.LeaseDuration == 0 {
+		lc.LeaseDuration = DefaultLeaseDuration
+	}
+	if lc.RenewDeadline == 0 {
+		lc.RenewDeadline = DefaultRenewDeadline
+	}
+	if lc.RetryPeriod == 0 {
+		lc.RetryPeriod = DefaultRetryPeriod
+	}
+	return lc
+}
+
+// RunLeaseElection participates in master election using a Lease until the supplied context is canceled.
+// becameMaster is called when this pod acquires the lease and lostMaster is called when it stops holding
+// the lease.  When the context is canceled, the lease is released so that another pod can take over
+// right away instead of waiting for the lease to expire.
+func RunLeaseElection(ctx context.Context, client *kubernetes.Clientset, config LeaseConfig, becameMaster func(), lostMaster func()) error {
+
+	config = config.withDefaults()
+	leaseName = config.LeaseName
+
+	// get name of the pod running this check from an environment variable we set
+	// in the pod spec
+	myPod, err := getEnvVar("POD_NAME")
+	if err != nil {
+		return err
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      config.LeaseName,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: myPod,
+		},
+	}
+
+	electionConfig := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   config.LeaseDuration,
+		RenewDeadline:   config.RenewDeadline,
+		RetryPeriod:     config.RetryPeriod,
+		Name:            config.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infoln("masterCalculation:", myPod, "acquired lease", config.LeaseName)
+				becameMaster()
+			},
+			OnStoppedLeading: func() {
+				log.Infoln("masterCalculation:", myPod, "no longer holds lease", config.LeaseName)
+				lostMaster()
+			},
+			OnNewLeader: func(identity string) {
+				log.Infoln("masterCalculation: current master is", identity)
+			},
+		},
+	}
+
+	// continue taking part in the election until our context is canceled. Run returns each time
+	// leadership is lost, so we rejoin the election with a fresh elector after that happens.
+	for {
+		elector, err := leaderelection.NewLeaderElector(electionConfig)
+		if err != nil {
+			return err
+		}
+
+		log.Debugln("masterCalculation: joining master election for lease", config.LeaseName, "as", myPod)
+		elector.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Debugln("masterCalculation: leaving master election due to context cancellation")
+			return nil
+		default:
+		}
+	}
+}
+
+// CalculateMaster determines which kuberhealthy pod currently holds the master lease
 func CalculateMaster(client *kubernetes.Clientset) (string, error) {
 	// TODO: refactor function to receive context on exported function in next breaking change.
 	ctx := context.TODO()
 
 	log.Debugln("Calculating current master...")
 
-	// get a list of all kuberhealthy pods
-	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: "app=kuberhealthy", FieldSelector: "status.phase=Running",
-	})
+	lease, err := client.CoordinationV1().Leases(namespace).Get(ctx, leaseName, metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
 
-	// create a slice of all kuberhealthy pod names for use in sort
-	var podlist []string
-	for _, p := range pods.Items {
-		podlist = append(podlist, p.Name)
+	if lease.Spec.HolderIdentity == nil || len(*lease.Spec.HolderIdentity) == 0 {
+		return "", errors.New("Master lease " + leaseName + " is not currently held by any Kuberhealthy pod")
 	}
-
-	if len(podlist) < 1 {
-		return "", errors.New("Failed to retrieve list of Kuberhealthy pods")
-	}
-
-	// choose master by grabbing the first in alphabetical order based on
-	// the pod name
-	sort.Strings(podlist)
-	master := podlist[0]
+	master := *lease.Spec.HolderIdentity
 
 	log.Debugln("Calculated master as", master)
 	return master, err
@@ -97,7 +199,7 @@ func IAmMaster(client *kubernetes.Clientset) (bool, error) {
 		log.Errorln(err)
 	}
 
-	// if our pod name matches the calculated master pod name, we are the master
+	// if our pod name matches the current lease holder, we are the master
 	if strings.ToLower(myPod) == strings.ToLower(master) {
 		log.Debugln("I am master")
 		return true, err
@@ -3,6 +3,7 @@ package masterCalculation
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/kubeClient"
 	log "github.com/sirupsen/logrus"
@@ -23,3 +24,20 @@ func TestRun(t *testing.T) {
 	}
 	t.Log(master)
 }
+
+func TestLeaseConfigDefaults(t *testing.T) {
+	lc := LeaseConfig{LeaseDuration: time.Second * 30}.withDefaults()
+
+	if lc.LeaseName != DefaultLeaseName {
+		t.Fatal("Expected lease name to default to", DefaultLeaseName, "but got", lc.LeaseName)
+	}
+	if lc.LeaseDuration != time.Second*30 {
+		t.Fatal("Expected configured lease duration to be kept but got", lc.LeaseDuration)
+	}
+	if lc.RenewDeadline != DefaultRenewDeadline {
+		t.Fatal("Expected renew deadline to default to", DefaultRenewDeadline, "but got", lc.RenewDeadline)
+	}
+	if lc.RetryPeriod != DefaultRetryPeriod {
+		t.Fatal("Expected retry period to default to", DefaultRetryPeriod, "but got", lc.RetryPeriod)
+	}
+}
@@ -0,0 +1,165 @@
+package masterCalculation
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultMemberLeaseDuration is how long a pod stays a shard member after it last renewed its membership lease
+const DefaultMemberLeaseDuration = time.Second * 30
+
+// DefaultMemberRenewInterval is how often pods renew their membership lease and look for membership changes
+const DefaultMemberRenewInterval = time.Second * 10
+
+// ShardGroupLabel is the label on membership leases that holds the name of the group the lease is a member of
+const ShardGroupLabel = "kuberhealthy-shard-group"
+
+// MembershipConfig holds the settings used to keep track of the pods that share the checks
+type MembershipConfig struct {
+	Group         string        // the name of the group of pods that share the checks. membership leases are named after it
+	LeaseDuration time.Duration // how long a pod stays a member after it last renewed its membership lease
+	RenewInterval time.Duration // how often pods renew their membership lease and look for membership changes
+}
+
+// withDefaults returns a copy of the MembershipConfig with any unset values filled in with defaults
+func (mc MembershipConfig) withDefaults() MembershipConfig {
+	if len(mc.Group) == 0 {
+		mc.Group = DefaultLeaseName
+	}
+	if mc.LeaseDuration == 0 {
+		mc.LeaseDuration = DefaultMemberLeaseDuration
+	}
+	if mc.RenewInterval == 0 {
+		mc.RenewInterval = DefaultMemberRenewInterval
+	}
+	return mc
+}
+
+// RunShardMembership holds a membership lease for this pod until the supplied context is canceled, and calls
+// membersChanged with the sorted names of the member pods each time they change.  Each pod holds a lease of its
+// own, which it renews on an interval.  Pods whose lease was not renewed within the lease duration are no longer
+// members.  When the context is canceled, the lease is deleted so that the other pods see this pod leave right away.
+func RunShardMembership(ctx context.Context, client *kubernetes.Clientset, config MembershipConfig, membersChanged func(members []string)) error {
+
+	config = config.withDefaults()
+
+	// get name of the pod running this check from an environment variable we set
+	// in the pod spec
+	myPod, err := getEnvVar("POD_NAME")
+	if err != nil {
+		return err
+	}
+	leases := client.CoordinationV1().Leases(namespace)
+	memberLeaseName := config.Group + "-" + myPod
+
+	// delete our lease when we leave, so that our checks are taken over without waiting for the lease to expire
+	defer func() {
+		deleteCtx, cancel := context.WithTimeout(context.Background(), config.RenewInterval)
+		defer cancel()
+		err := leases.Delete(deleteCtx, memberLeaseName, metav1.DeleteOptions{})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			log.Errorln("masterCalculation: error deleting membership lease", memberLeaseName+":", err)
+		}
+	}()
+
+	ticker := time.NewTicker(config.RenewInterval)
+	defer ticker.Stop()
+
+	var members []string
+	for {
+		err := renewMemberLease(ctx, client, memberLeaseName, myPod, config)
+		if err != nil {
+			log.Errorln("masterCalculation: error renewing membership lease", memberLeaseName+":", err)
+		}
+
+		leaseList, err := leases.List(ctx, metav1.ListOptions{LabelSelector: ShardGroupLabel + "=" + config.Group})
+		if err != nil {
+			log.Errorln("masterCalculation: error listing membership leases:", err)
+		} else if current := liveMembers(leaseList.Items, time.Now()); !sameMembers(members, current) {
+			log.Infoln("masterCalculation: shard members are now", current)
+			members = current
+			membersChanged(members)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debugln("masterCalculation: leaving shard membership due to context cancellation")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewMemberLease creates or renews the membership lease of this pod
+func renewMemberLease(ctx context.Context, client *kubernetes.Clientset, name string, myPod string, config MembershipConfig) error {
+	leases := client.CoordinationV1().Leases(namespace)
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(config.LeaseDuration.Seconds())
+
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{ShardGroupLabel: config.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &myPod,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &myPod
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// liveMembers returns the sorted holders of the supplied membership leases that were renewed within their lease
+// duration
+func liveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	members := []string{}
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || len(*spec.HolderIdentity) == 0 || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expires := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expires) {
+			continue
+		}
+		members = append(members, *spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// sameMembers indicates if two sorted lists of members are the same.  A nil list is members that were not reported
+// yet, which are never the same.
+func sameMembers(a []string, b []string) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
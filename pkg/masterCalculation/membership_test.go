@@ -0,0 +1,34 @@
+package masterCalculation
+
+import (
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestLiveMembers ensures that only the holders of membership leases renewed within their lease duration are members
+func TestLiveMembers(t *testing.T) {
+	now := time.Now()
+	newLease := func(holder string, renewed time.Time) coordinationv1.Lease {
+		durationSeconds := int32(30)
+		renewTime := metav1.NewMicroTime(renewed)
+		return coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &durationSeconds, RenewTime: &renewTime}}
+	}
+
+	leases := []coordinationv1.Lease{
+		newLease("kuberhealthy-c", now.Add(-time.Second*5)),
+		newLease("kuberhealthy-a", now.Add(-time.Second*29)),
+		newLease("kuberhealthy-b", now.Add(-time.Minute)),
+		{},
+	}
+	members := liveMembers(leases, now)
+	if !sameMembers(members, []string{"kuberhealthy-a", "kuberhealthy-c"}) {
+		t.Fatalf("expected the members with live leases in sorted order but got %v", members)
+	}
+
+	if sameMembers(nil, []string{}) {
+		t.Fatalf("expected members that were not reported yet to differ from no members")
+	}
+}
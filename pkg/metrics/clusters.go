@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
+)
+
+// ClusterStatus is the last known status of a single cluster in federated metrics
+type ClusterStatus struct {
+	Up    bool          // indicates if the status of the cluster could be fetched the last time it was tried
+	State *health.State // the last known state of the cluster, or nil if it was never fetched
+}
+
+// GenerateClusterMetrics returns the metrics of the states of several clusters in the Prometheus format, with a
+// cluster label on every sample
+func GenerateClusterMetrics(clusters map[string]ClusterStatus, config PromMetricsConfig) string {
+	return generateClusterMetrics(clusters, config)
+}
+
+// GenerateClusterOpenMetrics returns the metrics of the states of several clusters in the OpenMetrics format, with a
+// cluster label on every sample
+func GenerateClusterOpenMetrics(clusters map[string]ClusterStatus, config PromMetricsConfig) string {
+	return generateClusterMetrics(clusters, config) + "# EOF\n"
+}
+
+// generateClusterMetrics returns the metrics of the states of several clusters.  Only the metrics that are derived
+// from a state are included, because the counters and histograms of remote instances are not part of their status.
+// Every metric is a gauge, so the output is the same in the Prometheus and OpenMetrics text formats.
+func generateClusterMetrics(clusters map[string]ClusterStatus, config PromMetricsConfig) string {
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	up := make(map[string]string)
+	merged := stateSamples{
+		running:                  make(map[string]string),
+		clusterState:             make(map[string]string),
+		checkState:               make(map[string]string),
+		checkLastRun:             make(map[string]string),
+		checkConsecutiveFailures: make(map[string]string),
+		jobState:                 make(map[string]string),
+		jobDuration:              make(map[string]string),
+	}
+	for _, name := range names {
+		cluster := clusters[name]
+		upStatus := "0"
+		if cluster.Up {
+			upStatus = "1"
+		}
+		up[withLabel("kuberhealthy_federation_cluster_up", "cluster", name)] = upStatus
+		if cluster.State == nil {
+			continue
+		}
+
+		samples := newStateSamples(*cluster.State, config)
+		environment := cluster.State.Environment
+		mergeClusterSamples(merged.running, samples.running, name, environment)
+		mergeClusterSamples(merged.clusterState, samples.clusterState, name, environment)
+		mergeClusterSamples(merged.checkState, samples.checkState, name, environment)
+		mergeClusterSamples(merged.checkLastRun, samples.checkLastRun, name, environment)
+		mergeClusterSamples(merged.checkConsecutiveFailures, samples.checkConsecutiveFailures, name, environment)
+		mergeClusterSamples(merged.jobState, samples.jobState, name, environment)
+		mergeClusterSamples(merged.jobDuration, samples.jobDuration, name, environment)
+	}
+
+	metricsOutput := ""
+	metricsOutput += "# HELP kuberhealthy_federation_cluster_up Shows if the status of a federated cluster could be fetched\n"
+	metricsOutput += "# TYPE kuberhealthy_federation_cluster_up gauge\n"
+	metricsOutput += formatSamples(up)
+	metricsOutput += "# HELP kuberhealthy_running Shows if kuberhealthy is running error free\n"
+	metricsOutput += "# TYPE kuberhealthy_running gauge\n"
+	metricsOutput += formatSamples(merged.running)
+	metricsOutput += "# HELP kuberhealthy_cluster_state Shows the status of the cluster\n"
+	metricsOutput += "# TYPE kuberhealthy_cluster_state gauge\n"
+	metricsOutput += formatSamples(merged.clusterState)
+	metricsOutput += "# HELP kuberhealthy_check Shows the status of a Kuberhealthy check\n"
+	metricsOutput += "# TYPE kuberhealthy_check gauge\n"
+	metricsOutput += formatSamples(merged.checkState)
+	metricsOutput += "# HELP kuberhealthy_check_last_run_timestamp_seconds Shows the time of the last run of a Kuberhealthy check as a unix timestamp\n"
+	metricsOutput += "# TYPE kuberhealthy_check_last_run_timestamp_seconds gauge\n"
+	metricsOutput += formatSamples(merged.checkLastRun)
+	metricsOutput += "# HELP kuberhealthy_check_consecutive_failures Shows the number of failed runs in a row of a Kuberhealthy check\n"
+	metricsOutput += "# TYPE kuberhealthy_check_consecutive_failures gauge\n"
+	metricsOutput += formatSamples(merged.checkConsecutiveFailures)
+	metricsOutput += "# HELP kuberhealthy_job Shows the status of a Kuberhealthy job\n"
+	metricsOutput += "# TYPE kuberhealthy_job gauge\n"
+	metricsOutput += formatSamples(merged.jobState)
+	metricsOutput += "# HELP kuberhealthy_job_duration_seconds Shows the job run duration of a Kuberhealthy job\n"
+	metricsOutput += "# TYPE kuberhealthy_job_duration_seconds gauge\n"
+	metricsOutput += formatSamples(merged.jobDuration)
+	return metricsOutput
+}
+
+// mergeClusterSamples adds the samples of a cluster to the merged samples with a cluster label, and an environment
+// label when the cluster reports an environment
+func mergeClusterSamples(merged map[string]string, samples map[string]string, cluster string, environment string) {
+	for name, value := range samples {
+		if len(environment) > 0 {
+			name = withLabel(name, "environment", environment)
+		}
+		merged[withLabel(name, "cluster", cluster)] = value
+	}
+}
+
+// withIdentityLabels adds the cluster and environment labels of a state to every sample of a metrics exposition.
+// Labels are only added when the state has a cluster name or environment configured.
+func withIdentityLabels(exposition string, state health.State) string {
+	if len(state.ClusterName) == 0 && len(state.Environment) == 0 {
+		return exposition
+	}
+
+	lines := strings.SplitAfter(exposition, "\n")
+	for i, line := range lines {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// the metric name ends at the first brace or space, and labels are added right after the opening brace
+		end := strings.IndexAny(line, "{ ")
+		if end < 0 {
+			continue
+		}
+		sample, rest := line, ""
+		if line[end] == ' ' {
+			sample, rest = line[:end], line[end:]
+		}
+		if len(state.Environment) > 0 {
+			sample = withLabel(sample, "environment", state.Environment)
+		}
+		if len(state.ClusterName) > 0 {
+			sample = withLabel(sample, "cluster", state.ClusterName)
+		}
+		lines[i] = sample + rest
+	}
+	return strings.Join(lines, "")
+}
+
+// withLabel adds a label in front of the other labels of a sample name
+func withLabel(sample string, name string, value string) string {
+	label := fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(value))
+	i := strings.Index(sample, "{")
+	if i < 0 {
+		return sample + "{" + label + "}"
+	}
+	if strings.HasPrefix(sample[i+1:], "}") {
+		return sample[:i+1] + label + sample[i+1:]
+	}
+	return sample[:i+1] + label + "," + sample[i+1:]
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CheckerPodsRunning is the number of checker pods of checks and jobs that are running in each namespace.  It is
+// included in the output of GenerateMetrics.
+var CheckerPodsRunning = NewGauge("kuberhealthy_checker_pods_running", "Shows the number of checker pods of Kuberhealthy checks and jobs that are running", "namespace")
+
+// ReaperPodsDeleted is the number of completed checker pods deleted by the reaper, by namespace and pod phase.  It
+// is included in the output of GenerateMetrics.
+var ReaperPodsDeleted = NewCounter("kuberhealthy_reaper_pods_deleted", "Shows the number of completed checker pods deleted by the Kuberhealthy reaper", "namespace", "phase")
+
+// ReaperKHJobsDeleted is the number of finished khjobs deleted by the reaper, by namespace and job phase.  It is
+// included in the output of GenerateMetrics.
+var ReaperKHJobsDeleted = NewCounter("kuberhealthy_reaper_khjobs_deleted", "Shows the number of finished khjobs deleted by the Kuberhealthy reaper", "namespace", "phase")
+
+// ReaperDryRunDeletions is the number of checker pods and khjobs the reaper would have deleted if it was not in dry
+// run mode, by namespace and kind.  It is included in the output of GenerateMetrics.
+var ReaperDryRunDeletions = NewCounter("kuberhealthy_reaper_dry_run_deletions", "Shows the number of checker pods and khjobs the Kuberhealthy reaper would have deleted if dry run was disabled", "namespace", "kind")
+
+// ImagePullFailures is the number of runs of each check that failed because a checker pod could not pull its
+// image, by check and namespace.  It is included in the output of GenerateMetrics.
+var ImagePullFailures = NewCounter("kuberhealthy_check_image_pull_failures", "Shows the number of Kuberhealthy check runs that failed because a checker pod could not pull its image", "check", "namespace")
+
+// labeledValues holds the values of a metric for each set of label values.  It is safe for concurrent use.
+type labeledValues struct {
+	name       string
+	help       string
+	labelNames []string
+	values     map[string]float64 // values keyed by their label values joined with labelValueSeparator
+	sync.Mutex
+}
+
+// labelValueSeparator joins label values into a key.  It can not appear in a valid label value.
+const labelValueSeparator = "\xff"
+
+// key joins the supplied label values into the key of their series
+func (l *labeledValues) key(labelValues []string) string {
+	if len(labelValues) != len(l.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels but %d label values were supplied", l.name, len(l.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelValueSeparator)
+}
+
+// format writes the series of the metric in the Prometheus or OpenMetrics text format.  The samples are named
+// name+suffix, which lets counters add the _total suffix to their samples.
+func (l *labeledValues) format(metricType string, family string, suffix string) string {
+	l.Lock()
+	defer l.Unlock()
+
+	output := fmt.Sprintf("# HELP %s %s\n", family, l.help)
+	output += fmt.Sprintf("# TYPE %s %s\n", family, metricType)
+
+	// sort the series so that output is stable between scrapes
+	keys := make([]string, 0, len(l.values))
+	for key := range l.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var labels []string
+		if len(l.labelNames) > 0 {
+			for i, value := range strings.Split(key, labelValueSeparator) {
+				labels = append(labels, fmt.Sprintf("%s=\"%s\"", l.labelNames[i], escapeLabelValue(value)))
+			}
+		}
+		labelString := ""
+		if len(labels) > 0 {
+			labelString = "{" + strings.Join(labels, ",") + "}"
+		}
+		output += fmt.Sprintf("%s%s%s %s\n", l.name, suffix, labelString, strconv.FormatFloat(l.values[key], 'f', -1, 64))
+	}
+	return output
+}
+
+// Counter is a Prometheus counter with a fixed set of labels.  The name of a counter is given without the _total
+// suffix of its samples.
+type Counter struct {
+	labeledValues
+}
+
+// NewCounter creates a Counter with the supplied name, help text and label names
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	return &Counter{labeledValues{name: name, help: help, labelNames: labelNames, values: make(map[string]float64)}}
+}
+
+// Inc adds one to the series with the supplied label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the supplied value to the series with the supplied label values.  Negative values are ignored because
+// counters only go up.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.Lock()
+	defer c.Unlock()
+	c.values[key] += v
+}
+
+// Format returns the counter in the OpenMetrics text format, or in the Prometheus text format.  OpenMetrics names
+// the metric family without the _total suffix, while the Prometheus text format names it after its samples.
+func (c *Counter) Format(openMetrics bool) string {
+	if openMetrics {
+		return c.format("counter", c.name, "_total")
+	}
+	return c.format("counter", c.name+"_total", "_total")
+}
+
+// Gauge is a Prometheus gauge with a fixed set of labels
+type Gauge struct {
+	labeledValues
+}
+
+// NewGauge creates a Gauge with the supplied name, help text and label names
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return &Gauge{labeledValues{name: name, help: help, labelNames: labelNames, values: make(map[string]float64)}}
+}
+
+// Set sets the series with the supplied label values to the supplied value
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.Lock()
+	defer g.Unlock()
+	g.values[key] = v
+}
+
+// Delete removes the series with the supplied label values, so that it is no longer exported
+func (g *Gauge) Delete(labelValues ...string) {
+	key := g.key(labelValues)
+	g.Lock()
+	defer g.Unlock()
+	delete(g.values, key)
+}
+
+// Format returns the gauge in the Prometheus or OpenMetrics text format, which are the same for gauges
+func (g *Gauge) Format(openMetrics bool) string {
+	return g.format("gauge", g.name, "")
+}
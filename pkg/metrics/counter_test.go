@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterFormat(t *testing.T) {
+	c := NewCounter("kuberhealthy_test_deleted", "Shows test deletions", "namespace", "phase")
+	c.Inc("kuberhealthy", "Succeeded")
+	c.Inc("kuberhealthy", "Succeeded")
+	c.Add(3, "default", "Failed")
+	c.Add(-1, "default", "Failed")
+
+	expected := `# HELP kuberhealthy_test_deleted_total Shows test deletions
+# TYPE kuberhealthy_test_deleted_total counter
+kuberhealthy_test_deleted_total{namespace="default",phase="Failed"} 3
+kuberhealthy_test_deleted_total{namespace="kuberhealthy",phase="Succeeded"} 2
+`
+	if c.Format(false) != expected {
+		t.Fatalf("Unexpected Prometheus counter output:\n%s", c.Format(false))
+	}
+
+	// OpenMetrics names the family without the _total suffix of its samples
+	openMetrics := c.Format(true)
+	if !strings.Contains(openMetrics, "# TYPE kuberhealthy_test_deleted counter\n") || !strings.Contains(openMetrics, "kuberhealthy_test_deleted_total{namespace=\"default\",phase=\"Failed\"} 3\n") {
+		t.Fatalf("Unexpected OpenMetrics counter output:\n%s", openMetrics)
+	}
+}
+
+func TestGaugeFormat(t *testing.T) {
+	g := NewGauge("kuberhealthy_test_running", "Shows test runs", "namespace")
+	g.Set(2, "kuberhealthy")
+	g.Set(1, "default")
+	g.Delete("default")
+	g.Set(1, `quoted "namespace"`)
+
+	expected := `# HELP kuberhealthy_test_running Shows test runs
+# TYPE kuberhealthy_test_running gauge
+kuberhealthy_test_running{namespace="kuberhealthy"} 2
+kuberhealthy_test_running{namespace="quoted \"namespace\""} 1
+`
+	if g.Format(true) != expected {
+		t.Fatalf("Unexpected gauge output:\n%s", g.Format(true))
+	}
+}
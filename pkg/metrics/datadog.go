@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultDatadogSite is the Datadog site that results are sent to when none is configured
+const defaultDatadogSite = "datadoghq.com"
+
+// defaultDatadogMetricPrefix is the prefix of the names of Datadog metrics when none is configured
+const defaultDatadogMetricPrefix = "kuberhealthy"
+
+// datadogTimeout is how long a single request to the Datadog API or write to DogStatsD may take
+const datadogTimeout = time.Second * 10
+
+// datadogSourceType is the source type name of the events sent to Datadog
+const datadogSourceType = "kuberhealthy"
+
+// DatadogConfig holds the settings for sending check results to Datadog
+type DatadogConfig struct {
+	Enabled          bool     `yaml:"enabled"`                    // set to true to send the result of every check and job run to Datadog
+	DogStatsDAddress string   `yaml:"dogStatsDAddress,omitempty"` // a DogStatsD agent to send results to, such as 127.0.0.1:8125 or unix:///var/run/datadog/dsd.socket. when blank, results are sent to the Datadog API
+	Site             string   `yaml:"site,omitempty"`             // the Datadog site of the API, such as datadoghq.eu. Defaults to datadoghq.com
+	APIURL           string   `yaml:"apiURL,omitempty"`           // overrides the URL of the Datadog API, such as a proxy in front of it
+	APIKeyFile       string   `yaml:"apiKeyFile,omitempty"`       // a file holding the Datadog API key, usually mounted from a secret
+	APIKey           string   `yaml:"apiKey,omitempty"`           // the Datadog API key. apiKeyFile should be preferred so that the key is not stored in the configmap
+	MetricPrefix     string   `yaml:"metricPrefix,omitempty"`     // the prefix of metric names. Defaults to kuberhealthy
+	Tags             []string `yaml:"tags,omitempty"`             // tags added to every metric and event, such as env:prod
+	DisableEvents    bool     `yaml:"disableEvents,omitempty"`    // set to true to only send metrics
+}
+
+// DatadogExporter sends the result of every check run to Datadog as gauge metrics of its status and duration, and
+// as an event.  Results are sent to a DogStatsD agent when one is configured, and otherwise to the Datadog API.
+type DatadogExporter struct {
+	config DatadogConfig
+	client *http.Client
+}
+
+// datadogSeries is a single metric sent to the Datadog series API
+type datadogSeries struct {
+	Metric string       `json:"metric"`
+	Points [][2]float64 `json:"points"`
+	Type   string       `json:"type"`
+	Tags   []string     `json:"tags"`
+}
+
+// datadogEvent is a single event sent to the Datadog events API
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key"`
+	SourceTypeName string   `json:"source_type_name"`
+	DateHappened   int64    `json:"date_happened"`
+	Tags           []string `json:"tags"`
+}
+
+// NewDatadogExporter creates a DatadogExporter from the supplied configuration.  An error is returned if neither a
+// DogStatsD address nor an API key is configured.
+func NewDatadogExporter(config DatadogConfig) (*DatadogExporter, error) {
+	if len(config.DogStatsDAddress) == 0 && len(config.APIKeyFile) == 0 && len(config.APIKey) == 0 {
+		return nil, fmt.Errorf("datadog requires either a dogStatsDAddress or an apiKey or apiKeyFile")
+	}
+	return &DatadogExporter{
+		config: config,
+		client: &http.Client{Timeout: datadogTimeout},
+	}, nil
+}
+
+// Name returns the name of this exporter
+func (d *DatadogExporter) Name() string {
+	return "datadog"
+}
+
+// Export sends the supplied run result to Datadog
+func (d *DatadogExporter) Export(r RunResult) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if len(d.config.DogStatsDAddress) > 0 {
+		return d.exportDogStatsD(r)
+	}
+	return d.exportAPI(r)
+}
+
+// exportAPI sends the run result to the series and events endpoints of the Datadog API
+func (d *DatadogExporter) exportAPI(r RunResult) error {
+	apiKey, err := d.apiKey()
+	if err != nil {
+		return err
+	}
+
+	tags := d.tags(r)
+	timestamp := float64(r.Time.Unix())
+	series := map[string][]datadogSeries{
+		"series": {
+			{Metric: d.metricName("check.status"), Points: [][2]float64{{timestamp, statusValue(r.OK)}}, Type: "gauge", Tags: tags},
+			{Metric: d.metricName("check.duration_seconds"), Points: [][2]float64{{timestamp, r.RunDuration.Seconds()}}, Type: "gauge", Tags: tags},
+		},
+	}
+	err = d.post("/api/v1/series", series, apiKey)
+	if err != nil {
+		return err
+	}
+
+	if d.config.DisableEvents {
+		return nil
+	}
+	event := datadogEvent{
+		Title:          datadogEventTitle(r),
+		Text:           strings.Join(r.Errors, "\n"),
+		AlertType:      datadogAlertType(r.OK),
+		AggregationKey: r.Namespace + "/" + r.Name,
+		SourceTypeName: datadogSourceType,
+		DateHappened:   r.Time.Unix(),
+		Tags:           tags,
+	}
+	return d.post("/api/v1/events", event, apiKey)
+}
+
+// post sends the supplied payload to a path of the Datadog API
+func (d *DatadogExporter) post(path string, payload interface{}, apiKey string) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal datadog payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.apiURL()+path, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create datadog request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send results to datadog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("datadog api %s returned status code %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// exportDogStatsD sends the run result to the DogStatsD agent.  Each metric and event is written as its own
+// datagram.
+func (d *DatadogExporter) exportDogStatsD(r RunResult) error {
+	network, address := "udp", d.config.DogStatsDAddress
+	if strings.HasPrefix(address, "unix://") {
+		network, address = "unixgram", strings.TrimPrefix(address, "unix://")
+	}
+	conn, err := net.DialTimeout(network, address, datadogTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to dogstatsd at %s: %w", d.config.DogStatsDAddress, err)
+	}
+	defer conn.Close()
+	err = conn.SetWriteDeadline(time.Now().Add(datadogTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to set dogstatsd write deadline: %w", err)
+	}
+
+	tags := "|#" + strings.Join(d.tags(r), ",")
+	datagrams := []string{
+		fmt.Sprintf("%s:%g|g%s", d.metricName("check.status"), statusValue(r.OK), tags),
+		fmt.Sprintf("%s:%g|g%s", d.metricName("check.duration_seconds"), r.RunDuration.Seconds(), tags),
+	}
+	if !d.config.DisableEvents {
+		title := datadogEventTitle(r)
+		text := strings.ReplaceAll(strings.Join(r.Errors, "\n"), "\n", "\\n")
+		datagrams = append(datagrams, fmt.Sprintf("_e{%d,%d}:%s|%s|d:%d|k:%s|s:%s|t:%s%s",
+			len(title), len(text), title, text, r.Time.Unix(), r.Namespace+"/"+r.Name, datadogSourceType, datadogAlertType(r.OK), tags))
+	}
+
+	for _, datagram := range datagrams {
+		_, err = conn.Write([]byte(datagram))
+		if err != nil {
+			return fmt.Errorf("failed to write to dogstatsd at %s: %w", d.config.DogStatsDAddress, err)
+		}
+	}
+	return nil
+}
+
+// tags returns the tags of the metrics and events of a run result.  Every result is tagged with the name and
+// namespace of the check and its workload type, along with the cluster and environment when configured, the labels
+// of the khcheck and the configured tags.
+func (d *DatadogExporter) tags(r RunResult) []string {
+	tags := []string{
+		"check:" + datadogTagValue(r.Name),
+		"namespace:" + datadogTagValue(r.Namespace),
+		"workload:" + datadogTagValue(r.Workload),
+	}
+	if !r.OK && len(r.FailureReason) > 0 {
+		tags = append(tags, "failure_reason:"+datadogTagValue(r.FailureReason))
+	}
+	if len(r.Node) > 0 {
+		tags = append(tags, "node:"+datadogTagValue(r.Node))
+	}
+	if len(r.Cluster) > 0 {
+		tags = append(tags, "cluster:"+datadogTagValue(r.Cluster))
+	}
+	if len(r.Environment) > 0 {
+		tags = append(tags, "env:"+datadogTagValue(r.Environment))
+	}
+
+	// sort the labels so that the tags of every result of a check are the same
+	labelTags := make([]string, 0, len(r.Labels))
+	for k, v := range r.Labels {
+		labelTags = append(labelTags, datadogTagValue(k)+":"+datadogTagValue(v))
+	}
+	sort.Strings(labelTags)
+	tags = append(tags, labelTags...)
+
+	for _, t := range d.config.Tags {
+		tags = append(tags, datadogTagValue(t))
+	}
+	return tags
+}
+
+// metricName returns the full name of a metric with the configured prefix
+func (d *DatadogExporter) metricName(name string) string {
+	prefix := d.config.MetricPrefix
+	if len(prefix) == 0 {
+		prefix = defaultDatadogMetricPrefix
+	}
+	return strings.TrimSuffix(prefix, ".") + "." + name
+}
+
+// apiURL returns the base URL of the Datadog API
+func (d *DatadogExporter) apiURL() string {
+	if len(d.config.APIURL) > 0 {
+		return strings.TrimSuffix(d.config.APIURL, "/")
+	}
+	site := d.config.Site
+	if len(site) == 0 {
+		site = defaultDatadogSite
+	}
+	return "https://api." + site
+}
+
+// apiKey returns the configured API key.  The key file is read on each call so that a rotated secret is picked up
+// without restarting Kuberhealthy.
+func (d *DatadogExporter) apiKey() (string, error) {
+	if len(d.config.APIKeyFile) == 0 {
+		return d.config.APIKey, nil
+	}
+	b, err := os.ReadFile(d.config.APIKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read datadog api key file %s: %w", d.config.APIKeyFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// datadogEventTitle returns the title of the event for a run result
+func datadogEventTitle(r RunResult) string {
+	if r.OK {
+		return fmt.Sprintf("Kuberhealthy %s %s in namespace %s succeeded", r.Workload, r.Name, r.Namespace)
+	}
+	return fmt.Sprintf("Kuberhealthy %s %s in namespace %s failed", r.Workload, r.Name, r.Namespace)
+}
+
+// datadogAlertType returns the alert type of the event for a run result
+func datadogAlertType(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "error"
+}
+
+// datadogTagValue replaces the characters that separate tags and DogStatsD fields in a tag
+func datadogTagValue(v string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "\n", " ").Replace(v)
+}
+
+// statusValue returns the value of the status metric of a run, which is 1 when it succeeded and 0 when it failed
+func statusValue(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
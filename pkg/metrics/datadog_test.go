@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRunResult returns a failed run result used by the datadog tests
+func testRunResult() RunResult {
+	return RunResult{
+		Name:          "dns-check",
+		Namespace:     "kuberhealthy",
+		Workload:      "check",
+		OK:            false,
+		Errors:        []string{"lookup failed", "timeout, retrying"},
+		RunDuration:   time.Second * 3,
+		FailureReason: "ReportedFailure",
+		Labels:        map[string]string{"team": "platform"},
+		Time:          time.Unix(1600000000, 0),
+	}
+}
+
+func TestNewDatadogExporter(t *testing.T) {
+	_, err := NewDatadogExporter(DatadogConfig{Enabled: true})
+	if err == nil {
+		t.Fatal("expected an error without an api key or dogstatsd address")
+	}
+	_, err = NewDatadogExporter(DatadogConfig{Enabled: true, DogStatsDAddress: "127.0.0.1:8125"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestDatadogTags(t *testing.T) {
+	d := &DatadogExporter{config: DatadogConfig{Tags: []string{"env:prod"}}}
+	tags := d.tags(testRunResult())
+	expected := []string{"check:dns-check", "namespace:kuberhealthy", "workload:check", "failure_reason:ReportedFailure", "team:platform", "env:prod"}
+	if strings.Join(tags, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected tags %v, got %v", expected, tags)
+	}
+}
+
+func TestDatadogExportAPI(t *testing.T) {
+	requests := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "abc123" {
+			t.Errorf("expected api key header, got %q", r.Header.Get("DD-API-KEY"))
+		}
+		var body json.RawMessage
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Error("failed to decode request body:", err)
+		}
+		requests[r.URL.Path] = body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	d, err := NewDatadogExporter(DatadogConfig{Enabled: true, APIURL: server.URL, APIKey: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Export(testRunResult())
+	if err != nil {
+		t.Fatal("unexpected error exporting to datadog:", err)
+	}
+
+	var series map[string][]datadogSeries
+	err = json.Unmarshal(requests["/api/v1/series"], &series)
+	if err != nil {
+		t.Fatal("failed to decode series:", err)
+	}
+	if len(series["series"]) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(series["series"]))
+	}
+	if series["series"][0].Metric != "kuberhealthy.check.status" || series["series"][0].Points[0][1] != 0 {
+		t.Errorf("unexpected status series: %+v", series["series"][0])
+	}
+	if series["series"][1].Metric != "kuberhealthy.check.duration_seconds" || series["series"][1].Points[0][1] != 3 {
+		t.Errorf("unexpected duration series: %+v", series["series"][1])
+	}
+
+	var event datadogEvent
+	err = json.Unmarshal(requests["/api/v1/events"], &event)
+	if err != nil {
+		t.Fatal("failed to decode event:", err)
+	}
+	if event.AlertType != "error" || event.AggregationKey != "kuberhealthy/dns-check" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestDatadogExportAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	d, err := NewDatadogExporter(DatadogConfig{Enabled: true, APIURL: server.URL, APIKey: "bad"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Export(testRunResult())
+	if err == nil {
+		t.Fatal("expected an error when datadog rejects the request")
+	}
+}
+
+func TestDatadogExportDogStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d, err := NewDatadogExporter(DatadogConfig{Enabled: true, DogStatsDAddress: conn.LocalAddr().String(), MetricPrefix: "kh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Export(testRunResult())
+	if err != nil {
+		t.Fatal("unexpected error exporting to dogstatsd:", err)
+	}
+
+	tags := "|#check:dns-check,namespace:kuberhealthy,workload:check,failure_reason:ReportedFailure,team:platform"
+	expected := []string{
+		"kh.check.status:0|g" + tags,
+		"kh.check.duration_seconds:3|g" + tags,
+		"_e{61,32}:Kuberhealthy check dns-check in namespace kuberhealthy failed|lookup failed\\ntimeout, retrying|d:1600000000|k:kuberhealthy/dns-check|s:kuberhealthy|t:error" + tags,
+	}
+	buf := make([]byte, 1024)
+	for _, e := range expected {
+		err = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal("failed to read datagram:", err)
+		}
+		if string(buf[:n]) != e {
+			t.Errorf("expected datagram %q, got %q", e, string(buf[:n]))
+		}
+	}
+}
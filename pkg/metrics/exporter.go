@@ -3,6 +3,8 @@ package metrics // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/metrics"
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,9 +18,15 @@ type PromMetricsConfig struct {
 	ErrorLabelMaxLength int  `yaml:"errorLabelMaxLength,omitempty"` // if not suppress, then bound the error label value length to a number of bytes
 }
 
+// content types of the metrics endpoint
+const (
+	PrometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
 // promMetricName: helper fn for GenerateMetrics, does a quick format of the metric line - checkOrJob is literally the string "check" or "job"
 func promMetricName(config PromMetricsConfig, checkOrJob string, checkName string, namespace string, status string, errors []string) string {
-	metricName := fmt.Sprintf("kuberhealthy_%s{check=\"%s\",namespace=\"%s\",status=\"%s\"", checkOrJob, checkName, namespace, status)
+	metricName := fmt.Sprintf("kuberhealthy_%s{check=\"%s\",namespace=\"%s\",status=\"%s\"", checkOrJob, escapeLabelValue(checkName), escapeLabelValue(namespace), status)
 	if !config.SuppressErrorLabel {
 		errorsStr := ""
 		if len(errors) > 0 {
@@ -29,34 +37,161 @@ func promMetricName(config PromMetricsConfig, checkOrJob string, checkName strin
 			errorsStr = strings.ReplaceAll(errorsStr, "\"", "'")
 		}
 		if config.ErrorLabelMaxLength > 0 && len(errorsStr) > config.ErrorLabelMaxLength {
-			errorsStr = errorsStr[0:config.ErrorLabelMaxLength]
+			// truncating can split a multi byte character, which would make the label value invalid UTF-8
+			errorsStr = strings.ToValidUTF8(errorsStr[0:config.ErrorLabelMaxLength], "")
 		}
-		metricName += fmt.Sprintf(",error=\"%s\"}", errorsStr)
+		metricName += fmt.Sprintf(",error=\"%s\"}", escapeLabelValue(errorsStr))
 	} else {
 		metricName += "}"
 	}
 	return metricName
 }
 
-//GenerateMetrics takes the state and returns it in the Prometheus format
-func GenerateMetrics(state health.State, config PromMetricsConfig) string {
-	metricsOutput := ""
-	healthStatus := "0"
-	if state.OK {
-		healthStatus = "1"
+// escapeLabelValue escapes backslashes, double quotes and line feeds in a label value as both the Prometheus and
+// OpenMetrics text formats require
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// reservedLabelNames are the labels that Kuberhealthy sets on check metrics itself, which khcheck labels can not
+// replace
+var reservedLabelNames = map[string]bool{
+	"check":          true,
+	"namespace":      true,
+	"status":         true,
+	"error":          true,
+	"severity":       true,
+	"failure_reason": true,
+	"cluster":        true,
+	"environment":    true,
+}
+
+// LabelName turns the supplied khcheck label or annotation key into a valid Prometheus label name by replacing
+// every character that is not allowed with an underscore.  For example, app.kubernetes.io/component becomes
+// app_kubernetes_io_component.
+func LabelName(key string) string {
+	name := []rune(key)
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+		if !valid {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// formatCheckLabels formats the supplied khcheck labels as extra labels of a sample, sorted by name.  Labels that
+// Kuberhealthy sets itself are skipped.
+func formatCheckLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if reservedLabelNames[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatted := ""
+	for _, name := range names {
+		formatted += fmt.Sprintf(",%s=\"%s\"", name, escapeLabelValue(labels[name]))
 	}
+	return formatted
+}
+
+// AcceptsOpenMetrics indicates if a scraper asked for the OpenMetrics text format in the supplied Accept header
+func AcceptsOpenMetrics(accept string) bool {
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+		if mediaType == "application/openmetrics-text" {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateMetrics takes the state and returns it in the Prometheus format
+func GenerateMetrics(state health.State, config PromMetricsConfig) string {
+	return generateMetrics(state, config, false)
+}
+
+// GenerateOpenMetrics takes the state and returns it in the OpenMetrics format, including the # EOF marker that
+// ends an OpenMetrics exposition
+func GenerateOpenMetrics(state health.State, config PromMetricsConfig) string {
+	return generateMetrics(state, config, true) + "# EOF\n"
+}
+
+// generateMetrics returns the metrics of the state.  The Prometheus and OpenMetrics text formats only differ in
+// how counters are named, so both are generated here.
+func generateMetrics(state health.State, config PromMetricsConfig, openMetrics bool) string {
+	samples := newStateSamples(state, config)
+
 	// Kuberhealthy metrics
+	metricsOutput := ""
 	metricsOutput += "# HELP kuberhealthy_running Shows if kuberhealthy is running error free\n"
 	metricsOutput += "# TYPE kuberhealthy_running gauge\n"
-	metricsOutput += fmt.Sprintf("kuberhealthy_running{current_master=\"%s\"} 1\n", state.CurrentMaster)
+	metricsOutput += formatSamples(samples.running)
 	metricsOutput += "# HELP kuberhealthy_cluster_state Shows the status of the cluster\n"
 	metricsOutput += "# TYPE kuberhealthy_cluster_state gauge\n"
-	metricsOutput += fmt.Sprintf("kuberhealthy_cluster_state %s\n", healthStatus)
+	metricsOutput += formatSamples(samples.clusterState)
 
-	metricCheckState := make(map[string]string)
-	metricCheckDuration := make(map[string]string)
-	metricJobState := make(map[string]string)
-	metricJobDuration := make(map[string]string)
+	// Add each metric format individually. This addresses issue https://github.com/kuberhealthy/kuberhealthy/issues/813.
+	// Unless metric help and type are followed by the metric, datadog cannot process Kuberhealthy metrics.
+	// Kuberhealthy check metrics
+	metricsOutput += "# HELP kuberhealthy_check Shows the status of a Kuberhealthy check\n"
+	metricsOutput += "# TYPE kuberhealthy_check gauge\n"
+	metricsOutput += formatSamples(samples.checkState)
+	metricsOutput += "# HELP kuberhealthy_check_last_run_timestamp_seconds Shows the time of the last run of a Kuberhealthy check as a unix timestamp\n"
+	metricsOutput += "# TYPE kuberhealthy_check_last_run_timestamp_seconds gauge\n"
+	metricsOutput += formatSamples(samples.checkLastRun)
+	metricsOutput += "# HELP kuberhealthy_check_consecutive_failures Shows the number of failed runs in a row of a Kuberhealthy check\n"
+	metricsOutput += "# TYPE kuberhealthy_check_consecutive_failures gauge\n"
+	metricsOutput += formatSamples(samples.checkConsecutiveFailures)
+	metricsOutput += CheckDurations.String()
+	metricsOutput += SchedulingLatencies.String()
+	metricsOutput += ImagePullFailures.Format(openMetrics)
+	// Kuberhealthy job metrics
+	metricsOutput += "# HELP kuberhealthy_job Shows the status of a Kuberhealthy job\n"
+	metricsOutput += "# TYPE kuberhealthy_job gauge\n"
+	metricsOutput += formatSamples(samples.jobState)
+	metricsOutput += "# HELP kuberhealthy_job_duration_seconds Shows the job run duration of a Kuberhealthy job\n"
+	metricsOutput += "# TYPE kuberhealthy_job_duration_seconds gauge\n"
+	metricsOutput += formatSamples(samples.jobDuration)
+	// Kuberhealthy checker pod metrics
+	metricsOutput += CheckerPodsRunning.Format(openMetrics)
+	metricsOutput += ReaperPodsDeleted.Format(openMetrics)
+	metricsOutput += ReaperKHJobsDeleted.Format(openMetrics)
+	metricsOutput += ReaperDryRunDeletions.Format(openMetrics)
+
+	return withIdentityLabels(metricsOutput, state)
+}
+
+// stateSamples holds the samples of the metrics that are derived from a state, keyed by metric name and labels
+type stateSamples struct {
+	running                  map[string]string
+	clusterState             map[string]string
+	checkState               map[string]string
+	checkLastRun             map[string]string
+	checkConsecutiveFailures map[string]string
+	jobState                 map[string]string
+	jobDuration              map[string]string
+}
+
+// newStateSamples returns the samples of the metrics that are derived from the supplied state
+func newStateSamples(state health.State, config PromMetricsConfig) stateSamples {
+	healthStatus := "0"
+	if state.OK {
+		healthStatus = "1"
+	}
+	samples := stateSamples{
+		running:                  map[string]string{fmt.Sprintf("kuberhealthy_running{current_master=\"%s\"}", escapeLabelValue(state.CurrentMaster)): "1"},
+		clusterState:             map[string]string{"kuberhealthy_cluster_state": healthStatus},
+		checkState:               make(map[string]string),
+		checkLastRun:             make(map[string]string),
+		checkConsecutiveFailures: make(map[string]string),
+		jobState:                 make(map[string]string),
+		jobDuration:              make(map[string]string),
+	}
 
 	// Parse through all check details and append to metricState
 	for c, d := range state.CheckDetails {
@@ -65,18 +200,21 @@ func GenerateMetrics(state health.State, config PromMetricsConfig) string {
 			checkStatus = "1"
 		}
 		metricName := promMetricName(config, "check", c, d.Namespace, checkStatus, d.Errors)
-		metricDurationName := fmt.Sprintf("kuberhealthy_check_duration_seconds{check=\"%s\",namespace=\"%s\"}", c, d.Namespace)
-		metricCheckState[metricName] = checkStatus
-
-		// if runDuration hasn't been set yet, ie. pod never ran or failed to provision, set runDuration to 0
-		if d.RunDuration == "" {
-			d.RunDuration = time.Duration(0).String()
+		if len(d.Severity) > 0 {
+			metricName = strings.TrimSuffix(metricName, "}") + fmt.Sprintf(",severity=\"%s\"}", escapeLabelValue(d.Severity))
 		}
-		runDuration, err := time.ParseDuration(d.RunDuration)
-		if err != nil {
-			log.Errorln("Error parsing run duration:", d.RunDuration, "for metric:", metricName, "error:", err)
+		if !d.OK && len(d.FailureReason) > 0 {
+			metricName = strings.TrimSuffix(metricName, "}") + fmt.Sprintf(",failure_reason=\"%s\"}", escapeLabelValue(string(d.FailureReason)))
 		}
-		metricCheckDuration[metricDurationName] = fmt.Sprintf("%f", runDuration.Seconds())
+		extraLabels := formatCheckLabels(d.Labels)
+		metricName = strings.TrimSuffix(metricName, "}") + extraLabels + "}"
+		samples.checkState[metricName] = checkStatus
+
+		checkLabels := fmt.Sprintf("{check=\"%s\",namespace=\"%s\"%s}", escapeLabelValue(c), escapeLabelValue(d.Namespace), extraLabels)
+		if d.LastRun != nil && !d.LastRun.IsZero() {
+			samples.checkLastRun["kuberhealthy_check_last_run_timestamp_seconds"+checkLabels] = strconv.FormatInt(d.LastRun.Unix(), 10)
+		}
+		samples.checkConsecutiveFailures["kuberhealthy_check_consecutive_failures"+checkLabels] = strconv.Itoa(d.ConsecutiveFailures)
 	}
 
 	// Parse through all job details and append to metricState
@@ -86,8 +224,11 @@ func GenerateMetrics(state health.State, config PromMetricsConfig) string {
 			jobStatus = "1"
 		}
 		metricName := promMetricName(config, "job", c, d.Namespace, jobStatus, d.Errors)
-		metricDurationName := fmt.Sprintf("kuberhealthy_job_duration_seconds{check=\"%s\",namespace=\"%s\"}", c, d.Namespace)
-		metricJobState[metricName] = jobStatus
+		if !d.OK && len(d.FailureReason) > 0 {
+			metricName = strings.TrimSuffix(metricName, "}") + fmt.Sprintf(",failure_reason=\"%s\"}", escapeLabelValue(string(d.FailureReason)))
+		}
+		metricDurationName := fmt.Sprintf("kuberhealthy_job_duration_seconds{check=\"%s\",namespace=\"%s\"}", escapeLabelValue(c), escapeLabelValue(d.Namespace))
+		samples.jobState[metricName] = jobStatus
 
 		// if runDuration hasn't been set yet, ie. pod never ran or failed to provision, set runDuration to 0
 		if d.RunDuration == "" {
@@ -97,44 +238,34 @@ func GenerateMetrics(state health.State, config PromMetricsConfig) string {
 		if err != nil {
 			log.Errorln("Error parsing run duration:", d.RunDuration, "for metric:", metricName, "error:", err)
 		}
-		metricJobDuration[metricDurationName] = fmt.Sprintf("%f", runDuration.Seconds())
+		samples.jobDuration[metricDurationName] = fmt.Sprintf("%f", runDuration.Seconds())
 	}
 
-	// Add each metric format individually. This addresses issue https://github.com/kuberhealthy/kuberhealthy/issues/813.
-	// Unless metric help and type are followed by the metric, datadog cannot process Kuberhealthy metrics.
-	// Kuberhealthy check metrics
-	metricsOutput += "# HELP kuberhealthy_check Shows the status of a Kuberhealthy check\n"
-	metricsOutput += "# TYPE kuberhealthy_check gauge\n"
-	for m, v := range metricCheckState {
-		metricsOutput += fmt.Sprintf("%s %s\n", m, v)
-	}
-	metricsOutput += "# HELP kuberhealthy_check_duration_seconds Shows the check run duration of a Kuberhealthy check\n"
-	metricsOutput += "# TYPE kuberhealthy_check_duration_seconds gauge\n"
-	for m, v := range metricCheckDuration {
-		metricsOutput += fmt.Sprintf("%s %s\n", m, v)
-	}
-	// Kuberhealthy job metrics
-	metricsOutput += "# HELP kuberhealthy_job Shows the status of a Kuberhealthy job\n"
-	metricsOutput += "# TYPE kuberhealthy_job gauge\n"
-	for m, v := range metricJobState {
-		metricsOutput += fmt.Sprintf("%s %s\n", m, v)
-	}
-	metricsOutput += "# HELP kuberhealthy_job_duration_seconds Shows the job run duration of a Kuberhealthy job\n"
-	metricsOutput += "# TYPE kuberhealthy_job_duration_seconds gauge\n"
-	for m, v := range metricJobDuration {
-		metricsOutput += fmt.Sprintf("%s %s\n", m, v)
+	return samples
+}
+
+// formatSamples formats metric samples sorted by name and labels, so that output is stable between scrapes
+func formatSamples(samples map[string]string) string {
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return metricsOutput
+	output := ""
+	for _, name := range names {
+		output += fmt.Sprintf("%s %s\n", name, samples[name])
+	}
+	return output
 }
 
-//ErrorStateMetrics is a Prometheus metric meant to show Kuberhealthy has error
+// ErrorStateMetrics is a Prometheus metric meant to show Kuberhealthy has error
 func ErrorStateMetrics(state health.State) string {
 	errorOutput := ""
 	errorOutput += "# HELP kuberhealthy_running Shows if kuberhealthy is running error free\n"
 	errorOutput += "# TYPE kuberhealthy_running gauge\n"
 	errorOutput += fmt.Sprintf(`kuberhealthy_running{currentMaster="%s"} 0`, state.CurrentMaster)
-	return errorOutput
+	return withIdentityLabels(errorOutput, state)
 }
 
 // WriteMetricError handles errors in delivering metrics
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
 	"github.com/kuberhealthy/kuberhealthy/v2/pkg/health"
 )
@@ -133,6 +135,39 @@ func TestGenerateMetrics(t *testing.T) {
 	if metrics[`kuberhealthy_check{check="bad",namespace="",status="0",error="123"}`] != "0" {
 		t.Fatal("Kuberhealthy bad error label check does not match - test 4", metrics)
 	}
+	// Test check severity label
+	state = health.State{
+		OK: true,
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"warn": {
+				Errors:   []string{"123"},
+				Severity: "warning",
+			},
+		},
+	}
+	result = GenerateMetrics(state, PromMetricsConfig{SuppressErrorLabel: true})
+	metrics = parseMetrics(result)
+	if metrics[`kuberhealthy_check{check="warn",namespace="",status="0",severity="warning"}`] != "0" {
+		t.Fatal("Kuberhealthy check severity label does not match", metrics)
+	}
+	// Test check failure reason label
+	state = health.State{
+		OK: true,
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"slow": {
+				Errors:        []string{"123"},
+				FailureReason: khstatev1.FailureReasonTimeout,
+			},
+		},
+	}
+	result = GenerateMetrics(state, PromMetricsConfig{SuppressErrorLabel: true})
+	metrics = parseMetrics(result)
+	if metrics[`kuberhealthy_check{check="slow",namespace="",status="0",failure_reason="Timeout"}`] != "0" {
+		t.Fatal("Kuberhealthy check failure reason label does not match", metrics)
+	}
+	if metrics["kuberhealthy_cluster_state"] != "1" {
+		t.Fatal("Kuberhealthy shows cluster as not healthy when it is")
+	}
 }
 
 func TestErrorStateMetrics(t *testing.T) {
@@ -181,3 +216,181 @@ func TestWriteMetricError(t *testing.T) {
 		t.Fatal("Error Metric does not match actual error metric function")
 	}
 }
+
+func TestGenerateMetricsCheckSeries(t *testing.T) {
+	lastRun := metav1.Unix(1600000000, 0)
+	state := health.State{
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"kuberhealthy/dns": {
+				Namespace:           "kuberhealthy",
+				LastRun:             &lastRun,
+				ConsecutiveFailures: 3,
+				Errors:              []string{"lookup failed\nwith \\ backslash"},
+			},
+		},
+	}
+	result := GenerateMetrics(state, PromMetricsConfig{})
+	metrics := parseMetrics(result)
+	if metrics[`kuberhealthy_check_last_run_timestamp_seconds{check="kuberhealthy/dns",namespace="kuberhealthy"}`] != "1600000000" {
+		t.Fatal("Kuberhealthy check last run timestamp does not match", metrics)
+	}
+	if metrics[`kuberhealthy_check_consecutive_failures{check="kuberhealthy/dns",namespace="kuberhealthy"}`] != "3" {
+		t.Fatal("Kuberhealthy check consecutive failures does not match", metrics)
+	}
+	if !strings.Contains(result, `error="lookup failed\nwith \\ backslash"`) {
+		t.Fatal("Kuberhealthy check error label is not escaped", result)
+	}
+	if strings.Contains(result, "# EOF") {
+		t.Fatal("Prometheus metrics should not end with an OpenMetrics EOF marker")
+	}
+	if !strings.Contains(result, "# TYPE kuberhealthy_reaper_pods_deleted_total counter\n") {
+		t.Fatal("Prometheus metrics should name counter families after their samples", result)
+	}
+}
+
+func TestGenerateOpenMetrics(t *testing.T) {
+	state := health.State{
+		OK:           true,
+		CheckDetails: map[string]khstatev1.WorkloadDetails{"good": {OK: true}},
+	}
+	result := GenerateOpenMetrics(state, PromMetricsConfig{})
+	if !strings.HasSuffix(result, "\n# EOF\n") {
+		t.Fatal("OpenMetrics output does not end with an EOF marker", result)
+	}
+	if !strings.Contains(result, "# TYPE kuberhealthy_reaper_pods_deleted counter\n") {
+		t.Fatal("OpenMetrics output should name counter families without the _total suffix", result)
+	}
+	metrics := parseMetrics(result)
+	if metrics["kuberhealthy_cluster_state"] != "1" {
+		t.Fatal("Kuberhealthy shows cluster as not healthy when it is")
+	}
+}
+
+func TestAcceptsOpenMetrics(t *testing.T) {
+	testCases := map[string]bool{
+		"": false,
+		"text/plain;version=0.0.4;q=0.5,*/*;q=0.1":                                                     false,
+		"application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75": true,
+		"text/plain, application/openmetrics-text; version=1.0.0":                                      true,
+	}
+	for accept, expected := range testCases {
+		if AcceptsOpenMetrics(accept) != expected {
+			t.Fatalf("Expected AcceptsOpenMetrics(%q) to be %t", accept, expected)
+		}
+	}
+}
+
+func TestGenerateClusterMetrics(t *testing.T) {
+	east := health.State{
+		OK:            false,
+		CurrentMaster: "kuberhealthy-0",
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"dns-check": {OK: false, Namespace: "kuberhealthy", Errors: []string{"lookup failed"}},
+		},
+	}
+	west := health.State{
+		OK: true,
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"dns-check": {OK: true, Namespace: "kuberhealthy"},
+		},
+	}
+	clusters := map[string]ClusterStatus{
+		"east":  {Up: true, State: &east},
+		"west":  {Up: false, State: &west},
+		"north": {Up: false},
+	}
+
+	result := GenerateClusterOpenMetrics(clusters, PromMetricsConfig{SuppressErrorLabel: true})
+	if !strings.HasSuffix(result, "# EOF\n") {
+		t.Fatal("Expected OpenMetrics output to end with # EOF")
+	}
+	metrics := parseMetrics(result)
+	expected := map[string]string{
+		`kuberhealthy_federation_cluster_up{cluster="east"}`:                                                 "1",
+		`kuberhealthy_federation_cluster_up{cluster="west"}`:                                                 "0",
+		`kuberhealthy_federation_cluster_up{cluster="north"}`:                                                "0",
+		`kuberhealthy_cluster_state{cluster="east"}`:                                                         "0",
+		`kuberhealthy_cluster_state{cluster="west"}`:                                                         "1",
+		`kuberhealthy_running{cluster="east",current_master="kuberhealthy-0"}`:                               "1",
+		`kuberhealthy_check{cluster="east",check="dns-check",namespace="kuberhealthy",status="0"}`:           "0",
+		`kuberhealthy_check{cluster="west",check="dns-check",namespace="kuberhealthy",status="1"}`:           "1",
+		`kuberhealthy_check_consecutive_failures{cluster="west",check="dns-check",namespace="kuberhealthy"}`: "0",
+	}
+	for name, value := range expected {
+		if metrics[name] != value {
+			t.Fatalf("Expected %s to be %s, got %q in:\n%s", name, value, metrics[name], result)
+		}
+	}
+	if _, ok := metrics[`kuberhealthy_cluster_state{cluster="north"}`]; ok {
+		t.Fatal("Expected no state metrics for a cluster that was never fetched")
+	}
+}
+
+func TestGenerateMetricsIdentityLabels(t *testing.T) {
+	state := health.State{
+		OK:            true,
+		CurrentMaster: "kuberhealthy-0",
+		ClusterName:   "prod-east",
+		Environment:   "production",
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"dns-check": {OK: true, Namespace: "kuberhealthy"},
+		},
+	}
+	result := GenerateMetrics(state, PromMetricsConfig{})
+	metrics := parseMetrics(result)
+	expected := map[string]string{
+		`kuberhealthy_cluster_state{cluster="prod-east",environment="production"}`:                                                        "1",
+		`kuberhealthy_running{cluster="prod-east",environment="production",current_master="kuberhealthy-0"}`:                              "1",
+		`kuberhealthy_check{cluster="prod-east",environment="production",check="dns-check",namespace="kuberhealthy",status="1",error=""}`: "1",
+	}
+	for name, value := range expected {
+		if metrics[name] != value {
+			t.Fatalf("Expected %s to be %s, got %q in:\n%s", name, value, metrics[name], result)
+		}
+	}
+	for _, line := range strings.Split(result, "\n") {
+		if len(line) > 0 && !strings.HasPrefix(line, "#") && !strings.Contains(line, `cluster="prod-east"`) {
+			t.Fatalf("Expected every sample to have a cluster label but got %q", line)
+		}
+	}
+}
+
+func TestGenerateMetricsCheckLabels(t *testing.T) {
+	lastRun := metav1.Unix(1600000000, 0)
+	state := health.State{
+		CheckDetails: map[string]khstatev1.WorkloadDetails{
+			"payments/api": {
+				OK:        true,
+				Namespace: "payments",
+				LastRun:   &lastRun,
+				Labels:    map[string]string{"team": "payments", "tier": "\"gold\"", "check": "ignored"},
+			},
+		},
+	}
+	result := GenerateMetrics(state, PromMetricsConfig{SuppressErrorLabel: true})
+	metrics := parseMetrics(result)
+	expected := map[string]string{
+		`kuberhealthy_check{check="payments/api",namespace="payments",status="1",team="payments",tier="\"gold\""}`:                 "1",
+		`kuberhealthy_check_last_run_timestamp_seconds{check="payments/api",namespace="payments",team="payments",tier="\"gold\""}`: "1600000000",
+		`kuberhealthy_check_consecutive_failures{check="payments/api",namespace="payments",team="payments",tier="\"gold\""}`:       "0",
+	}
+	for name, value := range expected {
+		if metrics[name] != value {
+			t.Fatalf("Expected %s to be %s, got %q in:\n%s", name, value, metrics[name], result)
+		}
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	testCases := map[string]string{
+		"team":                        "team",
+		"app.kubernetes.io/component": "app_kubernetes_io_component",
+		"9lives":                      "_lives",
+		"owner-team":                  "owner_team",
+	}
+	for key, expected := range testCases {
+		if name := LabelName(key); name != expected {
+			t.Fatalf("Expected label name of %s to be %s but got %s", key, expected, name)
+		}
+	}
+}
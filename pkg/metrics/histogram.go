@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets used for check duration histograms
+var DefaultDurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 900}
+
+// CheckDurations is the histogram of external check run durations, measured from checker pod start to
+// report receipt.  It is included in the output of GenerateMetrics.
+var CheckDurations = NewDurationHistogram("kuberhealthy_check_duration_seconds", "Shows the time from checker pod start to report receipt for Kuberhealthy check runs", DefaultDurationBuckets)
+
+// SchedulingLatencies is the histogram of the time external checker pods take from creation to running, which
+// includes waiting to be scheduled and pulling images.  It is included in the output of GenerateMetrics.
+var SchedulingLatencies = NewDurationHistogram("kuberhealthy_check_scheduling_latency_seconds", "Shows the time from checker pod creation to the pod running for Kuberhealthy check runs", DefaultDurationBuckets)
+
+// DurationHistogram is a Prometheus histogram of durations with check and namespace labels. It is safe for
+// concurrent use.
+type DurationHistogram struct {
+	name    string
+	help    string
+	buckets []float64
+	series  map[histogramLabels]*histogramSeries
+	sync.Mutex
+}
+
+// histogramLabels are the labels of a single series in a DurationHistogram
+type histogramLabels struct {
+	check     string
+	namespace string
+}
+
+// histogramSeries holds the observations for a single set of labels
+type histogramSeries struct {
+	bucketCounts []uint64 // cumulative count of observations in each bucket
+	count        uint64
+	sum          float64
+}
+
+// NewDurationHistogram creates a DurationHistogram with the supplied name, help text and bucket upper bounds in seconds
+func NewDurationHistogram(name string, help string, buckets []float64) *DurationHistogram {
+	sortedBuckets := make([]float64, len(buckets))
+	copy(sortedBuckets, buckets)
+	sort.Float64s(sortedBuckets)
+
+	return &DurationHistogram{
+		name:    name,
+		help:    help,
+		buckets: sortedBuckets,
+		series:  make(map[histogramLabels]*histogramSeries),
+	}
+}
+
+// Observe records a duration for the supplied check in the supplied namespace
+func (h *DurationHistogram) Observe(check string, namespace string, d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	labels := histogramLabels{check: check, namespace: namespace}
+	s, ok := h.series[labels]
+	if !ok {
+		s = &histogramSeries{bucketCounts: make([]uint64, len(h.buckets))}
+		h.series[labels] = s
+	}
+
+	seconds := d.Seconds()
+	for i, upperBound := range h.buckets {
+		if seconds <= upperBound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.count++
+	s.sum += seconds
+}
+
+// String returns the histogram in the Prometheus text format
+func (h *DurationHistogram) String() string {
+	h.Lock()
+	defer h.Unlock()
+
+	output := fmt.Sprintf("# HELP %s %s\n", h.name, h.help)
+	output += fmt.Sprintf("# TYPE %s histogram\n", h.name)
+
+	// sort the series so that output is stable between scrapes
+	labelSets := make([]histogramLabels, 0, len(h.series))
+	for labels := range h.series {
+		labelSets = append(labelSets, labels)
+	}
+	sort.Slice(labelSets, func(i, j int) bool {
+		if labelSets[i].namespace != labelSets[j].namespace {
+			return labelSets[i].namespace < labelSets[j].namespace
+		}
+		return labelSets[i].check < labelSets[j].check
+	})
+
+	for _, labels := range labelSets {
+		s := h.series[labels]
+		labelString := fmt.Sprintf("check=\"%s\",namespace=\"%s\"", labels.check, labels.namespace)
+		for i, upperBound := range h.buckets {
+			output += fmt.Sprintf("%s_bucket{%s,le=\"%s\"} %d\n", h.name, labelString, strconv.FormatFloat(upperBound, 'f', -1, 64), s.bucketCounts[i])
+		}
+		output += fmt.Sprintf("%s_bucket{%s,le=\"+Inf\"} %d\n", h.name, labelString, s.count)
+		output += fmt.Sprintf("%s_sum{%s} %f\n", h.name, labelString, s.sum)
+		output += fmt.Sprintf("%s_count{%s} %d\n", h.name, labelString, s.count)
+	}
+
+	return output
+}
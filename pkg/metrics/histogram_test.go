@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationHistogram(t *testing.T) {
+	h := NewDurationHistogram("test_duration_seconds", "test histogram", []float64{10, 1, 5})
+	h.Observe("kuberhealthy/test", "kuberhealthy", time.Millisecond*500)
+	h.Observe("kuberhealthy/test", "kuberhealthy", time.Second*7)
+	h.Observe("kuberhealthy/test", "kuberhealthy", time.Minute)
+
+	metrics := parseMetrics(h.String())
+	expected := map[string]string{
+		`test_duration_seconds_bucket{check="kuberhealthy/test",namespace="kuberhealthy",le="1"}`:    "1",
+		`test_duration_seconds_bucket{check="kuberhealthy/test",namespace="kuberhealthy",le="5"}`:    "1",
+		`test_duration_seconds_bucket{check="kuberhealthy/test",namespace="kuberhealthy",le="10"}`:   "2",
+		`test_duration_seconds_bucket{check="kuberhealthy/test",namespace="kuberhealthy",le="+Inf"}`: "3",
+		`test_duration_seconds_sum{check="kuberhealthy/test",namespace="kuberhealthy"}`:              "67.500000",
+		`test_duration_seconds_count{check="kuberhealthy/test",namespace="kuberhealthy"}`:            "3",
+	}
+	for m, v := range expected {
+		if metrics[m] != v {
+			t.Fatalf("Expected %s to be %s but got %s", m, v, metrics[m])
+		}
+	}
+}
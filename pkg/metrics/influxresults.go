@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultInfluxResultsMeasurement is the measurement that run results are written to when none is configured
+const defaultInfluxResultsMeasurement = "kuberhealthy_check"
+
+// influxResultsTimeout is how long a single write to InfluxDB may take
+const influxResultsTimeout = time.Second * 10
+
+// InfluxResultsConfig holds the settings for writing check results to InfluxDB with the line protocol
+type InfluxResultsConfig struct {
+	Enabled         bool              `yaml:"enabled"`                   // set to true to write the result of every check and job run to InfluxDB
+	URL             string            `yaml:"url"`                       // the base URL of InfluxDB, such as http://influxdb.monitoring:8086
+	Version         int               `yaml:"version,omitempty"`         // the InfluxDB API version to write with, 1 or 2. Defaults to 2 when a bucket is set and 1 otherwise
+	Database        string            `yaml:"database,omitempty"`        // the database written to with the v1 API
+	RetentionPolicy string            `yaml:"retentionPolicy,omitempty"` // the retention policy written to with the v1 API. The default retention policy of the database is used when blank
+	Username        string            `yaml:"username,omitempty"`        // the username used with the v1 API
+	PasswordFile    string            `yaml:"passwordFile,omitempty"`    // a file holding the password used with the v1 API, usually mounted from a secret
+	Organization    string            `yaml:"organization,omitempty"`    // the organization written to with the v2 API
+	Bucket          string            `yaml:"bucket,omitempty"`          // the bucket written to with the v2 API
+	TokenFile       string            `yaml:"tokenFile,omitempty"`       // a file holding the API token used with the v2 API, usually mounted from a secret
+	Measurement     string            `yaml:"measurement,omitempty"`     // the measurement that results are written to. Defaults to kuberhealthy_check
+	Tags            map[string]string `yaml:"tags,omitempty"`            // tags added to every point
+}
+
+// InfluxResultsExporter writes the result of every check run to InfluxDB as a single point with the line protocol.
+// Both the v1 write API and the v2 write API are supported.
+type InfluxResultsExporter struct {
+	config InfluxResultsConfig
+	client *http.Client
+}
+
+// NewInfluxResultsExporter creates an InfluxResultsExporter from the supplied configuration.  An error is returned
+// if the configuration is missing the URL or the database or bucket to write to.
+func NewInfluxResultsExporter(config InfluxResultsConfig) (*InfluxResultsExporter, error) {
+	if len(config.URL) == 0 {
+		return nil, fmt.Errorf("influxdb results require a url")
+	}
+	_, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse influxdb url %s: %w", config.URL, err)
+	}
+
+	if config.Version == 0 {
+		config.Version = 1
+		if len(config.Bucket) > 0 {
+			config.Version = 2
+		}
+	}
+	switch config.Version {
+	case 1:
+		if len(config.Database) == 0 {
+			return nil, fmt.Errorf("influxdb v1 results require a database")
+		}
+	case 2:
+		if len(config.Bucket) == 0 || len(config.Organization) == 0 {
+			return nil, fmt.Errorf("influxdb v2 results require a bucket and organization")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported influxdb version %d", config.Version)
+	}
+
+	return &InfluxResultsExporter{
+		config: config,
+		client: &http.Client{Timeout: influxResultsTimeout},
+	}, nil
+}
+
+// Name returns the name of this exporter
+func (i *InfluxResultsExporter) Name() string {
+	return "influxdb"
+}
+
+// Export writes the supplied run result to InfluxDB
+func (i *InfluxResultsExporter) Export(r RunResult) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	req, err := i.writeRequest([]byte(i.line(r)))
+	if err != nil {
+		return err
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write results to influxdb: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("influxdb write api returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// writeRequest creates the request that writes the supplied lines with the configured API version
+func (i *InfluxResultsExporter) writeRequest(body []byte) (*http.Request, error) {
+	query := url.Values{}
+	query.Set("precision", "s")
+
+	var path string
+	switch i.config.Version {
+	case 2:
+		path = "/api/v2/write"
+		query.Set("org", i.config.Organization)
+		query.Set("bucket", i.config.Bucket)
+	default:
+		path = "/write"
+		query.Set("db", i.config.Database)
+		if len(i.config.RetentionPolicy) > 0 {
+			query.Set("rp", i.config.RetentionPolicy)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(i.config.URL, "/")+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create influxdb request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	// secret files are read on each write so that rotated secrets are picked up without restarting Kuberhealthy
+	switch i.config.Version {
+	case 2:
+		if len(i.config.TokenFile) > 0 {
+			token, err := readSecretFile(i.config.TokenFile)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Token "+token)
+		}
+	default:
+		if len(i.config.Username) > 0 {
+			var password string
+			if len(i.config.PasswordFile) > 0 {
+				password, err = readSecretFile(i.config.PasswordFile)
+				if err != nil {
+					return nil, err
+				}
+			}
+			req.SetBasicAuth(i.config.Username, password)
+		}
+	}
+	return req, nil
+}
+
+// line returns the run result as a point in the line protocol.  The point is tagged with the name and namespace of
+// the check, its workload type, node, cluster and environment, the labels of the khcheck and the configured tags.  The status, duration,
+// failure reason and errors of the run are written as fields.
+func (i *InfluxResultsExporter) line(r RunResult) string {
+	tags := make(map[string]string)
+	for k, v := range r.Labels {
+		tags[k] = v
+	}
+	for k, v := range i.config.Tags {
+		tags[k] = v
+	}
+	tags["check"] = r.Name
+	tags["namespace"] = r.Namespace
+	tags["workload"] = r.Workload
+	tags["node"] = r.Node
+	if len(r.Cluster) > 0 {
+		tags["cluster"] = r.Cluster
+	}
+	if len(r.Environment) > 0 {
+		tags["environment"] = r.Environment
+	}
+
+	// tags are sorted by key, which is what influxdb expects for the best write performance
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if len(k) > 0 && len(v) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	measurement := i.config.Measurement
+	if len(measurement) == 0 {
+		measurement = defaultInfluxResultsMeasurement
+	}
+
+	var b strings.Builder
+	b.WriteString(influxEscaper.Replace(measurement))
+	for _, k := range keys {
+		b.WriteString("," + influxTagEscaper.Replace(k) + "=" + influxTagEscaper.Replace(tags[k]))
+	}
+	b.WriteString(" status=" + strconv.Itoa(int(statusValue(r.OK))) + "i")
+	b.WriteString(",ok=" + strconv.FormatBool(r.OK))
+	b.WriteString(",duration_seconds=" + strconv.FormatFloat(r.RunDuration.Seconds(), 'f', -1, 64))
+	b.WriteString(",failure_reason=" + influxString(r.FailureReason))
+	b.WriteString(",errors=" + influxString(strings.Join(r.Errors, "\n")))
+	b.WriteString(" " + strconv.FormatInt(r.Time.Unix(), 10))
+	return b.String()
+}
+
+// influxEscaper escapes measurement names in the line protocol
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+
+// influxTagEscaper escapes tag keys and values in the line protocol
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+// influxString quotes a string field value for the line protocol
+func influxString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// readSecretFile reads a secret, such as a token or password, from a file
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
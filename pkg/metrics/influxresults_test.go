@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewInfluxResultsExporter(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		config  InfluxResultsConfig
+		version int
+		valid   bool
+	}{
+		{"v1", InfluxResultsConfig{URL: "http://influxdb:8086", Database: "kuberhealthy"}, 1, true},
+		{"v2 from bucket", InfluxResultsConfig{URL: "http://influxdb:8086", Bucket: "kuberhealthy", Organization: "sre"}, 2, true},
+		{"no url", InfluxResultsConfig{Database: "kuberhealthy"}, 0, false},
+		{"v1 without database", InfluxResultsConfig{URL: "http://influxdb:8086", Version: 1}, 0, false},
+		{"v2 without organization", InfluxResultsConfig{URL: "http://influxdb:8086", Version: 2, Bucket: "kuberhealthy"}, 0, false},
+		{"unknown version", InfluxResultsConfig{URL: "http://influxdb:8086", Version: 3, Database: "kuberhealthy"}, 0, false},
+	}
+
+	for _, tc := range testCases {
+		i, err := NewInfluxResultsExporter(tc.config)
+		if tc.valid != (err == nil) {
+			t.Fatalf("%s: expected valid %t, got error %v", tc.name, tc.valid, err)
+		}
+		if tc.valid && i.config.Version != tc.version {
+			t.Fatalf("%s: expected version %d, got %d", tc.name, tc.version, i.config.Version)
+		}
+	}
+}
+
+func TestInfluxResultsLine(t *testing.T) {
+	i := &InfluxResultsExporter{config: InfluxResultsConfig{Tags: map[string]string{"cluster": "prod east"}}}
+	r := testRunResult()
+	r.Errors = []string{`lookup "db" failed`}
+
+	expected := `kuberhealthy_check,check=dns-check,cluster=prod\ east,namespace=kuberhealthy,team=platform,workload=check status=0i,ok=false,duration_seconds=3,failure_reason="ReportedFailure",errors="lookup \"db\" failed" 1600000000`
+	line := i.line(r)
+	if line != expected {
+		t.Fatalf("expected line\n%s\ngot\n%s", expected, line)
+	}
+}
+
+func TestInfluxResultsExport(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCases = []struct {
+		name   string
+		config InfluxResultsConfig
+		path   string
+		query  string
+		auth   string
+	}{
+		{"v1", InfluxResultsConfig{Database: "kh", RetentionPolicy: "month"}, "/write", "db=kh&precision=s&rp=month", ""},
+		{"v2", InfluxResultsConfig{Bucket: "kh", Organization: "sre", TokenFile: tokenFile}, "/api/v2/write", "bucket=kh&org=sre&precision=s", "Token s3cret"},
+	}
+
+	for _, tc := range testCases {
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != tc.path || r.URL.RawQuery != tc.query {
+				t.Errorf("%s: unexpected request to %s?%s", tc.name, r.URL.Path, r.URL.RawQuery)
+			}
+			if r.Header.Get("Authorization") != tc.auth {
+				t.Errorf("%s: unexpected authorization %q", tc.name, r.Header.Get("Authorization"))
+			}
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		tc.config.URL = server.URL
+		i, err := NewInfluxResultsExporter(tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = i.Export(testRunResult())
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error writing to influxdb: %v", tc.name, err)
+		}
+		if string(body) != i.line(testRunResult()) {
+			t.Fatalf("%s: unexpected body %q", tc.name, string(body))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import "time"
+
+// RunResult is the result of a single run of a check or job, as sent to result exporters
+type RunResult struct {
+	Name          string            // the name of the khcheck or khjob
+	Namespace     string            // the namespace of the khcheck or khjob
+	Workload      string            // check or job
+	OK            bool              // indicates if the run succeeded
+	Errors        []string          // the errors reported by the run, with secrets already redacted
+	RunDuration   time.Duration     // how long the run took
+	FailureReason string            // why the run failed, such as ReportedFailure or Timeout
+	Node          string            // the node that the checker pod ran on
+	Labels        map[string]string // the labels of the khcheck or khjob
+	Cluster       string            // the configured name of the cluster that the run happened in
+	Environment   string            // the configured environment of the cluster, such as production
+	Time          time.Time         // when the run finished
+}
+
+// ResultExporter is implemented by backends that the result of every check and job run is sent to
+type ResultExporter interface {
+	Name() string
+	Export(r RunResult) error
+}
@@ -0,0 +1,210 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AlertmanagerLabelAnnotationPrefix is the prefix of khcheck annotations that add labels to the alerts of a single
+// check.  An annotation of comcast.github.io/alert-label-team: payments adds the label team="payments".
+const AlertmanagerLabelAnnotationPrefix = "comcast.github.io/alert-label-"
+
+// alertmanagerAlertsPath is the path of the Alertmanager v2 API that alerts are posted to
+const alertmanagerAlertsPath = "/api/v2/alerts"
+
+// defaultAlertmanagerAlertName is the alertname label of alerts when none is configured
+const defaultAlertmanagerAlertName = "KuberhealthyCheckFailed"
+
+// defaultAlertmanagerAlertTimeout is how long a firing alert lasts when none is configured.  Alerts are pushed
+// again on every failed run of their check, so they only expire if Kuberhealthy stops running the check.
+const defaultAlertmanagerAlertTimeout = time.Hour * 24
+
+// AlertmanagerConfig holds the global settings for pushing alerts to Alertmanager
+type AlertmanagerConfig struct {
+	URLs            []string          `yaml:"urls,omitempty"`            // the base URLs of every Alertmanager replica, such as http://alertmanager.monitoring:9093. Alertmanager alerts are disabled when empty
+	BearerTokenFile string            `yaml:"bearerTokenFile,omitempty"` // a file holding a bearer token sent with every request, usually mounted from a secret
+	AlertName       string            `yaml:"alertName,omitempty"`       // the alertname label of alerts. Defaults to KuberhealthyCheckFailed
+	Labels          map[string]string `yaml:"labels,omitempty"`          // labels added to every alert
+	AlertTimeout    time.Duration     `yaml:"alertTimeout,omitempty"`    // how long a firing alert lasts unless it is pushed again. Defaults to 24h
+	GeneratorURL    string            `yaml:"generatorURL,omitempty"`    // a link back to Kuberhealthy added to every alert, such as the status page URL
+}
+
+// AlertmanagerNotifier pushes a firing alert to Alertmanager when a check fails and resolves it when the check
+// recovers
+type AlertmanagerNotifier struct {
+	config AlertmanagerConfig
+	client *http.Client
+}
+
+// alertmanagerAlert is a single alert sent to the Alertmanager v2 API
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// NewAlertmanagerNotifier creates an AlertmanagerNotifier from the supplied configuration
+func NewAlertmanagerNotifier(config AlertmanagerConfig) *AlertmanagerNotifier {
+	return &AlertmanagerNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (a *AlertmanagerNotifier) Name() string {
+	return "alertmanager"
+}
+
+// Notify pushes a firing alert for a failing check or resolves the alert of a recovered check.  The alert is sent
+// to every configured Alertmanager.  Nothing is sent if no Alertmanager URLs are configured.
+func (a *AlertmanagerNotifier) Notify(t Transition) error {
+	if len(a.config.URLs) == 0 {
+		log.Debugln("notifications: no alertmanager urls configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	token, err := a.bearerToken()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal([]alertmanagerAlert{a.alert(t)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alertmanager alert: %w", err)
+	}
+
+	var failed []string
+	for _, u := range a.config.URLs {
+		err = a.post(u, b, token)
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	// alertmanager replicas share alerts with each other, so one of them receiving the alert is enough
+	if len(failed) == len(a.config.URLs) {
+		return fmt.Errorf("failed to push alert to alertmanager: %s", strings.Join(failed, "; "))
+	}
+	for _, f := range failed {
+		log.Warningln("notifications: failed to push alert for check", t.Namespace+"/"+t.CheckName, "to an alertmanager replica:", f)
+	}
+	return nil
+}
+
+// Refresh pushes the firing alert of a check that is still failing again so that it does not expire
+func (a *AlertmanagerNotifier) Refresh(t Transition) error {
+	if t.OK {
+		return nil
+	}
+	return a.Notify(t)
+}
+
+// alert builds the alert for the transition.  Firing alerts end after the alert timeout unless they are pushed
+// again, and resolved alerts end at the time of the transition.
+func (a *AlertmanagerNotifier) alert(t Transition) alertmanagerAlert {
+	now := t.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	alert := alertmanagerAlert{
+		Labels:       a.labels(t),
+		GeneratorURL: a.config.GeneratorURL,
+		EndsAt:       now.UTC().Format(time.RFC3339),
+	}
+	if !t.OK {
+		timeout := a.config.AlertTimeout
+		if timeout <= 0 {
+			timeout = defaultAlertmanagerAlertTimeout
+		}
+		alert.StartsAt = now.UTC().Format(time.RFC3339)
+		alert.EndsAt = now.Add(timeout).UTC().Format(time.RFC3339)
+		alert.Annotations = map[string]string{
+			"summary":     fmt.Sprintf("Kuberhealthy check %s in namespace %s%s is failing", t.CheckName, t.Namespace, clusterSuffix(t)),
+			"description": strings.Join(t.Errors, "\n"),
+		}
+		if len(t.PodName) > 0 {
+			alert.Annotations["checker_pod"] = t.PodName
+		}
+	}
+	return alert
+}
+
+// labels returns the labels that identify the alert of a check.  The configured labels, the alert label
+// annotations of the khcheck and its labels selected with the checkLabels setting are added, but can not replace
+// the alertname, check, namespace, cluster and environment labels.
+func (a *AlertmanagerNotifier) labels(t Transition) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range a.config.Labels {
+		labels[k] = v
+	}
+	for k, v := range t.Annotations {
+		if strings.HasPrefix(k, AlertmanagerLabelAnnotationPrefix) && len(k) > len(AlertmanagerLabelAnnotationPrefix) {
+			labels[strings.TrimPrefix(k, AlertmanagerLabelAnnotationPrefix)] = v
+		}
+	}
+	for k, v := range t.CheckLabels {
+		labels[k] = v
+	}
+
+	alertName := a.config.AlertName
+	if len(alertName) == 0 {
+		alertName = defaultAlertmanagerAlertName
+	}
+	labels["alertname"] = alertName
+	labels["check"] = t.CheckName
+	labels["namespace"] = t.Namespace
+	if len(t.Cluster) > 0 {
+		labels["cluster"] = t.Cluster
+	}
+	if len(t.Environment) > 0 {
+		labels["environment"] = t.Environment
+	}
+	return labels
+}
+
+// post sends the supplied alerts to a single Alertmanager
+func (a *AlertmanagerNotifier) post(baseURL string, body []byte, token string) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+alertmanagerAlertsPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alertmanager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert to %s: %w", baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alertmanager %s returned status code %d", baseURL, resp.StatusCode)
+	}
+	return nil
+}
+
+// bearerToken returns the configured bearer token.  The token file is read on each call so that a rotated secret
+// is picked up without restarting Kuberhealthy.
+func (a *AlertmanagerNotifier) bearerToken() (string, error) {
+	if len(a.config.BearerTokenFile) == 0 {
+		return "", nil
+	}
+	b, err := os.ReadFile(a.config.BearerTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read alertmanager bearer token file %s: %w", a.config.BearerTokenFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAlertmanagerNotify(t *testing.T) {
+	var received []alertmanagerAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != alertmanagerAlertsPath {
+			t.Fatalf("Expected a request to %s but got %s", alertmanagerAlertsPath, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			t.Fatalf("Expected the bearer token to be sent but got %q", r.Header.Get("Authorization"))
+		}
+		var alerts []alertmanagerAlert
+		err := json.NewDecoder(r.Body).Decode(&alerts)
+		if err != nil {
+			t.Fatal("Failed to decode alertmanager alerts:", err)
+		}
+		received = append(received, alerts...)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("abc123\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write bearer token file:", err)
+	}
+
+	// an unreachable replica does not fail the push as long as another replica receives the alert
+	n := NewAlertmanagerNotifier(AlertmanagerConfig{
+		URLs:            []string{"http://127.0.0.1:1", server.URL},
+		BearerTokenFile: tokenFile,
+		Labels:          map[string]string{"severity": "page", "check": "overridden"},
+		AlertTimeout:    time.Hour,
+	})
+	now := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	transition := Transition{
+		CheckName:   "deployment",
+		Namespace:   "kuberhealthy",
+		Errors:      []string{"deployment did not become ready"},
+		PodName:     "deployment-1600000000",
+		Time:        now,
+		Annotations: map[string]string{AlertmanagerLabelAnnotationPrefix + "team": "payments", "unrelated": "value"},
+	}
+
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to push firing alert:", err)
+	}
+	transition.OK = true
+	transition.Errors = nil
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to push resolved alert:", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 alerts but got %d", len(received))
+	}
+	firing, resolved := received[0], received[1]
+	expectedLabels := map[string]string{"alertname": defaultAlertmanagerAlertName, "check": "deployment", "namespace": "kuberhealthy", "severity": "page", "team": "payments"}
+	if len(firing.Labels) != len(expectedLabels) {
+		t.Fatalf("Expected the labels %v but got %v", expectedLabels, firing.Labels)
+	}
+	for k, v := range expectedLabels {
+		if firing.Labels[k] != v || resolved.Labels[k] != v {
+			t.Fatalf("Expected label %s to be %s on both alerts but got %v and %v", k, v, firing.Labels, resolved.Labels)
+		}
+	}
+	if firing.StartsAt != "2020-09-13T12:00:00Z" || firing.EndsAt != "2020-09-13T13:00:00Z" {
+		t.Fatalf("Expected the firing alert to last for the alert timeout but got %s to %s", firing.StartsAt, firing.EndsAt)
+	}
+	if firing.Annotations["description"] != "deployment did not become ready" || firing.Annotations["checker_pod"] != "deployment-1600000000" {
+		t.Fatalf("Expected the firing alert to describe the failure but got %v", firing.Annotations)
+	}
+	if resolved.EndsAt != "2020-09-13T12:00:00Z" {
+		t.Fatalf("Expected the resolved alert to end at the time of recovery but got %s", resolved.EndsAt)
+	}
+}
+
+func TestAlertmanagerRefresh(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewAlertmanagerNotifier(AlertmanagerConfig{URLs: []string{server.URL}})
+	SendRefresh([]Notifier{NewSlackNotifier(SlackConfig{}), n}, Transition{CheckName: "deployment", Namespace: "kuberhealthy"})
+	SendRefresh([]Notifier{n}, Transition{CheckName: "deployment", Namespace: "kuberhealthy", OK: true})
+	if requests != 1 {
+		t.Fatalf("Expected only the failing check to be refreshed but got %d requests", requests)
+	}
+}
+
+func TestAlertmanagerClusterLabels(t *testing.T) {
+	n := NewAlertmanagerNotifier(AlertmanagerConfig{Labels: map[string]string{"cluster": "overridden"}})
+	labels := n.labels(Transition{CheckName: "dns", Namespace: "kuberhealthy", Cluster: "prod-east", Environment: "production"})
+	if labels["cluster"] != "prod-east" || labels["environment"] != "production" {
+		t.Fatalf("Expected the cluster and environment labels to be set but got %v", labels)
+	}
+
+	labels = n.labels(Transition{CheckName: "dns", Namespace: "kuberhealthy"})
+	if _, ok := labels["environment"]; ok {
+		t.Fatalf("Expected no environment label without a configured environment but got %v", labels)
+	}
+}
+
+func TestAlertmanagerCheckLabels(t *testing.T) {
+	n := NewAlertmanagerNotifier(AlertmanagerConfig{Labels: map[string]string{"team": "platform"}})
+	labels := n.labels(Transition{CheckName: "dns", Namespace: "kuberhealthy", CheckLabels: map[string]string{"team": "payments", "check": "ignored"}})
+	if labels["team"] != "payments" {
+		t.Fatalf("Expected the team label of the khcheck to be set but got %v", labels)
+	}
+	if labels["check"] != "dns" {
+		t.Fatalf("Expected the check label not to be replaced but got %v", labels)
+	}
+}
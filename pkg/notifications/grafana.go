@@ -0,0 +1,187 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GrafanaDashboardUIDsAnnotation is the khcheck annotation that overrides the Grafana dashboards annotated for a
+// single check.  It holds a comma separated list of dashboard UIDs.
+const GrafanaDashboardUIDsAnnotation = "comcast.github.io/grafana-dashboard-uids"
+
+// GrafanaTagsAnnotation is the khcheck annotation that adds tags to the Grafana annotations of a single check.  It
+// holds a comma separated list of tags.
+const GrafanaTagsAnnotation = "comcast.github.io/grafana-tags"
+
+// grafanaAnnotationsPath is the path of the Grafana HTTP API that annotations are posted to
+const grafanaAnnotationsPath = "/api/annotations"
+
+// GrafanaConfig holds the global settings for Grafana annotations
+type GrafanaConfig struct {
+	URL           string   `yaml:"url,omitempty"`           // the base URL of Grafana, such as http://grafana.monitoring:3000. Grafana annotations are disabled when blank
+	APITokenFile  string   `yaml:"apiTokenFile,omitempty"`  // a file holding a service account token or API key, usually mounted from a secret
+	APIToken      string   `yaml:"apiToken,omitempty"`      // a service account token or API key. apiTokenFile should be preferred so that the token is not stored in the configmap
+	DashboardUIDs []string `yaml:"dashboardUIDs,omitempty"` // the dashboards to annotate. organization wide annotations are created when empty
+	Tags          []string `yaml:"tags,omitempty"`          // tags added to every annotation
+}
+
+// GrafanaNotifier creates Grafana annotations when a check starts failing or recovers, so that check events show
+// up on dashboards alongside metrics
+type GrafanaNotifier struct {
+	config GrafanaConfig
+	client *http.Client
+}
+
+// grafanaAnnotation is the payload sent to the Grafana annotations API
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	Time         int64    `json:"time,omitempty"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// NewGrafanaNotifier creates a GrafanaNotifier from the supplied configuration
+func NewGrafanaNotifier(config GrafanaConfig) *GrafanaNotifier {
+	return &GrafanaNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (g *GrafanaNotifier) Name() string {
+	return "grafana"
+}
+
+// Notify creates an annotation about the transition on every configured dashboard.  The dashboards can be
+// overridden and tags added with annotations on the khcheck.  Nothing is sent if no Grafana URL is configured.
+func (g *GrafanaNotifier) Notify(t Transition) error {
+	if len(g.config.URL) == 0 {
+		log.Debugln("notifications: no grafana url configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	token, err := g.apiToken()
+	if err != nil {
+		return err
+	}
+
+	dashboardUIDs := g.config.DashboardUIDs
+	if uids := splitAnnotationList(t.Annotations[GrafanaDashboardUIDsAnnotation]); len(uids) > 0 {
+		dashboardUIDs = uids
+	}
+	if len(dashboardUIDs) == 0 {
+		dashboardUIDs = []string{""}
+	}
+
+	annotation := grafanaAnnotation{
+		Tags: g.tags(t),
+		Text: grafanaText(t),
+	}
+	if !t.Time.IsZero() {
+		annotation.Time = t.Time.UnixMilli()
+	}
+
+	var failed []string
+	for _, uid := range dashboardUIDs {
+		annotation.DashboardUID = uid
+		err = g.post(annotation, token)
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create grafana annotations: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// post sends a single annotation to the Grafana annotations API
+func (g *GrafanaNotifier) post(annotation grafanaAnnotation, token string) error {
+	b, err := json.Marshal(annotation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal grafana annotation: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(g.config.URL, "/")+grafanaAnnotationsPath, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create grafana request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send grafana annotation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("grafana annotations api returned status code %d for dashboard %q", resp.StatusCode, annotation.DashboardUID)
+	}
+	return nil
+}
+
+// apiToken returns the configured API token.  The token file is read on each call so that a rotated secret is
+// picked up without restarting Kuberhealthy.
+func (g *GrafanaNotifier) apiToken() (string, error) {
+	if len(g.config.APITokenFile) == 0 {
+		return g.config.APIToken, nil
+	}
+	b, err := os.ReadFile(g.config.APITokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read grafana api token file %s: %w", g.config.APITokenFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// tags returns the tags of the annotation for the transition.  Every annotation is tagged with kuberhealthy, the
+// namespace and name of the check, its cluster and environment and whether it failed or recovered, along with the
+// configured tags and the tags of the khcheck.
+func (g *GrafanaNotifier) tags(t Transition) []string {
+	event := "failure"
+	if t.OK {
+		event = "recovery"
+	}
+	tags := []string{"kuberhealthy", t.Namespace, t.CheckName, event}
+	if len(t.Cluster) > 0 {
+		tags = append(tags, t.Cluster)
+	}
+	if len(t.Environment) > 0 {
+		tags = append(tags, t.Environment)
+	}
+	tags = append(tags, g.config.Tags...)
+	return append(tags, splitAnnotationList(t.Annotations[GrafanaTagsAnnotation])...)
+}
+
+// grafanaText returns the text of the annotation for the transition
+func grafanaText(t Transition) string {
+	if t.OK {
+		return fmt.Sprintf("Kuberhealthy check %s in namespace %s%s recovered", t.CheckName, t.Namespace, clusterSuffix(t))
+	}
+	text := fmt.Sprintf("Kuberhealthy check %s in namespace %s%s is failing", t.CheckName, t.Namespace, clusterSuffix(t))
+	if len(t.Errors) > 0 {
+		text += ": " + strings.Join(t.Errors, "; ")
+	}
+	return text
+}
+
+// splitAnnotationList splits the comma separated values of an annotation, dropping blank values
+func splitAnnotationList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
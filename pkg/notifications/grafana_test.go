@@ -0,0 +1,79 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrafanaNotify(t *testing.T) {
+	var received []grafanaAnnotation
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != grafanaAnnotationsPath {
+			t.Fatalf("Expected a request to %s but got %s", grafanaAnnotationsPath, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			t.Fatalf("Expected the api token to be sent but got %q", r.Header.Get("Authorization"))
+		}
+		var annotation grafanaAnnotation
+		err := json.NewDecoder(r.Body).Decode(&annotation)
+		if err != nil {
+			t.Fatal("Failed to decode grafana annotation:", err)
+		}
+		received = append(received, annotation)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewGrafanaNotifier(GrafanaConfig{URL: server.URL + "/", APIToken: "abc123", DashboardUIDs: []string{"abc", "def"}, Tags: []string{"prod"}})
+	transition := Transition{
+		CheckName: "deployment",
+		Namespace: "kuberhealthy",
+		Errors:    []string{"deployment did not become ready"},
+		Time:      time.Unix(1600000000, 0),
+	}
+
+	err := n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send grafana annotations:", err)
+	}
+	if len(received) != 2 || received[0].DashboardUID != "abc" || received[1].DashboardUID != "def" {
+		t.Fatalf("Expected an annotation on each configured dashboard but got %+v", received)
+	}
+	if received[0].Time != 1600000000000 {
+		t.Fatalf("Expected the annotation time in milliseconds but got %d", received[0].Time)
+	}
+	if strings.Join(received[0].Tags, ",") != "kuberhealthy,kuberhealthy,deployment,failure,prod" {
+		t.Fatalf("Expected the failure and configured tags but got %v", received[0].Tags)
+	}
+	if !strings.Contains(received[0].Text, "deployment did not become ready") {
+		t.Fatalf("Expected the annotation text to hold the errors but got %q", received[0].Text)
+	}
+
+	// the annotations of the khcheck override the dashboards and add tags
+	received = nil
+	transition.OK = true
+	transition.Errors = nil
+	transition.Annotations = map[string]string{GrafanaDashboardUIDsAnnotation: "xyz", GrafanaTagsAnnotation: "team-a, ,payments"}
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send grafana annotation:", err)
+	}
+	if len(received) != 1 || received[0].DashboardUID != "xyz" {
+		t.Fatalf("Expected a single annotation on the dashboard of the khcheck but got %+v", received)
+	}
+	if strings.Join(received[0].Tags, ",") != "kuberhealthy,kuberhealthy,deployment,recovery,prod,team-a,payments" {
+		t.Fatalf("Expected the recovery and khcheck tags but got %v", received[0].Tags)
+	}
+}
+
+func TestGrafanaNotifyDisabled(t *testing.T) {
+	n := NewGrafanaNotifier(GrafanaConfig{})
+	err := n.Notify(Transition{CheckName: "deployment", Namespace: "kuberhealthy"})
+	if err != nil {
+		t.Fatal("Expected no error when grafana is not configured but got:", err)
+	}
+}
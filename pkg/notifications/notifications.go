@@ -0,0 +1,166 @@
+// Package notifications sends notifications to external services when a Kuberhealthy check changes between
+// OK and failing states.
+package notifications // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/notifications"
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultTimeout is the time allowed for a single notification to be delivered
+const defaultTimeout = time.Second * 10
+
+// Transition describes a check or job changing between OK and failing states
+type Transition struct {
+	CheckName     string            // the name of the khcheck or khjob
+	Namespace     string            // the namespace of the khcheck or khjob
+	OK            bool              // the new state of the check
+	Errors        []string          // the errors reported by the check, if it is failing
+	PodName       string            // the name of the checker pod that reported the new state
+	RunUUID       string            // the UUID of the run that reported the new state
+	RunDuration   time.Duration     // how long the run that reported the new state took
+	FailureReason string            // why the run failed, such as ReportedFailure or Timeout
+	Severity      string            // the severity of the khcheck: critical, warning or info
+	Time          time.Time         // the time the transition was seen
+	Annotations   map[string]string // the annotations of the khcheck or khjob, used for per-check overrides
+	Labels        map[string]string // the labels of the khcheck or khjob, used for routing
+	CheckLabels   map[string]string // the labels and annotations of the khcheck selected with the checkLabels setting, keyed by Prometheus label name
+	Cluster       string            // the name of the cluster that the check runs in, when one is configured
+	Environment   string            // the environment of the cluster, such as production, when one is configured
+}
+
+// clusterSuffix describes the cluster and environment of a transition for notification messages, such as
+// " in cluster prod-east (production)".  It is blank when no cluster name or environment is configured.
+func clusterSuffix(t Transition) string {
+	switch {
+	case len(t.Cluster) > 0 && len(t.Environment) > 0:
+		return " in cluster " + t.Cluster + " (" + t.Environment + ")"
+	case len(t.Cluster) > 0:
+		return " in cluster " + t.Cluster
+	case len(t.Environment) > 0:
+		return " in environment " + t.Environment
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of the supplied map in order, so that labels are listed the same way each time
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkKey returns the key shared by all notifications of a check, such as kuberhealthy/prod-east/kuberhealthy/dns.
+// The cluster name is part of the key when one is configured, so that the same check in several clusters is kept
+// apart by services that deduplicate notifications.
+func checkKey(t Transition) string {
+	if len(t.Cluster) > 0 {
+		return "kuberhealthy/" + t.Cluster + "/" + t.Namespace + "/" + t.CheckName
+	}
+	return "kuberhealthy/" + t.Namespace + "/" + t.CheckName
+}
+
+// allowedURL indicates if the supplied URL starts with one of the supplied URL prefixes, such as
+// https://hooks.slack.com/services/T0123ABCD/.  The scheme and host of the URL must match those of a prefix exactly,
+// and URLs with credentials or dot segments in their path are never allowed, so that a URL can not climb out of a
+// prefix.
+func allowedURL(rawURL string, prefixes []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User != nil || len(u.Host) == 0 {
+		return false
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == ".." || segment == "." {
+			return false
+		}
+	}
+	for _, prefix := range prefixes {
+		p, err := url.Parse(prefix)
+		if err != nil || len(p.Host) == 0 {
+			continue
+		}
+		if strings.EqualFold(u.Scheme, p.Scheme) && strings.EqualFold(u.Host, p.Host) && strings.HasPrefix(u.Path, p.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier is implemented by notification sinks that can be told about check state transitions
+type Notifier interface {
+	Name() string
+	Notify(t Transition) error
+}
+
+// Refresher is implemented by notification sinks whose notifications expire unless they are sent again while a
+// check keeps failing
+type Refresher interface {
+	Refresh(t Transition) error
+}
+
+// Config holds the configuration of all notification sinks
+type Config struct {
+	Slack        SlackConfig        `yaml:"slack,omitempty"`        // settings for posting notifications to a Slack webhook
+	Teams        TeamsConfig        `yaml:"teams,omitempty"`        // settings for posting notifications to a Microsoft Teams webhook
+	SMTP         SMTPConfig         `yaml:"smtp,omitempty"`         // settings for emailing notifications
+	PagerDuty    PagerDutyConfig    `yaml:"pagerDuty,omitempty"`    // settings for opening and resolving PagerDuty incidents
+	Opsgenie     OpsgenieConfig     `yaml:"opsgenie,omitempty"`     // settings for creating and closing Opsgenie alerts
+	Grafana      GrafanaConfig      `yaml:"grafana,omitempty"`      // settings for creating Grafana annotations
+	Alertmanager AlertmanagerConfig `yaml:"alertmanager,omitempty"` // settings for pushing alerts to Alertmanager
+	Webhooks     []WebhookConfig    `yaml:"webhooks,omitempty"`     // outbound webhooks with templated bodies
+	Receivers    []Receiver         `yaml:"receivers,omitempty"`    // named sets of sinks that routes send notifications to
+	Routes       []Route            `yaml:"routes,omitempty"`       // rules that send the notifications of matching checks to receivers instead of the sinks above
+	Silences     []Silence          `yaml:"silences,omitempty"`     // windows during which the notifications of matching checks are not sent
+}
+
+// NewNotifiers creates all notification sinks from the supplied configuration.  Sinks that can be enabled
+// per check with annotations are always created and skip checks that have no settings.
+func NewNotifiers(config Config) []Notifier {
+	notifiers := []Notifier{
+		NewSlackNotifier(config.Slack),
+		NewTeamsNotifier(config.Teams),
+		sharedSMTPNotifier("global", config.SMTP),
+		NewPagerDutyNotifier(config.PagerDuty),
+		NewOpsgenieNotifier(config.Opsgenie),
+		NewGrafanaNotifier(config.Grafana),
+		NewAlertmanagerNotifier(config.Alertmanager),
+	}
+	for _, w := range config.Webhooks {
+		notifiers = append(notifiers, NewWebhookNotifier(w))
+	}
+	return notifiers
+}
+
+// Send delivers a transition to all of the supplied notifiers.  Errors are logged and do not stop delivery
+// to the remaining notifiers.
+func Send(notifiers []Notifier, t Transition) {
+	for _, n := range notifiers {
+		err := n.Notify(t)
+		if err != nil {
+			log.Errorln("notifications: failed to send", n.Name(), "notification for check", t.Namespace+"/"+t.CheckName+":", err)
+			continue
+		}
+	}
+}
+
+// SendRefresh delivers the state of a check that is still failing to all of the supplied notifiers that need to be
+// refreshed.  Errors are logged and do not stop delivery to the remaining notifiers.
+func SendRefresh(notifiers []Notifier, t Transition) {
+	for _, n := range notifiers {
+		r, ok := n.(Refresher)
+		if !ok {
+			continue
+		}
+		err := r.Refresh(t)
+		if err != nil {
+			log.Errorln("notifications: failed to refresh", n.Name(), "notification for check", t.Namespace+"/"+t.CheckName+":", err)
+		}
+	}
+}
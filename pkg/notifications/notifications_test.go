@@ -0,0 +1,44 @@
+package notifications
+
+import "testing"
+
+func TestClusterSuffix(t *testing.T) {
+	tests := map[string]Transition{
+		"":                                   {},
+		" in cluster prod-east":              {Cluster: "prod-east"},
+		" in environment production":         {Environment: "production"},
+		" in cluster prod-east (production)": {Cluster: "prod-east", Environment: "production"},
+	}
+	for expected, transition := range tests {
+		suffix := clusterSuffix(transition)
+		if suffix != expected {
+			t.Fatalf("Expected the suffix %q for %+v but got %q", expected, transition, suffix)
+		}
+	}
+	if checkKey(Transition{CheckName: "dns", Namespace: "kuberhealthy", Cluster: "prod-east"}) != "kuberhealthy/prod-east/kuberhealthy/dns" {
+		t.Fatal("Expected the check key to include the cluster name")
+	}
+}
+
+func TestAllowedURL(t *testing.T) {
+	prefixes := []string{"https://hooks.slack.com/services/T0123ABCD/", "not a url"}
+	tests := map[string]bool{
+		"https://hooks.slack.com/services/T0123ABCD/B01/xyz":          true,
+		"HTTPS://Hooks.Slack.com/services/T0123ABCD/B01/xyz":          true,
+		"https://hooks.slack.com/services/T9999/B01/xyz":              false,
+		"http://hooks.slack.com/services/T0123ABCD/B01/xyz":           false,
+		"https://hooks.slack.com.evil.example/services/T0123ABCD/B01": false,
+		"https://user@hooks.slack.com/services/T0123ABCD/B01":         false,
+		"https://hooks.slack.com/services/T0123ABCD/../T9999/B01/xyz": false,
+		"https://hooks.slack.com:8443/services/T0123ABCD/B01/xyz":     false,
+		"/services/T0123ABCD/B01/xyz":                                 false,
+	}
+	for u, expected := range tests {
+		if allowedURL(u, prefixes) != expected {
+			t.Fatalf("Expected allowedURL to return %t for %s", expected, u)
+		}
+	}
+	if allowedURL("https://hooks.slack.com/services/T0123ABCD/B01/xyz", nil) {
+		t.Fatal("Expected no URL to be allowed without any allowed URLs")
+	}
+}
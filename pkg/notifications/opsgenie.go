@@ -0,0 +1,216 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OpsgeniePriorityAnnotation is the khcheck annotation that overrides the Opsgenie priority for a single check
+const OpsgeniePriorityAnnotation = "comcast.github.io/opsgenie-priority"
+
+// defaultOpsgenieAPIURL is the Opsgenie API that alerts are sent to when none is configured
+const defaultOpsgenieAPIURL = "https://api.opsgenie.com"
+
+// defaultOpsgeniePriority is the priority of alerts for checks whose severity has no priority mapped to it
+const defaultOpsgeniePriority = "P3"
+
+// defaultOpsgeniePriorities map the severity of a khcheck to the priority of its alerts when no mapping is
+// configured
+var defaultOpsgeniePriorities = map[string]string{
+	"critical": "P1",
+	"warning":  "P3",
+	"info":     "P5",
+}
+
+// OpsgenieConfig holds the global settings for Opsgenie alerts
+type OpsgenieConfig struct {
+	APIKeyFile string            `yaml:"apiKeyFile,omitempty"` // a file holding the API key of an Opsgenie API integration, usually mounted from a secret. Opsgenie alerts are disabled when no API key is set
+	APIKey     string            `yaml:"apiKey,omitempty"`     // the API key. apiKeyFile should be preferred so that the key is not stored in the configmap
+	APIURL     string            `yaml:"apiURL,omitempty"`     // the Opsgenie API to send alerts to, such as https://api.eu.opsgenie.com. Defaults to https://api.opsgenie.com
+	Priorities map[string]string `yaml:"priorities,omitempty"` // the priority of alerts, P1 to P5, by khcheck severity. Defaults to critical: P1, warning: P3 and info: P5
+	Tags       []string          `yaml:"tags,omitempty"`       // tags added to every alert
+}
+
+// OpsgenieNotifier creates an Opsgenie alert when a check starts failing and closes it when the check recovers
+type OpsgenieNotifier struct {
+	config OpsgenieConfig
+	client *http.Client
+}
+
+// opsgenieAlert is the payload sent to the Opsgenie alert API to create an alert
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// opsgenieClose is the payload sent to the Opsgenie alert API to close an alert
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// NewOpsgenieNotifier creates an OpsgenieNotifier from the supplied configuration
+func NewOpsgenieNotifier(config OpsgenieConfig) *OpsgenieNotifier {
+	return &OpsgenieNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (o *OpsgenieNotifier) Name() string {
+	return "opsgenie"
+}
+
+// Notify creates an Opsgenie alert for a failing check or closes the alert of a recovered check.  Alerts for the
+// same check share an alias, so Opsgenie deduplicates them into a single open alert.  Nothing is sent if no API
+// key is configured.
+func (o *OpsgenieNotifier) Notify(t Transition) error {
+	apiKey, err := o.apiKey()
+	if err != nil {
+		return err
+	}
+	if len(apiKey) == 0 {
+		log.Debugln("notifications: no opsgenie api key configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	alias := opsgenieAlias(t)
+	if t.OK {
+		return o.post("/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", opsgenieClose{
+			Source: "kuberhealthy",
+			Note:   fmt.Sprintf("Kuberhealthy check %s in namespace %s%s has recovered", t.CheckName, t.Namespace, clusterSuffix(t)),
+		}, apiKey)
+	}
+
+	details := map[string]string{
+		"check":     t.CheckName,
+		"namespace": t.Namespace,
+	}
+	if len(t.PodName) > 0 {
+		details["checker_pod"] = t.PodName
+	}
+	if len(t.FailureReason) > 0 {
+		details["failure_reason"] = t.FailureReason
+	}
+	if len(t.Cluster) > 0 {
+		details["cluster"] = t.Cluster
+	}
+	if len(t.Environment) > 0 {
+		details["environment"] = t.Environment
+	}
+	for k, v := range t.CheckLabels {
+		if _, ok := details[k]; !ok {
+			details[k] = v
+		}
+	}
+	return o.post("/v2/alerts", opsgenieAlert{
+		Message:     fmt.Sprintf("Kuberhealthy check %s in namespace %s%s is failing", t.CheckName, t.Namespace, clusterSuffix(t)),
+		Alias:       alias,
+		Description: strings.Join(t.Errors, "\n"),
+		Priority:    o.priority(t),
+		Source:      "kuberhealthy",
+		Entity:      strings.TrimPrefix(checkKey(t), "kuberhealthy/"),
+		Tags:        append([]string{"kuberhealthy"}, o.config.Tags...),
+		Details:     details,
+	}, apiKey)
+}
+
+// post sends the supplied payload to a path of the Opsgenie API.  Opsgenie processes alert requests
+// asynchronously, so a successful response means the request was accepted.
+func (o *OpsgenieNotifier) post(path string, payload interface{}, apiKey string) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal opsgenie request: %w", err)
+	}
+
+	apiURL := o.config.APIURL
+	if len(apiURL) == 0 {
+		apiURL = defaultOpsgenieAPIURL
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(apiURL, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create opsgenie request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+apiKey)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send opsgenie request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("opsgenie alert api returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// apiKey returns the configured API key.  The API key file is read on each call so that a rotated secret is picked
+// up without restarting Kuberhealthy.
+func (o *OpsgenieNotifier) apiKey() (string, error) {
+	if len(o.config.APIKeyFile) == 0 {
+		return o.config.APIKey, nil
+	}
+	b, err := os.ReadFile(o.config.APIKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read opsgenie api key file %s: %w", o.config.APIKeyFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// priority returns the priority to create the alert of a transition with.  The priority annotation of the khcheck
+// takes precedence over the priority mapped from the severity of the check.  Invalid priorities fall back to the
+// default.
+func (o *OpsgenieNotifier) priority(t Transition) string {
+	severity := t.Severity
+	if len(severity) == 0 {
+		severity = "critical"
+	}
+
+	priorities := o.config.Priorities
+	if len(priorities) == 0 {
+		priorities = defaultOpsgeniePriorities
+	}
+	priority := priorities[severity]
+	if p, ok := t.Annotations[OpsgeniePriorityAnnotation]; ok && len(p) > 0 {
+		priority = p
+	}
+
+	priority = strings.ToUpper(priority)
+	if !validOpsgeniePriority(priority) {
+		if len(priority) > 0 {
+			log.Warningln("notifications: invalid opsgenie priority", priority, "for check", t.Namespace+"/"+t.CheckName+". Using", defaultOpsgeniePriority)
+		}
+		return defaultOpsgeniePriority
+	}
+	return priority
+}
+
+// validOpsgeniePriority indicates if the supplied priority is accepted by Opsgenie
+func validOpsgeniePriority(priority string) bool {
+	switch priority {
+	case "P1", "P2", "P3", "P4", "P5":
+		return true
+	}
+	return false
+}
+
+// opsgenieAlias returns the alias shared by all alerts of a check
+func opsgenieAlias(t Transition) string {
+	return checkKey(t)
+}
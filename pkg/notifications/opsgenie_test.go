@@ -0,0 +1,91 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpsgenieNotify(t *testing.T) {
+	var created opsgenieAlert
+	var closedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey abc123" {
+			t.Fatalf("Expected the api key to be sent but got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path == "/v2/alerts" {
+			err := json.NewDecoder(r.Body).Decode(&created)
+			if err != nil {
+				t.Fatal("Failed to decode opsgenie alert:", err)
+			}
+		} else {
+			closedPath = r.URL.EscapedPath() + "?" + r.URL.RawQuery
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	err := os.WriteFile(keyFile, []byte("abc123\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write api key file:", err)
+	}
+
+	n := NewOpsgenieNotifier(OpsgenieConfig{APIKeyFile: keyFile, APIURL: server.URL, Tags: []string{"cluster-a"}})
+	transition := Transition{
+		CheckName: "deployment",
+		Namespace: "kuberhealthy",
+		Errors:    []string{"deployment did not become ready"},
+		PodName:   "deployment-1600000000",
+		Severity:  "warning",
+	}
+
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to create opsgenie alert:", err)
+	}
+	if created.Alias != "kuberhealthy/kuberhealthy/deployment" || created.Priority != "P3" || created.Description != "deployment did not become ready" {
+		t.Fatalf("Unexpected opsgenie alert: %+v", created)
+	}
+	if len(created.Tags) != 2 || created.Tags[1] != "cluster-a" {
+		t.Fatalf("Expected the configured tags on the alert but got %v", created.Tags)
+	}
+
+	transition.OK = true
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to close opsgenie alert:", err)
+	}
+	expected := "/v2/alerts/kuberhealthy%2Fkuberhealthy%2Fdeployment/close?identifierType=alias"
+	if closedPath != expected {
+		t.Fatalf("Expected the alert to be closed at %s but got %s", expected, closedPath)
+	}
+}
+
+func TestOpsgeniePriority(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		config      OpsgenieConfig
+		severity    string
+		annotations map[string]string
+		expected    string
+	}{
+		{"critical by default", OpsgenieConfig{}, "", nil, "P1"},
+		{"info", OpsgenieConfig{}, "info", nil, "P5"},
+		{"configured mapping", OpsgenieConfig{Priorities: map[string]string{"critical": "p2"}}, "critical", nil, "P2"},
+		{"unmapped severity", OpsgenieConfig{Priorities: map[string]string{"critical": "P2"}}, "warning", nil, defaultOpsgeniePriority},
+		{"annotation", OpsgenieConfig{}, "info", map[string]string{OpsgeniePriorityAnnotation: "P2"}, "P2"},
+		{"invalid annotation", OpsgenieConfig{}, "info", map[string]string{OpsgeniePriorityAnnotation: "urgent"}, defaultOpsgeniePriority},
+	}
+
+	for _, tc := range testCases {
+		n := NewOpsgenieNotifier(tc.config)
+		priority := n.priority(Transition{Severity: tc.severity, Annotations: tc.annotations})
+		if priority != tc.expected {
+			t.Fatalf("%s: expected priority %s but got %s", tc.name, tc.expected, priority)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PagerDutySeverityAnnotation is the khcheck annotation that overrides the PagerDuty severity for a single check
+const PagerDutySeverityAnnotation = "comcast.github.io/pagerduty-severity"
+
+// defaultPagerDutyEventsURL is the PagerDuty Events API v2 endpoint that events are sent to
+const defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// defaultPagerDutySeverity is the severity of PagerDuty incidents when none is configured
+const defaultPagerDutySeverity = "error"
+
+// pagerDutySeverities are the severities accepted by the PagerDuty Events API v2
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// PagerDutyConfig holds the global settings for PagerDuty notifications
+type PagerDutyConfig struct {
+	RoutingKeyFile string `yaml:"routingKeyFile,omitempty"` // a file holding the integration routing key, usually mounted from a secret. PagerDuty notifications are disabled when no routing key is set
+	RoutingKey     string `yaml:"routingKey,omitempty"`     // the integration routing key. routingKeyFile should be preferred so that the key is not stored in the configmap
+	Severity       string `yaml:"severity,omitempty"`       // the severity of incidents: critical, error, warning or info. Defaults to error
+	EventsURL      string `yaml:"eventsURL,omitempty"`      // the Events API v2 endpoint to send events to. Defaults to the PagerDuty endpoint
+}
+
+// PagerDutyNotifier opens a PagerDuty incident when a check starts failing and resolves it when the check recovers
+type PagerDutyNotifier struct {
+	config PagerDutyConfig
+	client *http.Client
+}
+
+// pagerDutyEvent is the payload sent to the PagerDuty Events API v2
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// pagerDutyPayload describes the incident opened by a trigger event
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// NewPagerDutyNotifier creates a PagerDutyNotifier from the supplied configuration
+func NewPagerDutyNotifier(config PagerDutyConfig) *PagerDutyNotifier {
+	return &PagerDutyNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (p *PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// Notify triggers a PagerDuty incident for a failing check or resolves the incident of a recovered check.  Events
+// for the same check share a dedup key so that they act on a single incident.  Nothing is sent if no routing key
+// is configured.
+func (p *PagerDutyNotifier) Notify(t Transition) error {
+	routingKey, err := p.routingKey()
+	if err != nil {
+		return err
+	}
+	if len(routingKey) == 0 {
+		log.Debugln("notifications: no pagerduty routing key configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "resolve",
+		DedupKey:    pagerDutyDedupKey(t),
+	}
+	if !t.OK {
+		event.EventAction = "trigger"
+		event.Payload = &pagerDutyPayload{
+			Summary:   fmt.Sprintf("Kuberhealthy check %s in namespace %s%s is failing", t.CheckName, t.Namespace, clusterSuffix(t)),
+			Source:    strings.TrimPrefix(checkKey(t), "kuberhealthy/"),
+			Severity:  p.severity(t),
+			Component: t.CheckName,
+			Group:     t.Namespace,
+			CustomDetails: map[string]interface{}{
+				"errors":  t.Errors,
+				"checker": t.PodName,
+			},
+		}
+		if len(t.Cluster) > 0 {
+			event.Payload.CustomDetails["cluster"] = t.Cluster
+		}
+		if len(t.Environment) > 0 {
+			event.Payload.CustomDetails["environment"] = t.Environment
+		}
+		if len(t.CheckLabels) > 0 {
+			event.Payload.CustomDetails["labels"] = t.CheckLabels
+		}
+		if !t.Time.IsZero() {
+			event.Payload.Timestamp = t.Time.UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty event: %w", err)
+	}
+
+	eventsURL := p.config.EventsURL
+	if len(eventsURL) == 0 {
+		eventsURL = defaultPagerDutyEventsURL
+	}
+	resp, err := p.client.Post(eventsURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to send pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("pagerduty events api returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// routingKey returns the configured routing key.  The routing key file is read on each call so that a rotated
+// secret is picked up without restarting Kuberhealthy.
+func (p *PagerDutyNotifier) routingKey() (string, error) {
+	if len(p.config.RoutingKeyFile) == 0 {
+		return p.config.RoutingKey, nil
+	}
+	b, err := os.ReadFile(p.config.RoutingKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pagerduty routing key file %s: %w", p.config.RoutingKeyFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// severity returns the severity to open an incident with for the transition.  The severity annotation of the
+// khcheck takes precedence over the configured severity.  Invalid severities fall back to the default.
+func (p *PagerDutyNotifier) severity(t Transition) string {
+	severity := p.config.Severity
+	if s, ok := t.Annotations[PagerDutySeverityAnnotation]; ok && len(s) > 0 {
+		severity = s
+	}
+	if !ValidPagerDutySeverity(severity) {
+		if len(severity) > 0 {
+			log.Warningln("notifications: invalid pagerduty severity", severity, "for check", t.Namespace+"/"+t.CheckName+". Using", defaultPagerDutySeverity)
+		}
+		return defaultPagerDutySeverity
+	}
+	return severity
+}
+
+// ValidPagerDutySeverity indicates if the supplied severity is accepted by the PagerDuty Events API v2
+func ValidPagerDutySeverity(severity string) bool {
+	for _, s := range pagerDutySeverities {
+		if severity == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pagerDutyDedupKey returns the dedup key shared by all events of a check
+func pagerDutyDedupKey(t Transition) string {
+	return checkKey(t)
+}
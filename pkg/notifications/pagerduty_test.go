@@ -0,0 +1,91 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPagerDutyNotify(t *testing.T) {
+	var received []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil {
+			t.Fatal("Failed to decode pagerduty event:", err)
+		}
+		received = append(received, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "routing-key")
+	err := os.WriteFile(keyFile, []byte("abc123\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write routing key file:", err)
+	}
+
+	n := NewPagerDutyNotifier(PagerDutyConfig{RoutingKeyFile: keyFile, Severity: "warning", EventsURL: server.URL})
+	transition := Transition{
+		CheckName:   "deployment",
+		Namespace:   "kuberhealthy",
+		Errors:      []string{"deployment did not become ready"},
+		Annotations: map[string]string{PagerDutySeverityAnnotation: "critical"},
+	}
+
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send pagerduty trigger event:", err)
+	}
+	transition.OK = true
+	transition.Errors = nil
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send pagerduty resolve event:", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 pagerduty events but got %d", len(received))
+	}
+	trigger, resolve := received[0], received[1]
+	if trigger.EventAction != "trigger" || resolve.EventAction != "resolve" {
+		t.Fatalf("Expected a trigger and a resolve event but got %s and %s", trigger.EventAction, resolve.EventAction)
+	}
+	if trigger.RoutingKey != "abc123" {
+		t.Fatalf("Expected the routing key to be read from the file but got %q", trigger.RoutingKey)
+	}
+	if trigger.DedupKey != resolve.DedupKey {
+		t.Fatalf("Expected both events to share a dedup key but got %s and %s", trigger.DedupKey, resolve.DedupKey)
+	}
+	if trigger.Payload == nil || trigger.Payload.Severity != "critical" {
+		t.Fatal("Expected the severity annotation to override the configured severity")
+	}
+	if resolve.Payload != nil {
+		t.Fatal("Expected resolve events to have no payload")
+	}
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	n := NewPagerDutyNotifier(PagerDutyConfig{Severity: "warning"})
+
+	severity := n.severity(Transition{})
+	if severity != "warning" {
+		t.Fatalf("Expected the configured severity but got %s", severity)
+	}
+
+	severity = n.severity(Transition{Annotations: map[string]string{PagerDutySeverityAnnotation: "sev1"}})
+	if severity != defaultPagerDutySeverity {
+		t.Fatalf("Expected an invalid severity to fall back to %s but got %s", defaultPagerDutySeverity, severity)
+	}
+}
+
+func TestPagerDutyNotifyWithoutRoutingKey(t *testing.T) {
+	n := NewPagerDutyNotifier(PagerDutyConfig{})
+	err := n.Notify(Transition{CheckName: "deployment", Namespace: "kuberhealthy"})
+	if err != nil {
+		t.Fatal("Expected no error when no pagerduty routing key is configured:", err)
+	}
+}
@@ -0,0 +1,311 @@
+package notifications
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	log "github.com/sirupsen/logrus"
+)
+
+// Receiver is a named set of notification sinks that routes send transitions to, such as the Slack channel and
+// PagerDuty service of a single team.  Sinks that are not set are not notified.
+type Receiver struct {
+	Name         string              `yaml:"name"`                   // the name that routes refer to the receiver by
+	Slack        *SlackConfig        `yaml:"slack,omitempty"`        // settings for posting notifications to a Slack webhook
+	Teams        *TeamsConfig        `yaml:"teams,omitempty"`        // settings for posting notifications to a Microsoft Teams webhook
+	SMTP         *SMTPConfig         `yaml:"smtp,omitempty"`         // settings for emailing notifications
+	PagerDuty    *PagerDutyConfig    `yaml:"pagerDuty,omitempty"`    // settings for opening and resolving PagerDuty incidents
+	Opsgenie     *OpsgenieConfig     `yaml:"opsgenie,omitempty"`     // settings for creating and closing Opsgenie alerts
+	Grafana      *GrafanaConfig      `yaml:"grafana,omitempty"`      // settings for creating Grafana annotations
+	Alertmanager *AlertmanagerConfig `yaml:"alertmanager,omitempty"` // settings for pushing alerts to Alertmanager
+	Webhooks     []WebhookConfig     `yaml:"webhooks,omitempty"`     // outbound webhooks with templated bodies
+}
+
+// Route sends the transitions of the checks it matches to its receivers.  Routes are evaluated in order and the
+// first matching route wins, unless it is set to continue.
+type Route struct {
+	Match     Matcher  `yaml:"match,omitempty"`    // the checks that the route applies to. an empty matcher matches every check
+	Receivers []string `yaml:"receivers"`          // the names of the receivers that matching transitions are sent to
+	Continue  bool     `yaml:"continue,omitempty"` // set to true to keep evaluating the following routes after this one matches
+}
+
+// Silence stops notifications for the checks it matches while it is active.  A silence is either active between
+// startsAt and endsAt, or for duration after each start of a recurring cron schedule.
+type Silence struct {
+	Name     string        `yaml:"name,omitempty"`     // a name for the silence used in logs
+	Match    Matcher       `yaml:"match,omitempty"`    // the checks that are silenced. an empty matcher matches every check
+	StartsAt time.Time     `yaml:"startsAt,omitempty"` // when a one off silence starts
+	EndsAt   time.Time     `yaml:"endsAt,omitempty"`   // when a one off silence ends
+	Schedule string        `yaml:"schedule,omitempty"` // a cron expression for the start of each window of a recurring silence
+	Duration time.Duration `yaml:"duration,omitempty"` // how long each window of a recurring silence lasts
+}
+
+// Matcher selects checks by their namespace, name, labels and severity.  A check matches when it matches every
+// field that is set.
+type Matcher struct {
+	Namespaces []string          `yaml:"namespaces,omitempty"` // the namespaces of matching checks
+	CheckName  string            `yaml:"checkName,omitempty"`  // a regular expression that the whole name of matching checks matches
+	Labels     map[string]string `yaml:"labels,omitempty"`     // labels that matching checks have
+	Severities []string          `yaml:"severities,omitempty"` // the severities of matching checks: critical, warning or info
+}
+
+// matcherPatterns caches the compiled check name patterns of matchers
+var matcherPatterns = make(map[string]*regexp.Regexp)
+var matcherPatternsMu sync.Mutex
+
+// Matches indicates if the check of the supplied transition matches the matcher
+func (m Matcher) Matches(t Transition) (bool, error) {
+	if len(m.Namespaces) > 0 && !containsString(m.Namespaces, t.Namespace) {
+		return false, nil
+	}
+
+	if len(m.CheckName) > 0 {
+		re, err := compileCheckNamePattern(m.CheckName)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(t.CheckName) {
+			return false, nil
+		}
+	}
+
+	for k, v := range m.Labels {
+		if label, ok := t.Labels[k]; !ok || label != v {
+			return false, nil
+		}
+	}
+
+	if len(m.Severities) > 0 {
+		severity := t.Severity
+		if len(severity) == 0 {
+			severity = "critical"
+		}
+		if !containsString(m.Severities, severity) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// compileCheckNamePattern compiles a check name pattern so that it must match the whole name
+func compileCheckNamePattern(pattern string) (*regexp.Regexp, error) {
+	matcherPatternsMu.Lock()
+	defer matcherPatternsMu.Unlock()
+	if re, ok := matcherPatterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid check name pattern %q: %w", pattern, err)
+	}
+	matcherPatterns[pattern] = re
+	return re, nil
+}
+
+// Active indicates if the silence is active at the supplied time
+func (s Silence) Active(now time.Time) (bool, error) {
+	if len(s.Schedule) == 0 {
+		if s.StartsAt.IsZero() && s.EndsAt.IsZero() {
+			return false, nil
+		}
+		return !now.Before(s.StartsAt) && (s.EndsAt.IsZero() || now.Before(s.EndsAt)), nil
+	}
+
+	schedule, err := cronexpr.Parse(s.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("invalid schedule %q of silence %s: %w", s.Schedule, s.Name, err)
+	}
+	if s.Duration <= 0 {
+		return false, nil
+	}
+	// the first start of the window since it would have last been able to start
+	start := schedule.Next(now.Add(-s.Duration))
+	if start.IsZero() {
+		return false, nil
+	}
+	return !start.After(now), nil
+}
+
+// Validate checks the receivers, routes and silences of the configuration for mistakes that would stop
+// notifications from being routed
+func (c Config) Validate() error {
+	var problems []string
+
+	receivers := make(map[string]bool)
+	for _, r := range c.Receivers {
+		if len(r.Name) == 0 {
+			problems = append(problems, "a receiver has no name")
+		}
+		if receivers[r.Name] {
+			problems = append(problems, fmt.Sprintf("receiver %s is defined more than once", r.Name))
+		}
+		receivers[r.Name] = true
+	}
+
+	for i, route := range c.Routes {
+		if len(route.Receivers) == 0 {
+			problems = append(problems, fmt.Sprintf("route %d has no receivers", i))
+		}
+		for _, name := range route.Receivers {
+			if !receivers[name] {
+				problems = append(problems, fmt.Sprintf("route %d refers to unknown receiver %s", i, name))
+			}
+		}
+		if len(route.Match.CheckName) > 0 {
+			_, err := compileCheckNamePattern(route.Match.CheckName)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("route %d: %s", i, err))
+			}
+		}
+	}
+
+	for _, s := range c.Silences {
+		_, err := s.Active(time.Now())
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		if len(s.Match.CheckName) > 0 {
+			_, err = compileCheckNamePattern(s.Match.CheckName)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("silence %s: %s", s.Name, err))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid notification routing: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NeedsRefresh indicates if any of the configured sinks need the state of checks that keep failing to be sent
+// again, such as Alertmanager alerts that would otherwise expire
+func (c Config) NeedsRefresh() bool {
+	if len(c.Alertmanager.URLs) > 0 {
+		return true
+	}
+	for _, r := range c.Receivers {
+		if r.Alertmanager != nil && len(r.Alertmanager.URLs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteNotifiers returns the notification sinks that a transition should be sent to.  Nothing is returned while
+// the check is silenced.  Without routes, or when no route matches the check, the global sinks are returned.
+func RouteNotifiers(config Config, t Transition) []Notifier {
+	now := t.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for _, s := range config.Silences {
+		matches, err := s.Match.Matches(t)
+		if err != nil {
+			log.Errorln("notifications: failed to match silence", s.Name+":", err)
+			continue
+		}
+		if !matches {
+			continue
+		}
+		active, err := s.Active(now)
+		if err != nil {
+			log.Errorln("notifications:", err)
+			continue
+		}
+		if active {
+			log.Infoln("notifications: check", t.Namespace+"/"+t.CheckName, "is silenced by silence", s.Name)
+			return nil
+		}
+	}
+
+	var receiverNames []string
+	for i, route := range config.Routes {
+		matches, err := route.Match.Matches(t)
+		if err != nil {
+			log.Errorln("notifications: failed to match route", i, "for check", t.Namespace+"/"+t.CheckName+":", err)
+			continue
+		}
+		if !matches {
+			continue
+		}
+		for _, name := range route.Receivers {
+			if !containsString(receiverNames, name) {
+				receiverNames = append(receiverNames, name)
+			}
+		}
+		if !route.Continue {
+			break
+		}
+	}
+
+	if len(receiverNames) == 0 {
+		return NewNotifiers(config)
+	}
+
+	var notifiers []Notifier
+	for _, name := range receiverNames {
+		receiver, ok := findReceiver(config.Receivers, name)
+		if !ok {
+			log.Errorln("notifications: route for check", t.Namespace+"/"+t.CheckName, "refers to unknown receiver", name)
+			continue
+		}
+		notifiers = append(notifiers, receiver.notifiers()...)
+	}
+	return notifiers
+}
+
+// notifiers creates the notification sinks of the receiver
+func (r Receiver) notifiers() []Notifier {
+	var notifiers []Notifier
+	if r.Slack != nil {
+		notifiers = append(notifiers, NewSlackNotifier(*r.Slack))
+	}
+	if r.Teams != nil {
+		notifiers = append(notifiers, NewTeamsNotifier(*r.Teams))
+	}
+	if r.SMTP != nil {
+		notifiers = append(notifiers, sharedSMTPNotifier("receiver/"+r.Name, *r.SMTP))
+	}
+	if r.PagerDuty != nil {
+		notifiers = append(notifiers, NewPagerDutyNotifier(*r.PagerDuty))
+	}
+	if r.Opsgenie != nil {
+		notifiers = append(notifiers, NewOpsgenieNotifier(*r.Opsgenie))
+	}
+	if r.Grafana != nil {
+		notifiers = append(notifiers, NewGrafanaNotifier(*r.Grafana))
+	}
+	if r.Alertmanager != nil {
+		notifiers = append(notifiers, NewAlertmanagerNotifier(*r.Alertmanager))
+	}
+	for _, w := range r.Webhooks {
+		notifiers = append(notifiers, NewWebhookNotifier(w))
+	}
+	return notifiers
+}
+
+// findReceiver returns the receiver with the supplied name
+func findReceiver(receivers []Receiver, name string) (Receiver, bool) {
+	for _, r := range receivers {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Receiver{}, false
+}
+
+// containsString indicates if the supplied list contains the supplied string
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatcherMatches(t *testing.T) {
+	transition := Transition{
+		CheckName: "payments-api",
+		Namespace: "payments",
+		Labels:    map[string]string{"team": "payments", "tier": "1"},
+		Severity:  "warning",
+	}
+
+	var testCases = []struct {
+		name     string
+		matcher  Matcher
+		expected bool
+	}{
+		{"empty", Matcher{}, true},
+		{"namespace", Matcher{Namespaces: []string{"kuberhealthy", "payments"}}, true},
+		{"other namespace", Matcher{Namespaces: []string{"kuberhealthy"}}, false},
+		{"check name", Matcher{CheckName: "payments-.*"}, true},
+		{"partial check name", Matcher{CheckName: "payments"}, false},
+		{"labels", Matcher{Labels: map[string]string{"team": "payments"}}, true},
+		{"other labels", Matcher{Labels: map[string]string{"team": "search"}}, false},
+		{"severity", Matcher{Severities: []string{"warning", "info"}}, true},
+		{"other severity", Matcher{Severities: []string{"critical"}}, false},
+		{"every field", Matcher{Namespaces: []string{"payments"}, CheckName: ".*-api", Labels: map[string]string{"tier": "1"}, Severities: []string{"warning"}}, true},
+	}
+
+	for _, tc := range testCases {
+		matches, err := tc.matcher.Matches(transition)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if matches != tc.expected {
+			t.Fatalf("%s: expected match %t but got %t", tc.name, tc.expected, matches)
+		}
+	}
+
+	_, err := Matcher{CheckName: "("}.Matches(transition)
+	if err == nil {
+		t.Fatal("Expected an error for an invalid check name pattern")
+	}
+}
+
+func TestSilenceActive(t *testing.T) {
+	now := time.Date(2020, 9, 13, 2, 30, 0, 0, time.UTC)
+
+	var testCases = []struct {
+		name     string
+		silence  Silence
+		expected bool
+	}{
+		{"no window", Silence{}, false},
+		{"one off", Silence{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}, true},
+		{"one off ended", Silence{StartsAt: now.Add(-time.Hour * 2), EndsAt: now.Add(-time.Hour)}, false},
+		{"one off without end", Silence{StartsAt: now.Add(-time.Hour)}, true},
+		{"recurring", Silence{Schedule: "0 2 * * *", Duration: time.Hour}, true},
+		{"recurring closed", Silence{Schedule: "0 2 * * *", Duration: time.Minute * 15}, false},
+	}
+
+	for _, tc := range testCases {
+		active, err := tc.silence.Active(now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if active != tc.expected {
+			t.Fatalf("%s: expected active %t but got %t", tc.name, tc.expected, active)
+		}
+	}
+}
+
+func TestRouteNotifiers(t *testing.T) {
+	config := Config{
+		Slack: SlackConfig{WebhookURL: "http://slack.example.com/global"},
+		Receivers: []Receiver{
+			{Name: "payments", Slack: &SlackConfig{WebhookURL: "http://slack.example.com/payments"}, PagerDuty: &PagerDutyConfig{RoutingKey: "payments"}},
+			{Name: "audit", Webhooks: []WebhookConfig{{Name: "audit", URLs: []string{"http://audit.example.com"}}}},
+		},
+		Routes: []Route{
+			{Match: Matcher{Labels: map[string]string{"team": "payments"}}, Receivers: []string{"payments", "audit"}},
+			{Match: Matcher{Namespaces: []string{"payments"}}, Receivers: []string{"audit"}},
+		},
+		Silences: []Silence{
+			{Name: "migration", Match: Matcher{CheckName: "legacy-.*"}, StartsAt: time.Now().Add(-time.Hour)},
+		},
+	}
+	err := config.Validate()
+	if err != nil {
+		t.Fatal("Unexpected invalid config:", err)
+	}
+
+	names := func(notifiers []Notifier) []string {
+		var n []string
+		for _, notifier := range notifiers {
+			n = append(n, notifier.Name())
+		}
+		return n
+	}
+
+	routed := names(RouteNotifiers(config, Transition{CheckName: "api", Namespace: "payments", Labels: map[string]string{"team": "payments"}}))
+	if len(routed) != 3 || routed[0] != "slack" || routed[1] != "pagerduty" || routed[2] != "webhook audit" {
+		t.Fatalf("Expected the first matching route to send to its receivers but got %v", routed)
+	}
+
+	unrouted := RouteNotifiers(config, Transition{CheckName: "dns", Namespace: "kuberhealthy"})
+	if len(unrouted) != len(NewNotifiers(config)) {
+		t.Fatalf("Expected checks that match no route to use the global sinks but got %v", names(unrouted))
+	}
+
+	silenced := RouteNotifiers(config, Transition{CheckName: "legacy-db", Namespace: "payments"})
+	if len(silenced) != 0 {
+		t.Fatalf("Expected silenced checks to have no notifiers but got %v", names(silenced))
+	}
+
+	// continuing routes add the receivers of the following matching routes
+	config.Routes[0].Continue = true
+	config.Routes[0].Receivers = []string{"payments"}
+	routed = names(RouteNotifiers(config, Transition{CheckName: "api", Namespace: "payments", Labels: map[string]string{"team": "payments"}}))
+	if len(routed) != 3 || routed[2] != "webhook audit" {
+		t.Fatalf("Expected a continuing route to add the receivers of the next route but got %v", routed)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := Config{
+		Receivers: []Receiver{{Name: "a"}, {Name: "a"}},
+		Routes: []Route{
+			{Receivers: []string{"missing"}},
+			{Match: Matcher{CheckName: "("}, Receivers: []string{"a"}},
+		},
+		Silences: []Silence{{Name: "bad", Schedule: "not a schedule", Duration: time.Hour}},
+	}
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected an invalid routing config to fail validation")
+	}
+	for _, s := range []string{"receiver a is defined more than once", "unknown receiver missing", "invalid check name pattern", "invalid schedule"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Fatalf("Expected the validation error to mention %q but got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SlackWebhookURLAnnotation is the khcheck annotation that overrides the Slack webhook URL for a single check.  It is
+// only honored for URLs that start with one of the allowed webhook URLs of the Slack settings.
+const SlackWebhookURLAnnotation = "comcast.github.io/slack-webhook-url"
+
+// SlackChannelAnnotation is the khcheck annotation that overrides the Slack channel for a single check
+const SlackChannelAnnotation = "comcast.github.io/slack-channel"
+
+// SlackConfig holds the global settings for Slack notifications
+type SlackConfig struct {
+	WebhookURL         string   `yaml:"webhookURL,omitempty"`         // the incoming webhook URL to post to. Slack notifications are disabled when blank
+	Channel            string   `yaml:"channel,omitempty"`            // an optional channel that overrides the default channel of the webhook
+	Username           string   `yaml:"username,omitempty"`           // an optional username to post messages as
+	AllowedWebhookURLs []string `yaml:"allowedWebhookURLs,omitempty"` // the URL prefixes that the webhook URL annotation of a check may point at. the annotation is ignored when blank
+}
+
+// SlackNotifier posts check state transitions to a Slack incoming webhook
+type SlackNotifier struct {
+	config SlackConfig
+	client *http.Client
+}
+
+// slackMessage is the payload sent to a Slack incoming webhook
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// NewSlackNotifier creates a SlackNotifier from the supplied configuration
+func NewSlackNotifier(config SlackConfig) *SlackNotifier {
+	return &SlackNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify posts a message about the transition to Slack.  The webhook URL and channel can be overridden with
+// annotations on the khcheck, as long as the webhook URL is allowed.  Nothing is sent if no webhook URL is configured.
+func (s *SlackNotifier) Notify(t Transition) error {
+	webhookURL := s.config.WebhookURL
+	if url, ok := t.Annotations[SlackWebhookURLAnnotation]; ok && len(url) > 0 {
+		// anyone who can annotate a khcheck could otherwise make Kuberhealthy post to any URL
+		if allowedURL(url, s.config.AllowedWebhookURLs) {
+			webhookURL = url
+		} else {
+			log.Warningln("notifications: ignoring slack webhook URL of check", t.Namespace+"/"+t.CheckName, "because it is not an allowed webhook URL")
+		}
+	}
+	if len(webhookURL) == 0 {
+		log.Debugln("notifications: no slack webhook configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	channel := s.config.Channel
+	if c, ok := t.Annotations[SlackChannelAnnotation]; ok && len(c) > 0 {
+		channel = c
+	}
+
+	msg := slackMessage{
+		Text:     slackMessageText(t),
+		Channel:  channel,
+		Username: s.config.Username,
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	resp, err := s.client.Post(webhookURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to post to slack webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slack webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackMessageText formats the text of a Slack message for a transition
+func slackMessageText(t Transition) string {
+	var text string
+	if t.OK {
+		text = fmt.Sprintf(":large_green_circle: Kuberhealthy check *%s* in namespace *%s*%s has recovered", t.CheckName, t.Namespace, clusterSuffix(t))
+	} else {
+		text = fmt.Sprintf(":red_circle: Kuberhealthy check *%s* in namespace *%s*%s is failing", t.CheckName, t.Namespace, clusterSuffix(t))
+	}
+	if len(t.PodName) > 0 {
+		text += fmt.Sprintf(" (checker pod `%s`)", t.PodName)
+	}
+
+	if !t.OK && len(t.Errors) > 0 {
+		text += "\n• " + strings.Join(t.Errors, "\n• ")
+	}
+	return text
+}
@@ -0,0 +1,92 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// slackServer starts a server that sends the Slack messages posted to it on the returned channel
+func slackServer(t *testing.T) (*httptest.Server, chan slackMessage) {
+	received := make(chan slackMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		err := json.NewDecoder(r.Body).Decode(&msg)
+		if err != nil {
+			t.Error("Failed to decode slack message:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- msg
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestSlackNotify(t *testing.T) {
+	server, received := slackServer(t)
+
+	n := NewSlackNotifier(SlackConfig{WebhookURL: "http://127.0.0.1:1", Channel: "#global", AllowedWebhookURLs: []string{server.URL + "/services/"}})
+	transition := Transition{
+		CheckName: "deployment",
+		Namespace: "kuberhealthy",
+		Errors:    []string{"deployment did not become ready"},
+		PodName:   "deployment-1600000000",
+		Annotations: map[string]string{
+			SlackWebhookURLAnnotation: server.URL + "/services/team",
+			SlackChannelAnnotation:    "#team",
+		},
+	}
+
+	err := n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send slack notification:", err)
+	}
+	msg := <-received
+	if msg.Channel != "#team" {
+		t.Fatalf("Expected the channel annotation to override the channel but got %s", msg.Channel)
+	}
+	for _, s := range []string{"deployment-1600000000", "deployment did not become ready", "is failing"} {
+		if !strings.Contains(msg.Text, s) {
+			t.Fatalf("Expected slack message to contain %q but got %q", s, msg.Text)
+		}
+	}
+}
+
+// TestSlackNotifyDisallowedWebhook ensures that webhook URL annotations that are not allowed are ignored in favor of
+// the global webhook
+func TestSlackNotifyDisallowedWebhook(t *testing.T) {
+	global, received := slackServer(t)
+	other, otherReceived := slackServer(t)
+
+	n := NewSlackNotifier(SlackConfig{WebhookURL: global.URL + "/services/global", AllowedWebhookURLs: []string{"https://hooks.slack.com/services/"}})
+	transition := Transition{
+		CheckName:   "deployment",
+		Namespace:   "kuberhealthy",
+		Annotations: map[string]string{SlackWebhookURLAnnotation: other.URL + "/services/team"},
+	}
+	err := n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send slack notification:", err)
+	}
+	select {
+	case <-received:
+	default:
+		t.Fatal("Expected the notification to be sent to the global webhook")
+	}
+	select {
+	case <-otherReceived:
+		t.Fatal("Expected the webhook URL annotation to be ignored")
+	default:
+	}
+}
+
+func TestSlackNotifyWithoutWebhook(t *testing.T) {
+	n := NewSlackNotifier(SlackConfig{})
+	err := n.Notify(Transition{CheckName: "deployment", Namespace: "kuberhealthy", OK: true})
+	if err != nil {
+		t.Fatal("Expected no error when no slack webhook is configured:", err)
+	}
+}
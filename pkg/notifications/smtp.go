@@ -0,0 +1,417 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EmailRecipientsAnnotation is the khcheck annotation that overrides the email recipients for a single check.  It
+// holds a comma separated list of email addresses, of which only the allowed recipients of the SMTP settings are used.
+const EmailRecipientsAnnotation = "comcast.github.io/email-recipients"
+
+// defaultSMTPDigestInterval is how long transitions are collected into a digest email when none is configured
+const defaultSMTPDigestInterval = time.Minute
+
+// defaultSMTPMinRenotifyInterval is the least time between two emails about the same check when none is configured
+const defaultSMTPMinRenotifyInterval = time.Minute * 15
+
+// SMTP TLS modes
+const (
+	SMTPTLSModeStartTLS = "starttls" // upgrade a plain connection with STARTTLS, usually on port 587
+	SMTPTLSModeTLS      = "tls"      // connect over TLS, usually on port 465
+	SMTPTLSModeNone     = "none"     // send without encryption, only for relays on a trusted network
+)
+
+// SMTPConfig holds the global settings for email notifications
+type SMTPConfig struct {
+	Host                string        `yaml:"host,omitempty"`                // the SMTP server to send through. Email notifications are disabled when blank
+	Port                int           `yaml:"port,omitempty"`                // the port of the SMTP server. Defaults to 465 with the tls mode and 587 otherwise
+	TLSMode             string        `yaml:"tlsMode,omitempty"`             // starttls, tls or none. Defaults to starttls
+	InsecureSkipVerify  bool          `yaml:"insecureSkipVerify,omitempty"`  // set to true to skip verifying the certificate of the SMTP server
+	Username            string        `yaml:"username,omitempty"`            // the username to authenticate with. No authentication is attempted when blank
+	PasswordFile        string        `yaml:"passwordFile,omitempty"`        // a file holding the password to authenticate with, usually mounted from a secret
+	From                string        `yaml:"from,omitempty"`                // the sender address of emails
+	To                  []string      `yaml:"to,omitempty"`                  // the recipients of emails about checks that do not set their own
+	AllowedRecipients   []string      `yaml:"allowedRecipients,omitempty"`   // the addresses, or domains such as example.com, that checks may set as their recipients. recipients set by checks are ignored when blank
+	DigestInterval      time.Duration `yaml:"digestInterval,omitempty"`      // how long transitions are collected before they are sent together in a digest. Defaults to 1m
+	MinRenotifyInterval time.Duration `yaml:"minRenotifyInterval,omitempty"` // the least time between two emails about the same check. Defaults to 15m
+}
+
+// SMTPNotifier emails check state transitions.  Transitions are collected for the digest interval and sent to each
+// set of recipients as a single email, and a check that changes state again before the minimum re-notification
+// interval has passed is held back until it has, so that flapping checks do not flood inboxes.  The pending digest
+// and the time each check was last emailed about are only kept in memory, so every Kuberhealthy instance keeps its
+// own, and they are lost when the instance restarts.
+type SMTPNotifier struct {
+	mu       sync.Mutex
+	config   SMTPConfig
+	pending  map[string]*emailBatch    // transitions waiting to be sent, by recipients
+	lastSent map[string]sentEmailState // the last state emailed about each check, by recipients and check
+	timer    *time.Timer               // fires when the pending transitions should be sent
+	send     func(config SMTPConfig, to []string, msg []byte) error
+	now      func() time.Time
+}
+
+// emailBatch holds the transitions waiting to be sent to a single set of recipients, by check
+type emailBatch struct {
+	recipients  []string
+	transitions map[string]Transition
+}
+
+// sentEmailState records when recipients were last emailed about a check and the state they were told about
+type sentEmailState struct {
+	time time.Time
+	ok   bool
+}
+
+// smtpNotifiers are shared by every call to NewNotifiers, because they keep the pending digest and the time each
+// check was last emailed about between transitions.  The global notifier and the notifier of each receiver are kept
+// apart by key.
+var smtpNotifiers = make(map[string]*SMTPNotifier)
+var smtpNotifiersMu sync.Mutex
+
+// sharedSMTPNotifier returns the shared SMTPNotifier with the supplied key and configuration
+func sharedSMTPNotifier(key string, config SMTPConfig) *SMTPNotifier {
+	smtpNotifiersMu.Lock()
+	defer smtpNotifiersMu.Unlock()
+	n, ok := smtpNotifiers[key]
+	if !ok {
+		n = NewSMTPNotifier(config)
+		smtpNotifiers[key] = n
+		return n
+	}
+	n.mu.Lock()
+	n.config = config
+	n.mu.Unlock()
+	return n
+}
+
+// NewSMTPNotifier creates an SMTPNotifier from the supplied configuration
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{
+		config:   config,
+		pending:  make(map[string]*emailBatch),
+		lastSent: make(map[string]sentEmailState),
+		send:     sendSMTPMail,
+		now:      time.Now,
+	}
+}
+
+// Name returns the name of this notifier
+func (s *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify queues the transition to be emailed with the next digest.  The recipients can be overridden with an
+// annotation on the khcheck, as long as they are allowed recipients.  Nothing is sent if no SMTP server or recipients are configured.  Errors sending the
+// digest are logged when it is sent.
+func (s *SMTPNotifier) Notify(t Transition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.config.Host) == 0 {
+		log.Debugln("notifications: no smtp server configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+	recipients := s.config.To
+	if r := s.allowedRecipients(t, splitAnnotationList(t.Annotations[EmailRecipientsAnnotation])); len(r) > 0 {
+		recipients = r
+	}
+	if len(recipients) == 0 {
+		log.Debugln("notifications: no email recipients configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+	recipients = append([]string{}, recipients...)
+	sort.Strings(recipients)
+
+	key := strings.Join(recipients, ",")
+	batch, ok := s.pending[key]
+	if !ok {
+		batch = &emailBatch{recipients: recipients, transitions: make(map[string]Transition)}
+		s.pending[key] = batch
+	}
+	// only the latest state of a check is sent
+	batch.transitions[t.Namespace+"/"+t.CheckName] = t
+
+	interval := s.config.DigestInterval
+	if interval <= 0 {
+		interval = defaultSMTPDigestInterval
+	}
+	s.scheduleFlush(interval)
+	return nil
+}
+
+// allowedRecipients returns the supplied recipients of a transition that are allowed by the configuration.  Any
+// recipient that is not allowed is logged and left out, because anyone who can annotate a khcheck could otherwise
+// have check details emailed to any address.
+func (s *SMTPNotifier) allowedRecipients(t Transition, recipients []string) []string {
+	var allowed []string
+	for _, r := range recipients {
+		if !allowedRecipient(r, s.config.AllowedRecipients) {
+			log.Warningln("notifications: ignoring email recipient", r, "of check", t.Namespace+"/"+t.CheckName, "because it is not an allowed recipient")
+			continue
+		}
+		allowed = append(allowed, r)
+	}
+	return allowed
+}
+
+// allowedRecipient indicates if the supplied email address is one of the allowed addresses, or is in one of the
+// allowed domains.  Domains may be listed with or without a leading @.
+func allowedRecipient(address string, allowed []string) bool {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return false
+	}
+	domain := address[at+1:]
+	for _, a := range allowed {
+		a = strings.TrimSpace(a)
+		switch {
+		case strings.HasPrefix(a, "@"):
+			if strings.EqualFold(domain, a[1:]) {
+				return true
+			}
+		case strings.Contains(a, "@"):
+			if strings.EqualFold(address, a) {
+				return true
+			}
+		case len(a) > 0:
+			if strings.EqualFold(domain, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scheduleFlush sends the pending transitions after the supplied wait, unless they are already scheduled to be
+// sent.  The caller must hold the lock.
+func (s *SMTPNotifier) scheduleFlush(wait time.Duration) {
+	if s.timer != nil {
+		return
+	}
+	s.timer = time.AfterFunc(wait, s.flush)
+}
+
+// flush emails every pending transition whose check has not been emailed about within the minimum re-notification
+// interval.  Transitions that are held back are sent once the interval has passed, and transitions that return a
+// check to the state its recipients were last told about are dropped.
+func (s *SMTPNotifier) flush() {
+	s.mu.Lock()
+	s.timer = nil
+	config := s.config
+	minInterval := config.MinRenotifyInterval
+	if minInterval <= 0 {
+		minInterval = defaultSMTPMinRenotifyInterval
+	}
+	now := s.now()
+
+	type email struct {
+		recipients  []string
+		transitions []Transition
+	}
+	var emails []email
+	var nextFlush time.Duration
+	for key, batch := range s.pending {
+		var ready []Transition
+		for check, t := range batch.transitions {
+			sentKey := key + "|" + check
+			last, sent := s.lastSent[sentKey]
+			if sent && last.ok == t.OK {
+				delete(batch.transitions, check)
+				continue
+			}
+			if sent && now.Sub(last.time) < minInterval {
+				wait := last.time.Add(minInterval).Sub(now)
+				if nextFlush == 0 || wait < nextFlush {
+					nextFlush = wait
+				}
+				continue
+			}
+			ready = append(ready, t)
+			s.lastSent[sentKey] = sentEmailState{time: now, ok: t.OK}
+			delete(batch.transitions, check)
+		}
+		if len(batch.transitions) == 0 {
+			delete(s.pending, key)
+		}
+		if len(ready) > 0 {
+			sort.Slice(ready, func(i, j int) bool {
+				return ready[i].Namespace+"/"+ready[i].CheckName < ready[j].Namespace+"/"+ready[j].CheckName
+			})
+			emails = append(emails, email{recipients: batch.recipients, transitions: ready})
+		}
+	}
+	if nextFlush > 0 {
+		s.scheduleFlush(nextFlush)
+	}
+	send := s.send
+	s.mu.Unlock()
+
+	for _, e := range emails {
+		err := send(config, e.recipients, emailMessage(config.From, e.recipients, e.transitions, now))
+		if err != nil {
+			log.Errorln("notifications: failed to send email to", strings.Join(e.recipients, ", ")+":", err)
+		}
+	}
+}
+
+// emailSubjectTag returns the tag that email subjects start with, which names the cluster when one is configured
+func emailSubjectTag(t Transition) string {
+	if len(t.Cluster) > 0 {
+		return "[Kuberhealthy " + t.Cluster + "]"
+	}
+	return "[Kuberhealthy]"
+}
+
+// emailMessage builds the email for the supplied transitions.  A single transition gets an email of its own, and
+// several are sent as a digest.
+func emailMessage(from string, to []string, transitions []Transition, now time.Time) []byte {
+	var subject string
+	if len(transitions) == 1 {
+		t := transitions[0]
+		subject = fmt.Sprintf("%s %s/%s is failing", emailSubjectTag(t), t.Namespace, t.CheckName)
+		if t.OK {
+			subject = fmt.Sprintf("%s %s/%s has recovered", emailSubjectTag(t), t.Namespace, t.CheckName)
+		}
+	} else {
+		var failing int
+		for _, t := range transitions {
+			if !t.OK {
+				failing++
+			}
+		}
+		subject = fmt.Sprintf("%s %d checks changed state (%d failing, %d recovered)", emailSubjectTag(transitions[0]), len(transitions), failing, len(transitions)-failing)
+	}
+
+	var body strings.Builder
+	for _, t := range transitions {
+		if t.OK {
+			fmt.Fprintf(&body, "RECOVERED: Kuberhealthy check %s in namespace %s%s has recovered\r\n", t.CheckName, t.Namespace, clusterSuffix(t))
+		} else {
+			fmt.Fprintf(&body, "FAILING: Kuberhealthy check %s in namespace %s%s is failing\r\n", t.CheckName, t.Namespace, clusterSuffix(t))
+		}
+		if len(t.PodName) > 0 {
+			fmt.Fprintf(&body, "Checker pod: %s\r\n", t.PodName)
+		}
+		if !t.OK {
+			for _, e := range t.Errors {
+				fmt.Fprintf(&body, "  - %s\r\n", strings.ReplaceAll(e, "\n", "\r\n    "))
+			}
+		}
+		body.WriteString("\r\n")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body.String())
+	return msg.Bytes()
+}
+
+// sendSMTPMail delivers a message to the supplied recipients through the configured SMTP server
+func sendSMTPMail(config SMTPConfig, to []string, msg []byte) error {
+	mode := strings.ToLower(config.TLSMode)
+	if len(mode) == 0 {
+		mode = SMTPTLSModeStartTLS
+	}
+	port := config.Port
+	if port == 0 {
+		port = 587
+		if mode == SMTPTLSModeTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.InsecureSkipVerify}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: defaultTimeout}
+	switch mode {
+	case SMTPTLSModeTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case SMTPTLSModeStartTLS, SMTPTLSModeNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return fmt.Errorf("unknown smtp tls mode %q", config.TLSMode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server %s: %w", addr, err)
+	}
+	err = conn.SetDeadline(time.Now().Add(defaultTimeout * 3))
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set smtp connection deadline: %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session with %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if mode == SMTPTLSModeStartTLS {
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to start tls with smtp server %s: %w", addr, err)
+		}
+	}
+
+	if len(config.Username) > 0 {
+		var password string
+		if len(config.PasswordFile) > 0 {
+			// the password is read on each email so that a rotated secret is picked up without restarting Kuberhealthy
+			b, err := os.ReadFile(config.PasswordFile)
+			if err != nil {
+				return fmt.Errorf("failed to read smtp password file %s: %w", config.PasswordFile, err)
+			}
+			password = strings.TrimSpace(string(b))
+		}
+		err = c.Auth(smtp.PlainAuth("", config.Username, password, config.Host))
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server %s: %w", addr, err)
+		}
+	}
+
+	err = c.Mail(config.From)
+	if err != nil {
+		return fmt.Errorf("smtp server rejected sender %s: %w", config.From, err)
+	}
+	for _, r := range to {
+		err = c.Rcpt(r)
+		if err != nil {
+			return fmt.Errorf("smtp server rejected recipient %s: %w", r, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start sending email: %w", err)
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return c.Quit()
+}
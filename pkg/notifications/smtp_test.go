@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentEmail is an email captured by the smtp tests instead of being sent
+type sentEmail struct {
+	to  []string
+	msg string
+}
+
+// newTestSMTPNotifier creates an SMTPNotifier that captures emails instead of sending them and whose clock can be
+// moved by the test
+func newTestSMTPNotifier(now *time.Time, sent *[]sentEmail) *SMTPNotifier {
+	s := NewSMTPNotifier(SMTPConfig{
+		Host:              "smtp.example.com",
+		From:              "kuberhealthy@example.com",
+		To:                []string{"sre@example.com"},
+		AllowedRecipients: []string{"example.com"},
+		DigestInterval:    time.Hour,
+	})
+	s.now = func() time.Time { return *now }
+	s.send = func(config SMTPConfig, to []string, msg []byte) error {
+		*sent = append(*sent, sentEmail{to: to, msg: string(msg)})
+		return nil
+	}
+	return s
+}
+
+func TestSMTPDigest(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	var sent []sentEmail
+	s := newTestSMTPNotifier(&now, &sent)
+
+	for _, name := range []string{"dns", "deployment"} {
+		err := s.Notify(Transition{CheckName: name, Namespace: "kuberhealthy", Errors: []string{name + " failed"}})
+		if err != nil {
+			t.Fatal("Failed to queue email:", err)
+		}
+	}
+	err := s.Notify(Transition{
+		CheckName:   "payments",
+		Namespace:   "payments",
+		Errors:      []string{"payments failed"},
+		Annotations: map[string]string{EmailRecipientsAnnotation: "payments@example.com, oncall@example.com"},
+	})
+	if err != nil {
+		t.Fatal("Failed to queue email:", err)
+	}
+	s.flush()
+
+	if len(sent) != 2 {
+		t.Fatalf("Expected one email for each set of recipients but got %d", len(sent))
+	}
+	for _, e := range sent {
+		switch strings.Join(e.to, ",") {
+		case "sre@example.com":
+			for _, s := range []string{"Subject: [Kuberhealthy] 2 checks changed state (2 failing, 0 recovered)", "dns failed", "deployment failed"} {
+				if !strings.Contains(e.msg, s) {
+					t.Fatalf("Expected the digest to contain %q but got:\n%s", s, e.msg)
+				}
+			}
+		case "oncall@example.com,payments@example.com":
+			if !strings.Contains(e.msg, "Subject: [Kuberhealthy] payments/payments is failing") {
+				t.Fatalf("Unexpected email for annotated recipients:\n%s", e.msg)
+			}
+		default:
+			t.Fatalf("Unexpected recipients %v", e.to)
+		}
+	}
+}
+
+func TestSMTPMinRenotifyInterval(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	var sent []sentEmail
+	s := newTestSMTPNotifier(&now, &sent)
+
+	failing := Transition{CheckName: "dns", Namespace: "kuberhealthy", Errors: []string{"lookup failed"}}
+	recovered := Transition{CheckName: "dns", Namespace: "kuberhealthy", OK: true}
+
+	_ = s.Notify(failing)
+	s.flush()
+	if len(sent) != 1 {
+		t.Fatalf("Expected the first failure to be sent but got %d emails", len(sent))
+	}
+
+	// a recovery soon after the failure is held back
+	now = now.Add(time.Minute)
+	_ = s.Notify(recovered)
+	s.flush()
+	if len(sent) != 1 {
+		t.Fatalf("Expected the recovery to be held back but got %d emails", len(sent))
+	}
+
+	// a check that fails again before the recovery is sent is back in the state its recipients know about
+	_ = s.Notify(failing)
+	now = now.Add(time.Minute * 15)
+	s.flush()
+	if len(sent) != 1 {
+		t.Fatalf("Expected the flapping check to not be sent but got %d emails", len(sent))
+	}
+
+	// a recovery after the interval is sent
+	_ = s.Notify(recovered)
+	s.flush()
+	if len(sent) != 2 || !strings.Contains(sent[1].msg, "Subject: [Kuberhealthy] kuberhealthy/dns has recovered") {
+		t.Fatalf("Expected the recovery to be sent but got %+v", sent)
+	}
+}
+
+func TestSMTPNotConfigured(t *testing.T) {
+	s := NewSMTPNotifier(SMTPConfig{})
+	err := s.Notify(Transition{CheckName: "dns", Namespace: "kuberhealthy"})
+	if err != nil {
+		t.Fatal("Unexpected error when smtp is not configured:", err)
+	}
+	if len(s.pending) != 0 {
+		t.Fatal("Expected nothing to be queued when smtp is not configured")
+	}
+}
+
+// TestSMTPAllowedRecipients ensures that only allowed recipients set by checks are emailed
+func TestSMTPAllowedRecipients(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	var sent []sentEmail
+	s := newTestSMTPNotifier(&now, &sent)
+	s.config.AllowedRecipients = []string{"@payments.example.com", "oncall@example.org"}
+
+	tests := map[string]string{
+		"team@payments.example.com, attacker@example.net": "team@payments.example.com",
+		"ONCALL@example.org, other@example.org":           "ONCALL@example.org",
+		"attacker@example.net":                            "sre@example.com",
+		"team@evil.payments.example.com":                  "sre@example.com",
+	}
+	for annotation, expected := range tests {
+		sent = nil
+		err := s.Notify(Transition{
+			CheckName:   "payments",
+			Namespace:   "payments",
+			Annotations: map[string]string{EmailRecipientsAnnotation: annotation},
+		})
+		if err != nil {
+			t.Fatal("Failed to queue email:", err)
+		}
+		s.flush()
+		s.lastSent = make(map[string]sentEmailState)
+		if len(sent) != 1 || strings.Join(sent[0].to, ",") != expected {
+			t.Fatalf("Expected the recipients %q for the annotation %q but got %+v", expected, annotation, sent)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TeamsWebhookURLAnnotation is the khcheck annotation that overrides the Microsoft Teams webhook URL for a single
+// check.  It is only honored for URLs that start with one of the allowed webhook URLs of the Teams settings.
+const TeamsWebhookURLAnnotation = "comcast.github.io/teams-webhook-url"
+
+// TeamsDisabledAnnotation is the khcheck annotation that opts a single check out of Microsoft Teams notifications
+// when set to true
+const TeamsDisabledAnnotation = "comcast.github.io/teams-disabled"
+
+// TeamsConfig holds the global settings for Microsoft Teams notifications
+type TeamsConfig struct {
+	WebhookURLFile     string   `yaml:"webhookURLFile,omitempty"`     // a file holding the Teams webhook URL, usually mounted from a secret
+	WebhookURL         string   `yaml:"webhookURL,omitempty"`         // the Teams webhook URL. webhookURLFile should be preferred because the URL grants access to the channel. Teams notifications are disabled when neither is set
+	AllowedWebhookURLs []string `yaml:"allowedWebhookURLs,omitempty"` // the URL prefixes that the webhook URL annotation of a check may point at. the annotation is ignored when blank
+}
+
+// TeamsNotifier posts check state transitions to a Microsoft Teams webhook as Adaptive Cards
+type TeamsNotifier struct {
+	config TeamsConfig
+	client *http.Client
+}
+
+// teamsMessage is the payload sent to a Teams webhook, holding a single Adaptive Card
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// teamsAttachment wraps an Adaptive Card in a Teams message
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+// teamsCard is an Adaptive Card
+type teamsCard struct {
+	Schema  string             `json:"$schema"`
+	Type    string             `json:"type"`
+	Version string             `json:"version"`
+	Body    []teamsCardElement `json:"body"`
+	MSTeams map[string]string  `json:"msteams,omitempty"`
+}
+
+// teamsCardElement is a TextBlock or FactSet element of an Adaptive Card
+type teamsCardElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+// teamsFact is a single title and value pair of a FactSet
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// NewTeamsNotifier creates a TeamsNotifier from the supplied configuration
+func NewTeamsNotifier(config TeamsConfig) *TeamsNotifier {
+	return &TeamsNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (tn *TeamsNotifier) Name() string {
+	return "teams"
+}
+
+// Notify posts an Adaptive Card about the transition to Teams.  The webhook URL can be overridden with an annotation
+// on the khcheck if it is allowed, and a check can opt out of Teams notifications entirely.  Nothing is sent if no webhook URL is
+// configured.
+func (tn *TeamsNotifier) Notify(t Transition) error {
+	if strings.EqualFold(t.Annotations[TeamsDisabledAnnotation], "true") {
+		log.Debugln("notifications: teams notifications are disabled for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	webhookURL, err := tn.webhookURL()
+	if err != nil {
+		return err
+	}
+	if url, ok := t.Annotations[TeamsWebhookURLAnnotation]; ok && len(url) > 0 {
+		if allowedURL(url, tn.config.AllowedWebhookURLs) {
+			webhookURL = url
+		} else {
+			log.Warningln("notifications: ignoring teams webhook URL of check", t.Namespace+"/"+t.CheckName, "because it is not an allowed webhook URL")
+		}
+	}
+	if len(webhookURL) == 0 {
+		log.Debugln("notifications: no teams webhook configured for check", t.Namespace+"/"+t.CheckName)
+		return nil
+	}
+
+	b, err := json.Marshal(teamsMessageForTransition(t))
+	if err != nil {
+		return fmt.Errorf("failed to marshal teams message: %w", err)
+	}
+
+	resp, err := tn.client.Post(webhookURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to post to teams webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("teams webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookURL returns the configured webhook URL.  The URL file is read on each call so that a rotated secret is
+// picked up without restarting Kuberhealthy.
+func (tn *TeamsNotifier) webhookURL() (string, error) {
+	if len(tn.config.WebhookURLFile) == 0 {
+		return tn.config.WebhookURL, nil
+	}
+	b, err := os.ReadFile(tn.config.WebhookURLFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read teams webhook url file %s: %w", tn.config.WebhookURLFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// teamsMessageForTransition builds the Adaptive Card message for a transition.  The card carries the same
+// information as the Slack message of the transition.
+func teamsMessageForTransition(t Transition) teamsMessage {
+	title := fmt.Sprintf("Kuberhealthy check %s in namespace %s%s is failing", t.CheckName, t.Namespace, clusterSuffix(t))
+	color := "Attention"
+	if t.OK {
+		title = fmt.Sprintf("Kuberhealthy check %s in namespace %s%s has recovered", t.CheckName, t.Namespace, clusterSuffix(t))
+		color = "Good"
+	}
+
+	facts := []teamsFact{
+		{Title: "Check", Value: t.CheckName},
+		{Title: "Namespace", Value: t.Namespace},
+	}
+	if len(t.Cluster) > 0 {
+		facts = append(facts, teamsFact{Title: "Cluster", Value: t.Cluster})
+	}
+	if len(t.Environment) > 0 {
+		facts = append(facts, teamsFact{Title: "Environment", Value: t.Environment})
+	}
+	for _, name := range sortedKeys(t.CheckLabels) {
+		facts = append(facts, teamsFact{Title: name, Value: t.CheckLabels[name]})
+	}
+	if len(t.PodName) > 0 {
+		facts = append(facts, teamsFact{Title: "Checker pod", Value: t.PodName})
+	}
+
+	body := []teamsCardElement{
+		{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+		{Type: "FactSet", Facts: facts},
+	}
+	if !t.OK && len(t.Errors) > 0 {
+		body = append(body, teamsCardElement{Type: "TextBlock", Text: "- " + strings.Join(t.Errors, "\n- "), Wrap: true})
+	}
+
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				MSTeams: map[string]string{"width": "Full"},
+			},
+		}},
+	}
+}
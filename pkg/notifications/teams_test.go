@@ -0,0 +1,121 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// teamsServer starts a server that sends the Teams messages posted to it on the returned channel
+func teamsServer(t *testing.T) (*httptest.Server, chan teamsMessage) {
+	received := make(chan teamsMessage, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg teamsMessage
+		err := json.NewDecoder(r.Body).Decode(&msg)
+		if err != nil {
+			t.Error("Failed to decode teams message:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- msg
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestTeamsNotify(t *testing.T) {
+	server, messages := teamsServer(t)
+
+	urlFile := filepath.Join(t.TempDir(), "webhook-url")
+	err := os.WriteFile(urlFile, []byte(server.URL+"\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write teams webhook url file:", err)
+	}
+
+	n := NewTeamsNotifier(TeamsConfig{WebhookURLFile: urlFile})
+	transition := Transition{
+		CheckName: "deployment",
+		Namespace: "kuberhealthy",
+		Errors:    []string{"deployment did not become ready"},
+		PodName:   "deployment-1600000000",
+	}
+
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send teams notification:", err)
+	}
+	received := <-messages
+	if len(received.Attachments) != 1 || received.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("Expected a single adaptive card but got %+v", received)
+	}
+	card := received.Attachments[0].Content
+	b, _ := json.Marshal(card)
+	for _, s := range []string{"deployment-1600000000", "deployment did not become ready", "is failing", "Attention"} {
+		if !strings.Contains(string(b), s) {
+			t.Fatalf("Expected the card to contain %q but got %s", s, string(b))
+		}
+	}
+
+	// checks that opt out are not sent
+	transition.Annotations = map[string]string{TeamsDisabledAnnotation: "true"}
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Unexpected error for a check that opted out:", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("Expected no notification for a check that opted out but got %d", len(messages))
+	}
+}
+
+// TestTeamsWebhookAnnotation ensures that the webhook URL annotation is only honored for allowed webhook URLs
+func TestTeamsWebhookAnnotation(t *testing.T) {
+	global, globalMessages := teamsServer(t)
+	team, teamMessages := teamsServer(t)
+
+	n := NewTeamsNotifier(TeamsConfig{WebhookURL: global.URL + "/webhookb2/global", AllowedWebhookURLs: []string{team.URL + "/webhookb2/"}})
+	transition := Transition{
+		CheckName:   "deployment",
+		Namespace:   "kuberhealthy",
+		Annotations: map[string]string{TeamsWebhookURLAnnotation: team.URL + "/webhookb2/team"},
+	}
+	err := n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send teams notification:", err)
+	}
+	if len(teamMessages) != 1 || len(globalMessages) != 0 {
+		t.Fatalf("Expected the allowed webhook URL annotation to be used but got %d messages on it and %d on the global webhook", len(teamMessages), len(globalMessages))
+	}
+
+	transition.Annotations[TeamsWebhookURLAnnotation] = team.URL + "/other/team"
+	err = n.Notify(transition)
+	if err != nil {
+		t.Fatal("Failed to send teams notification:", err)
+	}
+	if len(teamMessages) != 1 || len(globalMessages) != 1 {
+		t.Fatalf("Expected the disallowed webhook URL annotation to be ignored but got %d messages on the global webhook", len(globalMessages))
+	}
+}
+
+func TestTeamsRecoveryMessage(t *testing.T) {
+	msg := teamsMessageForTransition(Transition{CheckName: "deployment", Namespace: "kuberhealthy", OK: true, Errors: []string{"stale"}})
+	body := msg.Attachments[0].Content.Body
+	if len(body) != 2 {
+		t.Fatalf("Expected recovered cards to leave out errors but got %d elements", len(body))
+	}
+	if body[0].Color != "Good" || !strings.Contains(body[0].Text, "has recovered") {
+		t.Fatalf("Unexpected title of recovered card: %+v", body[0])
+	}
+}
+
+func TestTeamsCheckLabels(t *testing.T) {
+	msg := teamsMessageForTransition(Transition{CheckName: "deployment", Namespace: "kuberhealthy", CheckLabels: map[string]string{"tier": "gold", "team": "payments"}})
+	facts := msg.Attachments[0].Content.Body[1].Facts
+	last := facts[len(facts)-2:]
+	if last[0] != (teamsFact{Title: "team", Value: "payments"}) || last[1] != (teamsFact{Title: "tier", Value: "gold"}) {
+		t.Fatalf("Expected the check labels to be listed in order but got %+v", facts)
+	}
+}
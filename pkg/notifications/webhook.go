@@ -0,0 +1,191 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookSignatureHeader is the header that holds the HMAC-SHA256 signature of a webhook body, as sha256=<hex>
+const WebhookSignatureHeader = "X-Kuberhealthy-Signature"
+
+// WebhookTimestampHeader is the header that holds the unix time a webhook was sent at.  The timestamp is signed
+// along with the body so that receivers can reject replayed webhooks.
+const WebhookTimestampHeader = "X-Kuberhealthy-Timestamp"
+
+// defaultWebhookMaxRetries is the number of times a failed webhook is retried when none is configured
+const defaultWebhookMaxRetries = 3
+
+// webhookRetryInterval is the wait before the first retry of a failed webhook, which doubles with every retry
+var webhookRetryInterval = time.Second
+
+// defaultWebhookTemplate is the body sent when a webhook has no template of its own
+const defaultWebhookTemplate = `{"check":{{json .CheckName}},"namespace":{{json .Namespace}},"ok":{{.OK}},"errors":{{json .Errors}},"podName":{{json .PodName}},"runUUID":{{json .RunUUID}},"runDuration":{{json .RunDuration.String}},"failureReason":{{json .FailureReason}},"cluster":{{json .Cluster}},"environment":{{json .Environment}},"labels":{{json .CheckLabels}},"time":{{json .Time}}}`
+
+// WebhookConfig holds the settings of a single outbound webhook
+type WebhookConfig struct {
+	Name       string            `yaml:"name"`                 // a name for the webhook used in logs
+	URLs       []string          `yaml:"urls"`                 // the URLs that the webhook is posted to
+	Template   string            `yaml:"template,omitempty"`   // a Go text/template that renders the JSON body of the webhook from the transition
+	Headers    map[string]string `yaml:"headers,omitempty"`    // extra headers sent with the webhook, such as an authorization header
+	SecretFile string            `yaml:"secretFile,omitempty"` // a file holding the key used to sign the body with HMAC-SHA256, usually mounted from a secret
+	MaxRetries int               `yaml:"maxRetries,omitempty"` // the number of times a failed webhook is retried. Defaults to 3. set below zero to disable retries
+}
+
+// WebhookNotifier posts a JSON body rendered from a user supplied template to one or more URLs when a check changes
+// state
+type WebhookNotifier struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// webhookTemplateFuncs are the functions available to webhook templates in addition to the text/template builtins
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// NewWebhookNotifier creates a WebhookNotifier from the supplied configuration
+func NewWebhookNotifier(config WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Name returns the name of this notifier
+func (w *WebhookNotifier) Name() string {
+	return "webhook " + w.config.Name
+}
+
+// Notify renders the body of the webhook for the transition and posts it to every configured URL.  Each URL is
+// retried on its own, and an error is returned if any of them could not be delivered to.
+func (w *WebhookNotifier) Notify(t Transition) error {
+	if len(w.config.URLs) == 0 {
+		return nil
+	}
+
+	body, err := w.render(t)
+	if err != nil {
+		return err
+	}
+
+	var secret []byte
+	if len(w.config.SecretFile) > 0 {
+		// the secret is read on each call so that a rotated secret is picked up without restarting Kuberhealthy
+		secret, err = os.ReadFile(w.config.SecretFile)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook secret file %s: %w", w.config.SecretFile, err)
+		}
+		secret = bytes.TrimSpace(secret)
+	}
+
+	var failed []string
+	for _, u := range w.config.URLs {
+		err = w.postWithRetries(u, body, secret)
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to deliver webhook %s: %s", w.config.Name, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// render executes the template of the webhook for the transition.  The rendered body must be valid JSON.
+func (w *WebhookNotifier) render(t Transition) ([]byte, error) {
+	text := w.config.Template
+	if len(strings.TrimSpace(text)) == 0 {
+		text = defaultWebhookTemplate
+	}
+	tmpl, err := template.New(w.config.Name).Funcs(webhookTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template of webhook %s: %w", w.config.Name, err)
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template of webhook %s: %w", w.config.Name, err)
+	}
+	if !json.Valid(b.Bytes()) {
+		return nil, fmt.Errorf("template of webhook %s did not render valid json: %s", w.config.Name, b.String())
+	}
+	return b.Bytes(), nil
+}
+
+// postWithRetries posts the body to a single URL, retrying with a doubling wait when the request fails or the
+// receiver returns a server error or asks to slow down
+func (w *WebhookNotifier) postWithRetries(url string, body []byte, secret []byte) error {
+	maxRetries := w.config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultWebhookMaxRetries
+	}
+
+	wait := webhookRetryInterval
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(url, body, secret)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= maxRetries {
+			return err
+		}
+		log.Warningln("notifications: webhook", w.config.Name, "failed, retrying in", wait.String()+":", err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post sends the body to a single URL once.  It returns whether a failed request is worth retrying.
+func (w *WebhookNotifier) post(url string, body []byte, secret []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+WebhookSignature(secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("webhook receiver returned status code %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// WebhookSignature returns the hex encoded HMAC-SHA256 of the timestamp and body of a webhook, joined by a period.
+// Receivers compute the same signature to verify that a webhook came from Kuberhealthy.
+func WebhookSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal("Failed to write webhook secret file:", err)
+	}
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		timestamp := r.Header.Get(WebhookTimestampHeader)
+		expected := "sha256=" + WebhookSignature([]byte("s3cret"), timestamp, body)
+		if r.Header.Get(WebhookSignatureHeader) != expected {
+			t.Errorf("Expected signature %s but got %s", expected, r.Header.Get(WebhookSignatureHeader))
+		}
+		if r.Header.Get("X-Team") != "sre" {
+			t.Errorf("Expected the configured header to be sent but got %q", r.Header.Get("X-Team"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(WebhookConfig{
+		Name:       "incidents",
+		URLs:       []string{server.URL},
+		Template:   `{"summary": {{json (printf "%s/%s failed" .Namespace .CheckName)}}, "errors": {{json (join .Errors "; ")}}, "run": {{json .RunUUID}}, "seconds": {{.RunDuration.Seconds}}}`,
+		Headers:    map[string]string{"X-Team": "sre"},
+		SecretFile: secretFile,
+	})
+	err = n.Notify(Transition{
+		CheckName:   "dns",
+		Namespace:   "kuberhealthy",
+		Errors:      []string{"lookup failed", `server said "no"`},
+		RunUUID:     "abc-123",
+		RunDuration: time.Second * 2,
+	})
+	if err != nil {
+		t.Fatal("Failed to send webhook:", err)
+	}
+
+	var received map[string]interface{}
+	err = json.Unmarshal(body, &received)
+	if err != nil {
+		t.Fatal("Failed to decode webhook body:", err)
+	}
+	if received["summary"] != "kuberhealthy/dns failed" || received["errors"] != `lookup failed; server said "no"` || received["run"] != "abc-123" || received["seconds"] != float64(2) {
+		t.Fatalf("Unexpected webhook body: %s", string(body))
+	}
+}
+
+func TestWebhookDefaultTemplate(t *testing.T) {
+	n := NewWebhookNotifier(WebhookConfig{Name: "default"})
+	body, err := n.render(Transition{CheckName: "dns", Namespace: "kuberhealthy", OK: true, CheckLabels: map[string]string{"team": "platform"}})
+	if err != nil {
+		t.Fatal("Failed to render default template:", err)
+	}
+	if !strings.Contains(string(body), `"check":"dns"`) || !strings.Contains(string(body), `"ok":true`) || !strings.Contains(string(body), `"labels":{"team":"platform"}`) {
+		t.Fatalf("Unexpected default webhook body: %s", string(body))
+	}
+}
+
+func TestWebhookInvalidTemplate(t *testing.T) {
+	n := NewWebhookNotifier(WebhookConfig{Name: "broken", Template: `{"check": {{.CheckName}}}`})
+	_, err := n.render(Transition{CheckName: "dns"})
+	if err == nil {
+		t.Fatal("Expected an error for a template that does not render valid json")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	webhookRetryInterval = time.Millisecond
+	defer func() { webhookRetryInterval = time.Second }()
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(WebhookConfig{Name: "flaky", URLs: []string{server.URL}})
+	err := n.Notify(Transition{CheckName: "dns", Namespace: "kuberhealthy"})
+	if err != nil {
+		t.Fatal("Expected the webhook to succeed after retrying:", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", attempts)
+	}
+
+	// client errors are not retried
+	attempts = 0
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	n = NewWebhookNotifier(WebhookConfig{Name: "rejecting", URLs: []string{rejecting.URL}})
+	err = n.Notify(Transition{CheckName: "dns", Namespace: "kuberhealthy"})
+	if err == nil {
+		t.Fatal("Expected an error when the receiver rejects the webhook")
+	}
+	if attempts != 1 {
+		t.Fatalf("Expected 1 attempt but got %d", attempts)
+	}
+}
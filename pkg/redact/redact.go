@@ -0,0 +1,152 @@
+// Package redact scrubs secrets out of the errors that checks report before they are stored in khstates and shown
+// on the status page.  Errors are scrubbed of the values of sensitive environment variables of the checker pod and
+// of text matching common token and password patterns.
+package redact // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/redact"
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// Redacted replaces every secret found in an error
+const Redacted = "[REDACTED]"
+
+// minSensitiveValueLength is the length below which environment variable values are not redacted, because short
+// values such as "1" or "true" would redact unrelated text
+const minSensitiveValueLength = 4
+
+// DefaultSensitiveEnvVarNames are the parts of environment variable names that mark them as sensitive when no
+// others are configured
+var DefaultSensitiveEnvVarNames = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL"}
+
+// redactionPattern is a regular expression whose matches are replaced with replacement
+type redactionPattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// defaultPatterns match common secrets.  Where the replacement keeps part of the match, the error still names what
+// was redacted.
+var defaultPatterns = []redactionPattern{
+	{regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9\-._~+/]+=*`), "${1}" + Redacted},
+	{regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[=:]\s*)(?:"[^"]*"|'[^']*'|[^\s,;&"']+)`), "${1}" + Redacted},
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}" + Redacted + "@"},
+	{regexp.MustCompile(`eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`), Redacted},
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), Redacted},
+}
+
+// Config holds the settings used to redact reported errors
+type Config struct {
+	Disabled             bool     `yaml:"disabled,omitempty"`             // set to true to store reported errors as they are
+	SensitiveEnvVarNames []string `yaml:"sensitiveEnvVarNames,omitempty"` // parts of environment variable names that mark them as sensitive
+	Patterns             []string `yaml:"patterns,omitempty"`             // extra regular expressions whose matches are redacted
+}
+
+var currentConfig Config
+var extraPatterns []*regexp.Regexp
+var configMu sync.RWMutex
+
+// Configure sets the settings used to redact errors.  Patterns that are not valid regular expressions are skipped
+// and returned as an error.
+func Configure(config Config) error {
+	var compiled []*regexp.Regexp
+	var invalid []string
+	for _, p := range config.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			invalid = append(invalid, p+": "+err.Error())
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+
+	configMu.Lock()
+	currentConfig = config
+	extraPatterns = compiled
+	configMu.Unlock()
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid redaction patterns: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// Errors returns a copy of the supplied errors with the supplied sensitive values and anything matching the
+// redaction patterns replaced
+func Errors(errs []string, sensitiveValues []string) []string {
+	if errs == nil {
+		return nil
+	}
+	redacted := make([]string, len(errs))
+	for i, e := range errs {
+		redacted[i] = String(e, sensitiveValues)
+	}
+	return redacted
+}
+
+// String returns the supplied string with the supplied sensitive values and anything matching the redaction
+// patterns replaced
+func String(s string, sensitiveValues []string) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	if currentConfig.Disabled {
+		return s
+	}
+
+	for _, v := range sensitiveValues {
+		if len(v) >= minSensitiveValueLength {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	for _, p := range defaultPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	for _, re := range extraPatterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// SensitiveEnvValues returns the literal values of the environment variables in the supplied pod spec that are
+// sensitive.  Variables are sensitive if they are named in markedNames or their name contains one of the configured
+// sensitive name parts.
+func SensitiveEnvValues(spec apiv1.PodSpec, markedNames []string) []string {
+	configMu.RLock()
+	nameParts := currentConfig.SensitiveEnvVarNames
+	configMu.RUnlock()
+	if len(nameParts) == 0 {
+		nameParts = DefaultSensitiveEnvVarNames
+	}
+
+	var values []string
+	containers := append(append([]apiv1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, env := range c.Env {
+			if len(env.Value) > 0 && sensitiveName(env.Name, nameParts, markedNames) {
+				values = append(values, env.Value)
+			}
+		}
+	}
+	return values
+}
+
+// sensitiveName indicates if an environment variable name is marked sensitive or contains a sensitive name part
+func sensitiveName(name string, nameParts []string, markedNames []string) bool {
+	for _, marked := range markedNames {
+		if name == marked {
+			return true
+		}
+	}
+	upper := strings.ToUpper(name)
+	for _, part := range nameParts {
+		if len(part) > 0 && strings.Contains(upper, strings.ToUpper(part)) {
+			return true
+		}
+	}
+	return false
+}
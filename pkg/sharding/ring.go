@@ -0,0 +1,86 @@
+// Package sharding spreads work across a set of members with consistent hashing, so that only a small share of
+// the work moves to another member when members join or leave.
+package sharding // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/sharding"
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points each member is placed at on the ring.  More points spread keys more evenly
+// across the members.
+const virtualNodes = 128
+
+// Ring assigns keys to members with consistent hashing.  A Ring is not modified after it is created, so it is safe
+// to use from multiple goroutines.
+type Ring struct {
+	members []string // the members of the ring in sorted order
+	points  []point  // the points of every member on the ring in hash order
+}
+
+// point is a position on the ring that is owned by a member
+type point struct {
+	hash   uint64
+	member string
+}
+
+// NewRing creates a ring with the supplied members.  Duplicate and blank members are ignored.
+func NewRing(members []string) *Ring {
+	r := &Ring{}
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		if len(member) == 0 || seen[member] {
+			continue
+		}
+		seen[member] = true
+		r.members = append(r.members, member)
+		for i := 0; i < virtualNodes; i++ {
+			r.points = append(r.points, point{hash: hashKey(member + "#" + strconv.Itoa(i)), member: member})
+		}
+	}
+	sort.Strings(r.members)
+
+	// points that hash the same are ordered by member so that every instance builds the same ring
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].member < r.points[j].member
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+// Members returns the members of the ring in sorted order
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+// Owner returns the member that owns the supplied key.  A blank member is returned when the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].member
+}
+
+// hashKey hashes a key to a position on the ring.  The FNV hash is mixed further so that similar keys, such as the
+// virtual nodes of a member, land far apart.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,50 @@
+package sharding
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestRingOwner ensures that keys are spread across every member and that the owner of a key does not depend on
+// the order members are supplied in
+func TestRingOwner(t *testing.T) {
+	ring := NewRing([]string{"kuberhealthy-a", "kuberhealthy-b", "kuberhealthy-c"})
+	reordered := NewRing([]string{"kuberhealthy-c", "kuberhealthy-a", "kuberhealthy-b", "kuberhealthy-a", ""})
+
+	if members := reordered.Members(); len(members) != 3 || members[0] != "kuberhealthy-a" {
+		t.Fatalf("expected 3 sorted members without duplicates but got %v", members)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1500; i++ {
+		key := "kuberhealthy/check-" + strconv.Itoa(i)
+		owner := ring.Owner(key)
+		if owner != reordered.Owner(key) {
+			t.Fatalf("expected %s to have the same owner regardless of member order", key)
+		}
+		counts[owner]++
+	}
+	for _, member := range ring.Members() {
+		if counts[member] < 300 || counts[member] > 700 {
+			t.Fatalf("expected keys to be spread evenly across members but got %v", counts)
+		}
+	}
+}
+
+// TestRingRebalance ensures that only the keys of a member that leaves move to other members
+func TestRingRebalance(t *testing.T) {
+	before := NewRing([]string{"kuberhealthy-a", "kuberhealthy-b", "kuberhealthy-c"})
+	after := NewRing([]string{"kuberhealthy-a", "kuberhealthy-b"})
+
+	for i := 0; i < 1500; i++ {
+		key := "kuberhealthy/check-" + strconv.Itoa(i)
+		owner := before.Owner(key)
+		if owner != "kuberhealthy-c" && after.Owner(key) != owner {
+			t.Fatalf("expected %s to stay with %s when another member left but it moved to %s", key, owner, after.Owner(key))
+		}
+	}
+
+	if owner := NewRing(nil).Owner("kuberhealthy/check"); owner != "" {
+		t.Fatalf("expected no owner on an empty ring but got %s", owner)
+	}
+}
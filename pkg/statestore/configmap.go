@@ -0,0 +1,250 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// stateConfigMapLabel is set on the ConfigMaps that hold khstates to the name of the khstate
+const stateConfigMapLabel = "comcast.github.io/khstate"
+
+// stateConfigMapSuffix is appended to the name of a khstate to name the ConfigMap it is kept in
+const stateConfigMapSuffix = "-khstate"
+
+// ConfigMap data keys that the spec and status of a khstate are kept under
+const (
+	stateConfigMapSpecKey   = "spec.json"
+	stateConfigMapStatusKey = "status.json"
+)
+
+// ConfigMapStore keeps each khstate in a ConfigMap in the namespace of its check or job.  The ConfigMap carries the
+// owner references of the khstate, so it is garbage collected along with its khcheck or khjob.
+type ConfigMapStore struct {
+	client kubernetes.Interface
+}
+
+// NewConfigMapStore creates a ConfigMapStore that uses the supplied kubernetes client
+func NewConfigMapStore(client kubernetes.Interface) *ConfigMapStore {
+	return &ConfigMapStore{client: client}
+}
+
+// KuberhealthyStates returns an interface to the khstates kept in ConfigMaps in the supplied namespace
+func (s *ConfigMapStore) KuberhealthyStates(namespace string) khstatev1.KuberhealthyStateInterface {
+	return &configMapStates{client: s.client, ns: namespace}
+}
+
+// configMapStates implements KuberhealthyStateInterface for khstates kept in ConfigMaps
+type configMapStates struct {
+	client kubernetes.Interface
+	ns     string
+}
+
+// Create creates the ConfigMap of a new khstate
+func (c *configMapStates) Create(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	cm, err := stateToConfigMap(state, c.ns)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	cm.ResourceVersion = ""
+	created, err := c.client.CoreV1().ConfigMaps(c.ns).Create(context.TODO(), cm, metav1.CreateOptions{})
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	return configMapToState(created)
+}
+
+// Update replaces the spec of a khstate.  The status of the khstate is left as it is.
+func (c *configMapStates) Update(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	return c.update(state, stateConfigMapSpecKey)
+}
+
+// UpdateStatus replaces the status of a khstate.  The spec of the khstate is left as it is.
+func (c *configMapStates) UpdateStatus(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	return c.update(state, stateConfigMapStatusKey)
+}
+
+// update writes the spec or status of a khstate to its ConfigMap.  The resource version of the khstate is passed
+// along, so changes made since the khstate was read are reported as conflicts.
+func (c *configMapStates) update(state *khstatev1.KuberhealthyState, key string) (khstatev1.KuberhealthyState, error) {
+	existing, err := c.client.CoreV1().ConfigMaps(c.ns).Get(context.TODO(), stateConfigMapName(state.GetName()), metav1.GetOptions{})
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, notFoundAsState(err, state.GetName())
+	}
+
+	cm, err := stateToConfigMap(state, c.ns)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+
+	// only the part of the khstate being updated is replaced
+	updated := existing.DeepCopy()
+	updated.ResourceVersion = state.GetResourceVersion()
+	updated.Labels = cm.Labels
+	updated.Annotations = cm.Annotations
+	updated.OwnerReferences = cm.OwnerReferences
+	if updated.Data == nil {
+		updated.Data = make(map[string]string)
+	}
+	updated.Data[key] = cm.Data[key]
+
+	updated, err = c.client.CoreV1().ConfigMaps(c.ns).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, notFoundAsState(err, state.GetName())
+	}
+	return configMapToState(updated)
+}
+
+// Delete deletes the ConfigMap of a khstate
+func (c *configMapStates) Delete(name string, options *metav1.DeleteOptions) error {
+	if options == nil {
+		options = &metav1.DeleteOptions{}
+	}
+	err := c.client.CoreV1().ConfigMaps(c.ns).Delete(context.TODO(), stateConfigMapName(name), *options)
+	return notFoundAsState(err, name)
+}
+
+// DeleteCollection deletes the ConfigMaps of all khstates in the namespace
+func (c *configMapStates) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	if options == nil {
+		options = &metav1.DeleteOptions{}
+	}
+	return c.client.CoreV1().ConfigMaps(c.ns).DeleteCollection(context.TODO(), *options, stateListOptions(listOptions))
+}
+
+// Get returns the khstate kept in the ConfigMap of the named khstate
+func (c *configMapStates) Get(name string, options metav1.GetOptions) (khstatev1.KuberhealthyState, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.ns).Get(context.TODO(), stateConfigMapName(name), options)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, notFoundAsState(err, name)
+	}
+	return configMapToState(cm)
+}
+
+// List returns the khstates kept in ConfigMaps in the namespace
+func (c *configMapStates) List(opts metav1.ListOptions) (khstatev1.KuberhealthyStateList, error) {
+	list := khstatev1.KuberhealthyStateList{}
+	cms, err := c.client.CoreV1().ConfigMaps(c.ns).List(context.TODO(), stateListOptions(opts))
+	if err != nil {
+		return list, err
+	}
+
+	list.ResourceVersion = cms.ResourceVersion
+	for i := range cms.Items {
+		state, err := configMapToState(&cms.Items[i])
+		if err != nil {
+			return list, err
+		}
+		list.Items = append(list.Items, state)
+	}
+	return list, nil
+}
+
+// Watch watches the ConfigMaps of khstates in the namespace and turns their events into khstate events
+func (c *configMapStates) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	w, err := c.client.CoreV1().ConfigMaps(c.ns).Watch(context.TODO(), stateListOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		cm, ok := in.Object.(*v1.ConfigMap)
+		if !ok {
+			return in, true
+		}
+		state, err := configMapToState(cm)
+		if err != nil {
+			return in, false
+		}
+		in.Object = &state
+		return in, true
+	}), nil
+}
+
+// Patch applies a JSON merge patch to a khstate.  Other patch types are not supported.
+func (c *configMapStates) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (khstatev1.KuberhealthyState, error) {
+	return patchByUpdate(c, name, pt, data, subresources)
+}
+
+// stateConfigMapName returns the name of the ConfigMap that the named khstate is kept in
+func stateConfigMapName(name string) string {
+	return name + stateConfigMapSuffix
+}
+
+// stateListOptions limits the supplied list options to the ConfigMaps that hold khstates
+func stateListOptions(opts metav1.ListOptions) metav1.ListOptions {
+	opts.LabelSelector = stateConfigMapLabel
+	return opts
+}
+
+// notFoundAsState reports ConfigMaps that are not found as missing khstates
+func notFoundAsState(err error, name string) error {
+	if k8sErrors.IsNotFound(err) {
+		return newNotFound(name)
+	}
+	return err
+}
+
+// stateToConfigMap converts a khstate to the ConfigMap it is kept in
+func stateToConfigMap(state *khstatev1.KuberhealthyState, namespace string) (*v1.ConfigMap, error) {
+	spec, err := json.Marshal(state.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec of khstate %s: %w", state.GetName(), err)
+	}
+	status, err := json.Marshal(state.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status of khstate %s: %w", state.GetName(), err)
+	}
+
+	labels := make(map[string]string, len(state.GetLabels())+1)
+	for k, v := range state.GetLabels() {
+		labels[k] = v
+	}
+	labels[stateConfigMapLabel] = state.GetName()
+
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            stateConfigMapName(state.GetName()),
+			Namespace:       namespace,
+			ResourceVersion: state.GetResourceVersion(),
+			Labels:          labels,
+			Annotations:     state.GetAnnotations(),
+			OwnerReferences: state.GetOwnerReferences(),
+		},
+		Data: map[string]string{
+			stateConfigMapSpecKey:   string(spec),
+			stateConfigMapStatusKey: string(status),
+		},
+	}, nil
+}
+
+// configMapToState converts a ConfigMap back to the khstate kept in it
+func configMapToState(cm *v1.ConfigMap) (khstatev1.KuberhealthyState, error) {
+	name := cm.Labels[stateConfigMapLabel]
+	state := khstatev1.KuberhealthyState{}
+	state.ObjectMeta = *cm.ObjectMeta.DeepCopy()
+	state.Name = name
+	delete(state.Labels, stateConfigMapLabel)
+
+	if spec := cm.Data[stateConfigMapSpecKey]; len(spec) > 0 {
+		err := json.Unmarshal([]byte(spec), &state.Spec)
+		if err != nil {
+			return state, fmt.Errorf("failed to unmarshal spec of khstate %s: %w", name, err)
+		}
+	}
+	if status := cm.Data[stateConfigMapStatusKey]; len(status) > 0 {
+		err := json.Unmarshal([]byte(status), &state.Status)
+		if err != nil {
+			return state, fmt.Errorf("failed to unmarshal status of khstate %s: %w", name, err)
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,118 @@
+package statestore
+
+import (
+	"context"
+	"testing"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestConfigMapStore ensures that khstates kept in ConfigMaps keep their spec, status and owner references
+func TestConfigMapStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	states := NewConfigMapStore(client).KuberhealthyStates("kuberhealthy")
+
+	_, err := states.Get("dns", metav1.GetOptions{})
+	if !k8sErrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error for a missing khstate but got %v", err)
+	}
+
+	initial := khstatev1.NewKuberhealthyState("dns", khstatev1.WorkloadDetails{OK: true})
+	initial.OwnerReferences = []metav1.OwnerReference{{APIVersion: "comcast.github.io/v1", Kind: "KuberhealthyCheck", Name: "dns", UID: "1234"}}
+	created, err := states.Create(&initial)
+	if err != nil {
+		t.Fatal("Failed to create khstate:", err)
+	}
+	if created.Name != "dns" {
+		t.Fatalf("Expected the khstate to be named dns but got %s", created.Name)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("kuberhealthy").Get(context.Background(), "dns"+stateConfigMapSuffix, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Expected the khstate to be kept in a ConfigMap:", err)
+	}
+	if cm.Labels[stateConfigMapLabel] != "dns" || len(cm.OwnerReferences) != 1 {
+		t.Fatalf("Expected the ConfigMap to be labeled and owned by the khcheck but got %+v", cm.ObjectMeta)
+	}
+
+	// the spec is updated without touching the status, and the status without touching the spec
+	failing := created.DeepCopy()
+	failing.Spec = khstatev1.WorkloadDetails{OK: false, Errors: []string{"lookup failed"}}
+	updated, err := states.Update(failing)
+	if err != nil {
+		t.Fatal("Failed to update khstate:", err)
+	}
+	updated.Status.ConsecutiveFailures = 1
+	_, err = states.UpdateStatus(&updated)
+	if err != nil {
+		t.Fatal("Failed to update khstate status:", err)
+	}
+
+	got, err := states.Get("dns", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Failed to get khstate:", err)
+	}
+	if got.Spec.OK || len(got.Spec.Errors) != 1 || got.Status.ConsecutiveFailures != 1 || len(got.OwnerReferences) != 1 {
+		t.Fatalf("Unexpected khstate after updates: %+v", got)
+	}
+	if _, labeled := got.Labels[stateConfigMapLabel]; labeled {
+		t.Fatal("Expected the ConfigMap label to be left off of the khstate")
+	}
+
+	list, err := states.List(metav1.ListOptions{})
+	if err != nil || len(list.Items) != 1 || list.Items[0].Name != "dns" {
+		t.Fatalf("Expected one khstate named dns to be listed but got %+v: %v", list.Items, err)
+	}
+
+	err = states.Delete("dns", &metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatal("Failed to delete khstate:", err)
+	}
+	err = states.Delete("dns", &metav1.DeleteOptions{})
+	if !k8sErrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error when deleting a missing khstate but got %v", err)
+	}
+}
+
+// TestConfigMapStorePatch ensures that merge patches only change the fields they set on khstates kept in ConfigMaps
+func TestConfigMapStorePatch(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	states := NewConfigMapStore(client).KuberhealthyStates("kuberhealthy")
+
+	initial := khstatev1.NewKuberhealthyState("dns", khstatev1.WorkloadDetails{OK: true, CurrentUUID: "1234"})
+	_, err := states.Create(&initial)
+	if err != nil {
+		t.Fatal("Failed to create khstate:", err)
+	}
+
+	_, err = states.Patch("dns", types.MergePatchType, []byte(`{"spec":{"OK":false,"Errors":["lookup failed"]}}`))
+	if err != nil {
+		t.Fatal("Failed to patch khstate:", err)
+	}
+	_, err = states.Patch("dns", types.MergePatchType, []byte(`{"status":{"consecutiveFailures":1}}`), "status")
+	if err != nil {
+		t.Fatal("Failed to patch khstate status:", err)
+	}
+
+	got, err := states.Get("dns", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Failed to get khstate:", err)
+	}
+	if got.Spec.OK || len(got.Spec.Errors) != 1 || got.Spec.CurrentUUID != "1234" || got.Status.ConsecutiveFailures != 1 {
+		t.Fatalf("Unexpected khstate after patches: %+v", got)
+	}
+
+	_, err = states.Patch("dns", types.JSONPatchType, []byte(`[]`))
+	if err == nil {
+		t.Fatal("Expected JSON patches to be rejected")
+	}
+	_, err = states.Patch("missing", types.MergePatchType, []byte(`{}`))
+	if !k8sErrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error when patching a missing khstate but got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package statestore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// patchMaxTries is how many times a patch is applied before giving up when the khstate keeps changing underneath it
+const patchMaxTries = 5
+
+// patchByUpdate applies a JSON merge patch to a khstate by reading it and writing back the patched khstate, for
+// backends that can not patch khstates themselves.  Writes made by others in the meantime are retried against the
+// latest khstate, like the API server does for patches of custom resources.  Only the status subresource can be
+// patched on its own.
+func patchByUpdate(states khstatev1.KuberhealthyStateInterface, name string, pt types.PatchType, data []byte, subresources []string) (khstatev1.KuberhealthyState, error) {
+	if pt != types.MergePatchType {
+		return khstatev1.KuberhealthyState{}, fmt.Errorf("patching khstate %s with a %s patch is not supported. only %s patches are", name, pt, types.MergePatchType)
+	}
+	status := false
+	switch {
+	case len(subresources) == 0:
+	case len(subresources) == 1 && subresources[0] == "status":
+		status = true
+	default:
+		return khstatev1.KuberhealthyState{}, fmt.Errorf("patching the %v subresource of khstate %s is not supported", subresources, name)
+	}
+
+	var err error
+	for tries := 0; tries < patchMaxTries; tries++ {
+		var existing khstatev1.KuberhealthyState
+		existing, err = states.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return khstatev1.KuberhealthyState{}, err
+		}
+
+		var patched khstatev1.KuberhealthyState
+		patched, err = mergePatchState(existing, data)
+		if err != nil {
+			return khstatev1.KuberhealthyState{}, err
+		}
+
+		var updated khstatev1.KuberhealthyState
+		if status {
+			updated, err = states.UpdateStatus(&patched)
+		} else {
+			updated, err = states.Update(&patched)
+		}
+		if err == nil {
+			return updated, nil
+		}
+		if !k8sErrors.IsConflict(err) {
+			return khstatev1.KuberhealthyState{}, err
+		}
+	}
+	return khstatev1.KuberhealthyState{}, err
+}
+
+// mergePatchState applies a JSON merge patch to a khstate.  The resource version of the khstate is kept, so that
+// writing the patched khstate fails with a conflict if it changed since it was read.
+func mergePatchState(state khstatev1.KuberhealthyState, data []byte) (khstatev1.KuberhealthyState, error) {
+	original, err := json.Marshal(state)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	b, err := jsonpatch.MergePatch(original, data)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, k8sErrors.NewBadRequest("invalid merge patch for khstate " + state.GetName() + ": " + err.Error())
+	}
+
+	patched := khstatev1.KuberhealthyState{}
+	err = json.Unmarshal(b, &patched)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	patched.ResourceVersion = state.ResourceVersion
+	return patched, nil
+}
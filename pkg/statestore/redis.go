@@ -0,0 +1,342 @@
+package statestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// defaultRedisKeyPrefix is prepended to the keys of khstates kept in Redis when no prefix is configured
+const defaultRedisKeyPrefix = "kuberhealthy"
+
+// RedisConfig holds the settings for keeping khstates in Redis
+type RedisConfig struct {
+	Address   string `yaml:"address,omitempty"`   // the host and port of the Redis server, such as redis.kuberhealthy.svc:6379
+	Username  string `yaml:"username,omitempty"`  // the ACL user to authenticate as. blank authenticates with the password only
+	Password  string `yaml:"password,omitempty"`  // the password to authenticate with. blank disables authentication
+	Database  int    `yaml:"database,omitempty"`  // the number of the Redis database to use
+	EnableTLS bool   `yaml:"enableTLS,omitempty"` // connect to the Redis server over TLS
+	KeyPrefix string `yaml:"keyPrefix,omitempty"` // prepended to all keys so that several Kuberhealthy instances can share a Redis server. defaults to kuberhealthy
+}
+
+// RedisStore keeps each khstate in a Redis key.  The value of the key holds the resource version of the khstate
+// followed by the khstate as JSON.  Writes are checked against the resource version with WATCH, so changes made
+// since a khstate was read are reported as conflicts just like they are for khstate custom resources.  The keys
+// of all khstates are kept in a set so that they can be listed without scanning the keyspace.
+type RedisStore struct {
+	client       *respClient
+	keyPrefix    string
+	pollInterval time.Duration
+}
+
+// NewRedisStore creates a RedisStore for the supplied Redis server settings.  Watches list khstates on the
+// supplied interval to find changes.
+func NewRedisStore(config RedisConfig, pollInterval time.Duration) *RedisStore {
+	keyPrefix := config.KeyPrefix
+	if len(keyPrefix) == 0 {
+		keyPrefix = defaultRedisKeyPrefix
+	}
+	return &RedisStore{
+		client:       newRESPClient(config),
+		keyPrefix:    keyPrefix,
+		pollInterval: pollInterval,
+	}
+}
+
+// KuberhealthyStates returns an interface to the khstates kept in Redis for the supplied namespace
+func (s *RedisStore) KuberhealthyStates(namespace string) khstatev1.KuberhealthyStateInterface {
+	return &redisStates{store: s, ns: namespace}
+}
+
+// stateKey returns the key that the named khstate is kept in
+func (s *RedisStore) stateKey(namespace string, name string) string {
+	return s.keyPrefix + ":khstate:" + namespace + "/" + name
+}
+
+// indexKey returns the key of the set that holds the keys of all khstates
+func (s *RedisStore) indexKey() string {
+	return s.keyPrefix + ":khstates"
+}
+
+// resourceVersionKey returns the key of the counter that resource versions are taken from
+func (s *RedisStore) resourceVersionKey() string {
+	return s.keyPrefix + ":resourceVersion"
+}
+
+// nextResourceVersion returns a new resource version.  Resource versions that are taken and not used are skipped.
+func (s *RedisStore) nextResourceVersion() (string, error) {
+	reply, err := s.client.do("INCR", s.resourceVersionKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to take a resource version for khstate: %w", err)
+	}
+	rv, ok := reply.(int64)
+	if !ok {
+		return "", fmt.Errorf("unexpected reply to INCR: %v", reply)
+	}
+	return strconv.FormatInt(rv, 10), nil
+}
+
+// redisStates implements KuberhealthyStateInterface for khstates kept in Redis
+type redisStates struct {
+	store *RedisStore
+	ns    string
+}
+
+// Create stores a new khstate.  An error is returned if the khstate already exists.
+func (c *redisStates) Create(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	rv, err := c.store.nextResourceVersion()
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+
+	created := *state.DeepCopy()
+	created.Namespace = c.ns
+	created.UID = types.UID(uuid.New().String())
+	created.CreationTimestamp = metav1.Now()
+	created.ResourceVersion = rv
+	value, err := encodeRedisState(&created)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+
+	key := c.store.stateKey(c.ns, created.Name)
+	_, err = c.store.client.do("SET", key, value, "NX")
+	if errors.Is(err, errRedisNil) {
+		return khstatev1.KuberhealthyState{}, k8sErrors.NewAlreadyExists(groupResource, created.Name)
+	}
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, fmt.Errorf("failed to create khstate %s: %w", created.Name, err)
+	}
+
+	_, err = c.store.client.do("SADD", c.store.indexKey(), key)
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, fmt.Errorf("failed to index khstate %s: %w", created.Name, err)
+	}
+	return created, nil
+}
+
+// Update replaces the spec and metadata of a khstate.  The status of the khstate is left as it is.
+func (c *redisStates) Update(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	return c.update(state, func(existing *khstatev1.KuberhealthyState) {
+		existing.Labels = state.Labels
+		existing.Annotations = state.Annotations
+		existing.OwnerReferences = state.OwnerReferences
+		existing.Spec = state.Spec
+	})
+}
+
+// UpdateStatus replaces the status of a khstate.  The spec of the khstate is left as it is.
+func (c *redisStates) UpdateStatus(state *khstatev1.KuberhealthyState) (khstatev1.KuberhealthyState, error) {
+	return c.update(state, func(existing *khstatev1.KuberhealthyState) {
+		existing.Status = state.Status
+	})
+}
+
+// update applies a change to the stored khstate.  The stored khstate is watched while it is changed, so that
+// writes made by others in the meantime are reported as conflicts.
+func (c *redisStates) update(state *khstatev1.KuberhealthyState, change func(existing *khstatev1.KuberhealthyState)) (khstatev1.KuberhealthyState, error) {
+	rv, err := c.store.nextResourceVersion()
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+
+	name := state.GetName()
+	key := c.store.stateKey(c.ns, name)
+	var updated khstatev1.KuberhealthyState
+	err = c.store.client.transaction(func(do func(args ...string) (interface{}, error)) error {
+		_, err := do("WATCH", key)
+		if err != nil {
+			return err
+		}
+		reply, err := do("GET", key)
+		if errors.Is(err, errRedisNil) {
+			do("UNWATCH")
+			return newNotFound(name)
+		}
+		if err != nil {
+			do("UNWATCH")
+			return err
+		}
+		existing, err := decodeRedisState(reply)
+		if err != nil {
+			do("UNWATCH")
+			return err
+		}
+		if len(state.GetResourceVersion()) > 0 && state.GetResourceVersion() != existing.GetResourceVersion() {
+			do("UNWATCH")
+			return newConflict(name)
+		}
+
+		change(&existing)
+		existing.ResourceVersion = rv
+		value, err := encodeRedisState(&existing)
+		if err != nil {
+			do("UNWATCH")
+			return err
+		}
+
+		_, err = do("MULTI")
+		if err != nil {
+			return err
+		}
+		_, err = do("SET", key, value)
+		if err != nil {
+			do("DISCARD")
+			return err
+		}
+		_, err = do("EXEC")
+		if errors.Is(err, errRedisNil) {
+			return newConflict(name)
+		}
+		if err != nil {
+			return err
+		}
+		updated = existing
+		return nil
+	})
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, err
+	}
+	return updated, nil
+}
+
+// Delete deletes a khstate
+func (c *redisStates) Delete(name string, options *metav1.DeleteOptions) error {
+	key := c.store.stateKey(c.ns, name)
+	reply, err := c.store.client.do("DEL", key)
+	if err != nil {
+		return fmt.Errorf("failed to delete khstate %s: %w", name, err)
+	}
+	_, err = c.store.client.do("SREM", c.store.indexKey(), key)
+	if err != nil {
+		return fmt.Errorf("failed to remove khstate %s from the index: %w", name, err)
+	}
+	if deleted, ok := reply.(int64); ok && deleted == 0 {
+		return newNotFound(name)
+	}
+	return nil
+}
+
+// DeleteCollection deletes all khstates in the namespace
+func (c *redisStates) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	list, err := c.List(listOptions)
+	if err != nil {
+		return err
+	}
+	for _, state := range list.Items {
+		err = c.store.KuberhealthyStates(state.GetNamespace()).Delete(state.GetName(), options)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the named khstate
+func (c *redisStates) Get(name string, options metav1.GetOptions) (khstatev1.KuberhealthyState, error) {
+	reply, err := c.store.client.do("GET", c.store.stateKey(c.ns, name))
+	if errors.Is(err, errRedisNil) {
+		return khstatev1.KuberhealthyState{}, newNotFound(name)
+	}
+	if err != nil {
+		return khstatev1.KuberhealthyState{}, fmt.Errorf("failed to get khstate %s: %w", name, err)
+	}
+	return decodeRedisState(reply)
+}
+
+// List returns the khstates in the namespace.  Khstates in all namespaces are listed when the namespace is blank.
+// All list options are ignored.
+func (c *redisStates) List(opts metav1.ListOptions) (khstatev1.KuberhealthyStateList, error) {
+	list := khstatev1.KuberhealthyStateList{}
+	reply, err := c.store.client.do("SMEMBERS", c.store.indexKey())
+	if err != nil {
+		return list, fmt.Errorf("failed to list khstates: %w", err)
+	}
+	members, _ := reply.([]interface{})
+
+	keyPrefix := c.store.stateKey(c.ns, "")
+	if len(c.ns) == 0 {
+		keyPrefix = c.store.keyPrefix + ":khstate:"
+	}
+	args := []string{"MGET"}
+	for _, member := range members {
+		key, ok := member.(string)
+		if ok && strings.HasPrefix(key, keyPrefix) {
+			args = append(args, key)
+		}
+	}
+	if len(args) == 1 {
+		return list, nil
+	}
+
+	reply, err = c.store.client.do(args...)
+	if err != nil {
+		return list, fmt.Errorf("failed to get khstates: %w", err)
+	}
+	values, _ := reply.([]interface{})
+	for _, value := range values {
+		// khstates deleted since the index was read are skipped
+		if value == nil {
+			continue
+		}
+		state, err := decodeRedisState(value)
+		if err != nil {
+			return list, err
+		}
+		if matchesNamespace(&state, c.ns) {
+			list.Items = append(list.Items, state)
+		}
+	}
+	return list, nil
+}
+
+// Watch lists the khstates in the namespace on the poll interval of the store and sends an event for each change
+func (c *redisStates) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return newPollWatcher(func() (khstatev1.KuberhealthyStateList, error) {
+		return c.List(opts)
+	}, c.store.pollInterval), nil
+}
+
+// Patch applies a JSON merge patch to a khstate.  Other patch types are not supported.
+func (c *redisStates) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (khstatev1.KuberhealthyState, error) {
+	return patchByUpdate(c, name, pt, data, subresources)
+}
+
+// encodeRedisState encodes a khstate as the value of its key: its resource version, a space and the khstate as JSON
+func encodeRedisState(state *khstatev1.KuberhealthyState) (string, error) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal khstate %s: %w", state.GetName(), err)
+	}
+	return state.GetResourceVersion() + " " + string(b), nil
+}
+
+// decodeRedisState decodes the value of the key of a khstate
+func decodeRedisState(reply interface{}) (khstatev1.KuberhealthyState, error) {
+	state := khstatev1.KuberhealthyState{}
+	value, ok := reply.(string)
+	if !ok {
+		return state, fmt.Errorf("unexpected khstate value in redis: %v", reply)
+	}
+	rv, data, found := strings.Cut(value, " ")
+	if !found {
+		return state, fmt.Errorf("malformed khstate value in redis: %q", value)
+	}
+	err := json.Unmarshal([]byte(data), &state)
+	if err != nil {
+		return state, fmt.Errorf("failed to unmarshal khstate from redis: %w", err)
+	}
+	state.ResourceVersion = rv
+	return state, nil
+}
@@ -0,0 +1,268 @@
+package statestore
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// fakeRedis is an in-memory server for the subset of Redis commands used by the RedisStore
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	sets     map[string]map[string]bool
+	versions map[string]int // incremented on every write to a key, so that WATCH can find changes
+	listener net.Listener
+}
+
+// newFakeRedis starts a fakeRedis on a random local port
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen for fake redis:", err)
+	}
+	r := &fakeRedis{
+		values:   make(map[string]string),
+		sets:     make(map[string]map[string]bool),
+		versions: make(map[string]int),
+		listener: listener,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return r
+}
+
+// serve answers the commands sent on a single connection
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	watched := make(map[string]int)
+	var queued [][]string
+	var inMulti bool
+
+	for {
+		reply, err := readRESPReply(reader)
+		if err != nil {
+			return
+		}
+		elements, _ := reply.([]interface{})
+		args := make([]string, len(elements))
+		for i, e := range elements {
+			args[i], _ = e.(string)
+		}
+
+		var out string
+		switch strings.ToUpper(args[0]) {
+		case "WATCH":
+			r.mu.Lock()
+			watched[args[1]] = r.versions[args[1]]
+			r.mu.Unlock()
+			out = "+OK\r\n"
+		case "UNWATCH":
+			watched = make(map[string]int)
+			out = "+OK\r\n"
+		case "MULTI":
+			inMulti = true
+			queued = nil
+			out = "+OK\r\n"
+		case "DISCARD":
+			inMulti = false
+			watched = make(map[string]int)
+			out = "+OK\r\n"
+		case "EXEC":
+			r.mu.Lock()
+			changed := false
+			for key, version := range watched {
+				if r.versions[key] != version {
+					changed = true
+				}
+			}
+			if changed {
+				out = "*-1\r\n"
+			} else {
+				out = "*" + strconv.Itoa(len(queued)) + "\r\n"
+				for _, q := range queued {
+					out += r.apply(q)
+				}
+			}
+			r.mu.Unlock()
+			inMulti = false
+			watched = make(map[string]int)
+		default:
+			if inMulti {
+				queued = append(queued, args)
+				out = "+QUEUED\r\n"
+				break
+			}
+			r.mu.Lock()
+			out = r.apply(args)
+			r.mu.Unlock()
+		}
+
+		_, err = conn.Write([]byte(out))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// apply runs a single data command and returns its encoded reply.  The caller must hold mu.
+func (r *fakeRedis) apply(args []string) string {
+	bulk := func(s string) string {
+		return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "INCR":
+		n, _ := strconv.Atoi(r.values[args[1]])
+		n++
+		r.values[args[1]] = strconv.Itoa(n)
+		r.versions[args[1]]++
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SET":
+		if len(args) > 3 && args[3] == "NX" {
+			if _, exists := r.values[args[1]]; exists {
+				return "$-1\r\n"
+			}
+		}
+		r.values[args[1]] = args[2]
+		r.versions[args[1]]++
+		return "+OK\r\n"
+	case "GET":
+		value, exists := r.values[args[1]]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "MGET":
+		out := "*" + strconv.Itoa(len(args)-1) + "\r\n"
+		for _, key := range args[1:] {
+			value, exists := r.values[key]
+			if !exists {
+				out += "$-1\r\n"
+				continue
+			}
+			out += bulk(value)
+		}
+		return out
+	case "DEL":
+		_, exists := r.values[args[1]]
+		delete(r.values, args[1])
+		r.versions[args[1]]++
+		if exists {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "SADD":
+		if r.sets[args[1]] == nil {
+			r.sets[args[1]] = make(map[string]bool)
+		}
+		r.sets[args[1]][args[2]] = true
+		return ":1\r\n"
+	case "SREM":
+		delete(r.sets[args[1]], args[2])
+		return ":1\r\n"
+	case "SMEMBERS":
+		out := "*" + strconv.Itoa(len(r.sets[args[1]])) + "\r\n"
+		for member := range r.sets[args[1]] {
+			out += bulk(member)
+		}
+		return out
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// TestRedisStore ensures that khstates kept in Redis can be created, read, updated, listed and deleted, and that
+// stale writes are rejected as conflicts
+func TestRedisStore(t *testing.T) {
+	server := newFakeRedis(t)
+	store := NewRedisStore(RedisConfig{Address: server.listener.Addr().String()}, time.Second)
+	states := store.KuberhealthyStates("kuberhealthy")
+
+	_, err := states.Get("dns", metav1.GetOptions{})
+	if !k8sErrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error for a missing khstate but got %v", err)
+	}
+
+	initial := khstatev1.NewKuberhealthyState("dns", khstatev1.WorkloadDetails{OK: true})
+	created, err := states.Create(&initial)
+	if err != nil {
+		t.Fatal("Failed to create khstate:", err)
+	}
+	if created.Namespace != "kuberhealthy" || len(created.ResourceVersion) == 0 || len(created.UID) == 0 {
+		t.Fatalf("Expected the created khstate to have a namespace, resource version and UID but got %+v", created.ObjectMeta)
+	}
+	_, err = states.Create(&initial)
+	if !k8sErrors.IsAlreadyExists(err) {
+		t.Fatalf("Expected an already exists error for a duplicate khstate but got %v", err)
+	}
+
+	// the spec is updated without touching the status, and the status without touching the spec
+	failing := created.DeepCopy()
+	failing.Spec = khstatev1.WorkloadDetails{OK: false, Errors: []string{"lookup failed"}}
+	updated, err := states.Update(failing)
+	if err != nil {
+		t.Fatal("Failed to update khstate:", err)
+	}
+	updated.Status.ConsecutiveFailures = 1
+	updated, err = states.UpdateStatus(&updated)
+	if err != nil {
+		t.Fatal("Failed to update khstate status:", err)
+	}
+
+	got, err := states.Get("dns", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Failed to get khstate:", err)
+	}
+	if got.Spec.OK || len(got.Spec.Errors) != 1 || got.Status.ConsecutiveFailures != 1 || got.ResourceVersion != updated.ResourceVersion {
+		t.Fatalf("Unexpected khstate after updates: %+v", got)
+	}
+
+	// writes based on an old resource version are conflicts
+	_, err = states.Update(failing)
+	if !k8sErrors.IsConflict(err) {
+		t.Fatalf("Expected a conflict for a stale update but got %v", err)
+	}
+
+	other := khstatev1.NewKuberhealthyState("payments", khstatev1.WorkloadDetails{OK: true})
+	_, err = store.KuberhealthyStates("payments").Create(&other)
+	if err != nil {
+		t.Fatal("Failed to create khstate in another namespace:", err)
+	}
+	list, err := states.List(metav1.ListOptions{})
+	if err != nil || len(list.Items) != 1 {
+		t.Fatalf("Expected one khstate in the namespace but got %d: %v", len(list.Items), err)
+	}
+	list, err = store.KuberhealthyStates("").List(metav1.ListOptions{})
+	if err != nil || len(list.Items) != 2 {
+		t.Fatalf("Expected two khstates in all namespaces but got %d: %v", len(list.Items), err)
+	}
+
+	err = states.Delete("dns", &metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatal("Failed to delete khstate:", err)
+	}
+	err = states.Delete("dns", &metav1.DeleteOptions{})
+	if !k8sErrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error when deleting a missing khstate but got %v", err)
+	}
+}
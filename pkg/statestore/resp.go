@@ -0,0 +1,200 @@
+package statestore
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisDialTimeout is the time allowed for connecting to the Redis server
+const redisDialTimeout = time.Second * 5
+
+// redisIOTimeout is the time allowed for a single command to be sent to the Redis server and answered
+const redisIOTimeout = time.Second * 10
+
+// errRedisNil is returned for the null replies of the Redis server, such as when a key does not exist
+var errRedisNil = errors.New("redis: nil")
+
+// redisError is an error reply from the Redis server
+type redisError string
+
+// Error returns the message of the error reply
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// respClient is a minimal client for the Redis serialization protocol.  It holds a single connection that is
+// opened on first use and opened again after any error.  Commands are sent one at a time.
+type respClient struct {
+	config RedisConfig
+	mu     sync.Mutex // guards conn and reader and serializes commands
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newRESPClient creates a respClient for the supplied Redis server settings
+func newRESPClient(config RedisConfig) *respClient {
+	return &respClient{config: config}
+}
+
+// do sends a single command and returns its reply
+func (c *respClient) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.doLocked(args...)
+}
+
+// transaction calls fn with exclusive use of the connection, so that commands such as WATCH, MULTI and EXEC can be
+// sent together without commands of other callers in between
+func (c *respClient) transaction(fn func(do func(args ...string) (interface{}, error)) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fn(c.doLocked)
+}
+
+// doLocked sends a single command and returns its reply.  The connection is closed after network and protocol
+// errors so that the next command opens a new one.
+func (c *respClient) doLocked(args ...string) (interface{}, error) {
+	if c.conn == nil {
+		err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(args...)
+	var replyErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// connect opens a connection to the Redis server, authenticates and selects the configured database
+func (c *respClient) connect() error {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if c.config.EnableTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.config.Address, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", c.config.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis at %s: %w", c.config.Address, err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	var setup [][]string
+	switch {
+	case len(c.config.Username) > 0:
+		setup = append(setup, []string{"AUTH", c.config.Username, c.config.Password})
+	case len(c.config.Password) > 0:
+		setup = append(setup, []string{"AUTH", c.config.Password})
+	}
+	if c.config.Database != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.config.Database)})
+	}
+	for _, args := range setup {
+		_, err = c.roundTrip(args...)
+		if err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("failed to set up redis connection with %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command to the connection and reads its reply
+func (c *respClient) roundTrip(args ...string) (interface{}, error) {
+	err := c.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err = c.conn.Write(buf)
+	if err != nil {
+		return nil, err
+	}
+	return readRESPReply(c.reader)
+}
+
+// readRESPReply reads a single reply.  Simple strings and bulk strings are returned as strings, integers as int64
+// and arrays as []interface{}.  Null replies are returned as errRedisNil and error replies as a redisError.  Null
+// elements of arrays are returned as nil and error elements as a redisError.
+func readRESPReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk string length %q", line)
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", line)
+		}
+		if count < 0 {
+			return nil, errRedisNil
+		}
+		elements := make([]interface{}, count)
+		for i := range elements {
+			elements[i], err = readRESPReply(r)
+			var replyErr redisError
+			switch {
+			case errors.Is(err, errRedisNil):
+			case errors.As(err, &replyErr):
+				elements[i] = replyErr
+			case err != nil:
+				return nil, err
+			}
+		}
+		return elements, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line)
+	}
+}
@@ -0,0 +1,188 @@
+// Package statestore persists the khstates of Kuberhealthy checks and jobs.  The khstate custom resource is the
+// default backend.  Very large clusters can keep khstates in ConfigMaps or in Redis instead, which keeps thousands of
+// khstate updates from hitting the object size and write rate limits of etcd.
+package statestore // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/statestore"
+
+import (
+	"fmt"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	log "github.com/sirupsen/logrus"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// Backend is the name of a storage backend for khstates
+type Backend string
+
+// The storage backends that khstates can be kept in
+const (
+	BackendCRD       Backend = "crd"       // khstate custom resources
+	BackendConfigMap Backend = "configMap" // one ConfigMap per khstate in the namespace of its check
+	BackendRedis     Backend = "redis"     // one key per khstate in a Redis server
+)
+
+// defaultWatchPollInterval is how often backends that can not watch for changes are listed to find them
+const defaultWatchPollInterval = time.Second * 10
+
+// Config holds the settings of the storage backend that khstates are kept in
+type Config struct {
+	Backend           Backend       `yaml:"backend,omitempty"`           // the backend khstates are kept in: crd, configMap or redis. defaults to crd
+	WatchPollInterval time.Duration `yaml:"watchPollInterval,omitempty"` // how often backends that can not watch for changes are listed to find them. defaults to 10s
+	Redis             RedisConfig   `yaml:"redis,omitempty"`             // settings for keeping khstates in Redis
+}
+
+// groupResource is the resource reported in the errors of all backends, so that callers can tell apart missing and
+// conflicting khstates the same way regardless of where they are kept
+var groupResource = schema.GroupResource{Group: "comcast.github.io", Resource: "khstates"}
+
+// New returns the khstate storage backend selected by the supplied config.  The khstate custom resource client is
+// returned as-is when the crd backend is selected.
+func New(config Config, client kubernetes.Interface, crdClient khstatev1.KuberhealthyStatesGetter) (khstatev1.KuberhealthyStatesGetter, error) {
+	pollInterval := config.WatchPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultWatchPollInterval
+	}
+
+	switch config.Backend {
+	case "", BackendCRD:
+		return crdClient, nil
+	case BackendConfigMap:
+		log.Infoln("Keeping khstates in ConfigMaps")
+		return NewConfigMapStore(client), nil
+	case BackendRedis:
+		if len(config.Redis.Address) == 0 {
+			return nil, fmt.Errorf("the redis state storage backend requires an address")
+		}
+		log.Infoln("Keeping khstates in Redis at", config.Redis.Address)
+		return NewRedisStore(config.Redis, pollInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown state storage backend %q. expected one of %s, %s or %s", config.Backend, BackendCRD, BackendConfigMap, BackendRedis)
+	}
+}
+
+// newNotFound returns the error that backends return when a khstate does not exist
+func newNotFound(name string) error {
+	return k8sErrors.NewNotFound(groupResource, name)
+}
+
+// newConflict returns the error that backends return when a khstate was changed since it was read
+func newConflict(name string) error {
+	return k8sErrors.NewConflict(groupResource, name, fmt.Errorf("the khstate has been modified; please apply your changes to the latest version and try again"))
+}
+
+// matchesNamespace determines if a khstate is in the namespace being listed.  A blank namespace lists khstates in
+// all namespaces.
+func matchesNamespace(state *khstatev1.KuberhealthyState, namespace string) bool {
+	return len(namespace) == 0 || state.GetNamespace() == namespace
+}
+
+// pollWatcher finds changes to khstates by listing them on an interval and comparing the resource versions of each
+// listing with the one before it.  It is used by backends that can not watch for changes themselves.
+type pollWatcher struct {
+	list     func() (khstatev1.KuberhealthyStateList, error)
+	interval time.Duration
+	result   chan watch.Event
+	stop     chan struct{}
+	seen     map[string]khstatev1.KuberhealthyState // the khstates of the last listing by namespace and name
+}
+
+// newPollWatcher starts a pollWatcher in the background.  Khstates in the first listing are sent as added events.
+func newPollWatcher(list func() (khstatev1.KuberhealthyStateList, error), interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		list:     list,
+		interval: interval,
+		result:   make(chan watch.Event),
+		stop:     make(chan struct{}),
+		seen:     make(map[string]khstatev1.KuberhealthyState),
+	}
+	go w.run()
+	return w
+}
+
+// Stop stops the watcher and closes its result channel
+func (w *pollWatcher) Stop() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+}
+
+// ResultChan returns the channel that events are sent on
+func (w *pollWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// run lists khstates on every interval until the watcher is stopped
+func (w *pollWatcher) run() {
+	defer close(w.result)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if !w.poll() {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// poll lists khstates once and sends an event for each change since the last listing.  It returns false when the
+// watcher has been stopped.
+func (w *pollWatcher) poll() bool {
+	list, err := w.list()
+	if err != nil {
+		log.Errorln("Error listing khstates to watch for changes:", err)
+		return w.send(watch.Event{Type: watch.Error, Object: &k8sErrors.NewInternalError(err).ErrStatus})
+	}
+
+	current := make(map[string]khstatev1.KuberhealthyState, len(list.Items))
+	for _, state := range list.Items {
+		key := state.GetNamespace() + "/" + state.GetName()
+		current[key] = state
+
+		previous, exists := w.seen[key]
+		switch {
+		case !exists:
+			if !w.send(watch.Event{Type: watch.Added, Object: state.DeepCopy()}) {
+				return false
+			}
+		case previous.GetResourceVersion() != state.GetResourceVersion():
+			if !w.send(watch.Event{Type: watch.Modified, Object: state.DeepCopy()}) {
+				return false
+			}
+		}
+	}
+
+	for key, state := range w.seen {
+		if _, exists := current[key]; exists {
+			continue
+		}
+		if !w.send(watch.Event{Type: watch.Deleted, Object: state.DeepCopy()}) {
+			return false
+		}
+	}
+
+	w.seen = current
+	return true
+}
+
+// send sends an event unless the watcher is stopped first.  It returns false when the watcher has been stopped.
+func (w *pollWatcher) send(event watch.Event) bool {
+	select {
+	case w.result <- event:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+package statestore
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/watch"
+
+	khstatev1 "github.com/kuberhealthy/kuberhealthy/v2/pkg/apis/khstate/v1"
+)
+
+// TestPollWatcher ensures that the poll watcher sends an event for each khstate that is added, modified or deleted
+// between listings
+func TestPollWatcher(t *testing.T) {
+	newState := func(name string, resourceVersion string) khstatev1.KuberhealthyState {
+		state := khstatev1.NewKuberhealthyState(name, khstatev1.WorkloadDetails{OK: true})
+		state.Namespace = "kuberhealthy"
+		state.ResourceVersion = resourceVersion
+		return state
+	}
+	listings := [][]khstatev1.KuberhealthyState{
+		{newState("dns", "1"), newState("deployment", "2")},
+		{newState("dns", "3")},
+	}
+
+	w := newPollWatcher(func() (khstatev1.KuberhealthyStateList, error) {
+		list := khstatev1.KuberhealthyStateList{}
+		if len(listings) > 0 {
+			list.Items = listings[0]
+			listings = listings[1:]
+		}
+		return list, nil
+	}, time.Millisecond*10)
+	defer w.Stop()
+
+	expected := map[watch.EventType]string{
+		watch.Added:    "dns,deployment",
+		watch.Modified: "dns",
+		watch.Deleted:  "deployment",
+	}
+	seen := make(map[watch.EventType]string)
+	timeout := time.After(time.Second * 5)
+	for i := 0; i < 4; i++ {
+		select {
+		case event := <-w.ResultChan():
+			state := event.Object.(*khstatev1.KuberhealthyState)
+			if len(seen[event.Type]) > 0 {
+				seen[event.Type] += ","
+			}
+			seen[event.Type] += state.Name
+		case <-timeout:
+			t.Fatalf("Timed out waiting for watch events. Saw %v", seen)
+		}
+	}
+
+	for eventType, names := range expected {
+		if seen[eventType] != names {
+			t.Fatalf("Expected %s events for %s but got %s", eventType, names, seen[eventType])
+		}
+	}
+
+	w.Stop()
+	for range w.ResultChan() {
+	}
+}
@@ -0,0 +1,242 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exportBatchSize is the number of spans that are sent to the OTLP endpoint at once
+const exportBatchSize = 256
+
+// exportInterval is how often spans waiting to be exported are sent to the OTLP endpoint
+const exportInterval = time.Second * 5
+
+// exportQueueSize is the number of spans that can wait to be exported.  Spans are dropped when the queue is full.
+const exportQueueSize = 2048
+
+// OTLP span kind and status codes
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOK     = 1
+	otlpStatusCodeError  = 2
+)
+
+// exporter batches finished spans and sends them to an OTLP/HTTP endpoint using the OTLP JSON encoding
+type exporter struct {
+	config  Config
+	client  *http.Client
+	queue   chan *Span
+	wg      sync.WaitGroup
+	mu      sync.Mutex // guards stopped and sends on queue
+	stopped bool
+}
+
+// newExporter creates an exporter for the supplied config and starts it in the background
+func newExporter(config Config) *exporter {
+	e := &exporter{
+		config: config,
+		client: &http.Client{Timeout: time.Second * 10},
+		queue:  make(chan *Span, exportQueueSize),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// export queues a finished span for export.  The span is dropped if the queue is full or the exporter has been
+// shut down.
+func (e *exporter) export(s *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+
+	select {
+	case e.queue <- s:
+	default:
+		log.Warningln("tracing: export queue is full. dropping span", s.name)
+	}
+}
+
+// shutdown stops the exporter after sending any spans that are waiting to be exported
+func (e *exporter) shutdown() {
+	e.mu.Lock()
+	e.stopped = true
+	close(e.queue)
+	e.mu.Unlock()
+	e.wg.Wait()
+}
+
+// run sends queued spans in batches until the exporter is shut down
+func (e *exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		e.send(batch)
+		batch = nil
+	}
+}
+
+// send posts a batch of spans to the OTLP endpoint
+func (e *exporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(newOTLPRequest(e.config.ServiceName, batch))
+	if err != nil {
+		log.Errorln("tracing: failed to encode spans:", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.config.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		log.Errorln("tracing: failed to create export request:", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Errorln("tracing: failed to export", len(batch), "spans:", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Errorln("tracing: failed to export", len(batch), "spans:", fmt.Sprintf("unexpected status code %d", resp.StatusCode))
+		return
+	}
+	log.Debugln("tracing: exported", len(batch), "spans")
+}
+
+// otlpRequest is the OTLP JSON encoding of an ExportTraceServiceRequest
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// newOTLPRequest encodes a batch of spans as an OTLP export request
+func newOTLPRequest(serviceName string, batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, newOTLPSpan(s))
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{newOTLPAttribute(String("service.name", serviceName))},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "github.com/kuberhealthy/kuberhealthy/v2"},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// newOTLPSpan encodes a single span for an OTLP export request
+func newOTLPSpan(s *Span) otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: otlpStatusCodeOK},
+	}
+	if s.hasParent {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for _, a := range s.attributes {
+		span.Attributes = append(span.Attributes, newOTLPAttribute(a))
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err.Error()}
+	}
+	return span
+}
+
+// newOTLPAttribute encodes a span attribute for an OTLP export request
+func newOTLPAttribute(a Attribute) otlpAttribute {
+	return otlpAttribute{Key: a.Key, Value: otlpAnyValue{StringValue: a.Value}}
+}
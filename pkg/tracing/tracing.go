@@ -0,0 +1,152 @@
+// Package tracing records OpenTelemetry compatible spans and exports them to an OTLP/HTTP endpoint.  Until
+// tracing is configured, spans are not recorded and all span methods are no-ops.
+package tracing // import "github.com/kuberhealthy/kuberhealthy/v2/pkg/tracing"
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultServiceName is the service name spans are exported with when none is configured
+const DefaultServiceName = "kuberhealthy"
+
+// Config holds the settings used to export spans
+type Config struct {
+	Enabled     bool              `yaml:"enabled"`               // export spans when true
+	Endpoint    string            `yaml:"endpoint"`              // the OTLP/HTTP traces endpoint, such as http://otel-collector:4318/v1/traces
+	ServiceName string            `yaml:"serviceName,omitempty"` // the service name spans are exported with
+	Headers     map[string]string `yaml:"headers,omitempty"`     // extra headers sent with each export, such as authentication
+}
+
+// Attribute is a key and value pair describing a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String creates a span attribute with a string value
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a single timed operation in a trace.  A nil Span is valid and does nothing, which is what Start returns
+// when tracing is not configured.
+type Span struct {
+	exporter   *exporter
+	name       string
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	hasParent  bool
+	start      time.Time
+	end        time.Time
+	attributes []Attribute
+	err        error
+	mu         sync.Mutex
+	ended      bool
+}
+
+// spanContextKey is the context key used to carry the current span
+type spanContextKey struct{}
+
+var currentExporter *exporter
+var exporterMu sync.RWMutex
+
+// Configure sets up span exporting with the supplied config.  Any previously configured exporter is flushed
+// and stopped.  When the config is not enabled, spans are no longer recorded.
+func Configure(config Config) {
+	exporterMu.Lock()
+	previous := currentExporter
+	currentExporter = nil
+	if config.Enabled && len(config.Endpoint) > 0 {
+		if len(config.ServiceName) == 0 {
+			config.ServiceName = DefaultServiceName
+		}
+		log.Infoln("tracing: exporting spans to", config.Endpoint, "as service", config.ServiceName)
+		currentExporter = newExporter(config)
+	}
+	exporterMu.Unlock()
+
+	if previous != nil {
+		previous.shutdown()
+	}
+}
+
+// Shutdown flushes any spans waiting to be exported and stops exporting
+func Shutdown() {
+	Configure(Config{})
+}
+
+// Start begins a new span.  If the supplied context carries a span, the new span is its child, otherwise a new
+// trace is started.  The returned context carries the new span.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	exporterMu.RLock()
+	e := currentExporter
+	exporterMu.RUnlock()
+	if e == nil {
+		return ctx, nil
+	}
+
+	s := &Span{
+		exporter:   e,
+		name:       name,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	rand.Read(s.spanID[:])
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+		s.hasParent = true
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// FromContext returns the span carried by the supplied context, or nil if there is none
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// SetError marks the span as failed with the supplied error.  A nil error leaves the span as it is.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End finishes the span and queues it for export.  Calling End more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.exporter.export(s)
+}
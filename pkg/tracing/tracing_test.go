@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStartWithoutConfig ensures that spans are not recorded until tracing is configured
+func TestStartWithoutConfig(t *testing.T) {
+	Configure(Config{})
+
+	ctx, span := Start(context.Background(), "test")
+	if span != nil {
+		t.Fatal("Expected no span when tracing is not configured")
+	}
+	if FromContext(ctx) != nil {
+		t.Fatal("Expected no span in the context when tracing is not configured")
+	}
+
+	// nil spans must be safe to use
+	span.SetAttributes(String("key", "value"))
+	span.SetError(errors.New("failed"))
+	span.End()
+}
+
+// TestExport ensures that finished spans are exported to the OTLP endpoint with their parents
+func TestExport(t *testing.T) {
+	requests := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := otlpRequest{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Error("Failed to decode export request:", err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	Configure(Config{Enabled: true, Endpoint: server.URL})
+
+	ctx, parent := Start(context.Background(), "parent", String("check", "kuberhealthy/test-check"))
+	_, child := Start(ctx, "child")
+	child.SetError(errors.New("failed"))
+	child.End()
+	parent.End()
+
+	// shutting down flushes the spans
+	Shutdown()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope in the export request but got %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans to be exported but got %d", len(spans))
+	}
+
+	exportedChild, exportedParent := spans[0], spans[1]
+	if exportedChild.TraceID != exportedParent.TraceID {
+		t.Fatal("Expected the child span to be in the trace of its parent")
+	}
+	if exportedChild.ParentSpanID != exportedParent.SpanID {
+		t.Fatal("Expected the child span to reference its parent")
+	}
+	if exportedChild.Status.Code != otlpStatusCodeError {
+		t.Fatal("Expected the child span to have an error status")
+	}
+	if exportedParent.Status.Code != otlpStatusCodeOK {
+		t.Fatal("Expected the parent span to have an OK status")
+	}
+	if req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != DefaultServiceName {
+		t.Fatal("Expected spans to be exported with the default service name")
+	}
+}